@@ -7,25 +7,23 @@ import (
 	"fmt"
 	"github.com/jhalter/mobius/hotline"
 	"github.com/jhalter/mobius/internal/mobius"
+	"github.com/jhalter/mobius/internal/version"
 	"github.com/oleksandr/bonjour"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"path"
 	"path/filepath"
 	"syscall"
+	"time"
 )
 
 //go:embed mobius/config
 var cfgTemplate embed.FS
 
-// Values swapped in by go-releaser at build time
-var (
-	version = "dev"
-	commit  = "none"
-)
-
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -40,15 +38,17 @@ func main() {
 	logLevel := flag.String("log-level", "info", "Log level")
 	logFile := flag.String("log-file", "", "Path to log file")
 	init := flag.Bool("init", false, "Populate the config dir with default configuration")
+	migrateNews := flag.Bool("migrate-news", false, "Copy ThreadedNews.yaml into a new ThreadedNews.db database for NewsStore: sqlite, then exit")
 
 	flag.Parse()
 
 	if *printVersion {
-		fmt.Printf("mobius-hotline-server version %s, commit %s\n", version, commit)
+		fmt.Printf("mobius-hotline-server %s\n", version.Get())
 		os.Exit(0)
 	}
 
 	slogger := mobius.NewLogger(logLevel, logFile)
+	build := version.Get()
 
 	// It's important for Windows compatibility to use path.Join and not filepath.Join for the config dir initialization.
 	// https://github.com/golang/go/issues/44305
@@ -63,17 +63,50 @@ func main() {
 				os.Exit(1)
 			}
 			slogger.Info("Config dir initialized at " + *configDir)
+
+			if isTerminal(os.Stdin) {
+				if err := runSetup(*configDir); err != nil {
+					slogger.Error(fmt.Sprintf("error during setup: %s", err))
+					os.Exit(1)
+				}
+			} else {
+				slogger.Info("The admin account will be given a new password when the server starts.  Look for it in the log.")
+			}
 		} else {
 			slogger.Info("Existing config dir found.  Skipping initialization.")
 		}
 	}
 
+	if *migrateNews {
+		count, err := mobius.MigrateThreadedNews(path.Join(*configDir, "ThreadedNews.yaml"), path.Join(*configDir, "ThreadedNews.db"))
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error migrating news: %v", err))
+			os.Exit(1)
+		}
+		slogger.Info("Migrated threaded news.  Set NewsStore: sqlite in config.yaml to use it.", "articles", count)
+		os.Exit(0)
+	}
+
 	config, err := mobius.LoadConfig(path.Join(*configDir, "config.yaml"))
 	if err != nil {
 		slogger.Error(fmt.Sprintf("Error loading config: %v", err))
 		os.Exit(1)
 	}
 
+	// The -bind flag takes precedence over the port in the config file.
+	if !flagSet("bind") && config.Port != 0 {
+		*basePort = config.Port
+	}
+
+	logHandlers, err := mobius.LogHandlers(*config, *logLevel)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error configuring log outputs: %v", err))
+		os.Exit(1)
+	}
+	if len(logHandlers) > 0 {
+		slogger = slog.New(append(mobius.MultiHandler{slogger.Handler()}, logHandlers...))
+	}
+
 	srv, err := hotline.NewServer(
 		hotline.WithInterface(*netInterface),
 		hotline.WithLogger(slogger),
@@ -85,6 +118,74 @@ func main() {
 		os.Exit(1)
 	}
 
+	srv.Version = build.Version
+
+	// Every listener is bound before privileges are dropped, and none is served until after.
+	hotlineLn, fileTransferLn, err := srv.Listen()
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error starting server: %s", err))
+		os.Exit(1)
+	}
+
+	var apiLn net.Listener
+	if *apiAddr != "" {
+		apiLn, err = net.Listen("tcp", *apiAddr)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error starting API server: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	var nntpLn net.Listener
+	if config.NNTP.Addr != "" {
+		nntpLn, err = net.Listen("tcp", config.NNTP.Addr)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error starting NNTP server: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if err := srv.DropPrivileges(); err != nil {
+		slogger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	srv.PasswordHasher, err = hotline.NewPasswordHasher(config.PasswordHashing)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error configuring password hashing: %v", err))
+		os.Exit(1)
+	}
+
+	srv.PanicReporter, err = mobius.NewPanicReporter(config.ErrorReporting, build, slogger)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error configuring error reporting: %v", err))
+		os.Exit(1)
+	}
+
+	if config.WebChat.Enabled {
+		srv.ChatFeed = hotline.NewChatFeed()
+	}
+
+	srv.UploadScanner, err = mobius.NewUploadScanner(config.UploadScan)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error configuring upload scanning: %v", err))
+		os.Exit(1)
+	}
+
+	if config.Tracing.OTLPEndpoint != "" {
+		exporter := mobius.NewOTLPSpanExporter(config.Tracing, build.Version, slogger)
+		srv.SpanExporter = exporter
+		go exporter.Run(ctx)
+	}
+
+	if config.AuditLog.Enabled() {
+		srv.AuditSink, err = mobius.NewAuditSink(config.AuditLog, *configDir, slogger)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error configuring audit log: %v", err))
+			os.Exit(1)
+		}
+	}
+
 	srv.MessageBoard, err = mobius.NewFlatNews(path.Join(*configDir, "MessageBoard.txt"))
 	if err != nil {
 		slogger.Error(fmt.Sprintf("Error loading message board: %v", err))
@@ -97,18 +198,120 @@ func main() {
 		os.Exit(1)
 	}
 
-	srv.ThreadedNewsMgr, err = mobius.NewThreadedNewsYAML(path.Join(*configDir, "ThreadedNews.yaml"))
+	srv.FileReportMgr, err = mobius.NewFileReportsYAML(path.Join(*configDir, "FileReports.yaml"))
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading file reports: %v", err))
+		os.Exit(1)
+	}
+
+	srv.IncompleteUploadMgr, err = mobius.NewIncompleteUploadsYAML(path.Join(*configDir, "IncompleteUploads.yaml"))
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading incomplete uploads: %v", err))
+		os.Exit(1)
+	}
+
+	srv.BroadcastHistory, err = mobius.NewBroadcastHistoryYAML(path.Join(*configDir, "Broadcasts.yaml"), path.Join(*configDir, "LastLogins.yaml"))
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading broadcast history: %v", err))
+		os.Exit(1)
+	}
+
+	if config.ChatHistory.Persist || config.PrivateChats.Persist {
+		var historyPath, chatsPath string
+		if config.ChatHistory.Persist {
+			historyPath = path.Join(*configDir, "ChatHistory.yaml")
+		}
+		if config.PrivateChats.Persist {
+			chatsPath = path.Join(*configDir, "PrivateChats.yaml")
+		}
+
+		srv.ChatMgr, err = mobius.NewChatYAML(historyPath, chatsPath, slogger)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error loading chats: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	srv.Events, err = mobius.NewEventsYAML(path.Join(*configDir, "Events.yaml"))
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading events: %v", err))
+		os.Exit(1)
+	}
+
+	switch config.NewsStore {
+	case "sqlite":
+		var news *mobius.SQLiteThreadedNews
+		news, err = mobius.NewSQLiteThreadedNews(path.Join(*configDir, "ThreadedNews.db"))
+		if err == nil {
+			srv.ThreadedNewsMgr = news
+			srv.OnAccountRename(news.RenamePoster)
+		}
+	case "", "yaml":
+		var news *mobius.ThreadedNewsYAML
+		news, err = mobius.NewThreadedNewsYAML(path.Join(*configDir, "ThreadedNews.yaml"))
+		if err == nil {
+			srv.ThreadedNewsMgr = news
+			srv.OnAccountRename(news.RenamePoster)
+		}
+	default:
+		err = fmt.Errorf("unknown NewsStore %q", config.NewsStore)
+	}
 	if err != nil {
 		slogger.Error(fmt.Sprintf("Error loading news: %v", err))
 		os.Exit(1)
 	}
 
-	srv.AccountManager, err = mobius.NewYAMLAccountManager(filepath.Join(*configDir, "Users/"))
+	srv.OnAccountRename(srv.FileReportMgr.(*mobius.FileReportsYAML).RenameReporter)
+	srv.OnAccountRename(srv.IncompleteUploadMgr.(*mobius.IncompleteUploadsYAML).RenameUploader)
+
+	switch config.AccountStore {
+	case "sqlite":
+		srv.AccountManager, err = mobius.NewSQLiteAccountManager(filepath.Join(*configDir, "Users.db"), filepath.Join(*configDir, "Users"))
+	case "", "yaml":
+		srv.AccountManager, err = mobius.NewYAMLAccountManager(filepath.Join(*configDir, "Users/"), config.AccountCacheSize)
+	default:
+		err = fmt.Errorf("unknown AccountStore %q", config.AccountStore)
+	}
 	if err != nil {
 		slogger.Error(fmt.Sprintf("Error loading accounts: %v", err))
 		os.Exit(1)
 	}
 
+	if !config.AllowDefaultAdminPassword {
+		password, err := mobius.ReplaceDefaultAdminPassword(srv.AccountManager, srv.HashPassword)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error replacing default admin password: %v", err))
+			os.Exit(1)
+		}
+		if password != "" {
+			slogger.Warn("The admin account had the default password and has been given a new one.  Save it now; it will not be shown again.", "login", "admin", "password", password)
+		}
+	}
+
+	if config.ConfineFiles {
+		roots := []string{config.FileRoot, config.PendingUploadsDir, config.QuarantineDir}
+		for _, account := range srv.AccountManager.List() {
+			if account.FileRoot != "" {
+				roots = append(roots, account.FileRoot)
+			}
+		}
+
+		srv.FS, err = hotline.NewConfinedFileStore(roots...)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error confining files: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if config.NativeForks {
+		fileStore, err := hotline.NewNativeForkFileStore(srv.FS)
+		if err != nil {
+			slogger.Warn("NativeForks is only supported on macOS; resource forks will be kept in sidecar files", "err", err)
+		} else {
+			srv.FS = fileStore
+		}
+	}
+
 	srv.Agreement, err = mobius.NewAgreement(*configDir, "\r")
 	if err != nil {
 		slogger.Error(fmt.Sprintf("Error loading agreement: %v", err))
@@ -116,13 +319,121 @@ func main() {
 	}
 
 	bannerPath := filepath.Join(*configDir, config.BannerFile)
-	srv.Banner, err = os.ReadFile(bannerPath)
+	banner, err := os.ReadFile(bannerPath)
 	if err != nil {
-		slogger.Error(fmt.Sprintf("Error loading accounts: %v", err))
+		slogger.Error(fmt.Sprintf("Error loading banner: %v", err))
 		os.Exit(1)
 	}
+	srv.SetBanner(banner)
+
+	reloadBanner := func() error {
+		banner, err := os.ReadFile(bannerPath)
+		if err != nil {
+			return err
+		}
+		srv.SetBanner(banner)
+
+		return nil
+	}
+
+	// reloadAccounts reloads the account files, if accounts are stored in them, and updates the sessions of connected
+	// users whose accounts changed.
+	reloadAccounts := func() error {
+		if yamlAccounts, ok := srv.AccountManager.(*mobius.YAMLAccountManager); ok {
+			if err := yamlAccounts.Reload(); err != nil {
+				return err
+			}
+		}
+		srv.AccountsChanged()
+
+		return nil
+	}
+
+	if config.EnableThumbnails {
+		srv.Thumbnailer = hotline.NewThumbnailer(srv.FS, config.ThumbnailSize, slogger)
+	}
+
+	srv.Messages, err = mobius.LoadMessageCatalog(*configDir, config.Locale)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading messages: %v", err))
+		os.Exit(1)
+	}
+
+	srv.Locales, err = mobius.LoadMessageCatalogs(*configDir)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading messages: %v", err))
+		os.Exit(1)
+	}
+
+	bandwidthPath := path.Join(*configDir, "Bandwidth.yaml")
+	bandwidthUsage, err := mobius.LoadBandwidthUsage(bandwidthPath)
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error loading bandwidth usage: %v", err))
+		os.Exit(1)
+	}
+	srv.Bandwidth = hotline.NewBandwidthMeter(bandwidthUsage)
+
+	saveBandwidth := func() {
+		if err := mobius.SaveBandwidthUsage(bandwidthPath, srv.Bandwidth.Usage()); err != nil {
+			slogger.Error("Error saving bandwidth usage", "err", err)
+		}
+	}
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			saveBandwidth()
+		}
+	}()
+
+	if config.DigestNewsCategory != "" {
+		srv.Digest = hotline.NewActivityDigest(srv.Stats)
+	}
+
+	if config.DiskSpace.WebhookURL != "" {
+		srv.OnLowDiskSpace(mobius.LowDiskSpaceWebhook(config.DiskSpace.WebhookURL, config.FileRoot, slogger))
+	}
+
+	if config.UsageReports.Enabled() {
+		usagePath := path.Join(*configDir, "Usage.yaml")
+		usage, err := mobius.LoadWeeklyUsage(usagePath)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error loading usage: %v", err))
+			os.Exit(1)
+		}
+		srv.Usage = hotline.NewUsageMeter(usage)
+
+		go mobius.RunUsageReports(ctx, srv, usagePath)
+	}
+
+	if config.MaxTransferRateKBps > 0 {
+		srv.TransferSched = hotline.NewTransferScheduler(config.MaxTransferRateKBps*1024, config.PriorityTransferWeight)
+	}
+
+	if config.EnableDedup {
+		srv.BlobStore, err = hotline.NewBlobStore(config.BlobDir)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error loading blob store: %v", err))
+			os.Exit(1)
+		}
+	}
+
+	if config.NewsArticles.Attachments.Enabled {
+		srv.NewsAttachments, err = hotline.NewNewsAttachmentStore(config.NewsAttachmentsDir)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error loading news attachments: %v", err))
+			os.Exit(1)
+		}
+	}
 
 	reloadFunc := func() {
+		if srv.BlobStore != nil {
+			if n, err := srv.BlobStore.Prune(); err != nil {
+				slogger.Error("Error pruning blob store", "err", err)
+			} else {
+				slogger.Info("Pruned unreferenced blobs", "count", n)
+			}
+		}
+
 		if err := srv.MessageBoard.(*mobius.FlatNews).Reload(); err != nil {
 			slogger.Error("Error reloading news", "err", err)
 		}
@@ -131,19 +442,86 @@ func main() {
 			slogger.Error("Error reloading ban list", "err", err)
 		}
 
-		if err := srv.ThreadedNewsMgr.(*mobius.ThreadedNewsYAML).Load(); err != nil {
-			slogger.Error("Error reloading threaded news list", "err", err)
+		if err := srv.FileReportMgr.(*mobius.FileReportsYAML).Load(); err != nil {
+			slogger.Error("Error reloading file reports", "err", err)
+		}
+
+		if news, ok := srv.ThreadedNewsMgr.(*mobius.ThreadedNewsYAML); ok {
+			if err := news.Load(); err != nil {
+				slogger.Error("Error reloading threaded news list", "err", err)
+			}
 		}
 
 		if err := srv.Agreement.(*mobius.Agreement).Reload(); err != nil {
 			slogger.Error(fmt.Sprintf("Error reloading agreement: %v", err))
 			os.Exit(1)
 		}
+
+		if err := reloadAccounts(); err != nil {
+			slogger.Error("Error reloading accounts", "err", err)
+		}
+
+		if err := srv.Events.(*mobius.EventsYAML).Load(); err != nil {
+			slogger.Error("Error reloading events", "err", err)
+		}
+
+		if err := reloadBanner(); err != nil {
+			slogger.Error("Error reloading banner", "err", err)
+		}
 	}
 
-	if *apiAddr != "" {
-		sh := mobius.NewAPIServer(srv, reloadFunc, slogger)
-		go sh.Serve(*apiAddr)
+	if config.WatchConfigFiles {
+		cw, err := mobius.NewConfigWatcher(slogger)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error starting config file watcher: %v", err))
+			os.Exit(1)
+		}
+
+		watches := []struct {
+			path   string
+			reload func() error
+		}{
+			{path.Join(*configDir, "Banlist.yaml"), srv.BanList.(*mobius.BanFile).Load},
+			{path.Join(*configDir, "Agreement.txt"), srv.Agreement.(*mobius.Agreement).Reload},
+			{path.Join(*configDir, "Events.yaml"), srv.Events.(*mobius.EventsYAML).Load},
+			{bannerPath, reloadBanner},
+		}
+		for _, w := range watches {
+			if err := cw.Watch(w.path, w.reload); err != nil {
+				slogger.Error(fmt.Sprintf("Error watching config file: %v", err))
+				os.Exit(1)
+			}
+		}
+		if _, ok := srv.AccountManager.(*mobius.YAMLAccountManager); ok {
+			if err := cw.WatchDir(filepath.Join(*configDir, "Users"), reloadAccounts); err != nil {
+				slogger.Error(fmt.Sprintf("Error watching config file: %v", err))
+				os.Exit(1)
+			}
+		}
+
+		go cw.Run(ctx)
+	}
+
+	if config.WatchFileRoot {
+		frw, err := mobius.NewFileRootWatcher(config.FileRoot, srv.FileIndex, slogger)
+		if err != nil {
+			slogger.Error(fmt.Sprintf("Error starting file root watcher: %v", err))
+			os.Exit(1)
+		}
+		go frw.Run(ctx)
+	}
+
+	if apiLn != nil {
+		sh := mobius.NewAPIServer(srv, path.Join(*configDir, "config.yaml"), reloadFunc, slogger)
+		go sh.Serve(apiLn)
+	}
+
+	if nntpLn != nil {
+		go func() {
+			if err := mobius.NewNNTPServer(srv, slogger).Serve(ctx, nntpLn); err != nil {
+				slogger.Error("NNTP server stopped", "err", err)
+			}
+		}()
 	}
 
 	go func() {
@@ -155,6 +533,10 @@ func main() {
 
 				reloadFunc()
 			default:
+				saveBandwidth()
+				if err := srv.IncompleteUploadMgr.(*mobius.IncompleteUploadsYAML).Flush(); err != nil {
+					slogger.Error("Error saving incomplete uploads", "err", err)
+				}
 				signal.Stop(sigChan)
 				cancel()
 				os.Exit(0)
@@ -163,12 +545,12 @@ func main() {
 		}
 	}()
 
-	slogger.Info("Hotline server started", "version", version, "config", *configDir)
+	slogger.Info("Hotline server started", "version", build.Version, "commit", build.Commit, "built", build.Date, "config", *configDir)
 
 	// Assign functions to handle specific Hotline transaction types
 	mobius.RegisterHandlers(srv)
 
-	if srv.Config.EnableBonjour {
+	if srv.Config.EnableBonjour && !srv.Config.Privacy.Unlisted {
 		s, err := bonjour.Register(srv.Config.Name, "_hotline._tcp", "", *basePort, []string{"txtv=1", "app=hotline"}, nil)
 		if err != nil {
 			slogger.Error("Error registering Hotline server with Bonjour", "err", err)
@@ -177,7 +559,47 @@ func main() {
 	}
 
 	// Serve Hotline requests until program exit
-	log.Fatal(srv.ListenAndServe(ctx))
+	log.Fatal(srv.ServeListeners(ctx, hotlineLn, fileTransferLn))
+}
+
+// isTerminal returns true if f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// flagSet returns true if the named flag was set on the command line.
+func flagSet(name string) bool {
+	var set bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// runSetup prompts for the basic server settings and writes them to the newly initialized config dir.
+func runSetup(configDir string) error {
+	answers, err := mobius.PromptSetup(os.Stdin, os.Stdout, mobius.SetupAnswers{
+		Name:        "My Hotline server",
+		Description: "A default configured Hotline server running Mobius",
+		AdminLogin:  "admin",
+		Port:        5500,
+		FileRoot:    "Files",
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := mobius.ApplySetup(configDir, answers); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nSetup complete.  Log in to the admin account with:\n\n  Login:    %s\n  Password: %s\n\n", answers.AdminLogin, answers.AdminPassword)
+	fmt.Println("Save the password now; it is not stored anywhere in plain text.")
+
+	return nil
 }
 
 func configSearchPaths() string {
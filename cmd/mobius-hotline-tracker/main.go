@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/mobius"
+	"github.com/jhalter/mobius/internal/version"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, os.Interrupt)
+
+	netInterface := flag.String("interface", "", "IP addr of interface to listen on.  Defaults to all interfaces.")
+	listPort := flag.Int("bind", 5498, "TCP port that clients connect to for the server list")
+	regPort := flag.Int("reg-port", 5499, "UDP port that servers send registrations to")
+	passwords := flag.String("passwords", "", "Comma separated list of passwords that servers must register with.  Defaults to allowing any server.")
+	ttl := flag.Duration("ttl", hotline.DefaultTrackerTTL, "How long a server stays listed without renewing its registration")
+	maxServers := flag.Int("max-servers", hotline.DefaultTrackerMaxServers, "Max number of listed servers")
+	maxServersPerIP := flag.Int("max-servers-per-ip", hotline.DefaultTrackerMaxServersPerIP, "Max number of servers listed from one IP address")
+	printVersion := flag.Bool("version", false, "Print version and exit")
+	logLevel := flag.String("log-level", "info", "Log level")
+	logFile := flag.String("log-file", "", "Path to log file")
+
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Printf("mobius-hotline-tracker %s\n", version.Get())
+		os.Exit(0)
+	}
+
+	slogger := mobius.NewLogger(logLevel, logFile)
+	build := version.Get()
+
+	tracker := hotline.NewTrackerServer(slogger)
+	tracker.TTL = *ttl
+	tracker.MaxServers = *maxServers
+	tracker.MaxServersPerIP = *maxServersPerIP
+	if *passwords != "" {
+		tracker.Passwords = strings.Split(*passwords, ",")
+	}
+
+	pc, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", *netInterface, *regPort))
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error listening for registrations: %v", err))
+		os.Exit(1)
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", *netInterface, *listPort))
+	if err != nil {
+		slogger.Error(fmt.Sprintf("Error listening for clients: %v", err))
+		os.Exit(1)
+	}
+
+	slogger.Info("Hotline tracker started", "version", build.Version, "commit", build.Commit, "built", build.Date, "listAddr", ln.Addr(), "regAddr", pc.LocalAddr())
+
+	go func() {
+		if err := tracker.ServeRegistrations(ctx, pc); err != nil {
+			slogger.Error(fmt.Sprintf("Error reading registrations: %v", err))
+			os.Exit(1)
+		}
+	}()
+
+	go func() {
+		sig := <-sigChan
+		slogger.Info("Stopping tracker", "signal", sig.String())
+		cancel()
+	}()
+
+	if err := tracker.ServeListings(ctx, ln); err != nil {
+		slogger.Error(fmt.Sprintf("Error serving server list: %v", err))
+		os.Exit(1)
+	}
+}
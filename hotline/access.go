@@ -43,6 +43,9 @@ const (
 	AccessUploadFolder     = 38 // File System Maintenance: Can Upload Folders
 	AccessDownloadFolder   = 39 // File System Maintenance: Can Download Folders
 	AccessSendPrivMsg      = 40 // Messaging: Can Send Messages (Note: 1.9 protocol doc incorrectly says this is bit 19)
+
+	// Mobius extensions use the high bits of the bitmap, which are unused by the Hotline protocol.
+	AccessViewHiddenFiles = 56 // File System Maintenance: Can View Hidden Files
 )
 
 type AccessBitmap [8]byte
@@ -193,6 +196,9 @@ func (bits *AccessBitmap) UnmarshalYAML(unmarshal func(interface{}) error) error
 		if f, ok := v["SendPrivMsg"].(bool); ok && f {
 			bits.Set(AccessSendPrivMsg)
 		}
+		if f, ok := v["ViewHiddenFiles"].(bool); ok && f {
+			bits.Set(AccessViewHiddenFiles)
+		}
 	}
 
 	return nil
@@ -240,6 +246,7 @@ type accessFlags struct {
 	NewsCreateFldr       bool `yaml:"NewsCreateFldr"`
 	NewsDeleteFldr       bool `yaml:"NewsDeleteFldr"`
 	SendPrivMsg          bool `yaml:"SendPrivMsg"`
+	ViewHiddenFiles      bool `yaml:"ViewHiddenFiles,omitempty"`
 }
 
 func (bits AccessBitmap) MarshalYAML() (interface{}, error) {
@@ -284,5 +291,6 @@ func (bits AccessBitmap) MarshalYAML() (interface{}, error) {
 		NewsCreateFldr:       bits.IsSet(AccessNewsCreateFldr),
 		NewsDeleteFldr:       bits.IsSet(AccessNewsDeleteFldr),
 		SendPrivMsg:          bits.IsSet(AccessSendPrivMsg),
+		ViewHiddenFiles:      bits.IsSet(AccessViewHiddenFiles),
 	}, nil
 }
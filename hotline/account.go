@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"io"
+	"net/netip"
 	"slices"
+	"strings"
 )
 
 const GuestAccount = "guest" // default account used when no login is provided for a connection
@@ -17,9 +19,24 @@ type Account struct {
 	Access   AccessBitmap `yaml:"Access"`
 	FileRoot string       `yaml:"FileRoot"`
 
+	PriorityTransfers bool              `yaml:"PriorityTransfers,omitempty"` // Give file transfers a larger share of bandwidth, e.g. for donor accounts
+	LoginHours        *LoginWindow      `yaml:"LoginHours,omitempty"`        // Optional daily time range during which the account may be logged in
+	MaxSessions       int               `yaml:"MaxSessions,omitempty"`       // Maximum simultaneous connections; 0 for unlimited
+	BumpOldestSession bool              `yaml:"BumpOldestSession,omitempty"` // Disconnect the oldest session instead of rejecting logins over MaxSessions
+	AllowedAddresses  []string          `yaml:"AllowedAddresses,omitempty"`  // Optional IP addresses or CIDR ranges the account may log in from
+	Bookmarks         []string          `yaml:"Bookmarks,omitempty"`         // Bookmarked files and folders, relative to the account's file root
+	BlockChatInvites  bool              `yaml:"BlockChatInvites,omitempty"`  // Silently drop private chat invitations from non-admins
+	Preferences       map[string]string `yaml:"Preferences,omitempty"`       // User preferences applied on login, see PrefRefusePM etc.
+	Group             string            `yaml:"Group,omitempty"`             // User list group hinted to clients that support grouping, see UserListGroups
+	RateLimits        TransactionLimits `yaml:"RateLimits,omitempty"`        // Transaction rate limits that replace the server's for this account
+	MaxDownloadKBps   int               `yaml:"MaxDownloadKBps,omitempty"`   // Replaces the server's MaxDownloadKBps if set; -1 for unlimited
+	MaxUploadKBps     int               `yaml:"MaxUploadKBps,omitempty"`     // Replaces the server's MaxUploadKBps if set; -1 for unlimited
+
 	readOffset int // Internal offset to track read progress
 }
 
+// NewAccount returns an account with password, an obfuscated plaintext password, hashed with HashAndSalt.  Accounts
+// created by a server hash their password with Server.HashPassword instead.
 func NewAccount(login, name, password string, access AccessBitmap) *Account {
 	return &Account{
 		Login:    login,
@@ -29,6 +46,35 @@ func NewAccount(login, name, password string, access AccessBitmap) *Account {
 	}
 }
 
+// AddressAllowed returns true if the account may be used from ip.  Accounts without AllowedAddresses may be used from
+// any address.  Entries that are not a valid IP address or CIDR range never match.
+func (a *Account) AddressAllowed(ip string) bool {
+	if len(a.AllowedAddresses) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, allowed := range a.AllowedAddresses {
+		if strings.Contains(allowed, "/") {
+			if prefix, err := netip.ParsePrefix(allowed); err == nil && prefix.Contains(addr) {
+				return true
+			}
+			continue
+		}
+
+		if allowedAddr, err := netip.ParseAddr(allowed); err == nil && allowedAddr.Unmap() == addr {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Read implements io.Reader interface for Account
 func (a *Account) Read(p []byte) (int, error) {
 	fields := []Field{
@@ -37,7 +83,7 @@ func (a *Account) Read(p []byte) (int, error) {
 		NewField(FieldUserAccess, a.Access[:]),
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(a.Password), []byte("")) != nil {
+	if !CheckPassword(a.Password, []byte("")) {
 		fields = append(fields, NewField(FieldUserPassword, []byte("x")))
 	}
 
@@ -64,7 +110,8 @@ func (a *Account) Read(p []byte) (int, error) {
 	return n, nil
 }
 
-// HashAndSalt generates a password hash from a users obfuscated plaintext password
+// HashAndSalt generates a password hash from a users obfuscated plaintext password with bcrypt at its minimum cost.
+// Servers with a PasswordHasher use Server.HashPassword instead, and upgrade these hashes when users log in.
 func HashAndSalt(pwd []byte) string {
 	hash, _ := bcrypt.GenerateFromPassword(pwd, bcrypt.MinCost)
 
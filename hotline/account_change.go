@@ -0,0 +1,101 @@
+package hotline
+
+import (
+	"reflect"
+	"time"
+)
+
+// AccountChanged updates the sessions of users logged in with the account login after the account is changed, e.g.
+// by an admin in the account editor, through the HTTP API, or by editing the account file.  Sessions are given the
+// stored account so that changes take effect without reconnecting.  Users whose access changed are sent their new
+// access, and other users are shown their new name and flags.
+//
+// If the account can no longer be used to log in, e.g. because it was deleted or guests are no longer allowed, its
+// users are disconnected.
+func (s *Server) AccountChanged(login string) {
+	account := s.loginAccount(login)
+
+	for _, c := range s.ClientMgr.List() {
+		if c.login() != login {
+			continue
+		}
+
+		if account == nil {
+			c.Logger.Info("Disconnecting client whose account can no longer log in")
+			s.outbox <- NewTransaction(TranDisconnectMsg, c.ID, NewField(FieldData, []byte(s.T("Your account is no longer allowed to log in."))))
+			go func() {
+				time.Sleep(1 * time.Second)
+				c.Disconnect()
+			}()
+			continue
+		}
+
+		c.updateAccount(account)
+	}
+}
+
+// AccountsChanged calls AccountChanged for the account of each connected user, e.g. after the account files are
+// reloaded.
+func (s *Server) AccountsChanged() {
+	seen := make(map[string]bool)
+	for _, c := range s.ClientMgr.List() {
+		login := c.login()
+		if login == "" || seen[login] {
+			continue
+		}
+		seen[login] = true
+
+		s.AccountChanged(login)
+	}
+}
+
+// UpdateSessionAccounts applies update to the account of each session logged in with login, e.g. after the user
+// changed their own account preferences.  Unlike AccountChanged, the other sessions aren't notified.
+func (s *Server) UpdateSessionAccounts(login string, update func(account *Account)) {
+	for _, c := range s.ClientMgr.List() {
+		c.mu.Lock()
+		if c.Account != nil && c.Account.Login == login {
+			account := *c.Account
+			update(&account)
+			c.Account = &account
+		}
+		c.mu.Unlock()
+	}
+}
+
+// updateAccount replaces the account of the session with a copy of account.
+func (cc *ClientConn) updateAccount(account *Account) {
+	cc.mu.Lock()
+	if reflect.DeepEqual(cc.Account, account) {
+		cc.mu.Unlock()
+		return
+	}
+
+	updated := *account
+	accessChanged := cc.Account.Access != updated.Access
+	cc.Account = &updated
+
+	// Users without AccessAnyName always appear with the name of their account.
+	if !updated.Access.IsSet(AccessAnyName) {
+		cc.UserName = []byte(updated.Name)
+	}
+	cc.mu.Unlock()
+
+	cc.FlagsMU.Lock()
+	if cc.Authorize(AccessDisconUser) {
+		cc.Flags.Set(UserFlagAdmin, 1)
+	} else {
+		cc.Flags.Set(UserFlagAdmin, 0)
+	}
+	cc.FlagsMU.Unlock()
+
+	cc.Logger.Info("Account changed", "login", updated.Login)
+
+	if accessChanged {
+		cc.Server.outbox <- NewTransaction(TranUserAccess, cc.ID, NewField(FieldUserAccess, updated.Access[:]))
+	}
+
+	for _, c := range cc.Server.ClientMgr.List() {
+		cc.Server.outbox <- cc.NotifyChangeUser(c)
+	}
+}
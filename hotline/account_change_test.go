@@ -0,0 +1,125 @@
+package hotline
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAccountMgr map[string]Account
+
+func (m testAccountMgr) Create(account Account) error {
+	m[account.Login] = account
+	return nil
+}
+
+func (m testAccountMgr) Update(account Account, newLogin string) error {
+	delete(m, account.Login)
+	account.Login = newLogin
+	m[newLogin] = account
+	return nil
+}
+
+func (m testAccountMgr) Modify(login string, modify func(*Account)) error {
+	account := m[login]
+	modify(&account)
+	m[login] = account
+	return nil
+}
+
+func (m testAccountMgr) Get(login string) *Account {
+	account, ok := m[login]
+	if !ok {
+		return nil
+	}
+	return &account
+}
+
+func (m testAccountMgr) List() []Account {
+	var accounts []Account
+	for _, account := range m {
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+func (m testAccountMgr) Delete(login string) error {
+	delete(m, login)
+	return nil
+}
+
+func TestServer_AccountChanged(t *testing.T) {
+	var access AccessBitmap
+	access.Set(AccessDownloadFile)
+
+	accounts := testAccountMgr{"alice": {Login: "alice", Name: "Alice", Access: access}, "bob": {Login: "bob"}}
+	s := &Server{
+		AccountManager: accounts,
+		ClientMgr:      NewMemClientMgr(),
+		Logger:         NewTestLogger(),
+		outbox:         make(chan Transaction, 10),
+	}
+
+	alice := &ClientConn{ID: ClientID{0, 1}, Server: s, Account: accounts.Get("alice"), UserName: []byte("Alice"), Icon: []byte{0, 1}, Logger: NewTestLogger()}
+	bob := &ClientConn{ID: ClientID{0, 2}, Server: s, Account: accounts.Get("bob"), UserName: []byte("Bob"), Logger: NewTestLogger()}
+	s.ClientMgr.Add(alice)
+	s.ClientMgr.Add(bob)
+
+	// Nothing is sent for unchanged accounts.
+	s.AccountsChanged()
+	assert.Len(t, s.outbox, 0)
+
+	access.Set(AccessDisconUser)
+	accounts["alice"] = Account{Login: "alice", Name: "Alice Admin", Access: access}
+	s.AccountChanged("alice")
+
+	assert.Equal(t, access, alice.Account.Access)
+	assert.Equal(t, "Alice Admin", alice.Account.Name)
+	assert.Equal(t, []byte("Alice Admin"), alice.UserName, "users without AccessAnyName are shown with the account name")
+	assert.True(t, alice.Flags.IsSet(UserFlagAdmin))
+
+	// The changed account isn't shared with the account manager.
+	accounts["alice"] = Account{Login: "alice"}
+	assert.Equal(t, "Alice Admin", alice.Account.Name)
+
+	var got []Transaction
+	for len(s.outbox) > 0 {
+		got = append(got, <-s.outbox)
+	}
+	assert.Len(t, got, 3)
+	assert.Equal(t, TranUserAccess, got[0].Type)
+	assert.Equal(t, alice.ID, got[0].ClientID)
+	assert.Equal(t, access[:], got[0].GetField(FieldUserAccess).Data)
+	for _, tran := range got[1:] {
+		assert.Equal(t, TranNotifyChangeUser, tran.Type)
+		assert.Equal(t, alice.ID[:], tran.GetField(FieldUserID).Data)
+		assert.Equal(t, []byte("Alice Admin"), tran.GetField(FieldUserName).Data)
+	}
+	assert.ElementsMatch(t, []ClientID{alice.ID, bob.ID}, []ClientID{got[1].ClientID, got[2].ClientID})
+}
+
+func TestServer_AccountChanged_guestsNotAllowed(t *testing.T) {
+	s := &Server{
+		Config:         Config{Guests: &GuestPolicy{Allow: true}},
+		AccountManager: testAccountMgr{},
+		ClientMgr:      NewMemClientMgr(),
+		Logger:         NewTestLogger(),
+		outbox:         make(chan Transaction, 10),
+	}
+
+	serverConn, clientConn := net.Pipe()
+	t.Cleanup(func() { _ = clientConn.Close() })
+	guest := &ClientConn{ID: ClientID{0, 1}, Server: s, Connection: serverConn, Account: s.loginAccount(GuestAccount), Logger: NewTestLogger()}
+	s.ClientMgr.Add(guest)
+
+	// Guests are disconnected once they are no longer allowed.
+	s.Config.Guests.Allow = false
+	s.AccountChanged(GuestAccount)
+
+	msg := <-s.outbox
+	assert.Equal(t, TranDisconnectMsg, msg.Type)
+	assert.Equal(t, guest.ID, msg.ClientID)
+	assert.Eventually(t, func() bool { return s.ClientMgr.Get(guest.ID) == nil }, 2*time.Second, 10*time.Millisecond)
+}
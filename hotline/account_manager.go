@@ -3,6 +3,10 @@ package hotline
 type AccountManager interface {
 	Create(account Account) error
 	Update(account Account, newLogin string) error
+
+	// Modify calls modify with the current account for login and saves the result, without letting other changes to
+	// the account happen in between.  modify must not change the login.
+	Modify(login string, modify func(*Account)) error
 	Get(login string) *Account
 	List() []Account
 	Delete(login string) error
@@ -0,0 +1,28 @@
+package hotline
+
+// AccountRenameHook updates stored references to an account's login, such as the reporter of a file report, after
+// the account is renamed.
+type AccountRenameHook func(oldLogin, newLogin string) error
+
+// OnAccountRename registers hook to be called whenever an account is renamed.
+func (s *Server) OnAccountRename(hook AccountRenameHook) {
+	s.renameHooks = append(s.renameHooks, hook)
+}
+
+// AccountRenamed is called after an account is renamed.  It updates the sessions of users logged in with the account
+// and runs the registered rename hooks.  Hook errors are logged and do not stop the remaining hooks from running.
+func (s *Server) AccountRenamed(oldLogin, newLogin string) {
+	if oldLogin == newLogin {
+		return
+	}
+
+	s.UpdateSessionAccounts(oldLogin, func(account *Account) {
+		account.Login = newLogin
+	})
+
+	for _, hook := range s.renameHooks {
+		if err := hook(oldLogin, newLogin); err != nil {
+			s.Logger.Error("Error updating references to renamed account", "oldLogin", oldLogin, "newLogin", newLogin, "err", err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package hotline
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServer_AccountRenamed(t *testing.T) {
+	alice := &ClientConn{Account: &Account{Login: "alice"}}
+	bob := &ClientConn{Account: &Account{Login: "bob"}}
+
+	mgr := &MockClientMgr{}
+	mgr.On("List").Return([]*ClientConn{alice, bob})
+
+	s := &Server{ClientMgr: mgr, Logger: NewTestLogger()}
+
+	var renames []string
+	s.OnAccountRename(func(oldLogin, newLogin string) error {
+		return errors.New("failed")
+	})
+	s.OnAccountRename(func(oldLogin, newLogin string) error {
+		renames = append(renames, oldLogin+" -> "+newLogin)
+		return nil
+	})
+
+	s.AccountRenamed("alice", "alicia")
+
+	assert.Equal(t, "alicia", alice.Account.Login)
+	assert.Equal(t, "bob", bob.Account.Login)
+	assert.Equal(t, []string{"alice -> alicia"}, renames, "hooks run even if an earlier hook fails")
+}
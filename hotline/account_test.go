@@ -0,0 +1,31 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAccount_AddressAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{name: "no restriction", allowed: nil, ip: "203.0.113.9", want: true},
+		{name: "exact match", allowed: []string{"203.0.113.9"}, ip: "203.0.113.9", want: true},
+		{name: "exact mismatch", allowed: []string{"203.0.113.9"}, ip: "203.0.113.10", want: false},
+		{name: "within CIDR", allowed: []string{"10.0.0.1", "192.168.1.0/24"}, ip: "192.168.1.77", want: true},
+		{name: "outside CIDR", allowed: []string{"192.168.1.0/24"}, ip: "192.168.2.1", want: false},
+		{name: "IPv6 CIDR", allowed: []string{"2001:db8::/32"}, ip: "2001:db8::1", want: true},
+		{name: "IPv4-mapped IPv6", allowed: []string{"192.168.1.0/24"}, ip: "::ffff:192.168.1.5", want: true},
+		{name: "invalid entry", allowed: []string{"not-an-ip"}, ip: "192.168.1.5", want: false},
+		{name: "invalid client address", allowed: []string{"192.168.1.0/24"}, ip: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Account{AllowedAddresses: tt.allowed}
+			assert.Equal(t, tt.want, a.AddressAllowed(tt.ip))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package hotline
+
+// anonymousAccess is the read-only access granted to guest sessions when AnonymousGuest is enabled: browsing and
+// downloading files, reading news, and reading public chat.
+var anonymousAccess = func() AccessBitmap {
+	var bits AccessBitmap
+	for _, i := range []int{AccessDownloadFile, AccessDownloadFolder, AccessNewsReadArt, AccessReadChat} {
+		bits.Set(i)
+	}
+	return bits
+}()
+
+// IsAnonymousGuest returns true if sessions logged in as login are restricted to read-only anonymous access, either
+// by AnonymousGuest or the ReadOnly setting of the Guests policy.
+func (s *Server) IsAnonymousGuest(login string) bool {
+	if login != GuestAccount {
+		return false
+	}
+
+	return s.Config.AnonymousGuest || s.Config.Guests != nil && s.Config.Guests.ReadOnly
+}
+
+// sessionAccount returns the account to use for a new session.  When AnonymousGuest is enabled, guest sessions get a
+// copy of the guest account with read-only access, regardless of the access set in the account file.  Guests in
+// ReadOnly mode of the Guests policy keep only the read-only access that the policy grants.
+func (s *Server) sessionAccount(account *Account) *Account {
+	if account == nil || !s.IsAnonymousGuest(account.Login) {
+		return account
+	}
+
+	anon := *account
+	if s.Config.AnonymousGuest {
+		anon.Access = anonymousAccess
+	} else {
+		for i := range anon.Access {
+			anon.Access[i] &= anonymousAccess[i]
+		}
+	}
+
+	return &anon
+}
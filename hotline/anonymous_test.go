@@ -0,0 +1,53 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServer_sessionAccount(t *testing.T) {
+	fullAccess := AccessBitmap{255, 255, 255, 255, 255, 255, 255, 255}
+
+	tests := []struct {
+		name           string
+		anonymousGuest bool
+		account        *Account
+		wantAccess     AccessBitmap
+	}{
+		{
+			name:           "when AnonymousGuest is enabled and the account is guest",
+			anonymousGuest: true,
+			account:        &Account{Login: GuestAccount, Access: fullAccess},
+			wantAccess:     anonymousAccess,
+		},
+		{
+			name:           "when AnonymousGuest is enabled and the account is not guest",
+			anonymousGuest: true,
+			account:        &Account{Login: "admin", Access: fullAccess},
+			wantAccess:     fullAccess,
+		},
+		{
+			name:           "when AnonymousGuest is disabled",
+			anonymousGuest: false,
+			account:        &Account{Login: GuestAccount, Access: fullAccess},
+			wantAccess:     fullAccess,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Config: Config{AnonymousGuest: tt.anonymousGuest}}
+
+			got := s.sessionAccount(tt.account)
+			assert.Equal(t, tt.wantAccess, got.Access)
+			assert.Equal(t, fullAccess, tt.account.Access, "the stored account should not be modified")
+		})
+	}
+}
+
+func TestAnonymousAccess(t *testing.T) {
+	assert.True(t, anonymousAccess.IsSet(AccessDownloadFile))
+	assert.True(t, anonymousAccess.IsSet(AccessReadChat))
+	assert.False(t, anonymousAccess.IsSet(AccessUploadFile))
+	assert.False(t, anonymousAccess.IsSet(AccessSendChat))
+	assert.False(t, anonymousAccess.IsSet(AccessNewsPostArt))
+}
@@ -0,0 +1,136 @@
+package hotline
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// AuditTypeLogin is the audit entry type of login attempts, which are handled before the transaction loop.
+const AuditTypeLogin = "Login"
+
+// AuditEntry is a record of a transaction handled by the server, for reconstructing what happened during an incident.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`               // Transaction type name, e.g. "Download file"
+	Login    string    `json:"login"`              // Account login of the user
+	UserName string    `json:"userName,omitempty"` // Display name of the user
+	IP       string    `json:"ip"`
+	Paths    []string  `json:"paths,omitempty"` // File and news paths the transaction refers to, e.g. the source and destination of a move
+	Error    string    `json:"error,omitempty"` // Error sent to the client if the transaction failed
+}
+
+// AuditSink records audit entries.  WriteAudit is called from the goroutine that handled the transaction and should
+// return quickly.
+type AuditSink interface {
+	WriteAudit(entry AuditEntry)
+}
+
+// auditEnabled returns true if transactions of type name are recorded in the audit log.
+func (s *Server) auditEnabled(name string) bool {
+	if s.AuditSink == nil {
+		return false
+	}
+
+	match := func(n string) bool { return strings.EqualFold(n, name) }
+
+	cfg := s.Config.AuditLog
+	if len(cfg.Transactions) > 0 && !slices.ContainsFunc(cfg.Transactions, match) {
+		return false
+	}
+
+	return !slices.ContainsFunc(cfg.Exclude, match)
+}
+
+// auditTransaction records a transaction handled for cc, and the replies sent to it, in the audit log.
+func (cc *ClientConn) auditTransaction(t *Transaction, replies []Transaction) {
+	name := tranTypeNames[t.Type]
+	if !cc.Server.auditEnabled(name) {
+		return
+	}
+
+	entry := cc.auditEntry(name)
+	entry.Paths = auditPaths(t)
+
+	for _, reply := range replies {
+		if reply.ClientID == cc.ID && reply.IsReply == 1 && reply.ErrorCode != [4]byte{} {
+			entry.Error = decodeMacRoman(reply.GetField(FieldError).Data)
+			break
+		}
+	}
+
+	cc.Server.AuditSink.WriteAudit(entry)
+}
+
+// auditLogin records a login attempt for login in the audit log, with errMsg set if the login was rejected.
+func (cc *ClientConn) auditLogin(login, errMsg string) {
+	if !cc.Server.auditEnabled(AuditTypeLogin) {
+		return
+	}
+
+	entry := cc.auditEntry(AuditTypeLogin)
+	entry.Login = login
+	entry.Error = errMsg
+
+	cc.Server.AuditSink.WriteAudit(entry)
+}
+
+func (cc *ClientConn) auditEntry(name string) AuditEntry {
+	entry := AuditEntry{
+		Time:     time.Now(),
+		Type:     name,
+		UserName: decodeMacRoman(cc.UserName),
+		IP:       cc.RemoteAddr,
+	}
+	if cc.Account != nil {
+		entry.Login = cc.Account.Login
+	}
+
+	return entry
+}
+
+// auditPaths returns the file and news paths in the fields of t.
+func auditPaths(t *Transaction) []string {
+	var paths []string
+
+	filePath := decodeFilePath(t.GetField(FieldFilePath).Data)
+	if name := t.GetField(FieldFileName).Data; name != nil {
+		paths = append(paths, path.Join(filePath, decodeMacRoman(name)))
+	} else if filePath != "" {
+		paths = append(paths, filePath)
+	}
+
+	if name := t.GetField(FieldFileNewName).Data; name != nil {
+		paths = append(paths, path.Join(filePath, decodeMacRoman(name)))
+	}
+	if newPath := t.GetField(FieldFileNewPath).Data; newPath != nil {
+		paths = append(paths, decodeFilePath(newPath))
+	}
+	if newsPath := decodeFilePath(t.GetField(FieldNewsPath).Data); newsPath != "" {
+		paths = append(paths, newsPath)
+	}
+
+	return paths
+}
+
+// decodeFilePath returns the items of an encoded file path joined with "/", or "" if b is empty or invalid.
+func decodeFilePath(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+
+	var fp FilePath
+	if _, err := fp.Write(b); err != nil {
+		return ""
+	}
+
+	return decodeMacRoman([]byte(fp.String()))
+}
+
+func decodeMacRoman(b []byte) string {
+	s, _ := charmap.Macintosh.NewDecoder().String(string(b))
+	return s
+}
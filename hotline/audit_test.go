@@ -0,0 +1,114 @@
+package hotline
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAuditSink struct {
+	entries []AuditEntry
+}
+
+func (s *testAuditSink) WriteAudit(entry AuditEntry) {
+	s.entries = append(s.entries, entry)
+}
+
+func TestClientConn_handleTransaction_audit(t *testing.T) {
+	sink := &testAuditSink{}
+	s := &Server{
+		AuditSink: sink,
+		Config:    Config{AuditLog: AuditLog{Exclude: []string{"get file list"}}},
+		outbox:    make(chan Transaction, 10),
+		handlers: map[TranType]HandlerFunc{
+			TranMoveFile: func(cc *ClientConn, t *Transaction) []Transaction {
+				return cc.NewErrReply(t, "You are not allowed to move files.")
+			},
+			TranGetFileNameList: func(cc *ClientConn, t *Transaction) []Transaction { return nil },
+			TranKeepAlive:       func(cc *ClientConn, t *Transaction) []Transaction { return nil },
+		},
+	}
+	cc := &ClientConn{
+		ID:         ClientID{0, 3},
+		RemoteAddr: "192.0.2.1:50000",
+		UserName:   []byte("Tom"),
+		Account:    &Account{Login: "tom"},
+		Server:     s,
+		Logger:     slog.Default(),
+	}
+
+	cc.handleTransaction(NewTransaction(TranMoveFile, ClientID{},
+		NewField(FieldFileName, []byte("Caf\x8e.txt")),
+		NewField(FieldFilePath, EncodeFilePath("Uploads")),
+		NewField(FieldFileNewPath, EncodeFilePath("Archive/2024")),
+	))
+	cc.handleTransaction(NewTransaction(TranGetFileNameList, ClientID{}))
+	cc.handleTransaction(NewTransaction(TranKeepAlive, ClientID{}))
+
+	assert.Len(t, sink.entries, 1)
+	entry := sink.entries[0]
+	assert.Equal(t, "Move file", entry.Type)
+	assert.Equal(t, "tom", entry.Login)
+	assert.Equal(t, "Tom", entry.UserName)
+	assert.Equal(t, "192.0.2.1:50000", entry.IP)
+	assert.Equal(t, []string{"Uploads/Café.txt", "Archive/2024"}, entry.Paths)
+	assert.Equal(t, "You are not allowed to move files.", entry.Error)
+	assert.False(t, entry.Time.IsZero())
+}
+
+func TestServer_auditEnabled(t *testing.T) {
+	s := &Server{}
+	assert.False(t, s.auditEnabled("Download file"))
+
+	s.AuditSink = &testAuditSink{}
+	assert.True(t, s.auditEnabled("Download file"))
+
+	s.Config.AuditLog = AuditLog{Transactions: []string{"Download file", "Login"}, Exclude: []string{"login"}}
+	assert.True(t, s.auditEnabled("Download file"))
+	assert.False(t, s.auditEnabled("Upload file"))
+	assert.False(t, s.auditEnabled(AuditTypeLogin))
+}
+
+func TestAuditPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []Field
+		want   []string
+	}{
+		{
+			name: "no paths",
+			want: nil,
+		},
+		{
+			name:   "file in root",
+			fields: []Field{NewField(FieldFileName, []byte("readme.txt"))},
+			want:   []string{"readme.txt"},
+		},
+		{
+			name:   "folder",
+			fields: []Field{NewField(FieldFilePath, EncodeFilePath("Games/Classic"))},
+			want:   []string{"Games/Classic"},
+		},
+		{
+			name: "rename",
+			fields: []Field{
+				NewField(FieldFileName, []byte("old.txt")),
+				NewField(FieldFilePath, EncodeFilePath("Docs")),
+				NewField(FieldFileNewName, []byte("new.txt")),
+			},
+			want: []string{"Docs/old.txt", "Docs/new.txt"},
+		},
+		{
+			name:   "news category",
+			fields: []Field{NewField(FieldNewsPath, EncodeFilePath("General/Announcements"))},
+			want:   []string{"General/Announcements"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tran := NewTransaction(TranGetFileInfo, ClientID{}, tt.fields...)
+			assert.Equal(t, tt.want, auditPaths(&tran))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package hotline
+
+import "unicode/utf8"
+
+// MaxAwayReasonLen is the longest away reason kept, in bytes.  Longer reasons are truncated.
+const MaxAwayReasonLen = 128
+
+// SetAway marks the user as away with a reason that is shown in their user info, or clears their away status if reason
+// is empty.  Unlike the idle flag set after a period of inactivity, away status is kept until the user clears it.
+func (cc *ClientConn) SetAway(reason string) {
+	for len(reason) > MaxAwayReasonLen {
+		_, size := utf8.DecodeLastRuneInString(reason)
+		reason = reason[:len(reason)-size]
+	}
+
+	cc.mu.Lock()
+	cc.awayReason = reason
+	cc.mu.Unlock()
+
+	cc.setIdle(reason != "")
+}
+
+// AwayReason returns the reason the user set for being away, or an empty string if they are not away.
+func (cc *ClientConn) AwayReason() string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	return cc.awayReason
+}
+
+// IsIdle returns true if the user is shown as idle or away in user lists.
+func (cc *ClientConn) IsIdle() bool {
+	cc.FlagsMU.Lock()
+	defer cc.FlagsMU.Unlock()
+
+	return cc.Flags.IsSet(UserFlagAway)
+}
@@ -0,0 +1,26 @@
+package hotline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConn_SetAway(t *testing.T) {
+	s := &Server{ClientMgr: NewMemClientMgr(), outbox: make(chan Transaction, 10)}
+	cc := &ClientConn{Server: s}
+	s.ClientMgr.Add(cc)
+
+	cc.SetAway(strings.Repeat("é", MaxAwayReasonLen))
+	assert.Equal(t, strings.Repeat("é", MaxAwayReasonLen/2), cc.AwayReason())
+	assert.True(t, cc.IsIdle())
+
+	// Away users stay idle until they clear their away status.
+	cc.setIdle(false)
+	assert.True(t, cc.IsIdle())
+
+	cc.SetAway("")
+	assert.False(t, cc.IsIdle())
+	assert.Len(t, s.outbox, 2)
+}
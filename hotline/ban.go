@@ -1,11 +1,182 @@
 package hotline
 
-import "time"
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 // BanDuration is the length of time for temporary bans.
 const BanDuration = 30 * time.Minute
 
+// defaultAutoBanWindow is the period in which failed logins are counted towards an automatic ban by default.
+const defaultAutoBanWindow = 10 * time.Minute
+
+// autoBanReason is the reason recorded for automatic bans.
+const autoBanReason = "Too many failed login attempts"
+
+// Ban is an entry in the ban list.
+type Ban struct {
+	Until  *time.Time `yaml:"Until"`            // Time the ban expires; nil for a permanent ban
+	Reason string     `yaml:"Reason,omitempty"` // Optional reason shown to the banned user
+}
+
+// Expired returns true if the ban is temporary and has ended by now.
+func (b Ban) Expired(now time.Time) bool {
+	return b.Until != nil && !now.Before(*b.Until)
+}
+
+// UnmarshalYAML accepts a ban as an expiration time or null, the format used before bans had reasons, as well as a
+// mapping of the Ban fields.
+func (b *Ban) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		var until *time.Time
+		if err := value.Decode(&until); err != nil {
+			return err
+		}
+		*b = Ban{Until: until}
+
+		return nil
+	}
+
+	type plain Ban
+	var v plain
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+	*b = Ban(v)
+
+	return nil
+}
+
+// MarshalYAML writes bans without a reason as just the expiration time, so that ban lists stay readable by older
+// versions.
+func (b Ban) MarshalYAML() (interface{}, error) {
+	if b.Reason == "" {
+		return b.Until, nil
+	}
+
+	type plain Ban
+	return plain(b), nil
+}
+
+// AddrIP returns the IP address from a "host:port" remote address, which for IPv6 addresses is of the form
+// "[host]:port".
+func AddrIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
 type BanMgr interface {
-	Add(ip string, until *time.Time) error
-	IsBanned(ip string) (bool, *time.Time)
+	Add(ip string, ban Ban) error
+
+	// IsBanned returns true and the ban if ip is banned, either by its address or by a ban list entry for a range or
+	// hostname pattern that matches it.  Expired bans are ignored.
+	IsBanned(ip string) (bool, Ban)
+}
+
+// AutoBan configures automatic temporary bans of IP addresses that repeatedly fail to log in or break the protocol.
+type AutoBan struct {
+	FailedLogins       int           `yaml:"FailedLogins"`       // Failed logins from an IP address within Window that trigger a ban; 0 to disable
+	ProtocolViolations int           `yaml:"ProtocolViolations"` // Malformed transactions and invalid handshakes from an IP address within Window that trigger a ban; 0 to disable
+	Window             time.Duration `yaml:"Window"`             // Period in which failed logins and violations are counted; defaults to 10m
+	Duration           time.Duration `yaml:"Duration"`           // Length of the ban; defaults to 30m
+}
+
+// banMessage returns the message sent to connections from a banned IP address.
+func (s *Server) banMessage(ban Ban) string {
+	msg := s.T("You are permanently banned on this server")
+	if ban.Until != nil {
+		msg = s.T("You are temporarily banned on this server")
+	}
+	if ban.Reason != "" {
+		msg += "\r" + fmt.Sprintf(s.T("Reason: %s"), ToMacRoman(ban.Reason))
+	}
+
+	return msg
+}
+
+// autoBanWindow returns the period in which failed logins and protocol violations are counted towards a ban.
+func (s *Server) autoBanWindow() time.Duration {
+	if s.Config.AutoBan.Window <= 0 {
+		return defaultAutoBanWindow
+	}
+
+	return s.Config.AutoBan.Window
+}
+
+// recordEvent adds an event from ip at now to events, and returns the number of events from ip within window.  Events
+// that have fallen out of the window are dropped for all addresses, so that the map doesn't grow without bound.
+func recordEvent(events map[string][]time.Time, ip string, now time.Time, window time.Duration) int {
+	for addr, times := range events {
+		for len(times) > 0 && now.Sub(times[0]) >= window {
+			times = times[1:]
+		}
+		if len(times) == 0 {
+			delete(events, addr)
+		} else {
+			events[addr] = times
+		}
+	}
+
+	events[ip] = append(events[ip], now)
+
+	return len(events[ip])
+}
+
+// autoBan bans ip for AutoBan.Duration, and records the ban in the audit log.
+func (s *Server) autoBan(ip, reason string, now time.Time) {
+	duration := s.Config.AutoBan.Duration
+	if duration <= 0 {
+		duration = BanDuration
+	}
+	until := now.Add(duration)
+
+	if err := s.BanList.Add(ip, Ban{Until: &until, Reason: reason}); err != nil {
+		s.Logger.Error("Error saving ban", "ip", ip, "err", err)
+	}
+
+	if s.auditEnabled(AuditTypeAutoBan) {
+		s.AuditSink.WriteAudit(AuditEntry{Time: now, Type: AuditTypeAutoBan, IP: ip, Error: reason})
+	}
+}
+
+// recordFailedLogin counts a failed login from ip, and bans ip for AutoBan.Duration once it reaches
+// AutoBan.FailedLogins failures within AutoBan.Window.
+func (s *Server) recordFailedLogin(ip string, now time.Time) {
+	limit := s.Config.AutoBan.FailedLogins
+	if limit <= 0 {
+		return
+	}
+
+	s.autoBanMu.Lock()
+	if s.failedLogins == nil {
+		s.failedLogins = make(map[string][]time.Time)
+	}
+	failures := recordEvent(s.failedLogins, ip, now, s.autoBanWindow())
+	if failures >= limit {
+		delete(s.failedLogins, ip)
+	}
+	s.autoBanMu.Unlock()
+
+	if failures < limit {
+		return
+	}
+
+	s.Logger.Warn("Temporarily banning IP after failed logins", "ip", ip, "failedLogins", failures)
+	s.autoBan(ip, autoBanReason, now)
+}
+
+// clearFailedLogins forgets the failed logins from ip after a successful login.
+func (s *Server) clearFailedLogins(ip string) {
+	s.autoBanMu.Lock()
+	defer s.autoBanMu.Unlock()
+
+	delete(s.failedLogins, ip)
 }
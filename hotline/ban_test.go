@@ -0,0 +1,96 @@
+package hotline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type testBanMgr map[string]Ban
+
+func (m testBanMgr) Add(ip string, ban Ban) error {
+	m[ip] = ban
+	return nil
+}
+
+func (m testBanMgr) IsBanned(ip string) (bool, Ban) {
+	ban, ok := m[ip]
+	return ok && !ban.Expired(time.Now()), ban
+}
+
+func TestServer_recordFailedLogin(t *testing.T) {
+	bans := testBanMgr{}
+	s := &Server{
+		Config:  Config{AutoBan: AutoBan{FailedLogins: 3, Window: time.Minute, Duration: time.Hour}},
+		Logger:  NewTestLogger(),
+		BanList: bans,
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	s.recordFailedLogin("192.0.2.1", now)
+	s.recordFailedLogin("192.0.2.1", now.Add(10*time.Second))
+
+	// Failures outside the window don't count towards a ban.
+	s.recordFailedLogin("192.0.2.1", now.Add(65*time.Second))
+	assert.Empty(t, bans)
+
+	// A successful login resets the count.
+	s.clearFailedLogins("192.0.2.1")
+	s.recordFailedLogin("192.0.2.1", now.Add(70*time.Second))
+	s.recordFailedLogin("192.0.2.1", now.Add(75*time.Second))
+	assert.Empty(t, bans)
+
+	s.recordFailedLogin("192.0.2.2", now.Add(75*time.Second))
+	s.recordFailedLogin("192.0.2.1", now.Add(80*time.Second))
+
+	until := now.Add(80*time.Second + time.Hour)
+	assert.Equal(t, testBanMgr{"192.0.2.1": {Until: &until, Reason: "Too many failed login attempts"}}, bans)
+}
+
+func TestServer_recordFailedLogin_disabled(t *testing.T) {
+	bans := testBanMgr{}
+	s := &Server{Logger: NewTestLogger(), BanList: bans}
+
+	for range 10 {
+		s.recordFailedLogin("192.0.2.1", time.Now())
+	}
+	assert.Empty(t, bans)
+}
+
+func TestServer_banMessage(t *testing.T) {
+	s := &Server{}
+	until := time.Now().Add(time.Hour)
+
+	assert.Equal(t, "You are permanently banned on this server", s.banMessage(Ban{}))
+	assert.Equal(t, "You are temporarily banned on this server", s.banMessage(Ban{Until: &until}))
+	assert.Equal(t,
+		"You are temporarily banned on this server\rReason: Too many failed login attempts",
+		s.banMessage(Ban{Until: &until, Reason: "Too many failed login attempts"}),
+	)
+}
+
+func TestBan_YAML(t *testing.T) {
+	until := time.Date(2024, 6, 29, 11, 34, 43, 0, time.UTC)
+	bans := map[string]Ban{
+		"192.0.2.1": {},
+		"192.0.2.2": {Until: &until},
+		"192.0.2.3": {Until: &until, Reason: "Spam"},
+	}
+
+	out, err := yaml.Marshal(bans)
+	assert.NoError(t, err)
+
+	// Bans without a reason keep the original format of just the expiration time.
+	assert.Equal(t, `192.0.2.1: null
+192.0.2.2: 2024-06-29T11:34:43Z
+192.0.2.3:
+    Until: 2024-06-29T11:34:43Z
+    Reason: Spam
+`, string(out))
+
+	var got map[string]Ban
+	assert.NoError(t, yaml.Unmarshal(out, &got))
+	assert.Equal(t, bans, got)
+}
@@ -0,0 +1,77 @@
+package hotline
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const bandwidthMonthFormat = "2006-01"
+
+// BandwidthUsage is the number of bytes transferred over the file transfer port during a calendar month.
+type BandwidthUsage struct {
+	Month   string `yaml:"Month" json:"month"` // Month in YYYY-MM format
+	Ingress uint64 `yaml:"Ingress" json:"ingress"`
+	Egress  uint64 `yaml:"Egress" json:"egress"`
+}
+
+// Total returns the combined ingress and egress bytes.
+func (u BandwidthUsage) Total() uint64 {
+	return u.Ingress + u.Egress
+}
+
+// BandwidthMeter accumulates file transfer bandwidth usage for the current month.  Usage is reset when a new month
+// begins.
+type BandwidthMeter struct {
+	mu    sync.Mutex
+	usage BandwidthUsage
+	now   func() time.Time
+}
+
+// NewBandwidthMeter returns a meter that resumes counting from usage, such as a value saved from a previous run.
+func NewBandwidthMeter(usage BandwidthUsage) *BandwidthMeter {
+	return &BandwidthMeter{usage: usage, now: time.Now}
+}
+
+// rollover resets the usage if the month has changed.  The caller must hold mu.
+func (m *BandwidthMeter) rollover() {
+	if month := m.now().Format(bandwidthMonthFormat); m.usage.Month != month {
+		m.usage = BandwidthUsage{Month: month}
+	}
+}
+
+func (m *BandwidthMeter) Add(ingress, egress int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rollover()
+	m.usage.Ingress += uint64(ingress)
+	m.usage.Egress += uint64(egress)
+}
+
+// Usage returns the bandwidth usage for the current month.
+func (m *BandwidthMeter) Usage() BandwidthUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rollover()
+	return m.usage
+}
+
+// meteredConn is a net.Conn that records the bytes read and written to a BandwidthMeter.
+type meteredConn struct {
+	net.Conn
+	meter *BandwidthMeter
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.meter.Add(n, 0)
+	return n, err
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.meter.Add(0, n)
+	return n, err
+}
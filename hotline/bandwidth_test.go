@@ -0,0 +1,39 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBandwidthMeter(t *testing.T) {
+	now := time.Date(2024, time.January, 31, 23, 59, 0, 0, time.UTC)
+
+	m := NewBandwidthMeter(BandwidthUsage{Month: "2024-01", Ingress: 100, Egress: 200})
+	m.now = func() time.Time { return now }
+
+	m.Add(10, 20)
+	assert.Equal(t, BandwidthUsage{Month: "2024-01", Ingress: 110, Egress: 220}, m.Usage())
+	assert.Equal(t, uint64(330), m.Usage().Total())
+
+	// Usage is reset at the start of a new month.
+	now = now.Add(time.Hour)
+	assert.Equal(t, BandwidthUsage{Month: "2024-02"}, m.Usage())
+
+	m.Add(1, 2)
+	assert.Equal(t, BandwidthUsage{Month: "2024-02", Ingress: 1, Egress: 2}, m.Usage())
+}
+
+func TestServer_TransferCapExceeded(t *testing.T) {
+	s := &Server{Config: Config{MonthlyTransferCapMB: 1}, Bandwidth: NewBandwidthMeter(BandwidthUsage{})}
+	assert.False(t, s.TransferCapExceeded())
+
+	s.Bandwidth.Add(1024*1024-1, 0)
+	assert.False(t, s.TransferCapExceeded())
+
+	s.Bandwidth.Add(0, 1)
+	assert.True(t, s.TransferCapExceeded())
+
+	s.Config.MonthlyTransferCapMB = 0
+	assert.False(t, s.TransferCapExceeded())
+}
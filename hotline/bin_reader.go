@@ -0,0 +1,88 @@
+package hotline
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrShortData is returned when binary data from a client ends before the values it describes, such as a field
+// shorter than its size says.
+var ErrShortData = errors.New("data is too short")
+
+// binReader reads big endian values from binary data sent by clients, checking each read against the length of the
+// data rather than trusting the sizes and counts in it.  After a read fails, further reads return zero values and Err
+// returns the first error, so that a parser can read a whole structure and check for errors once.
+type binReader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func newBinReader(b []byte) *binReader {
+	return &binReader{b: b}
+}
+
+// Err returns the error of the first read that went past the end of the data.
+func (r *binReader) Err() error {
+	return r.err
+}
+
+// Len returns the number of unread bytes.
+func (r *binReader) Len() int {
+	return len(r.b) - r.off
+}
+
+// Offset returns the number of bytes read.
+func (r *binReader) Offset() int {
+	return r.off
+}
+
+// Bytes returns the next n bytes.  The returned slice shares the data being read.
+func (r *binReader) Bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > r.Len() {
+		r.err = fmt.Errorf("%w: need %d bytes at offset %d, have %d", ErrShortData, n, r.off, r.Len())
+		return nil
+	}
+
+	b := r.b[r.off : r.off+n]
+	r.off += n
+
+	return b
+}
+
+// Read fills p with the next len(p) bytes, or leaves it unchanged if there aren't enough.  It's meant for reading into
+// the fixed size arrays of protocol structs, e.g. r.Read(t.Type[:]).
+func (r *binReader) Read(p []byte) {
+	copy(p, r.Bytes(len(p)))
+}
+
+func (r *binReader) Uint8() uint8 {
+	b := r.Bytes(1)
+	if b == nil {
+		return 0
+	}
+
+	return b[0]
+}
+
+func (r *binReader) Uint16() uint16 {
+	b := r.Bytes(2)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *binReader) Uint32() uint32 {
+	b := r.Bytes(4)
+	if b == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(b)
+}
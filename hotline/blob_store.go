@@ -0,0 +1,253 @@
+package hotline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blobPruneInterval is how often unreferenced blobs are removed.
+const blobPruneInterval = 24 * time.Hour
+
+// BlobStore provides content-addressed deduplicated storage of file data.
+//
+// Each unique file is stored once in the blob dir, named by the SHA-256 hash of its contents.  Files in the visible
+// file tree are hard links to their blob, so the link count of a blob is its reference count: deleting, moving, or
+// renaming files in the tree requires no bookkeeping, and blobs no longer referenced by any file are removed by Prune.
+// Because linked files share their data, a file must be given its own copy with Unshare before it is written in place.
+//
+// The blob dir must be on the same filesystem as the file root.
+type BlobStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewBlobStore(dir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("create blob dir: %w", err)
+	}
+
+	return &BlobStore{dir: dir}, nil
+}
+
+func (bs *BlobStore) blobPath(hash string) string {
+	return filepath.Join(bs.dir, hash[:2], hash)
+}
+
+// Store replaces the file at path with a link to the blob for its contents, creating the blob if needed.
+func (bs *BlobStore) Store(path string) error {
+	hash, err := fileHash(path)
+	if err != nil {
+		return err
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	blob := bs.blobPath(hash)
+
+	blobInfo, err := os.Stat(blob)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(blob), 0750); err != nil {
+			return err
+		}
+
+		// First copy of this content: the uploaded file itself becomes the blob.
+		return os.Link(path, blob)
+	}
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(blobInfo, fileInfo) {
+		return nil
+	}
+
+	// Link the existing blob in place of the duplicate file by way of a temp file so the file is never missing.
+	tmpPath := filepath.Join(filepath.Dir(path), ".dedup_"+filepath.Base(path))
+	if err := os.Link(blob, tmpPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// StoreAll stores each regular file under path, skipping hidden metadata files and incomplete uploads, which are
+// appended to when the upload is resumed.
+func (bs *BlobStore) StoreAll(path string) error {
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") || strings.HasSuffix(d.Name(), IncompleteFileSuffix) {
+			return nil
+		}
+
+		return bs.Store(p)
+	})
+}
+
+// Unshare replaces the file at path with a private copy of its data if it is linked to a blob, so that writing to the
+// file does not change the other files with the same content.  A missing file is not an error.
+func (bs *BlobStore) Unshare(path string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if refs, ok := linkCount(info); !ok || refs <= 1 {
+		return nil
+	}
+
+	// Copy the data to a temp file and rename it over the link so the file is never missing or partially written.
+	tmpPath := filepath.Join(filepath.Dir(path), ".dedup_"+filepath.Base(path))
+	if err := copyFile(path, tmpPath, info.Mode().Perm()); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// UnshareIncomplete unshares each incomplete upload under path so that resuming it does not change other files.
+func (bs *BlobStore) UnshareIncomplete(path string) error {
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() || !strings.HasSuffix(d.Name(), IncompleteFileSuffix) {
+			return nil
+		}
+
+		return bs.Unshare(p)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Prune removes blobs that are no longer referenced by any file and returns the number of blobs removed.
+func (bs *BlobStore) Prune() (int, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	var removed int
+	err := filepath.WalkDir(bs.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if refs, ok := linkCount(info); ok && refs <= 1 {
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			removed++
+		}
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// blobPruneScheduler prunes the blob store at blobPruneInterval until ctx is cancelled.
+func (s *Server) blobPruneScheduler(ctx context.Context) {
+	ticker := time.NewTicker(blobPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.BlobStore.Prune()
+			if err != nil {
+				s.Logger.Error("Error pruning blob store", "err", err)
+				continue
+			}
+			s.Logger.Info("Pruned unreferenced blobs", "count", n)
+		}
+	}
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package hotline
+
+import "io/fs"
+
+// linkCount is not supported on this platform, so unreferenced blobs are never pruned.
+func linkCount(_ fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
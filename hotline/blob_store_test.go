@@ -0,0 +1,97 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBlobStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not supported on windows")
+	}
+
+	root := t.TempDir()
+	bs, err := NewBlobStore(filepath.Join(root, "Blobs"))
+	assert.NoError(t, err)
+
+	fileA := filepath.Join(root, "a.sit")
+	fileB := filepath.Join(root, "b.sit")
+	fileC := filepath.Join(root, "c.sit")
+	assert.NoError(t, os.WriteFile(fileA, []byte("same content"), 0644))
+	assert.NoError(t, os.WriteFile(fileB, []byte("same content"), 0644))
+	assert.NoError(t, os.WriteFile(fileC, []byte("other content"), 0644))
+
+	assert.NoError(t, bs.StoreAll(root))
+
+	infoA, _ := os.Stat(fileA)
+	infoB, _ := os.Stat(fileB)
+	infoC, _ := os.Stat(fileC)
+	assert.True(t, os.SameFile(infoA, infoB), "duplicate files should share a blob")
+	assert.False(t, os.SameFile(infoA, infoC))
+
+	data, err := os.ReadFile(fileB)
+	assert.NoError(t, err)
+	assert.Equal(t, "same content", string(data))
+
+	// Storing an already deduplicated file is a no-op.
+	assert.NoError(t, bs.Store(fileA))
+
+	// Blobs are kept while any file references them.
+	assert.NoError(t, os.Remove(fileA))
+	assert.NoError(t, os.Remove(fileC))
+	removed, err := bs.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	assert.NoError(t, os.Remove(fileB))
+	removed, err = bs.Prune()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
+
+func TestBlobStore_Unshare(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not supported on windows")
+	}
+
+	root := t.TempDir()
+	bs, err := NewBlobStore(filepath.Join(root, "Blobs"))
+	assert.NoError(t, err)
+
+	fileA := filepath.Join(root, "a.sit")
+	fileB := filepath.Join(root, "b.sit")
+	incomplete := filepath.Join(root, "c.sit"+IncompleteFileSuffix)
+	assert.NoError(t, os.WriteFile(fileA, []byte("same content"), 0644))
+	assert.NoError(t, os.WriteFile(fileB, []byte("same content"), 0644))
+	assert.NoError(t, os.WriteFile(incomplete, []byte("same content"), 0644))
+
+	assert.NoError(t, bs.StoreAll(root))
+
+	// Incomplete uploads are never linked, since resuming the upload appends to them.
+	infoA, _ := os.Stat(fileA)
+	infoInc, _ := os.Stat(incomplete)
+	assert.False(t, os.SameFile(infoA, infoInc))
+
+	// Writing to an unshared file leaves the other copies unchanged.
+	assert.NoError(t, bs.Unshare(fileA))
+	f, err := os.OpenFile(fileA, os.O_APPEND|os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	_, err = f.WriteString(" and more")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	data, err := os.ReadFile(fileB)
+	assert.NoError(t, err)
+	assert.Equal(t, "same content", string(data))
+
+	data, err = os.ReadFile(fileA)
+	assert.NoError(t, err)
+	assert.Equal(t, "same content and more", string(data))
+
+	// Unsharing a file that is not linked, or is missing, is a no-op.
+	assert.NoError(t, bs.Unshare(fileA))
+	assert.NoError(t, bs.Unshare(filepath.Join(root, "missing.sit")))
+}
@@ -0,0 +1,37 @@
+package hotline
+
+import (
+	"errors"
+	"slices"
+)
+
+// MaxBookmarks is the maximum number of bookmarks an account can have.
+const MaxBookmarks = 200
+
+var ErrBookmarkLimit = errors.New("bookmark limit reached")
+
+// AddBookmark adds path to the account's bookmarks.  Adding a path that is already bookmarked has no effect.
+func (a *Account) AddBookmark(path string) error {
+	if slices.Contains(a.Bookmarks, path) {
+		return nil
+	}
+	if len(a.Bookmarks) >= MaxBookmarks {
+		return ErrBookmarkLimit
+	}
+
+	a.Bookmarks = append(a.Bookmarks, path)
+
+	return nil
+}
+
+// RemoveBookmark removes path from the account's bookmarks.  It returns false if the path was not bookmarked.
+func (a *Account) RemoveBookmark(path string) bool {
+	i := slices.Index(a.Bookmarks, path)
+	if i == -1 {
+		return false
+	}
+
+	a.Bookmarks = slices.Delete(a.Bookmarks, i, i+1)
+
+	return true
+}
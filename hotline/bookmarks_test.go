@@ -0,0 +1,25 @@
+package hotline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccount_Bookmarks(t *testing.T) {
+	acc := &Account{}
+
+	assert.NoError(t, acc.AddBookmark("Uploads/a.sit"))
+	assert.NoError(t, acc.AddBookmark("Uploads/a.sit"))
+	assert.Equal(t, []string{"Uploads/a.sit"}, acc.Bookmarks)
+
+	assert.False(t, acc.RemoveBookmark("Uploads/b.sit"))
+	assert.True(t, acc.RemoveBookmark("Uploads/a.sit"))
+	assert.Empty(t, acc.Bookmarks)
+
+	for i := range MaxBookmarks {
+		assert.NoError(t, acc.AddBookmark(fmt.Sprintf("file%d", i)))
+	}
+	assert.ErrorIs(t, acc.AddBookmark("one too many"), ErrBookmarkLimit)
+}
@@ -0,0 +1,101 @@
+package hotline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Broadcast is an admin broadcast message, kept so that users who log in after it was sent can read it.
+type Broadcast struct {
+	Message string    `yaml:"Message" json:"message"`
+	Sender  string    `yaml:"Sender" json:"sender"` // Login of the account that sent the broadcast
+	Date    time.Time `yaml:"Date" json:"date"`
+}
+
+// BroadcastHistory stores recent broadcasts.
+type BroadcastHistory interface {
+	// Add saves a broadcast and discards all but the most recent keep broadcasts.
+	Add(b Broadcast, keep int) error
+
+	// List returns the kept broadcasts from oldest to newest.
+	List() []Broadcast
+
+	// RecordLogin saves now as the last login time of the user identified by key, and returns their previous last login
+	// time, or the zero time if they haven't logged in since the oldest kept broadcast.
+	RecordLogin(key string, now time.Time) (time.Time, error)
+}
+
+// RecordBroadcast adds a broadcast to the broadcast history, if enabled.
+func (s *Server) RecordBroadcast(b Broadcast) error {
+	if s.BroadcastHistory == nil || s.Config.BroadcastHistory <= 0 {
+		return nil
+	}
+
+	return s.BroadcastHistory.Add(b, s.Config.BroadcastHistory)
+}
+
+// BroadcastsSince returns the kept broadcasts sent after t, from oldest to newest.
+func (s *Server) BroadcastsSince(t time.Time) []Broadcast {
+	if s.BroadcastHistory == nil || s.Config.BroadcastHistory <= 0 {
+		return nil
+	}
+
+	var broadcasts []Broadcast
+	for _, b := range s.BroadcastHistory.List() {
+		if b.Date.After(t) {
+			broadcasts = append(broadcasts, b)
+		}
+	}
+
+	return broadcasts
+}
+
+// recordLogin saves now as the last login time of the user of cc, and returns their previous last login time.  Last
+// login times are only kept when the broadcast history is enabled.  They are kept by login, except on the guest account,
+// which all guests share, where they are kept by login and IP address so that each guest is shown the broadcasts they
+// missed.
+func (s *Server) recordLogin(cc *ClientConn, now time.Time) time.Time {
+	if s.BroadcastHistory == nil || s.Config.BroadcastHistory <= 0 {
+		return time.Time{}
+	}
+
+	key := cc.Account.Login
+	if IsSharedAccount(key) {
+		key += "@" + AddrIP(cc.RemoteAddr)
+	}
+
+	prev, err := s.BroadcastHistory.RecordLogin(key, now)
+	if err != nil {
+		s.Logger.Warn("Error saving last login time", "login", cc.Account.Login, "err", err)
+	}
+
+	return prev
+}
+
+// MissedBroadcasts returns a server message with the broadcasts sent since the user last logged in, or nil
+// if there are none.
+func (cc *ClientConn) MissedBroadcasts() []Transaction {
+	broadcasts := cc.Server.BroadcastsSince(cc.prevLogin)
+	if len(broadcasts) == 0 {
+		return nil
+	}
+
+	return []Transaction{cc.BroadcastsMsg(cc.T("Broadcasts since your last visit:"), broadcasts)}
+}
+
+// BroadcastsMsg returns a server message listing broadcasts under a heading.
+func (cc *ClientConn) BroadcastsMsg(heading string, broadcasts []Broadcast) Transaction {
+	var sb strings.Builder
+	sb.WriteString(heading)
+	for _, b := range broadcasts {
+		sb.WriteString(fmt.Sprintf("\r\r%s\r%s", b.Date.Format("2006-01-02 15:04"), ToMacRoman(b.Message)))
+	}
+
+	return NewTransaction(
+		TranServerMsg,
+		cc.ID,
+		NewField(FieldData, []byte(sb.String())),
+		NewField(FieldChatOptions, []byte{0, 0}),
+	)
+}
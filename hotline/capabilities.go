@@ -0,0 +1,64 @@
+package hotline
+
+import "slices"
+
+// Capabilities
+//
+// Optional features beyond the classic protocol are negotiated per client.  After login, a client that supports Mobius
+// extensions sends TranNegotiateCapabilities with a FieldCapability for each feature it supports, and the server
+// replies with the features it enabled: those that both sides support.  Classic clients never negotiate, so features
+// that change existing replies must check ClientConn.HasCapability before using them.  Clients can also use the reply
+// to find out which extension transactions the server supports, instead of sending them and handling errors.
+const (
+	CapServerInfo        = "server-info"        // TranGetServerInfo
+	CapIncompleteUploads = "incomplete-uploads" // TranGetIncompleteUploads and TranDeleteIncompleteUpload
+	CapBookmarks         = "bookmarks"          // TranGetBookmarks, TranAddBookmark, and TranDeleteBookmark
+	CapPreferences       = "preferences"        // TranGetPreferences and TranSetPreferences
+	CapUserGroups        = "user-groups"        // FieldUserGroup hints in user lists and user change notifications
+)
+
+// RegisterCapability adds an optional feature that clients can enable.  Capabilities are registered with the handlers
+// that implement them, before the server starts.
+func (s *Server) RegisterCapability(name string) {
+	if !slices.Contains(s.capabilities, name) {
+		s.capabilities = append(s.capabilities, name)
+	}
+}
+
+// Capabilities returns the optional features supported by the server, in the order they were registered.
+func (s *Server) Capabilities() []string {
+	return slices.Clone(s.capabilities)
+}
+
+// EnableCapabilities enables the requested features that the server supports for the client, replacing any enabled
+// before, and returns them.  Unknown features are ignored.
+func (cc *ClientConn) EnableCapabilities(requested []string) []string {
+	var enabled []string
+	for _, name := range cc.Server.capabilities {
+		if slices.Contains(requested, name) {
+			enabled = append(enabled, name)
+		}
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.capabilities = enabled
+
+	return slices.Clone(enabled)
+}
+
+// HasCapability returns true if the client enabled the optional feature.
+func (cc *ClientConn) HasCapability(name string) bool {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	return slices.Contains(cc.capabilities, name)
+}
+
+// EnabledCapabilities returns the optional features enabled by the client.
+func (cc *ClientConn) EnabledCapabilities() []string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	return slices.Clone(cc.capabilities)
+}
@@ -1,30 +1,114 @@
 package hotline
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
 	"github.com/stretchr/testify/mock"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
+var (
+	ErrChatNotFound   = errors.New("chat not found")
+	ErrChatFull       = errors.New("chat is full")
+	ErrChatNotMember  = errors.New("not a member of the chat")
+	ErrChatNotInvited = errors.New("not invited to the chat")
+)
+
+// ChatOptions are the settings of a private chat.
+type ChatOptions struct {
+	MaxMembers int  // Maximum number of members; 0 for no limit
+	InviteOnly bool // Only members can invite users, and only invited users can join
+}
+
 type PrivateChat struct {
 	Subject    string
 	ClientConn map[[2]byte]*ClientConn
+	Options    ChatOptions
+	invites    []ChatInvite // Outstanding invitations
+}
+
+func (c *PrivateChat) full() bool {
+	return c.Options.MaxMembers > 0 && len(c.ClientConn) >= c.Options.MaxMembers
+}
+
+// invited returns true if the client has an outstanding invitation to the chat.
+func (c *PrivateChat) invited(clientID [2]byte) bool {
+	return slices.ContainsFunc(c.invites, func(inv ChatInvite) bool { return inv.ClientID == clientID })
+}
+
+// ChatInvite is an invitation to a private chat that hasn't been accepted or declined.  Invitations are kept with the
+// account login, name, and IP address of the invited user, so that they can be sent again if the user reconnects.
+type ChatInvite struct {
+	ChatID   ChatID    `yaml:"-"`
+	ClientID ClientID  `yaml:"-"`        // Client the invitation was sent to; zero for invitations restored after a restart
+	Login    string    `yaml:"Login"`    // Account login of the invited user
+	Name     string    `yaml:"Name"`     // User name of the invited user
+	IP       string    `yaml:"IP"`       // IP address the invited user was connected from
+	FromName string    `yaml:"FromName"` // User name of the user who sent the invitation
+	FromID   ClientID  `yaml:"-"`        // Client ID of the user who sent the invitation
+	Sent     time.Time `yaml:"Sent"`
+}
+
+// ChatMember is a member of a private chat saved by SavedChats.
+type ChatMember struct {
+	Login string `yaml:"Login"`
+	Name  string `yaml:"Name"`
+	IP    string `yaml:"IP"`
+}
+
+// forUser returns true if the invitation can be sent again to cc, a later connection of the invited user.  Since the
+// account login and name don't identify a user on their own, such as on the shared guest account or on accounts that
+// can use any name, the connection must also be from the same IP address, and invitations to the guest account are
+// never sent again.
+func (inv ChatInvite) forUser(cc *ClientConn) bool {
+	login := cc.login()
+
+	return login != "" && login != GuestAccount &&
+		inv.Login == login && inv.Name == string(cc.UserName) &&
+		inv.IP != "" && inv.IP == AddrIP(cc.RemoteAddr)
+}
+
+// SavedChat is the state of a private chat, as saved by persistent ChatManagers so that chats survive a restart.
+type SavedChat struct {
+	ID      ChatID       `yaml:"ID"`
+	Subject string       `yaml:"Subject"`
+	Options ChatOptions  `yaml:"Options"`
+	Members []ChatMember `yaml:"Members"`
+	Invites []ChatInvite `yaml:"Invites"`
 }
 
 type ChatID [4]byte
 
 type ChatManager interface {
-	New(cc *ClientConn) ChatID
+	New(cc *ClientConn, opts ChatOptions) ChatID
 	GetSubject(id ChatID) string
-	Join(id ChatID, cc *ClientConn)
+	Invite(id ChatID, inviter, invitee *ClientConn) error
+	DeclineInvite(id ChatID, clientID [2]byte)
+	Join(id ChatID, cc *ClientConn) error
 	Leave(id ChatID, clientID [2]byte)
 	SetSubject(id ChatID, subject string)
 	Members(id ChatID) []*ClientConn
+	Options(id ChatID) ChatOptions
+	SetOptions(id ChatID, opts ChatOptions)
+
+	// AppendHistory adds a public chat message to the chat history and discards all but the most recent keep messages.
+	AppendHistory(msg ChatHistoryMsg, keep int) error
+
+	// History returns the public chat history from oldest to newest.
+	History() []ChatHistoryMsg
+
+	// PendingInvites returns the outstanding invitations sent after since to earlier connections of the same user as cc,
+	// such as those sent before the user reconnected, and addresses them to cc so that cc can accept them.
+	PendingInvites(cc *ClientConn, since time.Time) []ChatInvite
 }
 
 type MemChatManager struct {
-	chats map[ChatID]*PrivateChat
+	chats   map[ChatID]*PrivateChat
+	history []ChatHistoryMsg // oldest first
 
 	mu sync.Mutex
 }
@@ -35,26 +119,191 @@ func NewMemChatManager() *MemChatManager {
 	}
 }
 
-func (cm *MemChatManager) New(cc *ClientConn) ChatID {
+func (cm *MemChatManager) New(cc *ClientConn, opts ChatOptions) ChatID {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
 	var randID [4]byte
 	_, _ = rand.Read(randID[:])
 
-	cm.chats[randID] = &PrivateChat{ClientConn: make(map[[2]byte]*ClientConn)}
+	cm.chats[randID] = &PrivateChat{
+		ClientConn: make(map[[2]byte]*ClientConn),
+		Options:    opts,
+	}
 
 	cm.chats[randID].ClientConn[cc.ID] = cc
 
 	return randID
 }
 
-func (cm *MemChatManager) Join(id ChatID, cc *ClientConn) {
+// Invite records an invitation to the chat for invitee.  Invitations to invite-only chats can only be sent by members.
+func (cm *MemChatManager) Invite(id ChatID, inviter, invitee *ClientConn) error {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	chat := cm.chats[id]
+	chat, ok := cm.chats[id]
+	if !ok {
+		return ErrChatNotFound
+	}
+
+	if _, ok := chat.ClientConn[inviter.ID]; !ok && chat.Options.InviteOnly {
+		return ErrChatNotMember
+	}
+
+	if chat.full() {
+		return ErrChatFull
+	}
+
+	chat.invites = append(chat.invites, ChatInvite{
+		ChatID:   id,
+		ClientID: invitee.ID,
+		Login:    invitee.login(),
+		Name:     string(invitee.UserName),
+		IP:       AddrIP(invitee.RemoteAddr),
+		FromName: string(inviter.UserName),
+		FromID:   inviter.ID,
+		Sent:     time.Now(),
+	})
+
+	return nil
+}
+
+// DeclineInvite removes the invitations to the chat for clientID, so that they aren't sent again.
+func (cm *MemChatManager) DeclineInvite(id ChatID, clientID [2]byte) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if chat, ok := cm.chats[id]; ok {
+		chat.invites = slices.DeleteFunc(chat.invites, func(inv ChatInvite) bool { return inv.ClientID == clientID })
+	}
+}
+
+// PendingInvites returns the outstanding invitations sent after since to earlier connections of the user of cc, as
+// matched by ChatInvite.forUser, and addresses them to cc.  Restored invitations that are older than since are
+// discarded.
+func (cm *MemChatManager) PendingInvites(cc *ClientConn, since time.Time) []ChatInvite {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var pending []ChatInvite
+	for _, chat := range cm.chats {
+		chat.invites = slices.DeleteFunc(chat.invites, func(inv ChatInvite) bool {
+			return inv.ClientID == [2]byte{} && inv.Sent.Before(since)
+		})
+
+		for _, inv := range chat.invites {
+			if !inv.forUser(cc) || inv.ClientID == cc.ID || !inv.Sent.After(since) {
+				continue
+			}
+			if chat.invited(cc.ID) {
+				break
+			}
+			if _, ok := chat.ClientConn[cc.ID]; ok {
+				break
+			}
+
+			inv.ClientID = cc.ID
+			chat.invites = append(chat.invites, inv)
+			pending = append(pending, inv)
+			break
+		}
+	}
+
+	slices.SortFunc(pending, func(a, b ChatInvite) int { return a.Sent.Compare(b.Sent) })
+
+	return pending
+}
+
+// SavedChats returns the private chats that have members or outstanding invitations, for saving.
+func (cm *MemChatManager) SavedChats() []SavedChat {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var saved []SavedChat
+	for id, chat := range cm.chats {
+		if len(chat.ClientConn) == 0 && len(chat.invites) == 0 {
+			continue
+		}
+
+		sc := SavedChat{ID: id, Subject: chat.Subject, Options: chat.Options, Invites: slices.Clone(chat.invites)}
+		for _, cc := range chat.ClientConn {
+			sc.Members = append(sc.Members, ChatMember{Login: cc.login(), Name: string(cc.UserName), IP: AddrIP(cc.RemoteAddr)})
+		}
+		slices.SortFunc(sc.Members, func(a, b ChatMember) int { return strings.Compare(a.Name, b.Name) })
+
+		saved = append(saved, sc)
+	}
+
+	slices.SortFunc(saved, func(a, b SavedChat) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+
+	return saved
+}
+
+// RestoreChats adds private chats saved before a restart.  The former members of each chat are invited back, so that
+// the chat is offered to them again if they log in within the invitation grace period.
+func (cm *MemChatManager) RestoreChats(saved []SavedChat) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	now := time.Now()
+	for _, sc := range saved {
+		chat := &PrivateChat{
+			Subject:    sc.Subject,
+			ClientConn: make(map[[2]byte]*ClientConn),
+			Options:    sc.Options,
+		}
+
+		for _, inv := range sc.Invites {
+			inv.ChatID, inv.ClientID, inv.FromID = sc.ID, [2]byte{}, [2]byte{}
+			chat.invites = append(chat.invites, inv)
+		}
+
+		for i, m := range sc.Members {
+			// Invitations need a sender, so each member is invited back by another former member.
+			from := sc.Members[(i+1)%len(sc.Members)]
+			chat.invites = append(chat.invites, ChatInvite{
+				ChatID:   sc.ID,
+				Login:    m.Login,
+				Name:     m.Name,
+				IP:       m.IP,
+				FromName: from.Name,
+				Sent:     now,
+			})
+		}
+
+		cm.chats[sc.ID] = chat
+	}
+}
+
+// Join adds cc to the chat.  Clients can only join invite-only chats they have been invited to.
+func (cm *MemChatManager) Join(id ChatID, cc *ClientConn) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	chat, ok := cm.chats[id]
+	if !ok {
+		return ErrChatNotFound
+	}
+
+	if _, ok := chat.ClientConn[cc.ID]; ok {
+		return nil
+	}
+
+	if !chat.invited(cc.ID) && chat.Options.InviteOnly {
+		return ErrChatNotInvited
+	}
+
+	if chat.full() {
+		return ErrChatFull
+	}
+
+	// Invitations sent to earlier connections of the same user are answered too.
+	chat.invites = slices.DeleteFunc(chat.invites, func(inv ChatInvite) bool {
+		return inv.ClientID == cc.ID || inv.forUser(cc)
+	})
 	chat.ClientConn[cc.ID] = cc
+
+	return nil
 }
 
 func (cm *MemChatManager) Leave(id ChatID, clientID [2]byte) {
@@ -80,7 +329,10 @@ func (cm *MemChatManager) Members(id ChatID) []*ClientConn {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
-	chat := cm.chats[id]
+	chat, ok := cm.chats[id]
+	if !ok {
+		return nil
+	}
 
 	var members []*ClientConn
 	for _, cc := range chat.ClientConn {
@@ -101,12 +353,62 @@ func (cm *MemChatManager) SetSubject(id ChatID, subject string) {
 	chat.Subject = subject
 }
 
+func (cm *MemChatManager) Options(id ChatID) ChatOptions {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	chat, ok := cm.chats[id]
+	if !ok {
+		return ChatOptions{}
+	}
+
+	return chat.Options
+}
+
+func (cm *MemChatManager) SetOptions(id ChatID, opts ChatOptions) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if chat, ok := cm.chats[id]; ok {
+		chat.Options = opts
+	}
+}
+
+// AppendHistory adds a public chat message to the chat history and discards all but the most recent keep messages.
+func (cm *MemChatManager) AppendHistory(msg ChatHistoryMsg, keep int) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.history = append(cm.history, msg)
+	if len(cm.history) > keep {
+		cm.history = slices.Clone(cm.history[len(cm.history)-keep:])
+	}
+
+	return nil
+}
+
+// History returns the public chat history from oldest to newest.
+func (cm *MemChatManager) History() []ChatHistoryMsg {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	return slices.Clone(cm.history)
+}
+
+// SetHistory replaces the public chat history, e.g. with a history saved before the server restarted.
+func (cm *MemChatManager) SetHistory(history []ChatHistoryMsg) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.history = slices.Clone(history)
+}
+
 type MockChatManager struct {
 	mock.Mock
 }
 
-func (m *MockChatManager) New(cc *ClientConn) ChatID {
-	args := m.Called(cc)
+func (m *MockChatManager) New(cc *ClientConn, opts ChatOptions) ChatID {
+	args := m.Called(cc, opts)
 
 	return args.Get(0).(ChatID)
 }
@@ -117,8 +419,20 @@ func (m *MockChatManager) GetSubject(id ChatID) string {
 	return args.String(0)
 }
 
-func (m *MockChatManager) Join(id ChatID, cc *ClientConn) {
-	m.Called(id, cc)
+func (m *MockChatManager) Invite(id ChatID, inviter, invitee *ClientConn) error {
+	args := m.Called(id, inviter, invitee)
+
+	return args.Error(0)
+}
+
+func (m *MockChatManager) DeclineInvite(id ChatID, clientID [2]byte) {
+	m.Called(id, clientID)
+}
+
+func (m *MockChatManager) Join(id ChatID, cc *ClientConn) error {
+	args := m.Called(id, cc)
+
+	return args.Error(0)
 }
 
 func (m *MockChatManager) Leave(id ChatID, clientID [2]byte) {
@@ -135,3 +449,31 @@ func (m *MockChatManager) Members(id ChatID) []*ClientConn {
 
 	return args.Get(0).([]*ClientConn)
 }
+
+func (m *MockChatManager) Options(id ChatID) ChatOptions {
+	args := m.Called(id)
+
+	return args.Get(0).(ChatOptions)
+}
+
+func (m *MockChatManager) SetOptions(id ChatID, opts ChatOptions) {
+	m.Called(id, opts)
+}
+
+func (m *MockChatManager) AppendHistory(msg ChatHistoryMsg, keep int) error {
+	args := m.Called(msg, keep)
+
+	return args.Error(0)
+}
+
+func (m *MockChatManager) History() []ChatHistoryMsg {
+	args := m.Called()
+
+	return args.Get(0).([]ChatHistoryMsg)
+}
+
+func (m *MockChatManager) PendingInvites(cc *ClientConn, since time.Time) []ChatInvite {
+	args := m.Called(cc, since)
+
+	return args.Get(0).([]ChatInvite)
+}
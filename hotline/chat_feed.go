@@ -0,0 +1,111 @@
+package hotline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// chatFeedBuffer is the number of events buffered for each ChatFeed subscriber.  Events are dropped for subscribers
+// that fall further behind, so that a slow web client can't hold up chat.
+const chatFeedBuffer = 64
+
+// Types of ChatFeedEvent.
+const (
+	ChatFeedChat  = "chat"
+	ChatFeedJoin  = "join"
+	ChatFeedLeave = "leave"
+)
+
+// ChatFeedEvent is a public chat message, or a user joining or leaving the server.
+type ChatFeedEvent struct {
+	Type  string    `json:"type"` // One of the ChatFeed constants
+	Time  time.Time `json:"time"`
+	User  string    `json:"user"`            // Name of the user
+	Text  string    `json:"text,omitempty"`  // Message of chat events
+	Emote bool      `json:"emote,omitempty"` // The message is an action, as sent with the option key or /me
+}
+
+// ChatFeed publishes public chat and users joining and leaving to subscribers, such as web chat clients.  Its methods
+// do nothing on a nil ChatFeed, for servers without one.
+type ChatFeed struct {
+	mu   sync.Mutex
+	subs map[chan ChatFeedEvent]struct{}
+}
+
+func NewChatFeed() *ChatFeed {
+	return &ChatFeed{subs: make(map[chan ChatFeedEvent]struct{})}
+}
+
+// Subscribe returns a channel of the events published from now on, and a func that ends the subscription and closes
+// the channel.
+func (f *ChatFeed) Subscribe() (<-chan ChatFeedEvent, func()) {
+	ch := make(chan ChatFeedEvent, chatFeedBuffer)
+
+	f.mu.Lock()
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, ch)
+			close(ch)
+			f.mu.Unlock()
+		})
+	}
+}
+
+func (f *ChatFeed) publish(e ChatFeedEvent) {
+	if f == nil {
+		return
+	}
+
+	e.Time = time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Chat publishes a public chat message from the user named name.  name and text are Mac Roman encoded, as sent by
+// clients.
+func (f *ChatFeed) Chat(name, text []byte, emote bool) {
+	f.publish(ChatFeedEvent{Type: ChatFeedChat, User: decodeMacRoman(name), Text: decodeMacRoman(text), Emote: emote})
+}
+
+// Join publishes a user joining the server.
+func (f *ChatFeed) Join(name []byte) {
+	f.publish(ChatFeedEvent{Type: ChatFeedJoin, User: decodeMacRoman(name)})
+}
+
+// Leave publishes a user leaving the server.
+func (f *ChatFeed) Leave(name []byte) {
+	f.publish(ChatFeedEvent{Type: ChatFeedLeave, User: decodeMacRoman(name)})
+}
+
+// SendChat sends a public chat message from name, which needn't be a connected user, e.g. for relaying messages from
+// another chat service.  name and text are UTF-8, and are converted to Mac Roman for clients.
+func (s *Server) SendChat(name, text string) {
+	macName := []byte(ToMacRoman(name))
+	msg, _ := LimitLines([]byte(ToMacRoman(text)), s.Config.MaxChatLines)
+
+	formattedMsg, _ := TruncateText([]byte(fmt.Sprintf("\r%13.13s:  %s", macName, msg)), s.MaxChatMsgLen())
+
+	for _, c := range s.ClientMgr.List() {
+		if c.Authorize(AccessReadChat) {
+			s.outbox <- NewTransaction(TranChatMsg, c.ID, NewField(FieldData, formattedMsg))
+		}
+	}
+
+	s.Digest.RecordChat(name)
+	s.ChatFeed.Chat(macName, msg, false)
+	s.RecordChat(formattedMsg)
+}
@@ -0,0 +1,107 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatFeed(t *testing.T) {
+	f := NewChatFeed()
+	events, unsubscribe := f.Subscribe()
+
+	f.Join([]byte("Caf\x8e"))
+	f.Chat([]byte("Caf\x8e"), []byte("hi"), false)
+	f.Chat([]byte("Caf\x8e"), []byte("waves"), true)
+	f.Leave([]byte("Caf\x8e"))
+
+	var got []ChatFeedEvent
+	for range 4 {
+		e := <-events
+		assert.False(t, e.Time.IsZero())
+		got = append(got, ChatFeedEvent{Type: e.Type, User: e.User, Text: e.Text, Emote: e.Emote})
+	}
+	assert.Equal(t, []ChatFeedEvent{
+		{Type: ChatFeedJoin, User: "Café"},
+		{Type: ChatFeedChat, User: "Café", Text: "hi"},
+		{Type: ChatFeedChat, User: "Café", Text: "waves", Emote: true},
+		{Type: ChatFeedLeave, User: "Café"},
+	}, got)
+
+	unsubscribe()
+	unsubscribe()
+	_, ok := <-events
+	assert.False(t, ok)
+
+	// Publishing without subscribers, or on a nil feed, does nothing.
+	f.Join([]byte("Alice"))
+	var nilFeed *ChatFeed
+	nilFeed.Chat([]byte("Alice"), []byte("hi"), false)
+}
+
+func TestChatFeed_SlowSubscriber(t *testing.T) {
+	f := NewChatFeed()
+	events, unsubscribe := f.Subscribe()
+	defer unsubscribe()
+
+	for range chatFeedBuffer + 10 {
+		f.Chat([]byte("Alice"), []byte("hi"), false)
+	}
+
+	assert.Len(t, events, chatFeedBuffer)
+}
+
+func TestServer_SendChat(t *testing.T) {
+	reader := &ClientConn{ID: [2]byte{0, 1}, Account: &Account{}}
+	reader.Account.Access.Set(AccessReadChat)
+	other := &ClientConn{ID: [2]byte{0, 2}, Account: &Account{}}
+
+	s := &Server{
+		ClientMgr: NewMemClientMgr(),
+		ChatFeed:  NewChatFeed(),
+		outbox:    make(chan Transaction, 10),
+	}
+	s.ClientMgr.Add(reader)
+	s.ClientMgr.Add(other)
+
+	events, unsubscribe := s.ChatFeed.Subscribe()
+	defer unsubscribe()
+
+	s.SendChat("Web", "<Café> hi")
+
+	require.Len(t, s.outbox, 1)
+	tran := <-s.outbox
+	assert.Equal(t, TranChatMsg, tran.Type)
+	assert.Equal(t, reader.ID, tran.ClientID)
+	assert.Equal(t, []byte("\r          Web:  <Caf\x8e> hi"), tran.GetField(FieldData).Data)
+
+	e := <-events
+	assert.Equal(t, ChatFeedChat, e.Type)
+	assert.Equal(t, "Web", e.User)
+	assert.Equal(t, "<Café> hi", e.Text)
+}
+
+func TestServer_CheckLogin(t *testing.T) {
+	s := &Server{
+		AccountManager: testAccountMgr{
+			"alice": {Login: "alice", Name: "Alice", Password: HashAndSalt(EncodeString([]byte("secret")))},
+		},
+		BanList: testBanMgr{},
+		Logger:  NewTestLogger(),
+	}
+
+	account, err := s.CheckLogin("192.0.2.1", "alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", account.Name)
+
+	_, err = s.CheckLogin("192.0.2.1", "alice", "wrong")
+	assert.ErrorIs(t, err, ErrIncorrectLogin)
+
+	_, err = s.CheckLogin("192.0.2.1", "bob", "secret")
+	assert.ErrorIs(t, err, ErrIncorrectLogin)
+
+	s.BanList = testBanMgr{"192.0.2.1": {}}
+	_, err = s.CheckLogin("192.0.2.1", "alice", "secret")
+	assert.ErrorIs(t, err, ErrTooManyLogins)
+}
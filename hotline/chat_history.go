@@ -0,0 +1,53 @@
+package hotline
+
+import (
+	"bytes"
+	"time"
+)
+
+// ChatHistoryMsg is a public chat message kept in the chat history, so that users who log in later can see recent chat.
+type ChatHistoryMsg struct {
+	Time    time.Time `yaml:"Time"`
+	Message string    `yaml:"Message"` // Message as sent to clients, e.g. "\r        Alice:  hi", Mac Roman encoded
+}
+
+// RecordChat adds a public chat message, formatted as sent to clients, to the chat history if it's enabled.
+func (s *Server) RecordChat(formattedMsg []byte) {
+	if s.Config.ChatHistory.Lines <= 0 || s.ChatMgr == nil {
+		return
+	}
+
+	msg := ChatHistoryMsg{Time: time.Now(), Message: string(formattedMsg)}
+	if err := s.ChatMgr.AppendHistory(msg, s.Config.ChatHistory.Lines); err != nil {
+		s.Logger.Error("Error saving chat history", "err", err)
+	}
+}
+
+// ReplayChatHistory returns a chat message with the chat history, for users who just logged in.  The history is sent
+// as a single message with a line for each kept message, since separate messages could arrive out of order.  Each line
+// is prefixed with the time the message was sent, e.g. "[14:05]", and the message has a FieldChatHistoryTime field, so
+// that both people and clients can tell the replay from live chat.
+func (cc *ClientConn) ReplayChatHistory() []Transaction {
+	if cc.Server.Config.ChatHistory.Lines <= 0 || !cc.Authorize(AccessReadChat) {
+		return nil
+	}
+
+	history := cc.Server.ChatMgr.History()
+	if len(history) == 0 {
+		return nil
+	}
+
+	var text []byte
+	for _, msg := range history {
+		text = append(text, "\r["+msg.Time.Format("15:04")+"] "...)
+		text = append(text, bytes.TrimPrefix([]byte(msg.Message), []byte("\r"))...)
+	}
+	oldest := NewTime(history[0].Time)
+
+	return []Transaction{
+		NewTransaction(TranChatMsg, cc.ID,
+			NewField(FieldData, text),
+			NewField(FieldChatHistoryTime, oldest[:]),
+		),
+	}
+}
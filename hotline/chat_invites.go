@@ -0,0 +1,83 @@
+package hotline
+
+import (
+	"golang.org/x/time/rate"
+	"time"
+)
+
+// Private chat invitations are rate limited per client to a burst of chatInviteBurst, then one every two seconds.
+const (
+	chatInviteRate  = rate.Limit(0.5)
+	chatInviteBurst = 5
+)
+
+// AllowChatInvite returns true if the client may send another private chat invitation.
+func (cc *ClientConn) AllowChatInvite() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.inviteLimiter == nil {
+		cc.inviteLimiter = rate.NewLimiter(chatInviteRate, chatInviteBurst)
+	}
+
+	return cc.inviteLimiter.Allow()
+}
+
+// BlocksChatInvitesFrom returns true if the client's account blocks private chat invitations from inviter, either
+// because inviter is ignored or because invitations from non-admins are blocked.
+func (cc *ClientConn) BlocksChatInvitesFrom(inviter *ClientConn) bool {
+	if cc.Ignores(inviter) {
+		return true
+	}
+
+	return cc.Account != nil && cc.Account.BlockChatInvites && !inviter.Authorize(AccessDisconUser)
+}
+
+// login returns the login of the client's account, or "" if the client hasn't logged in.
+func (cc *ClientConn) login() string {
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	if cc.Account == nil {
+		return ""
+	}
+
+	return cc.Account.Login
+}
+
+// defaultChatInviteGracePeriod is how long unanswered private chat invitations are sent again to users who reconnect.
+const defaultChatInviteGracePeriod = 5 * time.Minute
+
+// chatInviteGracePeriod returns how long unanswered invitations are sent again to users who reconnect, or 0 if they
+// aren't.
+func (s *Server) chatInviteGracePeriod() time.Duration {
+	switch {
+	case s.Config.PrivateChats.InviteGracePeriod < 0:
+		return 0
+	case s.Config.PrivateChats.InviteGracePeriod == 0:
+		return defaultChatInviteGracePeriod
+	}
+	return s.Config.PrivateChats.InviteGracePeriod
+}
+
+// RedeliverChatInvites returns the private chat invitations sent to the user within the invitation grace period that
+// haven't been answered, for users who just logged in.  This includes invitations sent before the user reconnected,
+// e.g. after a dropped connection, and invitations back to the private chats the user was in before a server restart.
+func (cc *ClientConn) RedeliverChatInvites() (res []Transaction) {
+	grace := cc.Server.chatInviteGracePeriod()
+	if grace == 0 || cc.Server.ChatMgr == nil {
+		return nil
+	}
+
+	for _, inv := range cc.Server.ChatMgr.PendingInvites(cc, time.Now().Add(-grace)) {
+		res = append(res, NewTransaction(
+			TranInviteToChat,
+			cc.ID,
+			NewField(FieldChatID, inv.ChatID[:]),
+			NewField(FieldUserName, []byte(inv.FromName)),
+			NewField(FieldUserID, inv.FromID[:]),
+		))
+	}
+
+	return res
+}
@@ -0,0 +1,31 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConn_AllowChatInvite(t *testing.T) {
+	cc := &ClientConn{}
+
+	for range chatInviteBurst {
+		assert.True(t, cc.AllowChatInvite())
+	}
+	assert.False(t, cc.AllowChatInvite())
+}
+
+func TestClientConn_BlocksChatInvitesFrom(t *testing.T) {
+	var adminAccess AccessBitmap
+	adminAccess.Set(AccessDisconUser)
+
+	admin := &ClientConn{Account: &Account{Access: adminAccess}}
+	user := &ClientConn{Account: &Account{}}
+
+	target := &ClientConn{Account: &Account{}}
+	assert.False(t, target.BlocksChatInvitesFrom(user))
+
+	target.Account.BlockChatInvites = true
+	assert.True(t, target.BlocksChatInvitesFrom(user))
+	assert.False(t, target.BlocksChatInvitesFrom(admin))
+}
@@ -3,8 +3,138 @@ package hotline
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
+func TestMemChatManager_Options(t *testing.T) {
+	cc1 := &ClientConn{ID: [2]byte{1}}
+	cc2 := &ClientConn{ID: [2]byte{2}}
+	cc3 := &ClientConn{ID: [2]byte{3}}
+
+	cm := NewMemChatManager()
+	chatID := cm.New(cc1, ChatOptions{MaxMembers: 2, InviteOnly: true})
+
+	// Only members can invite, and only invited users can join.
+	assert.ErrorIs(t, cm.Invite(chatID, cc3, cc2), ErrChatNotMember)
+	assert.ErrorIs(t, cm.Join(chatID, cc2), ErrChatNotInvited)
+	assert.NoError(t, cm.Invite(chatID, cc1, cc2))
+	assert.NoError(t, cm.Join(chatID, cc2))
+
+	// The chat is full.
+	assert.ErrorIs(t, cm.Invite(chatID, cc1, cc3), ErrChatFull)
+
+	cm.SetOptions(chatID, ChatOptions{MaxMembers: 3, InviteOnly: true})
+	assert.Equal(t, ChatOptions{MaxMembers: 3, InviteOnly: true}, cm.Options(chatID))
+	assert.NoError(t, cm.Invite(chatID, cc2, cc3))
+	assert.NoError(t, cm.Join(chatID, cc3))
+	assert.Equal(t, []*ClientConn{cc1, cc2, cc3}, cm.Members(chatID))
+
+	// Without invite-only, anyone can invite and join.
+	openID := cm.New(cc1, ChatOptions{})
+	assert.NoError(t, cm.Invite(openID, cc3, cc2))
+	assert.NoError(t, cm.Join(openID, cc3))
+}
+
+func TestMemChatManager_PendingInvites(t *testing.T) {
+	alice := &ClientConn{ID: [2]byte{1}, UserName: []byte("Alice"), Account: &Account{Login: "alice"}, RemoteAddr: "10.0.0.1:5000"}
+	bob := &ClientConn{ID: [2]byte{2}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5000"}
+
+	cm := NewMemChatManager()
+	chatID := cm.New(alice, ChatOptions{InviteOnly: true})
+	assert.NoError(t, cm.Invite(chatID, alice, bob))
+
+	// Bob reconnects as a new client, and is sent the invitation again.
+	bob2 := &ClientConn{ID: [2]byte{3}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5001"}
+	since := time.Now().Add(-time.Minute)
+	invites := cm.PendingInvites(bob2, since)
+	if assert.Len(t, invites, 1) {
+		assert.Equal(t, chatID, invites[0].ChatID)
+		assert.Equal(t, bob2.ID, invites[0].ClientID)
+		assert.Equal(t, "Alice", invites[0].FromName)
+		assert.Equal(t, alice.ID, invites[0].FromID)
+	}
+
+	// Invitations are sent once per connection, and only to the same login and name from the same address.
+	assert.Empty(t, cm.PendingInvites(bob2, since))
+	assert.Empty(t, cm.PendingInvites(&ClientConn{ID: [2]byte{4}, UserName: []byte("Bob"), Account: &Account{Login: "bobby"}, RemoteAddr: "10.0.0.2:5002"}, since))
+	assert.Empty(t, cm.PendingInvites(&ClientConn{ID: [2]byte{5}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.9:5000"}, since))
+	assert.Empty(t, cm.PendingInvites(&ClientConn{ID: [2]byte{5}, UserName: []byte("Bob")}, time.Now()))
+
+	// The new client can join the invite-only chat, which answers the invitation.
+	assert.NoError(t, cm.Join(chatID, bob2))
+	assert.Empty(t, cm.PendingInvites(&ClientConn{ID: [2]byte{6}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5003"}, since))
+
+	// Declined invitations aren't sent again.
+	otherID := cm.New(alice, ChatOptions{})
+	assert.NoError(t, cm.Invite(otherID, alice, bob))
+	cm.DeclineInvite(otherID, bob.ID)
+	assert.Empty(t, cm.PendingInvites(bob2, since))
+}
+
+func TestMemChatManager_PendingInvites_guest(t *testing.T) {
+	alice := &ClientConn{ID: [2]byte{1}, UserName: []byte("Alice"), Account: &Account{Login: "alice"}, RemoteAddr: "10.0.0.1:5000"}
+	guest := &ClientConn{ID: [2]byte{2}, UserName: []byte("Bob"), Account: &Account{Login: GuestAccount}, RemoteAddr: "10.0.0.2:5000"}
+
+	cm := NewMemChatManager()
+	chatID := cm.New(alice, ChatOptions{InviteOnly: true})
+	assert.NoError(t, cm.Invite(chatID, alice, guest))
+
+	// Anyone can log in as a guest with the same name, so invitations to guests aren't sent again.
+	guest2 := &ClientConn{ID: [2]byte{3}, UserName: []byte("Bob"), Account: &Account{Login: GuestAccount}, RemoteAddr: "10.0.0.2:5001"}
+	assert.Empty(t, cm.PendingInvites(guest2, time.Now().Add(-time.Minute)))
+	assert.ErrorIs(t, cm.Join(chatID, guest2), ErrChatNotInvited)
+}
+
+func TestMemChatManager_RestoreChats(t *testing.T) {
+	alice := &ClientConn{ID: [2]byte{1}, UserName: []byte("Alice"), Account: &Account{Login: "alice"}, RemoteAddr: "10.0.0.1:5000"}
+	bob := &ClientConn{ID: [2]byte{2}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5000"}
+	carol := &ClientConn{ID: [2]byte{3}, UserName: []byte("Carol"), Account: &Account{Login: "carol"}, RemoteAddr: "10.0.0.3:5000"}
+
+	cm := NewMemChatManager()
+	chatID := cm.New(alice, ChatOptions{MaxMembers: 5, InviteOnly: true})
+	cm.SetSubject(chatID, "Plans")
+	assert.NoError(t, cm.Invite(chatID, alice, bob))
+	assert.NoError(t, cm.Join(chatID, bob))
+	assert.NoError(t, cm.Invite(chatID, alice, carol))
+
+	saved := cm.SavedChats()
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, chatID, saved[0].ID)
+		assert.Equal(t, "Plans", saved[0].Subject)
+		assert.Equal(t, ChatOptions{MaxMembers: 5, InviteOnly: true}, saved[0].Options)
+		assert.Equal(t, []ChatMember{
+			{Login: "alice", Name: "Alice", IP: "10.0.0.1"},
+			{Login: "bob", Name: "Bob", IP: "10.0.0.2"},
+		}, saved[0].Members)
+		assert.Len(t, saved[0].Invites, 1)
+	}
+
+	// After a restart, members are invited back to the chat, and outstanding invitations are kept.
+	restored := NewMemChatManager()
+	restored.RestoreChats(saved)
+	assert.Equal(t, "Plans", restored.GetSubject(chatID))
+	assert.Empty(t, restored.Members(chatID))
+
+	since := time.Now().Add(-time.Minute)
+
+	// Only the former member's address gets the invitation.
+	assert.Empty(t, restored.PendingInvites(&ClientConn{ID: [2]byte{6}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.9:5000"}, since))
+
+	newBob := &ClientConn{ID: [2]byte{7}, UserName: []byte("Bob"), Account: &Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5001"}
+	invites := restored.PendingInvites(newBob, since)
+	if assert.Len(t, invites, 1) {
+		assert.Equal(t, chatID, invites[0].ChatID)
+		assert.Equal(t, "Alice", invites[0].FromName)
+	}
+	assert.NoError(t, restored.Join(chatID, newBob))
+	assert.Len(t, restored.PendingInvites(&ClientConn{ID: [2]byte{8}, UserName: []byte("Carol"), Account: &Account{Login: "carol"}, RemoteAddr: "10.0.0.3:5001"}, since), 1)
+
+	// Restored invitations expire after the grace period.
+	newAlice := &ClientConn{ID: [2]byte{9}, UserName: []byte("Alice"), Account: &Account{Login: "alice"}, RemoteAddr: "10.0.0.1:5001"}
+	assert.Empty(t, restored.PendingInvites(newAlice, time.Now().Add(time.Minute)))
+	assert.Empty(t, restored.PendingInvites(newAlice, since))
+}
+
 func TestMemChatManager(t *testing.T) {
 	cc1 := &ClientConn{ID: [2]byte{1}}
 	cc2 := &ClientConn{ID: [2]byte{2}}
@@ -12,11 +142,11 @@ func TestMemChatManager(t *testing.T) {
 	cm := NewMemChatManager()
 
 	// Create a new chat with cc1 as initial member.
-	randChatID := cm.New(cc1)
+	randChatID := cm.New(cc1, ChatOptions{})
 	assert.Equalf(t, []*ClientConn{cc1}, cm.Members(randChatID), "Initial ChatMembers")
 
 	// Second client joins.
-	cm.Join(randChatID, cc2)
+	assert.NoError(t, cm.Join(randChatID, cc2))
 	assert.Equalf(t, []*ClientConn{cc1, cc2}, cm.Members(randChatID), "ChatMembers")
 
 	// Initial subject is blank.
@@ -30,6 +160,9 @@ func TestMemChatManager(t *testing.T) {
 	cm.Leave(randChatID, cc2.ID)
 	assert.Equalf(t, []*ClientConn{cc1}, cm.Members(randChatID), "ChatMembers")
 
+	assert.ErrorIs(t, cm.Join(ChatID{}, cc2), ErrChatNotFound)
+	assert.Empty(t, cm.Members(ChatID{}))
+
 	//
 	//type fields struct {
 	//	chats map[ChatID]*PrivateChat
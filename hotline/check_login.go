@@ -0,0 +1,37 @@
+package hotline
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrIncorrectLogin is returned by CheckLogin for unknown logins, wrong passwords, and addresses the account isn't
+// allowed to log in from.
+var ErrIncorrectLogin = errors.New("incorrect login")
+
+// ErrTooManyLogins is returned by CheckLogin when ip has exceeded the login rate limit or is banned.
+var ErrTooManyLogins = errors.New("too many login attempts")
+
+// CheckLogin authenticates a login from ip outside of the Hotline protocol, such as a web chat client, and returns
+// the account for the session.  The password is plain text.  Logins are subject to the same rate limits, automatic
+// bans, and address restrictions as Hotline logins.
+func (s *Server) CheckLogin(ip, login, password string) (*Account, error) {
+	if banned, _ := s.BanList.IsBanned(ip); banned || !s.allowLoginAttempt(ip) {
+		return nil, ErrTooManyLogins
+	}
+
+	account := s.loginAccount(login)
+	if account == nil || !account.AddressAllowed(ip) {
+		s.recordFailedLogin(ip, time.Now())
+		return nil, ErrIncorrectLogin
+	}
+
+	cc := ClientConn{Server: s}
+	if !cc.Authenticate(login, EncodeString([]byte(password))) {
+		s.recordFailedLogin(ip, time.Now())
+		return nil, ErrIncorrectLogin
+	}
+	s.clearFailedLogins(ip)
+
+	return account, nil
+}
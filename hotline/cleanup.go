@@ -0,0 +1,156 @@
+package hotline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultCleanupInterval = 24 * time.Hour
+
+// CleanupRule deletes files in a folder of the file root on a schedule.
+//
+// Example rules:
+//
+//	# Delete uploads older than 30 days
+//	- Folder: Uploads
+//	  MaxAge: 720h
+//	# Purge abandoned partial uploads anywhere
+//	- Pattern: "*.incomplete"
+//	  MaxAge: 24h
+//	  Interval: 1h
+//	# Empty the drop box weekly
+//	- Folder: Drop Box
+//	  Interval: 168h
+type CleanupRule struct {
+	Folder   string        `yaml:"Folder"`   // Folder relative to the FileRoot; empty for the entire FileRoot
+	Pattern  string        `yaml:"Pattern"`  // Optional glob pattern matched against file names, e.g. "*.incomplete"
+	MaxAge   time.Duration `yaml:"MaxAge"`   // Delete files not modified for longer than MaxAge; 0 deletes all matching files
+	Interval time.Duration `yaml:"Interval"` // How often to run the rule; defaults to 24h
+	DryRun   bool          `yaml:"DryRun"`   // Log the files that would be deleted without deleting them
+}
+
+// Validate returns an error if the rule is malformed, or if it has no Folder, Pattern, or MaxAge to limit it and so
+// would delete every file in the FileRoot.
+func (r CleanupRule) Validate() error {
+	if _, err := filepath.Match(r.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid cleanup pattern %q: %w", r.Pattern, err)
+	}
+	if r.MaxAge < 0 {
+		return fmt.Errorf("cleanup rule for %q has a negative MaxAge", r.Folder)
+	}
+	if filepath.Join("/", r.Folder) == filepath.Clean("/") && r.Pattern == "" && r.MaxAge == 0 {
+		return errors.New("cleanup rule needs a Folder, Pattern, or MaxAge; it would delete every file")
+	}
+
+	return nil
+}
+
+// RunCleanupRule deletes the files matched by rule and returns their paths relative to the FileRoot.  Hidden
+// files are skipped, but the info and resource forks of deleted files are removed along with them.  If dryRun or
+// rule.DryRun is set, the matched files are returned without being deleted.
+func (s *Server) RunCleanupRule(rule CleanupRule, dryRun bool) ([]string, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	fileRoot := s.Config.FileRoot
+	folder := filepath.Join(fileRoot, filepath.Join("/", rule.Folder))
+	dryRun = dryRun || rule.DryRun
+	cutoff := time.Now().Add(-rule.MaxAge)
+
+	var matched []string
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		if rule.Pattern != "" {
+			if ok, _ := filepath.Match(rule.Pattern, d.Name()); !ok {
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(fileRoot, path)
+		matched = append(matched, filepath.ToSlash(rel))
+
+		if dryRun {
+			s.Logger.Info("Cleanup dry run: would delete file", "path", rel)
+			return nil
+		}
+
+		fw, err := NewFileWrapper(s.FS, path, 0)
+		if err != nil {
+			return err
+		}
+		if err := fw.Delete(); err != nil {
+			return fmt.Errorf("delete %s: %w", rel, err)
+		}
+		s.FileIndex.Invalidate(path)
+		if strings.HasSuffix(path, IncompleteFileSuffix) {
+			s.untrackIncompleteUpload(strings.TrimSuffix(path, IncompleteFileSuffix))
+		}
+
+		s.Logger.Info("Cleanup deleted file", "path", rel)
+
+		return nil
+	})
+
+	return matched, err
+}
+
+// RunCleanup runs all configured cleanup rules and returns the paths of the deleted files.
+func (s *Server) RunCleanup(dryRun bool) ([]string, error) {
+	var deleted []string
+	for _, rule := range s.Config.CleanupRules {
+		paths, err := s.RunCleanupRule(rule, dryRun)
+		deleted = append(deleted, paths...)
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// cleanupScheduler runs each cleanup rule at its configured interval until ctx is cancelled.
+func (s *Server) cleanupScheduler(ctx context.Context) {
+	for _, rule := range s.Config.CleanupRules {
+		go func() {
+			interval := rule.Interval
+			if interval <= 0 {
+				interval = defaultCleanupInterval
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if _, err := s.RunCleanupRule(rule, false); err != nil {
+						s.Logger.Error("Error running cleanup rule", "folder", rule.Folder, "err", err)
+					}
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,75 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServer_RunCleanupRule(t *testing.T) {
+	root := t.TempDir()
+	uploads := filepath.Join(root, "Uploads")
+	assert.NoError(t, os.MkdirAll(filepath.Join(uploads, "sub"), 0777))
+
+	old := time.Now().Add(-48 * time.Hour)
+	writeFile := func(path string, mtime time.Time) {
+		assert.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+		assert.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	writeFile(filepath.Join(uploads, "old.sit"), old)
+	writeFile(filepath.Join(uploads, ".info_old.sit"), old)
+	writeFile(filepath.Join(uploads, "new.sit"), time.Now())
+	writeFile(filepath.Join(uploads, "sub", "partial.sit.incomplete"), old)
+	writeFile(filepath.Join(root, "outside.sit"), old)
+
+	s := &Server{
+		FS:     &OSFileStore{},
+		Logger: slog.Default(),
+		Config: Config{FileRoot: root},
+	}
+
+	rule := CleanupRule{Folder: "Uploads", MaxAge: 24 * time.Hour}
+
+	matched, err := s.RunCleanupRule(rule, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Uploads/old.sit", "Uploads/sub/partial.sit.incomplete"}, matched)
+	assert.FileExists(t, filepath.Join(uploads, "old.sit"))
+
+	matched, err = s.RunCleanupRule(CleanupRule{Pattern: "*.incomplete", MaxAge: 24 * time.Hour}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Uploads/sub/partial.sit.incomplete"}, matched)
+	assert.NoFileExists(t, filepath.Join(uploads, "sub", "partial.sit.incomplete"))
+
+	matched, err = s.RunCleanupRule(rule, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Uploads/old.sit"}, matched)
+	assert.NoFileExists(t, filepath.Join(uploads, "old.sit"))
+	assert.NoFileExists(t, filepath.Join(uploads, ".info_old.sit"))
+	assert.FileExists(t, filepath.Join(uploads, "new.sit"))
+	assert.FileExists(t, filepath.Join(root, "outside.sit"))
+}
+
+func TestCleanupRule_Validate(t *testing.T) {
+	assert.NoError(t, CleanupRule{Folder: "Drop Box"}.Validate())
+	assert.NoError(t, CleanupRule{Pattern: "*.incomplete"}.Validate())
+	assert.NoError(t, CleanupRule{MaxAge: time.Hour}.Validate())
+
+	// Rules that would delete every file in the FileRoot are rejected.
+	assert.Error(t, CleanupRule{}.Validate())
+	assert.Error(t, CleanupRule{Folder: "/"}.Validate())
+	assert.Error(t, CleanupRule{Folder: "Uploads/.."}.Validate())
+
+	assert.Error(t, CleanupRule{Pattern: "["}.Validate())
+	assert.Error(t, CleanupRule{Folder: "Uploads", MaxAge: -time.Hour}.Validate())
+
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file.sit"), []byte("data"), 0644))
+	s := &Server{FS: &OSFileStore{}, Logger: slog.Default(), Config: Config{FileRoot: root}}
+
+	_, err := s.RunCleanupRule(CleanupRule{}, false)
+	assert.Error(t, err)
+	assert.FileExists(t, filepath.Join(root, "file.sit"))
+}
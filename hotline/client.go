@@ -6,17 +6,30 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"golang.org/x/net/proxy"
 	"io"
 	"log/slog"
 	"net"
+	"net/url"
+	"strconv"
 	"time"
 )
 
+// Bookmark is a saved server that the client can connect to.
+type Bookmark struct {
+	Name     string `yaml:"Name"`
+	Addr     string `yaml:"Addr"`
+	Login    string `yaml:"Login"`
+	Password string `yaml:"Password"`
+	Proxy    string `yaml:"Proxy,omitempty"` // Optional SOCKS5 proxy URL to connect through, e.g. "socks5://127.0.0.1:9050" for Tor
+}
+
 type ClientPrefs struct {
-	Username   string `yaml:"Username"`
-	IconID     int    `yaml:"IconID"`
-	Tracker    string `yaml:"Tracker"`
-	EnableBell bool   `yaml:"EnableBell"`
+	Username   string     `yaml:"Username"`
+	IconID     int        `yaml:"IconID"`
+	Bookmarks  []Bookmark `yaml:"Bookmarks"`
+	Tracker    string     `yaml:"Tracker"`
+	EnableBell bool       `yaml:"EnableBell"`
 }
 
 func (cp *ClientPrefs) IconBytes() []byte {
@@ -32,6 +45,9 @@ type Client struct {
 	Handlers    map[[2]byte]ClientHandler
 	activeTasks map[[4]byte]*Transaction
 	UserList    []User
+
+	Proxy      string // SOCKS5 proxy URL that Dial connects through, set from the bookmark being connected to
+	serverAddr string // Address of the connected server, for file transfer connections
 }
 
 type ClientHandler func(context.Context, *Client, *Transaction) ([]Transaction, error)
@@ -64,13 +80,21 @@ type ClientTHandler interface {
 	Handle(*Client, *Transaction) ([]Transaction, error)
 }
 
+// ConnectBookmark connects to the server saved in b, through the bookmark's proxy if it has one.
+func (c *Client) ConnectBookmark(b Bookmark) error {
+	c.Proxy = b.Proxy
+
+	return c.Connect(b.Addr, b.Login, b.Password)
+}
+
 // JoinServer connects to a Hotline server and completes the login flow
 func (c *Client) Connect(address, login, passwd string) (err error) {
 	// Establish TCP connection to server
-	c.Connection, err = net.DialTimeout("tcp", address, 5*time.Second)
+	c.Connection, err = c.Dial(address)
 	if err != nil {
 		return err
 	}
+	c.serverAddr = address
 
 	// Send handshake sequence
 	if err := c.Handshake(); err != nil {
@@ -98,6 +122,72 @@ func (c *Client) Connect(address, login, passwd string) (err error) {
 	return nil
 }
 
+const dialTimeout = 5 * time.Second
+
+// Dial opens a TCP connection to address, through the client's SOCKS5 Proxy if one is set.  Every connection the client
+// makes goes through Dial, so that file transfers take the same path as the connection to the server.
+func (c *Client) Dial(address string) (net.Conn, error) {
+	if c.Proxy == "" {
+		return net.DialTimeout("tcp", address, dialTimeout)
+	}
+
+	u, err := url.Parse(c.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy URL: %w", err)
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	dialer, err := proxy.FromURL(u, &net.Dialer{Timeout: dialTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("create proxy dialer: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	// The SOCKS5 dialers returned by FromURL support contexts, which bound the time spent on the proxy handshake.
+	var conn net.Conn
+	if cd, ok := dialer.(proxy.ContextDialer); ok {
+		conn, err = cd.DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect through proxy %s: %w", u.Host, err)
+	}
+
+	return conn, nil
+}
+
+// DialTransfer opens the file transfer connection for the transfer with refNum, whose reference number and size were
+// sent by the server in reply to a download or upload request.  The file transfer port is the one after the server's.
+func (c *Client) DialTransfer(refNum [4]byte, dataSize uint32) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(c.serverAddr)
+	if err != nil {
+		return nil, fmt.Errorf("parse server address: %w", err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("parse server port: %w", err)
+	}
+
+	conn, err := c.Dial(net.JoinHostPort(host, strconv.Itoa(portNum+1)))
+	if err != nil {
+		return nil, err
+	}
+
+	header := transfer{Protocol: HTXF, ReferenceNumber: refNum}
+	binary.BigEndian.PutUint32(header.DataSize[:], dataSize)
+	if err := binary.Write(conn, binary.BigEndian, header); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send transfer header: %w", err)
+	}
+
+	return conn, nil
+}
+
 const keepaliveInterval = 300 * time.Second
 
 func (c *Client) keepalive() error {
@@ -198,6 +288,7 @@ func (c *Client) HandleTransactions(ctx context.Context) error {
 	// Create a new scanner for parsing incoming bytes into transaction tokens
 	scanner := bufio.NewScanner(c.Connection)
 	scanner.Split(transactionScanner)
+	scanner.Buffer(nil, maxTransactionSize)
 
 	// Scan for new transactions and handle them as they come in.
 	for scanner.Scan() {
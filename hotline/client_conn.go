@@ -4,11 +4,12 @@ import (
 	"cmp"
 	"encoding/binary"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
 	"io"
 	"log/slog"
 	"strings"
 	"sync"
+	"time"
 )
 
 var clientConnSortFunc = func(a, b *ClientConn) int {
@@ -23,6 +24,8 @@ type ClientConn struct {
 	Connection io.ReadWriteCloser
 	RemoteAddr string
 	ID         ClientID
+	ConnID     uint64 // Unique ID of the connection, included in all log messages for the client
+	LoginTime  time.Time
 	Icon       []byte // TODO: make fixed size of 2
 	Version    []byte // TODO: make fixed size of 2
 
@@ -39,6 +42,19 @@ type ClientConn struct {
 
 	Logger *slog.Logger
 
+	confirmation  *pendingConfirmation // outstanding confirmation challenge, guarded by mu
+	inviteLimiter *rate.Limiter        // rate limit of private chat invitations, guarded by mu
+	awayReason    string               // reason set by the user for being away, guarded by mu
+	capabilities  []string             // optional features enabled by TranNegotiateCapabilities, guarded by mu
+	prevLogin     time.Time            // previous login of the user, for showing missed broadcasts
+
+	tranLimiters map[string]*rate.Limiter // rate limits of transactions by RateLimits key, guarded by mu
+
+	malformedLimiter *rate.Limiter // rate limit of replies to malformed transactions, guarded by mu
+	malformedLogged  bool          // a malformed transaction has been logged, guarded by mu
+
+	loginHoursTimer *time.Timer // disconnects the client after its login window closed, guarded by mu
+
 	mu sync.RWMutex
 }
 
@@ -49,6 +65,15 @@ func (cc *ClientConn) FileRoot() string {
 	return cc.Server.Config.FileRoot
 }
 
+// TransferPriority returns the scheduling class for the client's file transfers.  Admins and accounts with
+// PriorityTransfers enabled are given high priority.
+func (cc *ClientConn) TransferPriority() TransferPriority {
+	if cc.Account.PriorityTransfers || cc.Authorize(AccessDisconUser) {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
 type ClientFileTransferMgr struct {
 	transfers map[FileTransferType]map[FileTransferID]*FileTransfer
 
@@ -102,42 +127,97 @@ func (cc *ClientConn) SendAll(t [2]byte, fields ...Field) {
 }
 
 func (cc *ClientConn) handleTransaction(transaction Transaction) {
+	// Any transaction other than a keepalive is user activity.  Clear the idle flag before handling it, so that other
+	// users see the user as active by the time e.g. their chat message arrives.
+	if transaction.Type != TranKeepAlive {
+		cc.mu.Lock()
+		cc.IdleTime = 0
+		cc.mu.Unlock()
+
+		cc.setIdle(false)
+	}
+
 	if handler, ok := cc.Server.handlers[transaction.Type]; ok {
 		if transaction.Type != TranKeepAlive {
 			cc.Logger.Info(tranTypeNames[transaction.Type])
 		}
 
-		for _, t := range handler(cc, &transaction) {
+		if !cc.allowTransaction(transaction.Type) {
+			cc.rateLimited(&transaction)
+			return
+		}
+
+		span := cc.Server.startSpan("hotline.transaction", map[string]any{
+			"hotline.transaction.type": tranTypeNames[transaction.Type],
+			"hotline.client.id":        binary.BigEndian.Uint16(cc.ID[:]),
+			"hotline.conn.id":          cc.ConnID,
+		})
+
+		// Requests without a required privilege are denied by the handler, and counted as probes for privileges.
+		for _, access := range cc.Server.RequiredAccess(transaction.Type) {
+			if !cc.Authorize(access) {
+				cc.recordViolation(ViolationAccessDenied, tranTypeNames[transaction.Type])
+				break
+			}
+		}
+
+		replies := handler(cc, &transaction)
+		span.SetAttr("hotline.transaction.replies", len(replies))
+		span.End(nil)
+
+		if transaction.Type != TranKeepAlive {
+			cc.auditTransaction(&transaction, replies)
+		}
+
+		for _, t := range replies {
 			cc.Server.outbox <- t
 		}
 	}
+}
 
-	if transaction.Type != TranKeepAlive {
-		cc.mu.Lock()
-		defer cc.mu.Unlock()
+// setIdle sets or clears the user's idle flag, and notifies all connected clients when it changes so that user lists
+// show who is idle.  The flag stays set while the user has an away reason.
+func (cc *ClientConn) setIdle(idle bool) {
+	if !idle && cc.AwayReason() != "" {
+		return
+	}
 
-		// reset the user idle timer
-		cc.IdleTime = 0
+	var val uint
+	if idle {
+		val = 1
+	}
 
-		// if user was previously idle, mark as not idle and notify other connected clients that
-		// the user is no longer away
-		if cc.Flags.IsSet(UserFlagAway) {
-			cc.Flags.Set(UserFlagAway, 0)
-
-			cc.SendAll(
-				TranNotifyChangeUser,
-				NewField(FieldUserID, cc.ID[:]),
-				NewField(FieldUserFlags, cc.Flags[:]),
-				NewField(FieldUserName, cc.UserName),
-				NewField(FieldUserIconID, cc.Icon),
-			)
-		}
+	cc.FlagsMU.Lock()
+	if cc.Flags.IsSet(UserFlagAway) == idle {
+		cc.FlagsMU.Unlock()
+		return
 	}
+	cc.Flags.Set(UserFlagAway, val)
+	flags := cc.Flags
+	cc.FlagsMU.Unlock()
+
+	cc.SendAll(
+		TranNotifyChangeUser,
+		NewField(FieldUserID, cc.ID[:]),
+		NewField(FieldUserFlags, flags[:]),
+		NewField(FieldUserName, cc.UserName),
+		NewField(FieldUserIconID, cc.Icon),
+	)
 }
 
 func (cc *ClientConn) Authenticate(login string, password []byte) bool {
+	// Guests log in without a password under the Guests policy.
+	if login == GuestAccount && cc.Server.Config.Guests != nil {
+		return cc.Server.Config.Guests.Allow
+	}
+
 	if account := cc.Server.AccountManager.Get(login); account != nil {
-		return bcrypt.CompareHashAndPassword([]byte(account.Password), password) == nil
+		if !CheckPassword(account.Password, password) {
+			return false
+		}
+		cc.Server.rehashPassword(account, password)
+
+		return true
 	}
 
 	return false
@@ -158,9 +238,12 @@ func (cc *ClientConn) Disconnect() {
 	for _, t := range cc.NotifyOthers(NewTransaction(TranNotifyDeleteUser, [2]byte{}, NewField(FieldUserID, cc.ID[:]))) {
 		cc.Server.outbox <- t
 	}
+	if cc.Account != nil && len(cc.UserName) > 0 {
+		cc.Server.ChatFeed.Leave(cc.UserName)
+	}
 
 	if err := cc.Connection.Close(); err != nil {
-		cc.Server.Logger.Debug("error closing client connection", "RemoteAddr", cc.RemoteAddr)
+		cc.Server.Logger.Debug("error closing client connection", "connID", cc.ConnID, "RemoteAddr", cc.RemoteAddr)
 	}
 }
 
@@ -185,8 +268,24 @@ func (cc *ClientConn) NewReply(t *Transaction, fields ...Field) Transaction {
 	}
 }
 
-// NewErrReply returns an error reply Transaction with errMsg
+// NewErrReply returns an error reply Transaction with errMsg, replaced by its entry in the server message catalog if
+// one exists.
 func (cc *ClientConn) NewErrReply(t *Transaction, errMsg string) []Transaction {
+	return cc.errReply(t, cc.T(errMsg))
+}
+
+// NewErrReplyf returns an error reply Transaction with the message format, replaced by its entry in the server message
+// catalog if one exists, formatted with args.
+func (cc *ClientConn) NewErrReplyf(t *Transaction, format string, args ...any) []Transaction {
+	text := cc.T(format)
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+
+	return cc.errReply(t, text)
+}
+
+func (cc *ClientConn) errReply(t *Transaction, text string) []Transaction {
 	return []Transaction{
 		{
 			ClientID:  cc.ID,
@@ -194,7 +293,7 @@ func (cc *ClientConn) NewErrReply(t *Transaction, errMsg string) []Transaction {
 			ID:        t.ID,
 			ErrorCode: [4]byte{0, 0, 0, 1},
 			Fields: []Field{
-				NewField(FieldError, []byte(errMsg)),
+				NewField(FieldError, []byte(text)),
 			},
 		},
 	}
@@ -204,7 +303,7 @@ const userInfoTemplate = `Nickname:   %s
 Name:       %s
 Account:    %s
 Address:    %s
-
+%s
 -------- File Downloads ---------
 
 %s
@@ -222,6 +321,14 @@ Address:    %s
 %s
 `
 
+// formatAway returns the away line of the user info text, which is omitted for users who aren't away.
+func formatAway(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return "Away:       " + ToMacRoman(reason) + "\n"
+}
+
 func formatDownloadList(fts []FileTransfer) (s string) {
 	if len(fts) == 0 {
 		return "None.\n"
@@ -241,6 +348,7 @@ func (cc *ClientConn) String() string {
 		cc.Account.Name,
 		cc.Account.Login,
 		cc.RemoteAddr,
+		formatAway(cc.AwayReason()),
 		formatDownloadList(cc.ClientFileTransferMgr.Get(FileDownload)),
 		formatDownloadList(cc.ClientFileTransferMgr.Get(FolderDownload)),
 		formatDownloadList(cc.ClientFileTransferMgr.Get(FileUpload)),
@@ -0,0 +1,142 @@
+package hotline
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveSOCKS5 accepts one connection on ln, completes a SOCKS5 handshake without authentication, and relays the
+// connection to the requested address, which is sent on requested.
+func serveSOCKS5(ln net.Listener, requested chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Greeting: version, number of methods, methods.
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte{5, 0})
+
+	// Request: version, command, reserved, address type, address, port.
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		_, _ = io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3:
+		_, _ = io.ReadFull(conn, buf[:1])
+		n := int(buf[0])
+		_, _ = io.ReadFull(conn, buf[:n])
+		host = string(buf[:n])
+	}
+	_, _ = io.ReadFull(conn, buf[:2])
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+	requested <- addr
+
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go func() { _, _ = io.Copy(target, conn) }()
+	_, _ = io.Copy(conn, target)
+}
+
+func TestClient_Dial_Proxy(t *testing.T) {
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+	go func() {
+		conn, err := server.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("TRTP"))
+		_ = conn.Close()
+	}()
+
+	socks, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer socks.Close()
+	requested := make(chan string, 1)
+	go serveSOCKS5(socks, requested)
+
+	c := NewClient("test", NewTestLogger())
+	c.Proxy = "socks5://" + socks.Addr().String()
+
+	conn, err := c.Dial(server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, server.Addr().String(), <-requested)
+	got, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "TRTP", string(got))
+}
+
+func TestClient_Dial_UnsupportedProxy(t *testing.T) {
+	c := NewClient("test", NewTestLogger())
+	c.Proxy = "http://127.0.0.1:8080"
+
+	_, err := c.Dial("127.0.0.1:5500")
+	assert.ErrorContains(t, err, "unsupported proxy scheme")
+}
+
+func TestClient_DialTransfer_Proxy(t *testing.T) {
+	// The file transfer port is the one after the server port.
+	transferLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer transferLn.Close()
+	headers := make(chan transfer, 1)
+	go func() {
+		conn, err := transferLn.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var header transfer
+		if err := binary.Read(conn, binary.BigEndian, &header); err == nil {
+			headers <- header
+		}
+	}()
+
+	socks, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer socks.Close()
+	requested := make(chan string, 1)
+	go serveSOCKS5(socks, requested)
+
+	c := NewClient("test", NewTestLogger())
+	c.Proxy = "socks5://" + socks.Addr().String()
+	c.serverAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(transferLn.Addr().(*net.TCPAddr).Port-1))
+
+	conn, err := c.DialTransfer([4]byte{1, 2, 3, 4}, 1024)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, transferLn.Addr().String(), <-requested)
+	header := <-headers
+	assert.Equal(t, HTXF, header.Protocol)
+	assert.Equal(t, [4]byte{1, 2, 3, 4}, header.ReferenceNumber)
+	assert.Equal(t, [4]byte{0, 0, 0x04, 0}, header.DataSize)
+}
@@ -1,18 +1,234 @@
 package hotline
 
+import "time"
+
 type Config struct {
-	Name                      string   `yaml:"Name" validate:"required,max=50"`         // Name used for Tracker registration
-	Description               string   `yaml:"Description" validate:"required,max=200"` // Description used for Tracker registration
-	BannerFile                string   `yaml:"BannerFile"`                              // Path to Banner jpg
-	FileRoot                  string   `yaml:"FileRoot" validate:"required"`            // Path to Files
-	EnableTrackerRegistration bool     `yaml:"EnableTrackerRegistration"`               // Toggle Tracker Registration
-	Trackers                  []string `yaml:"Trackers" validate:"dive,hostname_port"`  // List of trackers that the server should register with
-	NewsDelimiter             string   `yaml:"NewsDelimiter"`                           // String used to separate news posts
-	NewsDateFormat            string   `yaml:"NewsDateFormat"`                          // Go template string to customize news date format
-	MaxDownloads              int      `yaml:"MaxDownloads"`                            // Global simultaneous download limit
-	MaxDownloadsPerClient     int      `yaml:"MaxDownloadsPerClient"`                   // Per client simultaneous download limit
-	MaxConnectionsPerIP       int      `yaml:"MaxConnectionsPerIP"`                     // Max connections per IP
-	PreserveResourceForks     bool     `yaml:"PreserveResourceForks"`                   // Enable preservation of file info and resource forks in sidecar files
-	IgnoreFiles               []string `yaml:"IgnoreFiles"`                             // List of regular expression for filtering files from the file list
-	EnableBonjour             bool     `yaml:"EnableBonjour"`                           // Enable service announcement on local network with Bonjour
+	Name                      string             `yaml:"Name" validate:"required,max=50"`         // Name used for Tracker registration
+	Description               string             `yaml:"Description" validate:"required,max=200"` // Description used for Tracker registration
+	MOTD                      string             `yaml:"MOTD"`                                    // Message of the day sent to users after they log in; empty for none
+	BannerFile                string             `yaml:"BannerFile"`                              // Path to Banner jpg
+	FileRoot                  string             `yaml:"FileRoot" validate:"required"`            // Path to Files
+	EnableTrackerRegistration bool               `yaml:"EnableTrackerRegistration"`               // Toggle Tracker Registration
+	Trackers                  []string           `yaml:"Trackers" validate:"dive,hostname_port"`  // List of trackers that the server should register with
+	TrackerVersion            bool               `yaml:"TrackerVersion"`                          // Add the server version to the end of the description in tracker registrations
+	NewsDelimiter             string             `yaml:"NewsDelimiter"`                           // String used to separate news posts
+	NewsDateFormat            string             `yaml:"NewsDateFormat"`                          // Go template string to customize news date format
+	MaxDownloads              int                `yaml:"MaxDownloads"`                            // Global simultaneous download limit
+	MaxDownloadsPerClient     int                `yaml:"MaxDownloadsPerClient"`                   // Per client simultaneous download limit
+	MaxConnectionsPerIP       int                `yaml:"MaxConnectionsPerIP"`                     // Max simultaneous connections per IP address; 0 for no limit
+	PreserveResourceForks     bool               `yaml:"PreserveResourceForks"`                   // Enable preservation of file info and resource forks in sidecar files
+	NativeForks               bool               `yaml:"NativeForks"`                             // On macOS, keep resource forks and type/creator codes in the file system instead of sidecar files
+	IgnoreFiles               []string           `yaml:"IgnoreFiles"`                             // List of regular expression for filtering files from the file list
+	HiddenFiles               HiddenFiles        `yaml:"HiddenFiles"`                             // Glob patterns for files hidden from listings and downloads; defaults to ".*"
+	EnableBonjour             bool               `yaml:"EnableBonjour"`                           // Enable service announcement on local network with Bonjour
+	EnableAPIInfo             bool               `yaml:"EnableAPIInfo"`                           // Serve the server info at /api/v1/info of the HTTP API without authentication
+	ModeratedFolders          []string           `yaml:"ModeratedFolders"`                        // List of folders, relative to FileRoot, where uploads are held for approval
+	PendingUploadsDir         string             `yaml:"PendingUploadsDir"`                       // Path to uploads awaiting approval
+	QuarantineDir             string             `yaml:"QuarantineDir"`                           // Path that corrupt info and resource forks are moved to
+	CaseInsensitivePaths      bool               `yaml:"CaseInsensitivePaths"`                    // Match requested file paths to files in the FileRoot regardless of case
+	ConfineFiles              bool               `yaml:"ConfineFiles"`                            // Refuse file operations on paths that resolve outside the FileRoot, e.g. through symlinks
+	EnableThumbnails          bool               `yaml:"EnableThumbnails"`                        // Enable generation of preview images for uploaded pictures
+	ThumbnailSize             int                `yaml:"ThumbnailSize"`                           // Max width and height of generated thumbnails in pixels
+	EnableDedup               bool               `yaml:"EnableDedup"`                             // Enable deduplicated storage of uploaded file data
+	BlobDir                   string             `yaml:"BlobDir"`                                 // Path to content-addressed file data used by EnableDedup
+	MonthlyTransferCapMB      uint64             `yaml:"MonthlyTransferCapMB"`                    // Monthly file transfer limit in megabytes after which downloads are disabled; 0 for unlimited
+	MaxTransferRateKBps       int                `yaml:"MaxTransferRateKBps"`                     // Total file transfer bandwidth limit in kilobytes per second; 0 for unlimited
+	MaxDownloadKBps           int                `yaml:"MaxDownloadKBps" validate:"min=0"`        // Bandwidth limit of each download connection in kilobytes per second; 0 for unlimited
+	MaxUploadKBps             int                `yaml:"MaxUploadKBps" validate:"min=0"`          // Bandwidth limit of each upload connection in kilobytes per second; 0 for unlimited
+	PriorityTransferWeight    int                `yaml:"PriorityTransferWeight"`                  // Bandwidth share of high priority transfers relative to normal transfers
+	CleanupRules              []CleanupRule      `yaml:"CleanupRules"`                            // Scheduled rules for deleting old files
+	NewsAnnouncements         []NewsAnnouncement `yaml:"NewsAnnouncements"`                       // Per category announcement of new news articles
+	DigestNewsCategory        string             `yaml:"DigestNewsCategory"`                      // News category path to post a daily activity digest to; empty to disable
+	DigestHour                int                `yaml:"DigestHour" validate:"min=0,max=23"`      // Hour of the day to post the daily activity digest
+	Locale                    string             `yaml:"Locale"`                                  // Language of server messages, matching a file in the Locales config dir
+	MessageOverrides          map[string]string  `yaml:"MessageOverrides"`                        // Replacement text for specific built-in server messages
+	WatchConfigFiles          bool               `yaml:"WatchConfigFiles"`                        // Automatically reload the ban list, agreement, banner, and accounts when edited
+	WatchFileRoot             bool               `yaml:"WatchFileRoot"`                           // Update cached file listings and folder sizes as soon as files are changed outside the server
+	AccountCacheSize          int                `yaml:"AccountCacheSize"`                        // Max number of user accounts kept in memory
+	AccountStore              string             `yaml:"AccountStore"`                            // Where accounts are stored: "yaml" files in the Users dir (default) or a "sqlite" database
+	NewsStore                 string             `yaml:"NewsStore"`                               // Where threaded news is stored: the "yaml" ThreadedNews.yaml file (default) or a "sqlite" database
+	LogSyslog                 *SyslogConfig      `yaml:"LogSyslog"`                               // Optional syslog server to send logs to
+	LogJournald               bool               `yaml:"LogJournald"`                             // Send logs to the systemd journal
+	ErrorReporting            ErrorReporting     `yaml:"ErrorReporting"`                          // Optional reporting of crashes to Sentry or an OTLP collector
+	Tracing                   Tracing            `yaml:"Tracing"`                                 // Optional export of transaction and file transfer traces
+	FileTransferTTL           time.Duration      `yaml:"FileTransferTTL"`                         // How long a requested file transfer waits for the client to connect; defaults to 10m
+	EnableTransferEncryption  bool               `yaml:"EnableTransferEncryption"`                // Encrypt file transfers for clients that request it
+	ServerPassword            string             `yaml:"ServerPassword"`                          // Password required to connect in addition to account credentials; empty to disable
+	AnonymousGuest            bool               `yaml:"AnonymousGuest"`                          // Restrict guest logins to read-only access regardless of the guest account file
+	Guests                    *GuestPolicy       `yaml:"Guests"`                                  // Optional policy for guest logins that replaces the access of the guest account file
+	PasswordHashing           PasswordHashing    `yaml:"PasswordHashing"`                         // Algorithm and cost of account password hashes
+	ConfirmBatchDeletes       int                `yaml:"ConfirmBatchDeletes"`                     // Require confirmation to delete this many or more accounts at once; 0 to disable
+	NotifyUploads             bool               `yaml:"NotifyUploads"`                           // Send uploaders a server message when their upload has been saved or has failed
+	HandshakeTimeout          time.Duration      `yaml:"HandshakeTimeout"`                        // Time allowed for new connections to send the Hotline handshake; defaults to 10s
+	ProbeResponse             string             `yaml:"ProbeResponse"`                           // Text sent to connections that aren't Hotline clients, e.g. health checks; empty to close silently
+	MaxChatMembers            int                `yaml:"MaxChatMembers" validate:"min=0"`         // Default member limit of new private chats; 0 for no limit
+	ChatInviteOnly            bool               `yaml:"ChatInviteOnly"`                          // Make new private chats invite-only by default
+	MaxChatMsgLen             int                `yaml:"MaxChatMsgLen" validate:"min=0"`          // Max length of a chat message in bytes, including the sender's name; defaults to 8192
+	MaxChatLines              int                `yaml:"MaxChatLines" validate:"min=0"`           // Max lines in a chat message; 0 for no limit
+	MaxUserNameLen            int                `yaml:"MaxUserNameLen" validate:"min=0"`         // Max length of user names in bytes; 0 for no limit
+	Port                      int                `yaml:"Port" validate:"min=0,max=65534"`         // Base Hotline port, with file transfers on Port+1; overridden by the -bind flag
+	AllowDefaultAdminPassword bool               `yaml:"AllowDefaultAdminPassword"`               // Don't replace the default admin password on startup, e.g. for local testing
+	Privacy                   Privacy            `yaml:"Privacy"`                                 // Controls what the server reveals to trackers, Bonjour, and server info requests
+	IdleTimeout               time.Duration      `yaml:"IdleTimeout"`                             // Time without activity after which users are shown as idle; defaults to 5m, negative to disable
+	UserListGroups            UserListGroups     `yaml:"UserListGroups"`                          // Optional grouping hints in user lists, e.g. to show staff at the top
+	BroadcastHistory          int                `yaml:"BroadcastHistory" validate:"min=0"`       // Number of recent admin broadcasts kept for users who log in later; 0 to disable
+	ChatHistory               ChatHistory        `yaml:"ChatHistory"`                             // Replay of recent public chat to users who log in later
+	PrivateChats              PrivateChats       `yaml:"PrivateChats"`                            // Saving of private chats and re-delivery of chat invitations
+	EventAnnouncements        EventAnnouncements `yaml:"EventAnnouncements"`                      // When and how to announce the community events in Events.yaml
+	UsageReports              UsageReports       `yaml:"UsageReports"`                            // Optional weekly usage reports
+	DiskSpace                 DiskSpace          `yaml:"DiskSpace"`                               // Low disk space protection for the FileRoot
+	AuditLog                  AuditLog           `yaml:"AuditLog"`                                // Optional audit log of handled transactions
+	RateLimits                RateLimits         `yaml:"RateLimits"`                              // Optional throttling of transactions and login attempts
+	AutoBan                   AutoBan            `yaml:"AutoBan"`                                 // Automatic temporary bans of IP addresses after repeated failed logins
+	RunAsUser                 string             `yaml:"RunAsUser"`                               // User to switch to after binding the server ports when started as root on Unix
+	RunAsGroup                string             `yaml:"RunAsGroup"`                              // Group to switch to after binding the server ports; defaults to the primary group of RunAsUser
+	UploadScan                UploadScan         `yaml:"UploadScan"`                              // Optional scanning of uploaded files for viruses before they are saved
+	WebChat                   WebChat            `yaml:"WebChat"`                                 // Optional WebSocket bridge of public chat for web sites, served by the HTTP API
+	NNTP                      NNTP               `yaml:"NNTP"`                                    // Optional NNTP gateway that serves threaded news to newsreaders
+	NewsArticles              NewsArticles       `yaml:"NewsArticles"`                            // Size limits of threaded news articles and their optional attachments
+	NewsAttachmentsDir        string             `yaml:"NewsAttachmentsDir"`                      // Path to files attached to threaded news articles
+}
+
+// Privacy controls how discoverable the server is, for private communities that don't want to advertise themselves.
+type Privacy struct {
+	Unlisted        bool `yaml:"Unlisted"`        // Don't register with trackers or announce with Bonjour, regardless of other settings
+	HideUserCount   bool `yaml:"HideUserCount"`   // Report 0 users to trackers, and omit the user count from server info for non-admins and from /api/v1/info
+	HideDescription bool `yaml:"HideDescription"` // Omit the server description from tracker registrations
+}
+
+// GuestPolicy configures logins without an account login.  Guests log in without a password whether or not the guest
+// account file exists, and get the policy's access instead of the access in the file.  Other settings in the file,
+// such as the account name and allowed addresses, still apply if it exists.
+type GuestPolicy struct {
+	Allow     bool         `yaml:"Allow"`                      // Allow guest logins
+	Access    AccessBitmap `yaml:"Access"`                     // Access granted to guests, in the same format as account files
+	MaxGuests int          `yaml:"MaxGuests" validate:"min=0"` // Max simultaneous guest sessions; 0 for no limit
+	ReadOnly  bool         `yaml:"ReadOnly"`                   // Limit guests to downloading files and reading news and chat, whatever Access allows
+}
+
+// PasswordHashing configures how account passwords are hashed.  Passwords hashed with a different algorithm or cost,
+// such as those in account files from older versions, still work and are hashed again when their users log in.
+type PasswordHashing struct {
+	Algorithm string `yaml:"Algorithm" validate:"omitempty,oneof=bcrypt argon2id"` // "bcrypt" (default) or "argon2id"
+	Cost      int    `yaml:"Cost" validate:"omitempty,min=4,max=31"`               // bcrypt cost; defaults to 10
+	Memory    uint32 `yaml:"Memory"`                                               // argon2id memory in KiB; defaults to 65536
+	Time      uint32 `yaml:"Time"`                                                 // argon2id passes over the memory; defaults to 3
+	Threads   uint8  `yaml:"Threads"`                                              // argon2id parallelism; defaults to 2
+}
+
+// SyslogConfig configures sending logs to a syslog server.
+type SyslogConfig struct {
+	Network string `yaml:"Network" validate:"omitempty,oneof=udp tcp unix unixgram"` // Network to use; empty for the local syslog daemon
+	Address string `yaml:"Address"`                                                  // Address of the syslog server, e.g. "logs.example.com:514"
+	Tag     string `yaml:"Tag"`                                                      // Tag to log with; defaults to "mobius"
+}
+
+// ErrorReporting configures reporting of recovered panics to external error tracking services.  Reports include the
+// panic value, stack trace, and server version, but not user names, logins, or IP addresses.
+type ErrorReporting struct {
+	SentryDSN    string            `yaml:"SentryDSN"`    // Sentry project DSN, e.g. "https://<key>@o0.ingest.sentry.io/<project>"
+	OTLPEndpoint string            `yaml:"OTLPEndpoint"` // Base URL of an OTLP/HTTP collector, e.g. "http://localhost:4318"
+	OTLPHeaders  map[string]string `yaml:"OTLPHeaders"`  // Extra headers to send to the OTLP collector, e.g. for authentication
+}
+
+// Tracing configures export of transaction and file transfer timing spans to an OpenTelemetry collector.
+type Tracing struct {
+	OTLPEndpoint string            `yaml:"OTLPEndpoint"` // Base URL of an OTLP/HTTP collector, e.g. "http://localhost:4318"; empty to disable
+	OTLPHeaders  map[string]string `yaml:"OTLPHeaders"`  // Extra headers to send to the OTLP collector, e.g. for authentication
+}
+
+// NewsAnnouncement configures the announcement of new articles posted to a news category.
+type NewsAnnouncement struct {
+	Category   string `yaml:"Category"`   // News category path, e.g. "General/Announcements", or "*" for all categories
+	Chat       bool   `yaml:"Chat"`       // Announce new articles in public chat
+	WebhookURL string `yaml:"WebhookURL"` // Optional URL to POST a JSON notification of new articles to
+}
+
+// ChatHistory configures the replay of recent public chat to users when they log in, so that they don't see an empty
+// chat until someone speaks.
+type ChatHistory struct {
+	Lines   int  `yaml:"Lines" validate:"min=0"` // Number of recent public chat messages kept and replayed; 0 to disable
+	Persist bool `yaml:"Persist"`                // Save the chat history to ChatHistory.yaml so that it's kept across restarts
+}
+
+// PrivateChats configures how private chats and their invitations survive server restarts and dropped connections.
+type PrivateChats struct {
+	Persist           bool          `yaml:"Persist"`           // Save private chats to PrivateChats.yaml so that members are invited back after a restart
+	InviteGracePeriod time.Duration `yaml:"InviteGracePeriod"` // How long unanswered invitations are sent again to users who reconnect; defaults to 5m, negative to disable
+}
+
+// UsageReports configures weekly reports of server usage: accounts, connections, peak users, transfers, and the most
+// downloaded files.  Reports are sent at the start of each ISO week, which begins on Monday.
+type UsageReports struct {
+	NewsCategory string `yaml:"NewsCategory"` // News category path to post reports to; empty to not post reports
+	WebhookURL   string `yaml:"WebhookURL"`   // Optional URL to POST reports to as JSON
+	TopFiles     int    `yaml:"TopFiles"`     // Number of most downloaded files to list; defaults to 10
+}
+
+// Enabled returns true if reports are posted to news or a webhook.
+func (r UsageReports) Enabled() bool {
+	return r.NewsCategory != "" || r.WebhookURL != ""
+}
+
+// DiskSpace configures protection against the FileRoot disk filling up.
+type DiskSpace struct {
+	MinFreeMB  uint64 `yaml:"MinFreeMB"`  // Refuse uploads that would leave less than this many megabytes free; 0 to disable
+	WebhookURL string `yaml:"WebhookURL"` // Optional URL to POST a JSON alert to when free space falls below MinFreeMB
+}
+
+// UploadScan configures scanning of completed uploads with a command line virus scanner such as clamdscan.  Files that
+// the scanner reports as infected are deleted instead of being saved.
+type UploadScan struct {
+	Enabled bool          `yaml:"Enabled"` // Scan uploaded files
+	Command []string      `yaml:"Command"` // Scanner and arguments, followed by the path of the file; defaults to clamdscan
+	Folders []string      `yaml:"Folders"` // Folders, relative to FileRoot, whose uploads are scanned; empty for all folders
+	Timeout time.Duration `yaml:"Timeout"` // Max time to wait for the scanner; defaults to 5m
+}
+
+// WebChat configures the WebSocket endpoint of the HTTP API that streams public chat and users joining and leaving to
+// web clients, such as a chat widget on the server's web site.  Web clients that log in with a Hotline account allowed
+// to send chat can post messages, which are sent to Hotline users from BotName.
+type WebChat struct {
+	Enabled        bool     `yaml:"Enabled"`        // Serve the /api/v1/chat WebSocket endpoint
+	BotName        string   `yaml:"BotName"`        // Name that messages from web clients are sent from; defaults to "Web"
+	AllowedOrigins []string `yaml:"AllowedOrigins"` // Web site origins allowed to connect, e.g. "https://example.com"; empty for only the API's own
+}
+
+// NNTP configures a gateway that serves threaded news over NNTP, so that it can be read and posted to with a
+// newsreader.  News categories are newsgroups named after their news path, e.g. "hotline.general.announcements".
+// Newsreaders log in with a Hotline account, which must be allowed to read or post news.
+type NNTP struct {
+	Addr        string `yaml:"Addr"`        // Address to listen on, e.g. ":119"; empty to disable
+	Hostname    string `yaml:"Hostname"`    // Domain of message IDs and poster addresses; defaults to "mobius.invalid"
+	GroupPrefix string `yaml:"GroupPrefix"` // First part of newsgroup names; defaults to "hotline"
+	AllowGuests bool   `yaml:"AllowGuests"` // Let newsreaders that don't log in use the guest account
+}
+
+// NewsArticles limits the size of threaded news articles, and configures attachments: small files such as pictures
+// that are posted with an article as additional data flavors.  Attachments are stored in NewsAttachmentsDir.
+type NewsArticles struct {
+	MaxSize     int             `yaml:"MaxSize" validate:"min=0,max=65535"` // Max size in bytes of article text; 0 for the protocol limit of 65535
+	Attachments NewsAttachments `yaml:"Attachments"`
+}
+
+type NewsAttachments struct {
+	Enabled  bool     `yaml:"Enabled"`                            // Accept attachments to news articles
+	MaxSize  int      `yaml:"MaxSize" validate:"min=0,max=65535"` // Max size in bytes of each attachment; defaults to 32768
+	MaxCount int      `yaml:"MaxCount" validate:"min=0"`          // Max attachments per article; defaults to 1
+	Types    []string `yaml:"Types"`                              // MIME types allowed, e.g. "image/jpeg"; empty for any
+}
+
+// AuditLog configures recording of handled transactions, with the user, IP address, and file paths involved, to one
+// or more destinations.
+type AuditLog struct {
+	File         string        `yaml:"File"`         // Path of a file to append entries to as JSON lines, relative to the config dir
+	Syslog       *SyslogConfig `yaml:"Syslog"`       // Optional syslog server to send entries to
+	WebhookURL   string        `yaml:"WebhookURL"`   // Optional URL to POST each entry to as JSON
+	Transactions []string      `yaml:"Transactions"` // Transaction types to record by name, e.g. "Download file"; empty for all
+	Exclude      []string      `yaml:"Exclude"`      // Transaction types not to record, e.g. "Get file list"
+}
+
+// Enabled returns true if audit entries are sent anywhere.
+func (a AuditLog) Enabled() bool {
+	return a.File != "" || a.Syslog != nil || a.WebhookURL != ""
 }
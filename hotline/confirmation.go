@@ -0,0 +1,88 @@
+package hotline
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"time"
+)
+
+// confirmationTTL is how long a client has to resend a transaction after a confirmation challenge.
+const confirmationTTL = time.Minute
+
+type pendingConfirmation struct {
+	token   [8]byte
+	digest  [sha256.Size]byte // digest of the challenged transaction, so the token can't confirm a different request
+	expires time.Time
+}
+
+// Confirm guards a destructive transaction with a confirmation challenge.  The first time a transaction is sent,
+// Confirm returns false and an error reply containing prompt and a FieldConfirmation token.  To proceed, the client
+// must resend the same transaction within a minute with the token added in FieldConfirmation, in which case Confirm
+// returns true.
+func (cc *ClientConn) Confirm(t *Transaction, prompt string) ([]Transaction, bool) {
+	digest := confirmationDigest(t)
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if pending := cc.confirmation; pending != nil {
+		cc.confirmation = nil
+
+		token := t.GetField(FieldConfirmation).Data
+		if time.Now().Before(pending.expires) &&
+			subtle.ConstantTimeCompare(token, pending.token[:]) == 1 &&
+			digest == pending.digest {
+			return nil, true
+		}
+	}
+
+	pending := &pendingConfirmation{digest: digest, expires: time.Now().Add(confirmationTTL)}
+	_, _ = rand.Read(pending.token[:])
+	cc.confirmation = pending
+
+	res := cc.NewErrReply(t, prompt)
+	res[0].Fields = append(res[0].Fields, NewField(FieldConfirmation, pending.token[:]))
+
+	return res, false
+}
+
+// confirmationDigest returns a digest of the transaction type and fields, excluding any confirmation token.
+func confirmationDigest(t *Transaction) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(t.Type[:])
+
+	for _, f := range t.Fields {
+		if f.Type == FieldConfirmation {
+			continue
+		}
+		h.Write(f.Type[:])
+		_ = binary.Write(h, binary.BigEndian, uint32(len(f.Data)))
+		h.Write(f.Data)
+	}
+
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+
+	return digest
+}
+
+// CanManageAccount returns true if the client has every access privilege granted to account.  Admins may only modify
+// or delete accounts that don't have more access than themselves, so that they can't take over a more privileged
+// account by changing its password.
+func (cc *ClientConn) CanManageAccount(account *Account) bool {
+	return CanManageAccount(cc.Authorize, account)
+}
+
+// CanManageAccount returns true if authorize allows every access privilege granted to account, for users other than
+// a ClientConn, such as the actors of the account service.
+func CanManageAccount(authorize func(access int) bool, account *Account) bool {
+	for i := 0; i < 64; i++ {
+		if account.Access.IsSet(i) && !authorize(i) {
+			return false
+		}
+	}
+
+	return true
+}
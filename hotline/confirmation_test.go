@@ -0,0 +1,55 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClientConn_Confirm(t *testing.T) {
+	cc := &ClientConn{Server: &Server{}}
+	tran := NewTransaction(TranUpdateUser, ClientID{0, 1}, NewField(FieldData, []byte("accounts")))
+
+	// The first request is challenged.
+	res, ok := cc.Confirm(&tran, "Are you sure?")
+	assert.False(t, ok)
+	assert.Len(t, res, 1)
+	assert.Equal(t, []byte("Are you sure?"), res[0].GetField(FieldError).Data)
+	token := res[0].GetField(FieldConfirmation).Data
+	assert.Len(t, token, 8)
+
+	// Resending without the token issues a new challenge.
+	_, ok = cc.Confirm(&tran, "Are you sure?")
+	assert.False(t, ok)
+
+	// A token doesn't confirm a different request.
+	res, _ = cc.Confirm(&tran, "Are you sure?")
+	other := NewTransaction(TranUpdateUser, ClientID{0, 1}, NewField(FieldData, []byte("other accounts")))
+	other.Fields = append(other.Fields, NewField(FieldConfirmation, res[0].GetField(FieldConfirmation).Data))
+	_, ok = cc.Confirm(&other, "Are you sure?")
+	assert.False(t, ok)
+
+	// Resending the same request with the token confirms it.
+	res, _ = cc.Confirm(&tran, "Are you sure?")
+	tran.Fields = append(tran.Fields, NewField(FieldConfirmation, res[0].GetField(FieldConfirmation).Data))
+	_, ok = cc.Confirm(&tran, "Are you sure?")
+	assert.True(t, ok)
+
+	// The token can only be used once.
+	_, ok = cc.Confirm(&tran, "Are you sure?")
+	assert.False(t, ok)
+}
+
+func TestClientConn_CanManageAccount(t *testing.T) {
+	var admin, user AccessBitmap
+	for _, i := range []int{AccessModifyUser, AccessDeleteUser, AccessDisconUser} {
+		admin.Set(i)
+	}
+	user.Set(AccessModifyUser)
+
+	cc := &ClientConn{Account: &Account{Access: user}}
+	assert.True(t, cc.CanManageAccount(&Account{Access: user}))
+	assert.False(t, cc.CanManageAccount(&Account{Access: admin}))
+
+	cc = &ClientConn{Account: &Account{Access: admin}}
+	assert.True(t, cc.CanManageAccount(&Account{Access: user}))
+}
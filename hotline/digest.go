@@ -0,0 +1,139 @@
+package hotline
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const digestTopChatters = 3
+
+// ActivityDigest collects server activity between digests and composes it into a daily news article.  Connection and
+// transfer counts come from the server Stats; chat and account activity are recorded directly on the digest.
+//
+// A nil *ActivityDigest is valid and records nothing.
+type ActivityDigest struct {
+	mu          sync.Mutex
+	chatters    map[string]int // chat message count by user name
+	newAccounts int
+	last        map[int]int // Stats values at the time of the previous digest
+}
+
+func NewActivityDigest(stats Counter) *ActivityDigest {
+	d := &ActivityDigest{chatters: make(map[string]int)}
+	d.last = d.snapshot(stats)
+
+	return d
+}
+
+func (d *ActivityDigest) snapshot(stats Counter) map[int]int {
+	return map[int]int{
+		StatConnectionCounter: stats.Get(StatConnectionCounter),
+		StatDownloadCounter:   stats.Get(StatDownloadCounter),
+		StatUploadCounter:     stats.Get(StatUploadCounter),
+	}
+}
+
+// RecordChat records a public chat message sent by the user.
+func (d *ActivityDigest) RecordChat(userName string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.chatters[userName]++
+}
+
+// RecordNewAccount records the creation of a user account.
+func (d *ActivityDigest) RecordNewAccount() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.newAccounts++
+}
+
+// Compose returns the title and body of a digest article summarizing activity since the previous digest, and resets
+// the collected activity.
+func (d *ActivityDigest) Compose(stats Counter, now time.Time) (title, body string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := d.snapshot(stats)
+
+	type chatter struct {
+		name  string
+		count int
+	}
+	var chatters []chatter
+	for name, count := range d.chatters {
+		chatters = append(chatters, chatter{name, count})
+	}
+	slices.SortFunc(chatters, func(a, b chatter) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), strings.Compare(a.name, b.name))
+	})
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "Connections: %d\r", current[StatConnectionCounter]-d.last[StatConnectionCounter])
+	_, _ = fmt.Fprintf(&sb, "New accounts: %d\r", d.newAccounts)
+	_, _ = fmt.Fprintf(&sb, "Uploads: %d\r", current[StatUploadCounter]-d.last[StatUploadCounter])
+	_, _ = fmt.Fprintf(&sb, "Downloads: %d\r", current[StatDownloadCounter]-d.last[StatDownloadCounter])
+	_, _ = fmt.Fprintf(&sb, "Users chatting: %d\r", len(chatters))
+
+	if len(chatters) > 0 {
+		sb.WriteString("\rTop chatters:\r")
+		for _, c := range chatters[:min(len(chatters), digestTopChatters)] {
+			_, _ = fmt.Fprintf(&sb, "  %s (%d messages)\r", c.name, c.count)
+		}
+	}
+
+	d.last = current
+	d.chatters = make(map[string]int)
+	d.newAccounts = 0
+
+	return "Daily digest for " + now.Format("Mon Jan 2 2006"), sb.String()
+}
+
+// nextDigestTime returns the next time after now at the given hour of the day.
+func nextDigestTime(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next
+}
+
+// digestScheduler posts a digest article to the DigestNewsCategory once a day at DigestHour until ctx is cancelled.
+func (s *Server) digestScheduler(ctx context.Context) {
+	newsPath := strings.Split(strings.Trim(s.Config.DigestNewsCategory, "/"), "/")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(nextDigestTime(time.Now(), s.Config.DigestHour))):
+			title, body := s.Digest.Compose(s.Stats, time.Now())
+
+			err := s.ThreadedNewsMgr.PostArticle(newsPath, 0, NewsArtData{
+				Title:    title,
+				Poster:   s.CurrentConfig().Name,
+				Date:     NewTime(time.Now()),
+				DataFlav: NewsFlavor,
+				Data:     body,
+			})
+			if err != nil {
+				s.Logger.Error("Error posting digest news article", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestActivityDigest_Compose(t *testing.T) {
+	stats := NewStats()
+	stats.Set(StatUploadCounter, 5)
+
+	d := NewActivityDigest(stats)
+
+	stats.Increment(StatConnectionCounter, StatConnectionCounter, StatUploadCounter, StatDownloadCounter)
+	d.RecordNewAccount()
+	for _, name := range []string{"bob", "alice", "bob", "carol", "dave", "dave", "bob"} {
+		d.RecordChat(name)
+	}
+
+	title, body := d.Compose(stats, time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "Daily digest for Mon Mar 4 2024", title)
+	assert.Equal(t,
+		"Connections: 2\r"+
+			"New accounts: 1\r"+
+			"Uploads: 1\r"+
+			"Downloads: 1\r"+
+			"Users chatting: 4\r"+
+			"\rTop chatters:\r"+
+			"  bob (3 messages)\r"+
+			"  dave (2 messages)\r"+
+			"  alice (1 messages)\r",
+		body,
+	)
+
+	// Activity is reset after each digest.
+	_, body = d.Compose(stats, time.Now())
+	assert.Equal(t, "Connections: 0\rNew accounts: 0\rUploads: 0\rDownloads: 0\rUsers chatting: 0\r", body)
+
+	// A nil digest is a no-op.
+	var nilDigest *ActivityDigest
+	nilDigest.RecordChat("bob")
+	nilDigest.RecordNewAccount()
+}
+
+func TestNextDigestTime(t *testing.T) {
+	now := time.Date(2024, time.March, 4, 10, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, time.March, 4, 18, 0, 0, 0, time.UTC), nextDigestTime(now, 18))
+	assert.Equal(t, time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC), nextDigestTime(now, 9))
+}
@@ -0,0 +1,109 @@
+package hotline
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// diskCheckInterval is the time between checks of the free space on the file root disk.
+const diskCheckInterval = time.Minute
+
+// LowDiskSpaceHook is called when the free space on the file root disk falls below the configured minimum, e.g. to
+// alert admins outside of the server.
+type LowDiskSpaceHook func(freeBytes, minFreeBytes uint64)
+
+// OnLowDiskSpace registers hook to be called whenever free disk space falls below DiskSpace.MinFreeMB.
+func (s *Server) OnLowDiskSpace(hook LowDiskSpaceHook) {
+	s.lowDiskHooks = append(s.lowDiskHooks, hook)
+}
+
+// minFreeDiskBytes returns the free space to keep on the file root disk, or 0 if low disk space protection is
+// disabled.
+func (s *Server) minFreeDiskBytes() uint64 {
+	return s.Config.DiskSpace.MinFreeMB * 1024 * 1024
+}
+
+// DiskFree returns the free space on the file root disk as of the last check, and false if it is unknown.
+func (s *Server) DiskFree() (uint64, bool) {
+	s.diskMu.Lock()
+	defer s.diskMu.Unlock()
+
+	return s.diskFree, s.diskFreeKnown
+}
+
+// checkDiskSpace measures the free space on the file root disk and returns it, or false if it can't be measured.
+// Admins are alerted when free space falls below the minimum, and the recovery is logged when it rises above it again.
+func (s *Server) checkDiskSpace() (uint64, bool) {
+	free, err := s.diskFreeFunc(s.Config.FileRoot)
+	if err != nil {
+		s.Logger.Debug("Unable to check free disk space", "path", s.Config.FileRoot, "err", err)
+		return 0, false
+	}
+
+	minFree := s.minFreeDiskBytes()
+	low := minFree > 0 && free < minFree
+
+	s.diskMu.Lock()
+	wasLow := s.diskLow
+	s.diskFree, s.diskFreeKnown, s.diskLow = free, true, low
+	s.diskMu.Unlock()
+
+	switch {
+	case low && !wasLow:
+		s.Logger.Warn("Low disk space; refusing uploads", "path", s.Config.FileRoot, "freeMB", free/1024/1024, "minFreeMB", s.Config.DiskSpace.MinFreeMB)
+		s.alertLowDiskSpace(free)
+
+		for _, hook := range s.lowDiskHooks {
+			hook(free, minFree)
+		}
+	case !low && wasLow:
+		s.Logger.Info("Disk space recovered; accepting uploads", "path", s.Config.FileRoot, "freeMB", free/1024/1024)
+	}
+
+	return free, true
+}
+
+// alertLowDiskSpace sends a warning server message to connected users with the disconnect user privilege.
+func (s *Server) alertLowDiskSpace(free uint64) {
+	msg := fmt.Sprintf(s.T("Warning: the server is low on disk space (%d MB free).  New uploads are refused until space is freed."), free/1024/1024)
+
+	for _, c := range s.ClientMgr.List() {
+		if c.Authorize(AccessDisconUser) {
+			s.outbox <- NewTransaction(TranServerMsg, c.ID, NewField(FieldData, []byte(msg)), NewField(FieldChatOptions, []byte{0, 0}))
+		}
+	}
+}
+
+// HasUploadSpace returns true if an upload of size bytes leaves at least DiskSpace.MinFreeMB free on the file root
+// disk.  Uploads are always allowed if the minimum is not set or free space can't be measured.
+func (s *Server) HasUploadSpace(size uint64) bool {
+	minFree := s.minFreeDiskBytes()
+	if minFree == 0 {
+		return true
+	}
+
+	free, ok := s.checkDiskSpace()
+	if !ok {
+		return true
+	}
+
+	return free >= minFree && free-minFree >= size
+}
+
+// diskSpaceMonitor checks free disk space every diskCheckInterval until ctx is cancelled, so that admins are alerted
+// to low disk space even when nobody is uploading.
+func (s *Server) diskSpaceMonitor(ctx context.Context) {
+	ticker := time.NewTicker(diskCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.checkDiskSpace()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
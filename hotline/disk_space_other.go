@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd)
+
+package hotline
+
+import "errors"
+
+// diskFree is not supported on this platform, so low disk space protection is disabled.
+func diskFree(_ string) (uint64, error) {
+	return 0, errors.New("free disk space is not supported on this platform")
+}
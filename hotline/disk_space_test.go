@@ -0,0 +1,75 @@
+package hotline
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mb = 1024 * 1024
+
+func TestServer_HasUploadSpace(t *testing.T) {
+	var free uint64
+	s := &Server{
+		Config:       Config{DiskSpace: DiskSpace{MinFreeMB: 100}},
+		Logger:       NewTestLogger(),
+		ClientMgr:    NewMemClientMgr(),
+		outbox:       make(chan Transaction, 10),
+		diskFreeFunc: func(string) (uint64, error) { return free, nil },
+	}
+
+	var admin Account
+	admin.Access.Set(AccessDisconUser)
+	s.ClientMgr.Add(&ClientConn{Server: s, Account: &admin})
+	s.ClientMgr.Add(&ClientConn{Server: s, Account: &Account{}})
+
+	var alerts []uint64
+	s.OnLowDiskSpace(func(freeBytes, minFreeBytes uint64) {
+		assert.Equal(t, uint64(100*mb), minFreeBytes)
+		alerts = append(alerts, freeBytes)
+	})
+
+	free = 150 * mb
+	assert.True(t, s.HasUploadSpace(50*mb))
+	assert.False(t, s.HasUploadSpace(50*mb+1))
+	assert.Empty(t, alerts)
+
+	// Admins are alerted once when free space falls below the minimum.
+	free = 90 * mb
+	assert.False(t, s.HasUploadSpace(0))
+	assert.False(t, s.HasUploadSpace(0))
+	assert.Equal(t, []uint64{90 * mb}, alerts)
+	assert.Len(t, s.outbox, 1)
+
+	got, ok := s.DiskFree()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(90*mb), got)
+
+	// ...and again if it falls low after recovering.
+	free = 200 * mb
+	assert.True(t, s.HasUploadSpace(0))
+	free = 10 * mb
+	assert.False(t, s.HasUploadSpace(0))
+	assert.Equal(t, []uint64{90 * mb, 10 * mb}, alerts)
+	assert.Len(t, s.outbox, 2)
+}
+
+func TestServer_HasUploadSpace_unknown(t *testing.T) {
+	s := &Server{
+		Config:       Config{DiskSpace: DiskSpace{MinFreeMB: 100}},
+		Logger:       NewTestLogger(),
+		diskFreeFunc: func(string) (uint64, error) { return 0, errors.New("unsupported") },
+	}
+
+	// Uploads are allowed when free space can't be measured.
+	assert.True(t, s.HasUploadSpace(1))
+
+	_, ok := s.DiskFree()
+	assert.False(t, ok)
+
+	// The check is disabled without a minimum.
+	s.Config.DiskSpace.MinFreeMB = 0
+	s.diskFreeFunc = nil
+	assert.True(t, s.HasUploadSpace(1<<40))
+}
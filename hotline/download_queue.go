@@ -0,0 +1,157 @@
+package hotline
+
+import (
+	"context"
+	"encoding/binary"
+	"slices"
+	"time"
+)
+
+// downloadQueueRecheck is how often waiting downloads recheck the queue, so that changes to the download limits take
+// effect without waiting for another download to finish.
+const downloadQueueRecheck = 5 * time.Second
+
+// DownloadLimits are the max simultaneous downloads of the server and of each user.  0 means no limit.
+type DownloadLimits struct {
+	Total     int
+	PerClient int
+}
+
+// DownloadLimits returns the simultaneous download limits of the MaxDownloads and MaxDownloadsPerClient config.
+func (s *Server) DownloadLimits() DownloadLimits {
+	config := s.CurrentConfig()
+
+	return DownloadLimits{Total: config.MaxDownloads, PerClient: config.MaxDownloadsPerClient}
+}
+
+// positions returns the queue position of each download in the queue, as for QueuePosition.  Queued downloads are
+// given free slots in the order they were requested, skipping downloads by users that have reached the per user
+// limit.  Downloads given a slot keep it until they start, even if the client hasn't connected yet.  The caller must
+// hold mu.
+func (ftm *MemFileTransferMgr) positions(limits DownloadLimits) map[*FileTransfer]int {
+	free := limits.Total - ftm.activeTotal
+	perClient := make(map[*ClientConn]int)
+
+	positions := make(map[*FileTransfer]int, len(ftm.queue))
+	waiting := 0
+	for _, ft := range ftm.queue {
+		cc := ft.ClientConn
+		if (limits.Total <= 0 || free > 0) && (limits.PerClient <= 0 || ftm.active[cc]+perClient[cc] < limits.PerClient) {
+			free--
+			perClient[cc]++
+			positions[ft] = 0
+			continue
+		}
+
+		waiting++
+		positions[ft] = waiting
+	}
+
+	return positions
+}
+
+func (ftm *MemFileTransferMgr) QueuePosition(ft *FileTransfer, limits DownloadLimits) int {
+	ftm.mu.Lock()
+	defer ftm.mu.Unlock()
+
+	return ftm.positions(limits)[ft]
+}
+
+func (ftm *MemFileTransferMgr) StartDownload(ctx context.Context, ft *FileTransfer, limits func() DownloadLimits, onWait func(position int)) (func(), error) {
+	ticker := time.NewTicker(downloadQueueRecheck)
+	defer ticker.Stop()
+
+	// Downloads that weren't queued when they were added join the back of the queue.
+	ftm.mu.Lock()
+	if !slices.Contains(ftm.queue, ft) {
+		ftm.queue = append(ftm.queue, ft)
+	}
+	ftm.mu.Unlock()
+
+	lastPosition := 0
+	for {
+		ftm.mu.Lock()
+		position := ftm.positions(limits())[ft]
+		if position == 0 {
+			ftm.dequeue(ft)
+			if ftm.active == nil {
+				ftm.active = make(map[*ClientConn]int)
+			}
+			ftm.active[ft.ClientConn]++
+			ftm.activeTotal++
+			ftm.mu.Unlock()
+
+			return func() { ftm.finishDownload(ft.ClientConn) }, nil
+		}
+		changed := ftm.changed()
+		ftm.mu.Unlock()
+
+		if position != lastPosition {
+			onWait(position)
+			lastPosition = position
+		}
+
+		select {
+		case <-ctx.Done():
+			ftm.mu.Lock()
+			ftm.dequeue(ft)
+			ftm.mu.Unlock()
+			return nil, ctx.Err()
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishDownload frees the download slot of a download by cc.
+func (ftm *MemFileTransferMgr) finishDownload(cc *ClientConn) {
+	ftm.mu.Lock()
+	defer ftm.mu.Unlock()
+
+	ftm.active[cc]--
+	if ftm.active[cc] <= 0 {
+		delete(ftm.active, cc)
+	}
+	ftm.activeTotal--
+	ftm.notifyQueue()
+}
+
+// dequeue removes ft from the download queue, if it's queued.  The caller must hold mu.
+func (ftm *MemFileTransferMgr) dequeue(ft *FileTransfer) {
+	if i := slices.Index(ftm.queue, ft); i >= 0 {
+		ftm.queue = slices.Delete(ftm.queue, i, i+1)
+		ftm.notifyQueue()
+	}
+}
+
+// changed returns a channel that is closed the next time the queue changes.  The caller must hold mu.
+func (ftm *MemFileTransferMgr) changed() <-chan struct{} {
+	if ftm.queueChanged == nil {
+		ftm.queueChanged = make(chan struct{})
+	}
+
+	return ftm.queueChanged
+}
+
+// notifyQueue wakes the downloads waiting in StartDownload.  The caller must hold mu.
+func (ftm *MemFileTransferMgr) notifyQueue() {
+	if ftm.queueChanged != nil {
+		close(ftm.queueChanged)
+		ftm.queueChanged = nil
+	}
+}
+
+// startDownload waits for a free download slot for ft, sending the client its queue position while it waits.
+func (s *Server) startDownload(ctx context.Context, ft *FileTransfer) (func(), error) {
+	return s.FileTransferMgr.StartDownload(ctx, ft, s.DownloadLimits, func(position int) {
+		s.outbox <- NewTransaction(TranDownloadInfo, ft.ClientConn.ID,
+			NewField(FieldRefNum, ft.RefNum[:]),
+			NewField(FieldWaitingCount, EncodeWaitingCount(position)),
+		)
+	})
+}
+
+// EncodeWaitingCount encodes a queue position as the data of a FieldWaitingCount field.
+func EncodeWaitingCount(position int) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(min(position, 0xFFFF)))
+}
@@ -0,0 +1,136 @@
+package hotline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueuedDownload(ftm *MemFileTransferMgr, cc *ClientConn) *FileTransfer {
+	ft := &FileTransfer{Type: FileDownload, ClientConn: cc}
+	ftm.Add(ft)
+
+	return ft
+}
+
+func TestMemFileTransferMgr_QueuePosition(t *testing.T) {
+	ftm := NewMemFileTransferMgr()
+	alice := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+	bob := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+
+	alice1 := newQueuedDownload(ftm, alice)
+	alice2 := newQueuedDownload(ftm, alice)
+	bob1 := newQueuedDownload(ftm, bob)
+	upload := &FileTransfer{Type: FileUpload, ClientConn: bob}
+	ftm.Add(upload)
+
+	// Without limits, every download can start.
+	assert.Equal(t, 0, ftm.QueuePosition(alice2, DownloadLimits{}))
+
+	total := DownloadLimits{Total: 1}
+	assert.Equal(t, 0, ftm.QueuePosition(alice1, total))
+	assert.Equal(t, 1, ftm.QueuePosition(alice2, total))
+	assert.Equal(t, 2, ftm.QueuePosition(bob1, total))
+	assert.Equal(t, 0, ftm.QueuePosition(upload, total))
+
+	// Downloads by users at the per user limit don't hold up other users.
+	perClient := DownloadLimits{PerClient: 1}
+	assert.Equal(t, 0, ftm.QueuePosition(alice1, perClient))
+	assert.Equal(t, 1, ftm.QueuePosition(alice2, perClient))
+	assert.Equal(t, 0, ftm.QueuePosition(bob1, perClient))
+
+	// Downloads that leave the queue move the rest up.
+	ftm.Delete(alice1.RefNum)
+	assert.Equal(t, 0, ftm.QueuePosition(alice2, total))
+	assert.Equal(t, 1, ftm.QueuePosition(bob1, total))
+}
+
+func TestMemFileTransferMgr_StartDownload(t *testing.T) {
+	ftm := NewMemFileTransferMgr()
+	limits := func() DownloadLimits { return DownloadLimits{Total: 1} }
+	alice := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+	bob := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+
+	first := newQueuedDownload(ftm, alice)
+	second := newQueuedDownload(ftm, bob)
+
+	done, err := ftm.StartDownload(context.Background(), first, limits, func(int) { t.Error("first download waited") })
+	require.NoError(t, err)
+
+	positions := make(chan int, 10)
+	started := make(chan func())
+	go func() {
+		done, err := ftm.StartDownload(context.Background(), second, limits, func(p int) { positions <- p })
+		assert.NoError(t, err)
+		started <- done
+	}()
+
+	assert.Equal(t, 1, <-positions)
+	select {
+	case <-started:
+		t.Fatal("second download started while the first was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	done()
+
+	select {
+	case done := <-started:
+		done()
+	case <-time.After(time.Second):
+		t.Fatal("second download didn't start after the first finished")
+	}
+	assert.Zero(t, ftm.activeTotal)
+	assert.Empty(t, ftm.active)
+}
+
+func TestMemFileTransferMgr_StartDownload_Cancel(t *testing.T) {
+	ftm := NewMemFileTransferMgr()
+	limits := func() DownloadLimits { return DownloadLimits{Total: 1} }
+	cc := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+
+	_ = newQueuedDownload(ftm, cc)
+	waiting := newQueuedDownload(ftm, cc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ftm.StartDownload(ctx, waiting, limits, func(int) {})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotContains(t, ftm.queue, waiting)
+}
+
+func TestServer_startDownload(t *testing.T) {
+	s := &Server{
+		Config:          Config{MaxDownloads: 1},
+		FileTransferMgr: NewMemFileTransferMgr(),
+		outbox:          make(chan Transaction, 1),
+	}
+	cc := &ClientConn{ID: [2]byte{0, 1}, ClientFileTransferMgr: NewClientFileTransferMgr()}
+
+	first := &FileTransfer{Type: FileDownload, ClientConn: cc}
+	second := &FileTransfer{Type: FolderDownload, ClientConn: cc}
+	s.FileTransferMgr.Add(first)
+	s.FileTransferMgr.Add(second)
+
+	done, err := s.startDownload(context.Background(), first)
+	require.NoError(t, err)
+
+	go func() {
+		done, err := s.startDownload(context.Background(), second)
+		if assert.NoError(t, err) {
+			done()
+		}
+	}()
+
+	tran := <-s.outbox
+	assert.Equal(t, TranDownloadInfo, tran.Type)
+	assert.Equal(t, cc.ID, tran.ClientID)
+	assert.Equal(t, second.RefNum[:], tran.GetField(FieldRefNum).Data)
+	assert.Equal(t, []byte{0, 1}, tran.GetField(FieldWaitingCount).Data)
+
+	done()
+}
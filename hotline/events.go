@@ -0,0 +1,153 @@
+package hotline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// eventCheckInterval is the time between checks for event announcements that are due.
+const eventCheckInterval = time.Minute
+
+// Event is an upcoming community event.
+type Event struct {
+	Title       string    `yaml:"Title" json:"title"`
+	Description string    `yaml:"Description,omitempty" json:"description,omitempty"`
+	Start       time.Time `yaml:"Start" json:"start"`
+}
+
+// EventList stores community events.
+type EventList interface {
+	// List returns all events ordered by start time.
+	List() []Event
+}
+
+// EventAnnouncements configures when and how events are announced to connected users.
+type EventAnnouncements struct {
+	LeadTimes []time.Duration `yaml:"LeadTimes"` // How long before the start of each event to announce it, e.g. 24h and 15m
+	Chat      bool            `yaml:"Chat"`      // Announce in public chat instead of with a broadcast message
+}
+
+// UpcomingEvents returns the events that start after now, ordered by start time.
+func (s *Server) UpcomingEvents(now time.Time) []Event {
+	if s.Events == nil {
+		return nil
+	}
+
+	var events []Event
+	for _, e := range s.Events.List() {
+		if e.Start.After(now) {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+// eventAnnouncement is an announcement of an event a lead time before it starts.
+type eventAnnouncement struct {
+	event Event
+	lead  time.Duration
+}
+
+// dueEventAnnouncements returns the announcements that fall due after from and up to to.
+func dueEventAnnouncements(events []Event, leadTimes []time.Duration, from, to time.Time) []eventAnnouncement {
+	var due []eventAnnouncement
+	for _, e := range events {
+		for _, lead := range leadTimes {
+			at := e.Start.Add(-lead)
+			if at.After(from) && !at.After(to) {
+				due = append(due, eventAnnouncement{event: e, lead: lead})
+			}
+		}
+	}
+
+	return due
+}
+
+// formatLeadTime formats an announcement lead time in the largest whole unit, e.g. "2 days" or "15 minutes".
+func formatLeadTime(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
+
+// announceEvent sends an event announcement to all connected users, as a chat message or a broadcast message as
+// configured.
+func (s *Server) announceEvent(a eventAnnouncement) {
+	title := ToMacRoman(a.event.Title)
+
+	var msg string
+	if a.lead < time.Minute {
+		msg = fmt.Sprintf(s.T("Event starting now: %s"), title)
+	} else {
+		msg = fmt.Sprintf(s.T("Event starting in %s: %s"), formatLeadTime(a.lead), title)
+	}
+	if a.event.Description != "" {
+		msg += "\r" + ToMacRoman(a.event.Description)
+	}
+
+	if !s.Config.EventAnnouncements.Chat {
+		s.SendAll(TranServerMsg, NewField(FieldData, []byte(msg)), NewField(FieldChatOptions, []byte{0}))
+		return
+	}
+
+	chatMsg, _ := TruncateText([]byte("\r*** "+msg), s.MaxChatMsgLen())
+	for _, c := range s.ClientMgr.List() {
+		if c.Authorize(AccessReadChat) {
+			s.outbox <- NewTransaction(TranChatMsg, c.ID, NewField(FieldData, chatMsg))
+		}
+	}
+}
+
+// eventScheduler announces events at the configured lead times until ctx is cancelled.  Announcements that fell due
+// while the server was not running are skipped.
+func (s *Server) eventScheduler(ctx context.Context) {
+	ticker := time.NewTicker(eventCheckInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, a := range dueEventAnnouncements(s.Events.List(), s.Config.EventAnnouncements.LeadTimes, last, now) {
+				s.announceEvent(a)
+			}
+			last = now
+		}
+	}
+}
+
+// EventsMsg returns a server message listing events under a heading.
+func (cc *ClientConn) EventsMsg(heading string, events []Event) Transaction {
+	var sb strings.Builder
+	sb.WriteString(heading)
+	for _, e := range events {
+		sb.WriteString(fmt.Sprintf("\r\r%s  %s", e.Start.Local().Format("Mon Jan 2 2006 15:04"), ToMacRoman(e.Title)))
+		if e.Description != "" {
+			sb.WriteString("\r" + ToMacRoman(e.Description))
+		}
+	}
+
+	return NewTransaction(
+		TranServerMsg,
+		cc.ID,
+		NewField(FieldData, []byte(sb.String())),
+		NewField(FieldChatOptions, []byte{0, 0}),
+	)
+}
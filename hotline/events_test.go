@@ -0,0 +1,51 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_dueEventAnnouncements(t *testing.T) {
+	start := time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC)
+	events := []Event{{Title: "Game night", Start: start}}
+	leadTimes := []time.Duration{24 * time.Hour, 15 * time.Minute, 0}
+
+	// Each lead time is announced once, in the check interval it falls in.
+	assert.Equal(t,
+		[]eventAnnouncement{{event: events[0], lead: 24 * time.Hour}},
+		dueEventAnnouncements(events, leadTimes, start.Add(-24*time.Hour-time.Minute), start.Add(-24*time.Hour)),
+	)
+	assert.Empty(t, dueEventAnnouncements(events, leadTimes, start.Add(-24*time.Hour), start.Add(-24*time.Hour+time.Minute)))
+	assert.Equal(t,
+		[]eventAnnouncement{{event: events[0], lead: 15 * time.Minute}},
+		dueEventAnnouncements(events, leadTimes, start.Add(-15*time.Minute-time.Second), start.Add(-14*time.Minute)),
+	)
+	assert.Equal(t,
+		[]eventAnnouncement{{event: events[0], lead: 0}},
+		dueEventAnnouncements(events, leadTimes, start.Add(-time.Second), start),
+	)
+	assert.Empty(t, dueEventAnnouncements(events, leadTimes, start, start.Add(time.Hour)))
+}
+
+func Test_formatLeadTime(t *testing.T) {
+	assert.Equal(t, "1 day", formatLeadTime(24*time.Hour))
+	assert.Equal(t, "2 days", formatLeadTime(48*time.Hour))
+	assert.Equal(t, "1 hour", formatLeadTime(time.Hour))
+	assert.Equal(t, "36 hours", formatLeadTime(36*time.Hour))
+	assert.Equal(t, "15 minutes", formatLeadTime(15*time.Minute))
+	assert.Equal(t, "90 minutes", formatLeadTime(90*time.Minute))
+}
+
+func TestServer_UpcomingEvents(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := Event{Title: "Past", Start: now.Add(-time.Hour)}
+	future := Event{Title: "Future", Start: now.Add(time.Hour)}
+
+	assert.Nil(t, (&Server{}).UpcomingEvents(now))
+	assert.Equal(t, []Event{future}, (&Server{Events: staticEvents{past, future}}).UpcomingEvents(now))
+}
+
+type staticEvents []Event
+
+func (e staticEvents) List() []Event { return e }
@@ -1,10 +1,10 @@
 package hotline
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"slices"
 )
@@ -71,6 +71,29 @@ var (
 	// FieldNewsArtFlags        = [2]byte{0x01, 0x4E} // 334
 )
 
+// Mobius protocol extensions.  These are ignored by classic clients.
+var (
+	FieldConfirmation        = [2]byte{0x0F, 0xA0} // 4000: token from a confirmation challenge, see ClientConn.Confirm
+	FieldServerVersion       = [2]byte{0x0F, 0xA1} // 4001: server software version string
+	FieldServerUptime        = [2]byte{0x0F, 0xA2} // 4002: seconds since the server started
+	FieldUserCount           = [2]byte{0x0F, 0xA3} // 4003: number of connected users
+	FieldDownloadCount       = [2]byte{0x0F, 0xA4} // 4004: downloads since the server started
+	FieldUploadCount         = [2]byte{0x0F, 0xA5} // 4005: uploads since the server started
+	FieldDownloadsInProgress = [2]byte{0x0F, 0xA6} // 4006: active downloads
+	FieldUploadsInProgress   = [2]byte{0x0F, 0xA7} // 4007: active uploads
+	FieldFileSortOrder       = [2]byte{0x0F, 0xA8} // 4008: file list sort order, see FileListOptions
+	FieldFileSortDescending  = [2]byte{0x0F, 0xA9} // 4009: non-zero to reverse the file list sort order
+	FieldFileFilter          = [2]byte{0x0F, 0xAA} // 4010: glob pattern to filter the file list
+	FieldIncompleteUpload    = [2]byte{0x0F, 0xAB} // 4011: an interrupted upload, see NewIncompleteUploadField
+	FieldFileUploader        = [2]byte{0x0F, 0xAC} // 4012: login of the account that uploaded the file
+	FieldFileUploadDate      = [2]byte{0x0F, 0xAD} // 4013: time the file was uploaded, in the same format as FieldFileCreateDate
+	FieldBookmark            = [2]byte{0x0F, 0xAE} // 4014: bookmarked file or folder path relative to the file root, e.g. "Uploads/file.sit"
+	FieldPreference          = [2]byte{0x0F, 0xAF} // 4015: account preference in the form "Name=Value", see Account.Preferences
+	FieldUserGroup           = [2]byte{0x0F, 0xB0} // 4016: user list group of a user, see NewUserGroupField
+	FieldCapability          = [2]byte{0x0F, 0xB1} // 4017: name of an optional feature, see Capabilities
+	FieldChatHistoryTime     = [2]byte{0x0F, 0xB2} // 4018: time of the oldest message in a replay of the chat history, in the same format as FieldFileCreateDate
+)
+
 type Field struct {
 	Type      [2]byte // Type of field
 	FieldSize [2]byte // Size of the data field
@@ -135,22 +158,55 @@ func (f *Field) DecodeNewsPath() ([]string, error) {
 	if len(f.Data) == 0 {
 		return []string{}, nil
 	}
+	if len(f.Data) < 2 {
+		return nil, errors.New("news path too short")
+	}
 
-	pathCount := binary.BigEndian.Uint16(f.Data[0:2])
+	r := newBinReader(f.Data)
+	pathCount := int(r.Uint16())
 
-	scanner := bufio.NewScanner(bytes.NewReader(f.Data[2:]))
-	scanner.Split(newsPathScanner)
+	// Each path item is at least 3 bytes.
+	if pathCount*3 > r.Len() {
+		return nil, fmt.Errorf("%w: %d news path items don't fit in %d bytes", ErrShortData, pathCount, r.Len())
+	}
 
 	var paths []string
-
-	for i := uint16(0); i < pathCount; i++ {
-		scanner.Scan()
-		paths = append(paths, scanner.Text())
+	for i := 0; i < pathCount; i++ {
+		r.Bytes(2) // Unused
+		name := r.Bytes(int(r.Uint8()))
+		if err := r.Err(); err != nil {
+			return nil, fmt.Errorf("read news path: %w", err)
+		}
+		paths = append(paths, string(name))
 	}
 
 	return paths, nil
 }
 
+// DecodeFieldList decodes data holding a list of fields, as in the FieldData fields of TranUpdateUser: a 2 byte field
+// count followed by the fields.
+func DecodeFieldList(data []byte) ([]Field, error) {
+	r := newBinReader(data)
+	count := int(r.Uint16())
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if count*minFieldLen > r.Len() {
+		return nil, fmt.Errorf("%w: %d fields don't fit in %d bytes", ErrShortData, count, r.Len())
+	}
+
+	fields := make([]Field, 0, count)
+	for i := 0; i < count; i++ {
+		var field Field
+		if err := field.read(r); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
 // Read implements io.Reader for Field
 func (f *Field) Read(p []byte) (int, error) {
 	buf := slices.Concat(f.Type[:], f.FieldSize[:], f.Data)
@@ -167,22 +223,29 @@ func (f *Field) Read(p []byte) (int, error) {
 
 // Write implements io.Writer for Field
 func (f *Field) Write(p []byte) (int, error) {
-	if len(p) < minFieldLen {
-		return 0, errors.New("input slice too short")
+	r := newBinReader(p)
+	if err := f.read(r); err != nil {
+		return 0, err
 	}
 
-	copy(f.Type[:], p[0:2])
-	copy(f.FieldSize[:], p[2:4])
+	return r.Offset(), nil
+}
 
-	dataSize := int(binary.BigEndian.Uint16(f.FieldSize[:]))
-	if len(p) < minFieldLen+dataSize {
-		return 0, errors.New("input slice too short for data size")
+// read reads a field from r.  The field's data is copied, so it doesn't share the data being read.
+func (f *Field) read(r *binReader) error {
+	r.Read(f.Type[:])
+	r.Read(f.FieldSize[:])
+	data := r.Bytes(int(binary.BigEndian.Uint16(f.FieldSize[:])))
+	if err := r.Err(); err != nil {
+		return err
 	}
 
-	f.Data = make([]byte, dataSize)
-	copy(f.Data, p[4:4+dataSize])
+	f.Data = bytes.Clone(data)
+	if f.Data == nil {
+		f.Data = []byte{}
+	}
 
-	return minFieldLen + dataSize, nil
+	return nil
 }
 
 func GetField(id [2]byte, fields *[]Field) *Field {
@@ -229,3 +229,55 @@ func TestField_DecodeInt(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeFieldList(t *testing.T) {
+	fields, err := DecodeFieldList([]byte{0x00, 0x01, 0x00, 0x66, 0x00, 0x03, 0x61, 0x62, 0x63})
+	assert.NoError(t, err)
+	assert.Equal(t, []Field{NewField(FieldUserName, []byte("abc"))}, fields)
+
+	// A count of more fields than fit in the data is rejected.
+	_, err = DecodeFieldList([]byte{0xff, 0xff, 0x00, 0x66, 0x00, 0x03, 0x61, 0x62, 0x63})
+	assert.ErrorIs(t, err, ErrShortData)
+
+	// As is a field shorter than its size.
+	_, err = DecodeFieldList([]byte{0x00, 0x01, 0x00, 0x66, 0x00, 0x09, 0x61, 0x62, 0x63})
+	assert.ErrorIs(t, err, ErrShortData)
+}
+
+func FuzzField_Write(f *testing.F) {
+	f.Add([]byte{0x00, 0x66, 0x00, 0x03, 0x61, 0x62, 0x63})
+	f.Add([]byte{0x00, 0x66, 0xff, 0xff, 0x61})
+
+	f.Fuzz(func(t *testing.T, p []byte) {
+		var field Field
+		n, err := field.Write(p)
+		if err != nil {
+			return
+		}
+		assert.Equal(t, minFieldLen+len(field.Data), n)
+		assert.LessOrEqual(t, n, len(p))
+	})
+}
+
+func FuzzField_DecodeNewsPath(f *testing.F) {
+	f.Add([]byte{0x00, 0x01, 0x00, 0x00, 0x07, 0x47, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x6c})
+	f.Add([]byte{0xff, 0xff, 0x00, 0x00, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		field := NewField(FieldNewsPath, data)
+		_, _ = field.DecodeNewsPath()
+	})
+}
+
+func FuzzDecodeFieldList(f *testing.F) {
+	f.Add([]byte{0x00, 0x01, 0x00, 0x66, 0x00, 0x03, 0x61, 0x62, 0x63})
+	f.Add([]byte{0xff, 0xff, 0x00, 0x66, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fields, err := DecodeFieldList(data)
+		if err != nil {
+			return
+		}
+		assert.LessOrEqual(t, len(fields)*minFieldLen, len(data))
+	})
+}
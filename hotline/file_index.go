@@ -0,0 +1,156 @@
+package hotline
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileIndexTTL bounds how long a cached entry is trusted, so that changes made to the file root outside the server are
+// eventually picked up even if they aren't reported with Invalidate.
+const fileIndexTTL = 10 * time.Minute
+
+// fileIndexKey identifies a cached folder.  Entries are cached separately for clients that can view hidden files.
+type fileIndexKey struct {
+	path       string
+	showHidden bool
+}
+
+type folderSize struct {
+	totalSize []byte
+	itemCount []byte
+	expires   time.Time
+}
+
+type fileList struct {
+	ignore  string // IgnoreFiles patterns the list was read with, joined
+	entries []fileListEntry
+	expires time.Time
+}
+
+// FileIndex caches the file listings of folders and their recursive total size and item count, so that they don't
+// need to be read from the file system on each request, which is slow for large folders or network file systems.
+// Entries are invalidated when the folder or any of its descendants change.
+//
+// A nil *FileIndex is valid and reads the values on each call.
+type FileIndex struct {
+	mu      sync.Mutex
+	folders map[fileIndexKey]folderSize
+	lists   map[fileIndexKey]fileList
+}
+
+func NewFileIndex() *FileIndex {
+	return &FileIndex{
+		folders: make(map[fileIndexKey]folderSize),
+		lists:   make(map[fileIndexKey]fileList),
+	}
+}
+
+// FolderSize returns the total size and item count of the folder at path, as calculated by CalcTotalSize and
+// CalcItemCount.  hidden should be either the server's HiddenFiles or nil, as returned by ClientConn.HiddenFiles.
+func (c *FileIndex) FolderSize(path string, hidden HiddenFiles) (totalSize, itemCount []byte, err error) {
+	path = filepath.Clean(path)
+	key := fileIndexKey{path: path, showHidden: hidden == nil}
+
+	if c != nil {
+		c.mu.Lock()
+		entry, ok := c.folders[key]
+		c.mu.Unlock()
+
+		if ok && time.Now().Before(entry.expires) {
+			return entry.totalSize, entry.itemCount, nil
+		}
+	}
+
+	totalSize, err = CalcTotalSize(path, hidden)
+	if err != nil {
+		return nil, nil, err
+	}
+	itemCount, err = CalcItemCount(path, hidden)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c != nil {
+		c.mu.Lock()
+		c.folders[key] = folderSize{
+			totalSize: totalSize,
+			itemCount: itemCount,
+			expires:   time.Now().Add(fileIndexTTL),
+		}
+		c.mu.Unlock()
+	}
+
+	return totalSize, itemCount, nil
+}
+
+// FileList returns the file listing of the folder at path, as returned by GetFileNameList.  opts.Hidden should be
+// either the server's HiddenFiles or nil, as returned by ClientConn.HiddenFiles.
+func (c *FileIndex) FileList(path string, ignoreList []string, opts FileListOptions) ([]Field, error) {
+	path = filepath.Clean(path)
+	key := fileIndexKey{path: path, showHidden: opts.Hidden == nil}
+	ignore := strings.Join(ignoreList, "\x00")
+
+	var entries []fileListEntry
+	cached := false
+	if c != nil {
+		c.mu.Lock()
+		list, ok := c.lists[key]
+		c.mu.Unlock()
+
+		if ok && list.ignore == ignore && time.Now().Before(list.expires) {
+			entries, cached = list.entries, true
+		}
+	}
+
+	if !cached {
+		var err error
+		if entries, err = readFileList(path, ignoreList, opts); err != nil {
+			return nil, err
+		}
+
+		if c != nil {
+			c.mu.Lock()
+			c.lists[key] = fileList{ignore: ignore, entries: entries, expires: time.Now().Add(fileIndexTTL)}
+			c.mu.Unlock()
+		}
+	}
+
+	// Sorting and filtering modify the slice, so they're applied to a copy of the cached entries.
+	var fields []Field
+	for _, entry := range opts.apply(slices.Clone(entries)) {
+		fields = append(fields, entry.field)
+	}
+
+	return fields, nil
+}
+
+// Invalidate removes cached entries affected by a change to path: path itself, its ancestors, and its descendants.
+func (c *FileIndex) Invalidate(path string) {
+	if c == nil {
+		return
+	}
+
+	path = filepath.Clean(path)
+	sep := string(filepath.Separator)
+	affected := func(key fileIndexKey) bool {
+		folder := key.path
+		return folder == path || strings.HasPrefix(path, folder+sep) || strings.HasPrefix(folder, path+sep)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.folders {
+		if affected(key) {
+			delete(c.folders, key)
+		}
+	}
+	for key := range c.lists {
+		if affected(key) {
+			delete(c.lists, key)
+		}
+	}
+}
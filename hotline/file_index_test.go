@@ -0,0 +1,87 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileIndex(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	assert.NoError(t, os.Mkdir(sub, 0777))
+	assert.NoError(t, os.WriteFile(filepath.Join(sub, "a.txt"), []byte("aaaa"), 0644))
+
+	cache := NewFileIndex()
+
+	size, count, err := cache.FolderSize(root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 4}, size)
+	assert.Equal(t, []byte{0, 2}, count)
+
+	// Changes are not visible until the folder is invalidated.
+	assert.NoError(t, os.WriteFile(filepath.Join(sub, "b.txt"), []byte("bb"), 0644))
+	size, _, err = cache.FolderSize(root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 4}, size)
+
+	// Invalidating a descendant also invalidates its ancestors.
+	cache.Invalidate(filepath.Join(sub, "b.txt"))
+	size, count, err = cache.FolderSize(root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 6}, size)
+	assert.Equal(t, []byte{0, 3}, count)
+
+	// A nil cache computes the values directly.
+	var nilCache *FileIndex
+	size, _, err = nilCache.FolderSize(sub, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 6}, size)
+	nilCache.Invalidate(sub)
+}
+
+func TestFileIndex_FileList(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), []byte("bb"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("aaaa"), 0644))
+
+	index := NewFileIndex()
+
+	fields, err := index.FileList(root, nil, FileListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 2)
+
+	// Sorting a cached listing doesn't change the listing cached for other requests.
+	bySize, err := index.FileList(root, nil, FileListOptions{SortOrder: FileSortSize, Descending: true})
+	assert.NoError(t, err)
+	assert.Equal(t, fields[0], bySize[0])
+	fields2, err := index.FileList(root, nil, FileListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, fields, fields2)
+
+	// Changes are not visible until the folder is invalidated.
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "c.txt"), []byte("c"), 0644))
+	fields, err = index.FileList(root, nil, FileListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 2)
+
+	index.Invalidate(filepath.Join(root, "c.txt"))
+	fields, err = index.FileList(root, nil, FileListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 3)
+
+	// Listings are cached separately for changed ignore lists and for clients that can't view hidden files.
+	fields, err = index.FileList(root, []string{"^c"}, FileListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 2)
+	fields, err = index.FileList(root, nil, FileListOptions{Hidden: HiddenFiles{"a.*"}})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 2)
+
+	// A nil index reads the listing directly.
+	var nilIndex *FileIndex
+	fields, err = nilIndex.FileList(root, nil, FileListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 3)
+}
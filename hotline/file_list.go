@@ -0,0 +1,111 @@
+package hotline
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File list sort orders for FieldFileSortOrder.
+const (
+	FileSortNone = 0 // Directory order, as sent to classic clients
+	FileSortName = 1
+	FileSortSize = 2 // By file size, or item count for folders
+	FileSortDate = 3 // By modification date
+)
+
+// FileListOptions are optional sort and filter settings that a client can request when listing a folder.
+type FileListOptions struct {
+	SortOrder  int    // One of the FileSort constants
+	Descending bool   // Reverse the sort order
+	Filter     string // Glob pattern, e.g. "*.sit", matched case-insensitively against file names; folders are always listed
+
+	Hidden HiddenFiles // Files to leave out of the listing; set by the server from the client's access, not by the client
+	FS     FileStore   // Store the forks of listed files are read from; set by the server, defaults to OSFileStore
+}
+
+// ReadFileListOptions reads the file list options from the Mobius extension fields of a TranGetFileNameList request.
+func ReadFileListOptions(t *Transaction) (FileListOptions, error) {
+	var opts FileListOptions
+
+	if data := t.GetField(FieldFileSortOrder).Data; len(data) > 0 {
+		switch len(data) {
+		case 1:
+			opts.SortOrder = int(data[0])
+		case 2:
+			opts.SortOrder = int(binary.BigEndian.Uint16(data))
+		default:
+			return opts, fmt.Errorf("invalid sort order field size %d", len(data))
+		}
+		if opts.SortOrder > FileSortDate {
+			return opts, fmt.Errorf("unknown sort order %d", opts.SortOrder)
+		}
+	}
+
+	if data := t.GetField(FieldFileSortDescending).Data; len(data) > 0 {
+		opts.Descending = slices.ContainsFunc(data, func(b byte) bool { return b != 0 })
+	}
+
+	if data := t.GetField(FieldFileFilter).Data; len(data) > 0 {
+		opts.Filter = string(data)
+		if _, err := filepath.Match(opts.Filter, ""); err != nil {
+			return opts, fmt.Errorf("invalid filter %q: %w", opts.Filter, err)
+		}
+	}
+
+	return opts, nil
+}
+
+// fileListEntry is a file in a folder listing along with the details needed to sort and filter it.
+type fileListEntry struct {
+	name    string
+	size    uint32
+	modTime time.Time
+	isDir   bool
+	field   Field
+}
+
+// apply filters and sorts the entries according to the options.
+func (opts FileListOptions) apply(entries []fileListEntry) []fileListEntry {
+	if opts.Filter != "" {
+		pattern := strings.ToLower(opts.Filter)
+		entries = slices.DeleteFunc(entries, func(e fileListEntry) bool {
+			if e.isDir {
+				return false
+			}
+			match, _ := filepath.Match(pattern, strings.ToLower(e.name))
+			return !match
+		})
+	}
+
+	var compare func(a, b fileListEntry) int
+	switch opts.SortOrder {
+	case FileSortName:
+		compare = func(a, b fileListEntry) int {
+			return strings.Compare(strings.ToLower(a.name), strings.ToLower(b.name))
+		}
+	case FileSortSize:
+		compare = func(a, b fileListEntry) int { return cmp.Compare(a.size, b.size) }
+	case FileSortDate:
+		compare = func(a, b fileListEntry) int { return a.modTime.Compare(b.modTime) }
+	default:
+		if opts.Descending {
+			slices.Reverse(entries)
+		}
+		return entries
+	}
+
+	slices.SortStableFunc(entries, func(a, b fileListEntry) int {
+		c := cmp.Or(compare(a, b), strings.Compare(a.name, b.name))
+		if opts.Descending {
+			return -c
+		}
+		return c
+	})
+
+	return entries
+}
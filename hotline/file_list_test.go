@@ -0,0 +1,114 @@
+package hotline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFileNameList_Options(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Now()
+	for i, f := range []struct {
+		name string
+		size int
+	}{
+		{"b.txt", 300},
+		{"A.sit", 100},
+		{"c.SIT", 200},
+	} {
+		path := filepath.Join(dir, f.name)
+		require.NoError(t, os.WriteFile(path, make([]byte, f.size), 0644))
+		require.NoError(t, os.Chtimes(path, now, now.Add(time.Duration(-i)*time.Hour)))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "folder"), 0755))
+
+	names := func(fields []Field) []string {
+		var got []string
+		for _, f := range fields {
+			var fnwi FileNameWithInfo
+			_, err := fnwi.Write(f.Data)
+			require.NoError(t, err)
+			got = append(got, string(fnwi.Name))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name string
+		opts FileListOptions
+		want []string
+	}{
+		{
+			name: "directory order",
+			want: []string{"A.sit", "b.txt", "c.SIT", "folder"},
+		},
+		{
+			name: "by name descending",
+			opts: FileListOptions{SortOrder: FileSortName, Descending: true},
+			want: []string{"folder", "c.SIT", "b.txt", "A.sit"},
+		},
+		{
+			name: "by size",
+			opts: FileListOptions{SortOrder: FileSortSize},
+			want: []string{"folder", "A.sit", "c.SIT", "b.txt"},
+		},
+		{
+			name: "by date with filter",
+			opts: FileListOptions{SortOrder: FileSortDate, Filter: "*.sit"},
+			want: []string{"c.SIT", "A.sit", "folder"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := GetFileNameList(dir, nil, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(fields))
+		})
+	}
+}
+
+func TestReadFileListOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       Transaction
+		want    FileListOptions
+		wantErr bool
+	}{
+		{
+			name: "no options",
+			t:    NewTransaction(TranGetFileNameList, [2]byte{}),
+		},
+		{
+			name: "all options",
+			t: NewTransaction(TranGetFileNameList, [2]byte{},
+				NewField(FieldFileSortOrder, []byte{0, FileSortDate}),
+				NewField(FieldFileSortDescending, []byte{1}),
+				NewField(FieldFileFilter, []byte("*.sit")),
+			),
+			want: FileListOptions{SortOrder: FileSortDate, Descending: true, Filter: "*.sit"},
+		},
+		{
+			name:    "unknown sort order",
+			t:       NewTransaction(TranGetFileNameList, [2]byte{}, NewField(FieldFileSortOrder, []byte{9})),
+			want:    FileListOptions{SortOrder: 9},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFileListOptions(&tt.t)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
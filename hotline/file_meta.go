@@ -0,0 +1,67 @@
+package hotline
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const MetaNameTemplate = ".meta_%s" // template string for file metadata filenames
+
+// FileMeta is information about a file that has no place in the Hotline info fork, such as who uploaded it.  It is
+// stored as YAML alongside the file in a sidecar file named using MetaNameTemplate.
+type FileMeta struct {
+	Uploader   string    `yaml:"Uploader" json:"uploader"`     // Login of the uploading account
+	UploadDate time.Time `yaml:"UploadDate" json:"uploadDate"` // Time the upload completed
+}
+
+// MetaPath returns the path of the metadata sidecar file for the file at path.
+func MetaPath(path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(MetaNameTemplate, filepath.Base(path)))
+}
+
+// ReadFileMeta returns the metadata for the file at path, or nil if none has been recorded.
+func ReadFileMeta(fileStore FileStore, path string) (*FileMeta, error) {
+	data, err := fileStore.ReadFile(MetaPath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read file metadata: %w", err)
+	}
+
+	var meta FileMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("read file metadata: %w", err)
+	}
+
+	return &meta, nil
+}
+
+// WriteFileMeta saves the metadata for the file at path.
+func WriteFileMeta(fileStore FileStore, path string, meta FileMeta) error {
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return fileStore.WriteFile(MetaPath(path), data, 0644)
+}
+
+// recordUploader saves the uploader of a completed upload in the file metadata.  Failures are logged rather than
+// failing an upload that has already been received.
+func recordUploader(fileStore FileStore, path string, fileTransfer *FileTransfer, logger *slog.Logger) {
+	if fileTransfer.ClientConn == nil || fileTransfer.ClientConn.Account == nil {
+		return
+	}
+
+	meta := FileMeta{Uploader: fileTransfer.ClientConn.Account.Login, UploadDate: time.Now()}
+	if err := WriteFileMeta(fileStore, path, meta); err != nil {
+		logger.Error("Error saving file metadata", "path", path, "err", err)
+	}
+}
@@ -0,0 +1,65 @@
+package hotline
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMeta(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.sit")
+	fs := &OSFileStore{}
+
+	meta, err := ReadFileMeta(fs, path)
+	assert.NoError(t, err)
+	assert.Nil(t, meta)
+
+	uploadDate := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, WriteFileMeta(fs, path, FileMeta{Uploader: "alice", UploadDate: uploadDate}))
+
+	meta, err = ReadFileMeta(fs, path)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", meta.Uploader)
+	assert.True(t, uploadDate.Equal(meta.UploadDate))
+	assert.FileExists(t, filepath.Join(dir, ".meta_file.sit"))
+}
+
+func TestUploadHandler_RecordsUploader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+
+	// A flattened file object with an empty info fork and a 4 byte data fork.
+	ffo := flattenedFileObject{
+		FlatFileHeader: FlatFileHeader{
+			Format:    [4]byte{'F', 'I', 'L', 'P'},
+			Version:   [2]byte{0, 1},
+			ForkCount: [2]byte{0, 2},
+		},
+		FlatFileInformationForkHeader: FlatFileForkHeader{ForkType: [4]byte{'I', 'N', 'F', 'O'}},
+		FlatFileDataForkHeader: FlatFileForkHeader{
+			ForkType: [4]byte{'D', 'A', 'T', 'A'},
+			DataSize: [4]byte{0, 0, 0, 4},
+		},
+	}
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(&ffo)
+	require.NoError(t, err)
+	buf.WriteString("data")
+
+	ft := &FileTransfer{
+		ClientConn:       &ClientConn{Account: &Account{Login: "alice"}},
+		bytesSentCounter: &WriteCounter{},
+	}
+	err = UploadHandler(&readWriteBuffer{input: &buf, output: &bytes.Buffer{}}, path, ft, &OSFileStore{}, NewTestLogger(), false)
+	require.NoError(t, err)
+
+	meta, err := ReadFileMeta(&OSFileStore{}, path)
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	assert.Equal(t, "alice", meta.Uploader)
+}
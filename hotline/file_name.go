@@ -0,0 +1,105 @@
+package hotline
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// windowsReservedNames are device names that Windows doesn't allow as file names, with or without an extension.
+var windowsReservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// ReservedFileName returns true if name is a device name that can't be used as a file name on Windows, such as "CON"
+// or "nul.txt".  Uploads with these names are refused on all platforms so that the file root can be moved between
+// servers.
+func ReservedFileName(name string) bool {
+	base, _, _ := strings.Cut(strings.TrimRight(name, ". "), ".")
+	base = strings.TrimRight(base, " ")
+
+	for _, reserved := range windowsReservedNames {
+		if strings.EqualFold(base, reserved) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// windowsNameChars maps characters that Windows doesn't allow in file names to characters in the Unicode private use
+// area, as Services for Macintosh does, so that names from Mac clients can be stored and listed unchanged.  Control
+// characters map to U+F001 through U+F01F, and a trailing space or period to U+F028 or U+F029.
+var windowsNameChars = map[byte]rune{
+	'"':  0xF020,
+	'*':  0xF021,
+	':':  0xF022,
+	'<':  0xF023,
+	'>':  0xF024,
+	'?':  0xF025,
+	'\\': 0xF026,
+	'|':  0xF027,
+}
+
+const (
+	windowsTrailingSpace  = 0xF028
+	windowsTrailingPeriod = 0xF029
+)
+
+// windowsFileName returns name with the characters that Windows doesn't allow in file names replaced as described by
+// windowsNameChars.  The names "." and ".." are returned unchanged so that they're still cleaned from paths.
+func windowsFileName(name string) string {
+	if strings.Trim(name, ".") == "" {
+		return name
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		last := i == len(name)-1
+
+		switch r, ok := windowsNameChars[c]; {
+		case ok:
+			b.WriteRune(r)
+		case c > 0 && c < 0x20:
+			b.WriteRune(0xF000 + rune(c))
+		case last && c == ' ':
+			b.WriteRune(windowsTrailingSpace)
+		case last && c == '.':
+			b.WriteRune(windowsTrailingPeriod)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// fromWindowsFileName reverses windowsFileName.
+func fromWindowsFileName(name string) string {
+	var b strings.Builder
+	for len(name) > 0 {
+		r, size := utf8.DecodeRuneInString(name)
+
+		switch {
+		case r >= 0xF001 && r < 0xF020:
+			b.WriteByte(byte(r - 0xF000))
+		case r == windowsTrailingSpace:
+			b.WriteByte(' ')
+		case r == windowsTrailingPeriod:
+			b.WriteByte('.')
+		case r >= 0xF020 && r < windowsTrailingSpace:
+			for c, mapped := range windowsNameChars {
+				if mapped == r {
+					b.WriteByte(c)
+				}
+			}
+		default:
+			b.WriteString(name[:size])
+		}
+		name = name[size:]
+	}
+
+	return b.String()
+}
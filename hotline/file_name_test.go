@@ -0,0 +1,57 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "CON", want: true},
+		{name: "nul", want: true},
+		{name: "Com1", want: true},
+		{name: "LPT9", want: true},
+		{name: "aux.txt", want: true},
+		{name: "NUL.tar.gz", want: true},
+		{name: "con .txt", want: true},
+		{name: "PRN.", want: true},
+		{name: "CONSOLE", want: false},
+		{name: "COM10", want: false},
+		{name: "my con", want: false},
+		{name: "readme.con", want: false},
+		{name: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ReservedFileName(tt.name))
+		})
+	}
+}
+
+func TestWindowsFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "ReadMe", want: "ReadMe"},
+		{name: "Mac OS 8.6: Update", want: "Mac OS 8.6\uf022 Update"},
+		{name: `What? <Really> "Yes" *|\`, want: "What\uf025 \uf023Really\uf024 \uf020Yes\uf020 \uf021\uf027\uf026"},
+		{name: "Icon\r", want: "Icon\uf00d"},
+		{name: "Trailing space ", want: "Trailing space\uf028"},
+		{name: "Ellipsis...", want: "Ellipsis..\uf029"},
+		{name: "Jägermeister", want: "Jägermeister"},
+		{name: ".", want: "."},
+		{name: "..", want: ".."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := windowsFileName(tt.name)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.name, fromWindowsFileName(got))
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package hotline
+
+// osFileName returns the name used on disk for a file or folder named name by a client.
+func osFileName(name string) string {
+	return windowsFileName(name)
+}
+
+// ClientFileName returns the name shown to clients for a file or folder named name on disk.
+func ClientFileName(name string) string {
+	return fromWindowsFileName(name)
+}
@@ -1,12 +1,10 @@
 package hotline
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
-	"errors"
 	"fmt"
-	"io"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -30,18 +28,30 @@ func fileItemScanner(data []byte, _ bool) (advance int, token []byte, err error)
 	}
 
 	advance = fileItemMinLen + int(data[2])
+	if advance > len(data) {
+		return 0, nil, nil
+	}
+
 	return advance, data[0:advance], nil
 }
 
 // Write implements the io.Writer interface for FilePathItem
 func (fpi *FilePathItem) Write(b []byte) (n int, err error) {
-	if len(b) < 3 {
-		return n, errors.New("buflen too small")
+	r := newBinReader(b)
+	if err := fpi.read(r); err != nil {
+		return 0, err
 	}
-	fpi.Len = b[2]
-	fpi.Name = b[fileItemMinLen : fpi.Len+fileItemMinLen]
 
-	return int(fpi.Len) + fileItemMinLen, nil
+	return r.Offset(), nil
+}
+
+// read reads a path item from r.  The name shares the data being read.
+func (fpi *FilePathItem) read(r *binReader) error {
+	r.Bytes(2) // Unused
+	fpi.Len = r.Uint8()
+	fpi.Name = r.Bytes(int(fpi.Len))
+
+	return r.Err()
 }
 
 type FilePath struct {
@@ -51,34 +61,32 @@ type FilePath struct {
 
 // Write implements io.Writer interface for FilePath
 func (fp *FilePath) Write(b []byte) (n int, err error) {
-	reader := bytes.NewReader(b)
-	err = binary.Read(reader, binary.BigEndian, &fp.ItemCount)
-	if err != nil && !errors.Is(err, io.EOF) {
-		return n, err
+	if len(b) == 0 {
+		return 0, nil
 	}
-	if errors.Is(err, io.EOF) {
-		return n, nil
+
+	// Copy the data so that the item names don't share the caller's buffer.
+	r := newBinReader(bytes.Clone(b))
+	r.Read(fp.ItemCount[:])
+	if err := r.Err(); err != nil {
+		return 0, err
 	}
 
-	scanner := bufio.NewScanner(reader)
-	scanner.Split(fileItemScanner)
+	// Each item is at least 3 bytes.
+	itemCount := int(fp.Len())
+	if itemCount*fileItemMinLen > r.Len() {
+		return 0, fmt.Errorf("%w: %d path items don't fit in %d bytes", ErrShortData, itemCount, r.Len())
+	}
 
-	for i := 0; i < int(binary.BigEndian.Uint16(fp.ItemCount[:])); i++ {
+	for i := 0; i < itemCount; i++ {
 		var fpi FilePathItem
-		scanner.Scan()
-
-		// Make a new []byte slice and copy the scanner bytes to it.  This is critical to avoid a data race as the
-		// scanner re-uses the buffer for subsequent scans.
-		buf := make([]byte, len(scanner.Bytes()))
-		copy(buf, scanner.Bytes())
-
-		if _, err := fpi.Write(buf); err != nil {
-			return n, err
+		if err := fpi.read(r); err != nil {
+			return 0, err
 		}
 		fp.Items = append(fp.Items, fpi)
 	}
 
-	return n, nil
+	return r.Offset(), nil
 }
 
 // IsDropbox checks if a FilePath matches the special drop box folder type
@@ -98,6 +106,16 @@ func (fp *FilePath) IsUploadDir() bool {
 	return strings.Contains(strings.ToLower(string(fp.Items[fp.Len()-1].Name)), "upload")
 }
 
+// String returns the path items joined with "/", e.g. "Uploads/Games".
+func (fp *FilePath) String() string {
+	var items []string
+	for _, item := range fp.Items {
+		items = append(items, string(item.Name))
+	}
+
+	return strings.Join(items, "/")
+}
+
 func (fp *FilePath) Len() uint16 {
 	return binary.BigEndian.Uint16(fp.ItemCount[:])
 }
@@ -110,19 +128,39 @@ func ReadPath(fileRoot string, filePath, fileName []byte) (fullPath string, err
 		}
 	}
 
+	// Each name is decoded and translated on its own, so that the file root isn't decoded as Mac Roman and characters
+	// that are path separators or otherwise special on Windows can't change the meaning of the path.
 	var subPath string
 	for _, pathItem := range fp.Items {
-		subPath = filepath.Join("/", subPath, string(pathItem.Name))
+		name, err := txtDecoder.String(string(pathItem.Name))
+		if err != nil {
+			return "", fmt.Errorf("invalid filepath encoding: %w", err)
+		}
+		subPath = filepath.Join("/", subPath, osFileName(name))
+	}
+
+	name, err := txtDecoder.String(string(fileName))
+	if err != nil {
+		return "", fmt.Errorf("invalid filepath encoding: %w", err)
 	}
 
 	fullPath = filepath.Join(
 		fileRoot,
 		subPath,
-		filepath.Join("/", string(fileName)),
+		filepath.Join("/", osFileName(name)),
 	)
-	fullPath, err = txtDecoder.String(fullPath)
-	if err != nil {
-		return "", fmt.Errorf("invalid filepath encoding: %w", err)
-	}
 	return fullPath, nil
 }
+
+// RelativePath returns the path of a file or folder relative to the file root, e.g. "Uploads/file.sit", from the
+// FieldFilePath and FieldFileName of a request.
+func RelativePath(filePath, fileName []byte) (string, error) {
+	var fp FilePath
+	if filePath != nil {
+		if _, err := fp.Write(filePath); err != nil {
+			return "", err
+		}
+	}
+
+	return txtDecoder.String(path.Join(fp.String(), string(fileName)))
+}
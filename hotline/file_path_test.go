@@ -142,6 +142,20 @@ func Test_readPath(t *testing.T) {
 			},
 			want: "/usr/local/var/mobius/Files/A SubDir/foo",
 		},
+		{
+			name: "when fileRoot contains non-ASCII characters",
+			args: args{
+				fileRoot: "/srv/Müsik/Files",
+				filePath: []byte{
+					0x00, 0x01,
+					0x00, 0x00,
+					0x04,
+					0x4a, 0x8a, 0x67, 0x65, // "Jäge" in Mac Roman
+				},
+				fileName: []byte("foo"),
+			},
+			want: "/srv/Müsik/Files/Jäge/foo",
+		},
 		{
 			name: "when filePath and fileName are nil",
 			args: args{
@@ -239,3 +253,45 @@ func Test_fileItemScanner(t *testing.T) {
 		})
 	}
 }
+
+func TestRelativePath(t *testing.T) {
+	got, err := RelativePath(EncodeFilePath("Uploads/Games"), []byte("game.sit"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Uploads/Games/game.sit", got)
+
+	got, err = RelativePath(nil, []byte("file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "file.txt", got)
+}
+
+func FuzzFilePath_Write(f *testing.F) {
+	f.Add([]byte{
+		0x00, 0x02,
+		0x00, 0x00, 0x05, 0x46, 0x69, 0x72, 0x73, 0x74,
+		0x00, 0x00, 0x03, 0x53, 0x75, 0x62,
+	})
+	f.Add([]byte{0xff, 0xff, 0x00, 0x00, 0xff})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var fp FilePath
+		n, err := fp.Write(b)
+		if err != nil {
+			return
+		}
+		assert.LessOrEqual(t, n, len(b))
+		assert.Len(t, fp.Items, int(fp.Len()))
+	})
+}
+
+func FuzzFileItemScanner(f *testing.F) {
+	f.Add([]byte{0, 0, 0x09, 0x73, 0x75, 0x62, 0x66, 0x6f, 0x6c, 0x64, 0x65, 0x72})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		advance, token, err := fileItemScanner(data, false)
+		if err != nil {
+			return
+		}
+		assert.LessOrEqual(t, advance, len(data))
+		assert.LessOrEqual(t, len(token), len(data))
+	})
+}
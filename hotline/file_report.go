@@ -0,0 +1,47 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+// File report reasons accepted from users.
+var FileReportReasons = []string{"spam", "illegal", "broken", "other"}
+
+// FileReport is a user submitted flag on a file that needs moderator attention.
+type FileReport struct {
+	ID       string    `yaml:"ID" json:"id"`
+	Path     string    `yaml:"Path" json:"path"`     // Path of the file relative to the file root
+	Reason   string    `yaml:"Reason" json:"reason"` // One of FileReportReasons
+	Comment  string    `yaml:"Comment" json:"comment"`
+	Reporter string    `yaml:"Reporter" json:"reporter"` // Login of the reporting account
+	Date     time.Time `yaml:"Date" json:"date"`
+}
+
+type FileReportMgr interface {
+	Add(report FileReport) (FileReport, error)
+	List() []FileReport
+	Delete(id string) error
+}
+
+type MockFileReportMgr struct {
+	mock.Mock
+}
+
+func (m *MockFileReportMgr) Add(report FileReport) (FileReport, error) {
+	args := m.Called(report)
+
+	return args.Get(0).(FileReport), args.Error(1)
+}
+
+func (m *MockFileReportMgr) List() []FileReport {
+	args := m.Called()
+
+	return args.Get(0).([]FileReport)
+}
+
+func (m *MockFileReportMgr) Delete(id string) error {
+	args := m.Called(id)
+
+	return args.Error(0)
+}
@@ -3,6 +3,7 @@ package hotline
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 )
 
 // FileResumeData is sent when a client or server would like to resume a transfer from an offset
@@ -81,22 +82,29 @@ func (frd *FileResumeData) BinaryMarshal() ([]byte, error) {
 }
 
 func (frd *FileResumeData) UnmarshalBinary(b []byte) error {
-	frd.Format = [4]byte{b[0], b[1], b[2], b[3]}
-	frd.Version = [2]byte{b[4], b[5]}
-	frd.ForkCount = [2]byte{b[40], b[41]}
+	r := newBinReader(b)
+	r.Read(frd.Format[:])
+	r.Read(frd.Version[:])
+	r.Read(frd.RSVD[:])
+	r.Read(frd.ForkCount[:])
 
+	// Files have at most 3 forks, so the high byte of the count is ignored, as it is by other implementations.
 	for i := 0; i < int(frd.ForkCount[1]); i++ {
 		var fil ForkInfoList
-		start := 42 + i*16
-		end := start + 16
-
-		r := bytes.NewReader(b[start:end])
-		if err := binary.Read(r, binary.BigEndian, &fil); err != nil {
-			return err
+		r.Read(fil.Fork[:])
+		r.Read(fil.DataSize[:])
+		r.Read(fil.RSVDA[:])
+		r.Read(fil.RSVDB[:])
+		if err := r.Err(); err != nil {
+			return fmt.Errorf("read file resume data: %w", err)
 		}
 
 		frd.ForkInfoList = append(frd.ForkInfoList, fil)
 	}
 
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("read file resume data: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,39 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileResumeData_UnmarshalBinary(t *testing.T) {
+	frd := NewFileResumeData([]ForkInfoList{*NewForkInfoList([]byte{0, 0, 0x01, 0x00})})
+	b, err := frd.BinaryMarshal()
+	assert.NoError(t, err)
+
+	var got FileResumeData
+	assert.NoError(t, got.UnmarshalBinary(b))
+	assert.Equal(t, *frd, got)
+
+	// Resume data with fewer forks than its count is rejected.
+	got = FileResumeData{}
+	assert.ErrorIs(t, got.UnmarshalBinary(b[:len(b)-1]), ErrShortData)
+
+	// As is resume data shorter than its header.
+	got = FileResumeData{}
+	assert.ErrorIs(t, got.UnmarshalBinary([]byte("RFLT")), ErrShortData)
+}
+
+func FuzzFileResumeData_UnmarshalBinary(f *testing.F) {
+	b, _ := NewFileResumeData([]ForkInfoList{*NewForkInfoList([]byte{0, 0, 0x01, 0x00})}).BinaryMarshal()
+	f.Add(b)
+	f.Add([]byte("RFLT"))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var frd FileResumeData
+		if err := frd.UnmarshalBinary(b); err != nil {
+			return
+		}
+		assert.Len(t, frd.ForkInfoList, int(frd.ForkCount[1]))
+	})
+}
@@ -10,6 +10,7 @@ import (
 type FileStore interface {
 	Create(name string) (*os.File, error)
 	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
 	Open(name string) (*os.File, error)
 	OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error)
 	Remove(name string) error
@@ -19,6 +20,7 @@ type FileStore interface {
 	Symlink(oldname, newname string) error
 	WriteFile(name string, data []byte, perm fs.FileMode) error
 	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]os.DirEntry, error)
 }
 
 type OSFileStore struct{}
@@ -27,6 +29,10 @@ func (fs *OSFileStore) Mkdir(name string, perm os.FileMode) error {
 	return os.Mkdir(name, perm)
 }
 
+func (fs *OSFileStore) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
 func (fs *OSFileStore) Stat(name string) (os.FileInfo, error) {
 	return os.Stat(name)
 }
@@ -63,6 +69,10 @@ func (fs *OSFileStore) ReadFile(name string) ([]byte, error) {
 	return os.ReadFile(name)
 }
 
+func (fs *OSFileStore) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
 func (fs *OSFileStore) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
 	return os.OpenFile(name, flag, perm)
 }
@@ -76,6 +86,11 @@ func (mfs *MockFileStore) Mkdir(name string, perm os.FileMode) error {
 	return args.Error(0)
 }
 
+func (mfs *MockFileStore) MkdirAll(path string, perm os.FileMode) error {
+	args := mfs.Called(path, perm)
+	return args.Error(0)
+}
+
 func (mfs *MockFileStore) Stat(name string) (os.FileInfo, error) {
 	args := mfs.Called(name)
 	if args.Get(0) == nil {
@@ -129,6 +144,14 @@ func (mfs *MockFileStore) ReadFile(name string) ([]byte, error) {
 	return args.Get(0).([]byte), args.Error(1)
 }
 
+func (mfs *MockFileStore) ReadDir(name string) ([]os.DirEntry, error) {
+	args := mfs.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]os.DirEntry), args.Error(1)
+}
+
 type MockFileInfo struct {
 	mock.Mock
 }
@@ -0,0 +1,241 @@
+package hotline
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxSymlinks is the number of symlinks followed when resolving a path before giving up.
+const maxSymlinks = 255
+
+// ErrOutsideRoot is returned by ConfinedFileStore for paths that resolve outside of its roots.
+var ErrOutsideRoot = errors.New("path resolves outside of the file root")
+
+// ConfinedFileStore is an OSFileStore that refuses to operate on paths that resolve outside of its root folders, such
+// as through a symlink, as a defense against mistakes in the validation of paths requested by clients.  Paths are
+// checked after resolving symlinks, and on Linux files are then opened with openat2 and RESOLVE_BENEATH so that a
+// folder can't be replaced by a symlink between the check and the open.
+type ConfinedFileStore struct {
+	OSFileStore
+
+	mu    sync.RWMutex
+	roots []string // Absolute paths of the roots with symlinks resolved
+}
+
+// NewConfinedFileStore returns a ConfinedFileStore for the given root folders, which need not exist yet.
+func NewConfinedFileStore(roots ...string) (*ConfinedFileStore, error) {
+	var cfs ConfinedFileStore
+	for _, root := range roots {
+		if err := cfs.AddRoot(root); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cfs, nil
+}
+
+// AddRoot allows operations on paths within root.
+func (cfs *ConfinedFileStore) AddRoot(root string) error {
+	resolved, err := resolvePath(root)
+	if err != nil {
+		return err
+	}
+
+	cfs.mu.Lock()
+	defer cfs.mu.Unlock()
+
+	for _, r := range cfs.roots {
+		if r == resolved {
+			return nil
+		}
+	}
+	cfs.roots = append(cfs.roots, resolved)
+
+	return nil
+}
+
+// resolve returns the root containing name, and the path of name relative to it with symlinks resolved.  If follow is
+// false, a symlink in the last element of name is not followed, as for operations on the symlink itself.
+func (cfs *ConfinedFileStore) resolve(op, name string, follow bool) (root, rel string, err error) {
+	var resolved string
+	if follow {
+		resolved, err = resolvePath(name)
+	} else {
+		dir, base := filepath.Split(filepath.Clean(name))
+		resolved, err = resolvePath(dir)
+		resolved = filepath.Join(resolved, base)
+	}
+	if err != nil {
+		return "", "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	cfs.mu.RLock()
+	defer cfs.mu.RUnlock()
+
+	for _, root := range cfs.roots {
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root, rel, nil
+		}
+	}
+
+	return "", "", &fs.PathError{Op: op, Path: name, Err: ErrOutsideRoot}
+}
+
+// check returns an error if name resolves outside of the roots.
+func (cfs *ConfinedFileStore) check(op, name string, follow bool) error {
+	_, _, err := cfs.resolve(op, name, follow)
+	return err
+}
+
+// resolvePath returns the absolute path of name with symlinks resolved.  Elements of name that don't exist yet are
+// kept as they are, while symlinks to files that don't exist are resolved to the path they would create.
+func resolvePath(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	for links := 0; ; {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		if info, err := os.Lstat(abs); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			if links++; links > maxSymlinks {
+				return "", errors.New("too many levels of symbolic links")
+			}
+
+			target, err := os.Readlink(abs)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(abs), target)
+			}
+			abs = target
+			continue
+		}
+
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return abs, nil
+		}
+		missing = append([]string{filepath.Base(abs)}, missing...)
+		abs = parent
+	}
+}
+
+func (cfs *ConfinedFileStore) Mkdir(name string, perm os.FileMode) error {
+	if err := cfs.check("mkdir", name, false); err != nil {
+		return err
+	}
+	return cfs.OSFileStore.Mkdir(name, perm)
+}
+
+func (cfs *ConfinedFileStore) MkdirAll(path string, perm os.FileMode) error {
+	if err := cfs.check("mkdir", path, false); err != nil {
+		return err
+	}
+	return cfs.OSFileStore.MkdirAll(path, perm)
+}
+
+func (cfs *ConfinedFileStore) Stat(name string) (os.FileInfo, error) {
+	if err := cfs.check("stat", name, true); err != nil {
+		return nil, err
+	}
+	return cfs.OSFileStore.Stat(name)
+}
+
+func (cfs *ConfinedFileStore) Open(name string) (*os.File, error) {
+	return cfs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (cfs *ConfinedFileStore) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	root, rel, err := cfs.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := openBeneath(root, rel, flag, perm, name)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return cfs.OSFileStore.OpenFile(filepath.Join(root, rel), flag, perm)
+	}
+
+	return f, err
+}
+
+func (cfs *ConfinedFileStore) Create(name string) (*os.File, error) {
+	return cfs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (cfs *ConfinedFileStore) Symlink(oldname, newname string) error {
+	if err := cfs.check("symlink", oldname, true); err != nil {
+		return err
+	}
+	if err := cfs.check("symlink", newname, false); err != nil {
+		return err
+	}
+	return cfs.OSFileStore.Symlink(oldname, newname)
+}
+
+func (cfs *ConfinedFileStore) RemoveAll(name string) error {
+	if err := cfs.check("removeall", name, false); err != nil {
+		return err
+	}
+	return cfs.OSFileStore.RemoveAll(name)
+}
+
+func (cfs *ConfinedFileStore) Remove(name string) error {
+	if err := cfs.check("remove", name, false); err != nil {
+		return err
+	}
+	return cfs.OSFileStore.Remove(name)
+}
+
+func (cfs *ConfinedFileStore) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := cfs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (cfs *ConfinedFileStore) Rename(oldpath string, newpath string) error {
+	if err := cfs.check("rename", oldpath, false); err != nil {
+		return err
+	}
+	if err := cfs.check("rename", newpath, false); err != nil {
+		return err
+	}
+	return cfs.OSFileStore.Rename(oldpath, newpath)
+}
+
+func (cfs *ConfinedFileStore) ReadFile(name string) ([]byte, error) {
+	if err := cfs.check("open", name, true); err != nil {
+		return nil, err
+	}
+	return cfs.OSFileStore.ReadFile(name)
+}
+
+func (cfs *ConfinedFileStore) ReadDir(name string) ([]os.DirEntry, error) {
+	if err := cfs.check("open", name, true); err != nil {
+		return nil, err
+	}
+	return cfs.OSFileStore.ReadDir(name)
+}
@@ -0,0 +1,98 @@
+package hotline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfinedFileStore(t *testing.T) {
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644))
+
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "Uploads"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "ReadMe"), []byte("hello"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(root, "ReadMe"), filepath.Join(root, "Alias")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "Escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "new"), filepath.Join(root, "Uploads", "Dangling")))
+
+	cfs, err := NewConfinedFileStore(root, filepath.Join(t.TempDir(), "Pending"))
+	require.NoError(t, err)
+
+	t.Run("files within the root", func(t *testing.T) {
+		data, err := cfs.ReadFile(filepath.Join(root, "ReadMe"))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+
+		f, err := cfs.Open(filepath.Join(root, "Alias"))
+		require.NoError(t, err)
+		assert.NoError(t, f.Close())
+
+		assert.NoError(t, cfs.WriteFile(filepath.Join(root, "Uploads", "new"), []byte("new"), 0644))
+		assert.NoError(t, cfs.Mkdir(filepath.Join(root, "Uploads", "Folder"), 0755))
+		assert.NoError(t, cfs.Rename(filepath.Join(root, "Uploads", "new"), filepath.Join(root, "Uploads", "Folder", "new")))
+	})
+
+	t.Run("files through a symlink out of the root", func(t *testing.T) {
+		_, err := cfs.ReadFile(filepath.Join(root, "Escape", "secret"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		_, err = cfs.Open(filepath.Join(root, "Escape", "secret"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		_, err = cfs.Stat(filepath.Join(root, "Escape"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		err = cfs.Mkdir(filepath.Join(root, "Escape", "Folder"), 0755)
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		err = cfs.Rename(filepath.Join(root, "ReadMe"), filepath.Join(root, "Escape", "ReadMe"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		err = cfs.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "Uploads", "Alias"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		_, err = cfs.ReadDir(filepath.Join(root, "Escape"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+
+		err = cfs.MkdirAll(filepath.Join(root, "Escape", "Folder", "Subfolder"), 0755)
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+	})
+
+	t.Run("file lists", func(t *testing.T) {
+		entries, err := readFileList(root, nil, FileListOptions{FS: cfs})
+		require.NoError(t, err)
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.name)
+		}
+		assert.ElementsMatch(t, []string{"Alias", "ReadMe", "Uploads"}, names)
+	})
+
+	t.Run("files through a dangling symlink out of the root", func(t *testing.T) {
+		err := cfs.WriteFile(filepath.Join(root, "Uploads", "Dangling"), []byte("new"), 0644)
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+		assert.NoFileExists(t, filepath.Join(outside, "new"))
+	})
+
+	t.Run("symlinks themselves", func(t *testing.T) {
+		assert.NoError(t, cfs.Remove(filepath.Join(root, "Escape")))
+		assert.FileExists(t, filepath.Join(outside, "secret"))
+	})
+
+	t.Run("paths outside of the roots", func(t *testing.T) {
+		_, err := cfs.Stat(filepath.Join(root, "..", filepath.Base(outside), "secret"))
+		assert.ErrorIs(t, err, ErrOutsideRoot)
+	})
+
+	t.Run("roots that don't exist yet", func(t *testing.T) {
+		assert.NoError(t, cfs.AddRoot(filepath.Join(outside, "Later")))
+		assert.NoError(t, cfs.Mkdir(filepath.Join(outside, "Later"), 0755))
+		assert.NoError(t, cfs.WriteFile(filepath.Join(outside, "Later", "file"), nil, 0644))
+	})
+}
@@ -0,0 +1,14 @@
+package hotline
+
+// nativeForks is implemented by FileStores that keep resource forks and Finder type and creator codes in the file
+// system's own metadata rather than in .rsrc_ and .info_ sidecar files.
+type nativeForks interface {
+	// rsrcForkPath returns the path to the resource fork of the file at path.
+	rsrcForkPath(path string) string
+
+	// finderInfo returns the Finder type and creator codes of the file at path, which are zero if they aren't set.
+	finderInfo(path string) (typeCode, creatorCode [4]byte, err error)
+
+	// setFinderInfo sets the Finder type and creator codes of the file at path.
+	setFinderInfo(path string, typeCode, creatorCode [4]byte) error
+}
@@ -0,0 +1,114 @@
+//go:build darwin
+
+package hotline
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	rsrcForkSuffix = "/..namedfork/rsrc"
+	finderInfoAttr = "com.apple.FinderInfo"
+	finderInfoSize = 32
+)
+
+// NativeForkFileStore is a FileStore that keeps resource forks in the real resource forks of files, and Finder type and
+// creator codes in the com.apple.FinderInfo extended attribute, so that they are seen by other Mac software and kept
+// when files are copied with the Finder.  Other operations are passed to the wrapped FileStore.
+type NativeForkFileStore struct {
+	FileStore
+}
+
+// NewNativeForkFileStore returns a NativeForkFileStore wrapping fileStore.  It returns errors.ErrUnsupported on
+// platforms other than macOS.
+func NewNativeForkFileStore(fileStore FileStore) (FileStore, error) {
+	return &NativeForkFileStore{FileStore: fileStore}, nil
+}
+
+func (nfs *NativeForkFileStore) rsrcForkPath(path string) string {
+	return path + rsrcForkSuffix
+}
+
+// checkFork returns true if name is the path to a resource fork, after checking the file it belongs to with the
+// wrapped FileStore, e.g. so that a ConfinedFileStore can refuse it.
+func (nfs *NativeForkFileStore) checkFork(name string) (bool, error) {
+	dataPath, ok := strings.CutSuffix(name, rsrcForkSuffix)
+	if !ok {
+		return false, nil
+	}
+
+	_, err := nfs.FileStore.Stat(dataPath)
+
+	return true, err
+}
+
+func (nfs *NativeForkFileStore) Stat(name string) (fs.FileInfo, error) {
+	if fork, err := nfs.checkFork(name); fork {
+		if err != nil {
+			return nil, err
+		}
+		return os.Stat(name)
+	}
+
+	return nfs.FileStore.Stat(name)
+}
+
+func (nfs *NativeForkFileStore) Open(name string) (*os.File, error) {
+	return nfs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (nfs *NativeForkFileStore) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	if fork, err := nfs.checkFork(name); fork {
+		if err != nil {
+			return nil, err
+		}
+		return os.OpenFile(name, flag, perm)
+	}
+
+	return nfs.FileStore.OpenFile(name, flag, perm)
+}
+
+func (nfs *NativeForkFileStore) finderInfo(path string) (typeCode, creatorCode [4]byte, err error) {
+	if _, err := nfs.FileStore.Stat(path); err != nil {
+		return typeCode, creatorCode, err
+	}
+
+	info := make([]byte, finderInfoSize)
+	n, err := unix.Getxattr(path, finderInfoAttr, info)
+	if errors.Is(err, unix.ENOATTR) {
+		return typeCode, creatorCode, nil
+	}
+	if err != nil {
+		return typeCode, creatorCode, &fs.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	if n < 8 {
+		return typeCode, creatorCode, nil
+	}
+
+	return [4]byte(info[0:4]), [4]byte(info[4:8]), nil
+}
+
+func (nfs *NativeForkFileStore) setFinderInfo(path string, typeCode, creatorCode [4]byte) error {
+	if _, err := nfs.FileStore.Stat(path); err != nil {
+		return err
+	}
+
+	// The rest of the Finder info, such as the label color, is kept.
+	info := make([]byte, finderInfoSize)
+	if _, err := unix.Getxattr(path, finderInfoAttr, info); err != nil && !errors.Is(err, unix.ENOATTR) {
+		return &fs.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	copy(info[0:4], typeCode[:])
+	copy(info[4:8], creatorCode[:])
+
+	if err := unix.Setxattr(path, finderInfoAttr, info, 0); err != nil {
+		return &fs.PathError{Op: "setxattr", Path: path, Err: err}
+	}
+
+	return nil
+}
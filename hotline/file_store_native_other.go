@@ -0,0 +1,12 @@
+//go:build !darwin
+
+package hotline
+
+import (
+	"errors"
+)
+
+// NewNativeForkFileStore is only implemented on macOS; elsewhere forks are kept in sidecar files.
+func NewNativeForkFileStore(fileStore FileStore) (FileStore, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package hotline
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openBeneath opens rel relative to the root folder with openat2 and RESOLVE_BENEATH, which fails if resolving rel
+// leaves root, e.g. because an element was replaced by a symlink.  name is the name of the returned file.  It returns
+// errors.ErrUnsupported on kernels without openat2.
+func openBeneath(root, rel string, flag int, perm fs.FileMode, name string) (*os.File, error) {
+	dirfd, err := unix.Open(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: root, Err: err}
+	}
+	defer unix.Close(dirfd)
+
+	how := unix.OpenHow{
+		Flags:   uint64(flag) | unix.O_CLOEXEC,
+		Mode:    uint64(perm.Perm()),
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+
+	for {
+		fd, err := unix.Openat2(dirfd, rel, &how)
+		switch {
+		case err == nil:
+			return os.NewFile(uintptr(fd), name), nil
+		case errors.Is(err, unix.EINTR), errors.Is(err, unix.EAGAIN):
+			continue
+		case errors.Is(err, unix.ENOSYS):
+			return nil, errors.ErrUnsupported
+		case errors.Is(err, unix.EXDEV):
+			return nil, &fs.PathError{Op: "open", Path: name, Err: ErrOutsideRoot}
+		default:
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package hotline
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// openBeneath is only implemented on Linux; elsewhere ConfinedFileStore relies on checking the resolved path.
+func openBeneath(root, rel string, flag int, perm fs.FileMode, name string) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
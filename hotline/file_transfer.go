@@ -2,6 +2,7 @@ package hotline
 
 import (
 	"bufio"
+	"context"
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
@@ -15,6 +16,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Folder download actions.  Send by the client to indicate the next action the server should take
@@ -38,15 +40,35 @@ const (
 
 type FileTransferID [4]byte
 
+// DefaultFileTransferTTL is how long a file transfer reservation remains valid if the client never connects to the
+// file transfer port to claim it.
+const DefaultFileTransferTTL = 10 * time.Minute
+
 type FileTransferMgr interface {
 	Add(ft *FileTransfer)
 	Get(id FileTransferID) *FileTransfer
+	Claim(id FileTransferID) *FileTransfer
 	Delete(id FileTransferID)
+	Expire(before time.Time) []*FileTransfer
+
+	// QueuePosition returns the number of downloads waiting ahead of download ft plus one, or 0 if ft can start now.
+	QueuePosition(ft *FileTransfer, limits DownloadLimits) int
+
+	// StartDownload waits until download ft can start within the limits, calling onWait with its queue position
+	// whenever it changes while waiting.  The returned func frees the download slot once the download is done.
+	StartDownload(ctx context.Context, ft *FileTransfer, limits func() DownloadLimits, onWait func(position int)) (func(), error)
 }
 
+// MemFileTransferMgr allocates a unique, non-zero reference number for each file transfer, and queues downloads
+// beyond the simultaneous download limits.
 type MemFileTransferMgr struct {
 	fileTransfers map[FileTransferID]*FileTransfer
 
+	queue        []*FileTransfer     // Downloads that haven't started, in the order they were requested
+	active       map[*ClientConn]int // Number of downloads in progress by each client
+	activeTotal  int
+	queueChanged chan struct{} // Closed and replaced when a download finishes or leaves the queue
+
 	mu sync.Mutex
 }
 
@@ -56,13 +78,23 @@ func NewMemFileTransferMgr() *MemFileTransferMgr {
 	}
 }
 
+// Add assigns ft a reference number that is not in use by any other transfer and registers it.
 func (ftm *MemFileTransferMgr) Add(ft *FileTransfer) {
 	ftm.mu.Lock()
 	defer ftm.mu.Unlock()
 
-	_, _ = rand.Read(ft.RefNum[:])
+	for {
+		_, _ = rand.Read(ft.RefNum[:])
+		if _, ok := ftm.fileTransfers[ft.RefNum]; !ok && ft.RefNum != (FileTransferID{}) {
+			break
+		}
+	}
+	ft.created = time.Now()
 
 	ftm.fileTransfers[ft.RefNum] = ft
+	if ft.Type == FileDownload || ft.Type == FolderDownload {
+		ftm.queue = append(ftm.queue, ft)
+	}
 
 	ft.ClientConn.ClientFileTransferMgr.Add(ft.Type, ft)
 }
@@ -74,16 +106,90 @@ func (ftm *MemFileTransferMgr) Get(id FileTransferID) *FileTransfer {
 	return ftm.fileTransfers[id]
 }
 
+// Claim returns the transfer with the reference number id and marks it as claimed so that it does not expire.  A
+// transfer can only be claimed once; Claim returns nil if id is unknown, expired, or already claimed.
+func (ftm *MemFileTransferMgr) Claim(id FileTransferID) *FileTransfer {
+	ftm.mu.Lock()
+	defer ftm.mu.Unlock()
+
+	ft := ftm.fileTransfers[id]
+	if ft == nil || ft.claimed {
+		return nil
+	}
+	ft.claimed = true
+
+	return ft
+}
+
 func (ftm *MemFileTransferMgr) Delete(id FileTransferID) {
 	ftm.mu.Lock()
 	defer ftm.mu.Unlock()
 
+	ftm.delete(id)
+}
+
+// delete removes the transfer with the reference number id.  The caller must hold mu.
+func (ftm *MemFileTransferMgr) delete(id FileTransferID) {
 	ft := ftm.fileTransfers[id]
+	if ft == nil {
+		return
+	}
 
 	ft.ClientConn.ClientFileTransferMgr.Delete(ft.Type, id)
 
 	delete(ftm.fileTransfers, id)
+	ftm.dequeue(ft)
+}
+
+// Expire removes and returns the unclaimed transfers that were added before the given time.
+func (ftm *MemFileTransferMgr) Expire(before time.Time) []*FileTransfer {
+	ftm.mu.Lock()
+	defer ftm.mu.Unlock()
+
+	var expired []*FileTransfer
+	for id, ft := range ftm.fileTransfers {
+		if !ft.claimed && ft.created.Before(before) {
+			ftm.delete(id)
+			expired = append(expired, ft)
+		}
+	}
+
+	return expired
+}
+
+// transferExpiryScheduler periodically expires file transfer reservations that have not been claimed within the
+// FileTransferTTL, for example because the client never connected to the file transfer port.
+func (s *Server) transferExpiryScheduler(ctx context.Context) {
+	ttl := s.Config.FileTransferTTL
+	if ttl <= 0 {
+		ttl = DefaultFileTransferTTL
+	}
+
+	ticker := time.NewTicker(min(ttl, time.Minute))
+	defer ticker.Stop()
 
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireFileTransfers(time.Now().Add(-ttl))
+		}
+	}
+}
+
+func (s *Server) expireFileTransfers(before time.Time) {
+	for _, ft := range s.FileTransferMgr.Expire(before) {
+		s.Stats.Increment(StatAbandonedTransfers)
+
+		s.Logger.Info(
+			"Expired unclaimed file transfer",
+			"connID", ft.ClientConn.ConnID,
+			"type", fileTransferTypeNames[ft.Type],
+			"name", string(ft.FileName),
+			"age", time.Since(ft.created).Round(time.Second),
+		)
+	}
 }
 
 type FileTransfer struct {
@@ -98,6 +204,9 @@ type FileTransfer struct {
 	Options          []byte
 	bytesSentCounter *WriteCounter
 	ClientConn       *ClientConn
+
+	created time.Time // when the reference number was allocated
+	claimed bool      // true once a file transfer connection has used the reference number
 }
 
 // WriteCounter counts the number of bytes written to it.
@@ -196,11 +305,23 @@ func (fu *folderUpload) FormattedPath() string {
 	// TODO: implement scanner interface instead?
 	for i := uint16(0); i < pathItemLen; i++ {
 		segLen := pathData[2]
-		pathSegments = append(pathSegments, string(pathData[3:3+segLen]))
+		pathSegments = append(pathSegments, osFileName(string(pathData[3:3+segLen])))
 		pathData = pathData[3+segLen:]
 	}
 
-	return filepath.Join(pathSegments...)
+	// Rooting the path keeps ".." segments from escaping the upload folder once it's joined to it.
+	return filepath.Join(append([]string{"/"}, pathSegments...)...)
+}
+
+// reserved returns true if any name in the path is reserved on Windows.
+func (fu *folderUpload) reserved() bool {
+	for _, name := range strings.Split(filepath.ToSlash(fu.FormattedPath()), "/") {
+		if ReservedFileName(name) {
+			return true
+		}
+	}
+
+	return false
 }
 
 type FileHeader struct {
@@ -314,13 +435,13 @@ func UploadHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileTransfe
 
 	// Check for existing file.  If found, do not proceed.  This is an invalid scenario, as the file upload transaction
 	// handler should have returned an error to the client indicating there was an existing file present.
-	_, err := os.Stat(fullPath)
+	_, err := fileStore.Stat(fullPath)
 	if err == nil {
 		return fmt.Errorf("existing file found: %s", fullPath)
 	}
 	if errors.Is(err, fs.ErrNotExist) {
 		// If not found, open or create a new .incomplete file
-		file, err = os.OpenFile(fullPath+IncompleteFileSuffix, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		file, err = fileStore.OpenFile(fullPath+IncompleteFileSuffix, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 		if err != nil {
 			return err
 		}
@@ -338,31 +459,44 @@ func UploadHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileTransfe
 	rForkWriter := io.Discard
 	iForkWriter := io.Discard
 	if preserveForks {
-		rForkWriter, err = f.rsrcForkWriter()
+		rWriter, err := f.rsrcForkWriter()
 		if err != nil {
 			return err
 		}
+		defer rWriter.Close()
+		rForkWriter = rWriter
 
-		iForkWriter, err = f.InfoForkWriter()
+		iWriter, err := f.InfoForkWriter()
 		if err != nil {
 			return err
 		}
+		defer iWriter.Close()
+		iForkWriter = iWriter
 	}
 
 	if err := receiveFile(rwc, file, rForkWriter, iForkWriter, fileTransfer.bytesSentCounter); err != nil {
 		return fmt.Errorf("receive file: %v", err)
 	}
 
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := scanUpload(fileStore, fullPath, fileTransfer, rLogger); err != nil {
+		return err
+	}
+
 	if err := fileStore.Rename(fullPath+".incomplete", fullPath); err != nil {
 		return fmt.Errorf("rename incomplete file: %v", err)
 	}
+	recordUploader(fileStore, fullPath, fileTransfer, rLogger)
 
 	rLogger.Info("File upload complete", "dstFile", fullPath)
 
 	return nil
 }
 
-func DownloadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileTransfer, fileStore FileStore, rLogger *slog.Logger, preserveForks bool) error {
+func DownloadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileTransfer, fileStore FileStore, rLogger *slog.Logger, preserveForks bool, hidden HiddenFiles) error {
 	// Folder Download flow:
 	// 1. Get filePath from the transfer
 	// 2. Iterate over files
@@ -408,8 +542,10 @@ func DownloadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *Fil
 			return err
 		}
 
-		// skip dot files
-		if strings.HasPrefix(info.Name(), ".") {
+		if i > 1 && hidden.Match(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -418,7 +554,11 @@ func DownloadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *Fil
 			return err
 		}
 
-		subPath := path[basePathLen+1:]
+		var names []string
+		for _, name := range strings.Split(filepath.ToSlash(path[basePathLen+1:]), "/") {
+			names = append(names, ClientFileName(name))
+		}
+		subPath := strings.Join(names, "/")
 
 		if i == 1 {
 			return nil
@@ -557,9 +697,18 @@ func UploadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileT
 			return err
 		}
 
+		// Skip items with names that are reserved on Windows, along with everything in folders with reserved names.
+		if fu.reserved() {
+			rLogger.Info("Skipping upload of reserved file name", "path", fu.FormattedPath())
+			if _, err := rwc.Write([]byte{0, DlFldrActionNextFile}); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if fu.IsFolder == [2]byte{0, 1} {
-			if _, err := os.Stat(filepath.Join(fullPath, fu.FormattedPath())); os.IsNotExist(err) {
-				if err := os.Mkdir(filepath.Join(fullPath, fu.FormattedPath()), 0777); err != nil {
+			if _, err := fileStore.Stat(filepath.Join(fullPath, fu.FormattedPath())); os.IsNotExist(err) {
+				if err := fileStore.Mkdir(filepath.Join(fullPath, fu.FormattedPath()), 0777); err != nil {
 					return err
 				}
 			}
@@ -569,45 +718,41 @@ func UploadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileT
 				return err
 			}
 		} else {
+			filePath := filepath.Join(fullPath, fu.FormattedPath())
 			nextAction := DlFldrActionSendFile
 
-			// Check if we have the full file already.  If so, send dlFldrAction_NextFile to client to skip.
-			_, err := os.Stat(filepath.Join(fullPath, fu.FormattedPath()))
+			// Check if we have a partial file from an interrupted upload.  If so, send dlFldrAction_ResumeFile to client
+			// to resume the upload from the end of the partial file.
+			incompleteFile, err := fileStore.Stat(filePath + IncompleteFileSuffix)
 			if err != nil && !errors.Is(err, fs.ErrNotExist) {
 				return err
 			}
 			if err == nil {
-				nextAction = DlFldrActionNextFile
+				nextAction = DlFldrActionResumeFile
 			}
 
-			//  Check if we have a partial file already.  If so, send dlFldrAction_ResumeFile to client to resume upload.
-			incompleteFile, err := os.Stat(filepath.Join(fullPath, fu.FormattedPath()+IncompleteFileSuffix))
+			// Check if we have the full file already.  If so, send dlFldrAction_NextFile to client to skip.
+			_, err = fileStore.Stat(filePath)
 			if err != nil && !errors.Is(err, fs.ErrNotExist) {
 				return err
 			}
 			if err == nil {
-				nextAction = DlFldrActionResumeFile
+				nextAction = DlFldrActionNextFile
 			}
 
 			if _, err := rwc.Write([]byte{0, uint8(nextAction)}); err != nil {
 				return err
 			}
 
-			switch nextAction {
-			case DlFldrActionNextFile:
+			if nextAction == DlFldrActionNextFile {
 				continue
-			case DlFldrActionResumeFile:
+			}
+
+			if nextAction == DlFldrActionResumeFile {
 				offset := make([]byte, 4)
 				binary.BigEndian.PutUint32(offset, uint32(incompleteFile.Size()))
 
-				file, err := os.OpenFile(fullPath+"/"+fu.FormattedPath()+IncompleteFileSuffix, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-				if err != nil {
-					return err
-				}
-
-				fileResumeData := NewFileResumeData([]ForkInfoList{*NewForkInfoList(offset)})
-
-				b, _ := fileResumeData.BinaryMarshal()
+				b, _ := NewFileResumeData([]ForkInfoList{*NewForkInfoList(offset)}).BinaryMarshal()
 
 				bs := make([]byte, 2)
 				binary.BigEndian.PutUint16(bs, uint16(len(b)))
@@ -615,59 +760,22 @@ func UploadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileT
 				if _, err := rwc.Write(append(bs, b...)); err != nil {
 					return err
 				}
+			}
 
-				if _, err := io.ReadFull(rwc, fileSize); err != nil {
-					return err
-				}
-
-				if err := receiveFile(rwc, file, io.Discard, io.Discard, fileTransfer.bytesSentCounter); err != nil {
-					rLogger.Error(err.Error())
-				}
-
-				err = os.Rename(fullPath+"/"+fu.FormattedPath()+".incomplete", fullPath+"/"+fu.FormattedPath())
-				if err != nil {
-					return err
-				}
-
-			case DlFldrActionSendFile:
-				if _, err := io.ReadFull(rwc, fileSize); err != nil {
-					return err
-				}
-
-				filePath := filepath.Join(fullPath, fu.FormattedPath())
-
-				hlFile, err := NewFileWrapper(fileStore, filePath, 0)
-				if err != nil {
-					return err
-				}
-
-				rLogger.Info("Starting file transfer", "path", filePath, "fileNum", i+1, "fileSize", binary.BigEndian.Uint32(fileSize))
-
-				incWriter, err := hlFile.incFileWriter()
-				if err != nil {
-					return err
-				}
+			if _, err := io.ReadFull(rwc, fileSize); err != nil {
+				return err
+			}
 
-				rForkWriter := io.Discard
-				iForkWriter := io.Discard
-				if preserveForks {
-					iForkWriter, err = hlFile.InfoForkWriter()
-					if err != nil {
-						return err
-					}
-
-					rForkWriter, err = hlFile.rsrcForkWriter()
-					if err != nil {
-						return err
-					}
-				}
-				if err := receiveFile(rwc, incWriter, rForkWriter, iForkWriter, fileTransfer.bytesSentCounter); err != nil {
-					return err
-				}
+			rLogger.Info("Starting file transfer", "path", filePath, "fileNum", i+1, "fileSize", binary.BigEndian.Uint32(fileSize), "resume", nextAction == DlFldrActionResumeFile)
 
-				if err := os.Rename(filePath+".incomplete", filePath); err != nil {
-					return err
-				}
+			err = receiveFolderItem(rwc, filePath, fileTransfer, fileStore, rLogger, preserveForks)
+			switch {
+			case errors.Is(err, ErrUploadRejected):
+				// The rejected file has been deleted, so carry on with the rest of the folder.
+			case err != nil:
+				return err
+			default:
+				recordUploader(fileStore, filePath, fileTransfer, rLogger)
 			}
 
 			// Tell client to send next file
@@ -679,3 +787,51 @@ func UploadFolderHandler(rwc io.ReadWriter, fullPath string, fileTransfer *FileT
 	rLogger.Info("Folder upload complete")
 	return nil
 }
+
+// receiveFolderItem receives a file of a folder upload into the .incomplete file for filePath, appending to any data
+// left by an interrupted upload, and renames it to filePath once it is complete and has passed the upload scan.  If the
+// transfer fails, the .incomplete file is kept so that the upload can be resumed later.
+func receiveFolderItem(rwc io.ReadWriter, filePath string, fileTransfer *FileTransfer, fileStore FileStore, rLogger *slog.Logger, preserveForks bool) error {
+	hlFile, err := NewFileWrapper(fileStore, filePath, 0)
+	if err != nil {
+		return err
+	}
+
+	incWriter, err := hlFile.incFileWriter()
+	if err != nil {
+		return err
+	}
+	defer incWriter.Close()
+
+	rForkWriter := io.Discard
+	iForkWriter := io.Discard
+	if preserveForks {
+		iWriter, err := hlFile.InfoForkWriter()
+		if err != nil {
+			return err
+		}
+		defer iWriter.Close()
+		iForkWriter = iWriter
+
+		rWriter, err := hlFile.rsrcForkWriter()
+		if err != nil {
+			return err
+		}
+		defer rWriter.Close()
+		rForkWriter = rWriter
+	}
+
+	if err := receiveFile(rwc, incWriter, rForkWriter, iForkWriter, fileTransfer.bytesSentCounter); err != nil {
+		return fmt.Errorf("receive file: %w", err)
+	}
+
+	if err := incWriter.Close(); err != nil {
+		return err
+	}
+
+	if err := scanUpload(fileStore, filePath, fileTransfer, rLogger); err != nil {
+		return err
+	}
+
+	return fileStore.Rename(filePath+IncompleteFileSuffix, filePath)
+}
@@ -1,10 +1,15 @@
 package hotline
 
 import (
+	"bytes"
 	"encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFileTransfer_String(t *testing.T) {
@@ -175,3 +180,193 @@ func TestFileHeader_Payload(t *testing.T) {
 		})
 	}
 }
+
+func TestMemFileTransferMgr_Add(t *testing.T) {
+	ftm := NewMemFileTransferMgr()
+	cc := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+
+	for i := 0; i < 1000; i++ {
+		ft := &FileTransfer{Type: FileDownload, ClientConn: cc}
+		ftm.Add(ft)
+		assert.NotEqual(t, FileTransferID{}, FileTransferID(ft.RefNum))
+	}
+
+	assert.Len(t, ftm.fileTransfers, 1000)
+	assert.Len(t, cc.ClientFileTransferMgr.Get(FileDownload), 1000)
+}
+
+func TestMemFileTransferMgr_Claim(t *testing.T) {
+	ftm := NewMemFileTransferMgr()
+	ft := &FileTransfer{Type: FileDownload, ClientConn: &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}}
+	ftm.Add(ft)
+
+	assert.Same(t, ft, ftm.Claim(ft.RefNum))
+	assert.Nil(t, ftm.Claim(ft.RefNum), "a transfer can only be claimed once")
+	assert.Nil(t, ftm.Claim(FileTransferID{1, 2, 3, 4}))
+}
+
+func TestMemFileTransferMgr_Expire(t *testing.T) {
+	ftm := NewMemFileTransferMgr()
+	cc := &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}
+
+	unclaimed := &FileTransfer{Type: FileDownload, ClientConn: cc}
+	claimed := &FileTransfer{Type: FileUpload, ClientConn: cc}
+	ftm.Add(unclaimed)
+	ftm.Add(claimed)
+	ftm.Claim(claimed.RefNum)
+
+	assert.Empty(t, ftm.Expire(time.Now().Add(-time.Minute)))
+
+	assert.Equal(t, []*FileTransfer{unclaimed}, ftm.Expire(time.Now().Add(time.Second)))
+	assert.Nil(t, ftm.Get(unclaimed.RefNum))
+	assert.Empty(t, cc.ClientFileTransferMgr.Get(FileDownload))
+	assert.Same(t, claimed, ftm.Get(claimed.RefNum))
+}
+
+func TestServer_expireFileTransfers(t *testing.T) {
+	s := &Server{
+		FileTransferMgr: NewMemFileTransferMgr(),
+		Stats:           NewStats(),
+		Logger:          NewTestLogger(),
+	}
+	ft := &FileTransfer{Type: FileDownload, ClientConn: &ClientConn{ClientFileTransferMgr: NewClientFileTransferMgr()}}
+	s.FileTransferMgr.Add(ft)
+
+	s.expireFileTransfers(time.Now().Add(time.Second))
+
+	assert.Nil(t, s.FileTransferMgr.Get(ft.RefNum))
+	assert.Equal(t, 1, s.Stats.Get(StatAbandonedTransfers))
+}
+
+func TestFolderUpload_FormattedPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         []string
+		want         string
+		wantReserved bool
+	}{
+		{
+			name: "nested file",
+			path: []string{"Games", "Marathon"},
+			want: filepath.Join("/", "Games", "Marathon"),
+		},
+		{
+			name: "parent folder items stay within the upload folder",
+			path: []string{"..", "..", "etc"},
+			want: filepath.Join("/", "etc"),
+		},
+		{
+			name:         "file in folder with reserved name",
+			path:         []string{"aux", "ReadMe"},
+			want:         filepath.Join("/", "aux", "ReadMe"),
+			wantReserved: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fu folderUpload
+			binary.BigEndian.PutUint16(fu.PathItemCount[:], uint16(len(tt.path)))
+			for _, name := range tt.path {
+				fu.FileNamePath = append(fu.FileNamePath, 0, 0, byte(len(name)))
+				fu.FileNamePath = append(fu.FileNamePath, name...)
+			}
+
+			assert.Equal(t, tt.want, fu.FormattedPath())
+			assert.Equal(t, tt.wantReserved, fu.reserved())
+		})
+	}
+}
+
+// folderUploadItem returns the header a client sends for a file in a folder upload.
+func folderUploadItem(path ...string) []byte {
+	var fileNamePath []byte
+	for _, name := range path {
+		fileNamePath = append(fileNamePath, 0, 0, byte(len(name)))
+		fileNamePath = append(fileNamePath, name...)
+	}
+
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(fileNamePath)+4))
+	b = append(b, 0, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(path)))
+	return append(b, fileNamePath...)
+}
+
+// flatFile returns a flattened file object with an empty info fork and the given data fork.
+func flatFile(t *testing.T, data string) []byte {
+	ffo := flattenedFileObject{
+		FlatFileHeader: FlatFileHeader{
+			Format:    [4]byte{'F', 'I', 'L', 'P'},
+			Version:   [2]byte{0, 1},
+			ForkCount: [2]byte{0, 2},
+		},
+		FlatFileInformationForkHeader: FlatFileForkHeader{ForkType: [4]byte{'I', 'N', 'F', 'O'}},
+		FlatFileDataForkHeader:        FlatFileForkHeader{ForkType: [4]byte{'D', 'A', 'T', 'A'}},
+	}
+	binary.BigEndian.PutUint32(ffo.FlatFileDataForkHeader.DataSize[:], uint32(len(data)))
+
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(&ffo)
+	require.NoError(t, err)
+	buf.WriteString(data)
+
+	return buf.Bytes()
+}
+
+func TestUploadFolderHandler_Resume(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "done.txt"), []byte("done"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "part.txt"+IncompleteFileSuffix), []byte("da"), 0644))
+
+	var input bytes.Buffer
+	input.Write(folderUploadItem("done.txt"))
+	input.Write(folderUploadItem("part.txt"))
+	input.Write([]byte{0, 0, 0, 2}) // File size
+	input.Write(flatFile(t, "ta"))
+
+	ft := &FileTransfer{
+		ClientConn:       &ClientConn{Account: &Account{Login: "alice"}},
+		FolderItemCount:  []byte{0, 2},
+		bytesSentCounter: &WriteCounter{},
+	}
+	var output bytes.Buffer
+	err := UploadFolderHandler(&readWriteBuffer{input: &input, output: &output}, dir, ft, &OSFileStore{}, NewTestLogger(), false)
+	require.NoError(t, err)
+
+	// The complete file is skipped, and the partial file is resumed from the end of its data.
+	assert.Equal(t, []byte{0, DlFldrActionNextFile, 0, DlFldrActionNextFile, 0, DlFldrActionResumeFile}, output.Next(6))
+
+	var frd FileResumeData
+	resumeDataSize := binary.BigEndian.Uint16(output.Next(2))
+	require.NoError(t, frd.UnmarshalBinary(output.Next(int(resumeDataSize))))
+	assert.Equal(t, [4]byte{0, 0, 0, 2}, frd.ForkInfoList[0].DataSize)
+	assert.Equal(t, []byte{0, DlFldrActionNextFile}, output.Bytes())
+
+	data, err := os.ReadFile(filepath.Join(dir, "part.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "part.txt"+IncompleteFileSuffix))
+}
+
+func TestUploadFolderHandler_InterruptedKeepsPartialFile(t *testing.T) {
+	dir := t.TempDir()
+
+	var input bytes.Buffer
+	input.Write(folderUploadItem("part.txt"))
+	input.Write([]byte{0, 0, 0, 4}) // File size
+	ffo := flatFile(t, "data")
+	input.Write(ffo[:len(ffo)-2]) // Connection lost before the end of the data fork
+
+	ft := &FileTransfer{
+		ClientConn:       &ClientConn{Account: &Account{Login: "alice"}},
+		FolderItemCount:  []byte{0, 1},
+		bytesSentCounter: &WriteCounter{},
+	}
+	err := UploadFolderHandler(&readWriteBuffer{input: &input, output: &bytes.Buffer{}}, dir, ft, &OSFileStore{}, NewTestLogger(), false)
+	assert.Error(t, err)
+
+	// The partial file is kept for a later resume, rather than renamed into place.
+	assert.NoFileExists(t, filepath.Join(dir, "part.txt"))
+	data, err := os.ReadFile(filepath.Join(dir, "part.txt"+IncompleteFileSuffix))
+	require.NoError(t, err)
+	assert.Equal(t, "da", string(data))
+}
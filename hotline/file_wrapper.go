@@ -24,8 +24,10 @@ type fileWrapper struct {
 	path           string // path to file directory
 	dataPath       string // path to the file data fork
 	dataOffset     int64
-	rsrcPath       string // path to the file resource fork
-	infoPath       string // path to the file information fork
+	rsrcPath       string // path to the file resource fork sidecar
+	infoPath       string // path to the file information fork sidecar
+	thumbPath      string // path to the generated thumbnail image
+	metaPath       string // path to the file metadata
 	incompletePath string // path to partially transferred temp file
 	Ffo            *flattenedFileObject
 }
@@ -41,6 +43,8 @@ func NewFileWrapper(fs FileStore, path string, dataOffset int64) (*fileWrapper,
 		dataOffset:     dataOffset,
 		rsrcPath:       filepath.Join(dir, fmt.Sprintf(RsrcForkNameTemplate, fName)),
 		infoPath:       filepath.Join(dir, fmt.Sprintf(InfoForkNameTemplate, fName)),
+		thumbPath:      filepath.Join(dir, fmt.Sprintf(ThumbnailNameTemplate, fName)),
+		metaPath:       filepath.Join(dir, fmt.Sprintf(MetaNameTemplate, fName)),
 		incompletePath: filepath.Join(dir, fName+IncompleteFileSuffix),
 		Ffo:            &flattenedFileObject{},
 	}
@@ -63,7 +67,7 @@ func (f *fileWrapper) TotalSize() []byte {
 		s += info.Size() - f.dataOffset
 	}
 
-	info, err = f.fs.Stat(f.rsrcPath)
+	info, err = f.fs.Stat(f.rsrcForkPath())
 	if err == nil {
 		s += info.Size()
 	}
@@ -74,7 +78,7 @@ func (f *fileWrapper) TotalSize() []byte {
 }
 
 func (f *fileWrapper) rsrcForkSize() (s [4]byte) {
-	info, err := f.fs.Stat(f.rsrcPath)
+	info, err := f.fs.Stat(f.rsrcForkPath())
 	if err != nil {
 		return s
 	}
@@ -102,8 +106,16 @@ func (f *fileWrapper) infoForkName() string {
 	return fmt.Sprintf(InfoForkNameTemplate, f.Name)
 }
 
+func (f *fileWrapper) thumbnailName() string {
+	return fmt.Sprintf(ThumbnailNameTemplate, f.Name)
+}
+
+func (f *fileWrapper) metaName() string {
+	return fmt.Sprintf(MetaNameTemplate, f.Name)
+}
+
 func (f *fileWrapper) rsrcForkWriter() (io.WriteCloser, error) {
-	file, err := os.OpenFile(f.rsrcPath, os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := f.fs.OpenFile(f.rsrcForkPath(), os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -112,16 +124,20 @@ func (f *fileWrapper) rsrcForkWriter() (io.WriteCloser, error) {
 }
 
 func (f *fileWrapper) InfoForkWriter() (io.WriteCloser, error) {
-	file, err := os.OpenFile(f.infoPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	file, err := f.fs.OpenFile(f.infoPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return nil, err
 	}
 
+	if native, ok := f.fs.(nativeForks); ok {
+		return &finderInfoWriter{WriteCloser: file, native: native, f: f}, nil
+	}
+
 	return file, nil
 }
 
 func (f *fileWrapper) incFileWriter() (io.WriteCloser, error) {
-	file, err := os.OpenFile(f.incompletePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := f.fs.OpenFile(f.incompletePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +150,61 @@ func (f *fileWrapper) dataForkReader() (io.Reader, error) {
 }
 
 func (f *fileWrapper) rsrcForkFile() (*os.File, error) {
-	return f.fs.Open(f.rsrcPath)
+	return f.fs.Open(f.rsrcForkPath())
+}
+
+// currentDataPath returns the path to the data fork, or to the partial file while the file is being uploaded.
+func (f *fileWrapper) currentDataPath() string {
+	if _, err := f.fs.Stat(f.dataPath); err != nil {
+		if _, err := f.fs.Stat(f.incompletePath); err == nil {
+			return f.incompletePath
+		}
+	}
+
+	return f.dataPath
+}
+
+// rsrcForkPath returns the path to the resource fork.  For FileStores with native forks, this is the real resource fork
+// of the file, unless the file has a sidecar resource fork saved before native forks were enabled.
+func (f *fileWrapper) rsrcForkPath() string {
+	native, ok := f.fs.(nativeForks)
+	if !ok {
+		return f.rsrcPath
+	}
+	if _, err := f.fs.Stat(f.rsrcPath); err == nil {
+		return f.rsrcPath
+	}
+
+	return native.rsrcForkPath(f.currentDataPath())
+}
+
+// finderInfoWriter writes an info fork and sets the Finder type and creator codes of the file from it when closed.  The
+// codes are set on the file as it is when closed, so that uploads can be closed before or after the partial file is
+// renamed.
+type finderInfoWriter struct {
+	io.WriteCloser
+	native nativeForks
+	f      *fileWrapper
+	buf    bytes.Buffer
+}
+
+func (w *finderInfoWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	return w.WriteCloser.Write(p)
+}
+
+func (w *finderInfoWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	var ffif FlatFileInformationFork
+	if err := ffif.UnmarshalBinary(w.buf.Bytes()); err != nil {
+		return nil // Invalid info forks are ignored as when they're read
+	}
+
+	return w.native.setFinderInfo(w.f.currentDataPath(), ffif.TypeSignature, ffif.CreatorSignature)
 }
 
 func (f *fileWrapper) DataFile() (os.FileInfo, error) {
@@ -153,6 +223,8 @@ func (f *fileWrapper) DataFile() (os.FileInfo, error) {
 // * Partially uploaded file ending with .incomplete
 // * Resource fork starting with .rsrc_
 // * Info fork starting with .info
+// * Thumbnail starting with .thumb_
+// * Metadata starting with .meta_
 // During Move of the meta files, os.ErrNotExist is ignored as these files may legitimately not exist.
 func (f *fileWrapper) Move(newPath string) error {
 	err := f.fs.Rename(f.dataPath, filepath.Join(newPath, f.Name))
@@ -175,6 +247,16 @@ func (f *fileWrapper) Move(newPath string) error {
 		return err
 	}
 
+	err = f.fs.Rename(f.thumbPath, filepath.Join(newPath, f.thumbnailName()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err = f.fs.Rename(f.metaPath, filepath.Join(newPath, f.metaName()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	return nil
 }
 
@@ -200,6 +282,16 @@ func (f *fileWrapper) Delete() error {
 		return err
 	}
 
+	err = f.fs.Remove(f.thumbPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	err = f.fs.Remove(f.metaPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
 	return nil
 }
 
@@ -232,20 +324,34 @@ func (f *fileWrapper) flattenedFileObject() (*flattenedFileObject, error) {
 		ForkCount: [2]byte{0, 2},
 	}
 
+	var infoFork []byte
 	_, err = f.fs.Stat(f.infoPath)
 	if err == nil {
-		b, err := f.fs.ReadFile(f.infoPath)
+		infoFork, err = f.fs.ReadFile(f.infoPath)
 		if err != nil {
 			return nil, err
 		}
+	}
 
+	// A corrupt info fork is ignored in favor of the defaults so that the file can still be downloaded, moved, or
+	// deleted.  Server.CheckMetadata quarantines such forks.
+	if infoFork != nil && validateInfoFork(infoFork) == nil {
 		f.Ffo.FlatFileHeader.ForkCount[1] = 3
 
-		_, err = io.Copy(&f.Ffo.FlatFileInformationFork, bytes.NewReader(b))
+		_, err = io.Copy(&f.Ffo.FlatFileInformationFork, bytes.NewReader(infoFork))
 		if err != nil {
 			return nil, fmt.Errorf("error copying FlatFileInformationFork: %w", err)
 		}
 	} else {
+		// Files without an info fork, such as those copied to the file root with the Finder, may have type and creator
+		// codes in their native Finder info.
+		if native, ok := f.fs.(nativeForks); ok {
+			typeCode, creatorCode, err := native.finderInfo(f.currentDataPath())
+			if err == nil && typeCode != [4]byte{} {
+				ft = fileType{TypeCode: string(typeCode[:]), CreatorCode: string(creatorCode[:])}
+			}
+		}
+
 		f.Ffo.FlatFileInformationFork = FlatFileInformationFork{
 			Platform:         [4]byte{0x41, 0x4D, 0x41, 0x43}, // "AMAC" TODO: Remove hardcode to support "AWIN" Platform (maybe?)
 			TypeSignature:    [4]byte([]byte(ft.TypeCode)),
@@ -253,12 +359,12 @@ func (f *fileWrapper) flattenedFileObject() (*flattenedFileObject, error) {
 			PlatformFlags:    [4]byte{0, 0, 1, 0}, // TODO: What is this?
 			CreateDate:       mTime,               // some filesystems don't support createTime
 			ModifyDate:       mTime,
-			Name:             []byte(f.Name),
+			Name:             []byte(ClientFileName(f.Name)),
 			Comment:          []byte{},
 		}
 
 		ns := make([]byte, 2)
-		binary.BigEndian.PutUint16(ns, uint16(len(f.Name)))
+		binary.BigEndian.PutUint16(ns, uint16(len(f.Ffo.FlatFileInformationFork.Name)))
 		f.Ffo.FlatFileInformationFork.NameSize = [2]byte(ns[:])
 	}
 
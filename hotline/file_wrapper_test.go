@@ -0,0 +1,83 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testNativeForkStore stands in for NativeForkFileStore on platforms without native forks.  Resource forks are kept in
+// ".fork" files and Finder info in memory.
+type testNativeForkStore struct {
+	OSFileStore
+	finder map[string][8]byte
+}
+
+func (s *testNativeForkStore) rsrcForkPath(path string) string {
+	return path + ".fork"
+}
+
+func (s *testNativeForkStore) finderInfo(path string) (typeCode, creatorCode [4]byte, err error) {
+	info := s.finder[path]
+	return [4]byte(info[0:4]), [4]byte(info[4:8]), nil
+}
+
+func (s *testNativeForkStore) setFinderInfo(path string, typeCode, creatorCode [4]byte) error {
+	s.finder[path] = [8]byte(append(typeCode[:], creatorCode[:]...))
+	return nil
+}
+
+func TestFileWrapper_nativeForks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Stuffit")
+	fileStore := &testNativeForkStore{finder: make(map[string][8]byte)}
+
+	// Forks written during an upload belong to the partial file.
+	require.NoError(t, os.WriteFile(path+IncompleteFileSuffix, []byte("data"), 0644))
+	fw, err := NewFileWrapper(fileStore, path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, path+IncompleteFileSuffix+".fork", fw.rsrcForkPath())
+
+	w, err := fw.rsrcForkWriter()
+	require.NoError(t, err)
+	_, err = w.Write([]byte("rsrc"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// The Finder info is set on the file as it is when the info fork is closed.
+	ffif := FlatFileInformationFork{
+		Platform:         [4]byte([]byte("AMAC")),
+		TypeSignature:    [4]byte([]byte("SITD")),
+		CreatorSignature: [4]byte([]byte("SIT!")),
+		NameSize:         [2]byte{0, 7},
+		Name:             []byte("Stuffit"),
+	}
+	iw, err := fw.InfoForkWriter()
+	require.NoError(t, err)
+	_, err = io.Copy(iw, &ffif)
+	require.NoError(t, err)
+	require.NoError(t, os.Rename(path+IncompleteFileSuffix, path))
+	require.NoError(t, os.Rename(path+IncompleteFileSuffix+".fork", path+".fork"))
+	require.NoError(t, iw.Close())
+	assert.Equal(t, [8]byte([]byte("SITDSIT!")), fileStore.finder[path])
+
+	fw, err = NewFileWrapper(fileStore, path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, path+".fork", fw.rsrcForkPath())
+	assert.Equal(t, [4]byte{0, 0, 0, 4}, fw.rsrcForkSize())
+	assert.Equal(t, []byte{0, 0, 0, 8}, fw.TotalSize())
+
+	// Without an info fork, the type and creator codes come from the Finder info.
+	require.NoError(t, os.Remove(filepath.Join(dir, ".info_Stuffit")))
+	fw, err = NewFileWrapper(fileStore, path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [4]byte([]byte("SITD")), fw.Ffo.FlatFileInformationFork.TypeSignature)
+	assert.Equal(t, [4]byte([]byte("SIT!")), fw.Ffo.FlatFileInformationFork.CreatorSignature)
+
+	// Sidecar resource forks saved before native forks were enabled are still used.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".rsrc_Stuffit"), []byte("old"), 0644))
+	assert.Equal(t, filepath.Join(dir, ".rsrc_Stuffit"), fw.rsrcForkPath())
+}
@@ -34,16 +34,36 @@ func fileTypeFromInfo(info fs.FileInfo) (ft fileType, err error) {
 
 const maxFileSize = 4294967296
 
-func GetFileNameList(path string, ignoreList []string) (fields []Field, err error) {
-	files, err := os.ReadDir(path)
+func GetFileNameList(path string, ignoreList []string, opts FileListOptions) (fields []Field, err error) {
+	entries, err := readFileList(path, ignoreList, opts)
 	if err != nil {
-		return fields, fmt.Errorf("error reading path: %s: %w", path, err)
+		return nil, err
+	}
+
+	for _, entry := range opts.apply(entries) {
+		fields = append(fields, entry.field)
+	}
+
+	return fields, nil
+}
+
+// readFileList reads the entries of the folder listing at path, unsorted and unfiltered.
+func readFileList(path string, ignoreList []string, opts FileListOptions) (entries []fileListEntry, err error) {
+	hidden := opts.Hidden
+	fileStore := opts.FS
+	if fileStore == nil {
+		fileStore = &OSFileStore{}
+	}
+
+	files, err := fileStore.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading path: %s: %w", path, err)
 	}
 
 	for _, file := range files {
 		var fnwi FileNameWithInfo
 
-		if ignoreFile(file.Name(), ignoreList) {
+		if ignoreFile(file.Name(), ignoreList) || hidden.Match(file.Name()) {
 			continue
 		}
 
@@ -51,33 +71,33 @@ func GetFileNameList(path string, ignoreList []string) (fields []Field, err erro
 
 		fileInfo, err := file.Info()
 		if err != nil {
-			return fields, fmt.Errorf("error getting file info: %s: %w", file.Name(), err)
+			return nil, fmt.Errorf("error getting file info: %s: %w", file.Name(), err)
 		}
+		modTime := fileInfo.ModTime()
+		isDir := file.IsDir()
 
-		// Check if path is a symlink.  If so, follow it.
+		// Check if path is a symlink.  If so, follow it through the file store, which may refuse links that lead
+		// outside of the file root.
 		if fileInfo.Mode()&os.ModeSymlink != 0 {
-			resolvedPath, err := os.Readlink(filepath.Join(path, file.Name()))
-			if err != nil {
-				return fields, fmt.Errorf("error following symlink: %s: %w", resolvedPath, err)
-			}
-
-			rFile, err := os.Stat(resolvedPath)
-			if errors.Is(err, os.ErrNotExist) {
+			rFile, err := fileStore.Stat(filepath.Join(path, file.Name()))
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, ErrOutsideRoot) {
 				continue
 			}
 			if err != nil {
-				return fields, err
+				return nil, err
 			}
+			modTime = rFile.ModTime()
+			isDir = rFile.IsDir()
 
 			if rFile.IsDir() {
-				dir, err := os.ReadDir(filepath.Join(path, file.Name()))
+				dir, err := fileStore.ReadDir(filepath.Join(path, file.Name()))
 				if err != nil {
-					return fields, err
+					return nil, err
 				}
 
 				var c uint32
 				for _, f := range dir {
-					if !ignoreFile(f.Name(), ignoreList) {
+					if !ignoreFile(f.Name(), ignoreList) && !hidden.Match(f.Name()) {
 						c += 1
 					}
 				}
@@ -91,14 +111,14 @@ func GetFileNameList(path string, ignoreList []string) (fields []Field, err erro
 				copy(fnwi.Creator[:], fileTypeFromFilename(rFile.Name()).CreatorCode)
 			}
 		} else if file.IsDir() {
-			dir, err := os.ReadDir(filepath.Join(path, file.Name()))
+			dir, err := fileStore.ReadDir(filepath.Join(path, file.Name()))
 			if err != nil {
-				return fields, fmt.Errorf("readDir: %w", err)
+				return nil, fmt.Errorf("readDir: %w", err)
 			}
 
 			var c uint32
 			for _, f := range dir {
-				if !ignoreFile(f.Name(), ignoreList) {
+				if !ignoreFile(f.Name(), ignoreList) && !hidden.Match(f.Name()) {
 					c += 1
 				}
 			}
@@ -112,7 +132,7 @@ func GetFileNameList(path string, ignoreList []string) (fields []Field, err erro
 				continue
 			}
 
-			hlFile, err := NewFileWrapper(&OSFileStore{}, path+"/"+file.Name(), 0)
+			hlFile, err := NewFileWrapper(fileStore, path+"/"+file.Name(), 0)
 			if err != nil {
 				return nil, fmt.Errorf("NewFileWrapper: %w", err)
 			}
@@ -122,7 +142,7 @@ func GetFileNameList(path string, ignoreList []string) (fields []Field, err erro
 			copy(fnwi.Creator[:], hlFile.Ffo.FlatFileInformationFork.CreatorSignature[:])
 		}
 
-		strippedName := strings.ReplaceAll(file.Name(), ".incomplete", "")
+		strippedName := ClientFileName(strings.ReplaceAll(file.Name(), ".incomplete", ""))
 		strippedName, err = txtEncoder.String(strippedName)
 		if err != nil {
 			continue
@@ -138,19 +158,35 @@ func GetFileNameList(path string, ignoreList []string) (fields []Field, err erro
 		if err != nil {
 			return nil, fmt.Errorf("error io.ReadAll: %w", err)
 		}
-		fields = append(fields, NewField(FieldFileNameWithInfo, b))
+
+		entries = append(entries, fileListEntry{
+			name:    strippedName,
+			size:    binary.BigEndian.Uint32(fnwi.FileSize[:]),
+			modTime: modTime,
+			isDir:   isDir,
+			field:   NewField(FieldFileNameWithInfo, b),
+		})
 	}
 
-	return fields, nil
+	return entries, nil
 }
 
-func CalcTotalSize(filePath string) ([]byte, error) {
+// CalcTotalSize recurses through a file path and totals the size of files that are not hidden.  Resource and info fork
+// files are included, as they are sent along with the file they belong to.
+func CalcTotalSize(filePath string, hidden HiddenFiles) ([]byte, error) {
 	var totalSize uint32
 	err := filepath.Walk(filePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		if path != filePath && !isSidecarFile(info.Name()) && hidden.Match(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if info.IsDir() {
 			return nil
 		}
@@ -169,8 +205,8 @@ func CalcTotalSize(filePath string) ([]byte, error) {
 	return bs, nil
 }
 
-// CalcItemCount recurses through a file path and counts the number of non-hidden files.
-func CalcItemCount(filePath string) ([]byte, error) {
+// CalcItemCount recurses through a file path and counts the number of files and folders that are not hidden.
+func CalcItemCount(filePath string, hidden HiddenFiles) ([]byte, error) {
 	var itemCount uint16
 
 	// Walk the directory and count items
@@ -179,10 +215,13 @@ func CalcItemCount(filePath string) ([]byte, error) {
 			return err
 		}
 
-		// Skip hidden files
-		if !strings.HasPrefix(info.Name(), ".") {
-			itemCount++
+		if path != filePath && hidden.Match(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
+		itemCount++
 
 		return nil
 	})
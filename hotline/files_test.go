@@ -72,7 +72,7 @@ func TestCalcTotalSize(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := CalcTotalSize(tt.args.filePath)
+			got, err := CalcTotalSize(tt.args.filePath, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CalcTotalSize() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -134,6 +134,15 @@ func TestCalcItemCount(t *testing.T) {
 			},
 			expected: 1, // 1 non-hidden file
 		},
+		{
+			name: "directory with hidden folder",
+			structure: map[string]string{
+				".hiddendir/":          "dir",
+				".hiddendir/file2.txt": "content2",
+				"file1.txt":            "content1",
+			},
+			expected: 1, // contents of hidden folders are hidden too
+		},
 		{
 			name:      "empty directory",
 			structure: map[string]string{},
@@ -156,7 +165,7 @@ func TestCalcItemCount(t *testing.T) {
 			}
 
 			// Calculate item count
-			result, err := CalcItemCount(tempDir)
+			result, err := CalcItemCount(tempDir, DefaultHiddenFiles)
 			if err != nil {
 				t.Fatalf("CalcItemCount returned an error: %v", err)
 			}
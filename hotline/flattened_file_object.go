@@ -3,7 +3,10 @@ package hotline
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"slices"
 )
 
@@ -161,66 +164,66 @@ func (ffif *FlatFileInformationFork) Read(p []byte) (int, error) {
 	return n, nil
 }
 
+// maxInfoForkSize is the size of an information fork with the longest name and comment.
+const maxInfoForkSize = 72 + math.MaxUint16 + 2 + math.MaxUint16
+
+// validateInfoFork returns an error if b is too short to hold the information fork fields it describes, such as a
+// fork truncated by an interrupted upload.
+func validateInfoFork(b []byte) error {
+	if len(b) < 72 {
+		return fmt.Errorf("information fork is %d bytes, shorter than the minimum of 72", len(b))
+	}
+
+	nameEnd := 72 + int(binary.BigEndian.Uint16(b[70:72]))
+	switch {
+	case len(b) < nameEnd:
+		return errors.New("information fork name is truncated")
+	case len(b) == nameEnd:
+		// The comment size may be omitted when there is no comment.
+		return nil
+	case len(b) < nameEnd+2:
+		return errors.New("information fork comment size is truncated")
+	case len(b) < nameEnd+2+int(binary.BigEndian.Uint16(b[nameEnd:nameEnd+2])):
+		return errors.New("information fork comment is truncated")
+	}
+
+	return nil
+}
+
 // Write implements the io.Writer interface for FlatFileInformationFork
 func (ffif *FlatFileInformationFork) Write(p []byte) (int, error) {
-	nameSize := p[70:72]
-	bs := binary.BigEndian.Uint16(nameSize)
-	total := 72 + bs
-
-	ffif.Platform = [4]byte(p[0:4])
-	ffif.TypeSignature = [4]byte(p[4:8])
-	ffif.CreatorSignature = [4]byte(p[8:12])
-	ffif.Flags = [4]byte(p[12:16])
-	ffif.PlatformFlags = [4]byte(p[16:20])
-	ffif.RSVD = [32]byte(p[20:52])
-	ffif.CreateDate = [8]byte(p[52:60])
-	ffif.ModifyDate = [8]byte(p[60:68])
-	ffif.NameScript = [2]byte(p[68:70])
-	ffif.NameSize = [2]byte(p[70:72])
-	ffif.Name = p[72:total]
-
-	if len(p) > int(total) {
-		ffif.CommentSize = [2]byte(p[total : total+2])
-		commentLen := binary.BigEndian.Uint16(ffif.CommentSize[:])
-		commentStartPos := int(total) + 2
-		commentEndPos := int(total) + 2 + int(commentLen)
-
-		ffif.Comment = p[commentStartPos:commentEndPos]
-
-		//total = uint16(commentEndPos)
+	if err := ffif.UnmarshalBinary(p); err != nil {
+		return 0, err
 	}
 
 	return len(p), nil
 }
 
 func (ffif *FlatFileInformationFork) UnmarshalBinary(b []byte) error {
-	nameSize := b[70:72]
-	bs := binary.BigEndian.Uint16(nameSize)
-	nameEnd := 72 + bs
-
-	ffif.Platform = [4]byte(b[0:4])
-	ffif.TypeSignature = [4]byte(b[4:8])
-	ffif.CreatorSignature = [4]byte(b[8:12])
-	ffif.Flags = [4]byte(b[12:16])
-	ffif.PlatformFlags = [4]byte(b[16:20])
-	ffif.RSVD = [32]byte(b[20:52])
-	ffif.CreateDate = [8]byte(b[52:60])
-	ffif.ModifyDate = [8]byte(b[60:68])
-	ffif.NameScript = [2]byte(b[68:70])
-	ffif.NameSize = [2]byte(b[70:72])
-	ffif.Name = b[72:nameEnd]
-
-	if len(b) > int(nameEnd) {
-		ffif.CommentSize = [2]byte(b[nameEnd : nameEnd+2])
-		commentLen := binary.BigEndian.Uint16(ffif.CommentSize[:])
-
-		commentStartPos := int(nameEnd) + 2
-		commentEndPos := int(nameEnd) + 2 + int(commentLen)
-
-		ffif.Comment = b[commentStartPos:commentEndPos]
+	if err := validateInfoFork(b); err != nil {
+		return err
 	}
 
-	return nil
+	r := newBinReader(b)
+	r.Read(ffif.Platform[:])
+	r.Read(ffif.TypeSignature[:])
+	r.Read(ffif.CreatorSignature[:])
+	r.Read(ffif.Flags[:])
+	r.Read(ffif.PlatformFlags[:])
+	r.Read(ffif.RSVD[:])
+	r.Read(ffif.CreateDate[:])
+	r.Read(ffif.ModifyDate[:])
+	r.Read(ffif.NameScript[:])
+	r.Read(ffif.NameSize[:])
+	ffif.Name = r.Bytes(int(binary.BigEndian.Uint16(ffif.NameSize[:])))
+
+	// The comment size may be omitted when there is no comment.
+	if r.Len() > 0 {
+		r.Read(ffif.CommentSize[:])
+		ffif.Comment = r.Bytes(int(binary.BigEndian.Uint16(ffif.CommentSize[:])))
+	}
+
+	return r.Err()
 }
 
 // Read implements the io.Reader interface for flattenedFileObject
@@ -275,6 +278,9 @@ func (ffo *flattenedFileObject) ReadFrom(r io.Reader) (int64, error) {
 	}
 
 	dataLen := binary.BigEndian.Uint32(ffo.FlatFileInformationForkHeader.DataSize[:])
+	if dataLen > maxInfoForkSize {
+		return n, fmt.Errorf("information fork is %d bytes, larger than the maximum of %d", dataLen, maxInfoForkSize)
+	}
 	ffifBuf := make([]byte, dataLen)
 	if _, err := io.ReadFull(r, ffifBuf); err != nil {
 		return n, err
@@ -1,8 +1,10 @@
 package hotline
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"testing"
 )
 
@@ -33,6 +35,29 @@ func TestFlatFileInformationFork_UnmarshalBinary(t *testing.T) {
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name:    "when the fork is shorter than the fixed size fields",
+			args:    args{b: []byte{0x41, 0x4d, 0x41, 0x43}},
+			wantErr: assert.Error,
+		},
+		{
+			name: "when the name is truncated",
+			args: args{
+				b: []byte{
+					0x41, 0x4d, 0x41, 0x43, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09, 0x62, 0x65, 0x61,
+				},
+			},
+			wantErr: assert.Error,
+		},
+		{
+			name: "when the comment is truncated",
+			args: args{
+				b: []byte{
+					0x41, 0x4d, 0x41, 0x43, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x3f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09, 0x62, 0x65, 0x61, 0x72, 0x2e, 0x74, 0x69, 0x66, 0x66, 0x00, 0x05, 0x68, 0x69,
+				},
+			},
+			wantErr: assert.Error,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -41,3 +66,28 @@ func TestFlatFileInformationFork_UnmarshalBinary(t *testing.T) {
 		})
 	}
 }
+
+func FuzzFlatFileInformationFork_UnmarshalBinary(f *testing.F) {
+	f.Add(append(append([]byte("AMAC????"), make([]byte, 63)...), 0x09, 0x62, 0x65, 0x61, 0x72, 0x2e, 0x74, 0x69, 0x66, 0x66, 0x00, 0x00))
+	f.Add(append(append([]byte("AMAC????"), make([]byte, 63)...), 0xff, 0x62))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var ffif FlatFileInformationFork
+		_ = ffif.UnmarshalBinary(b)
+	})
+}
+
+func FuzzFlattenedFileObject_ReadFrom(f *testing.F) {
+	ffo := &flattenedFileObject{
+		FlatFileHeader:          FlatFileHeader{Format: [4]byte{0x46, 0x49, 0x4c, 0x50}, Version: [2]byte{0, 1}, ForkCount: [2]byte{0, 2}},
+		FlatFileInformationFork: NewFlatFileInformationFork("bear.tiff", [8]byte{}, "TIFF", "8BIM"),
+		FlatFileDataForkHeader:  FlatFileForkHeader{ForkType: [4]byte{0x44, 0x41, 0x54, 0x41}, DataSize: [4]byte{0, 0, 0, 4}},
+	}
+	b, _ := io.ReadAll(ffo)
+	f.Add(b)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var ffo flattenedFileObject
+		_, _ = ffo.ReadFrom(bytes.NewReader(b))
+	})
+}
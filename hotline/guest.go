@@ -0,0 +1,41 @@
+package hotline
+
+// guestName is the name of guest sessions when the Guests policy is set and there's no guest account file.
+const guestName = "guest"
+
+// loginAccount returns the account to use for a new session logged in as login, or nil if there is none.  Guest
+// sessions follow the Guests policy if it's set: nil is returned if guests aren't allowed, and otherwise the account
+// is a copy of the guest account file, or a new account if there's no file, with the policy's access and limit.
+func (s *Server) loginAccount(login string) *Account {
+	account := s.AccountManager.Get(login)
+
+	if login == GuestAccount && s.Config.Guests != nil {
+		if !s.Config.Guests.Allow {
+			return nil
+		}
+
+		guest := Account{Login: GuestAccount, Name: guestName}
+		if account != nil {
+			guest = *account
+		}
+		guest.Access = s.Config.Guests.Access
+		guest.MaxSessions = s.Config.Guests.MaxGuests
+		guest.BumpOldestSession = false
+
+		account = &guest
+	}
+
+	return s.sessionAccount(account)
+}
+
+// guestsAllowed returns true unless the Guests policy is set and doesn't allow guest logins.  Without the policy,
+// guests can log in if the guest account file exists.
+func (s *Server) guestsAllowed() bool {
+	return s.Config.Guests == nil || s.Config.Guests.Allow
+}
+
+// IsSharedAccount returns true if login is the guest account, which all guests share.  Guests can't change the
+// settings saved to the account, such as preferences and bookmarks, since that would change them for every guest.
+func IsSharedAccount(login string) bool {
+	return login == GuestAccount
+}
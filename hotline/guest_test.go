@@ -0,0 +1,84 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_loginAccount_Guests(t *testing.T) {
+	var fileAccess, policyAccess AccessBitmap
+	fileAccess.Set(AccessDeleteFile)
+	policyAccess.Set(AccessDownloadFile)
+	policyAccess.Set(AccessSendChat)
+
+	file := Account{Login: GuestAccount, Name: "Visitor", Access: fileAccess, MaxSessions: 10, BumpOldestSession: true}
+
+	tests := []struct {
+		name     string
+		accounts testAccountMgr
+		policy   *GuestPolicy
+		want     *Account
+	}{
+		{
+			name:     "without a policy the guest account file is used as is",
+			accounts: testAccountMgr{GuestAccount: file},
+			want:     &file,
+		},
+		{
+			name:     "without a policy or guest account file",
+			accounts: testAccountMgr{},
+			want:     nil,
+		},
+		{
+			name:     "when the policy doesn't allow guests",
+			accounts: testAccountMgr{GuestAccount: file},
+			policy:   &GuestPolicy{Allow: false, Access: policyAccess},
+			want:     nil,
+		},
+		{
+			name:     "when the policy allows guests without a guest account file",
+			accounts: testAccountMgr{},
+			policy:   &GuestPolicy{Allow: true, Access: policyAccess, MaxGuests: 2},
+			want:     &Account{Login: GuestAccount, Name: guestName, Access: policyAccess, MaxSessions: 2},
+		},
+		{
+			name:     "when the policy allows guests with a guest account file",
+			accounts: testAccountMgr{GuestAccount: file},
+			policy:   &GuestPolicy{Allow: true, Access: policyAccess},
+			want:     &Account{Login: GuestAccount, Name: "Visitor", Access: policyAccess},
+		},
+		{
+			name:     "when the policy is read-only",
+			accounts: testAccountMgr{},
+			policy:   &GuestPolicy{Allow: true, Access: policyAccess, ReadOnly: true},
+			want: &Account{Login: GuestAccount, Name: guestName, Access: func() AccessBitmap {
+				var bits AccessBitmap
+				bits.Set(AccessDownloadFile)
+				return bits
+			}()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{AccountManager: tt.accounts, Config: Config{Guests: tt.policy}}
+
+			assert.Equal(t, tt.want, s.loginAccount(GuestAccount))
+			if stored := tt.accounts.Get(GuestAccount); stored != nil {
+				assert.Equal(t, file, *stored, "the stored account should not be modified")
+			}
+		})
+	}
+}
+
+func TestServer_IsAnonymousGuest(t *testing.T) {
+	s := &Server{Config: Config{Guests: &GuestPolicy{Allow: true, ReadOnly: true}}}
+	assert.True(t, s.IsAnonymousGuest(GuestAccount))
+	assert.False(t, s.IsAnonymousGuest("admin"))
+
+	s.Config.Guests.ReadOnly = false
+	assert.False(t, s.IsAnonymousGuest(GuestAccount))
+
+	s.Config.AnonymousGuest = true
+	assert.True(t, s.IsAnonymousGuest(GuestAccount))
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
 // Hotline handshake process
@@ -75,7 +76,7 @@ func performHandshake(rw io.ReadWriter) error {
 		return fmt.Errorf("read handshake: %w", err)
 	}
 	if !h.Valid() {
-		return errors.New("invalid protocol or sub-protocol in handshake")
+		return errInvalidHandshake
 	}
 
 	if _, err := rw.Write(handshakeResponse[:]); err != nil {
@@ -84,3 +85,44 @@ func performHandshake(rw io.ReadWriter) error {
 
 	return nil
 }
+
+const defaultHandshakeTimeout = 10 * time.Second
+
+// errInvalidHandshake is returned for connections that send something other than a Hotline handshake.
+var errInvalidHandshake = errors.New("invalid protocol or sub-protocol in handshake")
+
+// errNotHotline is returned for connections that close or time out before completing the handshake, or send something
+// other than a Hotline handshake, such as load balancer health checks and port scanners.
+var errNotHotline = errors.New("not a Hotline client")
+
+// acceptHandshake performs the handshake for a new connection within the HandshakeTimeout.  If the connection is not
+// from a Hotline client, the ProbeResponse is sent before returning an error wrapping errNotHotline.
+func (s *Server) acceptHandshake(rwc io.ReadWriter) error {
+	timeout := s.Config.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultHandshakeTimeout
+	}
+
+	conn, hasDeadline := rwc.(interface{ SetDeadline(time.Time) error })
+	if hasDeadline {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	if err := performHandshake(rwc); err != nil {
+		if s.Config.ProbeResponse != "" {
+			// The deadline has passed if the connection timed out without sending anything, e.g. an idle probe.
+			if hasDeadline {
+				_ = conn.SetDeadline(time.Now().Add(timeout))
+			}
+			_, _ = io.WriteString(rwc, s.Config.ProbeResponse)
+		}
+
+		return fmt.Errorf("%w: %w", errNotHotline, err)
+	}
+
+	if hasDeadline {
+		_ = conn.SetDeadline(time.Time{})
+	}
+
+	return nil
+}
@@ -2,7 +2,11 @@ package hotline
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestHandshakeWrite(t *testing.T) {
@@ -227,3 +231,71 @@ func TestPerformHandshake(t *testing.T) {
 		})
 	}
 }
+
+func TestAcceptHandshake(t *testing.T) {
+	t.Run("sends the probe response to non-Hotline connections", func(t *testing.T) {
+		s := &Server{Config: Config{ProbeResponse: "OK\n"}}
+		rw := &readWriteBuffer{
+			input:  bytes.NewBufferString("GET / HTTP/1.1\r\n"),
+			output: &bytes.Buffer{},
+		}
+
+		err := s.acceptHandshake(rw)
+		if !errors.Is(err, errNotHotline) {
+			t.Fatalf("expected errNotHotline, got %v", err)
+		}
+		if got := rw.output.String(); got != "OK\n" {
+			t.Fatalf("expected probe response %q, got %q", "OK\n", got)
+		}
+	})
+
+	t.Run("closes silently without a probe response", func(t *testing.T) {
+		s := &Server{}
+		rw := &readWriteBuffer{
+			input:  &bytes.Buffer{},
+			output: &bytes.Buffer{},
+		}
+
+		err := s.acceptHandshake(rw)
+		if !errors.Is(err, errNotHotline) || !errors.Is(err, io.EOF) {
+			t.Fatalf("expected errNotHotline wrapping io.EOF, got %v", err)
+		}
+		if rw.output.Len() != 0 {
+			t.Fatalf("expected no output, got %q", rw.output.String())
+		}
+	})
+
+	t.Run("times out idle connections", func(t *testing.T) {
+		s := &Server{Config: Config{HandshakeTimeout: 50 * time.Millisecond}}
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+
+		done := make(chan error, 1)
+		go func() { done <- s.acceptHandshake(serverConn) }()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, errNotHotline) {
+				t.Fatalf("expected errNotHotline, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("handshake did not time out")
+		}
+	})
+	t.Run("sends the probe response to idle connections", func(t *testing.T) {
+		s := &Server{Config: Config{HandshakeTimeout: 50 * time.Millisecond, ProbeResponse: "OK\n"}}
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+
+		go func() { _ = s.acceptHandshake(serverConn) }()
+
+		_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		got, err := io.ReadAll(io.LimitReader(clientConn, 3))
+		if err != nil {
+			t.Fatalf("read probe response: %v", err)
+		}
+		if string(got) != "OK\n" {
+			t.Fatalf("expected probe response %q, got %q", "OK\n", got)
+		}
+	})
+}
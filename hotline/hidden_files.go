@@ -0,0 +1,90 @@
+package hotline
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// ErrHiddenPath is returned by ClientConn.ReadPath for paths to files and folders hidden from the client.
+var ErrHiddenPath = fmt.Errorf("hidden path: %w", fs.ErrNotExist)
+
+// DefaultHiddenFiles are the hidden file patterns used when HiddenFiles is not set in the config.
+var DefaultHiddenFiles = HiddenFiles{".*"}
+
+// HiddenFiles is a list of glob patterns, e.g. ".*", "Thumbs.db", or "*.tmp", for files and folders that are hidden from
+// file listings and can't be downloaded.  Patterns are matched case-insensitively against each name in a path, so the
+// contents of a hidden folder are hidden too.
+//
+// The server's own sidecar files, such as resource and info forks, are always hidden, even by a nil HiddenFiles.
+type HiddenFiles []string
+
+// Validate returns an error if any of the patterns is malformed.
+func (h HiddenFiles) Validate() error {
+	for _, pattern := range h {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid hidden file pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// Match returns true if a file or folder with the given name is hidden.
+func (h HiddenFiles) Match(name string) bool {
+	if isSidecarFile(name) {
+		return true
+	}
+
+	name = strings.ToLower(name)
+	for _, pattern := range h {
+		if match, _ := filepath.Match(strings.ToLower(pattern), name); match {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchPath returns true if fullPath, or any of the folders between it and fileRoot, is hidden.
+func (h HiddenFiles) MatchPath(fileRoot, fullPath string) bool {
+	rel, err := filepath.Rel(fileRoot, fullPath)
+	if err != nil || rel == "." {
+		return false
+	}
+
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if h.Match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSidecarFile returns true if name is a file the server stores alongside another file: a resource or info fork kept
+// by PreserveResourceForks, a thumbnail, or file metadata.
+func isSidecarFile(name string) bool {
+	for _, prefix := range []string{".info_", ".rsrc_", ".thumb_", ".meta_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HiddenFiles returns the hidden file patterns that apply to the client: none if the account can view hidden files, or
+// the server's HiddenFiles otherwise.
+func (cc *ClientConn) HiddenFiles() HiddenFiles {
+	if cc.Authorize(AccessViewHiddenFiles) {
+		return nil
+	}
+
+	if cc.Server.Config.HiddenFiles == nil {
+		return DefaultHiddenFiles
+	}
+
+	return cc.Server.Config.HiddenFiles
+}
@@ -0,0 +1,97 @@
+package hotline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHiddenFiles_Match(t *testing.T) {
+	hidden := HiddenFiles{".*", "thumbs.db", "*.tmp"}
+
+	assert.True(t, hidden.Match(".DS_Store"))
+	assert.True(t, hidden.Match("Thumbs.db"))
+	assert.True(t, hidden.Match("upload.TMP"))
+	assert.False(t, hidden.Match("readme.txt"))
+
+	// Fork files are hidden even without any patterns.
+	var none HiddenFiles
+	assert.True(t, none.Match(".rsrc_readme.txt"))
+	assert.True(t, none.Match(".info_readme.txt"))
+	assert.False(t, none.Match(".DS_Store"))
+}
+
+func TestHiddenFiles_MatchPath(t *testing.T) {
+	hidden := HiddenFiles{".*"}
+	root := filepath.Join("/srv", "Files")
+
+	assert.False(t, hidden.MatchPath(root, root))
+	assert.False(t, hidden.MatchPath(root, filepath.Join(root, "Uploads", "file.txt")))
+	assert.True(t, hidden.MatchPath(root, filepath.Join(root, ".secret")))
+	assert.True(t, hidden.MatchPath(root, filepath.Join(root, ".secret", "file.txt")))
+}
+
+func TestHiddenFiles_Validate(t *testing.T) {
+	assert.NoError(t, HiddenFiles{".*", "Thumbs.db"}.Validate())
+	assert.Error(t, HiddenFiles{"[abc"}.Validate())
+}
+
+func TestClientConn_HiddenFiles(t *testing.T) {
+	var admin AccessBitmap
+	admin.Set(AccessViewHiddenFiles)
+
+	tests := []struct {
+		name   string
+		config Config
+		access AccessBitmap
+		want   HiddenFiles
+	}{
+		{
+			name: "defaults to dot files",
+			want: DefaultHiddenFiles,
+		},
+		{
+			name:   "configured patterns",
+			config: Config{HiddenFiles: HiddenFiles{"Thumbs.db"}},
+			want:   HiddenFiles{"Thumbs.db"},
+		},
+		{
+			name:   "account can view hidden files",
+			config: Config{HiddenFiles: HiddenFiles{"Thumbs.db"}},
+			access: admin,
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := &ClientConn{
+				Account: &Account{Access: tt.access},
+				Server:  &Server{Config: tt.config},
+			}
+			assert.Equal(t, tt.want, cc.HiddenFiles())
+		})
+	}
+}
+
+func TestClientConn_ReadPath_hidden(t *testing.T) {
+	var admin AccessBitmap
+	admin.Set(AccessViewHiddenFiles)
+
+	s := &Server{Config: Config{FileRoot: "/files", HiddenFiles: HiddenFiles{"Private"}}}
+	cc := &ClientConn{Account: &Account{}, Server: s}
+
+	fullPath, err := cc.ReadPath(EncodeFilePath("Uploads"), []byte("a.sit"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join("/files", "Uploads", "a.sit"), fullPath)
+
+	// Hidden files, and files in hidden folders, can't be reached by path.
+	_, err = cc.ReadPath(EncodeFilePath("Uploads"), []byte("Private"))
+	assert.ErrorIs(t, err, ErrHiddenPath)
+	_, err = cc.ReadPath(EncodeFilePath("Private/Sub"), []byte("a.sit"))
+	assert.ErrorIs(t, err, ErrHiddenPath)
+
+	cc.Account.Access = admin
+	_, err = cc.ReadPath(EncodeFilePath("Private/Sub"), []byte("a.sit"))
+	assert.NoError(t, err)
+}
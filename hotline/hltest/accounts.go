@@ -0,0 +1,101 @@
+package hltest
+
+import (
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemAccountManager is a hotline.AccountManager that keeps accounts in memory.
+type MemAccountManager struct {
+	mu       sync.Mutex
+	accounts map[string]hotline.Account
+}
+
+func NewMemAccountManager() *MemAccountManager {
+	return &MemAccountManager{accounts: make(map[string]hotline.Account)}
+}
+
+func (am *MemAccountManager) Create(account hotline.Account) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, ok := am.accounts[account.Login]; ok {
+		return errors.New("account already exists")
+	}
+	am.accounts[account.Login] = account
+
+	return nil
+}
+
+func (am *MemAccountManager) Update(account hotline.Account, newLogin string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, ok := am.accounts[account.Login]; !ok {
+		return errors.New("account not found")
+	}
+	if _, ok := am.accounts[newLogin]; ok && newLogin != account.Login {
+		return errors.New("account already exists")
+	}
+
+	delete(am.accounts, account.Login)
+	account.Login = newLogin
+	am.accounts[newLogin] = account
+
+	return nil
+}
+
+func (am *MemAccountManager) Modify(login string, modify func(*hotline.Account)) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	account, ok := am.accounts[login]
+	if !ok {
+		return errors.New("account not found")
+	}
+	modify(&account)
+	account.Login = login
+	am.accounts[login] = account
+
+	return nil
+}
+
+func (am *MemAccountManager) Get(login string) *hotline.Account {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	account, ok := am.accounts[login]
+	if !ok {
+		return nil
+	}
+
+	return &account
+}
+
+func (am *MemAccountManager) List() []hotline.Account {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	accounts := make([]hotline.Account, 0, len(am.accounts))
+	for _, account := range am.accounts {
+		accounts = append(accounts, account)
+	}
+	slices.SortFunc(accounts, func(a, b hotline.Account) int { return strings.Compare(a.Login, b.Login) })
+
+	return accounts
+}
+
+func (am *MemAccountManager) Delete(login string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if _, ok := am.accounts[login]; !ok {
+		return errors.New("account not found")
+	}
+	delete(am.accounts, login)
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package hltest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout is how long a Client waits for an expected transaction before failing the test.
+const DefaultTimeout = 5 * time.Second
+
+// HandlerFunc handles a transaction sent to a Client by the server, returning any transactions to send in response.
+type HandlerFunc func(t hotline.Transaction) []hotline.Transaction
+
+// Client is a Hotline client connected to a Server.  Transactions sent by the server are queued until they are taken by
+// Do or Expect, unless a handler has been registered for their type with Handle.
+//
+// The methods of Client are not safe for concurrent use by multiple goroutines.
+type Client struct {
+	Timeout time.Duration // How long to wait for expected transactions; DefaultTimeout if zero
+
+	t    testing.TB
+	conn net.Conn
+
+	mu       sync.Mutex
+	handlers map[hotline.TranType]HandlerFunc
+	queue    []hotline.Transaction
+	err      error         // Error that ended the read loop
+	arrived  chan struct{} // Signalled when a transaction is queued or the read loop ends
+}
+
+func newClient(t testing.TB, conn net.Conn) *Client {
+	c := &Client{
+		t:        t,
+		conn:     conn,
+		handlers: make(map[hotline.TranType]HandlerFunc),
+		arrived:  make(chan struct{}, 1),
+	}
+
+	t.Cleanup(func() { _ = c.conn.Close() })
+
+	return c
+}
+
+func (c *Client) handshake() {
+	c.t.Helper()
+
+	if _, err := c.conn.Write(hotline.ClientHandshake); err != nil {
+		c.t.Fatalf("send handshake: %v", err)
+	}
+
+	reply := make([]byte, len(hotline.ServerHandshake))
+	if _, err := io.ReadFull(c.conn, reply); err != nil {
+		c.t.Fatalf("read handshake reply: %v", err)
+	}
+	if !slices.Equal(reply, hotline.ServerHandshake) {
+		c.t.Fatalf("unexpected handshake reply: %x", reply)
+	}
+
+	go c.readLoop()
+}
+
+// readLoop reads transactions from the server until the connection is closed.
+func (c *Client) readLoop() {
+	for {
+		t, err := readTransaction(c.conn)
+
+		c.mu.Lock()
+		if err != nil {
+			c.err = err
+			c.mu.Unlock()
+			c.signal()
+			return
+		}
+
+		handler, ok := c.handlers[t.Type]
+		if !ok || t.IsReply == 1 {
+			c.queue = append(c.queue, t)
+		}
+		c.mu.Unlock()
+
+		if ok && t.IsReply == 0 {
+			for _, reply := range handler(t) {
+				_ = c.write(reply)
+			}
+		}
+
+		c.signal()
+	}
+}
+
+func (c *Client) signal() {
+	select {
+	case c.arrived <- struct{}{}:
+	default:
+	}
+}
+
+// readTransaction reads the next complete transaction from r.
+func readTransaction(r io.Reader) (hotline.Transaction, error) {
+	var t hotline.Transaction
+
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return t, err
+	}
+
+	buf := make([]byte, 20+binary.BigEndian.Uint32(header[12:16]))
+	copy(buf, header)
+	if _, err := io.ReadFull(r, buf[20:]); err != nil {
+		return t, err
+	}
+
+	_, err := t.Write(buf)
+
+	return t, err
+}
+
+func (c *Client) write(t hotline.Transaction) error {
+	_, err := io.Copy(c.conn, &t)
+
+	return err
+}
+
+// Handle registers a handler for transactions of tranType sent by the server, such as replying to
+// hotline.TranShowAgreement.  Handled transactions are not queued for Expect.
+func (c *Client) Handle(tranType hotline.TranType, handler HandlerFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers[tranType] = handler
+}
+
+// Send sends a transaction to the server without waiting for a reply.
+func (c *Client) Send(t hotline.Transaction) {
+	c.t.Helper()
+
+	if err := c.write(t); err != nil {
+		c.t.Fatalf("send %v: %v", t.Type, err)
+	}
+}
+
+// Do sends a request to the server and returns its reply.  The reply is returned whether or not it is an error; see
+// ErrorText.
+func (c *Client) Do(t hotline.Transaction) hotline.Transaction {
+	c.t.Helper()
+
+	c.Send(t)
+
+	reply, ok := c.wait(func(r hotline.Transaction) bool { return r.IsReply == 1 && r.ID == t.ID })
+	if !ok {
+		c.t.Fatalf("no reply to %v: %v", t.Type, c.readErr())
+	}
+
+	return reply
+}
+
+// Expect waits for the server to send a transaction of tranType and returns it.  Queued transactions of other types
+// are left in the queue.
+func (c *Client) Expect(tranType hotline.TranType) hotline.Transaction {
+	c.t.Helper()
+
+	t, ok := c.wait(func(t hotline.Transaction) bool { return t.IsReply == 0 && t.Type == tranType })
+	if !ok {
+		c.t.Fatalf("did not receive %v: %v", tranType, c.readErr())
+	}
+
+	return t
+}
+
+// Received removes and returns all queued transactions.
+func (c *Client) Received() []hotline.Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queue := c.queue
+	c.queue = nil
+
+	return queue
+}
+
+// wait removes and returns the first queued transaction that matches, waiting for one to arrive if needed.  It returns
+// false if none arrives before the timeout or the connection is closed.
+func (c *Client) wait(match func(hotline.Transaction) bool) (hotline.Transaction, bool) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		i := slices.IndexFunc(c.queue, match)
+		if i >= 0 {
+			t := c.queue[i]
+			c.queue = slices.Delete(c.queue, i, i+1)
+			c.mu.Unlock()
+			return t, true
+		}
+		closed := c.err != nil
+		c.mu.Unlock()
+
+		if closed {
+			return hotline.Transaction{}, false
+		}
+
+		select {
+		case <-c.arrived:
+		case <-timer.C:
+			return hotline.Transaction{}, false
+		}
+	}
+}
+
+func (c *Client) readErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		return errors.New("timed out")
+	}
+	return c.err
+}
+
+// Login logs in to the account with the given login and password, using name as the user name, and returns the
+// server's reply.  The test fails if the login is rejected.
+func (c *Client) Login(login, password, name string) hotline.Transaction {
+	c.t.Helper()
+
+	reply := c.Do(hotline.NewTransaction(hotline.TranLogin, [2]byte{},
+		hotline.NewField(hotline.FieldUserName, []byte(name)),
+		hotline.NewField(hotline.FieldUserIconID, []byte{0, 1}),
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte(login))),
+		hotline.NewField(hotline.FieldUserPassword, hotline.EncodeString([]byte(password))),
+	))
+	if msg := ErrorText(reply); msg != "" {
+		c.t.Fatalf("login as %q rejected: %s", login, msg)
+	}
+
+	return reply
+}
+
+// Close disconnects the client from the server.
+func (c *Client) Close() {
+	_ = c.conn.Close()
+}
+
+// ErrorText returns the error message of an error reply, or an empty string if t is not an error.
+func ErrorText(t hotline.Transaction) string {
+	if t.ErrorCode == [4]byte{} {
+		return ""
+	}
+	if f := t.GetField(hotline.FieldError); f != nil {
+		return string(f.Data)
+	}
+	return fmt.Sprintf("error code %d", binary.BigEndian.Uint32(t.ErrorCode[:]))
+}
@@ -0,0 +1,346 @@
+package hltest
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/mobius"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServer_Chat(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("admin", "password", hotline.AccessDisconUser, hotline.AccessAnyName, hotline.AccessReadChat, hotline.AccessSendChat)
+	srv.CreateAccount("guest", "", hotline.AccessAnyName, hotline.AccessReadChat, hotline.AccessSendChat)
+
+	admin := srv.Login("admin", "password", "Admin")
+	guest := srv.Login("guest", "", "Guest")
+
+	notify := admin.Expect(hotline.TranNotifyChangeUser)
+	assert.Equal(t, []byte("Guest"), notify.GetField(hotline.FieldUserName).Data)
+
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("hello"))))
+
+	for _, c := range []*Client{admin, guest} {
+		msg := c.Expect(hotline.TranChatMsg)
+		assert.Equal(t, "\r        Guest:  hello", string(msg.GetField(hotline.FieldData).Data))
+	}
+}
+
+func TestServer_Login(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("user", "secret")
+
+	c := srv.Connect()
+	reply := c.Do(hotline.NewTransaction(hotline.TranLogin, [2]byte{},
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte("user"))),
+		hotline.NewField(hotline.FieldUserPassword, hotline.EncodeString([]byte("wrong"))),
+	))
+	assert.Equal(t, "Incorrect login.", ErrorText(reply))
+
+	c = srv.Login("user", "secret", "User")
+	reply = c.Do(hotline.NewTransaction(hotline.TranGetMsgs, [2]byte{}))
+	assert.Equal(t, "You are not allowed to read news.", ErrorText(reply))
+}
+
+type auditSink struct {
+	mu      sync.Mutex
+	entries []hotline.AuditEntry
+}
+
+func (s *auditSink) WriteAudit(entry hotline.AuditEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry)
+}
+
+// errors returns the audited login errors.
+func (s *auditSink) errors() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []string
+	for _, e := range s.entries {
+		errs = append(errs, e.Error)
+	}
+
+	return errs
+}
+
+func TestServer_Login_audit(t *testing.T) {
+	srv := NewServer(t)
+	sink := &auditSink{}
+	srv.AuditSink = sink
+
+	account := srv.CreateAccount("user", "secret")
+	account.AllowedAddresses = []string{"10.1.0.1"}
+	account.MaxSessions = 1
+	require.NoError(t, srv.AccountManager.Update(*account, account.Login))
+
+	login := hotline.NewTransaction(hotline.TranLogin, [2]byte{},
+		hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte("user"))),
+		hotline.NewField(hotline.FieldUserPassword, hotline.EncodeString([]byte("secret"))),
+	)
+
+	// Logins rejected after the password is checked are audited too.
+	reply := srv.Connect().Do(login)
+	assert.Equal(t, "Incorrect login.", ErrorText(reply))
+
+	srv.ConnectFrom("10.1.0.1:5500").Login("user", "secret", "User")
+	reply = srv.ConnectFrom("10.1.0.1:5501").Do(login)
+	assert.Equal(t, "This account has too many simultaneous connections.", ErrorText(reply))
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(
+			[]string{"Address not allowed.", "", "This account has too many simultaneous connections."},
+			sink.errors(),
+		)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestClient_Handle(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("admin", "password", hotline.AccessDisconUser, hotline.AccessAnyName, hotline.AccessSendPrivMsg)
+	srv.CreateAccount("guest", "", hotline.AccessAnyName, hotline.AccessSendPrivMsg)
+
+	guest := srv.Login("guest", "", "Guest")
+	guest.Handle(hotline.TranServerMsg, func(t hotline.Transaction) []hotline.Transaction {
+		return []hotline.Transaction{
+			hotline.NewTransaction(hotline.TranSendInstantMsg, [2]byte{},
+				hotline.NewField(hotline.FieldUserID, t.GetField(hotline.FieldUserID).Data),
+				hotline.NewField(hotline.FieldOptions, []byte{0, 1}),
+				hotline.NewField(hotline.FieldData, []byte("pong")),
+			),
+		}
+	})
+
+	admin := srv.Login("admin", "password", "Admin")
+	users := admin.Do(hotline.NewTransaction(hotline.TranGetUserNameList, [2]byte{}))
+	assert.Len(t, users.Fields, 2)
+
+	var guestID []byte
+	for _, f := range users.Fields {
+		var u hotline.User
+		_, _ = u.Write(f.Data)
+		if u.Name == "Guest" {
+			guestID = u.ID[:]
+		}
+	}
+
+	admin.Do(hotline.NewTransaction(hotline.TranSendInstantMsg, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, guestID),
+		hotline.NewField(hotline.FieldOptions, []byte{0, 1}),
+		hotline.NewField(hotline.FieldData, []byte("ping")),
+	))
+
+	msg := admin.Expect(hotline.TranServerMsg)
+	assert.Equal(t, "pong", string(msg.GetField(hotline.FieldData).Data))
+}
+
+func TestServer_Away(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("admin", "password", hotline.AccessDisconUser, hotline.AccessAnyName, hotline.AccessSendChat, hotline.AccessGetClientInfo)
+	srv.CreateAccount("guest", "", hotline.AccessAnyName, hotline.AccessSendChat)
+
+	admin := srv.Login("admin", "password", "Admin")
+	guest := srv.Login("guest", "", "Guest")
+	admin.Expect(hotline.TranNotifyChangeUser)
+
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("/away at lunch"))))
+	msg := guest.Expect(hotline.TranServerMsg)
+	assert.Equal(t, "You are now away: at lunch", string(msg.GetField(hotline.FieldData).Data))
+
+	notify := admin.Expect(hotline.TranNotifyChangeUser)
+	flags := hotline.UserFlags(notify.GetField(hotline.FieldUserFlags).Data)
+	assert.True(t, flags.IsSet(hotline.UserFlagAway))
+
+	info := admin.Do(hotline.NewTransaction(hotline.TranGetClientInfoText, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, notify.GetField(hotline.FieldUserID).Data),
+	))
+	assert.Contains(t, string(info.GetField(hotline.FieldData).Data), "Away:       at lunch\r")
+
+	// Activity doesn't clear away status.
+	guest.Do(hotline.NewTransaction(hotline.TranGetUserNameList, [2]byte{}))
+	info = admin.Do(hotline.NewTransaction(hotline.TranGetClientInfoText, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, notify.GetField(hotline.FieldUserID).Data),
+	))
+	assert.Contains(t, string(info.GetField(hotline.FieldData).Data), "Away:")
+
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("/back"))))
+	notify = admin.Expect(hotline.TranNotifyChangeUser)
+	flags = hotline.UserFlags(notify.GetField(hotline.FieldUserFlags).Data)
+	assert.False(t, flags.IsSet(hotline.UserFlagAway))
+}
+
+func TestServer_NegotiateCapabilities(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("guest", "")
+
+	c := srv.Login("guest", "", "Guest")
+	reply := c.Do(hotline.NewTransaction(hotline.TranNegotiateCapabilities, [2]byte{},
+		hotline.NewField(hotline.FieldCapability, []byte(hotline.CapBookmarks)),
+		hotline.NewField(hotline.FieldCapability, []byte("teleportation")),
+		hotline.NewField(hotline.FieldCapability, []byte(hotline.CapServerInfo)),
+	))
+	assert.Equal(t, []hotline.Field{
+		hotline.NewField(hotline.FieldCapability, []byte(hotline.CapServerInfo)),
+		hotline.NewField(hotline.FieldCapability, []byte(hotline.CapBookmarks)),
+	}, reply.Fields)
+
+	clients := srv.ClientMgr.List()
+	assert.Len(t, clients, 1)
+	assert.True(t, clients[0].HasCapability(hotline.CapBookmarks))
+	assert.False(t, clients[0].HasCapability(hotline.CapPreferences))
+}
+
+func TestServer_ChatLimits(t *testing.T) {
+	srv := NewServer(t)
+	srv.Config.MaxChatLines = 2
+	srv.Config.MaxUserNameLen = 4
+	srv.CreateAccount("guest", "", hotline.AccessAnyName, hotline.AccessReadChat, hotline.AccessSendChat)
+
+	guest := srv.Login("guest", "", "Guesty")
+
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("one\rtwo\rthree"))))
+
+	warning := guest.Expect(hotline.TranServerMsg)
+	assert.Equal(t, "Your chat message was too long and has been shortened.", string(warning.GetField(hotline.FieldData).Data))
+
+	msg := guest.Expect(hotline.TranChatMsg)
+	assert.Equal(t, "\r         Gues:  one\rtwo", string(msg.GetField(hotline.FieldData).Data))
+}
+
+func TestServer_BroadcastHistory(t *testing.T) {
+	srv := NewServer(t)
+	srv.Config.BroadcastHistory = 5
+	srv.CreateAccount("admin", "password", hotline.AccessDisconUser, hotline.AccessAnyName, hotline.AccessBroadcast)
+	srv.CreateAccount("alice", "password", hotline.AccessAnyName)
+	srv.CreateAccount("guest", "", hotline.AccessAnyName)
+
+	// Alice has logged in before the broadcast.
+	srv.Login("alice", "password", "Alice").Close()
+
+	admin := srv.Login("admin", "password", "Admin")
+	admin.Do(hotline.NewTransaction(hotline.TranUserBroadcast, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("Server restart at noon"))))
+
+	alice := srv.Login("alice", "password", "Alice")
+	msg := alice.Expect(hotline.TranServerMsg)
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Broadcasts since your last visit:")
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Server restart at noon")
+	alice.Close()
+
+	// Broadcasts are only shown once per user, but can be listed with /broadcasts.
+	alice = srv.Login("alice", "password", "Alice")
+	alice.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("/broadcasts"))))
+	msg = alice.Expect(hotline.TranServerMsg)
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Recent broadcasts:")
+
+	// Guests share the guest account, so each guest is shown the broadcasts they missed.
+	for _, addr := range []string{"10.1.0.1:5500", "10.1.0.2:5500"} {
+		guest := srv.ConnectFrom(addr)
+		guest.Login("guest", "", "Guest")
+		msg = guest.Expect(hotline.TranServerMsg)
+		assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Server restart at noon")
+		guest.Close()
+	}
+
+	guest := srv.ConnectFrom("10.1.0.1:5500")
+	guest.Login("guest", "", "Guest")
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("/broadcasts"))))
+	msg = guest.Expect(hotline.TranServerMsg)
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Recent broadcasts:")
+}
+
+func TestServer_Events(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("guest", "", hotline.AccessAnyName, hotline.AccessReadChat, hotline.AccessSendChat)
+
+	guest := srv.Login("guest", "", "Guest")
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("/events"))))
+	msg := guest.Expect(hotline.TranServerMsg)
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "There are no upcoming events.")
+
+	start := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	require.NoError(t, os.WriteFile(filepath.Join(srv.Dir, "Events.yaml"), []byte("- Title: Game night\n  Start: "+start+"\n"), 0644))
+	require.NoError(t, srv.Events.(*mobius.EventsYAML).Load())
+
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("/events"))))
+	msg = guest.Expect(hotline.TranServerMsg)
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Upcoming events:")
+	assert.Contains(t, string(msg.GetField(hotline.FieldData).Data), "Game night")
+}
+
+func TestServer_GuestPolicy(t *testing.T) {
+	srv := NewServer(t)
+
+	var access hotline.AccessBitmap
+	access.Set(hotline.AccessReadChat)
+	access.Set(hotline.AccessSendChat)
+	srv.Config.Guests = &hotline.GuestPolicy{Allow: true, Access: access, MaxGuests: 1}
+
+	// Guests log in without a guest account.
+	guest := srv.Login("", "", "Guest")
+	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("hello"))))
+	msg := guest.Expect(hotline.TranChatMsg)
+	assert.Equal(t, "\r        guest:  hello", string(msg.GetField(hotline.FieldData).Data))
+
+	c := srv.Connect()
+	reply := c.Do(hotline.NewTransaction(hotline.TranLogin, [2]byte{}))
+	assert.Equal(t, "Too many guests are connected.  Please try again later.", ErrorText(reply))
+
+	srv.Config.Guests.Allow = false
+	c = srv.Connect()
+	reply = c.Do(hotline.NewTransaction(hotline.TranLogin, [2]byte{}))
+	assert.Equal(t, "This server does not allow guest logins.", ErrorText(reply))
+}
+
+func TestServer_ChatHistory(t *testing.T) {
+	srv := NewServer(t)
+	srv.Config.ChatHistory.Lines = 2
+	srv.CreateAccount("guest", "", hotline.AccessAnyName, hotline.AccessReadChat, hotline.AccessSendChat)
+
+	alice := srv.Login("guest", "", "Alice")
+	for _, msg := range []string{"one", "two", "three"} {
+		alice.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte(msg))))
+		alice.Expect(hotline.TranChatMsg)
+	}
+
+	// Users who log in later are sent the most recent messages, marked as replayed.
+	bob := srv.Login("guest", "", "Bob")
+	msg := bob.Expect(hotline.TranChatMsg)
+	assert.Regexp(t, `^\r\[\d\d:\d\d\] +Alice:  two\r\[\d\d:\d\d\] +Alice:  three$`, string(msg.GetField(hotline.FieldData).Data))
+	assert.NotNil(t, msg.GetField(hotline.FieldChatHistoryTime))
+}
+
+func TestServer_RedeliverChatInvites(t *testing.T) {
+	srv := NewServer(t)
+	srv.CreateAccount("alice", "password", hotline.AccessAnyName, hotline.AccessOpenChat)
+	srv.CreateAccount("bob", "password", hotline.AccessAnyName, hotline.AccessOpenChat)
+
+	alice := srv.Login("alice", "password", "Alice")
+	bob := srv.ConnectFrom("10.1.0.1:5500")
+	bob.Login("bob", "password", "Bob")
+	notify := alice.Expect(hotline.TranNotifyChangeUser)
+
+	reply := alice.Do(hotline.NewTransaction(hotline.TranInviteNewChat, [2]byte{},
+		hotline.NewField(hotline.FieldUserID, notify.GetField(hotline.FieldUserID).Data),
+	))
+	chatID := reply.GetField(hotline.FieldChatID).Data
+	bob.Expect(hotline.TranInviteToChat)
+
+	// Bob's connection drops before he answers, so the invitation is sent again when he reconnects.
+	bob.Close()
+	bob = srv.ConnectFrom("10.1.0.1:5501")
+	bob.Login("bob", "password", "Bob")
+	invite := bob.Expect(hotline.TranInviteToChat)
+	assert.Equal(t, chatID, invite.GetField(hotline.FieldChatID).Data)
+	assert.Equal(t, "Alice", string(invite.GetField(hotline.FieldUserName).Data))
+
+	reply = bob.Do(hotline.NewTransaction(hotline.TranJoinChat, [2]byte{}, hotline.NewField(hotline.FieldChatID, chatID)))
+	assert.Empty(t, ErrorText(reply))
+}
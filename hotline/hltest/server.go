@@ -0,0 +1,156 @@
+// Package hltest provides a Hotline server and client for end-to-end tests.
+//
+// A test starts a Server, creates the accounts it needs, and connects one or more Clients.  Clients talk to the server
+// over in-memory connections using the real Hotline protocol, so tests exercise the full login sequence, transaction
+// handlers, and notifications sent to other users:
+//
+//	srv := hltest.NewServer(t)
+//	srv.CreateAccount("admin", "password", hotline.AccessDisconUser, hotline.AccessSendChat)
+//	srv.CreateAccount("guest", "", hotline.AccessSendChat)
+//
+//	admin := srv.Login("admin", "password", "Admin")
+//	guest := srv.Login("guest", "", "Guest")
+//
+//	guest.Send(hotline.NewTransaction(hotline.TranChatSend, [2]byte{}, hotline.NewField(hotline.FieldData, []byte("hi"))))
+//	msg := admin.Expect(hotline.TranChatMsg)
+//
+// File transfers are not supported, as they require a second connection to the file transfer port.
+package hltest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/mobius"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// Server is a Hotline server with the standard Mobius transaction handlers.  Accounts are kept in memory, and the other
+// stores in a temporary directory that is removed when the test ends.  The embedded hotline.Server can be used to change the config or replace stores
+// before clients connect.
+type Server struct {
+	*hotline.Server
+
+	Dir string // Config directory holding the server's files and stores
+
+	t        testing.TB
+	ctx      context.Context
+	nextAddr atomic.Uint32
+}
+
+// NewServer starts a server for the duration of the test.  The server has no accounts; use CreateAccount to add the
+// accounts needed by the test.  By default the server logs are discarded and the file root is the Files folder in Dir.
+func NewServer(t testing.TB, opts ...hotline.Option) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "Files"), 0750); err != nil {
+		t.Fatalf("create file root: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "MessageBoard.txt"), nil, 0640); err != nil {
+		t.Fatalf("create message board: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ThreadedNews.yaml"), []byte("Categories: {}\n"), 0640); err != nil {
+		t.Fatalf("create threaded news: %v", err)
+	}
+
+	srv, err := hotline.NewServer(append([]hotline.Option{
+		hotline.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		hotline.WithConfig(hotline.Config{Name: "Test Server", FileRoot: filepath.Join(dir, "Files")}),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+
+	srv.Agreement = bytes.NewReader(nil)
+
+	if srv.MessageBoard, err = mobius.NewFlatNews(filepath.Join(dir, "MessageBoard.txt")); err != nil {
+		t.Fatalf("create message board: %v", err)
+	}
+	if srv.ThreadedNewsMgr, err = mobius.NewThreadedNewsYAML(filepath.Join(dir, "ThreadedNews.yaml")); err != nil {
+		t.Fatalf("create threaded news: %v", err)
+	}
+	if srv.BanList, err = mobius.NewBanFile(filepath.Join(dir, "Banlist.yaml")); err != nil {
+		t.Fatalf("create ban list: %v", err)
+	}
+	if srv.FileReportMgr, err = mobius.NewFileReportsYAML(filepath.Join(dir, "FileReports.yaml")); err != nil {
+		t.Fatalf("create file reports: %v", err)
+	}
+	if srv.IncompleteUploadMgr, err = mobius.NewIncompleteUploadsYAML(filepath.Join(dir, "IncompleteUploads.yaml")); err != nil {
+		t.Fatalf("create incomplete uploads: %v", err)
+	}
+	if srv.BroadcastHistory, err = mobius.NewBroadcastHistoryYAML(filepath.Join(dir, "Broadcasts.yaml"), filepath.Join(dir, "LastLogins.yaml")); err != nil {
+		t.Fatalf("create broadcast history: %v", err)
+	}
+	if srv.Events, err = mobius.NewEventsYAML(filepath.Join(dir, "Events.yaml")); err != nil {
+		t.Fatalf("create events: %v", err)
+	}
+	srv.AccountManager = NewMemAccountManager()
+
+	mobius.RegisterHandlers(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &Server{Server: srv, Dir: dir, t: t, ctx: ctx}
+}
+
+// CreateAccount creates an account with the given access privileges, e.g. hotline.AccessSendChat.  An empty password
+// creates an account that can log in without one, as with the default guest account.
+func (s *Server) CreateAccount(login, password string, access ...int) *hotline.Account {
+	s.t.Helper()
+
+	var bits hotline.AccessBitmap
+	for _, a := range access {
+		bits.Set(a)
+	}
+
+	account := hotline.NewAccount(login, login, string(hotline.EncodeString([]byte(password))), bits)
+	if err := s.AccountManager.Create(*account); err != nil {
+		s.t.Fatalf("create account %q: %v", login, err)
+	}
+
+	return account
+}
+
+// Connect connects a new client and completes the protocol handshake, but does not log in.  Each client is given a
+// distinct remote address so that per-address limits apply to it separately.
+func (s *Server) Connect() *Client {
+	s.t.Helper()
+
+	n := s.nextAddr.Add(1)
+
+	return s.ConnectFrom(fmt.Sprintf("10.0.%d.%d:5500", n/256, n%256))
+}
+
+// ConnectFrom is like Connect, but the client connects from remoteAddr, e.g. to reconnect from the same address.
+func (s *Server) ConnectFrom(remoteAddr string) *Client {
+	s.t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+
+	go func() { _ = s.ServeConn(s.ctx, serverConn, remoteAddr) }()
+
+	c := newClient(s.t, clientConn)
+	c.handshake()
+
+	return c
+}
+
+// Login connects a new client and logs in to the account with the given login and password, using name as the user
+// name.  The test fails if the login is rejected; use Connect and Client.Do to test failed logins.
+func (s *Server) Login(login, password, name string) *Client {
+	s.t.Helper()
+
+	c := s.Connect()
+	c.Login(login, password, name)
+
+	return c
+}
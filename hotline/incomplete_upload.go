@@ -0,0 +1,98 @@
+package hotline
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// IncompleteUpload is a file upload that was interrupted before it completed.  The data received so far is kept in a
+// file with the IncompleteFileSuffix so that the uploader can resume the upload later, or delete it.
+type IncompleteUpload struct {
+	FullPath string    `yaml:"FullPath"` // Path of the file on disk, without the IncompleteFileSuffix
+	Path     string    `yaml:"Path"`     // Path of the file relative to the uploader's file root, e.g. "Uploads/file.sit"
+	Login    string    `yaml:"Login"`    // Login of the uploading account
+	Started  time.Time `yaml:"Started"`
+}
+
+type IncompleteUploadMgr interface {
+	Add(upload IncompleteUpload) error
+	List(login string) []IncompleteUpload
+	Delete(fullPath string) error
+}
+
+// NewIncompleteUploadField returns a FieldIncompleteUpload describing an incomplete upload:
+//
+//	4 bytes: bytes received so far
+//	4 bytes: seconds since the upload started
+//	2 bytes: length of the path
+//	n bytes: path relative to the file root, with folders separated by "/"
+func NewIncompleteUploadField(uploadPath string, size int64, age time.Duration) Field {
+	encoded, err := txtEncoder.String(uploadPath)
+	if err != nil {
+		encoded = uploadPath
+	}
+
+	b := make([]byte, 10, 10+len(encoded))
+	binary.BigEndian.PutUint32(b[0:4], uint32(min(size, maxFileSize-1)))
+	binary.BigEndian.PutUint32(b[4:8], uint32(max(age, 0)/time.Second))
+	binary.BigEndian.PutUint16(b[8:10], uint16(len(encoded)))
+
+	return NewField(FieldIncompleteUpload, append(b, encoded...))
+}
+
+// trackIncompleteUpload records an upload that is starting, so that it is listed for the uploader if it is interrupted.
+func (s *Server) trackIncompleteUpload(ft *FileTransfer, fullPath string) {
+	if s.IncompleteUploadMgr == nil || ft.ClientConn == nil || ft.ClientConn.Account == nil {
+		return
+	}
+
+	uploadPath, err := RelativePath(ft.FilePath, ft.FileName)
+	if err != nil {
+		return
+	}
+
+	err = s.IncompleteUploadMgr.Add(IncompleteUpload{
+		FullPath: fullPath,
+		Path:     uploadPath,
+		Login:    ft.ClientConn.Account.Login,
+		Started:  time.Now(),
+	})
+	if err != nil {
+		s.Logger.Error("Error recording incomplete upload", "path", fullPath, "err", err)
+	}
+}
+
+// untrackIncompleteUpload removes a completed upload from the incomplete uploads.
+func (s *Server) untrackIncompleteUpload(fullPath string) {
+	if s.IncompleteUploadMgr == nil {
+		return
+	}
+
+	if err := s.IncompleteUploadMgr.Delete(fullPath); err != nil {
+		s.Logger.Error("Error removing incomplete upload", "path", fullPath, "err", err)
+	}
+}
+
+type MockIncompleteUploadMgr struct {
+	mock.Mock
+}
+
+func (m *MockIncompleteUploadMgr) Add(upload IncompleteUpload) error {
+	args := m.Called(upload)
+
+	return args.Error(0)
+}
+
+func (m *MockIncompleteUploadMgr) List(login string) []IncompleteUpload {
+	args := m.Called(login)
+
+	return args.Get(0).([]IncompleteUpload)
+}
+
+func (m *MockIncompleteUploadMgr) Delete(fullPath string) error {
+	args := m.Called(fullPath)
+
+	return args.Error(0)
+}
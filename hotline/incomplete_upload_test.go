@@ -0,0 +1,20 @@
+package hotline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIncompleteUploadField(t *testing.T) {
+	f := NewIncompleteUploadField("Uploads/a.sit", 2048, 90*time.Second)
+
+	assert.Equal(t, FieldIncompleteUpload, f.Type)
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x08, 0x00, // 2048 bytes
+		0x00, 0x00, 0x00, 0x5a, // 90 seconds
+		0x00, 0x0d, // path length
+		'U', 'p', 'l', 'o', 'a', 'd', 's', '/', 'a', '.', 's', 'i', 't',
+	}, f.Data)
+}
@@ -0,0 +1,86 @@
+package hotline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginWindow is a daily time range, in server local time, during which an account is allowed to be logged in.  A
+// window whose end is before its start spans midnight, e.g. "22:00-06:00".
+//
+// In account files it is written as "HH:MM-HH:MM", e.g.:
+//
+//	LoginHours: "08:00-23:00"
+type LoginWindow struct {
+	Start time.Duration // Offset from midnight
+	End   time.Duration // Offset from midnight
+}
+
+// ParseLoginWindow parses a login window in the form "HH:MM-HH:MM".
+func ParseLoginWindow(s string) (LoginWindow, error) {
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return LoginWindow{}, fmt.Errorf("invalid login window %q: expected HH:MM-HH:MM", s)
+	}
+
+	start, err := parseTimeOfDay(strings.TrimSpace(startStr))
+	if err != nil {
+		return LoginWindow{}, fmt.Errorf("invalid login window %q: %w", s, err)
+	}
+
+	end, err := parseTimeOfDay(strings.TrimSpace(endStr))
+	if err != nil {
+		return LoginWindow{}, fmt.Errorf("invalid login window %q: %w", s, err)
+	}
+
+	return LoginWindow{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns true if t falls within the login window.  A nil window allows logins at any time.
+func (w *LoginWindow) Contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+
+	// Window spans midnight
+	return offset >= w.Start || offset < w.End
+}
+
+func (w LoginWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", int(w.Start.Hours()), int(w.Start.Minutes())%60, int(w.End.Hours()), int(w.End.Minutes())%60)
+}
+
+func (w *LoginWindow) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseLoginWindow(s)
+	if err != nil {
+		return err
+	}
+	*w = parsed
+
+	return nil
+}
+
+func (w LoginWindow) MarshalYAML() (interface{}, error) {
+	return w.String(), nil
+}
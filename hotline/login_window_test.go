@@ -0,0 +1,45 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+	"time"
+)
+
+func TestLoginWindow_Contains(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, time.March, 4, hour, minute, 0, 0, time.Local)
+	}
+
+	day, err := ParseLoginWindow("08:00-23:00")
+	assert.NoError(t, err)
+	assert.False(t, day.Contains(at(7, 59)))
+	assert.True(t, day.Contains(at(8, 0)))
+	assert.True(t, day.Contains(at(22, 59)))
+	assert.False(t, day.Contains(at(23, 0)))
+
+	night, err := ParseLoginWindow("22:00 - 06:30")
+	assert.NoError(t, err)
+	assert.True(t, night.Contains(at(23, 0)))
+	assert.True(t, night.Contains(at(6, 29)))
+	assert.False(t, night.Contains(at(12, 0)))
+
+	var unrestricted *LoginWindow
+	assert.True(t, unrestricted.Contains(at(3, 0)))
+
+	_, err = ParseLoginWindow("8am-11pm")
+	assert.Error(t, err)
+}
+
+func TestLoginWindow_YAML(t *testing.T) {
+	var account Account
+	assert.NoError(t, yaml.Unmarshal([]byte(`LoginHours: "22:00-06:30"`), &account))
+	assert.Equal(t, &LoginWindow{Start: 22 * time.Hour, End: 6*time.Hour + 30*time.Minute}, account.LoginHours)
+
+	out, err := yaml.Marshal(account)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "LoginHours: 22:00-06:30")
+
+	assert.Error(t, yaml.Unmarshal([]byte(`LoginHours: "tomorrow"`), &account))
+}
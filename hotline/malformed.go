@@ -0,0 +1,66 @@
+package hotline
+
+import (
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+// ErrorCodeMalformed is the error code of replies to transactions with fields that couldn't be parsed, which lets
+// clients tell them apart from other errors.
+var ErrorCodeMalformed = [4]byte{0, 0, 0, 2}
+
+// malformedReplyLimit limits the error replies sent to a client for malformed transactions, so that a broken or
+// hostile client can't use them to flood the outbox.
+var malformedReplyLimit = RateLimit{Count: 1, Per: time.Second, Burst: 5}
+
+// malformedSampleLen is the max number of bytes of a malformed transaction included in the log.
+const malformedSampleLen = 256
+
+// NewMalformedReply returns an error reply to t, a transaction with fields that couldn't be parsed because of err, so
+// that the client isn't left waiting for a reply.  The first malformed transaction from each connection is logged with
+// a hex dump of its start.  Replies are rate limited per connection, and none is returned while over the limit.
+func (cc *ClientConn) NewMalformedReply(t *Transaction, err error) []Transaction {
+	cc.mu.Lock()
+	first := !cc.malformedLogged
+	cc.malformedLogged = true
+	if cc.malformedLimiter == nil {
+		cc.malformedLimiter = malformedReplyLimit.newLimiter()
+	}
+	allow := cc.malformedLimiter.Allow()
+	cc.mu.Unlock()
+
+	cc.recordViolation(ViolationMalformed, tranTypeNames[t.Type])
+
+	if first {
+		cc.Logger.Warn("Malformed transaction", "type", tranTypeNames[t.Type], "err", err, "sample", malformedSample(t))
+	} else {
+		cc.Logger.Debug("Malformed transaction", "type", tranTypeNames[t.Type], "err", err)
+	}
+
+	if !allow {
+		return nil
+	}
+
+	return []Transaction{
+		{
+			ClientID:  cc.ID,
+			IsReply:   1,
+			ID:        t.ID,
+			ErrorCode: ErrorCodeMalformed,
+			Fields: []Field{
+				NewField(FieldError, []byte(cc.T("The server could not understand the request."))),
+			},
+		},
+	}
+}
+
+// malformedSample returns a hex dump of the first malformedSampleLen bytes of t.
+func malformedSample(t *Transaction) string {
+	tc := *t
+	tc.readOffset = 0
+
+	b, _ := io.ReadAll(io.LimitReader(&tc, malformedSampleLen))
+
+	return hex.Dump(b)
+}
@@ -0,0 +1,37 @@
+package hotline
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConn_NewMalformedReply(t *testing.T) {
+	var logs bytes.Buffer
+	cc := &ClientConn{
+		ID:     [2]byte{0, 1},
+		Logger: slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo})),
+		Server: &Server{},
+	}
+	tran := NewTransaction(TranNewFolder, [2]byte{0, 1}, NewField(FieldFilePath, []byte{0x00}))
+
+	replies := cc.NewMalformedReply(&tran, errors.New("short path"))
+	if assert.Len(t, replies, 1) {
+		assert.Equal(t, ErrorCodeMalformed, replies[0].ErrorCode)
+		assert.Equal(t, tran.ID, replies[0].ID)
+		assert.Equal(t, []byte("The server could not understand the request."), replies[0].GetField(FieldError).Data)
+	}
+
+	// Replies are limited to the burst of the rate limit, and only the first transaction is logged with a sample.
+	var sent int
+	for range 10 {
+		sent += len(cc.NewMalformedReply(&tran, errors.New("short path")))
+	}
+	assert.Equal(t, malformedReplyLimit.Burst-1, sent)
+	assert.Equal(t, 1, strings.Count(logs.String(), `msg="Malformed transaction"`))
+	assert.Contains(t, logs.String(), "00000000  00 00 00 cd") // Flags, IsReply, and Type in the hex dump
+}
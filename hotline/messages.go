@@ -0,0 +1,94 @@
+package hotline
+
+import (
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// MessageCatalog maps the built-in English text of server messages to replacement text, such as a translation into
+// the server's locale.  Keys are the exact English message, including any fmt verbs, e.g.:
+//
+//	"You are not allowed to download files.": "Sie dürfen keine Dateien herunterladen."
+type MessageCatalog map[string]string
+
+// T returns the replacement for msg, transliterated to Mac Roman, or msg unchanged if there is none.  Operator
+// overrides from the MessageOverrides config take precedence over the locale message catalog.  Messages containing fmt
+// verbs should be passed to T before formatting.
+func (s *Server) T(msg string) string {
+	if s == nil {
+		return msg
+	}
+
+	if text, ok := s.Config.MessageOverrides[msg]; ok {
+		return ToMacRoman(text)
+	}
+
+	if text, ok := s.Messages[msg]; ok {
+		return ToMacRoman(text)
+	}
+
+	return msg
+}
+
+// T returns msg in the user's preferred locale if they have set one, or in the server locale otherwise.
+func (cc *ClientConn) T(msg string) string {
+	if cc.Server == nil || cc.Account == nil {
+		return cc.Server.T(msg)
+	}
+
+	if _, ok := cc.Server.Config.MessageOverrides[msg]; !ok {
+		if text, ok := cc.Server.Locales[cc.Account.Preferences[PrefLocale]][msg]; ok {
+			return ToMacRoman(text)
+		}
+	}
+
+	return cc.Server.T(msg)
+}
+
+// transliterations are replacements for common characters that are not in the Mac Roman character set and do not
+// decompose to a base character that is.
+var transliterations = map[rune]string{
+	'Đ': "D", 'đ': "d", 'Ł': "L", 'ł': "l", 'Ħ': "H", 'ħ': "h",
+	'Ŧ': "T", 'ŧ': "t", 'Ŋ': "N", 'ŋ': "n", 'Þ': "Th", 'þ': "th",
+	'Ð': "D", 'ð': "d", 'ı': "i", 'ŀ': "l", '„': "\"", '‚': "'",
+}
+
+// ToMacRoman encodes the UTF-8 string s as Mac Roman.  Characters that are not in the Mac Roman character set are
+// replaced with their unaccented equivalent where possible, or "?" otherwise.
+func ToMacRoman(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if b, ok := macRomanByte(r); ok {
+			sb.WriteByte(b)
+			continue
+		}
+
+		if t, ok := transliterations[r]; ok {
+			sb.WriteString(t)
+			continue
+		}
+
+		// Decompose accented characters and keep the base characters that can be encoded, e.g. "ő" -> "o".
+		var replaced bool
+		for _, dr := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, dr) {
+				continue
+			}
+			if b, ok := macRomanByte(dr); ok {
+				sb.WriteByte(b)
+				replaced = true
+			}
+		}
+		if !replaced {
+			sb.WriteByte('?')
+		}
+	}
+
+	return sb.String()
+}
+
+func macRomanByte(r rune) (byte, bool) {
+	return charmap.Macintosh.EncodeRune(r)
+}
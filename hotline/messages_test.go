@@ -0,0 +1,56 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestToMacRoman(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "ascii", in: "Hello", want: "Hello"},
+		{name: "mac roman characters", in: "Grüße", want: "Gr\x9f\xa7e"},
+		{name: "decomposable accents", in: "Wałęsa ő", want: "Wal\x65sa o"},
+		{name: "unencodable", in: "Привет", want: "??????"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ToMacRoman(tt.in))
+		})
+	}
+}
+
+func TestServer_T(t *testing.T) {
+	s := &Server{Messages: MessageCatalog{"Incorrect login.": "Falsche Anmeldedaten für %s."}}
+
+	assert.Equal(t, "Falsche Anmeldedaten f\x9fr %s.", s.T("Incorrect login."))
+	assert.Equal(t, "Account not found.", s.T("Account not found."))
+
+	// Config overrides take precedence over the message catalog.
+	s.Config.MessageOverrides = map[string]string{
+		"Incorrect login.":                       "Nope.",
+		"You are not allowed to download files.": "Downloads are for members only.",
+	}
+	assert.Equal(t, "Nope.", s.T("Incorrect login."))
+	assert.Equal(t, "Downloads are for members only.", s.T("You are not allowed to download files."))
+
+	var nilServer *Server
+	assert.Equal(t, "Incorrect login.", nilServer.T("Incorrect login."))
+}
+
+func TestClientConn_NewErrReplyf(t *testing.T) {
+	cc := &ClientConn{Server: &Server{Messages: MessageCatalog{
+		"Cannot delete %s.": "%s kann nicht gelöscht werden.",
+		"The disk is full.": "Die Festplatte ist zu 100% voll.",
+	}}}
+
+	// The format is translated once, before the args are formatted into it.
+	res := cc.NewErrReplyf(&Transaction{}, "Cannot delete %s.", "100%.txt")
+	assert.Equal(t, "100%.txt kann nicht gel\x9ascht werden.", string(res[0].GetField(FieldError).Data))
+
+	res = cc.NewErrReplyf(&Transaction{}, "The disk is full.")
+	assert.Equal(t, "Die Festplatte ist zu 100% voll.", string(res[0].GetField(FieldError).Data))
+}
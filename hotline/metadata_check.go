@@ -0,0 +1,158 @@
+package hotline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MetadataProblem is a corrupt info or resource fork found by CheckMetadata.
+type MetadataProblem struct {
+	Path        string `json:"path"`        // Path of the fork relative to the FileRoot
+	Error       string `json:"error"`       // What is wrong with the fork
+	Regenerated bool   `json:"regenerated"` // Whether a default info fork was written in place of the corrupt one
+}
+
+// CheckMetadata scans the FileRoot for info and resource forks that can't be read, such as forks truncated by an
+// interrupted upload, and moves them to the QuarantineDir so that the files they belong to can be downloaded again.
+// Corrupt info forks of existing files are replaced with the defaults the server would otherwise generate.  If dryRun
+// is set, the problems are returned without changing anything.
+func (s *Server) CheckMetadata(dryRun bool) ([]MetadataProblem, error) {
+	if s.Config.QuarantineDir == "" && !dryRun {
+		return nil, errors.New("no QuarantineDir is configured")
+	}
+
+	fileRoot := s.Config.FileRoot
+
+	var problems []MetadataProblem
+	err := filepath.WalkDir(fileRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == s.Config.QuarantineDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var checkErr error
+		switch {
+		case strings.HasPrefix(d.Name(), ".info_"):
+			checkErr = s.checkInfoFork(path)
+		case strings.HasPrefix(d.Name(), ".rsrc_"):
+			checkErr = checkRsrcFork(d)
+		default:
+			return nil
+		}
+		if checkErr == nil {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(fileRoot, path)
+		problem := MetadataProblem{Path: filepath.ToSlash(rel), Error: checkErr.Error()}
+
+		if dryRun {
+			s.Logger.Info("Metadata check dry run: would quarantine fork", "path", rel, "err", checkErr)
+			problems = append(problems, problem)
+			return nil
+		}
+
+		if err := s.quarantine(path, rel); err != nil {
+			return fmt.Errorf("quarantine %s: %w", rel, err)
+		}
+		s.Logger.Warn("Quarantined corrupt metadata fork", "path", rel, "err", checkErr)
+
+		if strings.HasPrefix(d.Name(), ".info_") {
+			problem.Regenerated, err = s.regenerateInfoFork(filepath.Join(filepath.Dir(path), strings.TrimPrefix(d.Name(), ".info_")))
+			if err != nil {
+				return fmt.Errorf("regenerate %s: %w", rel, err)
+			}
+		}
+
+		problems = append(problems, problem)
+
+		return nil
+	})
+
+	return problems, err
+}
+
+func (s *Server) checkInfoFork(path string) error {
+	b, err := s.FS.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return validateInfoFork(b)
+}
+
+// checkRsrcFork returns an error if the resource fork can't be sent to clients, whose transfer headers hold the fork
+// size in 4 bytes.
+func checkRsrcFork(d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return errors.New("resource fork is not a regular file")
+	}
+	if info.Size() > math.MaxUint32 {
+		return fmt.Errorf("resource fork is %d bytes, larger than the maximum of %d", info.Size(), uint32(math.MaxUint32))
+	}
+
+	return nil
+}
+
+// quarantine moves the file at path to the same relative path rel in the QuarantineDir.
+func (s *Server) quarantine(path, rel string) error {
+	dst := filepath.Join(s.Config.QuarantineDir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	defer s.FileIndex.Invalidate(path)
+
+	return s.FS.Rename(path, dst)
+}
+
+// regenerateInfoFork writes a default info fork for the file at dataPath, and returns false if the file no longer
+// exists.
+func (s *Server) regenerateInfoFork(dataPath string) (bool, error) {
+	fw, err := NewFileWrapper(s.FS, dataPath, 0)
+	if err != nil {
+		return false, err
+	}
+	if _, err := fw.DataFile(); err != nil {
+		return false, nil
+	}
+
+	w, err := fw.InfoForkWriter()
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(w, &fw.Ffo.FlatFileInformationFork); err != nil {
+		_ = w.Close()
+		return false, err
+	}
+
+	return true, w.Close()
+}
+
+// startupMetadataCheck runs CheckMetadata and logs the result, so that forks corrupted while the server was stopped
+// are repaired before users try to download the files.
+func (s *Server) startupMetadataCheck() {
+	problems, err := s.CheckMetadata(false)
+	if err != nil {
+		s.Logger.Error("Error checking file metadata", "err", err)
+		return
+	}
+	if len(problems) > 0 {
+		s.Logger.Info("Repaired file metadata", "quarantined", len(problems), "quarantineDir", s.Config.QuarantineDir)
+	}
+}
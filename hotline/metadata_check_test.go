@@ -0,0 +1,80 @@
+package hotline
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_CheckMetadata(t *testing.T) {
+	root := t.TempDir()
+	quarantineDir := filepath.Join(t.TempDir(), "Quarantine")
+	uploads := filepath.Join(root, "Uploads")
+	require.NoError(t, os.MkdirAll(uploads, 0777))
+
+	validFork := NewFlatFileInformationFork("good.sit", NewTime(time.Now()), "SIT!", "SIT!")
+	validForkBytes, err := io.ReadAll(&validFork)
+	require.NoError(t, err)
+
+	writeFile := func(path string, data []byte) {
+		require.NoError(t, os.WriteFile(path, data, 0644))
+	}
+	writeFile(filepath.Join(uploads, "good.sit"), []byte("data"))
+	writeFile(filepath.Join(uploads, ".info_good.sit"), validForkBytes)
+	writeFile(filepath.Join(uploads, "bad.sit"), []byte("data"))
+	writeFile(filepath.Join(uploads, ".info_bad.sit"), validForkBytes[:40])
+	writeFile(filepath.Join(uploads, ".info_orphan.sit"), []byte{})
+	writeFile(filepath.Join(uploads, ".rsrc_good.sit"), []byte("resource"))
+
+	s := &Server{
+		FS:     &OSFileStore{},
+		Logger: NewTestLogger(),
+		Config: Config{FileRoot: root, QuarantineDir: quarantineDir},
+	}
+
+	// A corrupt info fork doesn't stop the file from being read.
+	fw, err := NewFileWrapper(s.FS, filepath.Join(uploads, "bad.sit"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "bad.sit", string(fw.Ffo.FlatFileInformationFork.Name))
+
+	problems, err := s.CheckMetadata(true)
+	require.NoError(t, err)
+	assert.Len(t, problems, 2)
+	assert.FileExists(t, filepath.Join(uploads, ".info_bad.sit"))
+
+	problems, err = s.CheckMetadata(false)
+	require.NoError(t, err)
+	assert.Equal(t, []MetadataProblem{
+		{Path: "Uploads/.info_bad.sit", Error: "information fork is 40 bytes, shorter than the minimum of 72", Regenerated: true},
+		{Path: "Uploads/.info_orphan.sit", Error: "information fork is 0 bytes, shorter than the minimum of 72"},
+	}, problems)
+
+	assert.FileExists(t, filepath.Join(quarantineDir, "Uploads", ".info_bad.sit"))
+	assert.FileExists(t, filepath.Join(quarantineDir, "Uploads", ".info_orphan.sit"))
+	assert.NoFileExists(t, filepath.Join(uploads, ".info_orphan.sit"))
+
+	// The corrupt info fork is replaced with a valid default.
+	b, err := os.ReadFile(filepath.Join(uploads, ".info_bad.sit"))
+	require.NoError(t, err)
+	assert.NoError(t, validateInfoFork(b))
+
+	problems, err = s.CheckMetadata(false)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestServer_CheckMetadata_noQuarantineDir(t *testing.T) {
+	s := &Server{FS: &OSFileStore{}, Logger: NewTestLogger(), Config: Config{FileRoot: t.TempDir()}}
+
+	_, err := s.CheckMetadata(false)
+	assert.Error(t, err)
+
+	problems, err := s.CheckMetadata(true)
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
@@ -0,0 +1,18 @@
+package hotline
+
+import "strings"
+
+// MOTD returns a server message with the configured message of the day, or nil if there is none.
+func (cc *ClientConn) MOTD() []Transaction {
+	motd := cc.Server.CurrentConfig().MOTD
+	if motd == "" {
+		return nil
+	}
+
+	return []Transaction{NewTransaction(
+		TranServerMsg,
+		cc.ID,
+		NewField(FieldData, []byte(ToMacRoman(strings.ReplaceAll(motd, "\n", "\r")))),
+		NewField(FieldChatOptions, []byte{0, 0}),
+	)}
+}
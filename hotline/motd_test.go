@@ -0,0 +1,19 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConn_MOTD(t *testing.T) {
+	cc := &ClientConn{ID: ClientID{0, 1}, Server: &Server{}}
+	assert.Nil(t, cc.MOTD())
+
+	cc.Server.UpdateConfig(func(config *Config) { config.MOTD = "Welcome!\nNo spam." })
+
+	res := cc.MOTD()
+	assert.Len(t, res, 1)
+	assert.Equal(t, TranServerMsg, res[0].Type)
+	assert.Equal(t, []byte("Welcome!\rNo spam."), res[0].GetField(FieldData).Data)
+}
@@ -3,6 +3,7 @@ package hotline
 import (
 	"cmp"
 	"encoding/binary"
+	"fmt"
 	"github.com/stretchr/testify/mock"
 	"io"
 	"slices"
@@ -15,6 +16,7 @@ var (
 
 type ThreadedNewsMgr interface {
 	ListArticles(newsPath []string) NewsArtListData
+	Articles(newsPath []string) map[uint32]*NewsArtData
 	GetArticle(newsPath []string, articleID uint32) *NewsArtData
 	DeleteArticle(newsPath []string, articleID uint32, recursive bool) error
 	PostArticle(newsPath []string, parentArticleID uint32, article NewsArtData) error
@@ -49,12 +51,21 @@ func (newscat *NewsCategoryListData15) GetNewsArtListData() NewsArtListData {
 		id := make([]byte, 4)
 		binary.BigEndian.PutUint32(id, i)
 
+		var flavors []NewsFlavorList
+		for _, f := range art.AltFlavors {
+			flavors = append(flavors, NewsFlavorList{Flavor: []byte(f.Flavor), Size: uint16(len(f.Data))})
+		}
+		for _, a := range art.Attachments {
+			flavors = append(flavors, NewsFlavorList{Flavor: []byte(a.Flavor), Size: uint16(a.Size)})
+		}
+
 		newsArts = append(newsArts, NewsArtList{
 			ID:          [4]byte(id),
 			TimeStamp:   art.Date,
 			ParentID:    art.ParentArt,
 			Title:       []byte(art.Title),
 			Poster:      []byte(art.Poster),
+			FlavorList:  flavors,
 			ArticleSize: art.DataSize(),
 		})
 	}
@@ -88,6 +99,7 @@ func (newscat *NewsCategoryListData15) GetNewsArtListData() NewsArtListData {
 type NewsArtData struct {
 	Title         string  `yaml:"Title"`
 	Poster        string  `yaml:"Poster"`
+	PosterLogin   string  `yaml:"PosterLogin,omitempty"` // Login of the posting account; empty for articles posted before it was recorded
 	Date          [8]byte `yaml:"Date,flow"`
 	PrevArt       [4]byte `yaml:"PrevArt,flow"`
 	NextArt       [4]byte `yaml:"NextArt,flow"`
@@ -95,6 +107,40 @@ type NewsArtData struct {
 	FirstChildArt [4]byte `yaml:"FirstChildArtArt,flow"`
 	DataFlav      []byte  `yaml:"-"` // MIME type string.  Always "text/plain".
 	Data          string  `yaml:"Data"`
+
+	AltFlavors  []NewsArtFlavor  `yaml:"AltFlavors,omitempty"`  // The text in other formats, such as text/html, for clients that can show them
+	Attachments []NewsAttachment `yaml:"Attachments,omitempty"` // Files posted with the article as additional data flavors
+}
+
+// NewsHTMLFlavor is the data flavor of articles formatted as HTML.
+const NewsHTMLFlavor = "text/html"
+
+// NewsArtFlavor is the text of an article in a format other than text/plain.
+type NewsArtFlavor struct {
+	Flavor string `yaml:"Flavor"` // MIME type
+	Data   string `yaml:"Data"`
+}
+
+// AltFlavor returns the text of the article in the MIME type flavor, if it has one.
+func (art *NewsArtData) AltFlavor(flavor string) (string, bool) {
+	for _, f := range art.AltFlavors {
+		if f.Flavor == flavor {
+			return f.Data, true
+		}
+	}
+
+	return "", false
+}
+
+// Attachment returns the attachment with the MIME type flavor, or nil if there is none.
+func (art *NewsArtData) Attachment(flavor string) *NewsAttachment {
+	for i, a := range art.Attachments {
+		if a.Flavor == flavor {
+			return &art.Attachments[i]
+		}
+	}
+
+	return nil
 }
 
 func (art *NewsArtData) DataSize() [2]byte {
@@ -148,10 +194,9 @@ type NewsArtList struct {
 	Title []byte // string
 	// Poster size	1
 	// Poster	Poster string
-	Poster     []byte
-	FlavorList []NewsFlavorList
-	// Flavor list…			Optional (if flavor count > 0)
-	ArticleSize [2]byte // Size 2
+	Poster      []byte
+	FlavorList  []NewsFlavorList // Flavors of the article's attachments, which are listed after the text
+	ArticleSize [2]byte          // Size 2
 
 	readOffset int // Internal offset to track read progress
 }
@@ -162,12 +207,15 @@ var (
 )
 
 func (nal *NewsArtList) Read(p []byte) (int, error) {
+	flavorCount := make([]byte, 2)
+	binary.BigEndian.PutUint16(flavorCount, uint16(1+len(nal.FlavorList)))
+
 	out := slices.Concat(
 		nal.ID[:],
 		nal.TimeStamp[:],
 		nal.ParentID[:],
 		nal.Flags[:],
-		[]byte{0, 1}, // Flavor Count TODO: make this not hardcoded
+		flavorCount,
 		[]byte{uint8(len(nal.Title))},
 		nal.Title,
 		[]byte{uint8(len(nal.Poster))},
@@ -176,6 +224,9 @@ func (nal *NewsArtList) Read(p []byte) (int, error) {
 		NewsFlavor,
 		nal.ArticleSize[:],
 	)
+	for _, f := range nal.FlavorList {
+		out = slices.Concat(out, []byte{uint8(len(f.Flavor))}, f.Flavor, binary.BigEndian.AppendUint16(nil, f.Size))
+	}
 
 	if nal.readOffset >= len(out) {
 		return 0, io.EOF // All bytes have been read
@@ -187,10 +238,44 @@ func (nal *NewsArtList) Read(p []byte) (int, error) {
 	return n, io.EOF
 }
 
+// DecodeNewsArtList decodes the article list of a NewsArtListData, as encoded by GetNewsArtListData.
+func DecodeNewsArtList(b []byte) ([]NewsArtList, error) {
+	var arts []NewsArtList
+
+	r := newBinReader(b)
+	for r.Len() > 0 && r.Err() == nil {
+		var art NewsArtList
+		r.Read(art.ID[:])
+		r.Read(art.TimeStamp[:])
+		r.Read(art.ParentID[:])
+		r.Read(art.Flags[:])
+		r.Read(art.FlavorCount[:])
+		art.Title = r.Bytes(int(r.Uint8()))
+		art.Poster = r.Bytes(int(r.Uint8()))
+
+		// The text/plain flavor is listed first, with the size of the article text.
+		for i := range int(binary.BigEndian.Uint16(art.FlavorCount[:])) {
+			flavor := NewsFlavorList{Flavor: r.Bytes(int(r.Uint8())), Size: r.Uint16()}
+			if i == 0 {
+				binary.BigEndian.PutUint16(art.ArticleSize[:], flavor.Size)
+				continue
+			}
+			art.FlavorList = append(art.FlavorList, flavor)
+		}
+
+		arts = append(arts, art)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("decode news article list: %w", err)
+	}
+
+	return arts, nil
+}
+
 type NewsFlavorList struct {
-	// Flavor size	1
-	// Flavor text	size		MIME type string
-	// Article size	2
+	Flavor []byte // MIME type string
+	Size   uint16 // Size of the article data in this flavor
 }
 
 func (newscat *NewsCategoryListData15) Read(p []byte) (int, error) {
@@ -228,16 +313,6 @@ func (newscat *NewsCategoryListData15) nameLen() []byte {
 	return []byte{uint8(len(newscat.Name))}
 }
 
-// newsPathScanner implements bufio.SplitFunc for parsing incoming byte slices into complete tokens
-func newsPathScanner(data []byte, _ bool) (advance int, token []byte, err error) {
-	if len(data) < 3 {
-		return 0, nil, nil
-	}
-
-	advance = 3 + int(data[2])
-	return advance, data[3:advance], nil
-}
-
 type MockThreadNewsMgr struct {
 	mock.Mock
 }
@@ -253,6 +328,12 @@ func (m *MockThreadNewsMgr) GetArticle(newsPath []string, articleID uint32) *New
 
 	return args.Get(0).(*NewsArtData)
 }
+func (m *MockThreadNewsMgr) Articles(newsPath []string) map[uint32]*NewsArtData {
+	args := m.Called(newsPath)
+
+	return args.Get(0).(map[uint32]*NewsArtData)
+}
+
 func (m *MockThreadNewsMgr) DeleteArticle(newsPath []string, articleID uint32, recursive bool) error {
 	args := m.Called(newsPath, articleID, recursive)
 
@@ -0,0 +1,121 @@
+package hotline
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultNewsAttachmentMaxSize  = 32 * 1024
+	defaultNewsAttachmentMaxCount = 1
+)
+
+// NewsAttachment is a file posted with a news article.  Clients see each attachment as an additional data flavor of the
+// article, after the text.
+type NewsAttachment struct {
+	Flavor string `yaml:"Flavor"` // MIME type
+	File   string `yaml:"File"`   // Name of the file in the news attachments dir
+	Size   int    `yaml:"Size"`
+}
+
+// SizeLimit returns the max size in bytes of each attachment.
+func (a NewsAttachments) SizeLimit() int {
+	if a.MaxSize <= 0 {
+		return defaultNewsAttachmentMaxSize
+	}
+
+	return a.MaxSize
+}
+
+// CountLimit returns the max number of attachments per article.
+func (a NewsAttachments) CountLimit() int {
+	if a.MaxCount <= 0 {
+		return defaultNewsAttachmentMaxCount
+	}
+
+	return a.MaxCount
+}
+
+// TypeAllowed returns true if attachments with the MIME type flavor are allowed.
+func (a NewsAttachments) TypeAllowed(flavor string) bool {
+	if len(a.Types) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(a.Types, func(t string) bool { return strings.EqualFold(t, flavor) })
+}
+
+// NewsAttachmentStore stores the files attached to news articles in a directory, under random names that are
+// recorded in the article.
+type NewsAttachmentStore struct {
+	dir string
+}
+
+func NewNewsAttachmentStore(dir string) (*NewsAttachmentStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("create news attachments dir: %w", err)
+	}
+
+	return &NewsAttachmentStore{dir: dir}, nil
+}
+
+// path returns the path of the attachment file name, rejecting names that would point outside the store.
+func (s *NewsAttachmentStore) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid attachment name %q", name)
+	}
+
+	return filepath.Join(s.dir, name), nil
+}
+
+// Save stores data and returns the name of its file.
+func (s *NewsAttachmentStore) Save(data []byte) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(b)
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0640); err != nil {
+		return "", fmt.Errorf("save news attachment: %w", err)
+	}
+
+	return name, nil
+}
+
+// Read returns the data of the attachment file name.
+func (s *NewsAttachmentStore) Read(name string) ([]byte, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(p)
+}
+
+// Delete removes the files of attachments.  It does nothing on a nil store.
+func (s *NewsAttachmentStore) Delete(attachments []NewsAttachment) error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, a := range attachments {
+		p, err := s.path(a.File)
+		if err == nil {
+			err = os.Remove(p)
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io"
 	"testing"
+	"time"
 )
 
 func TestNewsCategoryListData15_MarshalBinary(t *testing.T) {
@@ -97,3 +98,27 @@ func TestNewsCategoryListData15_MarshalBinary(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeNewsArtList(t *testing.T) {
+	cat := NewsCategoryListData15{Articles: map[uint32]*NewsArtData{
+		1: {Title: "Hello", Poster: "Alice", Date: NewTime(time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)), Data: "first"},
+		2: {Title: "Re: Hello", Poster: "Bob", ParentArt: [4]byte{0, 0, 0, 1}, Data: "reply",
+			AltFlavors: []NewsArtFlavor{{Flavor: NewsHTMLFlavor, Data: "<p>reply</p>"}}},
+	}}
+
+	arts, err := DecodeNewsArtList(cat.GetNewsArtListData().NewsArtList)
+	assert.NoError(t, err)
+	if assert.Len(t, arts, 2) {
+		assert.Equal(t, [4]byte{0, 0, 0, 1}, arts[0].ID)
+		assert.Equal(t, "Hello", string(arts[0].Title))
+		assert.Equal(t, "Alice", string(arts[0].Poster))
+		assert.Equal(t, [2]byte{0, 5}, arts[0].ArticleSize)
+		assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local), Time(arts[0].TimeStamp).Time())
+
+		assert.Equal(t, [4]byte{0, 0, 0, 1}, arts[1].ParentID)
+		assert.Equal(t, []NewsFlavorList{{Flavor: []byte(NewsHTMLFlavor), Size: 12}}, arts[1].FlavorList)
+	}
+
+	_, err = DecodeNewsArtList(cat.GetNewsArtListData().NewsArtList[:30])
+	assert.ErrorIs(t, err, ErrShortData)
+}
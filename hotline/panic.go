@@ -2,14 +2,32 @@ package hotline
 
 import (
 	"fmt"
-	"log/slog"
 	"runtime/debug"
+	"time"
 )
 
-// dontPanic logs panics instead of crashing
-func dontPanic(logger *slog.Logger) {
+// PanicReport describes a panic recovered while serving a connection.
+type PanicReport struct {
+	Value any       // Value passed to panic
+	Stack string    // Stack trace of the panicking goroutine
+	Time  time.Time // Time the panic was recovered
+}
+
+// PanicReporter sends recovered panics to an external error tracking service.
+type PanicReporter interface {
+	ReportPanic(report PanicReport)
+}
+
+// dontPanic logs panics instead of crashing, and reports them to the PanicReporter if one is configured.
+func (s *Server) dontPanic() {
 	if r := recover(); r != nil {
-		fmt.Println("stacktrace from panic: \n" + string(debug.Stack()))
-		logger.Error("PANIC", "err", r, "trace", string(debug.Stack()))
+		stack := string(debug.Stack())
+
+		fmt.Println("stacktrace from panic: \n" + stack)
+		s.Logger.Error("PANIC", "err", r, "trace", stack)
+
+		if s.PanicReporter != nil {
+			s.PanicReporter.ReportPanic(PanicReport{Value: r, Stack: stack, Time: time.Now()})
+		}
 	}
 }
@@ -0,0 +1,31 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+type testPanicReporter struct {
+	reports []PanicReport
+}
+
+func (r *testPanicReporter) ReportPanic(report PanicReport) {
+	r.reports = append(r.reports, report)
+}
+
+func TestServer_dontPanic(t *testing.T) {
+	reporter := &testPanicReporter{}
+	s := &Server{Logger: slog.Default(), PanicReporter: reporter}
+
+	func() {
+		defer s.dontPanic()
+
+		var cc *ClientConn
+		_ = cc.Account.Name
+	}()
+
+	assert.Len(t, reporter.reports, 1)
+	assert.Contains(t, reporter.reports[0].Value.(error).Error(), "nil pointer dereference")
+	assert.Contains(t, reporter.reports[0].Stack, "TestServer_dontPanic")
+}
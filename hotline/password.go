@@ -0,0 +1,183 @@
+package hotline
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// PasswordHasher hashes account passwords for storage in account files.  Passwords are checked with CheckPassword,
+// which recognizes the hashes of each hasher, so accounts keep working after the algorithm or cost is changed.
+type PasswordHasher interface {
+	// Hash returns the hash of the obfuscated plaintext password pwd.
+	Hash(pwd []byte) (string, error)
+
+	// NeedsRehash returns true if hash wasn't made by this hasher with its current settings, so that the password
+	// should be hashed again the next time it's available, i.e. when the user logs in.
+	NeedsRehash(hash string) bool
+}
+
+// NewPasswordHasher returns the PasswordHasher configured by config.
+func NewPasswordHasher(config PasswordHashing) (PasswordHasher, error) {
+	switch config.Algorithm {
+	case "", "bcrypt":
+		cost := config.Cost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		return BcryptHasher{Cost: cost}, nil
+	case "argon2id":
+		h := Argon2idHasher{Memory: config.Memory, Time: config.Time, Threads: config.Threads}
+		if h.Memory == 0 {
+			h.Memory = 64 * 1024
+		}
+		if h.Time == 0 {
+			h.Time = 3
+		}
+		if h.Threads == 0 {
+			h.Threads = 2
+		}
+		return h, nil
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", config.Algorithm)
+	}
+}
+
+// BcryptHasher hashes passwords with bcrypt.  HashAndSalt uses bcrypt at the minimum cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(pwd []byte) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(pwd, h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost != h.Cost
+}
+
+// argon2idPrefix starts argon2id hashes, which are encoded in the PHC string format used by the reference
+// implementation, e.g. "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>" with unpadded base64 salt and key.
+const argon2idPrefix = "$argon2id$"
+
+const (
+	argon2idSaltLen = 16
+	argon2idKeyLen  = 32
+)
+
+// Argon2idHasher hashes passwords with argon2id.
+type Argon2idHasher struct {
+	Memory  uint32 // Memory used in KiB
+	Time    uint32 // Number of passes over the memory
+	Threads uint8  // Degree of parallelism
+}
+
+func (h Argon2idHasher) Hash(pwd []byte) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	key := argon2.IDKey(pwd, salt, h.Time, h.Memory, h.Threads, argon2idKeyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := parseArgon2id(hash)
+
+	return err != nil || params != h
+}
+
+// parseArgon2id decodes an argon2id hash made by Argon2idHasher.
+func parseArgon2id(hash string) (params Argon2idHasher, salt, key []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(hash, argon2idPrefix), "$")
+	if !strings.HasPrefix(hash, argon2idPrefix) || len(parts) != 4 {
+		return params, nil, nil, fmt.Errorf("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[0])
+	}
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters %q: %w", parts[1], err)
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	return params, salt, key, nil
+}
+
+// CheckPassword returns true if pwd, an obfuscated plaintext password, matches hash.  Both bcrypt and argon2id hashes
+// are recognized, including those of older account files made with HashAndSalt.
+func CheckPassword(hash string, pwd []byte) bool {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), pwd) == nil
+	}
+
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	got := argon2.IDKey(pwd, salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(got, key) == 1
+}
+
+// HashPassword returns the hash of the obfuscated plaintext password pwd, made with the server's PasswordHasher, or
+// with HashAndSalt if the server doesn't have one.
+func (s *Server) HashPassword(pwd []byte) string {
+	if s.PasswordHasher == nil {
+		return HashAndSalt(pwd)
+	}
+
+	hash, err := s.PasswordHasher.Hash(pwd)
+	if err != nil && s.Logger != nil {
+		s.Logger.Error("Error hashing password", "err", err)
+	}
+
+	return hash
+}
+
+// rehashPassword hashes the password of account again with the server's PasswordHasher if its stored hash was made
+// with a different algorithm or cost, e.g. by HashAndSalt, and saves it.  Only the password is saved, so that concurrent
+// changes to the rest of the account aren't reverted.  pwd is the password the user just logged in with.
+func (s *Server) rehashPassword(account *Account, pwd []byte) {
+	if s.PasswordHasher == nil || !s.PasswordHasher.NeedsRehash(account.Password) {
+		return
+	}
+
+	hash, err := s.PasswordHasher.Hash(pwd)
+	if err != nil {
+		s.Logger.Error("Error rehashing password", "login", account.Login, "err", err)
+		return
+	}
+
+	account.Password = hash
+	if err := s.AccountManager.Modify(account.Login, func(a *Account) { a.Password = hash }); err != nil {
+		s.Logger.Error("Error saving rehashed password", "login", account.Login, "err", err)
+		return
+	}
+
+	s.Logger.Info("Upgraded password hash", "login", account.Login)
+}
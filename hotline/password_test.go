@@ -0,0 +1,77 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPasswordHasher(t *testing.T) {
+	h, err := NewPasswordHasher(PasswordHashing{})
+	require.NoError(t, err)
+	assert.Equal(t, BcryptHasher{Cost: 10}, h)
+
+	h, err = NewPasswordHasher(PasswordHashing{Algorithm: "argon2id", Time: 1})
+	require.NoError(t, err)
+	assert.Equal(t, Argon2idHasher{Memory: 64 * 1024, Time: 1, Threads: 2}, h)
+
+	_, err = NewPasswordHasher(PasswordHashing{Algorithm: "md5"})
+	assert.Error(t, err)
+}
+
+func TestPasswordHashers(t *testing.T) {
+	pwd := EncodeString([]byte("secret"))
+
+	for _, h := range []PasswordHasher{BcryptHasher{Cost: 5}, Argon2idHasher{Memory: 64, Time: 1, Threads: 1}} {
+		hash, err := h.Hash(pwd)
+		require.NoError(t, err)
+
+		assert.True(t, CheckPassword(hash, pwd))
+		assert.False(t, CheckPassword(hash, EncodeString([]byte("wrong"))))
+		assert.False(t, h.NeedsRehash(hash))
+
+		// Hashes from older account files still work, but are rehashed.
+		legacy := HashAndSalt(pwd)
+		assert.True(t, CheckPassword(legacy, pwd))
+		assert.True(t, h.NeedsRehash(legacy))
+	}
+
+	// Hashes made with other settings are rehashed.
+	hash, err := Argon2idHasher{Memory: 64, Time: 1, Threads: 1}.Hash(pwd)
+	require.NoError(t, err)
+	assert.True(t, Argon2idHasher{Memory: 128, Time: 1, Threads: 1}.NeedsRehash(hash))
+	assert.True(t, BcryptHasher{Cost: 5}.NeedsRehash(hash))
+
+	assert.False(t, CheckPassword("$argon2id$v=19$m=64,t=1,p=1$bad", pwd))
+	assert.False(t, CheckPassword("", pwd))
+}
+
+func TestClientConn_Authenticate_Rehash(t *testing.T) {
+	pwd := EncodeString([]byte("secret"))
+	accounts := testAccountMgr{"alice": {Login: "alice", Password: HashAndSalt(pwd)}}
+	hasher := Argon2idHasher{Memory: 64, Time: 1, Threads: 1}
+	cc := &ClientConn{Server: &Server{AccountManager: accounts, PasswordHasher: hasher, Logger: NewTestLogger()}}
+
+	assert.False(t, cc.Authenticate("alice", EncodeString([]byte("wrong"))))
+	assert.True(t, hasher.NeedsRehash(accounts["alice"].Password), "failed logins don't rehash")
+
+	assert.True(t, cc.Authenticate("alice", pwd))
+	assert.False(t, hasher.NeedsRehash(accounts["alice"].Password))
+	assert.True(t, cc.Authenticate("alice", pwd))
+}
+
+func TestServer_rehashPassword_keepsOtherChanges(t *testing.T) {
+	pwd := EncodeString([]byte("secret"))
+	accounts := testAccountMgr{"alice": {Login: "alice", Name: "Alice", Password: HashAndSalt(pwd)}}
+	s := &Server{AccountManager: accounts, PasswordHasher: Argon2idHasher{Memory: 64, Time: 1, Threads: 1}, Logger: NewTestLogger()}
+
+	// The account is changed by an admin after the user's login loaded it.
+	account := accounts.Get("alice")
+	accounts["alice"] = Account{Login: "alice", Name: "Alicia", Password: account.Password}
+
+	s.rehashPassword(account, pwd)
+	assert.Equal(t, "Alicia", accounts["alice"].Name)
+	assert.True(t, CheckPassword(accounts["alice"].Password, pwd))
+	assert.False(t, s.PasswordHasher.NeedsRehash(accounts["alice"].Password))
+}
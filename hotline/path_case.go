@@ -0,0 +1,114 @@
+package hotline
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPathCaseDirs is the number of directory listings kept for case-insensitive path resolution before the cache is
+// cleared.
+const maxPathCaseDirs = 4096
+
+// pathCaseCache caches directory listings for case-insensitive path resolution.  A listing is reused until the
+// modification time of its directory changes, which happens when entries are added, removed, or renamed.
+type pathCaseCache struct {
+	mu   sync.Mutex
+	dirs map[string]dirListing
+}
+
+type dirListing struct {
+	modTime time.Time
+	names   map[string]string // Entry names by their lower case form
+}
+
+// lookup returns the name of the entry in dir that matches name regardless of case, reading dir through fileStore.  If
+// several entries match, the first in sorted order is returned.
+func (c *pathCaseCache) lookup(fileStore FileStore, dir, name string) (string, bool) {
+	info, err := fileStore.Stat(dir)
+	if err != nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	listing, ok := c.dirs[dir]
+	if !ok || !listing.modTime.Equal(info.ModTime()) {
+		entries, err := fileStore.ReadDir(dir)
+		if err != nil {
+			return "", false
+		}
+
+		listing = dirListing{modTime: info.ModTime(), names: make(map[string]string, len(entries))}
+		for _, e := range entries {
+			key := strings.ToLower(e.Name())
+			if _, ok := listing.names[key]; !ok {
+				listing.names[key] = e.Name()
+			}
+		}
+
+		if c.dirs == nil || len(c.dirs) >= maxPathCaseDirs {
+			c.dirs = make(map[string]dirListing)
+		}
+		c.dirs[dir] = listing
+	}
+
+	actual, ok := listing.names[strings.ToLower(name)]
+	return actual, ok
+}
+
+// ResolvePathCase returns fullPath, a path within root, with each element that doesn't exist replaced by an entry of
+// the same name in a different case, if CaseInsensitivePaths is enabled.  Elements without a match are kept as
+// requested, so the result may not exist, e.g. when it is the target of an upload.
+func (s *Server) ResolvePathCase(root, fullPath string) string {
+	if !s.Config.CaseInsensitivePaths {
+		return fullPath
+	}
+
+	if _, err := s.FS.Stat(fullPath); err == nil {
+		return fullPath
+	}
+
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fullPath
+	}
+
+	resolved := root
+	elems := strings.Split(rel, string(filepath.Separator))
+	for i, elem := range elems {
+		exact := filepath.Join(resolved, elem)
+		if _, err := s.FS.Stat(exact); err == nil {
+			resolved = exact
+			continue
+		}
+
+		name, ok := s.pathCase.lookup(s.FS, resolved, elem)
+		if !ok {
+			// Nothing beneath a missing element can exist, so keep the rest of the path as requested.
+			return filepath.Join(append([]string{resolved}, elems[i:]...)...)
+		}
+		resolved = filepath.Join(resolved, name)
+	}
+
+	return resolved
+}
+
+// ReadPath returns the full path of the file or folder identified by the FieldFilePath and FieldFileName of a
+// request, within the client's file root.  Unlike the ReadPath function, paths are resolved case-insensitively when
+// CaseInsensitivePaths is enabled, and paths to files hidden from the client return ErrHiddenPath.
+func (cc *ClientConn) ReadPath(filePath, fileName []byte) (string, error) {
+	fullPath, err := ReadPath(cc.FileRoot(), filePath, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath = cc.Server.ResolvePathCase(cc.FileRoot(), fullPath)
+	if cc.HiddenFiles().MatchPath(cc.FileRoot(), fullPath) {
+		return "", ErrHiddenPath
+	}
+
+	return fullPath, nil
+}
@@ -0,0 +1,61 @@
+package hotline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_ResolvePathCase(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "Files", "Utilities"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "Files", "Utilities", "StuffIt.sit"), nil, 0644))
+
+	s := &Server{FS: &OSFileStore{}, Config: Config{CaseInsensitivePaths: true}}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "exact match",
+			path: filepath.Join(root, "Files", "Utilities", "StuffIt.sit"),
+			want: filepath.Join(root, "Files", "Utilities", "StuffIt.sit"),
+		},
+		{
+			name: "wrong case",
+			path: filepath.Join(root, "files", "UTILITIES", "stuffit.SIT"),
+			want: filepath.Join(root, "Files", "Utilities", "StuffIt.sit"),
+		},
+		{
+			name: "missing file keeps requested name",
+			path: filepath.Join(root, "FILES", "utilities", "New.sit"),
+			want: filepath.Join(root, "Files", "Utilities", "New.sit"),
+		},
+		{
+			name: "missing folder keeps rest of path",
+			path: filepath.Join(root, "files", "Games", "stuffit.sit"),
+			want: filepath.Join(root, "Files", "Games", "stuffit.sit"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.ResolvePathCase(root, tt.path))
+		})
+	}
+
+	// Changes to a directory invalidate its cached listing.
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "Files", "ReadMe"), nil, 0644))
+	assert.Equal(t, filepath.Join(root, "Files", "ReadMe"), s.ResolvePathCase(root, filepath.Join(root, "files", "readme")))
+}
+
+func TestServer_ResolvePathCase_disabled(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "ReadMe"), nil, 0644))
+
+	s := &Server{FS: &OSFileStore{}}
+	assert.Equal(t, filepath.Join(root, "readme"), s.ResolvePathCase(root, filepath.Join(root, "readme")))
+}
@@ -0,0 +1,91 @@
+package hotline
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Names of account preferences.  Preferences are saved with the account and applied each time the user logs in, so
+// they follow the user across computers and clients.
+const (
+	PrefRefusePM   = "RefusePM"   // "true" to refuse private messages
+	PrefRefuseChat = "RefuseChat" // "true" to refuse private chat
+	PrefAutoReply  = "AutoReply"  // Automatic response to private messages
+	PrefIgnore     = "Ignore"     // Comma separated names of users whose chat and private messages are hidden
+	PrefLocale     = "Locale"     // Language of server messages, matching a file in the Locales config dir
+)
+
+// maxPreferenceLen is the maximum length of a preference value.
+const maxPreferenceLen = 255
+
+// ValidatePreference returns an error if value is not valid for the preference name.  An empty value is valid for all
+// preferences and removes the preference.
+func (s *Server) ValidatePreference(name, value string) error {
+	if len(value) > maxPreferenceLen {
+		return fmt.Errorf("%s is longer than %d characters", name, maxPreferenceLen)
+	}
+
+	switch name {
+	case PrefRefusePM, PrefRefuseChat:
+		if value != "" && value != "true" && value != "false" {
+			return fmt.Errorf("%s must be true or false", name)
+		}
+	case PrefAutoReply, PrefIgnore:
+	case PrefLocale:
+		if _, ok := s.Locales[value]; value != "" && !ok {
+			return fmt.Errorf("unknown locale %q", value)
+		}
+	default:
+		return fmt.Errorf("unknown preference %q", name)
+	}
+
+	return nil
+}
+
+// SetPreference sets the preference name to value, or removes it if value is empty.
+func (a *Account) SetPreference(name, value string) {
+	// Copy the map so that changes aren't visible in other copies of the account until it is saved.
+	a.Preferences = maps.Clone(a.Preferences)
+
+	if value == "" {
+		delete(a.Preferences, name)
+		return
+	}
+
+	if a.Preferences == nil {
+		a.Preferences = make(map[string]string)
+	}
+	a.Preferences[name] = value
+}
+
+// ApplyPreferences applies the account's saved preferences to the client's options.  Preferences only turn options on,
+// so options the user has set in their client still apply.
+func (cc *ClientConn) ApplyPreferences() {
+	prefs := cc.Account.Preferences
+
+	if prefs[PrefRefusePM] == "true" {
+		cc.Flags.Set(UserFlagRefusePM, 1)
+	}
+	if prefs[PrefRefuseChat] == "true" {
+		cc.Flags.Set(UserFlagRefusePChat, 1)
+	}
+	if len(cc.AutoReply) == 0 && prefs[PrefAutoReply] != "" {
+		cc.AutoReply = []byte(ToMacRoman(prefs[PrefAutoReply]))
+	}
+}
+
+// Ignores returns true if other is in the user's ignore list.
+func (cc *ClientConn) Ignores(other *ClientConn) bool {
+	if cc.Account == nil || cc.Account.Preferences[PrefIgnore] == "" {
+		return false
+	}
+
+	for _, name := range strings.Split(cc.Account.Preferences[PrefIgnore], ",") {
+		if strings.EqualFold(ToMacRoman(strings.TrimSpace(name)), string(other.UserName)) {
+			return true
+		}
+	}
+
+	return false
+}
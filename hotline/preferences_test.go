@@ -0,0 +1,71 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_ValidatePreference(t *testing.T) {
+	s := &Server{Locales: map[string]MessageCatalog{"de": {}}}
+
+	assert.NoError(t, s.ValidatePreference(PrefRefusePM, "true"))
+	assert.NoError(t, s.ValidatePreference(PrefRefusePM, ""))
+	assert.Error(t, s.ValidatePreference(PrefRefusePM, "yes"))
+	assert.NoError(t, s.ValidatePreference(PrefLocale, "de"))
+	assert.Error(t, s.ValidatePreference(PrefLocale, "xx"))
+	assert.Error(t, s.ValidatePreference("Color", "blue"))
+	assert.Error(t, s.ValidatePreference(PrefAutoReply, string(make([]byte, maxPreferenceLen+1))))
+}
+
+func TestAccount_SetPreference(t *testing.T) {
+	orig := Account{Preferences: map[string]string{PrefRefusePM: "true"}}
+
+	acc := orig
+	acc.SetPreference(PrefAutoReply, "Back soon")
+	acc.SetPreference(PrefRefusePM, "")
+
+	assert.Equal(t, map[string]string{PrefAutoReply: "Back soon"}, acc.Preferences)
+	assert.Equal(t, map[string]string{PrefRefusePM: "true"}, orig.Preferences)
+}
+
+func TestClientConn_ApplyPreferences(t *testing.T) {
+	cc := &ClientConn{Account: &Account{Preferences: map[string]string{
+		PrefRefusePM:   "true",
+		PrefRefuseChat: "false",
+		PrefAutoReply:  "Back soon",
+	}}}
+
+	cc.ApplyPreferences()
+
+	assert.True(t, cc.Flags.IsSet(UserFlagRefusePM))
+	assert.False(t, cc.Flags.IsSet(UserFlagRefusePChat))
+	assert.Equal(t, []byte("Back soon"), cc.AutoReply)
+
+	// An auto reply set by the client takes precedence.
+	cc.AutoReply = []byte("Out to lunch")
+	cc.ApplyPreferences()
+	assert.Equal(t, []byte("Out to lunch"), cc.AutoReply)
+}
+
+func TestClientConn_Ignores(t *testing.T) {
+	cc := &ClientConn{Account: &Account{Preferences: map[string]string{PrefIgnore: "Spammer, troll"}}}
+
+	assert.True(t, cc.Ignores(&ClientConn{UserName: []byte("spammer")}))
+	assert.True(t, cc.Ignores(&ClientConn{UserName: []byte("Troll")}))
+	assert.False(t, cc.Ignores(&ClientConn{UserName: []byte("Friend")}))
+}
+
+func TestClientConn_T(t *testing.T) {
+	s := &Server{
+		Messages: MessageCatalog{"Incorrect login.": "Server locale"},
+		Locales:  map[string]MessageCatalog{"de": {"Incorrect login.": "Falsche Anmeldedaten."}},
+	}
+
+	cc := &ClientConn{Server: s, Account: &Account{}}
+	assert.Equal(t, "Server locale", cc.T("Incorrect login."))
+
+	cc.Account.Preferences = map[string]string{PrefLocale: "de"}
+	assert.Equal(t, "Falsche Anmeldedaten.", cc.T("Incorrect login."))
+	assert.Equal(t, "Other message.", cc.T("Other message."))
+}
@@ -0,0 +1,67 @@
+package hotline
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// DropPrivileges switches the process to the RunAsUser and RunAsGroup, if set.  It must be called once every port the
+// process listens on is bound and before any of them are served, so that the server can be started as root to use
+// ports below 1024 without handling requests as root.
+func (s *Server) DropPrivileges() error {
+	if s.Config.RunAsUser == "" && s.Config.RunAsGroup == "" {
+		return nil
+	}
+
+	uid, gid, err := lookupRunAs(s.Config.RunAsUser, s.Config.RunAsGroup)
+	if err != nil {
+		return fmt.Errorf("drop privileges: %w", err)
+	}
+
+	if err := setIDs(uid, gid); err != nil {
+		return fmt.Errorf("drop privileges: %w", err)
+	}
+
+	s.Logger.Info("Dropped privileges", "uid", uid, "gid", gid)
+
+	return nil
+}
+
+// lookupRunAs returns the IDs of the user and group named by runAsUser and runAsGroup, which may be names or numeric
+// IDs.  The group defaults to the primary group of the user, and the user to the current user.
+func lookupRunAs(runAsUser, runAsGroup string) (uid, gid int, err error) {
+	uid, gid = os.Getuid(), os.Getgid()
+
+	if runAsUser != "" {
+		u, err := user.Lookup(runAsUser)
+		if err != nil {
+			if u, err = user.LookupId(runAsUser); err != nil {
+				return 0, 0, fmt.Errorf("look up user %q: %w", runAsUser, err)
+			}
+		}
+
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return 0, 0, fmt.Errorf("user %q has non-numeric ID %q", runAsUser, u.Uid)
+		}
+		if gid, err = strconv.Atoi(u.Gid); err != nil {
+			return 0, 0, fmt.Errorf("user %q has non-numeric group ID %q", runAsUser, u.Gid)
+		}
+	}
+
+	if runAsGroup != "" {
+		g, err := user.LookupGroup(runAsGroup)
+		if err != nil {
+			if g, err = user.LookupGroupId(runAsGroup); err != nil {
+				return 0, 0, fmt.Errorf("look up group %q: %w", runAsGroup, err)
+			}
+		}
+
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return 0, 0, fmt.Errorf("group %q has non-numeric ID %q", runAsGroup, g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package hotline
+
+import "errors"
+
+func setIDs(uid, gid int) error {
+	return errors.New("RunAsUser and RunAsGroup are only supported on Linux, macOS, and FreeBSD")
+}
@@ -0,0 +1,51 @@
+package hotline
+
+import (
+	"os/user"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupRunAs(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+	group, err := user.LookupGroupId(current.Gid)
+	require.NoError(t, err)
+
+	wantUID, _ := strconv.Atoi(current.Uid)
+	wantGID, _ := strconv.Atoi(current.Gid)
+
+	tests := []struct {
+		name      string
+		runAsUser string
+		runAsGrp  string
+		wantErr   bool
+	}{
+		{name: "user name", runAsUser: current.Username},
+		{name: "numeric user ID", runAsUser: current.Uid},
+		{name: "user and group names", runAsUser: current.Username, runAsGrp: group.Name},
+		{name: "numeric group ID", runAsGrp: current.Gid},
+		{name: "unknown user", runAsUser: "no-such-mobius-user", wantErr: true},
+		{name: "unknown group", runAsGrp: "no-such-mobius-group", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, gid, err := lookupRunAs(tt.runAsUser, tt.runAsGrp)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, wantUID, uid)
+			assert.Equal(t, wantGID, gid)
+		})
+	}
+}
+
+func TestServer_DropPrivileges_unset(t *testing.T) {
+	s := &Server{Logger: NewTestLogger()}
+	assert.NoError(t, s.DropPrivileges())
+}
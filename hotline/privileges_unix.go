@@ -0,0 +1,33 @@
+//go:build linux || darwin || freebsd
+
+package hotline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// setIDs switches the user and group of the process to uid and gid, replacing its supplementary groups.
+func setIDs(uid, gid int) error {
+	if os.Geteuid() != 0 {
+		if uid == os.Getuid() && gid == os.Getgid() {
+			return nil
+		}
+		return errors.New("the server must be started as root to switch users")
+	}
+
+	// The group must be changed first, as a non-root user can no longer change it.
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return fmt.Errorf("set supplementary groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("set group ID: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("set user ID: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package hotline
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitAll is the key of the transaction limit that applies to all transaction types without a limit of their own.
+const rateLimitAll = "*"
+
+// loginLimiterPruneSize is the number of tracked IP addresses above which idle login rate limiters are discarded.
+const loginLimiterPruneSize = 1024
+
+// RateLimit allows Count events per Per duration, e.g. 5 chat messages per 10s.
+type RateLimit struct {
+	Count int           `yaml:"Count"` // Number of events allowed per Per
+	Per   time.Duration `yaml:"Per"`
+	Burst int           `yaml:"Burst"` // Number of events allowed at once before the limit applies; defaults to Count
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Count > 0 && l.Per > 0
+}
+
+func (l RateLimit) newLimiter() *rate.Limiter {
+	burst := l.Burst
+	if burst <= 0 {
+		burst = l.Count
+	}
+
+	return rate.NewLimiter(rate.Every(l.Per/time.Duration(l.Count)), burst)
+}
+
+// TransactionLimits are rate limits by transaction type name, as shown in the server log, e.g. "Send chat".  The limit
+// with the key "*" applies to all other transaction types together.  A limit with a Count of 0 disables limiting, e.g.
+// to exempt an account from the server's limits.
+type TransactionLimits map[string]RateLimit
+
+// RateLimits configures throttling of users who send transactions or attempt logins too often.
+//
+// Example:
+//
+//	RateLimits:
+//	  Transactions:
+//	    Send chat: {Count: 5, Per: 10s}
+//	    "*": {Count: 30, Per: 10s}
+//	  LoginAttempts: {Count: 5, Per: 1m}
+type RateLimits struct {
+	Transactions  TransactionLimits `yaml:"Transactions"`  // Limits per connection; accounts may override them with their own RateLimits
+	LoginAttempts RateLimit         `yaml:"LoginAttempts"` // Limit on login attempts per IP address
+	Disconnect    bool              `yaml:"Disconnect"`    // Disconnect users who exceed a transaction limit instead of replying with an error
+}
+
+// transactionLimit returns the rate limit that applies to transactions of type name sent by cc, and the key that
+// identifies its limiter.  Limits in the user's account take precedence over those in the server config.
+func (cc *ClientConn) transactionLimit(name string) (RateLimit, string, bool) {
+	return cc.Server.transactionLimit(cc.Account, name)
+}
+
+// transactionLimit returns the rate limit that applies to transactions of type name sent by a user with account, which
+// may be nil, and the key that identifies its limiter.
+func (s *Server) transactionLimit(account *Account, name string) (RateLimit, string, bool) {
+	var sets []TransactionLimits
+	if account != nil {
+		sets = append(sets, account.RateLimits)
+	}
+	sets = append(sets, s.Config.RateLimits.Transactions)
+
+	for _, key := range []string{name, rateLimitAll} {
+		for _, limits := range sets {
+			if limit, ok := limits[key]; ok {
+				return limit, key, limit.enabled()
+			}
+		}
+	}
+
+	return RateLimit{}, "", false
+}
+
+// allowTransaction returns true if cc may send a transaction of type t without exceeding a rate limit.
+func (cc *ClientConn) allowTransaction(t TranType) bool {
+	if t == TranKeepAlive {
+		return true
+	}
+
+	limit, key, ok := cc.transactionLimit(tranTypeNames[t])
+	if !ok {
+		return true
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.tranLimiters == nil {
+		cc.tranLimiters = make(map[string]*rate.Limiter)
+	}
+	limiter, ok := cc.tranLimiters[key]
+	if !ok {
+		limiter = limit.newLimiter()
+		cc.tranLimiters[key] = limiter
+	}
+
+	return limiter.Allow()
+}
+
+// TransactionLimiter returns a rate limiter for the equivalent of transactions of type t sent by a user with account
+// outside of a Hotline connection, such as chat from a web client, or nil if no limit applies.
+func (s *Server) TransactionLimiter(account *Account, t TranType) *rate.Limiter {
+	limit, _, ok := s.transactionLimit(account, tranTypeNames[t])
+	if !ok {
+		return nil
+	}
+
+	return limit.newLimiter()
+}
+
+// rateLimited replies to a transaction that exceeded a rate limit with an error, or disconnects the user if the server
+// is configured to.
+func (cc *ClientConn) rateLimited(t *Transaction) {
+	cc.Logger.Info("Rate limit exceeded", "type", tranTypeNames[t.Type])
+
+	// Closing the connection ends the transaction loop, which then disconnects the user.
+	if cc.Server.Config.RateLimits.Disconnect {
+		_ = cc.Connection.Close()
+		return
+	}
+
+	for _, reply := range cc.NewErrReply(t, "You are doing that too often.  Please wait a moment and try again.") {
+		cc.Server.outbox <- reply
+	}
+}
+
+// loginLimiter limits login attempts per IP address.
+type loginLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// allowLoginAttempt returns true if ip may attempt another login.
+func (s *Server) allowLoginAttempt(ip string) bool {
+	limit := s.Config.RateLimits.LoginAttempts
+	if !limit.enabled() {
+		return true
+	}
+
+	s.loginLimiter.mu.Lock()
+	defer s.loginLimiter.mu.Unlock()
+
+	if s.loginLimiter.limiters == nil {
+		s.loginLimiter.limiters = make(map[string]*rate.Limiter)
+	}
+
+	limiter, ok := s.loginLimiter.limiters[ip]
+	if !ok {
+		// Limiters that have refilled completely behave like new ones, so they can be discarded to bound memory use.
+		if len(s.loginLimiter.limiters) >= loginLimiterPruneSize {
+			for addr, l := range s.loginLimiter.limiters {
+				if l.Tokens() >= float64(l.Burst()) {
+					delete(s.loginLimiter.limiters, addr)
+				}
+			}
+		}
+
+		limiter = limit.newLimiter()
+		s.loginLimiter.limiters[ip] = limiter
+	}
+
+	return limiter.Allow()
+}
@@ -0,0 +1,107 @@
+package hotline
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func newRateLimitTestConn(limits RateLimits, account *Account) *ClientConn {
+	s := &Server{
+		Config:    Config{RateLimits: limits},
+		ClientMgr: NewMemClientMgr(),
+		outbox:    make(chan Transaction, 10),
+		handlers: map[TranType]HandlerFunc{
+			TranChatSend:        func(cc *ClientConn, t *Transaction) []Transaction { return nil },
+			TranGetFileNameList: func(cc *ClientConn, t *Transaction) []Transaction { return nil },
+			TranGetUserNameList: func(cc *ClientConn, t *Transaction) []Transaction { return nil },
+		},
+	}
+
+	return &ClientConn{ID: ClientID{0, 1}, Server: s, Account: account, Connection: &closeRecorder{}, Logger: slog.Default()}
+}
+
+func TestClientConn_handleTransaction_rateLimit(t *testing.T) {
+	cc := newRateLimitTestConn(RateLimits{
+		Transactions: TransactionLimits{
+			"Send chat": {Count: 2, Per: time.Hour},
+			"*":         {Count: 1, Per: time.Hour},
+		},
+	}, &Account{Login: "troll"})
+
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+	assert.Len(t, cc.Server.outbox, 0)
+
+	// The third chat message exceeds the limit and gets an error reply.
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+	assert.Len(t, cc.Server.outbox, 1)
+	reply := <-cc.Server.outbox
+	assert.Equal(t, [4]byte{0, 0, 0, 1}, reply.ErrorCode)
+	assert.Equal(t, "You are doing that too often.  Please wait a moment and try again.", string(reply.GetField(FieldError).Data))
+
+	// Other transaction types share the "*" limit.
+	cc.handleTransaction(NewTransaction(TranGetFileNameList, ClientID{}))
+	cc.handleTransaction(NewTransaction(TranGetUserNameList, ClientID{}))
+	assert.Len(t, cc.Server.outbox, 1)
+
+	// Keepalives are never limited.
+	assert.True(t, cc.allowTransaction(TranKeepAlive))
+}
+
+func TestClientConn_handleTransaction_rateLimitDisconnect(t *testing.T) {
+	cc := newRateLimitTestConn(RateLimits{
+		Transactions: TransactionLimits{"Send chat": {Count: 1, Per: time.Hour}},
+		Disconnect:   true,
+	}, &Account{Login: "troll"})
+
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+	assert.False(t, cc.Connection.(*closeRecorder).closed)
+
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+	assert.True(t, cc.Connection.(*closeRecorder).closed)
+	assert.Len(t, cc.Server.outbox, 0)
+}
+
+func TestClientConn_allowTransaction_accountOverride(t *testing.T) {
+	limits := RateLimits{Transactions: TransactionLimits{"Send chat": {Count: 1, Per: time.Hour}}}
+
+	// A Count of 0 in the account exempts it from the server limit.
+	bot := newRateLimitTestConn(limits, &Account{Login: "bot", RateLimits: TransactionLimits{"Send chat": {}}})
+	for range 5 {
+		assert.True(t, bot.allowTransaction(TranChatSend))
+	}
+
+	// Account limits replace the server limit rather than adding to it.
+	chatty := newRateLimitTestConn(limits, &Account{Login: "chatty", RateLimits: TransactionLimits{"Send chat": {Count: 3, Per: time.Hour}}})
+	assert.True(t, chatty.allowTransaction(TranChatSend))
+	assert.True(t, chatty.allowTransaction(TranChatSend))
+	assert.True(t, chatty.allowTransaction(TranChatSend))
+	assert.False(t, chatty.allowTransaction(TranChatSend))
+}
+
+func TestServer_allowLoginAttempt(t *testing.T) {
+	s := &Server{}
+	assert.True(t, s.allowLoginAttempt("192.0.2.1"))
+
+	s.Config.RateLimits.LoginAttempts = RateLimit{Count: 2, Per: time.Minute}
+	assert.True(t, s.allowLoginAttempt("192.0.2.1"))
+	assert.True(t, s.allowLoginAttempt("192.0.2.1"))
+	assert.False(t, s.allowLoginAttempt("192.0.2.1"))
+
+	// Each IP address has its own limit.
+	assert.True(t, s.allowLoginAttempt("192.0.2.2"))
+}
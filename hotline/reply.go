@@ -0,0 +1,118 @@
+package hotline
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// maxFieldSize is the largest field payload that fits in the 2 byte field size.
+const maxFieldSize = math.MaxUint16
+
+// ReplyBuilder builds a reply to a transaction.  Typed methods encode field values to their wire format: text is
+// converted from UTF-8 to Mac Roman, and integers are encoded big-endian at the size the field uses.  Values longer
+// than the maximum field size are truncated.  Setting a field again replaces its value in place, so fields keep the
+// order they were first set in and are never duplicated.
+//
+//	return cc.Reply(t).
+//		WithFileName(name).
+//		WithFileSize(size).
+//		Transactions()
+type ReplyBuilder struct {
+	reply Transaction
+}
+
+// Reply starts building a reply to t.
+func (cc *ClientConn) Reply(t *Transaction) *ReplyBuilder {
+	return &ReplyBuilder{reply: cc.NewReply(t)}
+}
+
+// WithBytes sets a field to data as is.
+func (b *ReplyBuilder) WithBytes(fieldType [2]byte, data []byte) *ReplyBuilder {
+	if len(data) > maxFieldSize {
+		data = data[:maxFieldSize]
+	}
+
+	field := NewField(fieldType, data)
+	for i := range b.reply.Fields {
+		if b.reply.Fields[i].Type == fieldType {
+			b.reply.Fields[i] = field
+			return b
+		}
+	}
+	b.reply.Fields = append(b.reply.Fields, field)
+
+	return b
+}
+
+// WithString sets a text field to s, converted from UTF-8 to Mac Roman.
+func (b *ReplyBuilder) WithString(fieldType [2]byte, s string) *ReplyBuilder {
+	return b.WithBytes(fieldType, []byte(ToMacRoman(s)))
+}
+
+// WithUint16 sets a 2 byte integer field.
+func (b *ReplyBuilder) WithUint16(fieldType [2]byte, n uint16) *ReplyBuilder {
+	return b.WithBytes(fieldType, binary.BigEndian.AppendUint16(nil, n))
+}
+
+// WithUint32 sets a 4 byte integer field.
+func (b *ReplyBuilder) WithUint32(fieldType [2]byte, n uint32) *ReplyBuilder {
+	return b.WithBytes(fieldType, binary.BigEndian.AppendUint32(nil, n))
+}
+
+// WithTime sets a date field in the 8 byte Hotline time format.
+func (b *ReplyBuilder) WithTime(fieldType [2]byte, t Time) *ReplyBuilder {
+	return b.WithBytes(fieldType, t[:])
+}
+
+// WithData sets FieldData to the text s.
+func (b *ReplyBuilder) WithData(s string) *ReplyBuilder {
+	return b.WithString(FieldData, s)
+}
+
+// WithUserName sets FieldUserName to a user name, which is already Mac Roman encoded.
+func (b *ReplyBuilder) WithUserName(name []byte) *ReplyBuilder {
+	return b.WithBytes(FieldUserName, name)
+}
+
+// WithUserID sets FieldUserID.
+func (b *ReplyBuilder) WithUserID(id ClientID) *ReplyBuilder {
+	return b.WithBytes(FieldUserID, id[:])
+}
+
+// WithFileName sets FieldFileName to name, converted from UTF-8 to Mac Roman.
+func (b *ReplyBuilder) WithFileName(name string) *ReplyBuilder {
+	return b.WithString(FieldFileName, name)
+}
+
+// WithFileSize sets FieldFileSize.
+func (b *ReplyBuilder) WithFileSize(size uint32) *ReplyBuilder {
+	return b.WithUint32(FieldFileSize, size)
+}
+
+// WithFileComment sets FieldFileComment, or leaves it unset if comment is empty.
+func (b *ReplyBuilder) WithFileComment(comment []byte) *ReplyBuilder {
+	if len(comment) == 0 {
+		return b
+	}
+	return b.WithBytes(FieldFileComment, comment)
+}
+
+// WithTransferSize sets FieldTransferSize.
+func (b *ReplyBuilder) WithTransferSize(size uint32) *ReplyBuilder {
+	return b.WithUint32(FieldTransferSize, size)
+}
+
+// WithRefNum sets FieldRefNum to a file transfer reference number.
+func (b *ReplyBuilder) WithRefNum(refNum [4]byte) *ReplyBuilder {
+	return b.WithBytes(FieldRefNum, refNum[:])
+}
+
+// Build returns the reply.
+func (b *ReplyBuilder) Build() Transaction {
+	return b.reply
+}
+
+// Transactions returns the reply as the only transaction in a slice, as returned by handlers.
+func (b *ReplyBuilder) Transactions() []Transaction {
+	return []Transaction{b.reply}
+}
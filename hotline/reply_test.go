@@ -0,0 +1,41 @@
+package hotline
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReplyBuilder(t *testing.T) {
+	cc := &ClientConn{ID: ClientID{0, 1}}
+	req := NewTransaction(TranGetFileInfo, ClientID{0, 1})
+
+	reply := cc.Reply(&req).
+		WithFileName("Café.txt").
+		WithFileSize(1024).
+		WithUint16(FieldFolderItemCount, 3).
+		WithFileComment(nil).
+		WithFileName("Résumé.txt").
+		Build()
+
+	assert.Equal(t, Transaction{
+		IsReply:  1,
+		ID:       req.ID,
+		ClientID: ClientID{0, 1},
+		Fields: []Field{
+			NewField(FieldFileName, []byte("R\x8esum\x8e.txt")),
+			NewField(FieldFileSize, []byte{0, 0, 0x04, 0}),
+			NewField(FieldFolderItemCount, []byte{0, 3}),
+		},
+	}, reply)
+}
+
+func TestReplyBuilder_truncatesLongFields(t *testing.T) {
+	cc := &ClientConn{}
+	req := NewTransaction(TranGetMsgs, ClientID{})
+
+	reply := cc.Reply(&req).WithBytes(FieldData, bytes.Repeat([]byte("a"), maxFieldSize+10)).Build()
+
+	assert.Len(t, reply.Fields[0].Data, maxFieldSize)
+	assert.Equal(t, [2]byte{0xff, 0xff}, reply.Fields[0].FieldSize)
+}
@@ -13,10 +13,13 @@ import (
 	"io"
 	"log"
 	"log/slog"
+	"math"
 	"net"
 	"os"
-	"strings"
+	"path/filepath"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,6 +29,7 @@ var contextKeyReq = contextKey("req")
 
 type requestCtx struct {
 	remoteAddr string
+	connID     uint64
 }
 
 // Converts bytes from Mac Roman encoding to UTF-8
@@ -39,29 +43,82 @@ type Server struct {
 	Port         int
 
 	rateLimiters map[string]*rate.Limiter
+	loginLimiter loginLimiter // Limits login attempts per IP address, see RateLimits.LoginAttempts
 
-	handlers map[TranType]HandlerFunc
+	connsMu    sync.Mutex
+	connsPerIP map[string]int // Number of open connections by IP address, for MaxConnectionsPerIP
 
-	Config Config
-	Logger *slog.Logger
+	autoBanMu        sync.Mutex
+	failedLogins     map[string][]time.Time // Times of recent failed logins by IP address, for AutoBan
+	violations       map[string][]time.Time // Times of recent protocol violations by IP address, for AutoBan
+	recentViolations []ProtocolViolation    // Most recent protocol violations, oldest first
+
+	pathCase pathCaseCache // Directory listings for CaseInsensitivePaths
+
+	nextConnID atomic.Uint64 // Source of unique IDs for accepted connections
+
+	handlers      map[TranType]HandlerFunc
+	handlerAccess map[TranType][]int // Access privileges enforced by HandleFunc for each transaction type
+	chatCommands  map[string]ChatCommandFunc
+	capabilities  []string // Optional features that clients can enable, see RegisterCapability
+	renameHooks   []AccountRenameHook
+	lowDiskHooks  []LowDiskSpaceHook
+
+	diskFreeFunc  func(path string) (uint64, error) // Measures free disk space; replaced in tests
+	diskMu        sync.Mutex
+	diskFree      uint64 // Free space on the file root disk as of the last check
+	diskFreeKnown bool
+	diskLow       bool // Free space is below DiskSpace.MinFreeMB
+
+	configMu sync.RWMutex // Guards the Config settings that can be changed while the server is running, see UpdateConfig
+	Config   Config
+	Logger   *slog.Logger
+	Version  string // Server software version reported to clients by TranGetServerInfo
+
+	startTime time.Time
 
 	TrackerPassID [4]byte
+	extraTrackers []string // Trackers added with WithTrackers
+	trackerDialer Dialer
 
-	Stats Counter
+	Stats     Counter
+	Bandwidth *BandwidthMeter // File transfer bandwidth usage for the current month
 
 	FS FileStore // Storage backend to use for File storage
 
-	outbox chan Transaction
+	outbox     chan Transaction
+	outboxOnce sync.Once // Starts processOutbox once, for both ListenAndServe and ServeConn
 
 	Agreement io.ReadSeeker
-	Banner    []byte
 
-	FileTransferMgr FileTransferMgr
-	ChatMgr         ChatManager
-	ClientMgr       ClientManager
-	AccountManager  AccountManager
-	ThreadedNewsMgr ThreadedNewsMgr
-	BanList         BanMgr
+	banner   []byte
+	bannerMu sync.RWMutex // Guards banner, which can be replaced while the server is running, see SetBanner
+
+	FileTransferMgr     FileTransferMgr
+	ChatMgr             ChatManager
+	ClientMgr           ClientManager
+	AccountManager      AccountManager
+	ThreadedNewsMgr     ThreadedNewsMgr
+	BanList             BanMgr
+	FileReportMgr       FileReportMgr
+	IncompleteUploadMgr IncompleteUploadMgr
+	BroadcastHistory    BroadcastHistory // Optional; recent admin broadcasts for users who log in later
+	Events              EventList        // Optional; upcoming community events
+	Thumbnailer         *Thumbnailer
+	FileIndex           *FileIndex
+	BlobStore           *BlobStore
+	TransferSched       *TransferScheduler
+	Digest              *ActivityDigest
+	Usage               *UsageMeter               // Optional; activity collected for weekly usage reports
+	Messages            MessageCatalog            // Replacement text for built-in server messages
+	Locales             map[string]MessageCatalog // Message catalogs by locale, for users with a Locale preference
+	PanicReporter       PanicReporter             // Optional external error tracking for recovered panics
+	SpanExporter        SpanExporter              // Optional exporter of transaction and file transfer timing spans
+	AuditSink           AuditSink                 // Optional audit log of handled transactions
+	UploadScanner       UploadScanner             // Optional scanning of uploaded files before they are saved
+	ChatFeed            *ChatFeed                 // Optional feed of public chat and users joining and leaving, for WebChat
+	NewsAttachments     *NewsAttachmentStore      // Optional; files attached to news articles
+	PasswordHasher      PasswordHasher            // Optional; hashes new passwords and upgrades older hashes on login
 
 	MessageBoard io.ReadWriteSeeker
 }
@@ -87,6 +144,14 @@ func WithPort(port int) func(s *Server) {
 	}
 }
 
+// WithTrackers registers the server with trackers in addition to those in Config.Trackers, whether or not
+// EnableTrackerRegistration is set.  Trackers use the same "host:port" or "host:port:password" form as Config.Trackers.
+func WithTrackers(trackers ...string) func(s *Server) {
+	return func(s *Server) {
+		s.extraTrackers = append(s.extraTrackers, trackers...)
+	}
+}
+
 // WithInterface optionally sets a specific interface to listen on.
 func WithInterface(netInterface string) func(s *Server) {
 	return func(s *Server) {
@@ -100,13 +165,19 @@ type ServerConfig struct {
 func NewServer(options ...Option) (*Server, error) {
 	server := Server{
 		handlers:        make(map[TranType]HandlerFunc),
+		chatCommands:    make(map[string]ChatCommandFunc),
 		outbox:          make(chan Transaction),
 		rateLimiters:    make(map[string]*rate.Limiter),
 		FS:              &OSFileStore{},
+		trackerDialer:   &RealDialer{},
+		diskFreeFunc:    diskFree,
 		ChatMgr:         NewMemChatManager(),
 		ClientMgr:       NewMemClientMgr(),
 		FileTransferMgr: NewMemFileTransferMgr(),
+		FileIndex:       NewFileIndex(),
 		Stats:           NewStats(),
+		Bandwidth:       NewBandwidthMeter(BandwidthUsage{}),
+		startTime:       time.Now(),
 	}
 
 	for _, opt := range options {
@@ -122,35 +193,137 @@ func NewServer(options ...Option) (*Server, error) {
 	return &server, nil
 }
 
+// UpdateConfig changes the config of the running server with update.  Settings changed this way must only be read
+// with CurrentConfig.
+func (s *Server) UpdateConfig(update func(config *Config)) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	update(&s.Config)
+}
+
+// SetBanner replaces the banner image sent to clients that request it.
+func (s *Server) SetBanner(banner []byte) {
+	s.bannerMu.Lock()
+	defer s.bannerMu.Unlock()
+
+	s.banner = banner
+}
+
+// Banner returns the banner image set with SetBanner.  The returned slice must not be modified.
+func (s *Server) Banner() []byte {
+	s.bannerMu.RLock()
+	defer s.bannerMu.RUnlock()
+
+	return s.banner
+}
+
+// CurrentConfig returns a copy of the server config, including changes made with UpdateConfig.
+func (s *Server) CurrentConfig() Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+
+	return s.Config
+}
+
 func (s *Server) CurrentStats() map[string]interface{} {
-	return s.Stats.Values()
+	stats := s.Stats.Values()
+
+	if free, ok := s.DiskFree(); ok {
+		stats["DiskFreeBytes"] = free
+	}
+
+	if s.Bandwidth != nil {
+		usage := s.Bandwidth.Usage()
+		stats["BandwidthMonth"] = usage.Month
+		stats["BytesIn"] = usage.Ingress
+		stats["BytesOut"] = usage.Egress
+	}
+
+	return stats
+}
+
+// TransferCapExceeded returns true if the file transfer bandwidth used this month has reached the configured
+// MonthlyTransferCapMB.
+func (s *Server) TransferCapExceeded() bool {
+	if s.Config.MonthlyTransferCapMB == 0 || s.Bandwidth == nil {
+		return false
+	}
+
+	return s.Bandwidth.Usage().Total() >= s.Config.MonthlyTransferCapMB*1024*1024
 }
 
 func (s *Server) ListenAndServe(ctx context.Context) error {
+	ln, fileTransferLn, err := s.Listen()
+	if err != nil {
+		return err
+	}
+
+	if err := s.DropPrivileges(); err != nil {
+		return err
+	}
+
+	return s.ServeListeners(ctx, ln, fileTransferLn)
+}
+
+// Listen binds the Hotline port and the file transfer port above it.
+func (s *Server) Listen() (ln, fileTransferLn net.Listener, err error) {
+	ln, err = net.Listen("tcp", fmt.Sprintf("%s:%v", s.NetInterface, s.Port))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileTransferLn, err = net.Listen("tcp", fmt.Sprintf("%s:%v", s.NetInterface, s.Port+1))
+	if err != nil {
+		_ = ln.Close()
+		return nil, nil, err
+	}
+
+	return ln, fileTransferLn, nil
+}
+
+// ServeListeners starts the server's background jobs and serves Hotline and file transfer connections from listeners
+// returned by Listen.  Callers that bind other listeners should drop privileges with DropPrivileges before calling it.
+func (s *Server) ServeListeners(ctx context.Context, ln, fileTransferLn net.Listener) error {
 	go s.registerWithTrackers(ctx)
 	go s.keepaliveHandler(ctx)
-	go s.processOutbox()
+	s.outboxOnce.Do(func() { go s.processOutbox() })
+	go s.transferExpiryScheduler(ctx)
+
+	if s.Thumbnailer != nil {
+		go s.Thumbnailer.Run(ctx)
+	}
+
+	s.cleanupScheduler(ctx)
+
+	if s.BlobStore != nil {
+		go s.blobPruneScheduler(ctx)
+	}
+
+	if s.Digest != nil {
+		go s.digestScheduler(ctx)
+	}
+
+	go s.diskSpaceMonitor(ctx)
+
+	if s.Config.QuarantineDir != "" {
+		go s.startupMetadataCheck()
+	}
+
+	if s.Events != nil && len(s.Config.EventAnnouncements.LeadTimes) > 0 {
+		go s.eventScheduler(ctx)
+	}
 
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
-		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%v", s.NetInterface, s.Port))
-		if err != nil {
-			log.Fatal(err)
-		}
-
 		log.Fatal(s.Serve(ctx, ln))
 	}()
 
 	wg.Add(1)
 	go func() {
-		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%v", s.NetInterface, s.Port+1))
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		log.Fatal(s.ServeFileTransfers(ctx, ln))
+		log.Fatal(s.ServeFileTransfers(ctx, fileTransferLn))
 	}()
 
 	wg.Wait()
@@ -165,6 +338,10 @@ func (s *Server) ServeFileTransfers(ctx context.Context, ln net.Listener) error
 			return err
 		}
 
+		if s.Bandwidth != nil {
+			conn = &meteredConn{Conn: conn, meter: s.Bandwidth}
+		}
+
 		go func() {
 			defer func() { _ = conn.Close() }()
 
@@ -174,7 +351,7 @@ func (s *Server) ServeFileTransfers(ctx context.Context, ln net.Listener) error
 			)
 
 			if err != nil {
-				s.Logger.Error("file transfer error", "err", err)
+				s.Logger.Error("file transfer error", "remoteAddr", conn.RemoteAddr().String(), "err", err)
 			}
 		}()
 	}
@@ -206,6 +383,33 @@ func (s *Server) processOutbox() {
 	}
 }
 
+// acquireConn counts a new connection from ipAddr, and returns false if the address already has MaxConnectionsPerIP
+// open connections.  Connections that are counted must be released with releaseConn when they close.
+func (s *Server) acquireConn(ipAddr string) bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	if limit := s.CurrentConfig().MaxConnectionsPerIP; limit > 0 && s.connsPerIP[ipAddr] >= limit {
+		return false
+	}
+
+	if s.connsPerIP == nil {
+		s.connsPerIP = make(map[string]int)
+	}
+	s.connsPerIP[ipAddr]++
+
+	return true
+}
+
+func (s *Server) releaseConn(ipAddr string) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	if s.connsPerIP[ipAddr]--; s.connsPerIP[ipAddr] <= 0 {
+		delete(s.connsPerIP, ipAddr)
+	}
+}
+
 // perIPRateLimit controls how frequently an IP address can connect before being throttled.
 // 0.5 = 1 connection every 2 seconds
 const perIPRateLimit = rate.Limit(0.5)
@@ -224,13 +428,15 @@ func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
 			}
 
 			go func() {
-				ipAddr := strings.Split(conn.RemoteAddr().(*net.TCPAddr).String(), ":")[0]
+				ipAddr := conn.RemoteAddr().(*net.TCPAddr).IP.String()
+				connID := s.nextConnID.Add(1)
+				logger := s.Logger.With("connID", connID)
 
 				connCtx := context.WithValue(ctx, contextKeyReq, requestCtx{
 					remoteAddr: conn.RemoteAddr().String(),
+					connID:     connID,
 				})
 
-				s.Logger.Info("Connection established", "ip", ipAddr)
 				defer conn.Close()
 
 				// Check if we have an existing rate limit for the IP and create one if we do not.
@@ -242,16 +448,24 @@ func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
 
 				// Check if the rate limit is exceeded and close the connection if so.
 				if !rl.Allow() {
-					s.Logger.Info("Rate limit exceeded", "RemoteAddr", conn.RemoteAddr())
+					logger.Info("Rate limit exceeded", "RemoteAddr", conn.RemoteAddr())
 					conn.Close()
 					return
 				}
 
+				if !s.acquireConn(ipAddr) {
+					logger.Info("Too many connections from address", "RemoteAddr", conn.RemoteAddr())
+					return
+				}
+				defer s.releaseConn(ipAddr)
+
 				if err := s.handleNewConnection(connCtx, conn, conn.RemoteAddr().String()); err != nil {
-					if err == io.EOF {
-						s.Logger.Info("Client disconnected", "RemoteAddr", conn.RemoteAddr())
+					if errors.Is(err, errNotHotline) {
+						logger.Debug("Closed non-Hotline connection", "RemoteAddr", conn.RemoteAddr(), "err", err)
+					} else if err == io.EOF {
+						logger.Info("Client disconnected", "RemoteAddr", conn.RemoteAddr())
 					} else {
-						s.Logger.Error("Error serving request", "RemoteAddr", conn.RemoteAddr(), "err", err)
+						logger.Error("Error serving request", "RemoteAddr", conn.RemoteAddr(), "err", err)
 					}
 				}
 			}()
@@ -259,54 +473,169 @@ func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
 	}
 }
 
-// time in seconds between tracker re-registration
-const trackerUpdateFrequency = 300
+// ServeConn serves a single client connection until it disconnects.  Unlike Serve, it does not apply the per-IP
+// connection rate limit, so it can be used to serve connections that don't come from a listener, such as one end of a
+// net.Pipe in tests.
+func (s *Server) ServeConn(ctx context.Context, conn io.ReadWriteCloser, remoteAddr string) error {
+	s.outboxOnce.Do(func() { go s.processOutbox() })
 
-// registerWithTrackers runs every trackerUpdateFrequency seconds to update the server's tracker entry on all configured
-// trackers.
-func (s *Server) registerWithTrackers(ctx context.Context) {
+	defer func() { _ = conn.Close() }()
+
+	connCtx := context.WithValue(ctx, contextKeyReq, requestCtx{
+		remoteAddr: remoteAddr,
+		connID:     s.nextConnID.Add(1),
+	})
+
+	return s.handleNewConnection(connCtx, conn, remoteAddr)
+}
+
+// trackerRegistration returns the server's tracker registration, leaving out the details hidden by the Privacy config.
+func (s *Server) trackerRegistration() *TrackerRegistration {
+	config := s.CurrentConfig()
+	tr := &TrackerRegistration{
+		UserCount:   len(s.ClientMgr.List()),
+		PassID:      s.TrackerPassID,
+		Name:        config.Name,
+		Description: config.Description,
+	}
+	binary.BigEndian.PutUint16(tr.Port[:], uint16(s.Port))
+
+	if config.Privacy.HideUserCount {
+		tr.UserCount = 0
+	}
+	if config.Privacy.HideDescription {
+		tr.Description = ""
+	}
+
+	// The tracker protocol has no field for the server version, so it is sent at the end of the description, which is
+	// shortened if needed to stay within the 255 bytes the protocol allows.
+	if s.Version != "" && config.TrackerVersion {
+		if tr.Description == "" {
+			tr.Description = "Mobius " + s.Version
+		} else {
+			suffix := " (Mobius " + s.Version + ")"
+			tr.Description = tr.Description[:max(0, min(len(tr.Description), math.MaxUint8-len(suffix)))] + suffix
+		}
+	}
+
+	return tr
+}
+
+const (
+	trackerUpdateFrequency = 300 * time.Second // time between tracker re-registrations
+	trackerRetryDelay      = 15 * time.Second  // time before retrying a failed registration, doubled on each failure
+	trackerCheckInterval   = 5 * time.Second   // time between checks for trackers that are due for registration
+)
+
+// trackerSchedule is the registration schedule of a tracker.
+type trackerSchedule struct {
+	next     time.Time // Time of the next registration
+	failures int       // Number of consecutive failed registrations
+}
+
+// trackerBackoff returns the time to wait before retrying a tracker after the given number of consecutive failures.
+// The delay doubles with each failure, up to trackerUpdateFrequency.
+func trackerBackoff(failures int) time.Duration {
+	delay := trackerRetryDelay
+	for i := 1; i < failures && delay < trackerUpdateFrequency; i++ {
+		delay *= 2
+	}
+
+	return min(delay, trackerUpdateFrequency)
+}
+
+// trackers returns the trackers the server registers with: those in Config.Trackers if EnableTrackerRegistration is
+// set, and those added with WithTrackers.
+func (s *Server) trackers() []string {
+	if s.Config.Privacy.Unlisted {
+		return nil
+	}
+
+	var trackers []string
 	if s.Config.EnableTrackerRegistration {
-		s.Logger.Info("Tracker registration enabled", "trackers", s.Config.Trackers)
+		trackers = append(trackers, s.Config.Trackers...)
+	}
+
+	return append(trackers, s.extraTrackers...)
+}
+
+// registerWithTrackers updates the server's tracker entry on all trackers every trackerUpdateFrequency until ctx is
+// cancelled.  Failed registrations are retried with backoff rather than waiting for the next update.
+func (s *Server) registerWithTrackers(ctx context.Context) {
+	if s.Config.EnableTrackerRegistration || len(s.extraTrackers) > 0 {
+		if s.Config.Privacy.Unlisted {
+			s.Logger.Info("Tracker registration disabled because the server is unlisted")
+		} else {
+			s.Logger.Info("Tracker registration enabled", "trackers", s.trackers())
+		}
 	}
 
+	schedules := make(map[string]*trackerSchedule)
+
+	ticker := time.NewTicker(trackerCheckInterval)
+	defer ticker.Stop()
+
 	for {
-		if s.Config.EnableTrackerRegistration {
-			for _, t := range s.Config.Trackers {
-				tr := &TrackerRegistration{
-					UserCount:   len(s.ClientMgr.List()),
-					PassID:      s.TrackerPassID,
-					Name:        s.Config.Name,
-					Description: s.Config.Description,
-				}
-				binary.BigEndian.PutUint16(tr.Port[:], uint16(s.Port))
+		s.updateTrackers(time.Now(), schedules)
 
-				// Check the tracker string for a password.  This is janky but avoids a breaking change to the Config
-				// Trackers field.
-				splitAddr := strings.Split(":", t)
-				if len(splitAddr) == 3 {
-					tr.Password = splitAddr[2]
-				}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
-				if err := register(&RealDialer{}, t, tr); err != nil {
-					s.Logger.Error(fmt.Sprintf("Unable to register with tracker %v", t), "error", err)
-				}
-			}
+// updateTrackers registers with each tracker that is due at now, and schedules its next registration.
+func (s *Server) updateTrackers(now time.Time, schedules map[string]*trackerSchedule) {
+	for _, tracker := range s.trackers() {
+		sched, ok := schedules[tracker]
+		if !ok {
+			sched = &trackerSchedule{}
+			schedules[tracker] = sched
+		}
+		if now.Before(sched.next) {
+			continue
 		}
-		// Using time.Ticker with for/select would be more idiomatic, but it's super annoying that it doesn't tick on
-		// first pass.  Revist, maybe.
-		// https://github.com/golang/go/issues/17601
-		time.Sleep(trackerUpdateFrequency * time.Second)
+
+		addr, password := splitTrackerAddr(tracker)
+
+		tr := s.trackerRegistration()
+		tr.Password = password
+
+		if err := register(s.trackerDialer, addr, tr); err != nil {
+			sched.failures++
+			retryIn := trackerBackoff(sched.failures)
+			sched.next = now.Add(retryIn)
+
+			s.Logger.Error("Unable to register with tracker", "tracker", addr, "err", err, "retryIn", retryIn)
+			continue
+		}
+
+		sched.failures = 0
+		sched.next = now.Add(trackerUpdateFrequency)
+
+		s.Logger.Debug("Registered with tracker", "tracker", addr)
 	}
 }
 
 const (
-	userIdleSeconds   = 300 // time in seconds before an inactive user is marked idle
-	idleCheckInterval = 10  // time in seconds to check for idle users
+	defaultIdleTimeout = 5 * time.Minute // time before an inactive user is marked idle
+	idleCheckInterval  = 10              // time in seconds to check for idle users
 )
 
+// idleTimeout returns how long a user must be inactive to be marked idle, or 0 if users are never marked idle.
+func (s *Server) idleTimeout() time.Duration {
+	switch {
+	case s.Config.IdleTimeout < 0:
+		return 0
+	case s.Config.IdleTimeout == 0:
+		return defaultIdleTimeout
+	}
+	return s.Config.IdleTimeout
+}
+
 // keepaliveHandler runs every idleCheckInterval seconds and increments a user's idle time by idleCheckInterval seconds.
-// If the updated idle time exceeds userIdleSeconds and the user was not previously idle, we notify all connected clients
-// that the user has gone idle.  For most clients, this turns the user grey in the user list.
 func (s *Server) keepaliveHandler(ctx context.Context) {
 	ticker := time.NewTicker(idleCheckInterval * time.Second)
 	defer ticker.Stop()
@@ -316,34 +645,60 @@ func (s *Server) keepaliveHandler(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			for _, c := range s.ClientMgr.List() {
-				c.mu.Lock()
-				c.IdleTime += idleCheckInterval
-
-				// Check if the user
-				if c.IdleTime > userIdleSeconds && !c.Flags.IsSet(UserFlagAway) {
-					c.Flags.Set(UserFlagAway, 1)
-
-					c.SendAll(
-						TranNotifyChangeUser,
-						NewField(FieldUserID, c.ID[:]),
-						NewField(FieldUserFlags, c.Flags[:]),
-						NewField(FieldUserName, c.UserName),
-						NewField(FieldUserIconID, c.Icon),
-					)
-				}
-				c.mu.Unlock()
-			}
+			s.checkIdle(idleCheckInterval)
+
+			s.enforceLoginHours(time.Now())
 		}
 	}
 }
 
+// checkIdle adds elapsed seconds to each user's idle time.  Users whose idle time exceeds the idle timeout are marked
+// idle, and all connected clients are notified.  For most clients, this turns the user grey in the user list.
+func (s *Server) checkIdle(elapsed int) {
+	timeout := s.idleTimeout()
+
+	for _, c := range s.ClientMgr.List() {
+		c.mu.Lock()
+		c.IdleTime += elapsed
+		idle := timeout > 0 && time.Duration(c.IdleTime)*time.Second > timeout
+		c.mu.Unlock()
+
+		if idle {
+			c.setIdle(true)
+		}
+	}
+}
+
+// enforceLoginHours disconnects clients whose account login window has closed.  Each client is warned once, then
+// disconnected by a single timer.
+func (s *Server) enforceLoginHours(now time.Time) {
+	for _, c := range s.ClientMgr.List() {
+		c.mu.Lock()
+		if c.Account == nil || c.Account.LoginHours.Contains(now) || c.loginHoursTimer != nil {
+			c.mu.Unlock()
+			continue
+		}
+		loginHours := c.Account.LoginHours
+		c.loginHoursTimer = time.AfterFunc(1*time.Second, c.Disconnect)
+		c.mu.Unlock()
+
+		c.Logger.Info("Disconnecting client outside of allowed login hours", "loginHours", loginHours.String())
+
+		s.outbox <- NewTransaction(
+			TranDisconnectMsg,
+			c.ID,
+			NewField(FieldData, []byte(fmt.Sprintf(s.T("This account may only be used between %s."), loginHours))),
+		)
+	}
+}
+
 func (s *Server) NewClientConn(conn io.ReadWriteCloser, remoteAddr string) *ClientConn {
 	clientConn := &ClientConn{
 		Icon:       []byte{0, 0}, // TODO: make array type
 		Connection: conn,
 		Server:     s,
 		RemoteAddr: remoteAddr,
+		LoginTime:  time.Now(),
 
 		ClientFileTransferMgr: NewClientFileTransferMgr(),
 	}
@@ -366,33 +721,33 @@ func sendBanMessage(rwc io.Writer, message string) {
 
 // handleNewConnection takes a new net.Conn and performs the initial login sequence
 func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser, remoteAddr string) error {
-	defer dontPanic(s.Logger)
+	defer s.dontPanic()
 
-	if err := performHandshake(rwc); err != nil {
-		return fmt.Errorf("perform handshake: %w", err)
-	}
+	reqCtx, _ := ctx.Value(contextKeyReq).(requestCtx)
 
-	// Check if remoteAddr is present in the ban list
-	ipAddr := strings.Split(remoteAddr, ":")[0]
-	if isBanned, banUntil := s.BanList.IsBanned(ipAddr); isBanned {
-		// permaban
-		if banUntil == nil {
-			sendBanMessage(rwc, "You are permanently banned on this server")
-			s.Logger.Debug("Disconnecting permanently banned IP", "remoteAddr", ipAddr)
-			return nil
+	if err := s.acceptHandshake(rwc); err != nil {
+		// Connections that close without sending anything, such as TCP health checks, aren't counted as violations.
+		if errors.Is(err, errInvalidHandshake) {
+			s.Logger.Info("Protocol violation", "ip", AddrIP(remoteAddr), "kind", ViolationHandshake)
+			s.recordViolation(ProtocolViolation{Time: time.Now(), IP: AddrIP(remoteAddr), Kind: ViolationHandshake})
 		}
+		return err
+	}
 
-		// temporary ban
-		if time.Now().Before(*banUntil) {
-			sendBanMessage(rwc, "You are temporarily banned on this server")
-			s.Logger.Debug("Disconnecting temporarily banned IP", "remoteAddr", ipAddr)
-			return nil
-		}
+	ipAddr := AddrIP(remoteAddr)
+	s.Logger.Info("Connection established", "connID", reqCtx.connID, "ip", ipAddr, "serverVersion", s.Version)
+
+	// Check if remoteAddr is present in the ban list
+	if isBanned, ban := s.BanList.IsBanned(ipAddr); isBanned {
+		sendBanMessage(rwc, s.banMessage(ban))
+		s.Logger.Debug("Disconnecting banned IP", "connID", reqCtx.connID, "remoteAddr", ipAddr, "until", ban.Until, "reason", ban.Reason)
+		return nil
 	}
 
 	// Create a new scanner for parsing incoming bytes into transaction tokens
 	scanner := bufio.NewScanner(rwc)
 	scanner.Split(transactionScanner)
+	scanner.Buffer(nil, maxTransactionSize)
 
 	scanner.Scan()
 
@@ -407,6 +762,7 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 	}
 
 	c := s.NewClientConn(rwc, remoteAddr)
+	c.ConnID = reqCtx.connID
 	defer c.Disconnect()
 
 	encodedPassword := clientLogin.GetField(FieldUserPassword).Data
@@ -417,7 +773,42 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 		login = GuestAccount
 	}
 
-	c.Logger = s.Logger.With("ip", ipAddr, "login", login)
+	c.Logger = s.Logger.With("connID", c.ConnID, "ip", ipAddr, "login", login)
+
+	if !s.allowLoginAttempt(ipAddr) {
+		t := c.NewErrReply(&clientLogin, "Too many login attempts.  Please wait a moment and try again.")[0]
+		_, err := io.Copy(rwc, &t)
+		if err != nil {
+			return err
+		}
+
+		c.Logger.Info("Login rejected: rate limit exceeded")
+		c.auditLogin(login, "Too many login attempts.")
+
+		return nil
+	}
+
+	encodedPassword, ok := s.checkServerPassword(encodedPassword)
+	if !ok {
+		t := c.NewErrReply(&clientLogin, "Incorrect server password.")[0]
+		_, err := io.Copy(rwc, &t)
+
+		c.Logger.Info("Incorrect server password")
+		c.auditLogin(login, "Incorrect server password.")
+		s.recordFailedLogin(ipAddr, time.Now())
+
+		return err
+	}
+
+	if login == GuestAccount && !s.guestsAllowed() {
+		t := c.NewErrReply(&clientLogin, "This server does not allow guest logins.")[0]
+		_, err := io.Copy(rwc, &t)
+
+		c.Logger.Info("Login rejected: guest logins not allowed")
+		c.auditLogin(login, "This server does not allow guest logins.")
+
+		return err
+	}
 
 	// If authentication fails, send error reply and close connection
 	if !c.Authenticate(login, encodedPassword) {
@@ -429,6 +820,8 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 		}
 
 		c.Logger.Info("Incorrect login")
+		c.auditLogin(login, "Incorrect login.")
+		s.recordFailedLogin(ipAddr, time.Now())
 
 		return nil
 	}
@@ -437,14 +830,66 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 		c.Icon = clientLogin.GetField(FieldUserIconID).Data
 	}
 
-	c.Account = c.Server.AccountManager.Get(login)
+	c.Account = s.loginAccount(login)
 	if c.Account == nil {
+		c.auditLogin(login, "Incorrect login.")
 		return nil
 	}
 
+	if !c.Account.AddressAllowed(ipAddr) {
+		t := c.NewErrReply(&clientLogin, "Incorrect login.")[0]
+		_, err := io.Copy(rwc, &t)
+
+		c.Logger.Warn("Login rejected: address not allowed for account", "allowedAddresses", c.Account.AllowedAddresses)
+		c.auditLogin(login, "Address not allowed.")
+
+		return err
+	}
+
+	if !c.Account.LoginHours.Contains(time.Now()) {
+		t := c.NewErrReplyf(&clientLogin, "This account may only be used between %s.", c.Account.LoginHours)[0]
+		_, err := io.Copy(rwc, &t)
+
+		c.Logger.Info("Login outside of allowed hours", "loginHours", c.Account.LoginHours.String())
+		c.auditLogin(login, "Outside of allowed login hours.")
+
+		return err
+	}
+
+	if excess := s.excessSessions(c); len(excess) > 0 {
+		if !c.Account.BumpOldestSession {
+			msg := "This account has too many simultaneous connections."
+			if login == GuestAccount {
+				msg = "Too many guests are connected.  Please try again later."
+			}
+			t := c.NewErrReply(&clientLogin, msg)[0]
+			_, err := io.Copy(rwc, &t)
+
+			c.Logger.Info("Login rejected: session limit reached", "maxSessions", c.Account.MaxSessions)
+			c.auditLogin(login, msg)
+
+			return err
+		}
+
+		for _, old := range excess {
+			c.Logger.Info("Disconnecting oldest session of account", "maxSessions", c.Account.MaxSessions, "oldID", old.ID)
+
+			s.outbox <- NewTransaction(
+				TranDisconnectMsg,
+				old.ID,
+				NewField(FieldData, []byte(s.T("You have been disconnected because this account logged in from another location."))),
+			)
+
+			go func() {
+				time.Sleep(1 * time.Second)
+				old.Disconnect()
+			}()
+		}
+	}
+
 	if clientLogin.GetField(FieldUserName).Data != nil {
 		if c.Authorize(AccessAnyName) {
-			c.UserName = clientLogin.GetField(FieldUserName).Data
+			c.UserName = s.LimitUserName(clientLogin.GetField(FieldUserName).Data)
 		} else {
 			c.UserName = []byte(c.Account.Name)
 		}
@@ -454,10 +899,15 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 		c.Flags.Set(UserFlagAdmin, 1)
 	}
 
+	c.ApplyPreferences()
+	c.prevLogin = s.recordLogin(c, time.Now())
+	c.auditLogin(c.Account.Login, "")
+	s.clearFailedLogins(ipAddr)
+
 	s.outbox <- c.NewReply(&clientLogin,
 		NewField(FieldVersion, []byte{0x00, 0xbe}),
 		NewField(FieldCommunityBannerID, []byte{0, 0}),
-		NewField(FieldServerName, []byte(s.Config.Name)),
+		NewField(FieldServerName, []byte(s.CurrentConfig().Name)),
 	)
 
 	// Send user access privs so client UI knows how to behave
@@ -488,15 +938,12 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 
 		// Notify other clients on the server that the new user has logged in.  For 1.5+ clients we don't have this
 		// information yet, so we do it in TranAgreed instead
-		for _, t := range c.NotifyOthers(
-			NewTransaction(
-				TranNotifyChangeUser, [2]byte{0, 0},
-				NewField(FieldUserName, c.UserName),
-				NewField(FieldUserID, c.ID[:]),
-				NewField(FieldUserIconID, c.Icon),
-				NewField(FieldUserFlags, c.Flags[:]),
-			),
-		) {
+		for _, t := range c.NotifyOthersChangeUser() {
+			c.Server.outbox <- t
+		}
+		s.ChatFeed.Join(c.UserName)
+
+		for _, t := range slices.Concat(c.MOTD(), c.MissedBroadcasts(), c.ReplayChatHistory(), c.RedeliverChatInvites()) {
 			c.Server.outbox <- t
 		}
 	}
@@ -507,6 +954,7 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 	if len(s.ClientMgr.List()) > c.Server.Stats.Get(StatConnectionPeak) {
 		c.Server.Stats.Set(StatConnectionPeak, len(s.ClientMgr.List()))
 	}
+	s.Usage.RecordLogin(c.Account.Login, len(s.ClientMgr.List()))
 
 	// Scan for new transactions and handle them as they come in.
 	for scanner.Scan() {
@@ -525,8 +973,8 @@ func (s *Server) handleNewConnection(ctx context.Context, rwc io.ReadWriteCloser
 }
 
 // handleFileTransfer receives a client net.Conn from the file transfer server, performs the requested transfer type, then closes the connection
-func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) error {
-	defer dontPanic(s.Logger)
+func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) (err error) {
+	defer s.dontPanic()
 
 	// The first 16 bytes contain the file transfer.
 	var t transfer
@@ -534,7 +982,7 @@ func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) erro
 		return fmt.Errorf("error reading file transfer: %w", err)
 	}
 
-	fileTransfer := s.FileTransferMgr.Get(t.ReferenceNumber)
+	fileTransfer := s.FileTransferMgr.Claim(t.ReferenceNumber)
 	if fileTransfer == nil {
 		return errors.New("invalid transaction ID")
 	}
@@ -548,7 +996,49 @@ func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) erro
 		time.Sleep(3 * time.Second)
 	}()
 
+	if t.RSVD == transferEncryptionV2 {
+		rwc, err = acceptTransferEncryption(rwc, t.ReferenceNumber, s.Config.EnableTransferEncryption)
+		if err != nil {
+			return fmt.Errorf("negotiate transfer encryption: %w", err)
+		}
+	}
+
+	// Downloads beyond the simultaneous download limits wait here until a slot is free.
+	if fileTransfer.Type == FileDownload || fileTransfer.Type == FolderDownload {
+		done, err := s.startDownload(ctx, fileTransfer)
+		if err != nil {
+			return fmt.Errorf("wait for download slot: %w", err)
+		}
+		defer done()
+	}
+
+	if s.TransferSched != nil {
+		var done func()
+		rwc, done = s.TransferSched.Wrap(ctx, rwc, fileTransfer.ClientConn.TransferPriority())
+		defer done()
+	}
+
+	if download, upload := s.transferRateLimits(fileTransfer.ClientConn); download > 0 || upload > 0 {
+		rwc = limitTransferRate(ctx, rwc, download, upload)
+	}
+
+	if span := s.startSpan("hotline.file_transfer", map[string]any{
+		"hotline.transfer.type": fileTransferTypeNames[fileTransfer.Type],
+		"hotline.client.id":     binary.BigEndian.Uint16(fileTransfer.ClientConn.ID[:]),
+		"hotline.conn.id":       fileTransfer.ClientConn.ConnID,
+	}); span != nil {
+		counter := &countingRW{rw: rwc}
+		rwc = counter
+
+		defer func() {
+			span.SetAttr("hotline.transfer.bytes_sent", counter.written.Load())
+			span.SetAttr("hotline.transfer.bytes_received", counter.read.Load())
+			span.End(err)
+		}()
+	}
+
 	rLogger := s.Logger.With(
+		"connID", fileTransfer.ClientConn.ConnID,
 		"remoteAddr", ctx.Value(contextKeyReq).(requestCtx).remoteAddr,
 		"login", fileTransfer.ClientConn.Account.Login,
 		"Name", string(fileTransfer.ClientConn.UserName),
@@ -558,10 +1048,11 @@ func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) erro
 	if err != nil {
 		return err
 	}
+	fullPath = s.ResolvePathCase(fileTransfer.FileRoot, fullPath)
 
 	switch fileTransfer.Type {
 	case BannerDownload:
-		if _, err := io.Copy(rwc, bytes.NewBuffer(s.Banner)); err != nil {
+		if _, err := io.Copy(rwc, bytes.NewBuffer(s.Banner())); err != nil {
 			return fmt.Errorf("banner download: %w", err)
 		}
 	case FileDownload:
@@ -574,34 +1065,60 @@ func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) erro
 		if err != nil {
 			return fmt.Errorf("file download: %w", err)
 		}
+		s.recordDownload(fileTransfer, fullPath)
 
 	case FileUpload:
 		s.Stats.Increment(StatUploadCounter, StatUploadsInProgress)
 		defer func() {
 			s.Stats.Decrement(StatUploadsInProgress)
 		}()
+		defer func() { s.notifyUploadResult(fileTransfer, err) }()
+
+		s.trackIncompleteUpload(fileTransfer, fullPath)
+
+		if s.BlobStore != nil {
+			if err := s.BlobStore.Unshare(fullPath + IncompleteFileSuffix); err != nil {
+				return fmt.Errorf("file upload: %w", err)
+			}
+		}
 
 		err = UploadHandler(rwc, fullPath, fileTransfer, s.FS, rLogger, s.Config.PreserveResourceForks)
+		s.FileIndex.Invalidate(fullPath)
 		if err != nil {
 			return fmt.Errorf("file upload: %w", err)
 		}
 
+		s.untrackIncompleteUpload(fullPath)
+		s.Usage.RecordUpload()
+
+		if s.BlobStore != nil {
+			if err := s.BlobStore.Store(fullPath); err != nil {
+				rLogger.Error("Error deduplicating uploaded file", "err", err)
+			}
+		}
+
+		if s.Thumbnailer != nil {
+			s.Thumbnailer.Enqueue(fullPath)
+		}
+
 	case FolderDownload:
 		s.Stats.Increment(StatDownloadCounter, StatDownloadsInProgress)
 		defer func() {
 			s.Stats.Decrement(StatDownloadsInProgress)
 		}()
 
-		err = DownloadFolderHandler(rwc, fullPath, fileTransfer, s.FS, rLogger, s.Config.PreserveResourceForks)
+		err = DownloadFolderHandler(rwc, fullPath, fileTransfer, s.FS, rLogger, s.Config.PreserveResourceForks, fileTransfer.ClientConn.HiddenFiles())
 		if err != nil {
 			return fmt.Errorf("folder download: %w", err)
 		}
+		s.recordDownload(fileTransfer, fullPath)
 
 	case FolderUpload:
 		s.Stats.Increment(StatUploadCounter, StatUploadsInProgress)
 		defer func() {
 			s.Stats.Decrement(StatUploadsInProgress)
 		}()
+		defer func() { s.notifyUploadResult(fileTransfer, err) }()
 
 		rLogger.Info(
 			"Folder upload started",
@@ -610,14 +1127,41 @@ func (s *Server) handleFileTransfer(ctx context.Context, rwc io.ReadWriter) erro
 			"FolderItemCount", fileTransfer.FolderItemCount,
 		)
 
+		if s.BlobStore != nil {
+			if err := s.BlobStore.UnshareIncomplete(fullPath); err != nil {
+				return fmt.Errorf("folder upload: %w", err)
+			}
+		}
+
 		err = UploadFolderHandler(rwc, fullPath, fileTransfer, s.FS, rLogger, s.Config.PreserveResourceForks)
+		s.FileIndex.Invalidate(fullPath)
 		if err != nil {
 			return fmt.Errorf("folder upload: %w", err)
 		}
+		s.Usage.RecordUpload()
+
+		if s.BlobStore != nil {
+			if err := s.BlobStore.StoreAll(fullPath); err != nil {
+				rLogger.Error("Error deduplicating uploaded folder", "err", err)
+			}
+		}
 	}
 	return nil
 }
 
+// recordDownload records a completed download for usage reports, by its path relative to the user's file root.
+func (s *Server) recordDownload(fileTransfer *FileTransfer, fullPath string) {
+	if s.Usage == nil {
+		return
+	}
+
+	relPath, err := filepath.Rel(fileTransfer.FileRoot, fullPath)
+	if err != nil {
+		relPath = fullPath
+	}
+	s.Usage.RecordDownload(filepath.ToSlash(relPath))
+}
+
 func (s *Server) SendAll(t TranType, fields ...Field) {
 	for _, c := range s.ClientMgr.List() {
 		s.outbox <- NewTransaction(t, c.ID, fields...)
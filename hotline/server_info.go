@@ -0,0 +1,27 @@
+package hotline
+
+import "time"
+
+// ServerInfo is a summary of the server's version, uptime, and usage, for display in a client's server stats window.
+type ServerInfo struct {
+	Version             string `json:"version"`
+	UptimeSeconds       int64  `json:"uptimeSeconds"`
+	Users               int    `json:"users"`
+	Downloads           int    `json:"downloads"`
+	Uploads             int    `json:"uploads"`
+	DownloadsInProgress int    `json:"downloadsInProgress"`
+	UploadsInProgress   int    `json:"uploadsInProgress"`
+}
+
+// Info returns the current ServerInfo.
+func (s *Server) Info() ServerInfo {
+	return ServerInfo{
+		Version:             s.Version,
+		UptimeSeconds:       int64(time.Since(s.startTime).Seconds()),
+		Users:               len(s.ClientMgr.List()),
+		Downloads:           s.Stats.Get(StatDownloadCounter),
+		Uploads:             s.Stats.Get(StatUploadCounter),
+		DownloadsInProgress: s.Stats.Get(StatDownloadsInProgress),
+		UploadsInProgress:   s.Stats.Get(StatUploadsInProgress),
+	}
+}
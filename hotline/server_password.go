@@ -0,0 +1,35 @@
+package hotline
+
+import (
+	"crypto/subtle"
+)
+
+// serverPasswordSeparator separates the account password from the server password in the login password field.
+const serverPasswordSeparator = '/'
+
+// checkServerPassword verifies the server password, if one is configured, and returns the remaining account password.
+//
+// The Hotline handshake has no field for a connect password, so clients supply it in the login password field: on
+// its own for accounts without a password, such as guest, or after the account password and a "/", e.g.
+// "hunter2/serverpass".  Since the server password is known, it's matched as a suffix rather than by splitting at a
+// "/", so either password may contain "/".  Both the login password and the returned account password are obfuscated.
+func (s *Server) checkServerPassword(encodedPassword []byte) ([]byte, bool) {
+	if s.Config.ServerPassword == "" {
+		return encodedPassword, true
+	}
+
+	want := EncodeString([]byte(s.Config.ServerPassword))
+
+	if subtle.ConstantTimeCompare(encodedPassword, want) == 1 {
+		return nil, true
+	}
+
+	suffix := append(EncodeString([]byte{serverPasswordSeparator}), want...)
+	if i := len(encodedPassword) - len(suffix); i >= 0 {
+		if subtle.ConstantTimeCompare(encodedPassword[i:], suffix) == 1 {
+			return encodedPassword[:i], true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,74 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestServer_checkServerPassword(t *testing.T) {
+	tests := []struct {
+		name           string
+		serverPassword string
+		password       string
+		wantPassword   []byte
+		wantOK         bool
+	}{
+		{
+			name:         "when no server password is configured",
+			password:     "hunter2",
+			wantPassword: EncodeString([]byte("hunter2")),
+			wantOK:       true,
+		},
+		{
+			name:           "when the password is the server password",
+			serverPassword: "letmein",
+			password:       "letmein",
+			wantPassword:   nil,
+			wantOK:         true,
+		},
+		{
+			name:           "when the server password follows the account password",
+			serverPassword: "letmein",
+			password:       "hunter2/letmein",
+			wantPassword:   EncodeString([]byte("hunter2")),
+			wantOK:         true,
+		},
+		{
+			name:           "when the account password contains the separator",
+			serverPassword: "letmein",
+			password:       "a/b/letmein",
+			wantPassword:   EncodeString([]byte("a/b")),
+			wantOK:         true,
+		},
+		{
+			name:           "when the server password contains the separator",
+			serverPassword: "let/me/in",
+			password:       "hunter2/let/me/in",
+			wantPassword:   EncodeString([]byte("hunter2")),
+			wantOK:         true,
+		},
+		{
+			name:           "when the server password is wrong",
+			serverPassword: "letmein",
+			password:       "hunter2/wrong",
+			wantOK:         false,
+		},
+		{
+			name:           "when the server password is missing",
+			serverPassword: "letmein",
+			password:       "hunter2",
+			wantOK:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Config: Config{ServerPassword: tt.serverPassword}}
+
+			gotPassword, gotOK := s.checkServerPassword(EncodeString([]byte(tt.password)))
+			assert.Equal(t, tt.wantOK, gotOK)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantPassword, gotPassword)
+			}
+		})
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io"
 	"log/slog"
+	"net"
 	"os"
 	"testing"
+	"time"
 )
 
 type mockReadWriter struct {
@@ -181,3 +183,131 @@ func TestServer_handleFileTransfer(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_acquireConn(t *testing.T) {
+	s := &Server{Config: Config{MaxConnectionsPerIP: 2}}
+
+	assert.True(t, s.acquireConn("192.0.2.1"))
+	assert.True(t, s.acquireConn("192.0.2.1"))
+	assert.False(t, s.acquireConn("192.0.2.1"))
+	assert.True(t, s.acquireConn("192.0.2.2"))
+
+	// A closed connection makes room for a new one.
+	s.releaseConn("192.0.2.1")
+	assert.True(t, s.acquireConn("192.0.2.1"))
+
+	// The limit can be changed while the server is running, and 0 means no limit.
+	s.UpdateConfig(func(config *Config) { config.MaxConnectionsPerIP = 0 })
+	assert.True(t, s.acquireConn("192.0.2.1"))
+}
+
+func TestServer_trackerRegistration(t *testing.T) {
+	s := &Server{
+		Config:        Config{Name: "Test Server", Description: "A test server"},
+		ClientMgr:     NewMemClientMgr(),
+		TrackerPassID: [4]byte{1, 2, 3, 4},
+		Port:          5500,
+	}
+	s.ClientMgr.Add(&ClientConn{})
+
+	tr := s.trackerRegistration()
+	assert.Equal(t, 1, tr.UserCount)
+	assert.Equal(t, "A test server", tr.Description)
+	assert.Equal(t, [2]byte{0x15, 0x7c}, tr.Port)
+
+	s.Config.Privacy = Privacy{HideUserCount: true, HideDescription: true}
+
+	tr = s.trackerRegistration()
+	assert.Equal(t, 0, tr.UserCount)
+	assert.Equal(t, "", tr.Description)
+	assert.Equal(t, "Test Server", tr.Name)
+
+	// The server version is only sent, at the end of the description, if TrackerVersion is set.
+	s.Version = "v1.2.3"
+	s.Config.Privacy = Privacy{}
+	assert.Equal(t, "A test server", s.trackerRegistration().Description)
+
+	s.Config.TrackerVersion = true
+	assert.Equal(t, "A test server (Mobius v1.2.3)", s.trackerRegistration().Description)
+
+	s.Config.Privacy = Privacy{HideDescription: true}
+	assert.Equal(t, "Mobius v1.2.3", s.trackerRegistration().Description)
+}
+
+func TestServer_checkIdle(t *testing.T) {
+	s := &Server{
+		ClientMgr: NewMemClientMgr(),
+		outbox:    make(chan Transaction, 10),
+		handlers:  map[TranType]HandlerFunc{},
+	}
+	cc := &ClientConn{Server: s, Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), UserName: []byte("jane")}
+	s.ClientMgr.Add(cc)
+
+	s.checkIdle(300)
+	assert.False(t, cc.Flags.IsSet(UserFlagAway))
+	assert.Len(t, s.outbox, 0)
+
+	s.checkIdle(idleCheckInterval)
+	assert.True(t, cc.Flags.IsSet(UserFlagAway))
+	assert.Len(t, s.outbox, 1)
+	notify := <-s.outbox
+	assert.Equal(t, TranNotifyChangeUser, notify.Type)
+	assert.Equal(t, cc.Flags[:], notify.GetField(FieldUserFlags).Data)
+
+	// Staying idle doesn't notify again.
+	s.checkIdle(idleCheckInterval)
+	assert.Len(t, s.outbox, 0)
+
+	// Keepalives aren't activity.
+	cc.handleTransaction(NewTransaction(TranKeepAlive, ClientID{}))
+	assert.True(t, cc.Flags.IsSet(UserFlagAway))
+
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+	assert.False(t, cc.Flags.IsSet(UserFlagAway))
+	assert.Equal(t, 0, cc.IdleTime)
+	assert.Len(t, s.outbox, 1)
+	notify = <-s.outbox
+	assert.Equal(t, []byte{0, 0}, notify.GetField(FieldUserFlags).Data)
+}
+
+func TestServer_checkIdle_disabled(t *testing.T) {
+	s := &Server{
+		Config:    Config{IdleTimeout: -1},
+		ClientMgr: NewMemClientMgr(),
+		outbox:    make(chan Transaction, 10),
+	}
+	cc := &ClientConn{Server: s}
+	s.ClientMgr.Add(cc)
+
+	s.checkIdle(24 * 60 * 60)
+	assert.False(t, cc.Flags.IsSet(UserFlagAway))
+}
+
+func TestServer_enforceLoginHours(t *testing.T) {
+	s := &Server{
+		ClientMgr: NewMemClientMgr(),
+		outbox:    make(chan Transaction, 10),
+	}
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	cc := &ClientConn{
+		Server:     s,
+		Connection: serverConn,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Account:    &Account{LoginHours: &LoginWindow{Start: 9 * time.Hour, End: 17 * time.Hour}},
+	}
+	s.ClientMgr.Add(cc)
+
+	s.enforceLoginHours(time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local))
+	assert.Len(t, s.outbox, 0)
+
+	// The client is warned once, however often the login window is checked before it is disconnected.
+	now := time.Date(2025, 3, 1, 20, 0, 0, 0, time.Local)
+	s.enforceLoginHours(now)
+	s.enforceLoginHours(now)
+	assert.Len(t, s.outbox, 1)
+	msg := <-s.outbox
+	assert.Equal(t, TranDisconnectMsg, msg.Type)
+
+	assert.Eventually(t, func() bool { return len(s.ClientMgr.List()) == 0 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,32 @@
+package hotline
+
+import (
+	"slices"
+)
+
+// excessSessions returns the existing sessions of c's account, oldest first, that would need to be closed for c to
+// stay within the account's MaxSessions limit.  It returns nil if the account has no limit or is under it.
+func (s *Server) excessSessions(c *ClientConn) []*ClientConn {
+	if c.Account.MaxSessions <= 0 {
+		return nil
+	}
+
+	var sessions []*ClientConn
+	for _, other := range s.ClientMgr.List() {
+		if other.ID == c.ID || other.Account == nil || other.Account.Login != c.Account.Login {
+			continue
+		}
+		sessions = append(sessions, other)
+	}
+
+	excess := len(sessions) - c.Account.MaxSessions + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	slices.SortFunc(sessions, func(a, b *ClientConn) int {
+		return a.LoginTime.Compare(b.LoginTime)
+	})
+
+	return sessions[:excess]
+}
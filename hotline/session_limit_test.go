@@ -0,0 +1,34 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestServer_excessSessions(t *testing.T) {
+	guest := &Account{Login: "guest", MaxSessions: 2}
+	admin := &Account{Login: "admin"}
+	now := time.Now()
+
+	first := &ClientConn{ID: ClientID{0, 3}, Account: guest, LoginTime: now.Add(-2 * time.Hour)}
+	second := &ClientConn{ID: ClientID{0, 1}, Account: guest, LoginTime: now.Add(-1 * time.Hour)}
+	other := &ClientConn{ID: ClientID{0, 2}, Account: admin, LoginTime: now.Add(-3 * time.Hour)}
+	pending := &ClientConn{ID: ClientID{0, 4}}
+	newConn := &ClientConn{ID: ClientID{0, 5}, Account: guest, LoginTime: now}
+
+	mgr := &MockClientMgr{}
+	mgr.On("List").Return([]*ClientConn{second, other, first, pending, newConn})
+	s := &Server{ClientMgr: mgr}
+
+	assert.Equal(t, []*ClientConn{first}, s.excessSessions(newConn))
+
+	guest.MaxSessions = 1
+	assert.Equal(t, []*ClientConn{first, second}, s.excessSessions(newConn))
+
+	guest.MaxSessions = 3
+	assert.Nil(t, s.excessSessions(newConn))
+
+	guest.MaxSessions = 0
+	assert.Nil(t, s.excessSessions(newConn))
+}
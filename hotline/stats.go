@@ -15,6 +15,7 @@ const (
 	StatConnectionCounter
 	StatDownloadCounter
 	StatUploadCounter
+	StatAbandonedTransfers
 )
 
 type Counter interface {
@@ -44,6 +45,7 @@ func NewStats() *Stats {
 			StatDownloadCounter:     0,
 			StatUploadCounter:       0,
 			StatConnectionCounter:   0,
+			StatAbandonedTransfers:  0,
 		},
 	}
 }
@@ -91,6 +93,7 @@ func (s *Stats) Values() map[string]interface{} {
 		"ConnectionCounter":   s.stats[StatConnectionCounter],
 		"DownloadCounter":     s.stats[StatDownloadCounter],
 		"UploadCounter":       s.stats[StatUploadCounter],
+		"AbandonedTransfers":  s.stats[StatAbandonedTransfers],
 		"Since":               s.since,
 	}
 }
@@ -0,0 +1,65 @@
+package hotline
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// TruncateText shortens text to at most maxLen bytes, and reports whether it was shortened.  Text that is valid UTF-8,
+// as sent by some modern clients, is cut at a rune boundary so that multibyte characters aren't split.  Other text is
+// Mac Roman, with one byte per character.
+func TruncateText(text []byte, maxLen int) ([]byte, bool) {
+	if len(text) <= maxLen {
+		return text, false
+	}
+
+	n := maxLen
+	if utf8.Valid(text) {
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+	}
+
+	return text[:n], true
+}
+
+// LimitLines keeps the first maxLines lines of text, and reports whether any were removed.  Lines are separated by
+// carriage returns, as sent by classic clients, or newlines.  A maxLines of 0 means no limit.
+func LimitLines(text []byte, maxLines int) ([]byte, bool) {
+	if maxLines <= 0 {
+		return text, false
+	}
+
+	var lines int
+	for i, b := range text {
+		if b != '\r' && b != '\n' {
+			continue
+		}
+		lines++
+		if lines == maxLines {
+			rest := bytes.Trim(text[i:], "\r\n")
+			return text[:i], len(rest) > 0
+		}
+	}
+
+	return text, false
+}
+
+// MaxChatMsgLen returns the maximum length in bytes of a chat message as relayed to other users, including the
+// sender's name.
+func (s *Server) MaxChatMsgLen() int {
+	if s.Config.MaxChatMsgLen > 0 {
+		return s.Config.MaxChatMsgLen
+	}
+	return LimitChatMsg
+}
+
+// LimitUserName shortens name to the configured maximum user name length.
+func (s *Server) LimitUserName(name []byte) []byte {
+	if s.Config.MaxUserNameLen <= 0 {
+		return name
+	}
+
+	name, _ = TruncateText(name, s.Config.MaxUserNameLen)
+	return name
+}
@@ -0,0 +1,51 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        []byte
+		maxLen      int
+		want        []byte
+		wantClipped bool
+	}{
+		{name: "short text", text: []byte("hello"), maxLen: 5, want: []byte("hello")},
+		{name: "long text", text: []byte("hello"), maxLen: 3, want: []byte("hel"), wantClipped: true},
+		{name: "UTF-8 cut at a rune boundary", text: []byte("caf\xc3\xa9"), maxLen: 4, want: []byte("caf"), wantClipped: true},
+		{name: "Mac Roman cut at any byte", text: []byte("caf\x8e\x8e"), maxLen: 4, want: []byte("caf\x8e"), wantClipped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clipped := TruncateText(tt.text, tt.maxLen)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantClipped, clipped)
+		})
+	}
+}
+
+func TestLimitLines(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		maxLines    int
+		want        string
+		wantClipped bool
+	}{
+		{name: "no limit", text: "a\rb\rc", maxLines: 0, want: "a\rb\rc"},
+		{name: "within limit", text: "a\rb", maxLines: 2, want: "a\rb"},
+		{name: "trailing line break", text: "a\rb\r", maxLines: 2, want: "a\rb"},
+		{name: "over limit", text: "a\rb\nc", maxLines: 2, want: "a\rb", wantClipped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clipped := LimitLines([]byte(tt.text), tt.maxLines)
+			assert.Equal(t, tt.want, string(got))
+			assert.Equal(t, tt.wantClipped, clipped)
+		})
+	}
+}
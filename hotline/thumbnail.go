@@ -0,0 +1,178 @@
+package hotline
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ThumbnailNameTemplate = ".thumb_%s" // template string for thumbnail filenames
+	DefaultThumbnailSize  = 128         // default max width and height of generated thumbnails in pixels
+
+	// maxThumbnailPixels is the largest image, in pixels, that thumbnails are made of.  Decoding needs memory for each
+	// pixel, and a small compressed upload can declare a huge image.
+	maxThumbnailPixels = 25_000_000
+)
+
+// thumbnailExts is the set of file extensions eligible for thumbnail generation.
+var thumbnailExts = map[string]bool{
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+}
+
+// ThumbnailPath returns the path of the thumbnail sidecar file for the file at path.
+func ThumbnailPath(path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(ThumbnailNameTemplate, filepath.Base(path)))
+}
+
+// Thumbnailer generates PNG preview images for uploaded pictures in the background.  Thumbnails are stored alongside
+// the original file in a sidecar file named using ThumbnailNameTemplate.
+type Thumbnailer struct {
+	fs     FileStore
+	size   int
+	queue  chan string
+	logger *slog.Logger
+}
+
+func NewThumbnailer(fs FileStore, size int, logger *slog.Logger) *Thumbnailer {
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+
+	return &Thumbnailer{
+		fs:     fs,
+		size:   size,
+		queue:  make(chan string, 100),
+		logger: logger,
+	}
+}
+
+// Enqueue schedules thumbnail generation for the file at path.  Files that are not supported image types are ignored.
+// If the queue is full the request is dropped rather than blocking the caller.
+func (t *Thumbnailer) Enqueue(path string) {
+	if !thumbnailExts[strings.ToLower(filepath.Ext(path))] {
+		return
+	}
+
+	select {
+	case t.queue <- path:
+	default:
+		t.logger.Warn("Thumbnail queue full, skipping", "path", path)
+	}
+}
+
+// Run processes queued thumbnail requests until ctx is cancelled.
+func (t *Thumbnailer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case path := <-t.queue:
+			if err := t.Generate(path); err != nil {
+				t.logger.Error("Error generating thumbnail", "path", path, "err", err)
+			}
+		}
+	}
+}
+
+// Generate creates the thumbnail sidecar file for the image at path.
+func (t *Thumbnailer) Generate(path string) error {
+	f, err := t.fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+	if config.Width*config.Height > maxThumbnailPixels {
+		return fmt.Errorf("image is too large: %dx%d", config.Width, config.Height)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	thumb, err := t.fs.Create(ThumbnailPath(path))
+	if err != nil {
+		return err
+	}
+	defer thumb.Close()
+
+	if err := png.Encode(thumb, scaleImage(img, t.size)); err != nil {
+		_ = t.fs.Remove(thumb.Name())
+		return fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	return nil
+}
+
+// scaleImage scales img down to fit within a size x size box while preserving the aspect ratio.  Each destination pixel
+// is the average of the source pixels it covers.  Images that already fit are returned unchanged.
+func scaleImage(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW <= size && srcH <= size {
+		return img
+	}
+
+	dstW, dstH := size, size
+	if srcW > srcH {
+		dstH = max(1, srcH*size/srcW)
+	} else {
+		dstW = max(1, srcW*size/srcH)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := b.Min.Y+y*srcH/dstH, b.Min.Y+max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := b.Min.X+x*srcW/dstW, b.Min.X+max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// ReadThumbnail returns the thumbnail image data for the file at path.
+func ReadThumbnail(fs FileStore, path string) ([]byte, error) {
+	data, err := fs.ReadFile(ThumbnailPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("read thumbnail: %w", err)
+	}
+
+	return data, nil
+}
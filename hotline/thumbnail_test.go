@@ -0,0 +1,81 @@
+package hotline
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScaleImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		w, h, size int
+		wantW      int
+		wantH      int
+	}{
+		{name: "landscape", w: 400, h: 200, size: 100, wantW: 100, wantH: 50},
+		{name: "portrait", w: 200, h: 400, size: 100, wantW: 50, wantH: 100},
+		{name: "already small", w: 40, h: 20, size: 100, wantW: 40, wantH: 20},
+		{name: "extreme aspect ratio", w: 1000, h: 2, size: 100, wantW: 100, wantH: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scaleImage(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.size)
+			assert.Equal(t, tt.wantW, got.Bounds().Dx())
+			assert.Equal(t, tt.wantH, got.Bounds().Dy())
+		})
+	}
+}
+
+func TestThumbnailer_Generate(t *testing.T) {
+	dir := t.TempDir()
+	imgPath := filepath.Join(dir, "picture.png")
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	assert.NoError(t, os.WriteFile(imgPath, buf.Bytes(), 0644))
+
+	thumbnailer := NewThumbnailer(&OSFileStore{}, 16, slog.Default())
+	assert.NoError(t, thumbnailer.Generate(imgPath))
+
+	data, err := ReadThumbnail(&OSFileStore{}, imgPath)
+	assert.NoError(t, err)
+
+	thumb, err := png.Decode(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 16, 8), thumb.Bounds())
+
+	r, g, b, a := thumb.At(4, 4).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0, 0xffff}, []uint32{r, g, b, a})
+}
+
+func TestThumbnailer_Generate_tooLarge(t *testing.T) {
+	imgPath := filepath.Join(t.TempDir(), "bomb.gif")
+
+	var buf bytes.Buffer
+	assert.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil))
+
+	// Declare a 65535x65535 logical screen in the GIF header without adding any image data.
+	data := buf.Bytes()
+	copy(data[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+	assert.NoError(t, os.WriteFile(imgPath, data, 0644))
+
+	err := NewThumbnailer(&OSFileStore{}, 16, slog.Default()).Generate(imgPath)
+	assert.ErrorContains(t, err, "too large")
+
+	_, err = os.Stat(ThumbnailPath(imgPath))
+	assert.True(t, os.IsNotExist(err))
+}
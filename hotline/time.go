@@ -26,3 +26,12 @@ func NewTime(t time.Time) (b Time) {
 		secondBytes,
 	))
 }
+
+// Time converts t back to a time.Time in the local time zone, in which NewTime counts the seconds since the start of
+// the year.
+func (t Time) Time() time.Time {
+	year := int(binary.BigEndian.Uint16(t[0:2]))
+	seconds := binary.BigEndian.Uint32(t[4:8])
+
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local).Add(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,94 @@
+package hotline
+
+import (
+	"crypto/rand"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Span is a timed operation, such as handling a transaction or a file transfer, recorded for tracing.
+type Span struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Name    string
+	Start   time.Time
+	End     time.Time
+	Attrs   map[string]any
+	Err     error
+}
+
+// SpanExporter sends completed spans to a tracing backend.  ExportSpan is called from the goroutine that ended the span
+// and must not block.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+var fileTransferTypeNames = map[FileTransferType]string{
+	FileDownload:   "file download",
+	FileUpload:     "file upload",
+	FolderDownload: "folder download",
+	FolderUpload:   "folder upload",
+	BannerDownload: "banner download",
+}
+
+// activeSpan is a span that has been started but not ended.  A nil *activeSpan is valid and records nothing, so
+// that callers do not need to check whether tracing is enabled.
+type activeSpan struct {
+	Span
+	exporter SpanExporter
+}
+
+// startSpan starts a span with the given name and attributes, or returns nil if tracing is disabled.
+func (s *Server) startSpan(name string, attrs map[string]any) *activeSpan {
+	if s.SpanExporter == nil {
+		return nil
+	}
+
+	span := &activeSpan{
+		Span:     Span{Name: name, Start: time.Now(), Attrs: attrs},
+		exporter: s.SpanExporter,
+	}
+	_, _ = rand.Read(span.TraceID[:])
+	_, _ = rand.Read(span.SpanID[:])
+
+	return span
+}
+
+func (sp *activeSpan) SetAttr(key string, value any) {
+	if sp == nil {
+		return
+	}
+
+	sp.Attrs[key] = value
+}
+
+// End records the end time and result of the span and exports it.
+func (sp *activeSpan) End(err error) {
+	if sp == nil {
+		return
+	}
+
+	sp.Span.End = time.Now()
+	sp.Err = err
+	sp.exporter.ExportSpan(sp.Span)
+}
+
+// countingRW is an io.ReadWriter that counts the bytes read and written.
+type countingRW struct {
+	rw      io.ReadWriter
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+func (c *countingRW) Read(p []byte) (int, error) {
+	n, err := c.rw.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingRW) Write(p []byte) (int, error) {
+	n, err := c.rw.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
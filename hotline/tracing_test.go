@@ -0,0 +1,65 @@
+package hotline
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+type testSpanExporter struct {
+	spans []Span
+}
+
+func (e *testSpanExporter) ExportSpan(span Span) {
+	e.spans = append(e.spans, span)
+}
+
+func TestClientConn_handleTransaction_span(t *testing.T) {
+	exporter := &testSpanExporter{}
+	s := &Server{
+		SpanExporter: exporter,
+		outbox:       make(chan Transaction, 1),
+		handlers: map[TranType]HandlerFunc{
+			TranChatSend: func(cc *ClientConn, t *Transaction) []Transaction {
+				return []Transaction{NewTransaction(TranChatMsg, cc.ID)}
+			},
+		},
+	}
+	cc := &ClientConn{ID: ClientID{0, 3}, ConnID: 9, Server: s, Logger: slog.Default()}
+
+	cc.handleTransaction(NewTransaction(TranChatSend, ClientID{}))
+
+	assert.Len(t, exporter.spans, 1)
+	span := exporter.spans[0]
+	assert.Equal(t, "hotline.transaction", span.Name)
+	assert.Equal(t, map[string]any{
+		"hotline.transaction.type":    "Send chat",
+		"hotline.client.id":           uint16(3),
+		"hotline.conn.id":             uint64(9),
+		"hotline.transaction.replies": 1,
+	}, span.Attrs)
+	assert.False(t, span.End.Before(span.Start))
+	assert.NotEqual(t, [16]byte{}, span.TraceID)
+}
+
+func TestActiveSpan_nil(t *testing.T) {
+	s := &Server{}
+	span := s.startSpan("hotline.transaction", map[string]any{})
+	assert.Nil(t, span)
+
+	// Methods of a nil span are no-ops.
+	span.SetAttr("key", "value")
+	span.End(errors.New("error"))
+}
+
+func TestCountingRW(t *testing.T) {
+	rw := &countingRW{rw: &bytes.Buffer{}}
+
+	_, _ = rw.Write([]byte("hello"))
+	_, _ = rw.Read(make([]byte, 2))
+
+	assert.Equal(t, int64(5), rw.written.Load())
+	assert.Equal(t, int64(2), rw.read.Load())
+}
@@ -9,6 +9,7 @@ import (
 	"net"
 	"slices"
 	"strconv"
+	"strings"
 )
 
 // TrackerRegistration represents the payload a Hotline server sends to a Tracker to register
@@ -64,6 +65,16 @@ func (d *RealDialer) Dial(network, address string) (net.Conn, error) {
 	return net.Dial(network, address)
 }
 
+// splitTrackerAddr splits a tracker from the Trackers config into its address and its optional password, which follows
+// the port, e.g. "tracker.example.com:5499:password".
+func splitTrackerAddr(tracker string) (addr, password string) {
+	if parts := strings.SplitN(tracker, ":", 3); len(parts) == 3 {
+		return parts[0] + ":" + parts[1], parts[2]
+	}
+
+	return tracker, ""
+}
+
 func register(dialer Dialer, tracker string, tr io.Reader) error {
 	conn, err := dialer.Dial("udp", tracker)
 	if err != nil {
@@ -134,6 +145,9 @@ func GetListing(conn io.ReadWriteCloser) ([]ServerRecord, error) {
 	}
 
 	totalSrv := int(binary.BigEndian.Uint16(info.SrvCount[:]))
+	if totalSrv == 0 {
+		return nil, nil
+	}
 
 	scanner := bufio.NewScanner(conn)
 	scanner.Split(serverScanner)
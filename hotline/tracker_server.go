@@ -0,0 +1,291 @@
+package hotline
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTrackerTTL is how long a server stays listed without renewing its registration.  Servers re-register every
+// five minutes, so this allows for one lost registration packet.
+const DefaultTrackerTTL = 11 * time.Minute
+
+// DefaultTrackerMaxServers and DefaultTrackerMaxServersPerIP limit how many servers a tracker lists, so that
+// registrations sent from many addresses or for many ports can't use up its memory.
+const (
+	DefaultTrackerMaxServers      = 5000
+	DefaultTrackerMaxServersPerIP = 10
+)
+
+// trackerListingTimeout limits how long a client connection to the tracker may take.
+const trackerListingTimeout = 30 * time.Second
+
+// Write implements io.Writer to parse a tracker registration payload, as sent by a Hotline server.
+func (tr *TrackerRegistration) Write(b []byte) (int, error) {
+	if len(b) < 13 {
+		return 0, errors.New("too few bytes")
+	}
+	if b[0] != 0x00 || b[1] != 0x01 {
+		return 0, errors.New("invalid magic number")
+	}
+
+	copy(tr.Port[:], b[2:4])
+	tr.UserCount = int(binary.BigEndian.Uint16(b[4:6]))
+	copy(tr.PassID[:], b[8:12])
+
+	// The name, description, and password are each preceded by their length.  The password is optional.
+	var strs []string
+	rest := b[12:]
+	for len(rest) > 0 && len(strs) < 3 {
+		n := int(rest[0])
+		if len(rest) < 1+n {
+			return 0, errors.New("too few bytes")
+		}
+		strs = append(strs, string(rest[1:1+n]))
+		rest = rest[1+n:]
+	}
+	if len(strs) < 2 {
+		return 0, errors.New("too few bytes")
+	}
+
+	tr.Name, tr.Description = strs[0], strs[1]
+	if len(strs) == 3 {
+		tr.Password = strs[2]
+	}
+
+	return len(b) - len(rest), nil
+}
+
+// encode returns the server record in the format sent to tracker clients.
+func (s *ServerRecord) encode() []byte {
+	return slices.Concat(
+		s.IPAddr[:],
+		s.Port[:],
+		s.NumUsers[:],
+		s.Unused[:],
+		[]byte{s.NameSize},
+		s.Name,
+		[]byte{s.DescriptionSize},
+		s.Description,
+	)
+}
+
+// TrackerServer is a Hotline tracker.  Hotline servers register with it over UDP, and clients connect to it over TCP
+// to get the list of registered servers.
+type TrackerServer struct {
+	Passwords []string      // Passwords servers must register with, one of which must match; empty to allow any server
+	TTL       time.Duration // How long a server stays listed without renewing its registration; defaults to DefaultTrackerTTL
+	Logger    *slog.Logger
+
+	MaxServers      int // Max number of listed servers; defaults to DefaultTrackerMaxServers
+	MaxServersPerIP int // Max number of servers listed from one IP address; defaults to DefaultTrackerMaxServersPerIP
+
+	mu      sync.Mutex
+	servers map[string]trackedServer // Registered servers by IP address and port
+}
+
+type trackedServer struct {
+	record  ServerRecord
+	expires time.Time
+}
+
+func NewTrackerServer(logger *slog.Logger) *TrackerServer {
+	return &TrackerServer{
+		TTL:             DefaultTrackerTTL,
+		Logger:          logger,
+		MaxServers:      DefaultTrackerMaxServers,
+		MaxServersPerIP: DefaultTrackerMaxServersPerIP,
+		servers:         make(map[string]trackedServer),
+	}
+}
+
+// Register adds or renews the listing of the server that sent tr from ip.  New servers are refused once MaxServers are
+// listed, or MaxServersPerIP are listed from ip.
+func (ts *TrackerServer) Register(ip net.IP, tr TrackerRegistration, now time.Time) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("unsupported address %s: only IPv4 servers can be listed", ip)
+	}
+
+	if len(ts.Passwords) > 0 && !slices.Contains(ts.Passwords, tr.Password) {
+		return errors.New("incorrect tracker password")
+	}
+
+	record := ServerRecord{
+		Port:            tr.Port,
+		NameSize:        byte(len(tr.Name)),
+		Name:            []byte(tr.Name),
+		DescriptionSize: byte(len(tr.Description)),
+		Description:     []byte(tr.Description),
+	}
+	copy(record.IPAddr[:], ip4)
+	binary.BigEndian.PutUint16(record.NumUsers[:], uint16(min(tr.UserCount, math.MaxUint16)))
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, ok := ts.servers[record.Addr()]; !ok {
+		ts.removeExpired(now)
+
+		if len(ts.servers) >= cmp.Or(ts.MaxServers, DefaultTrackerMaxServers) {
+			return errors.New("too many servers listed")
+		}
+
+		var fromIP int
+		for _, s := range ts.servers {
+			if s.record.IPAddr == record.IPAddr {
+				fromIP++
+			}
+		}
+		if fromIP >= cmp.Or(ts.MaxServersPerIP, DefaultTrackerMaxServersPerIP) {
+			return fmt.Errorf("too many servers listed from %s", ip)
+		}
+	}
+
+	ts.servers[record.Addr()] = trackedServer{record: record, expires: now.Add(cmp.Or(ts.TTL, DefaultTrackerTTL))}
+
+	return nil
+}
+
+// Servers returns the servers listed at now, ordered by name.  Expired registrations are removed.
+func (ts *TrackerServer) Servers(now time.Time) []ServerRecord {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.removeExpired(now)
+
+	servers := make([]ServerRecord, 0, len(ts.servers))
+	for _, s := range ts.servers {
+		servers = append(servers, s.record)
+	}
+
+	slices.SortFunc(servers, func(a, b ServerRecord) int {
+		return cmp.Or(
+			strings.Compare(strings.ToLower(string(a.Name)), strings.ToLower(string(b.Name))),
+			strings.Compare(a.Addr(), b.Addr()),
+		)
+	})
+
+	return servers
+}
+
+func (ts *TrackerServer) removeExpired(now time.Time) {
+	for addr, s := range ts.servers {
+		if now.After(s.expires) {
+			delete(ts.servers, addr)
+		}
+	}
+}
+
+// ServeRegistrations reads server registrations from conn until ctx is cancelled.
+func (ts *TrackerServer) ServeRegistrations(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+
+		var tr TrackerRegistration
+		if _, err := tr.Write(buf[:n]); err != nil {
+			ts.Logger.Debug("Ignoring invalid tracker registration", "remoteAddr", addr, "err", err)
+			continue
+		}
+
+		if err := ts.Register(udpAddr.IP, tr, time.Now()); err != nil {
+			ts.Logger.Info("Rejected tracker registration", "remoteAddr", addr, "name", tr.Name, "err", err)
+			continue
+		}
+
+		ts.Logger.Debug("Server registered", "remoteAddr", addr, "name", tr.Name, "users", tr.UserCount)
+	}
+}
+
+// ServeListings serves the server list to clients that connect to ln until ctx is cancelled.
+func (ts *TrackerServer) ServeListings(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go func() {
+			defer func() { _ = conn.Close() }()
+
+			_ = conn.SetDeadline(time.Now().Add(trackerListingTimeout))
+			if err := ts.handleListing(conn); err != nil {
+				ts.Logger.Debug("Error serving tracker listing", "remoteAddr", conn.RemoteAddr(), "err", err)
+			}
+		}()
+	}
+}
+
+// handleListing reads a client's listing request from rw and writes the server list in reply.
+func (ts *TrackerServer) handleListing(rw io.ReadWriter) error {
+	var th TrackerHeader
+	if err := binary.Read(rw, binary.BigEndian, &th); err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	if !bytes.Equal(th.Protocol[:], []byte("HTRK")) {
+		return errors.New("invalid protocol")
+	}
+
+	// The size of the list is sent as 2 bytes, so list as many servers as fit.
+	var records []byte
+	var count int
+	for _, s := range ts.Servers(time.Now()) {
+		record := s.encode()
+		if 4+len(records)+len(record) > math.MaxUint16 {
+			break
+		}
+		records = append(records, record...)
+		count++
+	}
+
+	info := ServerInfoHeader{MsgType: [2]byte{0x00, 0x01}}
+	binary.BigEndian.PutUint16(info.MsgDataSize[:], uint16(4+len(records)))
+	binary.BigEndian.PutUint16(info.SrvCount[:], uint16(count))
+	info.SrvCountDup = info.SrvCount
+
+	var buf bytes.Buffer
+	buf.Write([]byte("HTRK"))
+	buf.Write([]byte{0x00, 0x01})
+	_ = binary.Write(&buf, binary.BigEndian, info)
+	buf.Write(records)
+
+	_, err := rw.Write(buf.Bytes())
+
+	return err
+}
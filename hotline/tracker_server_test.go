@@ -0,0 +1,132 @@
+package hotline
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTrackerRegistration_Write(t *testing.T) {
+	want := TrackerRegistration{
+		Port:        [2]byte{0x15, 0x7c},
+		UserCount:   3,
+		PassID:      [4]byte{1, 2, 3, 4},
+		Name:        "Test Serv",
+		Description: "Fooz",
+		Password:    "secret",
+	}
+
+	payload, err := io.ReadAll(&want)
+	require.NoError(t, err)
+
+	var got TrackerRegistration
+	n, err := got.Write(payload)
+	require.NoError(t, err)
+	assert.Equal(t, len(payload), n)
+
+	want.readOffset = 0
+	assert.Equal(t, want, got)
+
+	_, err = got.Write(payload[:14])
+	assert.Error(t, err)
+}
+
+func TestTrackerServer_Register(t *testing.T) {
+	ts := NewTrackerServer(NewTestLogger())
+	ts.Passwords = []string{"secret"}
+
+	now := time.Now()
+	tr := TrackerRegistration{Port: [2]byte{0x15, 0x7c}, UserCount: 2, Name: "Beta", Description: "Second"}
+
+	assert.Error(t, ts.Register(net.ParseIP("192.0.2.1"), tr, now), "registration without the password")
+
+	tr.Password = "secret"
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.1"), tr, now))
+	assert.Error(t, ts.Register(net.ParseIP("2001:db8::1"), tr, now), "IPv6 registration")
+
+	tr.Name = "Alpha"
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.2"), tr, now.Add(5*time.Minute)))
+
+	servers := ts.Servers(now.Add(5 * time.Minute))
+	require.Len(t, servers, 2)
+	assert.Equal(t, "Alpha", string(servers[0].Name))
+	assert.Equal(t, "192.0.2.2:5500", servers[0].Addr())
+	assert.Equal(t, "Beta", string(servers[1].Name))
+
+	// Registrations that aren't renewed expire.
+	servers = ts.Servers(now.Add(DefaultTrackerTTL + time.Minute))
+	require.Len(t, servers, 1)
+	assert.Equal(t, "Alpha", string(servers[0].Name))
+}
+
+func TestTrackerServer_Register_limits(t *testing.T) {
+	ts := NewTrackerServer(NewTestLogger())
+	ts.MaxServers = 3
+	ts.MaxServersPerIP = 2
+
+	now := time.Now()
+	tr := TrackerRegistration{Port: [2]byte{0x15, 0x7c}, Name: "Server"}
+
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.1"), tr, now))
+	tr.Port = [2]byte{0x15, 0x7d}
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.1"), tr, now))
+	tr.Port = [2]byte{0x15, 0x7e}
+	assert.EqualError(t, ts.Register(net.ParseIP("192.0.2.1"), tr, now), "too many servers listed from 192.0.2.1")
+
+	// Listed servers can still renew their registration.
+	tr.Port = [2]byte{0x15, 0x7c}
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.1"), tr, now))
+
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.2"), tr, now))
+	assert.EqualError(t, ts.Register(net.ParseIP("192.0.2.3"), tr, now), "too many servers listed")
+
+	// Expired registrations make room for new ones.
+	require.NoError(t, ts.Register(net.ParseIP("192.0.2.3"), tr, now.Add(DefaultTrackerTTL+time.Minute)))
+}
+
+func TestTrackerServer(t *testing.T) {
+	ts := NewTrackerServer(NewTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = ts.ServeRegistrations(ctx, pc) }()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = ts.ServeListings(ctx, ln) }()
+
+	// An empty tracker lists no servers.
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	servers, err := GetListing(conn)
+	require.NoError(t, err)
+	assert.Empty(t, servers)
+
+	require.NoError(t, register(&RealDialer{}, pc.LocalAddr().String(), &TrackerRegistration{
+		Port:        [2]byte{0x15, 0x7c},
+		UserCount:   7,
+		Name:        "Mobius Test",
+		Description: "A test server",
+	}))
+
+	require.Eventually(t, func() bool {
+		return len(ts.Servers(time.Now())) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	conn, err = net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	servers, err = GetListing(conn)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, "127.0.0.1:5500", servers[0].Addr())
+	assert.Equal(t, "Mobius Test", string(servers[0].Name))
+	assert.Equal(t, "A test server", string(servers[0].Description))
+	assert.Equal(t, []byte{0, 7}, servers[0].NumUsers[:])
+}
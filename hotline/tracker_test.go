@@ -2,11 +2,14 @@ package hotline
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io"
+	"net"
 	"testing"
+	"time"
 )
 
 func TestTrackerRegistration_Payload(t *testing.T) {
@@ -347,3 +350,90 @@ func TestGetListing(t *testing.T) {
 		})
 	}
 }
+
+// fakeTrackerDialer records tracker registrations, failing those to addresses in fail.
+type fakeTrackerDialer struct {
+	fail   map[string]bool
+	dialed []string
+}
+
+func (d *fakeTrackerDialer) Dial(_, address string) (net.Conn, error) {
+	d.dialed = append(d.dialed, address)
+	if d.fail[address] {
+		return nil, errors.New("connection refused")
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		_, _ = io.Copy(io.Discard, server)
+	}()
+
+	return client, nil
+}
+
+func Test_splitTrackerAddr(t *testing.T) {
+	addr, password := splitTrackerAddr("tracker.example.com:5499")
+	assert.Equal(t, "tracker.example.com:5499", addr)
+	assert.Equal(t, "", password)
+
+	addr, password = splitTrackerAddr("tracker.example.com:5499:secret")
+	assert.Equal(t, "tracker.example.com:5499", addr)
+	assert.Equal(t, "secret", password)
+}
+
+func Test_trackerBackoff(t *testing.T) {
+	assert.Equal(t, 15*time.Second, trackerBackoff(1))
+	assert.Equal(t, 30*time.Second, trackerBackoff(2))
+	assert.Equal(t, 60*time.Second, trackerBackoff(3))
+	assert.Equal(t, trackerUpdateFrequency, trackerBackoff(10))
+	assert.Equal(t, trackerUpdateFrequency, trackerBackoff(100))
+}
+
+func TestServer_updateTrackers(t *testing.T) {
+	dialer := &fakeTrackerDialer{fail: map[string]bool{"down.example.com:5499": true}}
+
+	s, err := NewServer(
+		WithLogger(NewTestLogger()),
+		WithConfig(Config{Name: "Test", EnableTrackerRegistration: true, Trackers: []string{"up.example.com:5499"}}),
+		WithTrackers("down.example.com:5499:secret"),
+	)
+	require.NoError(t, err)
+	s.trackerDialer = dialer
+
+	now := time.Now()
+	schedules := make(map[string]*trackerSchedule)
+
+	s.updateTrackers(now, schedules)
+	assert.Equal(t, []string{"up.example.com:5499", "down.example.com:5499"}, dialer.dialed)
+
+	// The failed tracker is retried after the backoff delay; the working one waits for the next update.
+	dialer.dialed = nil
+	s.updateTrackers(now.Add(trackerRetryDelay), schedules)
+	assert.Equal(t, []string{"down.example.com:5499"}, dialer.dialed)
+	assert.Equal(t, 2, schedules["down.example.com:5499:secret"].failures)
+
+	dialer.dialed = nil
+	delete(dialer.fail, "down.example.com:5499")
+	s.updateTrackers(now.Add(trackerRetryDelay+trackerBackoff(2)), schedules)
+	assert.Equal(t, []string{"down.example.com:5499"}, dialer.dialed)
+	assert.Equal(t, 0, schedules["down.example.com:5499:secret"].failures)
+
+	dialer.dialed = nil
+	s.updateTrackers(now.Add(trackerUpdateFrequency), schedules)
+	assert.Equal(t, []string{"up.example.com:5499"}, dialer.dialed)
+}
+
+func TestServer_updateTrackers_unlisted(t *testing.T) {
+	dialer := &fakeTrackerDialer{}
+
+	s, err := NewServer(
+		WithLogger(NewTestLogger()),
+		WithConfig(Config{EnableTrackerRegistration: true, Trackers: []string{"up.example.com:5499"}, Privacy: Privacy{Unlisted: true}}),
+		WithTrackers("other.example.com:5499"),
+	)
+	require.NoError(t, err)
+	s.trackerDialer = dialer
+
+	s.updateTrackers(time.Now(), make(map[string]*trackerSchedule))
+	assert.Empty(t, dialer.dialed)
+}
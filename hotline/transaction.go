@@ -1,7 +1,6 @@
 package hotline
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
 	"errors"
@@ -74,6 +73,19 @@ var (
 	TranKeepAlive            = TranType{0x01, 0xF4} // 500
 )
 
+// Mobius protocol extensions.  Classic servers reply to unknown transaction types with an error.
+var (
+	TranGetServerInfo          = TranType{0x0F, 0xA0} // 4000: get server version, uptime, and usage counts, see ServerInfo
+	TranGetIncompleteUploads   = TranType{0x0F, 0xA1} // 4001: list the user's interrupted uploads
+	TranDeleteIncompleteUpload = TranType{0x0F, 0xA2} // 4002: delete one of the user's interrupted uploads
+	TranGetBookmarks           = TranType{0x0F, 0xA3} // 4003: list the account's bookmarked files and folders
+	TranAddBookmark            = TranType{0x0F, 0xA4} // 4004: bookmark a file or folder
+	TranDeleteBookmark         = TranType{0x0F, 0xA5} // 4005: remove a bookmark
+	TranGetPreferences         = TranType{0x0F, 0xA6} // 4006: get the account's saved preferences
+	TranSetPreferences         = TranType{0x0F, 0xA7} // 4007: save account preferences
+	TranNegotiateCapabilities  = TranType{0x0F, 0xA8} // 4008: enable optional features supported by both client and server, see Capabilities
+)
+
 type Transaction struct {
 	Flags      byte     // Reserved (should be 0)
 	IsReply    byte     // Request (0) or reply (1)
@@ -90,55 +102,64 @@ type Transaction struct {
 }
 
 var tranTypeNames = map[TranType]string{
-	TranChatMsg:            "Receive chat",
-	TranNotifyChangeUser:   "User change",
-	TranError:              "Error",
-	TranShowAgreement:      "Show agreement",
-	TranUserAccess:         "User access",
-	TranNotifyDeleteUser:   "User left",
-	TranAgreed:             "Accept agreement",
-	TranChatSend:           "Send chat",
-	TranDelNewsArt:         "Delete news article",
-	TranDelNewsItem:        "Delete news item",
-	TranDeleteFile:         "Delete file",
-	TranDeleteUser:         "Delete user",
-	TranDisconnectUser:     "Disconnect user",
-	TranDownloadFile:       "Download file",
-	TranDownloadFldr:       "Download folder",
-	TranGetClientInfoText:  "Get client info",
-	TranGetFileInfo:        "Get file info",
-	TranGetFileNameList:    "Get file list",
-	TranGetMsgs:            "Get messages",
-	TranGetNewsArtData:     "Get news article",
-	TranGetNewsArtNameList: "Get news article list",
-	TranGetNewsCatNameList: "Get news categories",
-	TranGetUser:            "Get user",
-	TranGetUserNameList:    "Get user list",
-	TranInviteNewChat:      "Invite to new chat",
-	TranInviteToChat:       "Invite to chat",
-	TranJoinChat:           "Join chat",
-	TranKeepAlive:          "Keepalive",
-	TranLeaveChat:          "Leave chat",
-	TranListUsers:          "List user accounts",
-	TranMoveFile:           "Move file",
-	TranNewFolder:          "Create folder",
-	TranNewNewsCat:         "Create news category",
-	TranNewNewsFldr:        "Create news bundle",
-	TranNewUser:            "Create user account",
-	TranUpdateUser:         "Update user account",
-	TranOldPostNews:        "Post to message board",
-	TranPostNewsArt:        "Create news article",
-	TranRejectChatInvite:   "Decline chat invite",
-	TranSendInstantMsg:     "Send message",
-	TranSetChatSubject:     "Set chat subject",
-	TranMakeFileAlias:      "Make file alias",
-	TranSetClientUserInfo:  "Set client user info",
-	TranSetFileInfo:        "Set file info",
-	TranSetUser:            "Set user",
-	TranUploadFile:         "Upload file",
-	TranUploadFldr:         "Upload folder",
-	TranUserBroadcast:      "Send broadcast",
-	TranDownloadBanner:     "Download banner",
+	TranChatMsg:                "Receive chat",
+	TranNotifyChangeUser:       "User change",
+	TranError:                  "Error",
+	TranShowAgreement:          "Show agreement",
+	TranUserAccess:             "User access",
+	TranNotifyDeleteUser:       "User left",
+	TranAgreed:                 "Accept agreement",
+	TranChatSend:               "Send chat",
+	TranDelNewsArt:             "Delete news article",
+	TranDelNewsItem:            "Delete news item",
+	TranDeleteFile:             "Delete file",
+	TranDeleteUser:             "Delete user",
+	TranDisconnectUser:         "Disconnect user",
+	TranDownloadFile:           "Download file",
+	TranDownloadFldr:           "Download folder",
+	TranGetClientInfoText:      "Get client info",
+	TranGetFileInfo:            "Get file info",
+	TranGetFileNameList:        "Get file list",
+	TranGetMsgs:                "Get messages",
+	TranGetNewsArtData:         "Get news article",
+	TranGetNewsArtNameList:     "Get news article list",
+	TranGetNewsCatNameList:     "Get news categories",
+	TranGetUser:                "Get user",
+	TranGetUserNameList:        "Get user list",
+	TranInviteNewChat:          "Invite to new chat",
+	TranInviteToChat:           "Invite to chat",
+	TranJoinChat:               "Join chat",
+	TranKeepAlive:              "Keepalive",
+	TranGetServerInfo:          "Get server info",
+	TranGetIncompleteUploads:   "Get incomplete uploads",
+	TranDeleteIncompleteUpload: "Delete incomplete upload",
+	TranGetBookmarks:           "Get bookmarks",
+	TranAddBookmark:            "Add bookmark",
+	TranDeleteBookmark:         "Delete bookmark",
+	TranGetPreferences:         "Get preferences",
+	TranSetPreferences:         "Set preferences",
+	TranNegotiateCapabilities:  "Negotiate capabilities",
+	TranLeaveChat:              "Leave chat",
+	TranListUsers:              "List user accounts",
+	TranMoveFile:               "Move file",
+	TranNewFolder:              "Create folder",
+	TranNewNewsCat:             "Create news category",
+	TranNewNewsFldr:            "Create news bundle",
+	TranNewUser:                "Create user account",
+	TranUpdateUser:             "Update user account",
+	TranOldPostNews:            "Post to message board",
+	TranPostNewsArt:            "Create news article",
+	TranRejectChatInvite:       "Decline chat invite",
+	TranSendInstantMsg:         "Send message",
+	TranSetChatSubject:         "Set chat subject",
+	TranMakeFileAlias:          "Make file alias",
+	TranSetClientUserInfo:      "Set client user info",
+	TranSetFileInfo:            "Set file info",
+	TranSetUser:                "Set user",
+	TranUploadFile:             "Upload file",
+	TranUploadFldr:             "Upload folder",
+	TranUserBroadcast:          "Send broadcast",
+	TranDownloadBanner:         "Download banner",
 }
 
 // NewTransaction creates a new Transaction with the specified type, client, and optional fields.
@@ -159,50 +180,54 @@ func NewTransaction(t TranType, clientID ClientID, fields ...Field) Transaction
 // Transactions read from the network are read as complete tokens with a bufio.Scanner, so
 // the arg p is guaranteed to have the full byte payload of a complete transaction.
 func (t *Transaction) Write(p []byte) (n int, err error) {
-	// Make sure we have the minimum number of bytes for a transaction.
-	if len(p) < 22 {
-		return 0, errors.New("buffer too small")
+	r := newBinReader(p)
+
+	t.Flags = r.Uint8()
+	t.IsReply = r.Uint8()
+	r.Read(t.Type[:])
+	r.Read(t.ID[:])
+	r.Read(t.ErrorCode[:])
+	r.Read(t.TotalSize[:])
+	r.Read(t.DataSize[:])
+	r.Read(t.ParamCount[:])
+	if err := r.Err(); err != nil {
+		return 0, fmt.Errorf("read transaction header: %w", err)
 	}
 
-	// Read the total size field.
-	totalSize := binary.BigEndian.Uint32(p[12:16])
-	tranLen := int(20 + totalSize)
-
-	paramCount := binary.BigEndian.Uint16(p[20:22])
-
-	t.Flags = p[0]
-	t.IsReply = p[1]
-	copy(t.Type[:], p[2:4])
-	copy(t.ID[:], p[4:8])
-	copy(t.ErrorCode[:], p[8:12])
-	copy(t.TotalSize[:], p[12:16])
-	copy(t.DataSize[:], p[16:20])
-	copy(t.ParamCount[:], p[20:22])
-
-	scanner := bufio.NewScanner(bytes.NewReader(p[22:tranLen]))
-	scanner.Split(FieldScanner)
+	// The total size includes the 2 byte field count.
+	totalSize := binary.BigEndian.Uint32(t.TotalSize[:])
+	if totalSize < 2 || int64(totalSize) > int64(r.Len())+2 {
+		return 0, fmt.Errorf("%w: transaction size is %d, have %d bytes", ErrShortData, totalSize, r.Len()+2)
+	}
+	fields := newBinReader(r.Bytes(int(totalSize) - 2))
 
-	for i := 0; i < int(paramCount); i++ {
-		if !scanner.Scan() {
-			return 0, fmt.Errorf("error scanning field: %w", scanner.Err())
-		}
+	// Each field is at least 4 bytes, so a count of more fields than fit in the transaction is rejected before reading
+	// any of them.
+	paramCount := int(binary.BigEndian.Uint16(t.ParamCount[:]))
+	if paramCount*minFieldLen > fields.Len() {
+		return 0, fmt.Errorf("%w: %d fields don't fit in %d bytes", ErrShortData, paramCount, fields.Len())
+	}
 
+	for i := 0; i < paramCount; i++ {
 		var field Field
-		if _, err := field.Write(scanner.Bytes()); err != nil {
+		if err := field.read(fields); err != nil {
 			return 0, fmt.Errorf("error reading field: %w", err)
 		}
 		t.Fields = append(t.Fields, field)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return 0, fmt.Errorf("scanner error: %w", err)
-	}
-
 	return len(p), nil
 }
 
 const tranHeaderLen = 20 // fixed length of transaction fields before the variable length fields
 
+// maxTransactionSize is the largest transaction read from a connection, including the header.  It's enough for a news
+// article with each of its flavors and attachments at the maximum field size.
+const maxTransactionSize = 1 << 20
+
+// ErrTransactionTooLarge is returned when a transaction's size exceeds maxTransactionSize.
+var ErrTransactionTooLarge = errors.New("transaction is too large")
+
 // transactionScanner implements bufio.SplitFunc for parsing incoming byte slices into complete tokens
 func transactionScanner(data []byte, _ bool) (advance int, token []byte, err error) {
 	// The bytes that contain the size of a transaction are from 12:16, so we need at least 16 bytes
@@ -211,9 +236,12 @@ func transactionScanner(data []byte, _ bool) (advance int, token []byte, err err
 	}
 
 	totalSize := binary.BigEndian.Uint32(data[12:16])
+	if totalSize > maxTransactionSize-tranHeaderLen {
+		return 0, nil, fmt.Errorf("%w: %d bytes", ErrTransactionTooLarge, totalSize)
+	}
 
 	// tranLen represents the length of bytes that are part of the transaction
-	tranLen := int(tranHeaderLen + totalSize)
+	tranLen := tranHeaderLen + int(totalSize)
 	if tranLen > len(data) {
 		return 0, nil, nil
 	}
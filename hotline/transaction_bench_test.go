@@ -0,0 +1,45 @@
+package hotline
+
+import (
+	"io"
+	"testing"
+)
+
+func benchmarkTransaction() Transaction {
+	return NewTransaction(
+		TranChatMsg, ClientID{0, 1},
+		NewField(FieldData, make([]byte, 200)),
+		NewField(FieldUserName, []byte("benchmark user")),
+		NewField(FieldUserID, []byte{0, 1}),
+		NewField(FieldChatOptions, []byte{0, 0}),
+	)
+}
+
+func BenchmarkTransaction_Read(b *testing.B) {
+	tran := benchmarkTransaction()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		t := tran
+		if _, err := io.Copy(io.Discard, &t); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTransaction_Write(b *testing.B) {
+	tran := benchmarkTransaction()
+	data, err := io.ReadAll(&tran)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var t Transaction
+		if _, err := t.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package hotline
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidField is wrapped by the errors returned by the typed field getters when a field is missing or malformed,
+// e.g. because of a buggy client.
+var ErrInvalidField = errors.New("invalid field")
+
+func invalidField(fieldType [2]byte, msg string) error {
+	return fmt.Errorf("%w %d: %s", ErrInvalidField, binary.BigEndian.Uint16(fieldType[:]), msg)
+}
+
+// requiredField returns the data of a field that must be present.  A present field may be empty.
+func (t *Transaction) requiredField(fieldType [2]byte) ([]byte, error) {
+	for _, field := range t.Fields {
+		if field.Type == fieldType {
+			return field.Data, nil
+		}
+	}
+
+	return nil, invalidField(fieldType, "missing")
+}
+
+// GetUint32 returns the value of a required integer field.  Like Field.DecodeInt, it accepts integers sent as either
+// 2 or 4 bytes.
+func (t *Transaction) GetUint32(fieldType [2]byte) (uint32, error) {
+	data, err := t.requiredField(fieldType)
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(data) {
+	case 2:
+		return uint32(binary.BigEndian.Uint16(data)), nil
+	case 4:
+		return binary.BigEndian.Uint32(data), nil
+	}
+
+	return 0, invalidField(fieldType, fmt.Sprintf("unexpected length %d for an integer", len(data)))
+}
+
+// GetUint16 returns the value of a required 2 byte integer field.  Values sent as 4 bytes are accepted if they fit.
+func (t *Transaction) GetUint16(fieldType [2]byte) (uint16, error) {
+	n, err := t.GetUint32(fieldType)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint16 {
+		return 0, invalidField(fieldType, fmt.Sprintf("value %d out of range", n))
+	}
+
+	return uint16(n), nil
+}
+
+// GetString returns the value of a required text field that is at most maxLen bytes long.  The text is returned as
+// sent, without converting it from Mac Roman.
+func (t *Transaction) GetString(fieldType [2]byte, maxLen int) (string, error) {
+	data, err := t.requiredField(fieldType)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxLen {
+		return "", invalidField(fieldType, fmt.Sprintf("length %d exceeds the limit of %d", len(data), maxLen))
+	}
+
+	return string(data), nil
+}
+
+// GetClientID returns the value of a required user ID field, such as FieldUserID.
+func (t *Transaction) GetClientID(fieldType [2]byte) (ClientID, error) {
+	id, err := t.GetUint16(fieldType)
+	if err != nil {
+		return ClientID{}, err
+	}
+
+	return ClientID(binary.BigEndian.AppendUint16(nil, id)), nil
+}
+
+// GetChatID returns the value of the required FieldChatID.
+func (t *Transaction) GetChatID() (ChatID, error) {
+	data, err := t.requiredField(FieldChatID)
+	if err != nil {
+		return ChatID{}, err
+	}
+	if len(data) != len(ChatID{}) {
+		return ChatID{}, invalidField(FieldChatID, fmt.Sprintf("unexpected length %d", len(data)))
+	}
+
+	return ChatID(data), nil
+}
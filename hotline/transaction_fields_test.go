@@ -0,0 +1,106 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction_GetUint16(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []Field
+		want    uint16
+		wantErr bool
+	}{
+		{name: "2 byte value", fields: []Field{NewField(FieldUserID, []byte{0x01, 0x02})}, want: 0x0102},
+		{name: "4 byte value that fits", fields: []Field{NewField(FieldUserID, []byte{0, 0, 0x01, 0x02})}, want: 0x0102},
+		{name: "4 byte value out of range", fields: []Field{NewField(FieldUserID, []byte{0, 1, 0, 0})}, wantErr: true},
+		{name: "short value", fields: []Field{NewField(FieldUserID, []byte{0x01})}, wantErr: true},
+		{name: "empty value", fields: []Field{NewField(FieldUserID, nil)}, wantErr: true},
+		{name: "missing field", fields: []Field{NewField(FieldData, []byte{0, 1})}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tran := &Transaction{Fields: tt.fields}
+
+			got, err := tran.GetUint16(FieldUserID)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidField)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTransaction_GetUint32(t *testing.T) {
+	tran := &Transaction{Fields: []Field{
+		NewField(FieldFileSize, []byte{0x01, 0x02, 0x03, 0x04}),
+		NewField(FieldRefNum, []byte{0x01, 0x02}),
+		NewField(FieldData, []byte{0x01, 0x02, 0x03}),
+	}}
+
+	got, err := tran.GetUint32(FieldFileSize)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x01020304), got)
+
+	got, err = tran.GetUint32(FieldRefNum)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x0102), got)
+
+	_, err = tran.GetUint32(FieldData)
+	assert.ErrorIs(t, err, ErrInvalidField)
+}
+
+func TestTransaction_GetString(t *testing.T) {
+	tran := &Transaction{Fields: []Field{
+		NewField(FieldFileName, []byte("readme.txt")),
+		NewField(FieldData, nil),
+	}}
+
+	got, err := tran.GetString(FieldFileName, 31)
+	require.NoError(t, err)
+	assert.Equal(t, "readme.txt", got)
+
+	_, err = tran.GetString(FieldFileName, 4)
+	assert.ErrorIs(t, err, ErrInvalidField)
+
+	got, err = tran.GetString(FieldData, 4)
+	require.NoError(t, err, "a present but empty field is valid")
+	assert.Equal(t, "", got)
+
+	_, err = tran.GetString(FieldUserName, 31)
+	assert.ErrorIs(t, err, ErrInvalidField)
+}
+
+func TestTransaction_GetClientID(t *testing.T) {
+	tran := &Transaction{Fields: []Field{NewField(FieldUserID, []byte{0, 0, 0, 5})}}
+
+	got, err := tran.GetClientID(FieldUserID)
+	require.NoError(t, err)
+	assert.Equal(t, ClientID{0, 5}, got)
+}
+
+func TestTransaction_GetChatID(t *testing.T) {
+	tran := &Transaction{Fields: []Field{NewField(FieldChatID, []byte{0, 0, 0, 1})}}
+	got, err := tran.GetChatID()
+	require.NoError(t, err)
+	assert.Equal(t, ChatID{0, 0, 0, 1}, got)
+
+	tran = &Transaction{Fields: []Field{NewField(FieldChatID, []byte{0, 1})}}
+	_, err = tran.GetChatID()
+	assert.ErrorIs(t, err, ErrInvalidField)
+
+	_, err = (&Transaction{}).GetChatID()
+	assert.ErrorIs(t, err, ErrInvalidField)
+}
+
+func TestField_DecodeNewsPath_short(t *testing.T) {
+	f := NewField(FieldNewsPath, []byte{0})
+
+	_, err := f.DecodeNewsPath()
+	assert.Error(t, err)
+}
@@ -3,9 +3,90 @@ package hotline
 // HandlerFunc is the signature of a func to handle a Hotline transaction.
 type HandlerFunc func(*ClientConn, *Transaction) []Transaction
 
-func (s *Server) HandleFunc(tranType [2]byte, handler HandlerFunc) {
+// HandleFunc registers the handler for a transaction type.  Options such as RequireAccess are checked in the order
+// given before the handler is called.
+func (s *Server) HandleFunc(tranType [2]byte, handler HandlerFunc, opts ...HandlerOption) {
+	var reg handlerRegistration
+	for _, opt := range opts {
+		opt(&reg)
+	}
+
+	if len(reg.access) > 0 {
+		next := handler
+		handler = func(cc *ClientConn, t *Transaction) []Transaction {
+			for _, req := range reg.access {
+				if !cc.Authorize(req.access) {
+					return cc.NewErrReply(t, req.errMsg)
+				}
+			}
+			return next(cc, t)
+		}
+	}
+
 	s.handlers[tranType] = handler
+	if s.handlerAccess == nil {
+		s.handlerAccess = make(map[TranType][]int)
+	}
+	s.handlerAccess[tranType] = reg.accessBits()
+}
+
+// Handler returns the handler registered for a transaction type, including its registration options.
+func (s *Server) Handler(tranType [2]byte) (HandlerFunc, bool) {
+	handler, ok := s.handlers[tranType]
+	return handler, ok
+}
+
+// RequiredAccess returns the access privileges that HandleFunc enforces for a transaction type before calling its
+// handler.  Handlers may make further checks that depend on the request.
+func (s *Server) RequiredAccess(tranType [2]byte) []int {
+	return s.handlerAccess[tranType]
+}
+
+// HandlerOption configures a handler registered with HandleFunc.
+type HandlerOption func(*handlerRegistration)
+
+type handlerRegistration struct {
+	access []accessRequirement
+}
+
+type accessRequirement struct {
+	access int
+	errMsg string
+}
+
+func (r handlerRegistration) accessBits() []int {
+	var bits []int
+	for _, req := range r.access {
+		bits = append(bits, req.access)
+	}
+	return bits
+}
+
+// RequireAccess rejects the transaction with errMsg unless the user has the access privilege.  errMsg is translated
+// like other error replies.
+func RequireAccess(access int, errMsg string) HandlerOption {
+	return func(r *handlerRegistration) {
+		r.access = append(r.access, accessRequirement{access: access, errMsg: errMsg})
+	}
+}
+
+// ChatCommandFunc is the signature of a func to handle a slash command sent in chat, e.g. "/report".
+// args contains the remainder of the chat message following the command name.
+type ChatCommandFunc func(cc *ClientConn, t *Transaction, args string) []Transaction
+
+// HandleChatCommand registers a handler for the chat command name (without the leading slash).
+func (s *Server) HandleChatCommand(name string, handler ChatCommandFunc) {
+	if s.chatCommands == nil {
+		s.chatCommands = make(map[string]ChatCommandFunc)
+	}
+	s.chatCommands[name] = handler
+}
+
+// ChatCommand returns the handler registered for the chat command name, if any.
+func (s *Server) ChatCommand(name string) (ChatCommandFunc, bool) {
+	handler, ok := s.chatCommands[name]
+	return handler, ok
 }
 
-// The total size of a chat message data field is 8192 bytes.
+// LimitChatMsg is the default maximum size of a chat message data field, in bytes.  See Server.MaxChatMsgLen.
 const LimitChatMsg = 8192
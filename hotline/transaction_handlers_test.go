@@ -0,0 +1,40 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_HandleFunc_RequireAccess(t *testing.T) {
+	srv, err := NewServer()
+	assert.NoError(t, err)
+
+	var called bool
+	srv.HandleFunc(TranUserBroadcast, func(cc *ClientConn, t *Transaction) []Transaction {
+		called = true
+		return []Transaction{cc.NewReply(t)}
+	},
+		RequireAccess(AccessSendChat, "You are not allowed to participate in chat."),
+		RequireAccess(AccessBroadcast, "You are not allowed to send broadcast messages."),
+	)
+
+	assert.Equal(t, []int{AccessSendChat, AccessBroadcast}, srv.RequiredAccess(TranUserBroadcast))
+	assert.Empty(t, srv.RequiredAccess(TranChatSend))
+
+	handler, ok := srv.Handler(TranUserBroadcast)
+	assert.True(t, ok)
+
+	var access AccessBitmap
+	access.Set(AccessSendChat)
+	cc := &ClientConn{Server: srv, Account: &Account{Access: access}}
+
+	res := handler(cc, &Transaction{})
+	assert.False(t, called)
+	assert.Equal(t, []byte("You are not allowed to send broadcast messages."), res[0].GetField(FieldError).Data)
+
+	cc.Account.Access.Set(AccessBroadcast)
+	res = handler(cc, &Transaction{})
+	assert.True(t, called)
+	assert.Equal(t, [4]byte{}, res[0].ErrorCode)
+}
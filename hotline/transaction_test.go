@@ -1,6 +1,7 @@
 package hotline
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -380,3 +381,58 @@ func TestTransaction_Write(t1 *testing.T) {
 		})
 	}
 }
+
+func FuzzTransaction_Write(f *testing.F) {
+	f.Add([]byte{
+		0x00, 0x00, 0x00, 0x69, 0x00, 0x00, 0x15, 0x72,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09,
+		0x00, 0x00, 0x00, 0x09, 0x00, 0x01, 0x00, 0x65,
+		0x00, 0x03, 0x68, 0x61, 0x69,
+	})
+	f.Add([]byte{
+		0x00, 0x00, 0x00, 0x69, 0x00, 0x00, 0x15, 0x72,
+		0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	})
+
+	f.Fuzz(func(t *testing.T, p []byte) {
+		var tran Transaction
+		n, err := tran.Write(p)
+		if err != nil {
+			return
+		}
+		assert.LessOrEqual(t, n, len(p))
+		for _, field := range tran.Fields {
+			assert.Len(t, field.Data, int(binary.BigEndian.Uint16(field.FieldSize[:])))
+		}
+	})
+}
+
+func FuzzTransactionScanner(f *testing.F) {
+	f.Add([]byte{
+		0x00, 0x00, 0x00, 0x69, 0x00, 0x00, 0x15, 0x72,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09,
+		0x00, 0x00, 0x00, 0x09, 0x00, 0x01, 0x00, 0x65,
+		0x00, 0x03, 0x68, 0x61, 0x69,
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		advance, token, err := transactionScanner(data, false)
+		if err != nil {
+			return
+		}
+		assert.LessOrEqual(t, advance, len(data))
+		assert.LessOrEqual(t, len(token), maxTransactionSize)
+	})
+}
+
+func Test_transactionScanner_TooLarge(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, 0x69, 0x00, 0x00, 0x15, 0x72,
+		0x00, 0x00, 0x00, 0x00, 0x7f, 0xff, 0xff, 0xff,
+		0x7f, 0xff, 0xff, 0xff, 0x00, 0x01,
+	}
+
+	_, _, err := transactionScanner(data, false)
+	assert.ErrorIs(t, err, ErrTransactionTooLarge)
+}
@@ -2,9 +2,11 @@ package hotline
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"golang.org/x/time/rate"
 	"io"
 )
 
@@ -56,3 +58,57 @@ func receiveFile(r io.Reader, targetFile, resForkFile, infoFork, counterWriter i
 	}
 	return nil
 }
+
+// transferRateLimits returns the bandwidth limits in bytes per second of each of cc's download and upload connections,
+// from the MaxDownloadKBps and MaxUploadKBps of its account or else of the server.  0 means no limit.
+func (s *Server) transferRateLimits(cc *ClientConn) (download, upload int) {
+	download, upload = s.Config.MaxDownloadKBps, s.Config.MaxUploadKBps
+	if cc.Account != nil {
+		if cc.Account.MaxDownloadKBps != 0 {
+			download = cc.Account.MaxDownloadKBps
+		}
+		if cc.Account.MaxUploadKBps != 0 {
+			upload = cc.Account.MaxUploadKBps
+		}
+	}
+
+	return max(download, 0) * 1024, max(upload, 0) * 1024
+}
+
+// transferRateLimit limits the bandwidth of a file transfer connection with a token bucket for each direction: reads
+// are data uploaded by the client, and writes are data downloaded by the client.
+type transferRateLimit struct {
+	rw       io.ReadWriter
+	upload   *throttledRW // nil for no limit
+	download *throttledRW // nil for no limit
+}
+
+// limitTransferRate returns rw limited to download bytes per second of writes and upload bytes per second of reads.
+// 0 means no limit.
+func limitTransferRate(ctx context.Context, rw io.ReadWriter, download, upload int) io.ReadWriter {
+	t := &transferRateLimit{rw: rw}
+	if upload > 0 {
+		t.upload = &throttledRW{ctx: ctx, rw: rw, limiter: rate.NewLimiter(rate.Limit(upload), transferBurstSize)}
+	}
+	if download > 0 {
+		t.download = &throttledRW{ctx: ctx, rw: rw, limiter: rate.NewLimiter(rate.Limit(download), transferBurstSize)}
+	}
+
+	return t
+}
+
+func (t *transferRateLimit) Read(p []byte) (int, error) {
+	if t.upload == nil {
+		return t.rw.Read(p)
+	}
+
+	return t.upload.Read(p)
+}
+
+func (t *transferRateLimit) Write(p []byte) (int, error) {
+	if t.download == nil {
+		return t.rw.Write(p)
+	}
+
+	return t.download.Write(p)
+}
@@ -0,0 +1,218 @@
+package hotline
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// Transfer stream encryption
+//
+// Clients that support encrypted file transfers advertise it by setting the reserved field of the 16 byte transfer
+// header to "MXE2", followed immediately by a 32 byte ephemeral X25519 public key.  Classic clients send zeros in the
+// reserved field and never see any of the following.
+//
+// The server replies with 4 bytes:
+//
+//	"MXE2" followed by its own 32 byte ephemeral X25519 public key if encryption is accepted, or
+//	0x00000000 if the server has transfer encryption disabled, in which case the transfer continues unencrypted.
+//
+// Once both keys are exchanged, a key for each direction of the stream is derived from the X25519 shared secret with
+// HKDF-SHA256, salted with the transfer reference number and both public keys.  Each direction is then sent as a
+// sequence of records:
+//
+//	2 byte length of the sealed record
+//	AES-256-GCM sealed data of at most 16 KiB, with a 12 byte nonce of 4 zero bytes and an 8 byte record counter
+//
+// Records that are modified, reordered, replayed, or dropped fail authentication and end the transfer.  Like the rest
+// of the Hotline protocol, the key exchange does not authenticate the server, so it protects transfers from passive
+// observers and from tampering, but not from an active attacker who intercepts the connection from the start.
+
+// transferEncryptionV2 is the value of the transfer header reserved field used to request an encrypted stream.
+var transferEncryptionV2 = [4]byte{'M', 'X', 'E', '2'}
+
+const (
+	transferKeySize       = 32
+	transferRecordMaxData = 16 * 1024
+)
+
+// acceptTransferEncryption completes the server side of the transfer encryption negotiation for a client that has
+// requested it for the transfer with refNum.  If enabled is false, the request is declined and rw is returned
+// unchanged.
+func acceptTransferEncryption(rw io.ReadWriter, refNum [4]byte, enabled bool) (io.ReadWriter, error) {
+	clientKey := make([]byte, transferKeySize)
+	if _, err := io.ReadFull(rw, clientKey); err != nil {
+		return nil, fmt.Errorf("read client key: %w", err)
+	}
+
+	if !enabled {
+		if _, err := rw.Write([]byte{0, 0, 0, 0}); err != nil {
+			return nil, err
+		}
+		return rw, nil
+	}
+
+	privKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := rw.Write(append(transferEncryptionV2[:], privKey.PublicKey().Bytes()...)); err != nil {
+		return nil, err
+	}
+
+	return newEncryptedStream(rw, refNum, privKey, clientKey, false)
+}
+
+// RequestTransferEncryption performs the client side of the transfer encryption negotiation.  The caller must have
+// just sent the transfer header for refNum with the reserved field set to "MXE2".  If the server declines, rw is
+// returned unchanged and the transfer continues unencrypted.
+func RequestTransferEncryption(rw io.ReadWriter, refNum [4]byte) (io.ReadWriter, error) {
+	privKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := rw.Write(privKey.PublicKey().Bytes()); err != nil {
+		return nil, err
+	}
+
+	var reply [4]byte
+	if _, err := io.ReadFull(rw, reply[:]); err != nil {
+		return nil, fmt.Errorf("read server reply: %w", err)
+	}
+
+	switch reply {
+	case [4]byte{}:
+		return rw, nil
+	case transferEncryptionV2:
+	default:
+		return nil, errors.New("invalid transfer encryption reply")
+	}
+
+	serverKey := make([]byte, transferKeySize)
+	if _, err := io.ReadFull(rw, serverKey); err != nil {
+		return nil, fmt.Errorf("read server key: %w", err)
+	}
+
+	return newEncryptedStream(rw, refNum, privKey, serverKey, true)
+}
+
+// newEncryptedStream derives the stream keys from the local private key and the peer's public key and returns rw
+// wrapped with authenticated encryption in both directions.
+func newEncryptedStream(rw io.ReadWriter, refNum [4]byte, privKey *ecdh.PrivateKey, peerKey []byte, isClient bool) (io.ReadWriter, error) {
+	pubKey, err := ecdh.X25519().NewPublicKey(peerKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer key: %w", err)
+	}
+
+	secret, err := privKey.ECDH(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientKey, serverKey := peerKey, privKey.PublicKey().Bytes()
+	if isClient {
+		clientKey, serverKey = serverKey, clientKey
+	}
+	salt := append(append(append([]byte{}, refNum[:]...), clientKey...), serverKey...)
+
+	toServer, err := transferRecordCipher(secret, salt, "mobius transfer client to server")
+	if err != nil {
+		return nil, err
+	}
+	toClient, err := transferRecordCipher(secret, salt, "mobius transfer server to client")
+	if err != nil {
+		return nil, err
+	}
+
+	if isClient {
+		return &encryptedStream{rw: rw, r: toClient, w: toServer}, nil
+	}
+	return &encryptedStream{rw: rw, r: toServer, w: toClient}, nil
+}
+
+func transferRecordCipher(secret, salt []byte, info string) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptedStream is an io.ReadWriter that opens records read from and seals records written to rw.
+type encryptedStream struct {
+	rw io.ReadWriter
+	r  cipher.AEAD
+	w  cipher.AEAD
+
+	rSeq uint64
+	wSeq uint64
+	buf  []byte // decrypted data of the last record not yet read
+}
+
+func recordNonce(seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+
+	return nonce
+}
+
+func (s *encryptedStream) Read(p []byte) (int, error) {
+	if len(s.buf) == 0 {
+		var size [2]byte
+		if _, err := io.ReadFull(s.rw, size[:]); err != nil {
+			return 0, err
+		}
+
+		record := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(s.rw, record); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		data, err := s.r.Open(record[:0], recordNonce(s.rSeq), record, nil)
+		if err != nil {
+			return 0, errors.New("transfer record failed authentication")
+		}
+		s.rSeq++
+		s.buf = data
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+
+	return n, nil
+}
+
+func (s *encryptedStream) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		data := p[:min(len(p), transferRecordMaxData)]
+
+		record := make([]byte, 2, 2+len(data)+s.w.Overhead())
+		record = s.w.Seal(record, recordNonce(s.wSeq), data, nil)
+		binary.BigEndian.PutUint16(record, uint16(len(record)-2))
+		s.wSeq++
+
+		if _, err := s.rw.Write(record); err != nil {
+			return written, err
+		}
+		written += len(data)
+		p = p[len(data):]
+	}
+
+	return written, nil
+}
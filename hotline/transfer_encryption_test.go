@@ -0,0 +1,166 @@
+package hotline
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"testing"
+)
+
+// recordingConn records the raw bytes written by the client side of a connection.
+type recordingConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.written.Write(p)
+	return c.Conn.Write(p)
+}
+
+func TestTransferEncryption(t *testing.T) {
+	tests := []struct {
+		name          string
+		enabled       bool
+		wantEncrypted bool
+	}{
+		{name: "when enabled on the server", enabled: true, wantEncrypted: true},
+		{name: "when disabled on the server", enabled: false, wantEncrypted: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			client := &recordingConn{Conn: clientConn}
+			msg := []byte("the quick brown fox jumps over the lazy dog")
+
+			errs := make(chan error, 1)
+			go func() {
+				rw, err := acceptTransferEncryption(serverConn, [4]byte{0, 0, 0, 1}, tt.enabled)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				buf := make([]byte, len(msg))
+				if _, err := io.ReadFull(rw, buf); err != nil {
+					errs <- err
+					return
+				}
+
+				// Echo the message back to the client.
+				_, err = rw.Write(buf)
+				errs <- err
+			}()
+
+			rw, err := RequestTransferEncryption(client, [4]byte{0, 0, 0, 1})
+			require.NoError(t, err)
+
+			client.written.Reset()
+			_, err = rw.Write(msg)
+			require.NoError(t, err)
+
+			got := make([]byte, len(msg))
+			_, err = io.ReadFull(rw, got)
+			require.NoError(t, err)
+			require.NoError(t, <-errs)
+
+			assert.Equal(t, msg, got)
+			assert.Equal(t, tt.wantEncrypted, !bytes.Equal(msg, client.written.Bytes()))
+		})
+	}
+}
+
+// tamperConn flips a bit of the data written to it after the key exchange.
+type tamperConn struct {
+	net.Conn
+	writes int
+}
+
+func (c *tamperConn) Write(p []byte) (int, error) {
+	c.writes++
+	if c.writes > 1 {
+		p = append([]byte{}, p...)
+		p[len(p)-1] ^= 1
+	}
+	return c.Conn.Write(p)
+}
+
+func TestTransferEncryption_authenticated(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverRefNum [4]byte
+		tamper       bool
+	}{
+		{name: "modified records", serverRefNum: [4]byte{0, 0, 0, 1}, tamper: true},
+		{name: "different transfer", serverRefNum: [4]byte{0, 0, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			var client net.Conn = clientConn
+			if tt.tamper {
+				client = &tamperConn{Conn: clientConn}
+			}
+
+			errs := make(chan error, 1)
+			go func() {
+				rw, err := acceptTransferEncryption(serverConn, tt.serverRefNum, true)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				_, err = io.ReadFull(rw, make([]byte, 5))
+				errs <- err
+			}()
+
+			rw, err := RequestTransferEncryption(client, [4]byte{0, 0, 0, 1})
+			require.NoError(t, err)
+
+			_, err = rw.Write([]byte("hello"))
+			require.NoError(t, err)
+
+			assert.EqualError(t, <-errs, "transfer record failed authentication")
+		})
+	}
+}
+
+func TestEncryptedStream_largeWrites(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	msg := bytes.Repeat([]byte("0123456789"), transferRecordMaxData/4)
+
+	errs := make(chan error, 1)
+	got := make([]byte, len(msg))
+	go func() {
+		rw, err := acceptTransferEncryption(serverConn, [4]byte{}, true)
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		_, err = io.ReadFull(rw, got)
+		errs <- err
+	}()
+
+	rw, err := RequestTransferEncryption(clientConn, [4]byte{})
+	require.NoError(t, err)
+
+	// Data larger than a record is split into several records.
+	n, err := rw.Write(msg)
+	require.NoError(t, err)
+	assert.Equal(t, len(msg), n)
+
+	require.NoError(t, <-errs)
+	assert.Equal(t, msg, got)
+}
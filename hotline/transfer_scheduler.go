@@ -0,0 +1,131 @@
+package hotline
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"io"
+	"sync"
+)
+
+// TransferPriority is the scheduling class of a file transfer.
+type TransferPriority int
+
+const (
+	PriorityNormal TransferPriority = iota
+	PriorityHigh
+)
+
+const (
+	DefaultPriorityWeight = 4         // default bandwidth share of a high priority transfer relative to a normal one
+	transferBurstSize     = 32 * 1024 // max bytes read or written per rate limiter wait
+)
+
+// TransferScheduler divides a server-wide file transfer bandwidth limit between active transfers.  Each transfer's
+// share is proportional to the weight of its priority class, so that when the server is saturated high priority
+// transfers receive PriorityWeight times the bandwidth of normal transfers.  Shares are recalculated whenever a
+// transfer starts or finishes.
+type TransferScheduler struct {
+	limit          int // total bytes per second
+	priorityWeight int
+
+	mu     sync.Mutex
+	active map[*throttledRW]TransferPriority
+}
+
+// NewTransferScheduler returns a scheduler limiting total transfer bandwidth to limit bytes per second.
+func NewTransferScheduler(limit, priorityWeight int) *TransferScheduler {
+	if priorityWeight <= 0 {
+		priorityWeight = DefaultPriorityWeight
+	}
+
+	return &TransferScheduler{
+		limit:          limit,
+		priorityWeight: priorityWeight,
+		active:         make(map[*throttledRW]TransferPriority),
+	}
+}
+
+func (ts *TransferScheduler) weight(p TransferPriority) int {
+	if p == PriorityHigh {
+		return ts.priorityWeight
+	}
+	return 1
+}
+
+// rebalance recalculates the rate of each active transfer.  The caller must hold mu.
+func (ts *TransferScheduler) rebalance() {
+	var totalWeight int
+	for _, p := range ts.active {
+		totalWeight += ts.weight(p)
+	}
+
+	for rw, p := range ts.active {
+		rw.limiter.SetLimit(rate.Limit(ts.limit * ts.weight(p) / totalWeight))
+	}
+}
+
+// Wrap returns a rate limited wrapper around rwc for a transfer of the given priority.  The returned func must be
+// called when the transfer is complete to release its share of the bandwidth.
+func (ts *TransferScheduler) Wrap(ctx context.Context, rwc io.ReadWriter, priority TransferPriority) (io.ReadWriter, func()) {
+	rw := &throttledRW{
+		ctx:     ctx,
+		rw:      rwc,
+		limiter: rate.NewLimiter(rate.Limit(ts.limit), transferBurstSize),
+	}
+
+	ts.mu.Lock()
+	ts.active[rw] = priority
+	ts.rebalance()
+	ts.mu.Unlock()
+
+	return rw, func() {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+
+		delete(ts.active, rw)
+		ts.rebalance()
+	}
+}
+
+// throttledRW is an io.ReadWriter that waits on a rate limiter before each read and write.
+type throttledRW struct {
+	ctx     context.Context
+	rw      io.ReadWriter
+	limiter *rate.Limiter
+}
+
+func (t *throttledRW) Read(p []byte) (int, error) {
+	if len(p) > transferBurstSize {
+		p = p[:transferBurstSize]
+	}
+
+	n, err := t.rw.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.WaitN(t.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+func (t *throttledRW) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), transferBurstSize)]
+
+		if err := t.limiter.WaitN(t.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := t.rw.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
@@ -0,0 +1,49 @@
+package hotline
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"testing"
+)
+
+func TestTransferScheduler(t *testing.T) {
+	ts := NewTransferScheduler(500*1024, 4)
+
+	normal1, doneNormal1 := ts.Wrap(context.Background(), &bytes.Buffer{}, PriorityNormal)
+	assert.Equal(t, rate.Limit(500*1024), normal1.(*throttledRW).limiter.Limit())
+
+	normal2, doneNormal2 := ts.Wrap(context.Background(), &bytes.Buffer{}, PriorityNormal)
+	high, doneHigh := ts.Wrap(context.Background(), &bytes.Buffer{}, PriorityHigh)
+
+	// Weights are 1 + 1 + 4.
+	assert.Equal(t, rate.Limit(500*1024/6), normal1.(*throttledRW).limiter.Limit())
+	assert.Equal(t, rate.Limit(500*1024/6), normal2.(*throttledRW).limiter.Limit())
+	assert.Equal(t, rate.Limit(500*1024*4/6), high.(*throttledRW).limiter.Limit())
+
+	doneNormal1()
+	doneNormal2()
+	assert.Equal(t, rate.Limit(500*1024), high.(*throttledRW).limiter.Limit())
+
+	doneHigh()
+	assert.Empty(t, ts.active)
+}
+
+func TestThrottledRW(t *testing.T) {
+	ts := NewTransferScheduler(10*1024*1024, 4)
+
+	var buf bytes.Buffer
+	rw, done := ts.Wrap(context.Background(), &buf, PriorityNormal)
+	defer done()
+
+	data := bytes.Repeat([]byte("a"), transferBurstSize*2+10)
+	n, err := rw.Write(data)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+
+	got := make([]byte, len(data))
+	n, err = rw.Read(got)
+	assert.NoError(t, err)
+	assert.Equal(t, transferBurstSize, n)
+}
@@ -2,8 +2,10 @@ package hotline
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
 	"io"
 	"testing"
 )
@@ -195,3 +197,59 @@ func Test_receiveFile(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_transferRateLimits(t *testing.T) {
+	s := &Server{Config: Config{MaxDownloadKBps: 100, MaxUploadKBps: 50}}
+
+	tests := []struct {
+		name         string
+		account      *Account
+		wantDownload int
+		wantUpload   int
+	}{
+		{
+			name:         "server limits",
+			account:      &Account{},
+			wantDownload: 100 * 1024,
+			wantUpload:   50 * 1024,
+		},
+		{
+			name:         "account overrides",
+			account:      &Account{MaxDownloadKBps: 500},
+			wantDownload: 500 * 1024,
+			wantUpload:   50 * 1024,
+		},
+		{
+			name:         "account unlimited",
+			account:      &Account{MaxDownloadKBps: -1, MaxUploadKBps: -1},
+			wantDownload: 0,
+			wantUpload:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			download, upload := s.transferRateLimits(&ClientConn{Account: tt.account})
+			assert.Equal(t, tt.wantDownload, download)
+			assert.Equal(t, tt.wantUpload, upload)
+		})
+	}
+}
+
+func TestLimitTransferRate(t *testing.T) {
+	var buf bytes.Buffer
+	rw := limitTransferRate(context.Background(), &buf, 100*1024, 0).(*transferRateLimit)
+	assert.Equal(t, rate.Limit(100*1024), rw.download.limiter.Limit())
+	assert.Nil(t, rw.upload)
+
+	data := bytes.Repeat([]byte("a"), transferBurstSize*2+10)
+	n, err := rw.Write(data)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+
+	// Reads are unlimited, so they aren't split into bursts.
+	got := make([]byte, len(data))
+	n, err = rw.Read(got)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, data, got)
+}
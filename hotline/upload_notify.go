@@ -0,0 +1,24 @@
+package hotline
+
+import "fmt"
+
+// notifyUploadResult sends the uploading client a server message once the server has finished saving and processing
+// an upload, or when the upload fails, so that the user doesn't have to infer the result from the transfer closing.
+// Nothing is sent unless NotifyUploads is enabled.
+func (s *Server) notifyUploadResult(ft *FileTransfer, uploadErr error) {
+	if !s.Config.NotifyUploads || ft.ClientConn == nil {
+		return
+	}
+
+	msg := fmt.Sprintf(ft.ClientConn.T("Your upload of \"%s\" is complete."), ft.FileName)
+	if uploadErr != nil {
+		msg = fmt.Sprintf(ft.ClientConn.T("Your upload of \"%s\" failed.  Please try again."), ft.FileName)
+	}
+
+	s.outbox <- NewTransaction(
+		TranServerMsg,
+		ft.ClientConn.ID,
+		NewField(FieldData, []byte(msg)),
+		NewField(FieldChatOptions, []byte{0, 0}),
+	)
+}
@@ -0,0 +1,62 @@
+package hotline
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestServer_notifyUploadResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		uploadErr error
+		want      []Transaction
+	}{
+		{
+			name:    "disabled",
+			enabled: false,
+		},
+		{
+			name:    "upload complete",
+			enabled: true,
+			want: []Transaction{
+				NewTransaction(TranServerMsg, ClientID{0, 1},
+					NewField(FieldData, []byte(`Your upload of "file.txt" is complete.`)),
+					NewField(FieldChatOptions, []byte{0, 0}),
+				),
+			},
+		},
+		{
+			name:      "upload failed",
+			enabled:   true,
+			uploadErr: errors.New("receive file: unexpected EOF"),
+			want: []Transaction{
+				NewTransaction(TranServerMsg, ClientID{0, 1},
+					NewField(FieldData, []byte(`Your upload of "file.txt" failed.  Please try again.`)),
+					NewField(FieldChatOptions, []byte{0, 0}),
+				),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Config: Config{NotifyUploads: tt.enabled},
+				outbox: make(chan Transaction, 1),
+			}
+			ft := &FileTransfer{
+				FileName:   []byte("file.txt"),
+				ClientConn: &ClientConn{ID: ClientID{0, 1}},
+			}
+
+			s.notifyUploadResult(ft, tt.uploadErr)
+			close(s.outbox)
+
+			var got []Transaction
+			for t := range s.outbox {
+				got = append(got, t)
+			}
+			TranAssertEqual(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package hotline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUploadRejected is returned by an UploadScanner for uploads that must not be saved, e.g. because they are infected.
+var ErrUploadRejected = errors.New("upload rejected by scanner")
+
+// UploadInfo describes a completed upload to be scanned.
+type UploadInfo struct {
+	Path  string // Path of the uploaded data, which is still an .incomplete file during the scan
+	Dest  string // Path the file is saved to if it passes the scan
+	Login string // Login of the uploader
+	Name  string // Name of the uploader
+}
+
+// UploadScanner checks uploaded files, e.g. for viruses, before they are saved.
+type UploadScanner interface {
+	// ScanUpload returns an error wrapping ErrUploadRejected if the upload must be deleted, or another error if the
+	// upload couldn't be scanned.
+	ScanUpload(ctx context.Context, upload UploadInfo) error
+}
+
+// scansFolder returns true if uploads to folder, a path relative to the file root, are scanned.  A folder is scanned
+// if it or any of its parents is listed in the UploadScan Folders config, or if no folders are listed.
+func (s *Server) scansFolder(folder string) bool {
+	if len(s.Config.UploadScan.Folders) == 0 {
+		return true
+	}
+
+	folder = strings.ToLower(strings.Trim(filepath.ToSlash(folder), "/"))
+	for _, f := range s.Config.UploadScan.Folders {
+		f = strings.ToLower(strings.Trim(filepath.ToSlash(f), "/"))
+		if folder == f || f == "" || strings.HasPrefix(folder, f+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanUpload passes the .incomplete file of a completed upload to fullPath to the server's UploadScanner, if uploads
+// to its folder are scanned.  Rejected uploads are deleted along with their forks.
+func scanUpload(fileStore FileStore, fullPath string, fileTransfer *FileTransfer, logger *slog.Logger) error {
+	cc := fileTransfer.ClientConn
+	if cc == nil || cc.Server == nil || cc.Server.UploadScanner == nil {
+		return nil
+	}
+
+	s := cc.Server
+	folder, err := filepath.Rel(fileTransfer.FileRoot, filepath.Dir(fullPath))
+	if err != nil || !s.scansFolder(folder) {
+		return nil
+	}
+
+	upload := UploadInfo{Path: fullPath + IncompleteFileSuffix, Dest: fullPath, Name: string(cc.UserName)}
+	if cc.Account != nil {
+		upload.Login = cc.Account.Login
+	}
+
+	err = s.UploadScanner.ScanUpload(context.Background(), upload)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrUploadRejected) {
+		return fmt.Errorf("scan upload: %w", err)
+	}
+
+	logger.Warn("Deleting upload rejected by scanner", "path", fullPath, "err", err)
+
+	dir, name := filepath.Split(fullPath)
+	for _, p := range []string{
+		upload.Path,
+		filepath.Join(dir, fmt.Sprintf(RsrcForkNameTemplate, name)),
+		filepath.Join(dir, fmt.Sprintf(InfoForkNameTemplate, name)),
+	} {
+		if err := fileStore.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Error("Error deleting rejected upload", "path", p, "err", err)
+		}
+	}
+
+	return err
+}
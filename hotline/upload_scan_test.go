@@ -0,0 +1,143 @@
+package hotline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUploadScanner struct {
+	scanned []UploadInfo
+	err     error
+}
+
+func (ts *testUploadScanner) ScanUpload(_ context.Context, upload UploadInfo) error {
+	ts.scanned = append(ts.scanned, upload)
+	return ts.err
+}
+
+func TestServer_scansFolder(t *testing.T) {
+	s := &Server{}
+	assert.True(t, s.scansFolder("Anything"))
+
+	s.Config.UploadScan.Folders = []string{"Uploads", "/Public/Games/"}
+	tests := []struct {
+		folder string
+		want   bool
+	}{
+		{"Uploads", true},
+		{"uploads/Sub Folder", true},
+		{"Public/Games/Arcade", true},
+		{"Public", false},
+		{"Uploads Archive", false},
+		{".", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.scansFolder(tt.folder))
+		})
+	}
+}
+
+func TestUploadHandler_Scan(t *testing.T) {
+	tests := []struct {
+		name        string
+		scanErr     error
+		wantErr     bool
+		wantSaved   bool
+		wantPartial bool
+	}{
+		{name: "clean file is saved", wantSaved: true},
+		{name: "rejected file is deleted", scanErr: fmt.Errorf("%w: Eicar-Signature FOUND", ErrUploadRejected), wantErr: true},
+		{name: "scanner error keeps the partial file", scanErr: fmt.Errorf("clamd not running"), wantErr: true, wantPartial: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "file.txt")
+
+			scanner := &testUploadScanner{err: tt.scanErr}
+			ft := &FileTransfer{
+				FileRoot: dir,
+				ClientConn: &ClientConn{
+					Account:  &Account{Login: "alice"},
+					UserName: []byte("Alice"),
+					Server:   &Server{UploadScanner: scanner},
+				},
+				bytesSentCounter: &WriteCounter{},
+			}
+			input := bytes.NewBuffer(flatFile(t, "data"))
+			err := UploadHandler(&readWriteBuffer{input: input, output: &bytes.Buffer{}}, path, ft, &OSFileStore{}, NewTestLogger(), false)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			require.Len(t, scanner.scanned, 1)
+			assert.Equal(t, UploadInfo{Path: path + IncompleteFileSuffix, Dest: path, Login: "alice", Name: "Alice"}, scanner.scanned[0])
+
+			if tt.wantSaved {
+				assert.FileExists(t, path)
+			} else {
+				assert.NoFileExists(t, path)
+			}
+
+			if tt.wantPartial {
+				assert.FileExists(t, path+IncompleteFileSuffix)
+			} else {
+				assert.NoFileExists(t, path+IncompleteFileSuffix)
+			}
+		})
+	}
+}
+
+func TestUploadFolderHandler_ScanRejectsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	var input bytes.Buffer
+	input.Write(folderUploadItem("bad.exe"))
+	input.Write([]byte{0, 0, 0, 4}) // File size
+	input.Write(flatFile(t, "evil"))
+	input.Write(folderUploadItem("good.txt"))
+	input.Write([]byte{0, 0, 0, 4})
+	input.Write(flatFile(t, "good"))
+
+	scanner := &testUploadScanner{}
+	ft := &FileTransfer{
+		FileRoot: dir,
+		ClientConn: &ClientConn{
+			Account: &Account{Login: "alice"},
+			Server:  &Server{UploadScanner: rejectScanner{scanner, "bad.exe"}},
+		},
+		FolderItemCount:  []byte{0, 2},
+		bytesSentCounter: &WriteCounter{},
+	}
+	err := UploadFolderHandler(&readWriteBuffer{input: &input, output: &bytes.Buffer{}}, dir, ft, &OSFileStore{}, NewTestLogger(), false)
+	require.NoError(t, err)
+
+	// The rejected file is deleted, and the rest of the folder is still uploaded.
+	assert.Len(t, scanner.scanned, 2)
+	assert.NoFileExists(t, filepath.Join(dir, "bad.exe"))
+	assert.NoFileExists(t, filepath.Join(dir, "bad.exe"+IncompleteFileSuffix))
+	assert.FileExists(t, filepath.Join(dir, "good.txt"))
+}
+
+// rejectScanner rejects uploads saved as name.
+type rejectScanner struct {
+	*testUploadScanner
+	name string
+}
+
+func (rs rejectScanner) ScanUpload(ctx context.Context, upload UploadInfo) error {
+	_ = rs.testUploadScanner.ScanUpload(ctx, upload)
+	if filepath.Base(upload.Dest) == rs.name {
+		return ErrUploadRejected
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package hotline
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUsageReportTopFiles is the number of most downloaded files listed in usage reports by default.
+const DefaultUsageReportTopFiles = 10
+
+// WeeklyUsage is server activity during a week.  It is saved across restarts so that weekly usage reports cover the
+// whole week.
+type WeeklyUsage struct {
+	Week        string         `yaml:"Week" json:"week"`               // ISO week in YYYY-Www format
+	Logins      map[string]int `yaml:"Logins" json:"-"`                // Number of connections by account login
+	Connections int            `yaml:"Connections" json:"connections"` // Number of connections
+	PeakUsers   int            `yaml:"PeakUsers" json:"peakUsers"`     // Most users connected at once
+	Uploads     int            `yaml:"Uploads" json:"uploads"`
+	Downloads   int            `yaml:"Downloads" json:"downloads"`
+	Files       map[string]int `yaml:"Files" json:"-"` // Number of downloads by file path
+}
+
+// FileDownloads is the number of times a file was downloaded.
+type FileDownloads struct {
+	Path      string `json:"path"`
+	Downloads int    `json:"downloads"`
+}
+
+// usageWeek returns the ISO week of t in YYYY-Www format.
+func usageWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Accounts returns the number of different accounts that connected.
+func (u WeeklyUsage) Accounts() int {
+	return len(u.Logins)
+}
+
+// TopFiles returns up to n of the most downloaded files, most downloaded first.
+func (u WeeklyUsage) TopFiles(n int) []FileDownloads {
+	var files []FileDownloads
+	for path, count := range u.Files {
+		files = append(files, FileDownloads{Path: path, Downloads: count})
+	}
+	slices.SortFunc(files, func(a, b FileDownloads) int {
+		return cmp.Or(cmp.Compare(b.Downloads, a.Downloads), strings.Compare(a.Path, b.Path))
+	})
+
+	return files[:min(len(files), n)]
+}
+
+// Report returns the title and body of a usage report news article for the week.
+func (u WeeklyUsage) Report(topFiles int) (title, body string) {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "Accounts: %d\r", u.Accounts())
+	_, _ = fmt.Fprintf(&sb, "Connections: %d\r", u.Connections)
+	_, _ = fmt.Fprintf(&sb, "Peak users: %d\r", u.PeakUsers)
+	_, _ = fmt.Fprintf(&sb, "Uploads: %d\r", u.Uploads)
+	_, _ = fmt.Fprintf(&sb, "Downloads: %d\r", u.Downloads)
+
+	if files := u.TopFiles(topFiles); len(files) > 0 {
+		sb.WriteString("\rTop downloads:\r")
+		for _, f := range files {
+			_, _ = fmt.Fprintf(&sb, "  %s (%d)\r", f.Path, f.Downloads)
+		}
+	}
+
+	return "Usage report for " + u.Week, sb.String()
+}
+
+// UsageMeter collects server activity for weekly usage reports.
+//
+// A nil *UsageMeter is valid and records nothing.
+type UsageMeter struct {
+	mu    sync.Mutex
+	usage WeeklyUsage
+}
+
+// NewUsageMeter returns a meter that resumes collecting from usage, such as a value saved from a previous run.
+func NewUsageMeter(usage WeeklyUsage) *UsageMeter {
+	return &UsageMeter{usage: usage}
+}
+
+// RecordLogin records a connection by the account with login, with connected users now connected in total.
+func (m *UsageMeter) RecordLogin(login string, connected int) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.usage.Logins == nil {
+		m.usage.Logins = make(map[string]int)
+	}
+	m.usage.Logins[login]++
+	m.usage.Connections++
+	m.usage.PeakUsers = max(m.usage.PeakUsers, connected)
+}
+
+// RecordDownload records a completed download of the file or folder at filePath, relative to the file root.
+func (m *UsageMeter) RecordDownload(filePath string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.usage.Files == nil {
+		m.usage.Files = make(map[string]int)
+	}
+	m.usage.Files[filePath]++
+	m.usage.Downloads++
+}
+
+// RecordUpload records a completed upload.
+func (m *UsageMeter) RecordUpload() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.usage.Uploads++
+}
+
+// Usage returns a copy of the usage collected so far.
+func (m *UsageMeter) Usage() WeeklyUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usage := m.usage
+	usage.Logins = maps.Clone(usage.Logins)
+	usage.Files = maps.Clone(usage.Files)
+
+	return usage
+}
+
+// Rollover starts collecting usage for a new week if the week of now differs from the week being collected.  It
+// returns the usage of the finished week and true if a week was finished.
+func (m *UsageMeter) Rollover(now time.Time) (WeeklyUsage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	week := usageWeek(now)
+	if m.usage.Week == week {
+		return WeeklyUsage{}, false
+	}
+
+	finished := m.usage
+	m.usage = WeeklyUsage{Week: week}
+
+	// Usage collected before the week was first set, e.g. on a new server, is carried over rather than reported.
+	if finished.Week == "" {
+		m.usage = finished
+		m.usage.Week = week
+		return WeeklyUsage{}, false
+	}
+
+	return finished, true
+}
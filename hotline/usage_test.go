@@ -0,0 +1,67 @@
+package hotline
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestUsageMeter(t *testing.T) {
+	monday := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	m := NewUsageMeter(WeeklyUsage{})
+
+	// Usage collected before the first rollover is kept for the current week.
+	m.RecordLogin("alice", 1)
+	_, ok := m.Rollover(monday)
+	assert.False(t, ok)
+
+	m.RecordLogin("bob", 2)
+	m.RecordLogin("alice", 1)
+	m.RecordDownload("Docs/readme.txt")
+	m.RecordDownload("Apps/game.sit")
+	m.RecordDownload("Apps/game.sit")
+	m.RecordUpload()
+
+	_, ok = m.Rollover(monday.Add(6 * 24 * time.Hour))
+	assert.False(t, ok, "still the same week on Sunday")
+
+	finished, ok := m.Rollover(monday.Add(7 * 24 * time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, "2025-W10", finished.Week)
+	assert.Equal(t, 2, finished.Accounts())
+	assert.Equal(t, 3, finished.Connections)
+	assert.Equal(t, 2, finished.PeakUsers)
+	assert.Equal(t, 1, finished.Uploads)
+	assert.Equal(t, 3, finished.Downloads)
+	assert.Equal(t, []FileDownloads{{Path: "Apps/game.sit", Downloads: 2}}, finished.TopFiles(1))
+
+	assert.Equal(t, WeeklyUsage{Week: "2025-W11"}, m.Usage())
+}
+
+func TestWeeklyUsage_Report(t *testing.T) {
+	usage := WeeklyUsage{
+		Week:        "2025-W10",
+		Logins:      map[string]int{"alice": 2, "bob": 1},
+		Connections: 3,
+		PeakUsers:   2,
+		Uploads:     1,
+		Downloads:   3,
+		Files:       map[string]int{"Apps/game.sit": 2, "Docs/readme.txt": 1},
+	}
+
+	title, body := usage.Report(DefaultUsageReportTopFiles)
+	assert.Equal(t, "Usage report for 2025-W10", title)
+	assert.Equal(t,
+		"Accounts: 2\rConnections: 3\rPeak users: 2\rUploads: 1\rDownloads: 3\r"+
+			"\rTop downloads:\r  Apps/game.sit (2)\r  Docs/readme.txt (1)\r",
+		body,
+	)
+}
+
+func TestUsageMeter_nil(t *testing.T) {
+	var m *UsageMeter
+	m.RecordLogin("alice", 1)
+	m.RecordDownload("file.txt")
+	m.RecordUpload()
+}
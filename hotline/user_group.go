@@ -0,0 +1,92 @@
+package hotline
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// defaultStaffGroup is the group of users with the Disconnect Users privilege whose account has no group.
+const defaultStaffGroup = "Staff"
+
+// UserListGroups configures grouping hints sent with user lists, so that clients that support them can group users,
+// e.g. to show staff at the top.  Classic clients ignore the hints.
+type UserListGroups struct {
+	Enabled    bool     `yaml:"Enabled"`    // Send the group of each user with user lists and user change notifications
+	StaffGroup string   `yaml:"StaffGroup"` // Group of users with the Disconnect Users privilege and no account group; defaults to "Staff"
+	Order      []string `yaml:"Order"`      // Display order of groups; groups not listed come after listed groups
+}
+
+// UserGroup returns the user list group of cc and its rank in the configured group order, lowest first.  Users in an
+// account group are in that group, and users with the Disconnect Users privilege are otherwise in the staff group.  It
+// returns false for users without a group, who are listed after all groups.
+func (s *Server) UserGroup(cc *ClientConn) (name string, rank uint16, ok bool) {
+	if cc.Account == nil {
+		return "", 0, false
+	}
+
+	name = cc.Account.Group
+	if name == "" && cc.Authorize(AccessDisconUser) {
+		name = s.Config.UserListGroups.StaffGroup
+		if name == "" {
+			name = defaultStaffGroup
+		}
+	}
+	if name == "" {
+		return "", 0, false
+	}
+
+	order := s.Config.UserListGroups.Order
+	if i := slices.Index(order, name); i >= 0 {
+		return name, uint16(i), true
+	}
+
+	return name, uint16(len(order)), true
+}
+
+// UserGroupFields returns the FieldUserGroup hint for cc to send to recipient, or nil if hints are disabled, recipient
+// hasn't enabled CapUserGroups, or the user has no group.
+func (s *Server) UserGroupFields(recipient, cc *ClientConn) []Field {
+	if !s.Config.UserListGroups.Enabled || !recipient.HasCapability(CapUserGroups) {
+		return nil
+	}
+
+	name, rank, ok := s.UserGroup(cc)
+	if !ok {
+		return nil
+	}
+
+	return []Field{NewUserGroupField(cc.ID, rank, name)}
+}
+
+// NotifyChangeUser returns the TranNotifyChangeUser that tells recipient the name, icon, flags, and user list group of
+// cc.
+func (cc *ClientConn) NotifyChangeUser(recipient *ClientConn) Transaction {
+	return NewTransaction(
+		TranNotifyChangeUser, recipient.ID,
+		append([]Field{
+			NewField(FieldUserName, cc.UserName),
+			NewField(FieldUserID, cc.ID[:]),
+			NewField(FieldUserIconID, cc.Icon),
+			NewField(FieldUserFlags, cc.Flags[:]),
+		}, cc.Server.UserGroupFields(recipient, cc)...)...,
+	)
+}
+
+// NotifyOthersChangeUser returns a NotifyChangeUser transaction of cc for each other connected client.
+func (cc *ClientConn) NotifyOthersChangeUser() (trans []Transaction) {
+	for _, c := range cc.Server.ClientMgr.List() {
+		if c.ID != cc.ID {
+			trans = append(trans, cc.NotifyChangeUser(c))
+		}
+	}
+
+	return trans
+}
+
+// NewUserGroupField returns a FieldUserGroup hint: the user ID, the 2 byte rank of the group, then the Mac Roman group
+// name.
+func NewUserGroupField(id ClientID, rank uint16, name string) Field {
+	data := append(id[:], binary.BigEndian.AppendUint16(nil, rank)...)
+
+	return NewField(FieldUserGroup, append(data, ToMacRoman(name)...))
+}
@@ -0,0 +1,54 @@
+package hotline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_UserGroup(t *testing.T) {
+	var admin AccessBitmap
+	admin.Set(AccessDisconUser)
+
+	s := &Server{Config: Config{UserListGroups: UserListGroups{Enabled: true, Order: []string{"Staff", "Donors"}}}}
+
+	tests := []struct {
+		name     string
+		account  *Account
+		wantName string
+		wantRank uint16
+		wantOK   bool
+	}{
+		{name: "admin without a group", account: &Account{Access: admin}, wantName: "Staff", wantRank: 0, wantOK: true},
+		{name: "account group", account: &Account{Group: "Donors"}, wantName: "Donors", wantRank: 1, wantOK: true},
+		{name: "account group overrides staff", account: &Account{Access: admin, Group: "Donors"}, wantName: "Donors", wantRank: 1, wantOK: true},
+		{name: "unlisted group", account: &Account{Group: "Friends"}, wantName: "Friends", wantRank: 2, wantOK: true},
+		{name: "no group", account: &Account{}, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, rank, ok := s.UserGroup(&ClientConn{Account: tt.account})
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantRank, rank)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestServer_UserGroupFields(t *testing.T) {
+	var admin AccessBitmap
+	admin.Set(AccessDisconUser)
+	cc := &ClientConn{ID: ClientID{0, 7}, Account: &Account{Access: admin}}
+	recipient := &ClientConn{ID: ClientID{0, 8}}
+
+	s := &Server{capabilities: []string{CapUserGroups}}
+	recipient.Server = s
+	recipient.EnableCapabilities([]string{CapUserGroups})
+	assert.Nil(t, s.UserGroupFields(recipient, cc), "hints are disabled by default")
+
+	s.Config.UserListGroups = UserListGroups{Enabled: true, StaffGroup: "Ops"}
+	assert.Equal(t, []Field{NewField(FieldUserGroup, []byte{0, 7, 0, 0, 'O', 'p', 's'})}, s.UserGroupFields(recipient, cc))
+
+	// Classic clients don't enable the capability, so they aren't sent hints.
+	assert.Nil(t, s.UserGroupFields(&ClientConn{Server: s}, cc))
+}
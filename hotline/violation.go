@@ -0,0 +1,102 @@
+package hotline
+
+import (
+	"slices"
+	"time"
+)
+
+// Kinds of protocol violations.  All are recorded, but only malformed transactions and invalid handshakes count towards
+// AutoBan.ProtocolViolations, since guests clicking disabled menus, or many users behind one NAT, easily make
+// requests they don't have access for.
+const (
+	ViolationMalformed    = "Malformed transaction"
+	ViolationHandshake    = "Invalid handshake"
+	ViolationAccessDenied = "Access denied"
+)
+
+// AuditTypeViolation is the audit entry type of protocol violations.
+const AuditTypeViolation = "Protocol violation"
+
+// AuditTypeAutoBan is the audit entry type of automatic bans.
+const AuditTypeAutoBan = "Automatic ban"
+
+// autoBanViolationsReason is the reason recorded for automatic bans after protocol violations.
+const autoBanViolationsReason = "Too many protocol violations"
+
+// maxRecentViolations is the number of protocol violations kept for RecentViolations.
+const maxRecentViolations = 100
+
+// ProtocolViolation is a request that breaks the Hotline protocol, or that probes for privileges the user doesn't
+// have.  Occasional violations are expected from buggy clients and users exploring menus, but many in a short time
+// suggest an attack.
+type ProtocolViolation struct {
+	Time   time.Time `json:"time"`
+	IP     string    `json:"ip"`
+	Login  string    `json:"login,omitempty"`
+	Kind   string    `json:"kind"`             // One of the Violation constants
+	Detail string    `json:"detail,omitempty"` // e.g. the transaction type
+	Banned bool      `json:"banned"`           // The violation led to an automatic ban of IP
+}
+
+// RecentViolations returns the most recent protocol violations, oldest first.
+func (s *Server) RecentViolations() []ProtocolViolation {
+	s.autoBanMu.Lock()
+	defer s.autoBanMu.Unlock()
+
+	return slices.Clone(s.recentViolations)
+}
+
+// recordViolation records a protocol violation in the audit log and RecentViolations, and bans its IP address for
+// AutoBan.Duration once it reaches AutoBan.ProtocolViolations violations within AutoBan.Window, not counting access
+// denials.  It returns true if the IP address was banned.
+func (s *Server) recordViolation(v ProtocolViolation) bool {
+	limit := s.Config.AutoBan.ProtocolViolations
+
+	s.autoBanMu.Lock()
+	var violations int
+	if limit > 0 && v.Kind != ViolationAccessDenied {
+		if s.violations == nil {
+			s.violations = make(map[string][]time.Time)
+		}
+		violations = recordEvent(s.violations, v.IP, v.Time, s.autoBanWindow())
+		if violations >= limit {
+			delete(s.violations, v.IP)
+			v.Banned = true
+		}
+	}
+
+	if len(s.recentViolations) >= maxRecentViolations {
+		s.recentViolations = slices.Delete(s.recentViolations, 0, len(s.recentViolations)-maxRecentViolations+1)
+	}
+	s.recentViolations = append(s.recentViolations, v)
+	s.autoBanMu.Unlock()
+
+	if s.auditEnabled(AuditTypeViolation) {
+		errMsg := v.Kind
+		if v.Detail != "" {
+			errMsg += ": " + v.Detail
+		}
+		s.AuditSink.WriteAudit(AuditEntry{Time: v.Time, Type: AuditTypeViolation, Login: v.Login, IP: v.IP, Error: errMsg})
+	}
+
+	if v.Banned {
+		s.Logger.Warn("Temporarily banning IP after protocol violations", "ip", v.IP, "violations", violations)
+		s.autoBan(v.IP, autoBanViolationsReason, v.Time)
+	}
+
+	return v.Banned
+}
+
+// recordViolation records a protocol violation by the user, and disconnects them if it leads to a ban.
+func (cc *ClientConn) recordViolation(kind, detail string) {
+	v := ProtocolViolation{Time: time.Now(), IP: AddrIP(cc.RemoteAddr), Kind: kind, Detail: detail}
+	if cc.Account != nil {
+		v.Login = cc.Account.Login
+	}
+	cc.Logger.Info("Protocol violation", "kind", kind, "detail", detail)
+
+	// Closing the connection ends the transaction loop, which then disconnects the user.
+	if cc.Server.recordViolation(v) && cc.Connection != nil {
+		_ = cc.Connection.Close()
+	}
+}
@@ -0,0 +1,95 @@
+package hotline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_recordViolation(t *testing.T) {
+	bans := testBanMgr{}
+	sink := &testAuditSink{}
+	s := &Server{
+		Config:    Config{AutoBan: AutoBan{ProtocolViolations: 3, Window: time.Minute, Duration: time.Hour}},
+		Logger:    NewTestLogger(),
+		BanList:   bans,
+		AuditSink: sink,
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.False(t, s.recordViolation(ProtocolViolation{Time: now, IP: "192.0.2.1", Kind: ViolationHandshake}))
+	assert.False(t, s.recordViolation(ProtocolViolation{Time: now.Add(10 * time.Second), IP: "192.0.2.1", Kind: ViolationMalformed}))
+
+	// Violations outside the window don't count towards a ban.
+	assert.False(t, s.recordViolation(ProtocolViolation{Time: now.Add(65 * time.Second), IP: "192.0.2.1", Kind: ViolationMalformed}))
+	assert.False(t, s.recordViolation(ProtocolViolation{Time: now.Add(72 * time.Second), IP: "192.0.2.1", Kind: ViolationMalformed}))
+	assert.False(t, s.recordViolation(ProtocolViolation{Time: now.Add(70 * time.Second), IP: "192.0.2.2", Kind: ViolationMalformed}))
+	assert.Empty(t, bans)
+
+	// Access denials are recorded, but don't count towards a ban.
+	for range 5 {
+		assert.False(t, s.recordViolation(ProtocolViolation{
+			Time:   now.Add(74 * time.Second),
+			IP:     "192.0.2.1",
+			Login:  "guest",
+			Kind:   ViolationAccessDenied,
+			Detail: "Delete file",
+		}))
+	}
+	assert.Empty(t, bans)
+
+	assert.True(t, s.recordViolation(ProtocolViolation{Time: now.Add(75 * time.Second), IP: "192.0.2.1", Login: "guest", Kind: ViolationMalformed, Detail: "Delete file"}))
+
+	until := now.Add(75*time.Second + time.Hour)
+	assert.Equal(t, testBanMgr{"192.0.2.1": {Until: &until, Reason: "Too many protocol violations"}}, bans)
+
+	violations := s.RecentViolations()
+	assert.Len(t, violations, 11)
+	assert.False(t, violations[9].Banned)
+	assert.True(t, violations[10].Banned)
+
+	assert.Len(t, sink.entries, 12)
+	assert.Equal(t, AuditEntry{Time: now.Add(74 * time.Second), Type: "Protocol violation", Login: "guest", IP: "192.0.2.1", Error: "Access denied: Delete file"}, sink.entries[5])
+	assert.Equal(t, AuditEntry{Time: now.Add(75 * time.Second), Type: "Protocol violation", Login: "guest", IP: "192.0.2.1", Error: "Malformed transaction: Delete file"}, sink.entries[10])
+	assert.Equal(t, AuditEntry{Time: now.Add(75 * time.Second), Type: "Automatic ban", IP: "192.0.2.1", Error: "Too many protocol violations"}, sink.entries[11])
+}
+
+func TestServer_RecentViolations_limit(t *testing.T) {
+	s := &Server{Logger: NewTestLogger()}
+	for i := range maxRecentViolations + 10 {
+		s.recordViolation(ProtocolViolation{Time: time.Unix(int64(i), 0), IP: "192.0.2.1", Kind: ViolationMalformed})
+	}
+
+	violations := s.RecentViolations()
+	assert.Len(t, violations, maxRecentViolations)
+	assert.Equal(t, time.Unix(10, 0), violations[0].Time)
+}
+
+func TestClientConn_handleTransaction_accessDenied(t *testing.T) {
+	s := &Server{
+		Logger:   NewTestLogger(),
+		outbox:   make(chan Transaction, 10),
+		handlers: map[TranType]HandlerFunc{},
+	}
+	s.HandleFunc(TranDeleteFile, func(cc *ClientConn, t *Transaction) []Transaction { return nil },
+		RequireAccess(AccessDeleteFile, "You are not allowed to delete files."))
+	s.HandleFunc(TranGetFileNameList, func(cc *ClientConn, t *Transaction) []Transaction { return nil })
+
+	cc := &ClientConn{
+		RemoteAddr: "192.0.2.1:50000",
+		Account:    &Account{Login: "guest"},
+		Server:     s,
+		Logger:     NewTestLogger(),
+	}
+	cc.handleTransaction(NewTransaction(TranGetFileNameList, ClientID{}))
+	cc.handleTransaction(NewTransaction(TranDeleteFile, ClientID{}))
+
+	violations := s.RecentViolations()
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "192.0.2.1", violations[0].IP)
+		assert.Equal(t, "guest", violations[0].Login)
+		assert.Equal(t, ViolationAccessDenied, violations[0].Kind)
+		assert.Equal(t, "Delete file", violations[0].Detail)
+	}
+}
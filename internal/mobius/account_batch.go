@@ -0,0 +1,218 @@
+package mobius
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"gopkg.in/yaml.v3"
+)
+
+// AccountOp is a single change in an AccountBatch, mirroring the changes that the Hotline multi-user account editor
+// can make in a single save.
+type AccountOp struct {
+	Action   string          `json:"action"`             // "create", "update", "rename", or "delete"
+	Login    string          `json:"login"`              // Login of the account to change
+	NewLogin string          `json:"newLogin,omitempty"` // New login for "rename", or to rename an account as part of an "update"
+	Name     *string         `json:"name,omitempty"`     // Account name; unchanged if omitted
+	Password *string         `json:"password,omitempty"` // Plain text password, or "" to remove it; unchanged if omitted
+	Access   map[string]bool `json:"access,omitempty"`   // Access privileges by name, as in the account files; unchanged if omitted
+}
+
+// AccountBatch is a list of account changes that are applied in order as a single unit: either all of them are
+// applied, or none are.
+type AccountBatch struct {
+	DryRun     bool        `json:"dryRun"` // Validate the batch and return the changes without applying them
+	Operations []AccountOp `json:"operations"`
+}
+
+// AccountBatchError describes why a batch was rejected.
+type AccountBatchError struct {
+	Index int    // Index of the operation that failed
+	Msg   string // Reason the operation failed
+}
+
+func (e *AccountBatchError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Msg)
+}
+
+// accountStep is a planned change with the operation that reverts it.
+type accountStep struct {
+	desc  string
+	apply func(hotline.AccountManager) error
+	undo  func(hotline.AccountManager) error
+}
+
+// plan validates the batch against the current accounts and returns the steps needed to apply it.  Each operation is
+// validated against the state left by the operations before it, so an account can be created and then renamed in the
+// same batch.
+func (b AccountBatch) plan(am hotline.AccountManager, hashPassword func([]byte) string) ([]accountStep, error) {
+	// state holds the accounts changed by earlier operations; a nil entry is an account that has been deleted.
+	state := make(map[string]*hotline.Account)
+	lookup := func(login string) *hotline.Account {
+		if acc, ok := state[login]; ok {
+			return acc
+		}
+		return am.Get(login)
+	}
+
+	var steps []accountStep
+	for i, op := range b.Operations {
+		fail := func(format string, a ...any) error {
+			return &AccountBatchError{Index: i, Msg: fmt.Sprintf(format, a...)}
+		}
+
+		if op.Login == "" {
+			return nil, fail("login is required")
+		}
+
+		var access *hotline.AccessBitmap
+		if op.Access != nil {
+			bits, err := accessFromNames(op.Access)
+			if err != nil {
+				return nil, fail("invalid access: %v", err)
+			}
+			access = &bits
+		}
+
+		switch op.Action {
+		case "create":
+			if lookup(op.Login) != nil {
+				return nil, fail("account %q already exists", op.Login)
+			}
+
+			acc := &hotline.Account{Login: op.Login}
+			if op.Name != nil {
+				acc.Name = *op.Name
+			}
+			var password []byte
+			if op.Password != nil {
+				password = hotline.EncodeString([]byte(*op.Password))
+			}
+			acc.Password = hashPassword(password)
+			if access != nil {
+				acc.Access = *access
+			}
+
+			state[op.Login] = acc
+
+			steps = append(steps, accountStep{
+				desc:  "create " + op.Login,
+				apply: func(am hotline.AccountManager) error { return am.Create(*acc) },
+				undo:  func(am hotline.AccountManager) error { return am.Delete(acc.Login) },
+			})
+		case "update", "rename":
+			prev := lookup(op.Login)
+			if prev == nil {
+				return nil, fail("account %q not found", op.Login)
+			}
+
+			newLogin := op.Login
+			if op.NewLogin != "" {
+				newLogin = op.NewLogin
+			} else if op.Action == "rename" {
+				return nil, fail("newLogin is required")
+			}
+			if newLogin != op.Login && lookup(newLogin) != nil {
+				return nil, fail("account %q already exists", newLogin)
+			}
+
+			next := *prev
+			if op.Name != nil {
+				next.Name = *op.Name
+			}
+			if op.Password != nil {
+				next.Password = hashPassword(hotline.EncodeString([]byte(*op.Password)))
+			}
+			if access != nil {
+				next.Access = *access
+			}
+
+			before := *prev
+			state[op.Login] = nil
+			renamed := next
+			renamed.Login = newLogin
+			state[newLogin] = &renamed
+
+			desc := "update " + op.Login
+			if newLogin != op.Login {
+				desc = fmt.Sprintf("rename %s to %s", op.Login, newLogin)
+			}
+
+			steps = append(steps, accountStep{
+				desc:  desc,
+				apply: func(am hotline.AccountManager) error { return am.Update(next, newLogin) },
+				undo: func(am hotline.AccountManager) error {
+					reverted := before
+					reverted.Login = newLogin
+					return am.Update(reverted, before.Login)
+				},
+			})
+		case "delete":
+			prev := lookup(op.Login)
+			if prev == nil {
+				return nil, fail("account %q not found", op.Login)
+			}
+			before := *prev
+			state[op.Login] = nil
+
+			steps = append(steps, accountStep{
+				desc:  "delete " + op.Login,
+				apply: func(am hotline.AccountManager) error { return am.Delete(before.Login) },
+				undo:  func(am hotline.AccountManager) error { return am.Create(before) },
+			})
+		default:
+			return nil, fail("unknown action %q", op.Action)
+		}
+	}
+
+	return steps, nil
+}
+
+// Apply validates and applies the batch, returning a description of each change.  If any change fails to apply, the
+// changes already made are reverted.  In a dry run the changes are returned without being applied.  New passwords are
+// hashed with hashPassword, e.g. Server.HashPassword.
+func (b AccountBatch) Apply(am hotline.AccountManager, hashPassword func([]byte) string) ([]string, error) {
+	steps, err := b.plan(am, hashPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]string, 0, len(steps))
+	for _, step := range steps {
+		changes = append(changes, step.desc)
+	}
+
+	if b.DryRun {
+		return changes, nil
+	}
+
+	for i, step := range steps {
+		if err := step.apply(am); err != nil {
+			applyErr := fmt.Errorf("%s: %w", step.desc, err)
+
+			for j := i - 1; j >= 0; j-- {
+				if undoErr := steps[j].undo(am); undoErr != nil {
+					return nil, errors.Join(applyErr, fmt.Errorf("revert %s: %w", steps[j].desc, undoErr))
+				}
+			}
+
+			return nil, applyErr
+		}
+	}
+
+	return changes, nil
+}
+
+// accessFromNames converts access privileges by name, as they appear in account files, to an access bitmap.
+func accessFromNames(names map[string]bool) (hotline.AccessBitmap, error) {
+	var bits hotline.AccessBitmap
+
+	data, err := yaml.Marshal(names)
+	if err != nil {
+		return bits, err
+	}
+
+	err = yaml.Unmarshal(data, &bits)
+
+	return bits, err
+}
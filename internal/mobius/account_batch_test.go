@@ -0,0 +1,120 @@
+package mobius
+
+import (
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestAccountManager(t *testing.T, logins ...string) *YAMLAccountManager {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, login := range logins {
+		require.NoError(t, writeYAMLFile(filepath.Join(dir, login+".yaml"), hotline.NewAccount(login, login, "", hotline.AccessBitmap{})))
+	}
+
+	am, err := NewYAMLAccountManager(dir, 0)
+	require.NoError(t, err)
+
+	return am
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestAccountBatch_Apply(t *testing.T) {
+	am := newTestAccountManager(t, "alice", "bob", "carol")
+
+	batch := AccountBatch{Operations: []AccountOp{
+		{Action: "create", Login: "dave", Name: strPtr("Dave"), Access: map[string]bool{"DownloadFile": true}},
+		{Action: "rename", Login: "dave", NewLogin: "david"},
+		{Action: "update", Login: "alice", Name: strPtr("Alice"), NewLogin: "alicia"},
+		{Action: "delete", Login: "bob"},
+	}}
+
+	changes, err := batch.Apply(am, hotline.HashAndSalt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create dave", "rename dave to david", "rename alice to alicia", "delete bob"}, changes)
+
+	david := am.Get("david")
+	require.NotNil(t, david)
+	assert.Equal(t, "Dave", david.Name)
+	assert.True(t, david.Access.IsSet(hotline.AccessDownloadFile))
+
+	assert.Nil(t, am.Get("dave"))
+	assert.Nil(t, am.Get("alice"))
+	assert.Equal(t, "Alice", am.Get("alicia").Name)
+	assert.Nil(t, am.Get("bob"))
+	assert.NotNil(t, am.Get("carol"))
+}
+
+func TestAccountBatch_Apply_password(t *testing.T) {
+	am := newTestAccountManager(t, "alice")
+
+	batch := AccountBatch{Operations: []AccountOp{
+		{Action: "create", Login: "dave", Password: strPtr("hunter2")},
+		{Action: "update", Login: "alice", Password: strPtr("s3cret")},
+	}}
+	_, err := batch.Apply(am, hotline.HashAndSalt)
+	require.NoError(t, err)
+
+	// Passwords are hashed in the form clients send, so that the accounts can log in.
+	cc := &hotline.ClientConn{Server: &hotline.Server{AccountManager: am}}
+	assert.True(t, cc.Authenticate("dave", hotline.EncodeString([]byte("hunter2"))))
+	assert.True(t, cc.Authenticate("alice", hotline.EncodeString([]byte("s3cret"))))
+	assert.False(t, cc.Authenticate("alice", []byte("s3cret")))
+}
+
+func TestAccountBatch_Apply_dryRun(t *testing.T) {
+	am := newTestAccountManager(t, "alice")
+
+	changes, err := AccountBatch{DryRun: true, Operations: []AccountOp{
+		{Action: "delete", Login: "alice"},
+		{Action: "create", Login: "alice"},
+	}}.Apply(am, hotline.HashAndSalt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"delete alice", "create alice"}, changes)
+
+	assert.Equal(t, "alice", am.Get("alice").Name, "a dry run should not change accounts")
+}
+
+func TestAccountBatch_Apply_invalid(t *testing.T) {
+	am := newTestAccountManager(t, "alice", "bob")
+
+	_, err := AccountBatch{Operations: []AccountOp{
+		{Action: "delete", Login: "alice"},
+		{Action: "rename", Login: "bob", NewLogin: "carol"},
+		{Action: "update", Login: "alice"},
+	}}.Apply(am, hotline.HashAndSalt)
+
+	var batchErr *AccountBatchError
+	require.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 2, batchErr.Index)
+	assert.Equal(t, `account "alice" not found`, batchErr.Msg)
+
+	assert.NotNil(t, am.Get("alice"), "no changes should be applied")
+	assert.NotNil(t, am.Get("bob"))
+	assert.Nil(t, am.Get("carol"))
+}
+
+func TestAccountBatch_Apply_rollback(t *testing.T) {
+	am := newTestAccountManager(t, "alice", "bob")
+
+	// Remove bob's cached account file behind the account manager's back so that deleting it fails.
+	require.NotNil(t, am.Get("bob"))
+	require.NoError(t, os.Remove(filepath.Join(am.accountDir, "bob.yaml")))
+
+	_, err := AccountBatch{Operations: []AccountOp{
+		{Action: "update", Login: "alice", Name: strPtr("Alice")},
+		{Action: "create", Login: "carol"},
+		{Action: "delete", Login: "bob"},
+	}}.Apply(am, hotline.HashAndSalt)
+	require.ErrorContains(t, err, "delete bob")
+
+	assert.Equal(t, "alice", am.Get("alice").Name)
+	assert.Nil(t, am.Get("carol"))
+}
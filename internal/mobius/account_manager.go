@@ -5,6 +5,7 @@ import (
 	"github.com/jhalter/mobius/hotline"
 	"github.com/stretchr/testify/mock"
 	"gopkg.in/yaml.v3"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
@@ -24,78 +25,168 @@ func loadFromYAMLFile(path string, data interface{}) error {
 	return decoder.Decode(data)
 }
 
+// DefaultAccountCacheSize is the default number of accounts kept in memory by the YAMLAccountManager.
+const DefaultAccountCacheSize = 1000
+
+// YAMLAccountManager stores accounts as YAML files named after the account login.  Account files are loaded on first
+// use and kept in a fixed size LRU cache, so that servers with many accounts start quickly and don't hold every
+// account in memory.
 type YAMLAccountManager struct {
-	accounts   map[string]hotline.Account
 	accountDir string
+	index      map[string]string // Account file path by login
+	cache      *lruCache[string, hotline.Account]
 
 	mu sync.Mutex
 }
 
-func NewYAMLAccountManager(accountDir string) (*YAMLAccountManager, error) {
+// NewYAMLAccountManager returns an account manager for the account files in accountDir, caching up to cacheSize
+// accounts in memory.  If cacheSize is 0, DefaultAccountCacheSize is used.
+func NewYAMLAccountManager(accountDir string, cacheSize int) (*YAMLAccountManager, error) {
+	if cacheSize <= 0 {
+		cacheSize = DefaultAccountCacheSize
+	}
+
 	accountMgr := YAMLAccountManager{
 		accountDir: accountDir,
-		accounts:   make(map[string]hotline.Account),
+		cache:      newLRUCache[string, hotline.Account](cacheSize),
+	}
+
+	index, err := accountMgr.buildIndex()
+	if err != nil {
+		return nil, err
 	}
+	accountMgr.index = index
+
+	return &accountMgr, nil
+}
+
+// Reload rebuilds the account index from the account directory and discards cached accounts so that they are read
+// from disk again on next use.
+func (am *YAMLAccountManager) Reload() error {
+	index, err := am.buildIndex()
+	if err != nil {
+		return err
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.index = index
+	am.cache.Clear()
+
+	return nil
+}
 
-	matches, err := filepath.Glob(filepath.Join(accountDir, "*.yaml"))
+// buildIndex maps the login of each account to its account file.  Logins are read from the files, since account files
+// aren't always named after their login, but the rest of each account isn't loaded until it's used.
+func (am *YAMLAccountManager) buildIndex() (map[string]string, error) {
+	matches, err := filepath.Glob(filepath.Join(am.accountDir, "*.yaml"))
 	if err != nil {
 		return nil, fmt.Errorf("list account files: %w", err)
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no accounts found in directory: %s", accountDir)
+		return nil, fmt.Errorf("no accounts found in directory: %s", am.accountDir)
 	}
 
+	index := make(map[string]string, len(matches))
 	for _, filePath := range matches {
-		var account hotline.Account
-		fileContents, err := os.ReadFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("read file: %v", err)
+		var account struct {
+			Login string `yaml:"Login"`
+		}
+		if err := loadFromYAMLFile(filePath, &account); err != nil {
+			return nil, fmt.Errorf("read %s: %v", filepath.Base(filePath), err)
 		}
 
-		if err := yaml.Unmarshal(fileContents, &account); err != nil {
-			return nil, fmt.Errorf("unmarshal: %v", err)
+		// If more than one file has the same login, the file named after the login is used.
+		if _, ok := index[account.Login]; ok && filePath != am.accountPath(account.Login) {
+			continue
 		}
+		index[account.Login] = filePath
+	}
+
+	return index, nil
+}
 
-		// Check the account file contents for a field name that only appears in the new AccessBitmap flag format.
-		// If not present, re-save the file to migrate it from the old array of ints format to new bool flag format.
-		if !strings.Contains(string(fileContents), "    DownloadFile:") {
-			if err := accountMgr.Update(account, account.Login); err != nil {
-				return nil, fmt.Errorf("migrate account to new access flag format: %v", err)
-			}
+func (am *YAMLAccountManager) accountPath(login string) string {
+	return filepath.Join(am.accountDir, path.Join("/", login)+".yaml")
+}
+
+// filePath returns the path of the account file for login: the indexed file if there is one, or else the file named
+// after the login.  The caller must hold mu.
+func (am *YAMLAccountManager) filePath(login string) string {
+	if filePath, ok := am.index[login]; ok {
+		return filePath
+	}
+
+	return am.accountPath(login)
+}
+
+// load returns the account for login from the cache, reading it from its account file if needed.  The caller must
+// hold mu.
+func (am *YAMLAccountManager) load(login string) (hotline.Account, bool) {
+	if account, ok := am.cache.Get(login); ok {
+		return account, true
+	}
+
+	filePath, ok := am.index[login]
+	if !ok {
+		return hotline.Account{}, false
+	}
+
+	account, oldFormat, err := readAccountFile(filePath)
+	if err != nil || account.Login != login {
+		return hotline.Account{}, false
+	}
+
+	// Re-save files in the old array of ints access format to migrate them to the new bool flag format.
+	if oldFormat {
+		if err := writeYAMLFile(filePath, &account); err != nil {
+			return hotline.Account{}, false
 		}
+	}
 
-		accountMgr.accounts[account.Login] = account
+	am.cache.Put(login, account)
+
+	return account, true
+}
+
+// readAccountFile reads the account file at filePath.  oldFormat is true if the file uses the old array of ints
+// format for access flags, which is detected by the absence of a field name that only appears in the new AccessBitmap
+// flag format.
+func readAccountFile(filePath string) (account hotline.Account, oldFormat bool, err error) {
+	fileContents, err := os.ReadFile(filePath)
+	if err != nil {
+		return account, false, fmt.Errorf("read file: %v", err)
 	}
 
-	return &accountMgr, nil
+	if err := yaml.Unmarshal(fileContents, &account); err != nil {
+		return account, false, fmt.Errorf("unmarshal %s: %v", filepath.Base(filePath), err)
+	}
+
+	return account, !strings.Contains(string(fileContents), "    DownloadFile:"), nil
 }
 
 func (am *YAMLAccountManager) Create(account hotline.Account) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
-	// Create account file, returning an error if one already exists.
-	file, err := os.OpenFile(
-		filepath.Join(am.accountDir, path.Join("/", account.Login+".yaml")),
-		os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644,
-	)
-	if err != nil {
-		return fmt.Errorf("create account file: %w", err)
-	}
-	defer file.Close()
+	accountPath := am.accountPath(account.Login)
 
-	b, err := yaml.Marshal(account)
-	if err != nil {
-		return fmt.Errorf("marshal account to YAML: %v", err)
+	// Return an error if an account file or an account with the login already exists.
+	if _, ok := am.index[account.Login]; ok {
+		return fmt.Errorf("create account file: %w", fs.ErrExist)
+	}
+	if _, err := os.Stat(accountPath); err == nil {
+		return fmt.Errorf("create account file: %w", fs.ErrExist)
 	}
 
-	_, err = file.Write(b)
-	if err != nil {
+	if err := writeYAMLFile(accountPath, account); err != nil {
 		return fmt.Errorf("write account file: %w", err)
 	}
 
-	am.accounts[account.Login] = account
+	am.index[account.Login] = accountPath
+	am.cache.Put(account.Login, account)
 
 	return nil
 }
@@ -104,32 +195,49 @@ func (am *YAMLAccountManager) Update(account hotline.Account, newLogin string) e
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
+	accountPath := am.filePath(account.Login)
+
 	// If the login has changed, rename the account file.
 	if account.Login != newLogin {
-		err := os.Rename(
-			filepath.Join(am.accountDir, path.Join("/", account.Login)+".yaml"),
-			filepath.Join(am.accountDir, path.Join("/", newLogin)+".yaml"),
-		)
-		if err != nil {
+		newPath := am.accountPath(newLogin)
+		if err := os.Rename(accountPath, newPath); err != nil {
 			return fmt.Errorf("error renaming account file: %w", err)
 		}
 
+		delete(am.index, account.Login)
+		am.cache.Delete(account.Login)
+
 		account.Login = newLogin
-		am.accounts[newLogin] = account
+		accountPath = newPath
+	}
 
-		delete(am.accounts, account.Login)
+	if err := writeYAMLFile(accountPath, &account); err != nil {
+		return fmt.Errorf("error writing account file: %w", err)
 	}
 
-	out, err := yaml.Marshal(&account)
-	if err != nil {
-		return err
+	am.index[account.Login] = accountPath
+	am.cache.Put(account.Login, account)
+
+	return nil
+}
+
+func (am *YAMLAccountManager) Modify(login string, modify func(*hotline.Account)) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	account, ok := am.load(login)
+	if !ok {
+		return fmt.Errorf("modify account: %w", fs.ErrNotExist)
 	}
 
-	if err := os.WriteFile(filepath.Join(am.accountDir, newLogin+".yaml"), out, 0644); err != nil {
+	modify(&account)
+	account.Login = login
+
+	if err := writeYAMLFile(am.index[login], &account); err != nil {
+		am.cache.Delete(login)
 		return fmt.Errorf("error writing account file: %w", err)
 	}
-
-	am.accounts[account.Login] = account
+	am.cache.Put(login, account)
 
 	return nil
 }
@@ -138,7 +246,7 @@ func (am *YAMLAccountManager) Get(login string) *hotline.Account {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
-	account, ok := am.accounts[login]
+	account, ok := am.load(login)
 	if !ok {
 		return nil
 	}
@@ -146,12 +254,27 @@ func (am *YAMLAccountManager) Get(login string) *hotline.Account {
 	return &account
 }
 
+// List returns all accounts.  Accounts that are not cached are read from disk without being added to the cache, so
+// that listing accounts does not evict the accounts of connected users, and without holding the lock, so that reading
+// many account files doesn't block logins.
 func (am *YAMLAccountManager) List() []hotline.Account {
 	am.mu.Lock()
-	defer am.mu.Unlock()
-
 	var accounts []hotline.Account
-	for _, account := range am.accounts {
+	uncached := make(map[string]string)
+	for login, filePath := range am.index {
+		if account, ok := am.cache.Get(login); ok {
+			accounts = append(accounts, account)
+		} else {
+			uncached[login] = filePath
+		}
+	}
+	am.mu.Unlock()
+
+	for login, filePath := range uncached {
+		account, _, err := readAccountFile(filePath)
+		if err != nil || account.Login != login {
+			continue
+		}
 		accounts = append(accounts, account)
 	}
 
@@ -162,12 +285,13 @@ func (am *YAMLAccountManager) Delete(login string) error {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
-	err := os.Remove(filepath.Join(am.accountDir, path.Join("/", login+".yaml")))
+	err := os.Remove(am.filePath(login))
 	if err != nil {
 		return fmt.Errorf("delete account file: %v", err)
 	}
 
-	delete(am.accounts, login)
+	delete(am.index, login)
+	am.cache.Delete(login)
 
 	return nil
 }
@@ -188,6 +312,12 @@ func (m *MockAccountManager) Update(account hotline.Account, newLogin string) er
 	return args.Error(0)
 }
 
+func (m *MockAccountManager) Modify(login string, modify func(*hotline.Account)) error {
+	args := m.Called(login, modify)
+
+	return args.Error(0)
+}
+
 func (m *MockAccountManager) Get(login string) *hotline.Account {
 	args := m.Called(login)
 
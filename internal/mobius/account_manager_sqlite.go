@@ -0,0 +1,292 @@
+package mobius
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are the schema changes applied to an account database, in order.  The index of the last applied
+// migration plus one is stored in the database's user_version, so new migrations must only ever be appended.
+var sqliteMigrations = []string{
+	`CREATE TABLE accounts (
+		login   TEXT PRIMARY KEY NOT NULL,
+		account TEXT NOT NULL
+	)`,
+}
+
+// SQLiteAccountManager stores accounts in a SQLite database, for servers with more accounts than are practical to
+// keep as individual files.  Each account is stored as YAML in the same format as the account files, so accounts can
+// be moved between the two stores without conversion.
+type SQLiteAccountManager struct {
+	db *sql.DB
+}
+
+// NewSQLiteAccountManager opens the account database at dbPath, creating it if needed.  When the database is first
+// created, the YAML account files in importDir are imported into it; importDir may be empty to skip the import.
+func NewSQLiteAccountManager(dbPath, importDir string) (*SQLiteAccountManager, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open account database: %w", err)
+	}
+
+	// SQLite allows one writer at a time; a single connection avoids "database is locked" errors between connections.
+	db.SetMaxOpenConns(1)
+
+	am := SQLiteAccountManager{db: db}
+
+	created, err := migrateSQLite(db, sqliteMigrations, "account database")
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	if created && importDir != "" {
+		if err := am.importYAML(importDir); err != nil {
+			// Remove the partly imported database so that the import is tried again on next start.
+			_ = db.Close()
+			_ = os.Remove(dbPath)
+			return nil, err
+		}
+	}
+
+	return &am, nil
+}
+
+// migrateSQLite applies any pending schema migrations to db and reports whether the database was newly created.  The
+// index of the last applied migration plus one is stored in the database's user_version.  name describes the database
+// in errors, e.g. "account database".
+func migrateSQLite(db *sql.DB, migrations []string, name string) (bool, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return false, fmt.Errorf("read %s version: %w", name, err)
+	}
+
+	if version > len(migrations) {
+		return false, fmt.Errorf("%s version %d is newer than supported version %d", name, version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return false, fmt.Errorf("migrate %s to version %d: %w", name, i+1, err)
+		}
+		// PRAGMA statements don't accept bound parameters.
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return false, fmt.Errorf("migrate %s to version %d: %w", name, i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("migrate %s to version %d: %w", name, i+1, err)
+		}
+	}
+
+	return version == 0, nil
+}
+
+// importYAML copies the YAML account files in accountDir into the database.  Accounts are imported by the login in
+// each file, as the YAMLAccountManager indexes them, and the import fails rather than skip a file that can't be read
+// or whose login is already used by another file.
+func (am *SQLiteAccountManager) importYAML(accountDir string) error {
+	matches, err := filepath.Glob(filepath.Join(accountDir, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("list account files: %w", err)
+	}
+
+	imported := make(map[string]string, len(matches))
+	for _, filePath := range matches {
+		account, _, err := readAccountFile(filePath)
+		if err != nil {
+			return fmt.Errorf("import %s: %w", filepath.Base(filePath), err)
+		}
+		if other, ok := imported[account.Login]; ok {
+			return fmt.Errorf("import %s: login %q is also used by %s", filepath.Base(filePath), account.Login, other)
+		}
+
+		if err := am.Create(account); err != nil {
+			return fmt.Errorf("import %s: %w", filepath.Base(filePath), err)
+		}
+		imported[account.Login] = filepath.Base(filePath)
+	}
+
+	return nil
+}
+
+// Close closes the account database.
+func (am *SQLiteAccountManager) Close() error {
+	return am.db.Close()
+}
+
+func (am *SQLiteAccountManager) Create(account hotline.Account) error {
+	data, err := yaml.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("marshal account: %w", err)
+	}
+
+	tx, err := am.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Check for an existing account first so that the error matches the YAMLAccountManager regardless of driver.
+	if exists, err := accountExists(tx, account.Login); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("create account: %w", fs.ErrExist)
+	}
+
+	if _, err := tx.Exec("INSERT INTO accounts (login, account) VALUES (?, ?)", account.Login, string(data)); err != nil {
+		return fmt.Errorf("create account: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (am *SQLiteAccountManager) Update(account hotline.Account, newLogin string) error {
+	oldLogin := account.Login
+	account.Login = newLogin
+
+	data, err := yaml.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("marshal account: %w", err)
+	}
+
+	tx, err := am.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if oldLogin != newLogin {
+		if exists, err := accountExists(tx, newLogin); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("rename account: %w", fs.ErrExist)
+		}
+	}
+
+	res, err := tx.Exec("UPDATE accounts SET login = ?, account = ? WHERE login = ?", newLogin, string(data), oldLogin)
+	if err != nil {
+		return fmt.Errorf("update account: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("update account: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("update account: %w", fs.ErrNotExist)
+	}
+
+	return tx.Commit()
+}
+
+func (am *SQLiteAccountManager) Modify(login string, modify func(*hotline.Account)) error {
+	tx, err := am.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var data string
+	err = tx.QueryRow("SELECT account FROM accounts WHERE login = ?", login).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("modify account: %w", fs.ErrNotExist)
+	}
+	if err != nil {
+		return fmt.Errorf("look up account: %w", err)
+	}
+
+	var account hotline.Account
+	if err := yaml.Unmarshal([]byte(data), &account); err != nil {
+		return fmt.Errorf("unmarshal account: %w", err)
+	}
+
+	modify(&account)
+	account.Login = login
+
+	out, err := yaml.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("marshal account: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE accounts SET account = ? WHERE login = ?", string(out), login); err != nil {
+		return fmt.Errorf("modify account: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (am *SQLiteAccountManager) Get(login string) *hotline.Account {
+	var data string
+	if err := am.db.QueryRow("SELECT account FROM accounts WHERE login = ?", login).Scan(&data); err != nil {
+		return nil
+	}
+
+	var account hotline.Account
+	if err := yaml.Unmarshal([]byte(data), &account); err != nil {
+		return nil
+	}
+
+	return &account
+}
+
+// List returns all accounts, ordered by login.
+func (am *SQLiteAccountManager) List() []hotline.Account {
+	rows, err := am.db.Query("SELECT account FROM accounts ORDER BY login")
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var accounts []hotline.Account
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			continue
+		}
+
+		var account hotline.Account
+		if err := yaml.Unmarshal([]byte(data), &account); err != nil {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts
+}
+
+func (am *SQLiteAccountManager) Delete(login string) error {
+	res, err := am.db.Exec("DELETE FROM accounts WHERE login = ?", login)
+	if err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("delete account: %w", err)
+	} else if n == 0 {
+		return fmt.Errorf("delete account: %w", fs.ErrNotExist)
+	}
+
+	return nil
+}
+
+func accountExists(tx *sql.Tx, login string) (bool, error) {
+	var one int
+	err := tx.QueryRow("SELECT 1 FROM accounts WHERE login = ?", login).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("look up account: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,123 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyAccountFiles copies the named account files from test/config/Users to a new directory and returns it.
+func copyAccountFiles(t *testing.T, names ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("test/config/Users", name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	return dir
+}
+
+func TestNewSQLiteAccountManager_importsAccounts(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "Users.db")
+
+	// The login of user-with-old-access-format.yaml is test-user.
+	accountDir := copyAccountFiles(t, "admin.yaml", "guest.yaml", "user-with-old-access-format.yaml")
+
+	am, err := NewSQLiteAccountManager(dbPath, accountDir)
+	require.NoError(t, err)
+
+	var logins []string
+	for _, account := range am.List() {
+		logins = append(logins, account.Login)
+	}
+	assert.Equal(t, []string{"admin", "guest", "test-user"}, logins)
+
+	assert.Equal(t,
+		&hotline.Account{
+			Name:     "admin",
+			Login:    "admin",
+			Password: "$2a$04$2itGEYx8C1N5bsfRSoC9JuonS3I4YfnyVPZHLSwp7kEInRX0yoB.a",
+			Access:   hotline.AccessBitmap{0xff, 0xff, 0xef, 0xff, 0xff, 0x80, 0x00, 0x00},
+		},
+		am.Get("admin"),
+	)
+
+	// Accounts are only imported when the database is created, so deleted accounts stay deleted after a restart.
+	require.NoError(t, am.Delete("guest"))
+	require.NoError(t, am.Close())
+
+	am, err = NewSQLiteAccountManager(dbPath, accountDir)
+	require.NoError(t, err)
+	defer am.Close()
+
+	assert.Nil(t, am.Get("guest"))
+	assert.Len(t, am.List(), 2)
+}
+
+func TestNewSQLiteAccountManager_importDuplicateLogin(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "Users.db")
+
+	accountDir := copyAccountFiles(t, "user-with-old-access-format.yaml")
+	data, err := os.ReadFile(filepath.Join(accountDir, "user-with-old-access-format.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(accountDir, "test-user.yaml"), data, 0644))
+
+	_, err = NewSQLiteAccountManager(dbPath, accountDir)
+	assert.ErrorContains(t, err, `login "test-user" is also used by test-user.yaml`)
+	assert.NoFileExists(t, dbPath)
+}
+
+func TestSQLiteAccountManager(t *testing.T) {
+	am, err := NewSQLiteAccountManager(filepath.Join(t.TempDir(), "Users.db"), "")
+	require.NoError(t, err)
+	defer am.Close()
+
+	assert.Empty(t, am.List())
+	assert.Nil(t, am.Get("alice"))
+
+	alice := hotline.Account{Login: "alice", Name: "Alice", Password: "hash", Access: hotline.AccessBitmap{0x60}}
+	require.NoError(t, am.Create(alice))
+	assert.Equal(t, &alice, am.Get("alice"))
+
+	err = am.Create(alice)
+	assert.ErrorIs(t, err, fs.ErrExist)
+
+	alice.Name = "Alice A."
+	require.NoError(t, am.Update(alice, "alice"))
+	assert.Equal(t, "Alice A.", am.Get("alice").Name)
+
+	// Renaming an account moves it to the new login.
+	require.NoError(t, am.Update(alice, "alicia"))
+	assert.Nil(t, am.Get("alice"))
+	assert.Equal(t, "alicia", am.Get("alicia").Login)
+	assert.Equal(t, "Alice A.", am.Get("alicia").Name)
+
+	// Renaming onto an existing login fails without changing either account.
+	require.NoError(t, am.Create(hotline.Account{Login: "bob", Name: "Bob"}))
+	err = am.Update(*am.Get("bob"), "alicia")
+	assert.ErrorIs(t, err, fs.ErrExist)
+	assert.Equal(t, "Bob", am.Get("bob").Name)
+	assert.Equal(t, "Alice A.", am.Get("alicia").Name)
+
+	err = am.Update(hotline.Account{Login: "carol"}, "carol")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// Modify changes only what the function changes.
+	require.NoError(t, am.Modify("bob", func(a *hotline.Account) { a.Password = "new hash" }))
+	assert.Equal(t, hotline.Account{Login: "bob", Name: "Bob", Password: "new hash"}, *am.Get("bob"))
+	assert.ErrorIs(t, am.Modify("carol", func(*hotline.Account) {}), fs.ErrNotExist)
+
+	require.NoError(t, am.Delete("bob"))
+	assert.ErrorIs(t, am.Delete("bob"), fs.ErrNotExist)
+
+	accounts := am.List()
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "alicia", accounts[0].Login)
+}
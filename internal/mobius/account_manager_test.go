@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"github.com/jhalter/mobius/hotline"
 	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"path/filepath"
 	"testing"
 )
 
 func TestNewYAMLAccountManager(t *testing.T) {
+	// Loading migrates account files in place, so the test loads copies of them.
+	accountDir := copyAccountFiles(t, "admin.yaml", "guest.yaml", "user-with-old-access-format.yaml")
+
 	type args struct {
 		accountDir string
 	}
@@ -20,29 +25,14 @@ func TestNewYAMLAccountManager(t *testing.T) {
 		{
 			name: "loads accounts from a directory",
 			args: args{
-				accountDir: "test/config/Users",
+				accountDir: accountDir,
 			},
 			want: &YAMLAccountManager{
-				accountDir: "test/config/Users",
-				accounts: map[string]hotline.Account{
-					"admin": {
-						Name:     "admin",
-						Login:    "admin",
-						Password: "$2a$04$2itGEYx8C1N5bsfRSoC9JuonS3I4YfnyVPZHLSwp7kEInRX0yoB.a",
-						Access:   hotline.AccessBitmap{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00},
-					},
-					"Test User Name": {
-						Name:     "test-user",
-						Login:    "Test User Name",
-						Password: "$2a$04$9P/jgLn1fR9TjSoWL.rKxuN6g.1TSpf2o6Hw.aaRuBwrWIJNwsKkS",
-						Access:   hotline.AccessBitmap{0x7d, 0xf0, 0x0c, 0xef, 0xab, 0x80, 0x00, 0x00},
-					},
-					"guest": {
-						Name:     "guest",
-						Login:    "guest",
-						Password: "$2a$04$6Yq/TIlgjSD.FbARwtYs9ODnkHawonu1TJ5W2jJKfhnHwBIQTk./y",
-						Access:   hotline.AccessBitmap{0x7d, 0xf0, 0x0c, 0xef, 0xab, 0x80, 0x00, 0x00},
-					},
+				accountDir: accountDir,
+				index: map[string]string{
+					"admin":     filepath.Join(accountDir, "admin.yaml"),
+					"guest":     filepath.Join(accountDir, "guest.yaml"),
+					"test-user": filepath.Join(accountDir, "user-with-old-access-format.yaml"),
 				},
 			},
 			wantErr: assert.NoError,
@@ -50,11 +40,13 @@ func TestNewYAMLAccountManager(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewYAMLAccountManager(tt.args.accountDir)
+			got, err := NewYAMLAccountManager(tt.args.accountDir, 0)
 			if !tt.wantErr(t, err, fmt.Sprintf("NewYAMLAccountManager(%v)", tt.args.accountDir)) {
 				return
 			}
 
+			assert.Equal(t, tt.want.index, got.index)
+
 			assert.Equal(t,
 				&hotline.Account{
 					Name:     "admin",
@@ -87,3 +79,67 @@ func TestNewYAMLAccountManager(t *testing.T) {
 		})
 	}
 }
+
+func TestYAMLAccountManager_cache(t *testing.T) {
+	dir := copyAccountFiles(t, "admin.yaml", "guest.yaml")
+
+	am, err := NewYAMLAccountManager(dir, 0)
+	assert.NoError(t, err)
+	guest := am.Get("guest")
+	admin := am.Get("admin")
+
+	am, err = NewYAMLAccountManager(dir, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, am.cache.order.Len())
+
+	assert.Equal(t, guest, am.Get("guest"))
+	assert.Equal(t, admin, am.Get("admin"))
+	assert.Equal(t, 1, am.cache.order.Len())
+	assert.Nil(t, am.Get("nobody"))
+
+	// Evicted accounts are read back from disk.
+	assert.Equal(t, guest, am.Get("guest"))
+	assert.Len(t, am.List(), 2)
+
+	newAccount := hotline.NewAccount("new", "New User", "password", hotline.AccessBitmap{})
+	assert.NoError(t, am.Create(*newAccount))
+	assert.Equal(t, newAccount, am.Get("new"))
+
+	assert.NoError(t, am.Update(*newAccount, "renamed"))
+	assert.Nil(t, am.Get("new"))
+	assert.Equal(t, "renamed", am.Get("renamed").Login)
+
+	assert.NoError(t, am.Modify("renamed", func(a *hotline.Account) { a.Name = "Modified" }))
+	assert.Equal(t, "Modified", am.Get("renamed").Name)
+	assert.NoError(t, am.Modify("guest", func(a *hotline.Account) { a.Name = "Evicted" }))
+	assert.Equal(t, "Evicted", am.Get("guest").Name)
+	assert.ErrorIs(t, am.Modify("nobody", func(*hotline.Account) {}), fs.ErrNotExist)
+
+	assert.NoError(t, am.Delete("renamed"))
+	assert.Nil(t, am.Get("renamed"))
+	assert.Len(t, am.List(), 2)
+}
+
+func TestYAMLAccountManager_loginDiffersFromFileName(t *testing.T) {
+	dir := t.TempDir()
+
+	account := hotline.NewAccount("Test User", "Test User Name", "password", hotline.AccessBitmap{})
+	assert.NoError(t, writeYAMLFile(filepath.Join(dir, "test-user.yaml"), account))
+
+	am, err := NewYAMLAccountManager(dir, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"Test User": filepath.Join(dir, "test-user.yaml")}, am.index)
+	assert.Equal(t, account, am.Get("Test User"))
+	assert.Len(t, am.List(), 1)
+
+	account.Name = "Renamed"
+	assert.NoError(t, am.Update(*account, account.Login))
+	assert.NoFileExists(t, filepath.Join(dir, "Test User.yaml"))
+
+	am, err = NewYAMLAccountManager(dir, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", am.Get("Test User").Name)
+
+	assert.NoError(t, am.Delete("Test User"))
+	assert.NoFileExists(t, filepath.Join(dir, "test-user.yaml"))
+}
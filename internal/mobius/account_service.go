@@ -0,0 +1,80 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+)
+
+// AccountService manages user accounts.
+type AccountService struct {
+	Accounts hotline.AccountManager
+	Digest   *hotline.ActivityDigest // Optional; records new accounts for the activity digest
+
+	// HashPassword hashes the passwords of new accounts, e.g. Server.HashPassword.  Optional; HashAndSalt is used if
+	// nil.
+	HashPassword func(pwd []byte) string
+}
+
+// Get returns the account with the given login.
+func (s AccountService) Get(actor Actor, login string) (*hotline.Account, error) {
+	if !actor.Authorize(hotline.AccessOpenUser) {
+		return nil, userErr("You are not allowed to view accounts.")
+	}
+
+	account := s.Accounts.Get(login)
+	if account == nil {
+		return nil, userErr("Account does not exist.")
+	}
+
+	return account, nil
+}
+
+// List returns all accounts.
+func (s AccountService) List(actor Actor) ([]hotline.Account, error) {
+	if !actor.Authorize(hotline.AccessOpenUser) {
+		return nil, userErr("You are not allowed to view accounts.")
+	}
+
+	return s.Accounts.List(), nil
+}
+
+// Create creates an account.  Password is the account password as sent by the client.  The actor can't grant access
+// privileges that they don't have themselves.
+func (s AccountService) Create(actor Actor, login, name, password string, access hotline.AccessBitmap) error {
+	if !actor.Authorize(hotline.AccessCreateUser) {
+		return userErr("You are not allowed to create new accounts.")
+	}
+
+	if s.Accounts.Get(login) != nil {
+		return userErr("Cannot create account %s because there is already an account with that login.", login)
+	}
+
+	if !hotline.CanManageAccount(actor.Authorize, &hotline.Account{Access: access}) {
+		return userErr("Cannot create account with more access than yourself.")
+	}
+
+	hashPassword := s.HashPassword
+	if hashPassword == nil {
+		hashPassword = hotline.HashAndSalt
+	}
+
+	account := hotline.Account{Login: login, Name: name, Password: hashPassword([]byte(password)), Access: access}
+	if err := s.Accounts.Create(account); err != nil {
+		return userErr("Cannot create account because there is already an account with that login.")
+	}
+	s.Digest.RecordNewAccount()
+
+	return nil
+}
+
+// Delete deletes the account with the given login.  The actor can't delete accounts with more access than themselves.
+func (s AccountService) Delete(actor Actor, login string) error {
+	if !actor.Authorize(hotline.AccessDeleteUser) {
+		return userErr("You are not allowed to delete accounts.")
+	}
+
+	if account := s.Accounts.Get(login); account != nil && !hotline.CanManageAccount(actor.Authorize, account) {
+		return userErr("Cannot delete account with more access than yourself.")
+	}
+
+	return s.Accounts.Delete(login)
+}
@@ -0,0 +1,90 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testActor is an Actor with a fixed set of access privileges.
+type testActor hotline.AccessBitmap
+
+func newTestActor(access ...int) testActor {
+	var bits hotline.AccessBitmap
+	for _, a := range access {
+		bits.Set(a)
+	}
+	return testActor(bits)
+}
+
+func (a testActor) Authorize(access int) bool {
+	bits := hotline.AccessBitmap(a)
+	return bits.IsSet(access)
+}
+
+func TestAccountService_Create(t *testing.T) {
+	svc := AccountService{Accounts: newTestAccountManager(t, "alice")}
+	admin := newTestActor(hotline.AccessCreateUser, hotline.AccessDownloadFile)
+
+	err := svc.Create(newTestActor(), "bob", "Bob", "", hotline.AccessBitmap{})
+	assert.EqualError(t, err, "You are not allowed to create new accounts.")
+
+	err = svc.Create(admin, "alice", "Alice", "", hotline.AccessBitmap{})
+	assert.EqualError(t, err, "Cannot create account alice because there is already an account with that login.")
+
+	var tooMuch hotline.AccessBitmap
+	tooMuch.Set(hotline.AccessDeleteUser)
+	err = svc.Create(admin, "bob", "Bob", "", tooMuch)
+	assert.EqualError(t, err, "Cannot create account with more access than yourself.")
+
+	var access hotline.AccessBitmap
+	access.Set(hotline.AccessDownloadFile)
+	require.NoError(t, svc.Create(admin, "bob", "Bob", "", access))
+
+	acc, err := svc.Get(newTestActor(hotline.AccessOpenUser), "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", acc.Name)
+	assert.True(t, acc.Access.IsSet(hotline.AccessDownloadFile))
+}
+
+func TestAccountService_Create_hashPassword(t *testing.T) {
+	hasher := hotline.Argon2idHasher{Memory: 64, Time: 1, Threads: 1}
+	svc := AccountService{Accounts: newTestAccountManager(t, "alice"), HashPassword: (&hotline.Server{PasswordHasher: hasher}).HashPassword}
+
+	require.NoError(t, svc.Create(newTestActor(hotline.AccessCreateUser), "bob", "Bob", "secret", hotline.AccessBitmap{}))
+
+	hash := svc.Accounts.Get("bob").Password
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, hotline.CheckPassword(hash, []byte("secret")))
+}
+
+func TestAccountService_Delete(t *testing.T) {
+	am := newTestAccountManager(t, "alice")
+	acc := am.Get("alice")
+	acc.Access.Set(hotline.AccessDisconUser)
+	require.NoError(t, am.Update(*acc, "alice"))
+
+	svc := AccountService{Accounts: am}
+
+	assert.EqualError(t, svc.Delete(newTestActor(), "alice"), "You are not allowed to delete accounts.")
+	assert.EqualError(t, svc.Delete(newTestActor(hotline.AccessDeleteUser), "alice"), "Cannot delete account with more access than yourself.")
+
+	require.NoError(t, svc.Delete(newTestActor(hotline.AccessDeleteUser, hotline.AccessDisconUser), "alice"))
+
+	_, err := svc.Get(newTestActor(hotline.AccessOpenUser), "alice")
+	assert.EqualError(t, err, "Account does not exist.")
+}
+
+func TestUserError_Message(t *testing.T) {
+	err := userErr("Cannot delete file %s because it does not exist or cannot be found.", "a.txt")
+
+	translate := func(msg string) string {
+		return map[string]string{
+			"Cannot delete file %s because it does not exist or cannot be found.": "Datei %s nicht gefunden.",
+		}[msg]
+	}
+
+	assert.Equal(t, "Datei a.txt nicht gefunden.", err.Message(translate))
+	assert.Equal(t, "Cannot delete file a.txt because it does not exist or cannot be found.", err.Error())
+}
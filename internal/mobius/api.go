@@ -2,12 +2,23 @@ package mobius
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/version"
+	"golang.org/x/text/encoding/charmap"
 	"io"
 	"log"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 type logResponseWriter struct {
@@ -31,9 +42,11 @@ func (lrw *logResponseWriter) Write(b []byte) (int, error) {
 }
 
 type APIServer struct {
-	hlServer *hotline.Server
-	logger   *slog.Logger
-	mux      *http.ServeMux
+	hlServer   *hotline.Server
+	configPath string
+	configMu   sync.Mutex // Serializes config changes, so that the config file and the running config stay in sync
+	logger     *slog.Logger
+	mux        *http.ServeMux
 }
 
 func (srv *APIServer) logMiddleware(next http.Handler) http.Handler {
@@ -45,16 +58,36 @@ func (srv *APIServer) logMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func NewAPIServer(hlServer *hotline.Server, reloadFunc func(), logger *slog.Logger) *APIServer {
+func NewAPIServer(hlServer *hotline.Server, configPath string, reloadFunc func(), logger *slog.Logger) *APIServer {
 	srv := APIServer{
-		hlServer: hlServer,
-		logger:   logger,
-		mux:      http.NewServeMux(),
+		hlServer:   hlServer,
+		configPath: configPath,
+		logger:     logger,
+		mux:        http.NewServeMux(),
 	}
 
 	srv.mux.Handle("/api/v1/reload", srv.logMiddleware(http.HandlerFunc(srv.ReloadHandler(reloadFunc))))
 	srv.mux.Handle("/api/v1/shutdown", srv.logMiddleware(http.HandlerFunc(srv.ShutdownHandler)))
 	srv.mux.Handle("/api/v1/stats", srv.logMiddleware(http.HandlerFunc(srv.RenderStats)))
+	srv.mux.Handle("/api/v1/info", srv.logMiddleware(http.HandlerFunc(srv.InfoHandler)))
+	srv.mux.Handle("/api/v1/version", srv.logMiddleware(http.HandlerFunc(srv.VersionHandler)))
+	srv.mux.Handle("/api/v1/users", srv.logMiddleware(http.HandlerFunc(srv.UsersHandler)))
+	srv.mux.Handle("/api/v1/config", srv.logMiddleware(http.HandlerFunc(srv.ConfigHandler)))
+	srv.mux.Handle("/api/v1/accounts:batch", srv.logMiddleware(http.HandlerFunc(srv.AccountBatchHandler)))
+	srv.mux.Handle("/api/v1/reports", srv.logMiddleware(http.HandlerFunc(srv.FileReportsHandler)))
+	srv.mux.Handle("/api/v1/cleanup", srv.logMiddleware(http.HandlerFunc(srv.CleanupHandler)))
+	srv.mux.Handle("/api/v1/metadata/check", srv.logMiddleware(http.HandlerFunc(srv.MetadataCheckHandler)))
+	srv.mux.Handle("/api/v1/thumbnail", srv.logMiddleware(http.HandlerFunc(srv.ThumbnailHandler)))
+	srv.mux.Handle("/api/v1/files/info", srv.logMiddleware(http.HandlerFunc(srv.FileInfoHandler)))
+	srv.mux.Handle("/api/v1/preferences", srv.logMiddleware(http.HandlerFunc(srv.PreferencesHandler)))
+	srv.mux.Handle("/api/v1/events", srv.logMiddleware(http.HandlerFunc(srv.EventsHandler)))
+	srv.mux.Handle("/api/v1/violations", srv.logMiddleware(http.HandlerFunc(srv.ViolationsHandler)))
+
+	// The WebSocket endpoint hijacks the connection, which the logging middleware's response writer doesn't support.
+	srv.mux.HandleFunc("/api/v1/chat", srv.WebChatHandler)
+	srv.mux.Handle("/api/v1/pending", srv.logMiddleware(http.HandlerFunc(srv.PendingUploadsHandler)))
+	srv.mux.Handle("/api/v1/pending/approve", srv.logMiddleware(http.HandlerFunc(srv.PendingUploadActionHandler(approvePendingUpload, "upload approved"))))
+	srv.mux.Handle("/api/v1/pending/reject", srv.logMiddleware(http.HandlerFunc(srv.PendingUploadActionHandler(rejectPendingUpload, "upload rejected"))))
 
 	return &srv
 }
@@ -88,8 +121,475 @@ func (srv *APIServer) RenderStats(w http.ResponseWriter, _ *http.Request) {
 	_, _ = io.WriteString(w, string(u))
 }
 
-func (srv *APIServer) Serve(port string) {
-	err := http.ListenAndServe(port, srv.mux)
+// apiServerInfo is the server info returned by the info endpoint.  Its Users field replaces the one of ServerInfo so
+// that the user count can be left out.
+type apiServerInfo struct {
+	hotline.ServerInfo
+	Users *int `json:"users,omitempty"`
+}
+
+// InfoHandler returns the server version, uptime, and usage counts, the same information clients get from
+// TranGetServerInfo, if EnableAPIInfo is set.  As for tracker registrations, the user count is left out if
+// Privacy.HideUserCount is set.
+func (srv *APIServer) InfoHandler(w http.ResponseWriter, _ *http.Request) {
+	config := srv.hlServer.CurrentConfig()
+	if !config.EnableAPIInfo {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	info := apiServerInfo{ServerInfo: srv.hlServer.Info()}
+	if !config.Privacy.HideUserCount {
+		info.Users = &info.ServerInfo.Users
+	}
+
+	_ = json.NewEncoder(w).Encode(info)
+}
+
+// VersionHandler returns the version, commit, and build date of the server binary.
+func (srv *APIServer) VersionHandler(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(version.Get())
+}
+
+// apiUser is a connected user as returned by the users endpoint.
+type apiUser struct {
+	ConnID       uint64    `json:"connID"` // Matches the connID field of the client's log messages
+	ID           uint16    `json:"id"`
+	Login        string    `json:"login"`
+	Name         string    `json:"name"`
+	RemoteAddr   string    `json:"remoteAddr"`
+	LoginTime    time.Time `json:"loginTime"`
+	Idle         bool      `json:"idle"`                   // Shown as idle in user lists, after a period of inactivity or while away
+	Away         string    `json:"away,omitempty"`         // Reason the user set for being away
+	Capabilities []string  `json:"capabilities,omitempty"` // Optional features enabled by the client
+}
+
+// UsersHandler lists the connected users.
+func (srv *APIServer) UsersHandler(w http.ResponseWriter, _ *http.Request) {
+	users := []apiUser{}
+	for _, c := range srv.hlServer.ClientMgr.List() {
+		user := apiUser{
+			ConnID:       c.ConnID,
+			ID:           binary.BigEndian.Uint16(c.ID[:]),
+			RemoteAddr:   c.RemoteAddr,
+			LoginTime:    c.LoginTime,
+			Idle:         c.IsIdle(),
+			Away:         c.AwayReason(),
+			Capabilities: c.EnabledCapabilities(),
+		}
+		user.Name, _ = charmap.Macintosh.NewDecoder().String(string(c.UserName))
+		if c.Account != nil {
+			user.Login = c.Account.Login
+		}
+
+		users = append(users, user)
+	}
+
+	_ = json.NewEncoder(w).Encode(users)
+}
+
+// redactedValue replaces secrets in config API responses.
+const redactedValue = "REDACTED"
+
+// redactConfig returns a copy of config with the server password, tracker passwords, webhook URLs, and error reporting
+// credentials, which may contain tokens, redacted.
+func redactConfig(config hotline.Config) hotline.Config {
+	if config.ServerPassword != "" {
+		config.ServerPassword = redactedValue
+	}
+
+	config.Trackers = slices.Clone(config.Trackers)
+	for i, tracker := range config.Trackers {
+		if parts := strings.SplitN(tracker, ":", 3); len(parts) == 3 {
+			config.Trackers[i] = parts[0] + ":" + parts[1] + ":" + redactedValue
+		}
+	}
+
+	config.NewsAnnouncements = slices.Clone(config.NewsAnnouncements)
+	for i := range config.NewsAnnouncements {
+		if config.NewsAnnouncements[i].WebhookURL != "" {
+			config.NewsAnnouncements[i].WebhookURL = redactedValue
+		}
+	}
+
+	if config.UsageReports.WebhookURL != "" {
+		config.UsageReports.WebhookURL = redactedValue
+	}
+	if config.AuditLog.WebhookURL != "" {
+		config.AuditLog.WebhookURL = redactedValue
+	}
+	if config.DiskSpace.WebhookURL != "" {
+		config.DiskSpace.WebhookURL = redactedValue
+	}
+
+	if config.ErrorReporting.SentryDSN != "" {
+		config.ErrorReporting.SentryDSN = redactedValue
+	}
+	config.ErrorReporting.OTLPHeaders = redactHeaders(config.ErrorReporting.OTLPHeaders)
+	config.Tracing.OTLPHeaders = redactHeaders(config.Tracing.OTLPHeaders)
+
+	return config
+}
+
+func redactHeaders(headers map[string]string) map[string]string {
+	headers = maps.Clone(headers)
+	for k := range headers {
+		headers[k] = redactedValue
+	}
+
+	return headers
+}
+
+// ConfigHandler returns the running server config, with secrets redacted, on GET.  On PATCH it applies a ConfigPatch
+// to the running server and saves the changed settings to the config file.
+func (srv *APIServer) ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(redactConfig(srv.hlServer.CurrentConfig()))
+	case http.MethodPatch:
+		var patch ConfigPatch
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&patch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+			return
+		}
+
+		// Multi-line messages are saved with Hotline's line breaks, keeping them on one line in config.yaml.
+		if patch.MOTD != nil {
+			motd := strings.ReplaceAll(*patch.MOTD, "\r\n", "\r")
+			motd = strings.ReplaceAll(motd, "\n", "\r")
+			patch.MOTD = &motd
+		}
+
+		srv.configMu.Lock()
+		defer srv.configMu.Unlock()
+
+		config, err := patch.Apply(srv.hlServer.CurrentConfig())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+			return
+		}
+
+		if err := SaveConfigPatch(srv.configPath, patch); err != nil {
+			srv.logger.Error("Error saving config", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		srv.hlServer.UpdateConfig(patch.set)
+		srv.logger.Info("Config updated", "settings", slices.Sorted(maps.Keys(patch.values())))
+
+		_ = json.NewEncoder(w).Encode(redactConfig(config))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// AccountBatchHandler applies an AccountBatch of account changes as a single unit and returns the list of changes.
+// Users logged in with deleted accounts are disconnected, users logged in with changed accounts have their sessions
+// updated, and references to renamed accounts are updated.
+func (srv *APIServer) AccountBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var batch AccountBatch
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+		return
+	}
+
+	changes, err := batch.Apply(srv.hlServer.AccountManager, srv.hlServer.HashPassword)
+	if err != nil {
+		var batchErr *AccountBatchError
+		if errors.As(err, &batchErr) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"msg": batchErr.Msg, "operation": batchErr.Index})
+			return
+		}
+
+		srv.logger.Error("Error applying account batch", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+		return
+	}
+
+	if !batch.DryRun {
+		srv.logger.Info("Account batch applied", "changes", changes)
+
+		for _, op := range batch.Operations {
+			if op.NewLogin != "" {
+				srv.hlServer.AccountRenamed(op.Login, op.NewLogin)
+			}
+
+			if op.Action != "delete" {
+				if op.NewLogin != "" {
+					srv.hlServer.AccountChanged(op.NewLogin)
+				} else {
+					srv.hlServer.AccountChanged(op.Login)
+				}
+				continue
+			}
+			for _, c := range srv.hlServer.ClientMgr.List() {
+				if c.Account != nil && c.Account.Login == op.Login {
+					c.Disconnect()
+				}
+			}
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"dryRun": batch.DryRun, "changes": changes})
+}
+
+// FileReportsHandler lists file reports on GET and resolves (deletes) the report specified by the id query parameter
+// on DELETE.
+func (srv *APIServer) FileReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if srv.hlServer.FileReportMgr == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = json.NewEncoder(w).Encode(srv.hlServer.FileReportMgr.List())
+	case http.MethodDelete:
+		if err := srv.hlServer.FileReportMgr.Delete(r.URL.Query().Get("id")); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = io.WriteString(w, `{ "msg": "report resolved" }`)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// CleanupHandler runs the configured cleanup rules and returns the list of deleted files.  If the dryRun query parameter
+// is "true", the files that would be deleted are returned without deleting them.
+func (srv *APIServer) CleanupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	deleted, err := srv.hlServer.RunCleanup(r.URL.Query().Get("dryRun") == "true")
+	if err != nil {
+		srv.logger.Error("Error running cleanup", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string][]string{"deleted": deleted})
+}
+
+// MetadataCheckHandler checks the info and resource forks in the FileRoot, quarantines corrupt ones, and returns the
+// problems found.  If the dryRun query parameter is "true", the problems are returned without changing anything.
+func (srv *APIServer) MetadataCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	problems, err := srv.hlServer.CheckMetadata(r.URL.Query().Get("dryRun") == "true")
+	if err != nil {
+		srv.logger.Error("Error checking file metadata", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string][]hotline.MetadataProblem{"problems": problems})
+}
+
+// ThumbnailHandler serves the PNG thumbnail for the file specified by the path query parameter, relative to the
+// FileRoot.
+func (srv *APIServer) ThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	data, err := hotline.ReadThumbnail(srv.hlServer.FS, filepath.Join(srv.hlServer.Config.FileRoot, filepath.Join("/", p)))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(data)
+}
+
+// FileInfo is the response body of the file info endpoint.
+type FileInfo struct {
+	Name       string     `json:"name"`
+	Size       int64      `json:"size"`
+	ModifyDate time.Time  `json:"modifyDate"`
+	Uploader   string     `json:"uploader,omitempty"`   // Login of the uploading account, if recorded
+	UploadDate *time.Time `json:"uploadDate,omitempty"` // Time the upload completed, if recorded
+}
+
+// FileInfoHandler returns details of the file at path, relative to the FileRoot, including who uploaded it.
+func (srv *APIServer) FileInfoHandler(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fullPath := filepath.Join(srv.hlServer.Config.FileRoot, filepath.Join("/", p))
+
+	fi, err := srv.hlServer.FS.Stat(fullPath)
+	if err != nil || fi.IsDir() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	meta, err := hotline.ReadFileMeta(srv.hlServer.FS, fullPath)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	info := FileInfo{
+		Name:       fi.Name(),
+		Size:       fi.Size(),
+		ModifyDate: fi.ModTime(),
+	}
+	if meta != nil {
+		info.Uploader = meta.Uploader
+		info.UploadDate = &meta.UploadDate
+	}
+
+	_ = json.NewEncoder(w).Encode(info)
+}
+
+// PreferencesHandler returns the saved preferences of the account specified by the login query parameter on GET.  On
+// PATCH it saves the preferences in the request body, removing those with an empty value, and returns the result.
+func (srv *APIServer) PreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	login := r.URL.Query().Get("login")
+	if login == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	account := srv.hlServer.AccountManager.Get(login)
+	if account == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		var prefs map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+			return
+		}
+
+		var err error
+		account, err = savePreferences(srv.hlServer, login, prefs)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+			return
+		}
+
+		srv.logger.Info("Preferences updated", "login", login, "preferences", slices.Sorted(maps.Keys(prefs)))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefs := account.Preferences
+	if prefs == nil {
+		prefs = map[string]string{}
+	}
+	_ = json.NewEncoder(w).Encode(prefs)
+}
+
+// EventsHandler lists upcoming community events.
+func (srv *APIServer) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	events := srv.hlServer.UpcomingEvents(time.Now())
+	if events == nil {
+		events = []hotline.Event{}
+	}
+
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+// ViolationsHandler lists recent protocol violations, oldest first.
+func (srv *APIServer) ViolationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	violations := srv.hlServer.RecentViolations()
+	if violations == nil {
+		violations = []hotline.ProtocolViolation{}
+	}
+
+	_ = json.NewEncoder(w).Encode(violations)
+}
+
+// PendingUploadsHandler lists uploads awaiting moderator approval.
+func (srv *APIServer) PendingUploadsHandler(w http.ResponseWriter, _ *http.Request) {
+	items, err := pendingUploads(srv.hlServer.Config)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(items)
+}
+
+// PendingUploadActionHandler returns a handler that applies action to the pending upload specified by the path query
+// parameter.
+func (srv *APIServer) PendingUploadActionHandler(action func(*hotline.Server, string) error, msg string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		p := r.URL.Query().Get("path")
+		if p == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := action(srv.hlServer, p); err != nil {
+			if errors.Is(err, errPendingUploadNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{"msg": err.Error()})
+			return
+		}
+
+		_, _ = io.WriteString(w, `{ "msg": "`+msg+`" }`)
+	}
+}
+
+// Serve serves the API on ln.  The listener is bound by the caller, so that it can be bound before the server drops
+// privileges.
+func (srv *APIServer) Serve(ln net.Listener) {
+	err := http.Serve(ln, srv.mux)
 	if err != nil {
 		log.Fatal(err)
 	}
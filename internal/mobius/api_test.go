@@ -0,0 +1,216 @@
+package mobius
+
+import (
+	"encoding/json"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIServer_UsersHandler(t *testing.T) {
+	loginTime := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	mgr := &hotline.MockClientMgr{}
+	mgr.On("List").Return([]*hotline.ClientConn{
+		{
+			ID:         hotline.ClientID{0, 2},
+			ConnID:     17,
+			UserName:   []byte("caf\x8e"),
+			Account:    &hotline.Account{Login: "guest"},
+			RemoteAddr: "192.168.1.2:54321",
+			LoginTime:  loginTime,
+		},
+	})
+	srv := &APIServer{hlServer: &hotline.Server{ClientMgr: mgr}}
+
+	w := httptest.NewRecorder()
+	srv.UsersHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+
+	assert.JSONEq(t,
+		`[{"connID":17,"id":2,"login":"guest","name":"café","remoteAddr":"192.168.1.2:54321","loginTime":"2024-03-04T12:00:00Z","idle":false}]`,
+		w.Body.String(),
+	)
+}
+
+func TestAPIServer_AccountBatchHandler(t *testing.T) {
+	am := newTestAccountManager(t, "alice")
+	srv := NewAPIServer(&hotline.Server{AccountManager: am}, "", func() {}, NewTestLogger())
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "with a valid dry run",
+			body:     `{"dryRun": true, "operations": [{"action": "delete", "login": "alice"}]}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"dryRun": true, "changes": ["delete alice"]}`,
+		},
+		{
+			name:     "with an invalid operation",
+			body:     `{"operations": [{"action": "create", "login": "alice"}]}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"msg": "account \"alice\" already exists", "operation": 0}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/accounts:batch", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestAPIServer_InfoHandler(t *testing.T) {
+	clientMgr := &hotline.MockClientMgr{}
+	clientMgr.On("List").Return([]*hotline.ClientConn{{}})
+
+	srv := &APIServer{hlServer: &hotline.Server{Version: "v1.2.3", Stats: hotline.NewStats(), ClientMgr: clientMgr}}
+
+	// The endpoint is only served with EnableAPIInfo.
+	w := httptest.NewRecorder()
+	srv.InfoHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/info", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	srv.hlServer.Config.EnableAPIInfo = true
+	w = httptest.NewRecorder()
+	srv.InfoHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/info", nil))
+
+	var info hotline.ServerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "v1.2.3", info.Version)
+	assert.Equal(t, 1, info.Users)
+
+	// The user count is left out with Privacy.HideUserCount.
+	srv.hlServer.Config.Privacy.HideUserCount = true
+	w = httptest.NewRecorder()
+	srv.InfoHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/info", nil))
+
+	var fields map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &fields))
+	assert.Equal(t, "v1.2.3", fields["version"])
+	assert.NotContains(t, fields, "users")
+}
+
+func TestAPIServer_VersionHandler(t *testing.T) {
+	srv := &APIServer{hlServer: &hotline.Server{}}
+
+	w := httptest.NewRecorder()
+	srv.VersionHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+
+	var info version.Info
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, version.Get(), info)
+}
+
+func TestAPIServer_ConfigHandler(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("Name: Old name\nDescription: Old description\nFileRoot: Files\n"), 0644))
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	hlServer := &hotline.Server{Config: *config}
+	srv := &APIServer{hlServer: hlServer, configPath: configPath, logger: NewTestLogger()}
+
+	w := httptest.NewRecorder()
+	srv.ConfigHandler(w, httptest.NewRequest(http.MethodPatch, "/api/v1/config", strings.NewReader(`{"Name": "New name", "MOTD": "Welcome!\nBe nice."}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, "New name", hlServer.CurrentConfig().Name)
+	assert.Equal(t, "Welcome!\rBe nice.", hlServer.CurrentConfig().MOTD)
+
+	saved, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "New name", saved.Name)
+	assert.Equal(t, "Welcome!\rBe nice.", saved.MOTD)
+
+	// Invalid changes are neither applied nor saved.
+	w = httptest.NewRecorder()
+	srv.ConfigHandler(w, httptest.NewRequest(http.MethodPatch, "/api/v1/config", strings.NewReader(`{"Name": ""}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "New name", hlServer.CurrentConfig().Name)
+}
+
+func TestAPIServer_FileInfoHandler(t *testing.T) {
+	fileRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(fileRoot, "Uploads"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(fileRoot, "Uploads", "a.sit"), []byte("data"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(fileRoot, "Uploads", "b.sit"), []byte("data"), 0644))
+
+	uploadDate := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, hotline.WriteFileMeta(&hotline.OSFileStore{}, filepath.Join(fileRoot, "Uploads", "a.sit"), hotline.FileMeta{Uploader: "alice", UploadDate: uploadDate}))
+
+	srv := &APIServer{hlServer: &hotline.Server{Config: hotline.Config{FileRoot: fileRoot}, FS: &hotline.OSFileStore{}}}
+
+	w := httptest.NewRecorder()
+	srv.FileInfoHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/files/info?path=Uploads/a.sit", nil))
+
+	var info FileInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "a.sit", info.Name)
+	assert.Equal(t, int64(4), info.Size)
+	assert.Equal(t, "alice", info.Uploader)
+	assert.True(t, uploadDate.Equal(*info.UploadDate))
+
+	// Files without recorded metadata have no uploader.
+	w = httptest.NewRecorder()
+	srv.FileInfoHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/files/info?path=Uploads/b.sit", nil))
+	info = FileInfo{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Empty(t, info.Uploader)
+	assert.Nil(t, info.UploadDate)
+
+	w = httptest.NewRecorder()
+	srv.FileInfoHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/files/info?path=Uploads/missing.sit", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAPIServer_PreferencesHandler(t *testing.T) {
+	am := newTestAccountManager(t, "alice")
+	srv := NewAPIServer(&hotline.Server{AccountManager: am}, "", func() {}, NewTestLogger())
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/preferences?login=alice", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v1/preferences?login=alice", strings.NewReader(`{"RefusePM": "true", "AutoReply": "Back soon"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"RefusePM": "true", "AutoReply": "Back soon"}`, w.Body.String())
+	assert.Equal(t, "Back soon", am.Get("alice").Preferences[hotline.PrefAutoReply])
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v1/preferences?login=alice", strings.NewReader(`{"RefusePM": "maybe"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"msg": "RefusePM must be true or false"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/preferences?login=bob", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRedactConfig(t *testing.T) {
+	config := redactConfig(hotline.Config{
+		ServerPassword: "letmein",
+		Trackers:       []string{"tracker.example.com:5499:secret", "other.example.com:5499"},
+		AuditLog:       hotline.AuditLog{WebhookURL: "https://hooks.example.com/token"},
+	})
+
+	assert.Equal(t, redactedValue, config.ServerPassword)
+	assert.Equal(t, []string{"tracker.example.com:5499:" + redactedValue, "other.example.com:5499"}, config.Trackers)
+	assert.Equal(t, redactedValue, config.AuditLog.WebhookURL)
+}
@@ -0,0 +1,76 @@
+package mobius
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// writeFileAtomic replaces the contents of the file at path with data so that a crash or power loss mid-write leaves
+// either the previous or the new contents intact, never a partial file.  The data is written to a temporary file in
+// the same directory, synced to disk, read back and checked with validate (if non-nil), and then renamed over path.
+// If validation fails the existing file is left untouched.
+func writeFileAtomic(path string, data []byte, validate func([]byte) error) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write to temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("chmod temporary file: %w", err)
+	}
+
+	if validate != nil {
+		written, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return fmt.Errorf("read back temporary file: %w", err)
+		}
+		if err := validate(written); err != nil {
+			return fmt.Errorf("validate %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	// Atomically rename the temporary file to the final file path.
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename temporary file to final file: %w", err)
+	}
+
+	// Sync the directory so the rename itself survives a crash.  Not all platforms support syncing directories, so
+	// errors are ignored.
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+
+	return nil
+}
+
+// writeYAMLFile marshals v to YAML and atomically writes it to path.  Before the file is replaced, the written YAML is
+// checked to decode back into a value of v's type.
+func writeYAMLFile(path string, v any) error {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal yaml: %w", err)
+	}
+
+	return writeFileAtomic(path, out, func(data []byte) error {
+		return yaml.Unmarshal(data, reflect.New(reflect.TypeOf(v)).Interface())
+	})
+}
@@ -0,0 +1,41 @@
+package mobius
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Banlist.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+
+	assert.NoError(t, writeFileAtomic(path, []byte("new"), nil))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	err = writeFileAtomic(path, []byte("bad"), func([]byte) error { return errors.New("invalid") })
+	assert.Error(t, err)
+	data, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteYAMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Banlist.yaml")
+
+	assert.NoError(t, writeYAMLFile(path, map[string]string{"192.168.1.1": "until"}))
+
+	var got map[string]string
+	assert.NoError(t, loadFromYAMLFile(path, &got))
+	assert.Equal(t, map[string]string{"192.168.1.1": "until"}, got)
+}
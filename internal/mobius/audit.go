@@ -0,0 +1,129 @@
+package mobius
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// NewAuditSink returns a sink that sends audit entries to each destination enabled in config.  A relative File path
+// is relative to configDir.
+func NewAuditSink(config hotline.AuditLog, configDir string, logger *slog.Logger) (MultiAuditSink, error) {
+	var sinks MultiAuditSink
+
+	if config.File != "" {
+		filePath := config.File
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(configDir, filePath)
+		}
+
+		f, err := NewAuditFile(filePath, logger)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, f)
+	}
+
+	if config.Syslog != nil {
+		h, err := newSyslogHandler(*config.Syslog, &slog.HandlerOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("audit syslog: %w", err)
+		}
+		sinks = append(sinks, &auditLogger{logger: slog.New(h)})
+	}
+
+	if config.WebhookURL != "" {
+		sinks = append(sinks, NewAuditWebhook(config.WebhookURL, logger))
+	}
+
+	return sinks, nil
+}
+
+// MultiAuditSink is a hotline.AuditSink that sends each entry to all of its sinks.
+type MultiAuditSink []hotline.AuditSink
+
+func (m MultiAuditSink) WriteAudit(entry hotline.AuditEntry) {
+	for _, sink := range m {
+		sink.WriteAudit(entry)
+	}
+}
+
+// AuditFile appends audit entries to a file as JSON lines.
+type AuditFile struct {
+	logger *slog.Logger
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewAuditFile(filePath string, logger *slog.Logger) (*AuditFile, error) {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+
+	return &AuditFile{logger: logger, f: f}, nil
+}
+
+func (a *AuditFile) WriteAudit(entry hotline.AuditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		a.logger.Error("Error encoding audit entry", "err", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.f.Write(append(line, '\n')); err != nil {
+		a.logger.Error("Error writing audit log", "err", err)
+	}
+}
+
+// Close closes the audit log file.
+func (a *AuditFile) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.f.Close()
+}
+
+// auditLogger writes audit entries as log records, e.g. to send them to syslog.
+type auditLogger struct {
+	logger *slog.Logger
+}
+
+func (a *auditLogger) WriteAudit(entry hotline.AuditEntry) {
+	attrs := []slog.Attr{slog.String("login", entry.Login), slog.String("ip", entry.IP)}
+	if entry.UserName != "" {
+		attrs = append(attrs, slog.String("name", entry.UserName))
+	}
+	if len(entry.Paths) > 0 {
+		attrs = append(attrs, slog.String("paths", strings.Join(entry.Paths, ", ")))
+	}
+	if entry.Error != "" {
+		attrs = append(attrs, slog.String("error", entry.Error))
+	}
+
+	a.logger.LogAttrs(context.Background(), slog.LevelInfo, entry.Type, attrs...)
+}
+
+// AuditWebhook posts each audit entry to a URL as JSON.  Entries are sent one at a time in order, and dropped if too
+// many are waiting to be sent.
+type AuditWebhook struct {
+	queue *webhookQueue
+}
+
+func NewAuditWebhook(url string, logger *slog.Logger) *AuditWebhook {
+	return &AuditWebhook{queue: newWebhookQueue(url, webhookQueueSize, logger)}
+}
+
+func (a *AuditWebhook) WriteAudit(entry hotline.AuditEntry) {
+	a.queue.post(entry)
+}
@@ -0,0 +1,93 @@
+package mobius
+
+import (
+	"encoding/json"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAuditSink_file(t *testing.T) {
+	configDir := t.TempDir()
+
+	sink, err := NewAuditSink(hotline.AuditLog{File: "Audit.log"}, configDir, NewTestLogger())
+	require.NoError(t, err)
+	require.Len(t, sink, 1)
+
+	entries := []hotline.AuditEntry{
+		{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Type: "Login", Login: "tom", IP: "192.0.2.1:50000"},
+		{
+			Time:  time.Date(2024, 5, 1, 12, 1, 0, 0, time.UTC),
+			Type:  "Delete file",
+			Login: "tom",
+			IP:    "192.0.2.1:50000",
+			Paths: []string{"Uploads/a.txt"},
+			Error: "You are not allowed to delete files.",
+		},
+	}
+	for _, e := range entries {
+		sink.WriteAudit(e)
+	}
+	require.NoError(t, sink[0].(*AuditFile).Close())
+
+	data, err := os.ReadFile(filepath.Join(configDir, "Audit.log"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t,
+		`{"time":"2024-05-01T12:01:00Z","type":"Delete file","login":"tom","ip":"192.0.2.1:50000","paths":["Uploads/a.txt"],"error":"You are not allowed to delete files."}`,
+		lines[1],
+	)
+
+	var got hotline.AuditEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+	assert.Equal(t, entries[0], got)
+}
+
+func TestNewAuditSink_none(t *testing.T) {
+	sink, err := NewAuditSink(hotline.AuditLog{}, t.TempDir(), NewTestLogger())
+	require.NoError(t, err)
+	assert.Empty(t, sink)
+}
+
+func TestAuditWebhook(t *testing.T) {
+	received := make(chan string)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entry hotline.AuditEntry
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &entry)
+		received <- entry.Type
+		<-release
+	}))
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { close(release) })
+
+	a := &AuditWebhook{queue: newWebhookQueue(ts.URL, 2, NewTestLogger())}
+
+	// Entries are sent one at a time, and entries posted while the queue is full are dropped.
+	a.WriteAudit(hotline.AuditEntry{Type: "one"})
+	assert.Equal(t, "one", <-received)
+	a.WriteAudit(hotline.AuditEntry{Type: "two"})
+	a.WriteAudit(hotline.AuditEntry{Type: "three"})
+	a.WriteAudit(hotline.AuditEntry{Type: "dropped"})
+	assert.Equal(t, uint64(1), a.queue.dropped.Load())
+
+	release <- struct{}{}
+	assert.Equal(t, "two", <-received)
+	a.WriteAudit(hotline.AuditEntry{Type: "four"})
+	release <- struct{}{}
+	assert.Equal(t, "three", <-received)
+	release <- struct{}{}
+	assert.Equal(t, "four", <-received)
+	release <- struct{}{}
+}
@@ -1,25 +1,41 @@
 package mobius
 
 import (
+	"context"
 	"fmt"
+	"github.com/jhalter/mobius/hotline"
 	"gopkg.in/yaml.v3"
+	"net"
+	"net/netip"
 	"os"
-	"path/filepath"
+	"path"
+	"strings"
 	"sync"
 	"time"
 )
 
+// hostnameLookupTimeout bounds the reverse DNS lookup used to match hostname patterns in the ban list.
+const hostnameLookupTimeout = 2 * time.Second
+
+// BanFile stores the ban list in a YAML file, keyed by IP address.  Bans without a reason are saved as just their
+// expiration time, or null for permanent bans.
+//
+// Besides single IP addresses, keys may be CIDR ranges such as 84.26.0.0/16 or 2001:db8::/32, or wildcard patterns
+// such as *.example.net that are matched against the IP address and its hostnames from a reverse DNS lookup.
 type BanFile struct {
-	banList  map[string]*time.Time
+	banList  map[string]hotline.Ban
 	filePath string
 
+	// lookupAddr returns the hostnames of an IP address; nil uses net.DefaultResolver.
+	lookupAddr func(ctx context.Context, ip string) ([]string, error)
+
 	sync.Mutex
 }
 
 func NewBanFile(path string) (*BanFile, error) {
 	bf := &BanFile{
 		filePath: path,
-		banList:  make(map[string]*time.Time),
+		banList:  make(map[string]hotline.Ban),
 	}
 
 	err := bf.Load()
@@ -34,7 +50,7 @@ func (bf *BanFile) Load() error {
 	bf.Lock()
 	defer bf.Unlock()
 
-	bf.banList = make(map[string]*time.Time)
+	bf.banList = make(map[string]hotline.Ban)
 
 	fh, err := os.Open(bf.filePath)
 	if os.IsNotExist(err) {
@@ -53,32 +69,85 @@ func (bf *BanFile) Load() error {
 	return nil
 }
 
-func (bf *BanFile) Add(ip string, until *time.Time) error {
+func (bf *BanFile) Add(ip string, ban hotline.Ban) error {
 	bf.Lock()
 	defer bf.Unlock()
 
-	bf.banList[ip] = until
-
-	out, err := yaml.Marshal(bf.banList)
-	if err != nil {
-		return fmt.Errorf("marshal yaml: %v", err)
-	}
+	bf.banList[ip] = ban
 
-	err = os.WriteFile(filepath.Join(bf.filePath), out, 0644)
-	if err != nil {
+	if err := writeYAMLFile(bf.filePath, bf.banList); err != nil {
 		return fmt.Errorf("write file: %v", err)
 	}
 
 	return nil
 }
 
-func (bf *BanFile) IsBanned(ip string) (bool, *time.Time) {
+func (bf *BanFile) IsBanned(ip string) (bool, hotline.Ban) {
+	now := time.Now()
+	addr, addrErr := netip.ParseAddr(ip)
+
+	bf.Lock()
+	if ban, ok := bf.banList[ip]; ok && !ban.Expired(now) {
+		bf.Unlock()
+		return true, ban
+	}
+
+	var patterns []string
+	for key, ban := range bf.banList {
+		if ban.Expired(now) {
+			continue
+		}
+
+		if strings.Contains(key, "/") {
+			prefix, err := netip.ParsePrefix(key)
+			if err == nil && addrErr == nil && prefix.Contains(addr.Unmap()) {
+				bf.Unlock()
+				return true, ban
+			}
+			continue
+		}
+
+		if strings.ContainsAny(key, "*?[") {
+			if match, _ := path.Match(strings.ToLower(key), strings.ToLower(ip)); match {
+				bf.Unlock()
+				return true, ban
+			}
+			patterns = append(patterns, key)
+		}
+	}
+	lookupAddr := bf.lookupAddr
+	bf.Unlock()
+
+	if len(patterns) == 0 {
+		return false, hotline.Ban{}
+	}
+
+	// Look up hostnames without holding the lock, as it can take a while.
+	if lookupAddr == nil {
+		lookupAddr = net.DefaultResolver.LookupAddr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hostnameLookupTimeout)
+	defer cancel()
+	names, err := lookupAddr(ctx, ip)
+	if err != nil {
+		return false, hotline.Ban{}
+	}
+
 	bf.Lock()
 	defer bf.Unlock()
 
-	if until, ok := bf.banList[ip]; ok {
-		return true, until
+	for _, key := range patterns {
+		ban, ok := bf.banList[key]
+		if !ok || ban.Expired(now) {
+			continue
+		}
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			if match, _ := path.Match(strings.ToLower(key), name); match {
+				return true, ban
+			}
+		}
 	}
 
-	return false, nil
+	return false, hotline.Ban{}
 }
@@ -1,7 +1,10 @@
 package mobius
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/jhalter/mobius/hotline"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path/filepath"
@@ -29,7 +32,7 @@ func TestNewBanFile(t *testing.T) {
 			args: args{path: filepath.Join(cwd, "test", "config", "Banlist.yaml")},
 			want: &BanFile{
 				filePath: filepath.Join(cwd, "test", "config", "Banlist.yaml"),
-				banList:  map[string]*time.Time{"192.168.86.29": &testTime},
+				banList:  map[string]hotline.Ban{"192.168.86.29": {Until: &testTime}},
 			},
 			wantErr: assert.NoError,
 		},
@@ -60,31 +63,43 @@ func TestAdd(t *testing.T) {
 	// Initialize BanFile.
 	bf := &BanFile{
 		filePath: tmpFilePath,
-		banList:  make(map[string]*time.Time),
+		banList:  make(map[string]hotline.Ban),
 	}
 
+	expiry := time.Date(2024, 6, 29, 11, 34, 43, 245899000, time.UTC)
+
 	// Define the test cases.
 	tests := []struct {
 		name   string
 		ip     string
-		until  *time.Time
-		expect map[string]*time.Time
+		ban    hotline.Ban
+		expect map[string]hotline.Ban
 	}{
 		{
-			name:  "Add IP with no expiration",
-			ip:    "192.168.1.1",
-			until: nil,
-			expect: map[string]*time.Time{
-				"192.168.1.1": nil,
+			name: "Add IP with no expiration",
+			ip:   "192.168.1.1",
+			ban:  hotline.Ban{},
+			expect: map[string]hotline.Ban{
+				"192.168.1.1": {},
+			},
+		},
+		{
+			name: "Add IP with expiration",
+			ip:   "192.168.1.2",
+			ban:  hotline.Ban{Until: &expiry},
+			expect: map[string]hotline.Ban{
+				"192.168.1.1": {},
+				"192.168.1.2": {Until: &expiry},
 			},
 		},
 		{
-			name:  "Add IP with expiration",
-			ip:    "192.168.1.2",
-			until: func() *time.Time { t := time.Date(2024, 6, 29, 11, 34, 43, 245899000, time.UTC); return &t }(),
-			expect: map[string]*time.Time{
-				"192.168.1.1": nil,
-				"192.168.1.2": func() *time.Time { t := time.Date(2024, 6, 29, 11, 34, 43, 245899000, time.UTC); return &t }(),
+			name: "Add IP with expiration and reason",
+			ip:   "192.168.1.3",
+			ban:  hotline.Ban{Until: &expiry, Reason: "Too many failed login attempts"},
+			expect: map[string]hotline.Ban{
+				"192.168.1.1": {},
+				"192.168.1.2": {Until: &expiry},
+				"192.168.1.3": {Until: &expiry, Reason: "Too many failed login attempts"},
 			},
 		},
 	}
@@ -92,7 +107,7 @@ func TestAdd(t *testing.T) {
 	// Run the test cases.
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := bf.Add(tt.ip, tt.until)
+			err := bf.Add(tt.ip, tt.ban)
 			assert.NoError(t, err, "Add() error")
 
 			// Load the file to check its contents.
@@ -105,8 +120,21 @@ func TestAdd(t *testing.T) {
 }
 
 func TestBanFile_IsBanned(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+
+	lookupAddr := func(ctx context.Context, ip string) ([]string, error) {
+		switch ip {
+		case "203.0.113.7":
+			return []string{"dsl-7.Pool.Example.net."}, nil
+		case "2001:db8::7":
+			return []string{"host7.ipv6.example.org."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
 	type fields struct {
-		banList map[string]*time.Time
+		banList map[string]hotline.Ban
 	}
 	type args struct {
 		ip string
@@ -116,34 +144,167 @@ func TestBanFile_IsBanned(t *testing.T) {
 		fields fields
 		args   args
 		want   bool
-		want1  *time.Time
+		want1  hotline.Ban
 	}{
 		{
 			name: "with permanent ban",
 			fields: fields{
-				banList: map[string]*time.Time{
-					"192.168.86.1": nil,
+				banList: map[string]hotline.Ban{
+					"192.168.86.1": {},
 				},
 			},
 			args:  args{ip: "192.168.86.1"},
 			want:  true,
-			want1: nil,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with temporary ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"192.168.86.1": {Until: &future, Reason: "Spam"},
+				},
+			},
+			args:  args{ip: "192.168.86.1"},
+			want:  true,
+			want1: hotline.Ban{Until: &future, Reason: "Spam"},
+		},
+		{
+			name: "with expired ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"192.168.86.1": {Until: &expired},
+				},
+			},
+			args:  args{ip: "192.168.86.1"},
+			want:  false,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with IPv4 CIDR ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"84.26.0.0/16": {Reason: "Spam"},
+				},
+			},
+			args:  args{ip: "84.26.101.3"},
+			want:  true,
+			want1: hotline.Ban{Reason: "Spam"},
+		},
+		{
+			name: "with IPv4 address outside CIDR ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"84.26.0.0/16": {},
+				},
+			},
+			args:  args{ip: "84.27.0.1"},
+			want:  false,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with expired CIDR ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"84.26.0.0/16": {Until: &expired},
+				},
+			},
+			args:  args{ip: "84.26.101.3"},
+			want:  false,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with IPv6 CIDR ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"2001:db8::/32": {},
+				},
+			},
+			args:  args{ip: "2001:db8:1234::1"},
+			want:  true,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with IPv4-mapped IPv6 address in IPv4 CIDR ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"84.26.0.0/16": {},
+				},
+			},
+			args:  args{ip: "::ffff:84.26.1.1"},
+			want:  true,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with IPv4 wildcard ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"192.168.86.*": {},
+				},
+			},
+			args:  args{ip: "192.168.86.200"},
+			want:  true,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with hostname wildcard ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"*.pool.example.net": {Until: &future},
+				},
+			},
+			args:  args{ip: "203.0.113.7"},
+			want:  true,
+			want1: hotline.Ban{Until: &future},
+		},
+		{
+			name: "with IPv6 hostname wildcard ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"*.ipv6.example.org": {},
+				},
+			},
+			args:  args{ip: "2001:db8::7"},
+			want:  true,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with non-matching hostname wildcard ban",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"*.example.com": {},
+				},
+			},
+			args:  args{ip: "203.0.113.7"},
+			want:  false,
+			want1: hotline.Ban{},
+		},
+		{
+			name: "with hostname wildcard ban and failed lookup",
+			fields: fields{
+				banList: map[string]hotline.Ban{
+					"*.example.net": {},
+				},
+			},
+			args:  args{ip: "198.51.100.1"},
+			want:  false,
+			want1: hotline.Ban{},
 		},
 		{
 			name: "with no ban",
 			fields: fields{
-				banList: map[string]*time.Time{},
+				banList: map[string]hotline.Ban{},
 			},
 			args:  args{ip: "192.168.86.1"},
 			want:  false,
-			want1: nil,
+			want1: hotline.Ban{},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			bf := &BanFile{
-				banList: tt.fields.banList,
-				Mutex:   sync.Mutex{},
+				banList:    tt.fields.banList,
+				lookupAddr: lookupAddr,
+				Mutex:      sync.Mutex{},
 			}
 			got, got1 := bf.IsBanned(tt.args.ip)
 			assert.Equalf(t, tt.want, got, "IsBanned(%v)", tt.args.ip)
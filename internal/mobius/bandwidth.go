@@ -0,0 +1,22 @@
+package mobius
+
+import (
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"io/fs"
+)
+
+// LoadBandwidthUsage reads saved bandwidth usage from the YAML file at path.  A missing file is not an error.
+func LoadBandwidthUsage(path string) (usage hotline.BandwidthUsage, err error) {
+	err = loadFromYAMLFile(path, &usage)
+	if errors.Is(err, fs.ErrNotExist) {
+		return usage, nil
+	}
+
+	return usage, err
+}
+
+// SaveBandwidthUsage writes bandwidth usage to the YAML file at path so that it survives server restarts.
+func SaveBandwidthUsage(path string, usage hotline.BandwidthUsage) error {
+	return writeYAMLFile(path, usage)
+}
@@ -0,0 +1,90 @@
+package mobius
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BroadcastHistoryYAML persists recent admin broadcasts to a YAML file, and the last login times of users to another.
+type BroadcastHistoryYAML struct {
+	broadcasts     []hotline.Broadcast // oldest first
+	lastLogins     map[string]time.Time
+	filePath       string
+	lastLoginsPath string
+
+	mu sync.Mutex
+}
+
+func NewBroadcastHistoryYAML(filePath, lastLoginsPath string) (*BroadcastHistoryYAML, error) {
+	bh := &BroadcastHistoryYAML{filePath: filePath, lastLoginsPath: lastLoginsPath}
+
+	if err := bh.Load(); err != nil {
+		return nil, fmt.Errorf("load broadcast history: %w", err)
+	}
+
+	return bh, nil
+}
+
+func (bh *BroadcastHistoryYAML) Load() error {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	bh.broadcasts = nil
+	bh.lastLogins = make(map[string]time.Time)
+
+	err := loadFromYAMLFile(bh.filePath, &bh.broadcasts)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("decode yaml: %v", err)
+	}
+
+	err = loadFromYAMLFile(bh.lastLoginsPath, &bh.lastLogins)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("decode last logins yaml: %v", err)
+	}
+
+	return nil
+}
+
+// Add saves a broadcast and discards all but the most recent keep broadcasts.
+func (bh *BroadcastHistoryYAML) Add(b hotline.Broadcast, keep int) error {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	bh.broadcasts = append(bh.broadcasts, b)
+	if len(bh.broadcasts) > keep {
+		bh.broadcasts = slices.Clone(bh.broadcasts[len(bh.broadcasts)-keep:])
+	}
+
+	return writeYAMLFile(bh.filePath, bh.broadcasts)
+}
+
+// List returns the kept broadcasts from oldest to newest.
+func (bh *BroadcastHistoryYAML) List() []hotline.Broadcast {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	return slices.Clone(bh.broadcasts)
+}
+
+// RecordLogin saves now as the last login time of the user identified by key, and returns their previous last login
+// time.  Last login times from before the oldest kept broadcast are discarded, since all kept broadcasts are new to
+// those users anyway.
+func (bh *BroadcastHistoryYAML) RecordLogin(key string, now time.Time) (time.Time, error) {
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+
+	prev := bh.lastLogins[key]
+
+	for k, t := range bh.lastLogins {
+		if len(bh.broadcasts) == 0 || t.Before(bh.broadcasts[0].Date) {
+			delete(bh.lastLogins, k)
+		}
+	}
+	bh.lastLogins[key] = now
+
+	return prev, writeYAMLFile(bh.lastLoginsPath, bh.lastLogins)
+}
@@ -0,0 +1,57 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBroadcastHistoryYAML(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Broadcasts.yaml")
+	lastLoginsPath := filepath.Join(t.TempDir(), "LastLogins.yaml")
+
+	bh, err := NewBroadcastHistoryYAML(filePath, lastLoginsPath)
+	assert.NoError(t, err)
+	assert.Empty(t, bh.List())
+
+	for i, msg := range []string{"one", "two", "three"} {
+		assert.NoError(t, bh.Add(hotline.Broadcast{Message: msg, Sender: "admin", Date: time.Unix(int64(i), 0).UTC()}, 2))
+	}
+
+	// Only the most recent broadcasts are kept, and they survive a reload from disk.
+	reloaded, err := NewBroadcastHistoryYAML(filePath, lastLoginsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []hotline.Broadcast{
+		{Message: "two", Sender: "admin", Date: time.Unix(1, 0).UTC()},
+		{Message: "three", Sender: "admin", Date: time.Unix(2, 0).UTC()},
+	}, reloaded.List())
+}
+
+func TestBroadcastHistoryYAML_RecordLogin(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Broadcasts.yaml")
+	lastLoginsPath := filepath.Join(t.TempDir(), "LastLogins.yaml")
+
+	bh, err := NewBroadcastHistoryYAML(filePath, lastLoginsPath)
+	assert.NoError(t, err)
+	assert.NoError(t, bh.Add(hotline.Broadcast{Message: "one", Date: time.Unix(10, 0).UTC()}, 2))
+
+	prev, err := bh.RecordLogin("alice", time.Unix(20, 0).UTC())
+	assert.NoError(t, err)
+	assert.True(t, prev.IsZero())
+
+	_, err = bh.RecordLogin("guest@10.0.0.1", time.Unix(5, 0).UTC())
+	assert.NoError(t, err)
+
+	// Last login times survive a reload from disk, and those from before the oldest kept broadcast are discarded.
+	reloaded, err := NewBroadcastHistoryYAML(filePath, lastLoginsPath)
+	assert.NoError(t, err)
+	prev, err = reloaded.RecordLogin("alice", time.Unix(30, 0).UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(20, 0).UTC(), prev)
+
+	prev, err = reloaded.RecordLogin("guest@10.0.0.1", time.Unix(30, 0).UTC())
+	assert.NoError(t, err)
+	assert.True(t, prev.IsZero())
+}
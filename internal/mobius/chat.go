@@ -0,0 +1,153 @@
+package mobius
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ChatYAML is a MemChatManager that saves the public chat history and private chats to YAML files, so that the history
+// replayed to users who log in and the private chats they're invited back to are kept across restarts.
+type ChatYAML struct {
+	*hotline.MemChatManager
+	historyPath string // Path of the chat history file, or "" to keep the history in memory
+	chatsPath   string // Path of the private chats file, or "" to keep private chats in memory
+	logger      *slog.Logger
+
+	mu sync.Mutex // Serializes writes of the files
+}
+
+// NewChatYAML returns a ChatYAML that saves the chat history to historyPath and private chats to chatsPath.  Either
+// path can be empty to not save it.
+func NewChatYAML(historyPath, chatsPath string, logger *slog.Logger) (*ChatYAML, error) {
+	ch := &ChatYAML{
+		MemChatManager: hotline.NewMemChatManager(),
+		historyPath:    historyPath,
+		chatsPath:      chatsPath,
+		logger:         logger,
+	}
+
+	if historyPath != "" {
+		var history []hotline.ChatHistoryMsg
+		err := loadFromYAMLFile(historyPath, &history)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("load chat history: %w", err)
+		}
+		ch.SetHistory(history)
+	}
+
+	if chatsPath != "" {
+		var chats []hotline.SavedChat
+		err := loadFromYAMLFile(chatsPath, &chats)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("load private chats: %w", err)
+		}
+		ch.RestoreChats(chats)
+	}
+
+	return ch, nil
+}
+
+// AppendHistory adds a public chat message to the chat history, discards all but the most recent keep messages, and
+// saves the history.
+func (ch *ChatYAML) AppendHistory(msg hotline.ChatHistoryMsg, keep int) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	_ = ch.MemChatManager.AppendHistory(msg, keep)
+	if ch.historyPath == "" {
+		return nil
+	}
+
+	return writeYAMLFile(ch.historyPath, ch.History())
+}
+
+func (ch *ChatYAML) New(cc *hotline.ClientConn, opts hotline.ChatOptions) hotline.ChatID {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	id := ch.MemChatManager.New(cc, opts)
+	ch.saveChats()
+
+	return id
+}
+
+func (ch *ChatYAML) Invite(id hotline.ChatID, inviter, invitee *hotline.ClientConn) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if err := ch.MemChatManager.Invite(id, inviter, invitee); err != nil {
+		return err
+	}
+	ch.saveChats()
+
+	return nil
+}
+
+func (ch *ChatYAML) DeclineInvite(id hotline.ChatID, clientID [2]byte) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.MemChatManager.DeclineInvite(id, clientID)
+	ch.saveChats()
+}
+
+func (ch *ChatYAML) Join(id hotline.ChatID, cc *hotline.ClientConn) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if err := ch.MemChatManager.Join(id, cc); err != nil {
+		return err
+	}
+	ch.saveChats()
+
+	return nil
+}
+
+func (ch *ChatYAML) Leave(id hotline.ChatID, clientID [2]byte) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.MemChatManager.Leave(id, clientID)
+	ch.saveChats()
+}
+
+func (ch *ChatYAML) SetSubject(id hotline.ChatID, subject string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.MemChatManager.SetSubject(id, subject)
+	ch.saveChats()
+}
+
+func (ch *ChatYAML) SetOptions(id hotline.ChatID, opts hotline.ChatOptions) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.MemChatManager.SetOptions(id, opts)
+	ch.saveChats()
+}
+
+func (ch *ChatYAML) PendingInvites(cc *hotline.ClientConn, since time.Time) []hotline.ChatInvite {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	invites := ch.MemChatManager.PendingInvites(cc, since)
+	ch.saveChats()
+
+	return invites
+}
+
+// saveChats writes the private chats file.  Private chats still work if it can't be written, so errors are logged.
+func (ch *ChatYAML) saveChats() {
+	if ch.chatsPath == "" {
+		return
+	}
+
+	if err := writeYAMLFile(ch.chatsPath, ch.SavedChats()); err != nil {
+		ch.logger.Error("Error saving private chats", "err", err)
+	}
+}
@@ -0,0 +1,310 @@
+package mobius
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseChatCommand splits a chat message of the form "/name args" into the command name and its arguments.
+func parseChatCommand(msg []byte) (name, args string, ok bool) {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) < 2 || msg[0] != '/' {
+		return "", "", false
+	}
+
+	decoded, err := txtDecoder.String(string(msg[1:]))
+	if err != nil {
+		return "", "", false
+	}
+
+	name, args, _ = strings.Cut(decoded, " ")
+
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// serverMsg returns a server message transaction for display in the client's message window.
+func serverMsg(clientID hotline.ClientID, msg string) hotline.Transaction {
+	encoded, err := txtEncoder.String(msg)
+	if err != nil {
+		encoded = msg
+	}
+
+	return hotline.NewTransaction(
+		hotline.TranServerMsg,
+		clientID,
+		hotline.NewField(hotline.FieldData, []byte(strings.ReplaceAll(encoded, "\n", "\r"))),
+		hotline.NewField(hotline.FieldChatOptions, []byte{0, 0}),
+	)
+}
+
+// notifyAdmins returns server message transactions for all connected clients with the disconnect user permission.
+func notifyAdmins(cc *hotline.ClientConn, msg string) (res []hotline.Transaction) {
+	for _, c := range cc.Server.ClientMgr.List() {
+		if c.Authorize(hotline.AccessDisconUser) {
+			res = append(res, serverMsg(c.ID, msg))
+		}
+	}
+
+	return res
+}
+
+const reportUsage = "Usage: /report <spam|illegal|broken|other> <path/to/file> [-- comment]"
+
+// HandleReportCommand lets users flag a file for moderator attention.
+//
+// Example: /report broken Uploads/game.sit -- archive fails to extract
+func HandleReportCommand(cc *hotline.ClientConn, _ *hotline.Transaction, args string) (res []hotline.Transaction) {
+	if cc.Server.FileReportMgr == nil {
+		return append(res, serverMsg(cc.ID, "File reports are not enabled on this server."))
+	}
+
+	reason, rest, _ := strings.Cut(args, " ")
+	reason = strings.ToLower(reason)
+	filePath, comment, _ := strings.Cut(rest, " -- ")
+	filePath = strings.Trim(strings.TrimSpace(filePath), "/")
+
+	if !slices.Contains(hotline.FileReportReasons, reason) || filePath == "" {
+		return append(res, serverMsg(cc.ID, reportUsage))
+	}
+
+	if _, err := cc.Server.FS.Stat(filepath.Join(cc.FileRoot(), filepath.Join("/", filePath))); err != nil {
+		return append(res, serverMsg(cc.ID, fmt.Sprintf("Cannot report \"%s\" because it does not exist or cannot be found.", filePath)))
+	}
+
+	report, err := cc.Server.FileReportMgr.Add(hotline.FileReport{
+		Path:     filePath,
+		Reason:   reason,
+		Comment:  strings.TrimSpace(comment),
+		Reporter: cc.Account.Login,
+		Date:     time.Now(),
+	})
+	if err != nil {
+		cc.Logger.Error("Error saving file report", "err", err)
+		return append(res, serverMsg(cc.ID, "Your report could not be saved.  Please try again later."))
+	}
+
+	cc.Logger.Info("File reported", "path", filePath, "reason", reason, "reportID", report.ID)
+
+	res = append(res, serverMsg(cc.ID, "Thank you, your report has been sent to the server moderators."))
+	res = append(res, notifyAdmins(cc, fmt.Sprintf(
+		"File report #%s from %s\n%s: %s\n%s",
+		report.ID, cc.UserName, report.Reason, report.Path, report.Comment,
+	))...)
+
+	return res
+}
+
+// HandleReportsCommand lists outstanding file reports for moderators.
+func HandleReportsCommand(cc *hotline.ClientConn, _ *hotline.Transaction, _ string) (res []hotline.Transaction) {
+	if !cc.Authorize(hotline.AccessDisconUser) {
+		return append(res, serverMsg(cc.ID, "You are not allowed to view file reports."))
+	}
+
+	if cc.Server.FileReportMgr == nil {
+		return append(res, serverMsg(cc.ID, "File reports are not enabled on this server."))
+	}
+
+	reports := cc.Server.FileReportMgr.List()
+	if len(reports) == 0 {
+		return append(res, serverMsg(cc.ID, "There are no open file reports."))
+	}
+
+	var sb strings.Builder
+	for _, r := range reports {
+		_, _ = fmt.Fprintf(&sb, "#%s %s [%s] %s (%s)\n", r.ID, r.Date.Format(time.DateTime), r.Reason, r.Path, r.Reporter)
+	}
+
+	return append(res, serverMsg(cc.ID, sb.String()))
+}
+
+// privateChatID returns the ID of the private chat that t was sent in, if cc is one of its members.
+func privateChatID(cc *hotline.ClientConn, t *hotline.Transaction) (hotline.ChatID, bool) {
+	data := t.GetField(hotline.FieldChatID).Data
+	if len(data) != 4 || bytes.Equal(data, []byte{0, 0, 0, 0}) {
+		return hotline.ChatID{}, false
+	}
+	chatID := hotline.ChatID(data)
+
+	return chatID, slices.ContainsFunc(cc.Server.ChatMgr.Members(chatID), func(c *hotline.ClientConn) bool {
+		return c.ID == cc.ID
+	})
+}
+
+// chatNotice returns chat messages announcing msg to all members of the private chat.
+func chatNotice(cc *hotline.ClientConn, chatID hotline.ChatID, msg string) (res []hotline.Transaction) {
+	for _, c := range cc.Server.ChatMgr.Members(chatID) {
+		res = append(res, hotline.NewTransaction(
+			hotline.TranChatMsg,
+			c.ID,
+			hotline.NewField(hotline.FieldChatID, chatID[:]),
+			hotline.NewField(hotline.FieldData, []byte("\r*** "+msg)),
+		))
+	}
+
+	return res
+}
+
+// HandleChatLimitCommand shows or sets the member limit of the private chat it is sent in.
+//
+// Example: /chatlimit 5
+func HandleChatLimitCommand(cc *hotline.ClientConn, t *hotline.Transaction, args string) (res []hotline.Transaction) {
+	chatID, ok := privateChatID(cc, t)
+	if !ok {
+		return append(res, serverMsg(cc.ID, "This command can only be used in a private chat."))
+	}
+
+	opts := cc.Server.ChatMgr.Options(chatID)
+
+	switch args {
+	case "":
+		if opts.MaxMembers == 0 {
+			return append(res, serverMsg(cc.ID, "This chat has no member limit."))
+		}
+		return append(res, serverMsg(cc.ID, fmt.Sprintf("This chat is limited to %d members.", opts.MaxMembers)))
+	case "off":
+		opts.MaxMembers = 0
+	default:
+		limit, err := strconv.Atoi(args)
+		if err != nil || limit < 1 {
+			return append(res, serverMsg(cc.ID, "Usage: /chatlimit [number|off]"))
+		}
+		opts.MaxMembers = limit
+	}
+
+	cc.Server.ChatMgr.SetOptions(chatID, opts)
+
+	if opts.MaxMembers == 0 {
+		return chatNotice(cc, chatID, fmt.Sprintf("%s removed the chat member limit", cc.UserName))
+	}
+	return chatNotice(cc, chatID, fmt.Sprintf("%s limited the chat to %d members", cc.UserName, opts.MaxMembers))
+}
+
+// HandleInviteOnlyCommand shows or sets whether the private chat it is sent in is invite-only.
+//
+// Example: /inviteonly on
+func HandleInviteOnlyCommand(cc *hotline.ClientConn, t *hotline.Transaction, args string) (res []hotline.Transaction) {
+	chatID, ok := privateChatID(cc, t)
+	if !ok {
+		return append(res, serverMsg(cc.ID, "This command can only be used in a private chat."))
+	}
+
+	opts := cc.Server.ChatMgr.Options(chatID)
+
+	switch strings.ToLower(args) {
+	case "":
+		if opts.InviteOnly {
+			return append(res, serverMsg(cc.ID, "This chat is invite-only."))
+		}
+		return append(res, serverMsg(cc.ID, "This chat is not invite-only."))
+	case "on":
+		opts.InviteOnly = true
+	case "off":
+		opts.InviteOnly = false
+	default:
+		return append(res, serverMsg(cc.ID, "Usage: /inviteonly [on|off]"))
+	}
+
+	cc.Server.ChatMgr.SetOptions(chatID, opts)
+
+	if opts.InviteOnly {
+		return chatNotice(cc, chatID, fmt.Sprintf("%s made the chat invite-only", cc.UserName))
+	}
+	return chatNotice(cc, chatID, fmt.Sprintf("%s turned off invite-only mode", cc.UserName))
+}
+
+// HandleBlockInvitesCommand shows or sets whether private chat invitations from non-admins are blocked for the user's
+// account.  Blocked invitations are dropped without notifying the sender.
+//
+// Example: /blockinvites on
+func HandleBlockInvitesCommand(cc *hotline.ClientConn, _ *hotline.Transaction, args string) (res []hotline.Transaction) {
+	if hotline.IsSharedAccount(cc.Account.Login) {
+		return append(res, serverMsg(cc.ID, "You are not allowed to change account preferences."))
+	}
+
+	var block bool
+	switch strings.ToLower(args) {
+	case "":
+		if cc.Account.BlockChatInvites {
+			return append(res, serverMsg(cc.ID, "Private chat invitations from non-admins are blocked."))
+		}
+		return append(res, serverMsg(cc.ID, "Private chat invitations from non-admins are allowed."))
+	case "on":
+		block = true
+	case "off":
+		block = false
+	default:
+		return append(res, serverMsg(cc.ID, "Usage: /blockinvites [on|off]"))
+	}
+
+	err := cc.Server.AccountManager.Modify(cc.Account.Login, func(account *hotline.Account) {
+		account.BlockChatInvites = block
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return append(res, serverMsg(cc.ID, "Account not found."))
+	}
+	if err != nil {
+		cc.Logger.Error("Error saving account preference", "err", err)
+		return append(res, serverMsg(cc.ID, "Cannot save account preference."))
+	}
+	cc.Server.UpdateSessionAccounts(cc.Account.Login, func(account *hotline.Account) {
+		account.BlockChatInvites = block
+	})
+
+	if block {
+		return append(res, serverMsg(cc.ID, "Private chat invitations from non-admins are now blocked."))
+	}
+	return append(res, serverMsg(cc.ID, "Private chat invitations from non-admins are now allowed."))
+}
+
+// HandleAwayCommand marks the user as away with a reason, which is shown in their user info until they return with
+// /back.  Like idle users, away users are shown as idle in user lists.
+//
+// Example: /away at lunch
+func HandleAwayCommand(cc *hotline.ClientConn, _ *hotline.Transaction, args string) (res []hotline.Transaction) {
+	if args == "" {
+		return append(res, serverMsg(cc.ID, "Usage: /away <reason>"))
+	}
+
+	cc.SetAway(args)
+
+	return append(res, serverMsg(cc.ID, fmt.Sprintf("You are now away: %s", cc.AwayReason())))
+}
+
+// HandleBackCommand clears the away status set with /away.
+func HandleBackCommand(cc *hotline.ClientConn, _ *hotline.Transaction, _ string) (res []hotline.Transaction) {
+	if cc.AwayReason() == "" {
+		return append(res, serverMsg(cc.ID, "You are not away."))
+	}
+
+	cc.SetAway("")
+
+	return append(res, serverMsg(cc.ID, "You are no longer away."))
+}
+
+// HandleBroadcastsCommand shows the recent admin broadcasts kept in the broadcast history.
+func HandleBroadcastsCommand(cc *hotline.ClientConn, _ *hotline.Transaction, _ string) (res []hotline.Transaction) {
+	broadcasts := cc.Server.BroadcastsSince(time.Time{})
+	if len(broadcasts) == 0 {
+		return append(res, serverMsg(cc.ID, "There are no recent broadcasts."))
+	}
+
+	return append(res, cc.BroadcastsMsg(cc.T("Recent broadcasts:"), broadcasts))
+}
+
+// HandleEventsCommand lists upcoming community events.
+func HandleEventsCommand(cc *hotline.ClientConn, _ *hotline.Transaction, _ string) (res []hotline.Transaction) {
+	events := cc.Server.UpcomingEvents(time.Now())
+	if len(events) == 0 {
+		return append(res, serverMsg(cc.ID, "There are no upcoming events."))
+	}
+
+	return append(res, cc.EventsMsg(cc.T("Upcoming events:"), events))
+}
@@ -0,0 +1,190 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"os"
+	"testing"
+)
+
+func TestParseChatCommand(t *testing.T) {
+	tests := []struct {
+		msg      string
+		wantName string
+		wantArgs string
+		wantOk   bool
+	}{
+		{msg: "/report spam foo", wantName: "report", wantArgs: "spam foo", wantOk: true},
+		{msg: "  /Reports  ", wantName: "reports", wantArgs: "", wantOk: true},
+		{msg: "hello /report", wantOk: false},
+		{msg: "/", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			name, args, ok := parseChatCommand([]byte(tt.msg))
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestHandleReportCommand(t *testing.T) {
+	newClient := func(reportMgr hotline.FileReportMgr) *hotline.ClientConn {
+		return &hotline.ClientConn{
+			ID:       [2]byte{0, 1},
+			UserName: []byte("Test"),
+			Account:  &hotline.Account{Login: "guest"},
+			Logger:   NewTestLogger(),
+			Server: &hotline.Server{
+				FS:            &hotline.OSFileStore{},
+				Config:        hotline.Config{FileRoot: func() string { path, _ := os.Getwd(); return path + "/test/config/Files" }()},
+				FileReportMgr: reportMgr,
+				ClientMgr: func() *hotline.MockClientMgr {
+					m := hotline.MockClientMgr{}
+					m.On("List").Return([]*hotline.ClientConn{
+						{
+							ID:      [2]byte{0, 2},
+							Account: &hotline.Account{Access: hotline.AccessBitmap{255, 255, 255, 255, 255, 255, 255, 255}},
+						},
+						{
+							ID:      [2]byte{0, 3},
+							Account: &hotline.Account{},
+						},
+					})
+					return &m
+				}(),
+			},
+		}
+	}
+
+	t.Run("with invalid reason", func(t *testing.T) {
+		cc := newClient(&hotline.MockFileReportMgr{})
+
+		res := HandleReportCommand(cc, &hotline.Transaction{}, "bogus testfile.txt")
+		TranAssertEqual(t, []hotline.Transaction{serverMsg([2]byte{0, 1}, reportUsage)}, res)
+	})
+
+	t.Run("with missing file", func(t *testing.T) {
+		cc := newClient(&hotline.MockFileReportMgr{})
+
+		res := HandleReportCommand(cc, &hotline.Transaction{}, "spam nope.txt")
+		TranAssertEqual(t, []hotline.Transaction{
+			serverMsg([2]byte{0, 1}, "Cannot report \"nope.txt\" because it does not exist or cannot be found."),
+		}, res)
+	})
+
+	t.Run("with valid report", func(t *testing.T) {
+		reportMgr := &hotline.MockFileReportMgr{}
+		reportMgr.On("Add", mock.MatchedBy(func(r hotline.FileReport) bool {
+			return r.Path == "testfile.txt" && r.Reason == "broken" && r.Comment == "will not open" && r.Reporter == "guest"
+		})).Return(hotline.FileReport{ID: "7", Path: "testfile.txt", Reason: "broken", Comment: "will not open"}, nil)
+
+		cc := newClient(reportMgr)
+
+		res := HandleReportCommand(cc, &hotline.Transaction{}, "broken /testfile.txt -- will not open")
+		TranAssertEqual(t, []hotline.Transaction{
+			serverMsg([2]byte{0, 1}, "Thank you, your report has been sent to the server moderators."),
+			serverMsg([2]byte{0, 2}, "File report #7 from Test\nbroken: testfile.txt\nwill not open"),
+		}, res)
+		reportMgr.AssertExpectations(t)
+	})
+}
+
+func TestPrivateChatOptionsCommands(t *testing.T) {
+	chatMgr := hotline.NewMemChatManager()
+	clientMgr := &hotline.MockClientMgr{}
+	srv := &hotline.Server{ChatMgr: chatMgr, ClientMgr: clientMgr}
+	clientMgr.On("Get", hotline.ClientID{0, 3}).Return(&hotline.ClientConn{ID: [2]byte{0, 3}, Server: srv})
+
+	owner := &hotline.ClientConn{ID: [2]byte{0, 1}, UserName: []byte("Owner"), Account: &hotline.Account{}, Server: srv}
+	outsider := &hotline.ClientConn{ID: [2]byte{0, 2}, UserName: []byte("Outsider"), Account: &hotline.Account{}, Server: srv}
+
+	chatID := chatMgr.New(owner, hotline.ChatOptions{})
+	chatTran := func(id hotline.ChatID) *hotline.Transaction {
+		return &hotline.Transaction{Fields: []hotline.Field{hotline.NewField(hotline.FieldChatID, id[:])}}
+	}
+
+	// Commands only work for members of a private chat.
+	res := HandleInviteOnlyCommand(outsider, chatTran(chatID), "on")
+	assert.Equal(t, []byte("This command can only be used in a private chat."), res[0].GetField(hotline.FieldData).Data)
+	res = HandleChatLimitCommand(owner, &hotline.Transaction{}, "2")
+	assert.Equal(t, []byte("This command can only be used in a private chat."), res[0].GetField(hotline.FieldData).Data)
+
+	res = HandleInviteOnlyCommand(owner, chatTran(chatID), "on")
+	assert.Equal(t, []byte("\r*** Owner made the chat invite-only"), res[0].GetField(hotline.FieldData).Data)
+
+	res = HandleChatLimitCommand(owner, chatTran(chatID), "none")
+	assert.Equal(t, []byte("Usage: /chatlimit [number|off]"), res[0].GetField(hotline.FieldData).Data)
+
+	res = HandleChatLimitCommand(owner, chatTran(chatID), "2")
+	assert.Equal(t, []byte("\r*** Owner limited the chat to 2 members"), res[0].GetField(hotline.FieldData).Data)
+	assert.Equal(t, hotline.ChatOptions{MaxMembers: 2, InviteOnly: true}, chatMgr.Options(chatID))
+
+	// Uninvited users can't join an invite-only chat.
+	join := &hotline.Transaction{Type: hotline.TranJoinChat, Fields: []hotline.Field{hotline.NewField(hotline.FieldChatID, chatID[:])}}
+	TranAssertEqual(t, []hotline.Transaction{{
+		ClientID:  [2]byte{0, 2},
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("You must be invited to join this chat."))},
+	}}, HandleJoinChat(outsider, join))
+
+	// Non-members can't send invitations.
+	invite := &hotline.Transaction{
+		Type: hotline.TranInviteToChat,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldChatID, chatID[:]),
+			hotline.NewField(hotline.FieldUserID, []byte{0, 3}),
+		},
+	}
+	TranAssertEqual(t, []hotline.Transaction{{
+		ClientID:  [2]byte{0, 2},
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("You are not allowed to invite users to this chat."))},
+	}}, HandleInviteToChat(&hotline.ClientConn{ID: [2]byte{0, 2}, Account: &hotline.Account{Access: hotline.AccessBitmap{255, 255, 255, 255, 255, 255, 255, 255}}, Server: srv}, invite))
+}
+
+func TestHandleBlockInvitesCommand(t *testing.T) {
+	am := newTestAccountManager(t, "alice", hotline.GuestAccount)
+
+	var access hotline.AccessBitmap
+	access.Set(hotline.AccessOpenChat)
+
+	target := &hotline.ClientConn{ID: [2]byte{0, 2}, Account: &hotline.Account{Login: "alice"}, Logger: NewTestLogger()}
+	sender := &hotline.ClientConn{ID: [2]byte{0, 1}, UserName: []byte("Sender"), Account: &hotline.Account{Access: access}}
+
+	srv := &hotline.Server{
+		AccountManager: am,
+		ChatMgr:        hotline.NewMemChatManager(),
+		ClientMgr: func() *hotline.MockClientMgr {
+			m := hotline.MockClientMgr{}
+			m.On("Get", hotline.ClientID{0, 2}).Return(target)
+			m.On("List").Return([]*hotline.ClientConn{target})
+			return &m
+		}(),
+	}
+	target.Server = srv
+	sender.Server = srv
+
+	res := HandleBlockInvitesCommand(target, &hotline.Transaction{}, "on")
+	assert.Equal(t, []byte("Private chat invitations from non-admins are now blocked."), res[0].GetField(hotline.FieldData).Data)
+	assert.True(t, target.Account.BlockChatInvites)
+	assert.True(t, am.Get("alice").BlockChatInvites)
+
+	// The invitation is dropped, and the sender only gets the reply for their own chat window.
+	res = HandleInviteNewChat(sender, &hotline.Transaction{
+		Type:   hotline.TranInviteNewChat,
+		Fields: []hotline.Field{hotline.NewField(hotline.FieldUserID, []byte{0, 2})},
+	})
+	assert.Len(t, res, 1)
+	assert.Equal(t, sender.ID, res[0].ClientID)
+
+	// Guests share the guest account, so they can't change its settings.
+	guest := &hotline.ClientConn{ID: [2]byte{0, 3}, Account: am.Get(hotline.GuestAccount), Server: srv, Logger: NewTestLogger()}
+	res = HandleBlockInvitesCommand(guest, &hotline.Transaction{}, "on")
+	assert.Equal(t, []byte("You are not allowed to change account preferences."), res[0].GetField(hotline.FieldData).Data)
+	assert.False(t, am.Get(hotline.GuestAccount).BlockChatInvites)
+}
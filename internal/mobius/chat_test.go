@@ -0,0 +1,58 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChatYAML_History(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "ChatHistory.yaml")
+
+	ch, err := NewChatYAML(filePath, "", NewTestLogger())
+	assert.NoError(t, err)
+	assert.Empty(t, ch.History())
+
+	// Messages are Mac Roman encoded, so they aren't always valid UTF-8.
+	for i, msg := range []string{"\r        Alice:  one", "\r        Alice:  caf\x8e", "\r*** Bob waves"} {
+		assert.NoError(t, ch.AppendHistory(hotline.ChatHistoryMsg{Time: time.Unix(int64(i), 0).UTC(), Message: msg}, 2))
+	}
+
+	// Only the most recent messages are kept, and they survive a reload from disk.
+	reloaded, err := NewChatYAML(filePath, "", NewTestLogger())
+	assert.NoError(t, err)
+	assert.Equal(t, []hotline.ChatHistoryMsg{
+		{Time: time.Unix(1, 0).UTC(), Message: "\r        Alice:  caf\x8e"},
+		{Time: time.Unix(2, 0).UTC(), Message: "\r*** Bob waves"},
+	}, reloaded.History())
+}
+
+func TestChatYAML_PrivateChats(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "PrivateChats.yaml")
+
+	ch, err := NewChatYAML("", filePath, NewTestLogger())
+	assert.NoError(t, err)
+
+	alice := &hotline.ClientConn{ID: [2]byte{0, 1}, UserName: []byte("Alice"), Account: &hotline.Account{Login: "alice"}, RemoteAddr: "10.0.0.1:5000"}
+	bob := &hotline.ClientConn{ID: [2]byte{0, 2}, UserName: []byte("Bob"), Account: &hotline.Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5000"}
+
+	chatID := ch.New(alice, hotline.ChatOptions{InviteOnly: true})
+	ch.SetSubject(chatID, "Plans")
+	assert.NoError(t, ch.Invite(chatID, alice, bob))
+
+	// The chat survives a reload from disk, and Bob can still join when he logs in again.
+	reloaded, err := NewChatYAML("", filePath, NewTestLogger())
+	assert.NoError(t, err)
+	assert.Equal(t, "Plans", reloaded.GetSubject(chatID))
+	assert.Equal(t, hotline.ChatOptions{InviteOnly: true}, reloaded.Options(chatID))
+
+	newBob := &hotline.ClientConn{ID: [2]byte{0, 3}, UserName: []byte("Bob"), Account: &hotline.Account{Login: "bob"}, RemoteAddr: "10.0.0.2:5001"}
+	invites := reloaded.PendingInvites(newBob, time.Now().Add(-time.Minute))
+	if assert.Len(t, invites, 1) {
+		assert.Equal(t, chatID, invites[0].ChatID)
+		assert.Equal(t, "Alice", invites[0].FromName)
+	}
+	assert.NoError(t, reloaded.Join(chatID, newBob))
+}
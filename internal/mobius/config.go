@@ -5,8 +5,11 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/jhalter/mobius/hotline"
 	"gopkg.in/yaml.v3"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 )
 
 var ConfigSearchOrder = []string{
@@ -15,6 +18,13 @@ var ConfigSearchOrder = []string{
 	"/opt/homebrew/var/mobius/config",
 }
 
+const (
+	defaultPendingUploadsDir  = "PendingUploads"
+	defaultBlobDir            = "Blobs"
+	defaultQuarantineDir      = "Quarantine"
+	defaultNewsAttachmentsDir = "NewsAttachments"
+)
+
 func LoadConfig(path string) (*hotline.Config, error) {
 	var config hotline.Config
 
@@ -32,10 +42,172 @@ func LoadConfig(path string) (*hotline.Config, error) {
 		return nil, fmt.Errorf("validate config: %v", err)
 	}
 
-	// If the FileRoot is an absolute path, use it, otherwise treat as a relative path to the config dir.
-	if !filepath.IsAbs(config.FileRoot) {
-		config.FileRoot = filepath.Join(path, "../", config.FileRoot)
+	if err := config.HiddenFiles.Validate(); err != nil {
+		return nil, fmt.Errorf("validate config: %v", err)
+	}
+	for _, rule := range config.CleanupRules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("validate config: %v", err)
+		}
+	}
+
+	// Deduplicated files are hard links, and hard links share their resource fork and Finder info.
+	if config.EnableDedup && config.NativeForks {
+		return nil, fmt.Errorf("validate config: EnableDedup can not be used with NativeForks")
+	}
+
+	// Relative paths are relative to the config dir.  All paths are made absolute, which also lets the os package
+	// handle paths longer than MAX_PATH on Windows.
+	if config.PendingUploadsDir == "" {
+		config.PendingUploadsDir = defaultPendingUploadsDir
+	}
+	if config.BlobDir == "" {
+		config.BlobDir = defaultBlobDir
+	}
+	if config.QuarantineDir == "" {
+		config.QuarantineDir = defaultQuarantineDir
+	}
+	if config.NewsAttachmentsDir == "" {
+		config.NewsAttachmentsDir = defaultNewsAttachmentsDir
+	}
+	for _, p := range []*string{&config.FileRoot, &config.PendingUploadsDir, &config.BlobDir, &config.QuarantineDir, &config.NewsAttachmentsDir} {
+		if *p, err = configPath(path, *p); err != nil {
+			return nil, fmt.Errorf("resolve path: %v", err)
+		}
 	}
 
 	return &config, nil
 }
+
+// configPath returns p as an absolute path, treating a relative path as relative to the directory of configFile.
+func configPath(configFile, p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(configFile), p)
+	}
+
+	return filepath.Abs(p)
+}
+
+// ConfigPatch is the subset of config settings that can be changed while the server is running.  Nil fields are left
+// unchanged.
+type ConfigPatch struct {
+	Name                  *string `json:"Name"`
+	Description           *string `json:"Description"`
+	MOTD                  *string `json:"MOTD"`
+	MaxDownloads          *int    `json:"MaxDownloads"`
+	MaxDownloadsPerClient *int    `json:"MaxDownloadsPerClient"`
+	MaxConnectionsPerIP   *int    `json:"MaxConnectionsPerIP"`
+}
+
+// values returns the settings set in the patch keyed by their config.yaml key.
+func (p ConfigPatch) values() map[string]any {
+	values := make(map[string]any)
+	if p.Name != nil {
+		values["Name"] = *p.Name
+	}
+	if p.Description != nil {
+		values["Description"] = *p.Description
+	}
+	if p.MOTD != nil {
+		values["MOTD"] = *p.MOTD
+	}
+	if p.MaxDownloads != nil {
+		values["MaxDownloads"] = *p.MaxDownloads
+	}
+	if p.MaxDownloadsPerClient != nil {
+		values["MaxDownloadsPerClient"] = *p.MaxDownloadsPerClient
+	}
+	if p.MaxConnectionsPerIP != nil {
+		values["MaxConnectionsPerIP"] = *p.MaxConnectionsPerIP
+	}
+
+	return values
+}
+
+// Apply returns a copy of config with the patch applied, or an error if the result is not a valid config.
+func (p ConfigPatch) Apply(config hotline.Config) (hotline.Config, error) {
+	p.set(&config)
+
+	if err := validator.New().Struct(config); err != nil {
+		return config, fmt.Errorf("validate config: %v", err)
+	}
+
+	return config, nil
+}
+
+// set changes the settings of config that are set in the patch, leaving the others untouched so that it can be used
+// with hotline.Server.UpdateConfig.
+func (p ConfigPatch) set(config *hotline.Config) {
+	if p.Name != nil {
+		config.Name = *p.Name
+	}
+	if p.Description != nil {
+		config.Description = *p.Description
+	}
+	if p.MOTD != nil {
+		config.MOTD = *p.MOTD
+	}
+	if p.MaxDownloads != nil {
+		config.MaxDownloads = *p.MaxDownloads
+	}
+	if p.MaxDownloadsPerClient != nil {
+		config.MaxDownloadsPerClient = *p.MaxDownloadsPerClient
+	}
+	if p.MaxConnectionsPerIP != nil {
+		config.MaxConnectionsPerIP = *p.MaxConnectionsPerIP
+	}
+}
+
+// SaveConfigPatch writes the settings in the patch to the config file at path.  Only the lines of the changed settings
+// are rewritten so that the comments and formatting of the rest of the file are preserved.
+func SaveConfigPatch(path string, p ConfigPatch) error {
+	return saveConfigValues(path, p.values())
+}
+
+// saveConfigValues writes settings keyed by their config.yaml key to the config file at path, rewriting only the lines
+// of the changed settings.  Settings that are not in the file are appended.
+func saveConfigValues(path string, values map[string]any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read file: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("unmarshal YAML: %v", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("unexpected config file structure")
+	}
+	mapping := doc.Content[0]
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		out, err := yaml.Marshal(map[string]any{key: values[key]})
+		if err != nil {
+			return fmt.Errorf("marshal yaml: %v", err)
+		}
+		line := strings.TrimSuffix(string(out), "\n")
+
+		idx := slices.IndexFunc(mapping.Content, func(n *yaml.Node) bool { return n.Value == key })
+		if idx < 0 || idx%2 != 0 {
+			lines = append(lines, line)
+			continue
+		}
+
+		value := mapping.Content[idx+1]
+		if value.Kind != yaml.ScalarNode || value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return fmt.Errorf("%s is not a single line value", key)
+		}
+		if value.LineComment != "" {
+			line += " " + value.LineComment
+		}
+		lines[mapping.Content[idx].Line-1] = line
+	}
+
+	return writeFileAtomic(path, []byte(strings.Join(lines, "\n")+"\n"), func(data []byte) error {
+		var config hotline.Config
+		return yaml.Unmarshal(data, &config)
+	})
+}
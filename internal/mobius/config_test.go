@@ -0,0 +1,78 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigPatch_Apply(t *testing.T) {
+	config := hotline.Config{Name: "Old name", Description: "Old description", FileRoot: "Files", MaxDownloads: 5}
+
+	name := "New name"
+	maxConns := 3
+	got, err := ConfigPatch{Name: &name, MaxConnectionsPerIP: &maxConns}.Apply(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "New name", got.Name)
+	assert.Equal(t, "Old description", got.Description)
+	assert.Equal(t, 5, got.MaxDownloads)
+	assert.Equal(t, 3, got.MaxConnectionsPerIP)
+	assert.Equal(t, "Old name", config.Name)
+
+	empty := ""
+	_, err = ConfigPatch{Name: &empty}.Apply(config)
+	assert.Error(t, err)
+}
+
+func TestSaveConfigPatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`# Name of the server
+Name: Old name
+
+# Description of the server
+Description: Old description # inline comment
+
+FileRoot: Files
+`), 0644))
+
+	name := "New name"
+	description := "Two words: quoted"
+	maxDownloads := 2
+	assert.NoError(t, SaveConfigPatch(path, ConfigPatch{Name: &name, Description: &description, MaxDownloads: &maxDownloads}))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `# Name of the server
+Name: New name
+
+# Description of the server
+Description: 'Two words: quoted' # inline comment
+
+FileRoot: Files
+MaxDownloads: 2
+`, string(data))
+
+	config, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "New name", config.Name)
+	assert.Equal(t, "Two words: quoted", config.Description)
+	assert.Equal(t, 2, config.MaxDownloads)
+}
+
+func TestLoadConfig_cleanupRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("Name: Test\nDescription: Test\nFileRoot: Files\nCleanupRules:\n  - Interval: 1h\n"), 0644))
+
+	_, err := LoadConfig(path)
+	assert.ErrorContains(t, err, "cleanup rule needs a Folder, Pattern, or MaxAge")
+}
+
+func TestLoadConfig_dedupNativeForks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("Name: Test\nDescription: Test\nFileRoot: Files\nEnableDedup: true\nNativeForks: true\n"), 0644))
+
+	_, err := LoadConfig(path)
+	assert.ErrorContains(t, err, "EnableDedup can not be used with NativeForks")
+}
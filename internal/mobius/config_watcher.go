@@ -0,0 +1,137 @@
+package mobius
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configWatchDebounce is how long to wait after the last change to a file before reloading it, so that editors that
+// write a file in several steps trigger a single reload.
+const configWatchDebounce = 500 * time.Millisecond
+
+// ConfigWatcher reloads config files when they are changed on disk.
+//
+// The parent directory of each file is watched rather than the file itself, so that changes made by editors that
+// replace the file with a new one are detected.
+type ConfigWatcher struct {
+	watcher *fsnotify.Watcher
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	targets []watchTarget
+	timers  map[string]*time.Timer
+}
+
+type watchTarget struct {
+	path   string // Path to a watched file, or a watched directory if dir is set
+	dir    bool   // Reload on changes to any file in the directory
+	reload func() error
+}
+
+func NewConfigWatcher(logger *slog.Logger) (*ConfigWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create watcher: %w", err)
+	}
+
+	return &ConfigWatcher{
+		watcher: w,
+		logger:  logger,
+		timers:  make(map[string]*time.Timer),
+	}, nil
+}
+
+// Watch calls reload when the file at path changes.
+func (cw *ConfigWatcher) Watch(path string, reload func() error) error {
+	return cw.add(watchTarget{path: filepath.Clean(path), reload: reload})
+}
+
+// WatchDir calls reload when any file in the directory at path changes.
+func (cw *ConfigWatcher) WatchDir(path string, reload func() error) error {
+	return cw.add(watchTarget{path: filepath.Clean(path), dir: true, reload: reload})
+}
+
+func (cw *ConfigWatcher) add(target watchTarget) error {
+	dir := target.path
+	if !target.dir {
+		dir = filepath.Dir(target.path)
+	}
+
+	if err := cw.watcher.Add(dir); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	cw.targets = append(cw.targets, target)
+
+	return nil
+}
+
+// Run dispatches file change events until ctx is cancelled.
+func (cw *ConfigWatcher) Run(ctx context.Context) {
+	defer func() { _ = cw.watcher.Close() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-cw.watcher.Events:
+			if !ok {
+				return
+			}
+			cw.handleEvent(event)
+		case err, ok := <-cw.watcher.Errors:
+			if !ok {
+				return
+			}
+			cw.logger.Error("Error watching config files", "err", err)
+		}
+	}
+}
+
+func (cw *ConfigWatcher) handleEvent(event fsnotify.Event) {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return
+	}
+
+	name := filepath.Clean(event.Name)
+
+	// Ignore hidden files, such as the temporary files used for atomic writes and editor swap files.
+	if strings.HasPrefix(filepath.Base(name), ".") {
+		return
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	for _, target := range cw.targets {
+		if target.path != name && !(target.dir && filepath.Dir(name) == target.path) {
+			continue
+		}
+
+		if timer, ok := cw.timers[target.path]; ok {
+			timer.Reset(configWatchDebounce)
+			continue
+		}
+
+		cw.timers[target.path] = time.AfterFunc(configWatchDebounce, func() {
+			cw.mu.Lock()
+			delete(cw.timers, target.path)
+			cw.mu.Unlock()
+
+			if err := target.reload(); err != nil {
+				cw.logger.Error("Error reloading changed config file", "path", target.path, "err", err)
+				return
+			}
+			cw.logger.Info("Reloaded changed config file", "path", target.path)
+		})
+	}
+}
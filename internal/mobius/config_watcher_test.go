@@ -0,0 +1,53 @@
+package mobius
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	dir := t.TempDir()
+	usersDir := filepath.Join(dir, "Users")
+	assert.NoError(t, os.Mkdir(usersDir, 0750))
+
+	cw, err := NewConfigWatcher(slog.Default())
+	assert.NoError(t, err)
+
+	var banReloads, accountReloads atomic.Int32
+	assert.NoError(t, cw.Watch(filepath.Join(dir, "Banlist.yaml"), func() error {
+		banReloads.Add(1)
+		return nil
+	}))
+	assert.NoError(t, cw.WatchDir(usersDir, func() error {
+		accountReloads.Add(1)
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cw.Run(ctx)
+
+	// Several writes in quick succession are debounced into a single reload.
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "Banlist.yaml"), []byte("{}"), 0644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(usersDir, "guest.yaml"), []byte("Login: guest"), 0644))
+
+	// Unwatched and hidden files are ignored.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "Agreement.txt"), []byte("hi"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(usersDir, ".guest.yaml.tmp123"), []byte("x"), 0644))
+
+	assert.Eventually(t, func() bool {
+		return banReloads.Load() == 1 && accountReloads.Load() == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	time.Sleep(2 * configWatchDebounce)
+	assert.Equal(t, int32(1), banReloads.Load())
+	assert.Equal(t, int32(1), accountReloads.Load())
+}
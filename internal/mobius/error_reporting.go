@@ -0,0 +1,237 @@
+package mobius
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/version"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewPanicReporter returns a reporter for the error reporting services enabled in config, or nil if none are enabled.
+func NewPanicReporter(config hotline.ErrorReporting, build version.Info, logger *slog.Logger) (hotline.PanicReporter, error) {
+	var reporters panicReporters
+
+	if config.SentryDSN != "" {
+		r, err := newSentryReporter(config.SentryDSN, build, logger)
+		if err != nil {
+			return nil, fmt.Errorf("sentry: %w", err)
+		}
+		reporters = append(reporters, r)
+	}
+
+	if config.OTLPEndpoint != "" {
+		reporters = append(reporters, &otlpReporter{
+			url:     strings.TrimSuffix(config.OTLPEndpoint, "/") + "/v1/logs",
+			headers: config.OTLPHeaders,
+			build:   build,
+			logger:  logger,
+		})
+	}
+
+	if len(reporters) == 0 {
+		return nil, nil
+	}
+
+	return reporters, nil
+}
+
+type panicReporters []hotline.PanicReporter
+
+func (rs panicReporters) ReportPanic(report hotline.PanicReport) {
+	for _, r := range rs {
+		r.ReportPanic(report)
+	}
+}
+
+// postJSON sends payload as a JSON POST request with the given headers.
+func postJSON(url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// stackFrame is a function call parsed from a stack trace.
+type stackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"filename"`
+	Line     int    `json:"lineno"`
+}
+
+// parseStack parses the frames of a stack trace in the format returned by debug.Stack, innermost call first.
+func parseStack(stack string) []stackFrame {
+	var frames []stackFrame
+
+	lines := strings.Split(stack, "\n")
+	for i := 1; i+1 < len(lines); i++ {
+		fileLine := lines[i+1]
+		if !strings.HasPrefix(fileLine, "\t") {
+			continue
+		}
+
+		function := lines[i]
+		if idx := strings.LastIndex(function, "("); idx > 0 {
+			function = function[:idx]
+		}
+
+		location, _, _ := strings.Cut(strings.TrimSpace(fileLine), " ")
+		file, lineStr, _ := strings.Cut(location, ":")
+		line, _ := strconv.Atoi(lineStr)
+
+		frames = append(frames, stackFrame{Function: function, File: file, Line: line})
+		i++
+	}
+
+	return frames
+}
+
+// sentryReporter sends panics to Sentry as events using the store API.
+type sentryReporter struct {
+	storeURL string
+	auth     string
+	build    version.Info
+	logger   *slog.Logger
+}
+
+func newSentryReporter(dsn string, build version.Info, logger *slog.Logger) (*sentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse DSN: %w", err)
+	}
+
+	key := u.User.Username()
+	projectID := strings.Trim(u.Path, "/")
+	if key == "" || projectID == "" {
+		return nil, fmt.Errorf("invalid DSN: expected https://<key>@<host>/<project>")
+	}
+
+	return &sentryReporter{
+		storeURL: fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, projectID),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=mobius/%s, sentry_key=%s", build.Version, key),
+		build:    build,
+		logger:   logger,
+	}, nil
+}
+
+func (r *sentryReporter) ReportPanic(report hotline.PanicReport) {
+	eventID := make([]byte, 16)
+	_, _ = rand.Read(eventID)
+
+	// Sentry expects frames ordered from outermost to innermost call.
+	frames := parseStack(report.Stack)
+	slices.Reverse(frames)
+
+	event := map[string]any{
+		"event_id":  hex.EncodeToString(eventID),
+		"timestamp": report.Time.UTC().Format(time.RFC3339),
+		"level":     "fatal",
+		"platform":  "go",
+		"logger":    "mobius",
+		"release":   r.build.Version,
+		"tags":      map[string]string{"commit": r.build.Commit, "build_date": r.build.Date},
+		"contexts": map[string]any{
+			"os":      map[string]string{"name": runtime.GOOS},
+			"runtime": map[string]string{"name": "go", "version": r.build.GoVersion},
+		},
+		"exception": map[string]any{
+			"values": []map[string]any{
+				{
+					"type":       "panic",
+					"value":      fmt.Sprint(report.Value),
+					"stacktrace": map[string]any{"frames": frames},
+				},
+			},
+		},
+	}
+
+	if err := postJSON(r.storeURL, map[string]string{"X-Sentry-Auth": r.auth}, event); err != nil {
+		r.logger.Error("Error reporting panic to Sentry", "err", err)
+	}
+}
+
+// otlpReporter sends panics to an OpenTelemetry collector as log records using OTLP/HTTP with JSON encoding.
+type otlpReporter struct {
+	url     string
+	headers map[string]string
+	build   version.Info
+	logger  *slog.Logger
+}
+
+func otlpString(key, value string) map[string]any {
+	return map[string]any{"key": key, "value": map[string]string{"stringValue": value}}
+}
+
+func (r *otlpReporter) ReportPanic(report hotline.PanicReport) {
+	msg := fmt.Sprint(report.Value)
+
+	payload := map[string]any{
+		"resourceLogs": []map[string]any{
+			{
+				"resource": map[string]any{
+					"attributes": []map[string]any{
+						otlpString("service.name", "mobius"),
+						otlpString("service.version", r.build.Version),
+						otlpString("vcs.revision", r.build.Commit),
+						otlpString("mobius.build.date", r.build.Date),
+						otlpString("os.type", runtime.GOOS),
+						otlpString("process.runtime.version", r.build.GoVersion),
+					},
+				},
+				"scopeLogs": []map[string]any{
+					{
+						"scope": map[string]string{"name": "mobius"},
+						"logRecords": []map[string]any{
+							{
+								"timeUnixNano":   strconv.FormatInt(report.Time.UnixNano(), 10),
+								"severityNumber": 21, // FATAL
+								"severityText":   "FATAL",
+								"body":           map[string]string{"stringValue": "panic: " + msg},
+								"attributes": []map[string]any{
+									otlpString("exception.type", "panic"),
+									otlpString("exception.message", msg),
+									otlpString("exception.stacktrace", report.Stack),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := postJSON(r.url, r.headers, payload); err != nil {
+		r.logger.Error("Error reporting panic to OTLP collector", "err", err)
+	}
+}
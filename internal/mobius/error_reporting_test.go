@@ -0,0 +1,106 @@
+package mobius
+
+import (
+	"encoding/json"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/jhalter/mobius/internal/version"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testStack = `goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:26 +0x5e
+github.com/jhalter/mobius/hotline.(*Server).dontPanic(0xc000100000)
+	/src/hotline/panic.go:24 +0x45
+panic({0x8a2b40?, 0xd3e1c0?})
+	/usr/local/go/src/runtime/panic.go:785 +0x132
+github.com/jhalter/mobius/hotline.(*ClientConn).String(0xc000200000)
+	/src/hotline/client_conn.go:254 +0x1d
+`
+
+func TestParseStack(t *testing.T) {
+	assert.Equal(t, []stackFrame{
+		{Function: "runtime/debug.Stack", File: "/usr/local/go/src/runtime/debug/stack.go", Line: 26},
+		{Function: "github.com/jhalter/mobius/hotline.(*Server).dontPanic", File: "/src/hotline/panic.go", Line: 24},
+		{Function: "panic", File: "/usr/local/go/src/runtime/panic.go", Line: 785},
+		{Function: "github.com/jhalter/mobius/hotline.(*ClientConn).String", File: "/src/hotline/client_conn.go", Line: 254},
+	}, parseStack(testStack))
+}
+
+type capturedRequest struct {
+	path    string
+	headers http.Header
+	body    map[string]any
+}
+
+var testBuild = version.Info{Version: "1.2.3", Commit: "abc1234", Date: "2025-03-01T20:00:00Z", GoVersion: "go1.23.4"}
+
+func captureServer(t *testing.T, captured *capturedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		captured.path = r.URL.Path
+		captured.headers = r.Header
+		assert.NoError(t, json.Unmarshal(data, &captured.body))
+	}))
+}
+
+func TestSentryReporter(t *testing.T) {
+	var captured capturedRequest
+	ts := captureServer(t, &captured)
+	defer ts.Close()
+
+	dsn := strings.Replace(ts.URL, "http://", "http://abc123@", 1) + "/42"
+	reporter, err := NewPanicReporter(hotline.ErrorReporting{SentryDSN: dsn}, testBuild, slog.Default())
+	assert.NoError(t, err)
+
+	reporter.ReportPanic(hotline.PanicReport{Value: "boom", Stack: testStack, Time: time.Now()})
+
+	assert.Equal(t, "/api/42/store/", captured.path)
+	assert.Contains(t, captured.headers.Get("X-Sentry-Auth"), "sentry_key=abc123")
+	assert.Equal(t, "1.2.3", captured.body["release"])
+	assert.Equal(t, map[string]any{"commit": "abc1234", "build_date": "2025-03-01T20:00:00Z"}, captured.body["tags"])
+
+	exception := captured.body["exception"].(map[string]any)["values"].([]any)[0].(map[string]any)
+	assert.Equal(t, "boom", exception["value"])
+
+	frames := exception["stacktrace"].(map[string]any)["frames"].([]any)
+	assert.Len(t, frames, 4)
+	assert.Equal(t, "github.com/jhalter/mobius/hotline.(*ClientConn).String", frames[0].(map[string]any)["function"])
+
+	_, err = NewPanicReporter(hotline.ErrorReporting{SentryDSN: "https://sentry.example.com/"}, testBuild, slog.Default())
+	assert.Error(t, err)
+}
+
+func TestOTLPReporter(t *testing.T) {
+	var captured capturedRequest
+	ts := captureServer(t, &captured)
+	defer ts.Close()
+
+	reporter, err := NewPanicReporter(hotline.ErrorReporting{
+		OTLPEndpoint: ts.URL + "/",
+		OTLPHeaders:  map[string]string{"Authorization": "Bearer token"},
+	}, testBuild, slog.Default())
+	assert.NoError(t, err)
+
+	reporter.ReportPanic(hotline.PanicReport{Value: "boom", Stack: testStack, Time: time.Unix(1, 0)})
+
+	assert.Equal(t, "/v1/logs", captured.path)
+	assert.Equal(t, "Bearer token", captured.headers.Get("Authorization"))
+
+	record := captured.body["resourceLogs"].([]any)[0].(map[string]any)["scopeLogs"].([]any)[0].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+	assert.Equal(t, "1000000000", record["timeUnixNano"])
+	assert.Equal(t, map[string]any{"stringValue": "panic: boom"}, record["body"])
+}
+
+func TestNewPanicReporter_disabled(t *testing.T) {
+	reporter, err := NewPanicReporter(hotline.ErrorReporting{}, testBuild, slog.Default())
+	assert.NoError(t, err)
+	assert.Nil(t, reporter)
+}
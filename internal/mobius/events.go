@@ -0,0 +1,58 @@
+package mobius
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// EventsYAML reads community events from a YAML file maintained by the server admin.
+type EventsYAML struct {
+	events   []hotline.Event // ordered by start time
+	filePath string
+
+	mu sync.Mutex
+}
+
+func NewEventsYAML(filePath string) (*EventsYAML, error) {
+	el := &EventsYAML{filePath: filePath}
+
+	if err := el.Load(); err != nil {
+		return nil, fmt.Errorf("load events: %w", err)
+	}
+
+	return el, nil
+}
+
+// Load reads the events file.  A missing or empty file is treated as an empty list of events.
+func (el *EventsYAML) Load() error {
+	var events []hotline.Event
+
+	err := loadFromYAMLFile(el.filePath, &events)
+	if err != nil && !os.IsNotExist(err) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode yaml: %v", err)
+	}
+
+	slices.SortStableFunc(events, func(a, b hotline.Event) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	el.events = events
+
+	return nil
+}
+
+// List returns all events ordered by start time.
+func (el *EventsYAML) List() []hotline.Event {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	return slices.Clone(el.events)
+}
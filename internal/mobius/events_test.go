@@ -0,0 +1,42 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventsYAML(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Events.yaml")
+
+	// A missing or empty events file has no events.
+	el, err := NewEventsYAML(filePath)
+	require.NoError(t, err)
+	assert.Empty(t, el.List())
+
+	require.NoError(t, os.WriteFile(filePath, nil, 0644))
+	require.NoError(t, el.Load())
+	assert.Empty(t, el.List())
+
+	require.NoError(t, os.WriteFile(filePath, []byte(`
+- Title: Game night
+  Start: 2025-03-08T20:00:00Z
+- Title: Swap meet
+  Description: Bring your spare floppies
+  Start: 2025-03-01T18:00:00Z
+`), 0644))
+	require.NoError(t, el.Load())
+
+	// Events are ordered by start time.
+	assert.Equal(t, []hotline.Event{
+		{Title: "Swap meet", Description: "Bring your spare floppies", Start: time.Date(2025, 3, 1, 18, 0, 0, 0, time.UTC)},
+		{Title: "Game night", Start: time.Date(2025, 3, 8, 20, 0, 0, 0, time.UTC)},
+	}, el.List())
+
+	require.NoError(t, os.WriteFile(filePath, []byte("Title: not a list\n"), 0644))
+	assert.Error(t, el.Load())
+}
@@ -0,0 +1,134 @@
+package mobius
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// FileReportsYAML persists user submitted file reports to a YAML file.
+type FileReportsYAML struct {
+	reports  map[string]hotline.FileReport
+	filePath string
+
+	mu sync.Mutex
+}
+
+func NewFileReportsYAML(filePath string) (*FileReportsYAML, error) {
+	fr := &FileReportsYAML{
+		filePath: filePath,
+		reports:  make(map[string]hotline.FileReport),
+	}
+
+	if err := fr.Load(); err != nil {
+		return nil, fmt.Errorf("load file reports: %w", err)
+	}
+
+	return fr, nil
+}
+
+func (fr *FileReportsYAML) Load() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	fr.reports = make(map[string]hotline.FileReport)
+
+	var reports []hotline.FileReport
+	err := loadFromYAMLFile(fr.filePath, &reports)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("decode yaml: %v", err)
+	}
+
+	for _, report := range reports {
+		fr.reports[report.ID] = report
+	}
+
+	return nil
+}
+
+// Add assigns the next available ID to the report and saves it.
+func (fr *FileReportsYAML) Add(report hotline.FileReport) (hotline.FileReport, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	var nextID int
+	for id := range fr.reports {
+		if n, err := strconv.Atoi(id); err == nil && n > nextID {
+			nextID = n
+		}
+	}
+	report.ID = strconv.Itoa(nextID + 1)
+
+	fr.reports[report.ID] = report
+
+	return report, fr.writeFile()
+}
+
+// List returns the reports sorted from oldest to newest.
+func (fr *FileReportsYAML) List() []hotline.FileReport {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return fr.sorted()
+}
+
+func (fr *FileReportsYAML) Delete(id string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if _, ok := fr.reports[id]; !ok {
+		return fmt.Errorf("report %s not found", id)
+	}
+
+	delete(fr.reports, id)
+
+	return fr.writeFile()
+}
+
+// RenameReporter is an AccountRenameHook that updates the reporter of reports made by a renamed account.
+func (fr *FileReportsYAML) RenameReporter(oldLogin, newLogin string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	var changed bool
+	for id, report := range fr.reports {
+		if report.Reporter == oldLogin {
+			report.Reporter = newLogin
+			fr.reports[id] = report
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return fr.writeFile()
+}
+
+func (fr *FileReportsYAML) sorted() []hotline.FileReport {
+	reports := make([]hotline.FileReport, 0, len(fr.reports))
+	for _, report := range fr.reports {
+		reports = append(reports, report)
+	}
+
+	slices.SortFunc(reports, func(a, b hotline.FileReport) int {
+		return cmp.Or(
+			a.Date.Compare(b.Date),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	return reports
+}
+
+func (fr *FileReportsYAML) writeFile() error {
+	return writeYAMLFile(fr.filePath, fr.sorted())
+}
@@ -0,0 +1,53 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileReportsYAML(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "FileReports.yaml")
+
+	fr, err := NewFileReportsYAML(filePath)
+	assert.NoError(t, err)
+	assert.Empty(t, fr.List())
+
+	first, err := fr.Add(hotline.FileReport{Path: "Uploads/a.sit", Reason: "spam", Reporter: "guest", Date: time.Unix(100, 0)})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", first.ID)
+
+	second, err := fr.Add(hotline.FileReport{Path: "Uploads/b.sit", Reason: "broken", Reporter: "guest", Date: time.Unix(200, 0)})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", second.ID)
+
+	// Reports should survive a reload from disk.
+	reloaded, err := NewFileReportsYAML(filePath)
+	assert.NoError(t, err)
+	assert.Len(t, reloaded.List(), 2)
+	assert.Equal(t, "Uploads/a.sit", reloaded.List()[0].Path)
+
+	assert.NoError(t, reloaded.Delete("1"))
+	assert.Error(t, reloaded.Delete("1"))
+	assert.Equal(t, []hotline.FileReport{reloaded.List()[0]}, reloaded.List())
+	assert.Equal(t, "2", reloaded.List()[0].ID)
+}
+
+func TestFileReportsYAML_RenameReporter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "FileReports.yaml")
+
+	fr, err := NewFileReportsYAML(filePath)
+	assert.NoError(t, err)
+
+	_, _ = fr.Add(hotline.FileReport{Path: "a.sit", Reporter: "alice", Date: time.Unix(100, 0)})
+	_, _ = fr.Add(hotline.FileReport{Path: "b.sit", Reporter: "bob", Date: time.Unix(200, 0)})
+
+	assert.NoError(t, fr.RenameReporter("alice", "alicia"))
+
+	reloaded, err := NewFileReportsYAML(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "alicia", reloaded.List()[0].Reporter)
+	assert.Equal(t, "bob", reloaded.List()[1].Reporter)
+}
@@ -0,0 +1,104 @@
+package mobius
+
+import (
+	"context"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/jhalter/mobius/hotline"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// FileRootWatcher invalidates cached file listings and folder sizes when files in the file root are changed outside
+// the server, e.g. by an FTP server or rsync job, instead of waiting for the cached entries to expire.
+//
+// Each folder in the file root is watched, and folders created later are added as they appear.  If a folder can't be
+// watched, e.g. because the OS limit on watches has been reached, changes in it are picked up when the cache expires.
+type FileRootWatcher struct {
+	watcher *fsnotify.Watcher
+	index   *hotline.FileIndex
+	logger  *slog.Logger
+}
+
+func NewFileRootWatcher(root string, index *hotline.FileIndex, logger *slog.Logger) (*FileRootWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create watcher: %w", err)
+	}
+
+	frw := &FileRootWatcher{watcher: w, index: index, logger: logger}
+	if err := frw.addTree(root); err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("watch %s: %w", root, err)
+	}
+
+	return frw, nil
+}
+
+// addTree watches the folder at path and the folders within it.  Folders that can't be watched are logged and skipped.
+func (frw *FileRootWatcher) addTree(path string) error {
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == path {
+				return err
+			}
+			frw.logger.Warn("Error reading folder to watch for changes", "path", p, "err", err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if err := frw.watcher.Add(p); err != nil {
+			if p == path {
+				return err
+			}
+			frw.logger.Warn("Error watching folder for changes", "path", p, "err", err)
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+}
+
+// Run invalidates the cached entries of changed files until ctx is cancelled.
+func (frw *FileRootWatcher) Run(ctx context.Context) {
+	defer func() { _ = frw.watcher.Close() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-frw.watcher.Events:
+			if !ok {
+				return
+			}
+			frw.handleEvent(event)
+		case err, ok := <-frw.watcher.Errors:
+			if !ok {
+				return
+			}
+			frw.logger.Error("Error watching file root", "err", err)
+		}
+	}
+}
+
+func (frw *FileRootWatcher) handleEvent(event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+
+	// Folders created or moved into the file root are watched along with their contents, before the change is made
+	// visible, so that changes to their contents aren't missed.
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+			if err := frw.addTree(event.Name); err != nil {
+				frw.logger.Warn("Error watching folder for changes", "path", event.Name, "err", err)
+			}
+		}
+	}
+
+	frw.index.Invalidate(event.Name)
+}
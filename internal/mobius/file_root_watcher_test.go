@@ -0,0 +1,47 @@
+package mobius
+
+import (
+	"context"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRootWatcher(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	require.NoError(t, os.Mkdir(sub, 0750))
+
+	index := hotline.NewFileIndex()
+	frw, err := NewFileRootWatcher(root, index, slog.Default())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go frw.Run(ctx)
+
+	listLen := func(path string) int {
+		fields, err := index.FileList(path, nil, hotline.FileListOptions{})
+		require.NoError(t, err)
+		return len(fields)
+	}
+
+	// Files added by other programs show up without waiting for the cache to expire.
+	assert.Equal(t, 0, listLen(sub))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "a.txt"), []byte("a"), 0644))
+	assert.Eventually(t, func() bool { return listLen(sub) == 1 }, 5*time.Second, 50*time.Millisecond)
+
+	// Folders created after the watcher started are watched too.
+	newDir := filepath.Join(root, "new")
+	require.NoError(t, os.Mkdir(newDir, 0750))
+	assert.Eventually(t, func() bool { return listLen(root) == 2 }, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, 0, listLen(newDir))
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "b.txt"), []byte("b"), 0644))
+	assert.Eventually(t, func() bool { return listLen(newDir) == 1 }, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,92 @@
+package mobius
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"os"
+)
+
+// FileService manages files and folders.  Paths are full paths within the file store, resolved by the caller from
+// the user's file root.
+type FileService struct {
+	FS    hotline.FileStore
+	Index *hotline.FileIndex // Optional; cached listings and folder sizes to invalidate when folder contents change
+}
+
+// Delete deletes the file or folder at filePath.  Name is the name shown to the user in error messages.
+func (s FileService) Delete(actor Actor, filePath, name string) error {
+	hlFile, err := hotline.NewFileWrapper(s.FS, filePath, 0)
+	if err != nil {
+		return err
+	}
+
+	fi, err := hlFile.DataFile()
+	if err != nil {
+		return userErr("Cannot delete file %s because it does not exist or cannot be found.", name)
+	}
+
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		if !actor.Authorize(hotline.AccessDeleteFolder) {
+			return userErr("You are not allowed to delete folders.")
+		}
+	case mode.IsRegular():
+		if !actor.Authorize(hotline.AccessDeleteFile) {
+			return userErr("You are not allowed to delete files.")
+		}
+	}
+
+	err = hlFile.Delete()
+	s.Index.Invalidate(filePath)
+
+	return err
+}
+
+// Move moves the file or folder at filePath into the folder newDir.  Name is the name shown to the user in error
+// messages.
+func (s FileService) Move(actor Actor, filePath, newDir, name string) error {
+	hlFile, err := hotline.NewFileWrapper(s.FS, filePath, 0)
+	if err != nil {
+		return err
+	}
+
+	fi, err := hlFile.DataFile()
+	if err != nil {
+		return userErr("Cannot delete file %s because it does not exist or cannot be found.", name)
+	}
+
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		if !actor.Authorize(hotline.AccessMoveFolder) {
+			return userErr("You are not allowed to move folders.")
+		}
+	case mode.IsRegular():
+		if !actor.Authorize(hotline.AccessMoveFile) {
+			return userErr("You are not allowed to move files.")
+		}
+	}
+
+	err = hlFile.Move(newDir)
+	s.Index.Invalidate(filePath)
+	s.Index.Invalidate(newDir)
+
+	return err
+}
+
+// NewFolder creates a folder at folderPath.  Name is the name shown to the user in error messages.
+func (s FileService) NewFolder(actor Actor, folderPath, name string) error {
+	if !actor.Authorize(hotline.AccessCreateFolder) {
+		return userErr("You are not allowed to create folders.")
+	}
+
+	if _, err := s.FS.Stat(folderPath); !os.IsNotExist(err) {
+		return userErr("Cannot create folder \"%s\" because there is already a file or folder with that Name.", name)
+	}
+
+	if err := s.FS.Mkdir(folderPath, 0777); err != nil {
+		return fmt.Errorf("%w: %w", userErr("Cannot create folder \"%s\" because an error occurred.", name), err)
+	}
+	s.Index.Invalidate(folderPath)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileService(t *testing.T) {
+	root := t.TempDir()
+	svc := FileService{FS: &hotline.OSFileStore{}}
+
+	folder := filepath.Join(root, "Uploads")
+	assert.EqualError(t, svc.NewFolder(newTestActor(), folder, "Uploads"), "You are not allowed to create folders.")
+	require.NoError(t, svc.NewFolder(newTestActor(hotline.AccessCreateFolder), folder, "Uploads"))
+	assert.EqualError(t,
+		svc.NewFolder(newTestActor(hotline.AccessCreateFolder), folder, "Uploads"),
+		`Cannot create folder "Uploads" because there is already a file or folder with that Name.`,
+	)
+
+	file := filepath.Join(root, "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte("hello"), 0640))
+
+	assert.EqualError(t, svc.Move(newTestActor(hotline.AccessMoveFolder), file, folder, "a.txt"), "You are not allowed to move files.")
+	require.NoError(t, svc.Move(newTestActor(hotline.AccessMoveFile), file, folder, "a.txt"))
+	assert.FileExists(t, filepath.Join(folder, "a.txt"))
+
+	assert.EqualError(t,
+		svc.Delete(newTestActor(hotline.AccessDeleteFile), file, "a.txt"),
+		"Cannot delete file a.txt because it does not exist or cannot be found.",
+	)
+	assert.EqualError(t, svc.Delete(newTestActor(hotline.AccessDeleteFile), folder, "Uploads"), "You are not allowed to delete folders.")
+	require.NoError(t, svc.Delete(newTestActor(hotline.AccessDeleteFolder), folder, "Uploads"))
+	assert.NoDirExists(t, folder)
+}
@@ -0,0 +1,185 @@
+package mobius
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// incompleteUploadsWriteDelay is how long changes are batched before the file is rewritten, so that it isn't rewritten
+// at the start and end of every upload on busy servers.
+const incompleteUploadsWriteDelay = 5 * time.Second
+
+// IncompleteUploadsYAML persists the owners of interrupted uploads to a YAML file.  Changes are written after
+// incompleteUploadsWriteDelay, or when Flush is called.  An error from a delayed write is returned by the next change.
+type IncompleteUploadsYAML struct {
+	uploads  map[string]hotline.IncompleteUpload // keyed by FullPath
+	filePath string
+
+	mu         sync.Mutex
+	writeTimer *time.Timer // Pending write of changes, if any
+	writeErr   error       // Error of the last delayed write
+}
+
+func NewIncompleteUploadsYAML(filePath string) (*IncompleteUploadsYAML, error) {
+	iu := &IncompleteUploadsYAML{
+		filePath: filePath,
+		uploads:  make(map[string]hotline.IncompleteUpload),
+	}
+
+	if err := iu.Load(); err != nil {
+		return nil, fmt.Errorf("load incomplete uploads: %w", err)
+	}
+
+	return iu, nil
+}
+
+func (iu *IncompleteUploadsYAML) Load() error {
+	iu.mu.Lock()
+	defer iu.mu.Unlock()
+
+	iu.uploads = make(map[string]hotline.IncompleteUpload)
+
+	var uploads []hotline.IncompleteUpload
+	err := loadFromYAMLFile(iu.filePath, &uploads)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("decode yaml: %v", err)
+	}
+
+	for _, upload := range uploads {
+		iu.uploads[upload.FullPath] = upload
+	}
+
+	return nil
+}
+
+// Add records an upload.  If the upload is already recorded, e.g. because it is being resumed, its original start time
+// is kept.
+func (iu *IncompleteUploadsYAML) Add(upload hotline.IncompleteUpload) error {
+	iu.mu.Lock()
+	defer iu.mu.Unlock()
+
+	if existing, ok := iu.uploads[upload.FullPath]; ok && existing.Login == upload.Login {
+		upload.Started = existing.Started
+	}
+
+	iu.uploads[upload.FullPath] = upload
+
+	return iu.scheduleWrite()
+}
+
+// List returns the uploads by the account with the given login, sorted from oldest to newest.
+func (iu *IncompleteUploadsYAML) List(login string) []hotline.IncompleteUpload {
+	iu.mu.Lock()
+	defer iu.mu.Unlock()
+
+	return slices.DeleteFunc(iu.sorted(), func(upload hotline.IncompleteUpload) bool {
+		return upload.Login != login
+	})
+}
+
+// Delete removes an upload from the list.  Deleting an upload that isn't recorded is not an error.
+func (iu *IncompleteUploadsYAML) Delete(fullPath string) error {
+	iu.mu.Lock()
+	defer iu.mu.Unlock()
+
+	if _, ok := iu.uploads[fullPath]; !ok {
+		return nil
+	}
+
+	delete(iu.uploads, fullPath)
+
+	return iu.scheduleWrite()
+}
+
+// RenameUploader is an AccountRenameHook that updates the owner of uploads made by a renamed account.
+func (iu *IncompleteUploadsYAML) RenameUploader(oldLogin, newLogin string) error {
+	iu.mu.Lock()
+	defer iu.mu.Unlock()
+
+	var changed bool
+	for fullPath, upload := range iu.uploads {
+		if upload.Login == oldLogin {
+			upload.Login = newLogin
+			iu.uploads[fullPath] = upload
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return iu.scheduleWrite()
+}
+
+// Flush writes pending changes to the file, e.g. before the server exits.
+func (iu *IncompleteUploadsYAML) Flush() error {
+	iu.mu.Lock()
+	defer iu.mu.Unlock()
+
+	err := iu.flush()
+	if err == nil {
+		err = iu.writeErr
+	}
+	iu.writeErr = nil
+
+	return err
+}
+
+// scheduleWrite starts the timer of a delayed write unless one is already pending, and returns the error of the last
+// delayed write.
+func (iu *IncompleteUploadsYAML) scheduleWrite() error {
+	err := iu.writeErr
+	iu.writeErr = nil
+
+	if iu.writeTimer == nil {
+		iu.writeTimer = time.AfterFunc(incompleteUploadsWriteDelay, func() {
+			iu.mu.Lock()
+			defer iu.mu.Unlock()
+
+			if err := iu.flush(); err != nil {
+				iu.writeErr = err
+			}
+		})
+	}
+
+	return err
+}
+
+func (iu *IncompleteUploadsYAML) flush() error {
+	if iu.writeTimer == nil {
+		return nil
+	}
+	iu.writeTimer.Stop()
+	iu.writeTimer = nil
+
+	return iu.writeFile()
+}
+
+func (iu *IncompleteUploadsYAML) sorted() []hotline.IncompleteUpload {
+	uploads := make([]hotline.IncompleteUpload, 0, len(iu.uploads))
+	for _, upload := range iu.uploads {
+		uploads = append(uploads, upload)
+	}
+
+	slices.SortFunc(uploads, func(a, b hotline.IncompleteUpload) int {
+		return cmp.Or(
+			a.Started.Compare(b.Started),
+			cmp.Compare(a.FullPath, b.FullPath),
+		)
+	})
+
+	return uploads
+}
+
+func (iu *IncompleteUploadsYAML) writeFile() error {
+	return writeYAMLFile(iu.filePath, iu.sorted())
+}
@@ -0,0 +1,119 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIncompleteUploadsYAML(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "IncompleteUploads.yaml")
+
+	iu, err := NewIncompleteUploadsYAML(filePath)
+	assert.NoError(t, err)
+	assert.Empty(t, iu.List("alice"))
+
+	assert.NoError(t, iu.Add(hotline.IncompleteUpload{FullPath: "/files/Uploads/a.sit", Path: "Uploads/a.sit", Login: "alice", Started: time.Unix(100, 0)}))
+	assert.NoError(t, iu.Add(hotline.IncompleteUpload{FullPath: "/files/Uploads/b.sit", Path: "Uploads/b.sit", Login: "bob", Started: time.Unix(200, 0)}))
+
+	// Resuming an upload keeps its original start time.
+	assert.NoError(t, iu.Add(hotline.IncompleteUpload{FullPath: "/files/Uploads/a.sit", Path: "Uploads/a.sit", Login: "alice", Started: time.Unix(300, 0)}))
+
+	// Changes are written once they are flushed.
+	assert.NoFileExists(t, filePath)
+	assert.NoError(t, iu.Flush())
+
+	// Uploads should survive a reload from disk.
+	reloaded, err := NewIncompleteUploadsYAML(filePath)
+	assert.NoError(t, err)
+	assert.Len(t, reloaded.List("alice"), 1)
+	assert.Equal(t, "Uploads/a.sit", reloaded.List("alice")[0].Path)
+	assert.True(t, time.Unix(100, 0).Equal(reloaded.List("alice")[0].Started))
+
+	assert.NoError(t, reloaded.RenameUploader("alice", "alicia"))
+	assert.Empty(t, reloaded.List("alice"))
+	assert.Len(t, reloaded.List("alicia"), 1)
+
+	assert.NoError(t, reloaded.Delete("/files/Uploads/a.sit"))
+	assert.NoError(t, reloaded.Delete("/files/Uploads/a.sit"))
+	assert.Empty(t, reloaded.List("alicia"))
+	assert.Len(t, reloaded.List("bob"), 1)
+}
+
+func TestHandleGetIncompleteUploads(t *testing.T) {
+	fileRoot := t.TempDir()
+	fullPath := filepath.Join(fileRoot, "Uploads", "a.sit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+	require.NoError(t, os.WriteFile(fullPath+hotline.IncompleteFileSuffix, make([]byte, 1024), 0644))
+
+	started := time.Now().Add(-time.Hour)
+	mgr := &hotline.MockIncompleteUploadMgr{}
+	mgr.On("List", "alice").Return([]hotline.IncompleteUpload{
+		{FullPath: fullPath, Path: "Uploads/a.sit", Login: "alice", Started: started},
+		{FullPath: filepath.Join(fileRoot, "gone.sit"), Path: "gone.sit", Login: "alice", Started: started},
+	})
+	mgr.On("Delete", filepath.Join(fileRoot, "gone.sit")).Return(nil)
+
+	cc := &hotline.ClientConn{
+		Account: &hotline.Account{Login: "alice"},
+		Server:  &hotline.Server{FS: &hotline.OSFileStore{}, IncompleteUploadMgr: mgr},
+		Logger:  NewTestLogger(),
+	}
+
+	res := HandleGetIncompleteUploads(cc, &hotline.Transaction{Type: hotline.TranGetIncompleteUploads})
+
+	require.Len(t, res, 1)
+	require.Len(t, res[0].Fields, 1)
+	data := res[0].Fields[0].Data
+	assert.Equal(t, []byte{0, 0, 0x04, 0}, data[0:4])
+	assert.Equal(t, []byte("Uploads/a.sit"), data[10:])
+	mgr.AssertExpectations(t)
+}
+
+func TestHandleDeleteIncompleteUpload(t *testing.T) {
+	fileRoot := t.TempDir()
+	fullPath := filepath.Join(fileRoot, "Uploads", "a.sit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+	require.NoError(t, os.WriteFile(fullPath+hotline.IncompleteFileSuffix, []byte("partial"), 0644))
+
+	mgr := &hotline.MockIncompleteUploadMgr{}
+	mgr.On("List", "alice").Return([]hotline.IncompleteUpload{
+		{FullPath: fullPath, Path: "Uploads/a.sit", Login: "alice"},
+	})
+	mgr.On("Delete", fullPath).Return(nil)
+
+	cc := &hotline.ClientConn{
+		Account: &hotline.Account{Login: "alice"},
+		Server:  &hotline.Server{FS: &hotline.OSFileStore{}, IncompleteUploadMgr: mgr},
+		Logger:  NewTestLogger(),
+	}
+
+	// Uploads by other accounts, or that don't exist, can't be deleted.
+	res := HandleDeleteIncompleteUpload(cc, &hotline.Transaction{
+		Type: hotline.TranDeleteIncompleteUpload,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldFileName, []byte("b.sit")),
+			hotline.NewField(hotline.FieldFilePath, hotline.EncodeFilePath("Uploads")),
+		},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("Incomplete upload not found."))},
+	}}, res)
+
+	res = HandleDeleteIncompleteUpload(cc, &hotline.Transaction{
+		Type: hotline.TranDeleteIncompleteUpload,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldFileName, []byte("a.sit")),
+			hotline.NewField(hotline.FieldFilePath, hotline.EncodeFilePath("Uploads")),
+		},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{IsReply: 0x01}}, res)
+	assert.NoFileExists(t, fullPath+hotline.IncompleteFileSuffix)
+	mgr.AssertExpectations(t)
+}
@@ -0,0 +1,155 @@
+package mobius
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"unicode"
+)
+
+const (
+	journaldSocket     = "/run/systemd/journal/socket"
+	journaldIdentifier = "mobius"
+)
+
+// journaldHandler sends log records to the systemd journal using its native protocol, so that each record attribute is
+// stored as a separate journal field, e.g. the "connID" attribute becomes the CONNID field.
+type journaldHandler struct {
+	conn   *net.UnixConn
+	level  slog.Leveler
+	prefix string   // Field name prefix from WithGroup
+	fields []string // Encoded fields from WithAttrs
+}
+
+func newJournaldHandler(socket string, opts *slog.HandlerOptions) (slog.Handler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	level := opts.Level
+	if level == nil {
+		level = slog.LevelInfo
+	}
+
+	return &journaldHandler{conn: conn, level: level}, nil
+}
+
+func (h *journaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *journaldHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", r.Message)
+	appendJournalField(&buf, "PRIORITY", journaldPriority(r.Level))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", journaldIdentifier)
+	for _, f := range h.fields {
+		buf.WriteString(f)
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		for _, f := range journalFields(h.prefix, a) {
+			buf.WriteString(f)
+		}
+		return true
+	})
+
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *journaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = append([]string(nil), h.fields...)
+	for _, a := range attrs {
+		h2.fields = append(h2.fields, journalFields(h.prefix, a)...)
+	}
+	return &h2
+}
+
+func (h *journaldHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+// journalFields returns the encoded journal fields for attribute a, flattening groups into prefixed field names.
+func journalFields(prefix string, a slog.Attr) []string {
+	v := a.Value.Resolve()
+
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "_"
+		}
+		var fields []string
+		for _, ga := range v.Group() {
+			fields = append(fields, journalFields(prefix, ga)...)
+		}
+		return fields
+	}
+
+	if a.Key == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	appendJournalField(&buf, journalFieldName(prefix+a.Key), v.String())
+
+	return []string{buf.String()}
+}
+
+// journalFieldName converts key to a valid journal field name: upper case letters, digits, and underscores, not
+// starting with an underscore or digit.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return unicode.ToUpper(r)
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "FIELD_" + name
+	}
+
+	return name[:min(len(name), 64)]
+}
+
+// appendJournalField encodes a field in the journal native protocol.  Values containing newlines use the binary
+// length-prefixed form.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		_, _ = fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journaldPriority returns the syslog priority for level.
+func journaldPriority(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "3"
+	case level >= slog.LevelWarn:
+		return "4"
+	case level >= slog.LevelInfo:
+		return "6"
+	default:
+		return "7"
+	}
+}
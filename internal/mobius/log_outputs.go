@@ -0,0 +1,73 @@
+package mobius
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+)
+
+// LogHandlers returns handlers for the additional log outputs enabled in config, such as syslog and the systemd
+// journal.
+func LogHandlers(config hotline.Config, logLevel string) ([]slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: logLevels[logLevel]}
+
+	var handlers []slog.Handler
+
+	if config.LogSyslog != nil {
+		h, err := newSyslogHandler(*config.LogSyslog, opts)
+		if err != nil {
+			return nil, fmt.Errorf("syslog: %w", err)
+		}
+		handlers = append(handlers, h)
+	}
+
+	if config.LogJournald {
+		h, err := newJournaldHandler(journaldSocket, opts)
+		if err != nil {
+			return nil, fmt.Errorf("journald: %w", err)
+		}
+		handlers = append(handlers, h)
+	}
+
+	return handlers, nil
+}
+
+// MultiHandler is a slog.Handler that sends each record to all of its handlers.
+type MultiHandler []slog.Handler
+
+func (m MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(MultiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(MultiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
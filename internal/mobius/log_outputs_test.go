@@ -0,0 +1,63 @@
+package mobius
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestMultiHandler(t *testing.T) {
+	var debugBuf, errorBuf bytes.Buffer
+	logger := slog.New(MultiHandler{
+		slog.NewTextHandler(&debugBuf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&errorBuf, &slog.HandlerOptions{Level: slog.LevelError}),
+	}).With("connID", 7)
+
+	logger.Debug("debug message")
+	logger.Error("error message")
+
+	assert.Contains(t, debugBuf.String(), `msg="debug message" connID=7`)
+	assert.Contains(t, debugBuf.String(), `msg="error message" connID=7`)
+	assert.NotContains(t, errorBuf.String(), "debug message")
+	assert.Contains(t, errorBuf.String(), `msg="error message" connID=7`)
+}
+
+func TestJournaldHandler(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets not supported: %v", err)
+	}
+	defer conn.Close()
+
+	h, err := newJournaldHandler(socket, &slog.HandlerOptions{Level: slog.LevelInfo})
+	assert.NoError(t, err)
+
+	logger := slog.New(h).With("connID", 7).WithGroup("file")
+	logger.Debug("not sent")
+	logger.Warn("Upload failed", "path", "Uploads/a.sit", "err", "line1\nline2")
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	assert.NoError(t, err)
+
+	assert.Equal(t,
+		"MESSAGE=Upload failed\n"+
+			"PRIORITY=4\n"+
+			"SYSLOG_IDENTIFIER=mobius\n"+
+			"CONNID=7\n"+
+			"FILE_PATH=Uploads/a.sit\n"+
+			"FILE_ERR\n\x0b\x00\x00\x00\x00\x00\x00\x00line1\nline2\n",
+		string(buf[:n]),
+	)
+}
+
+func TestJournalFieldName(t *testing.T) {
+	assert.Equal(t, "REMOTEADDR", journalFieldName("remoteAddr"))
+	assert.Equal(t, "FILE_PATH", journalFieldName("file.path"))
+	assert.Equal(t, "PRIVATE", journalFieldName("_private"))
+	assert.Equal(t, "FIELD_1ST", journalFieldName("1st"))
+}
@@ -0,0 +1,89 @@
+//go:build !windows && !plan9
+
+package mobius
+
+import (
+	"bytes"
+	"context"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+	"log/syslog"
+	"strings"
+	"sync"
+)
+
+const defaultSyslogTag = "mobius"
+
+// syslogHandler sends log records to syslog with a priority matching the record level.  The record attributes are
+// formatted as key=value pairs after the message, as in the text log.
+type syslogHandler struct {
+	w    *syslog.Writer
+	text slog.Handler // Formats records into buf
+
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func newSyslogHandler(config hotline.SyslogConfig, opts *slog.HandlerOptions) (slog.Handler, error) {
+	tag := config.Tag
+	if tag == "" {
+		tag = defaultSyslogTag
+	}
+
+	w, err := syslog.Dial(config.Network, config.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	return &syslogHandler{
+		w: w,
+		text: slog.NewTextHandler(buf, &slog.HandlerOptions{
+			Level: opts.Level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				// Syslog records the time and priority itself.
+				if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+		mu:  &sync.Mutex{},
+		buf: buf,
+	}, nil
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.text.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.text.Handle(ctx, r); err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(h.buf.String(), "\n")
+
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.w.Err(line)
+	case r.Level >= slog.LevelWarn:
+		return h.w.Warning(line)
+	case r.Level >= slog.LevelInfo:
+		return h.w.Info(line)
+	default:
+		return h.w.Debug(line)
+	}
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{w: h.w, text: h.text.WithAttrs(attrs), mu: h.mu, buf: h.buf}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{w: h.w, text: h.text.WithGroup(name), mu: h.mu, buf: h.buf}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package mobius
+
+import (
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+)
+
+func newSyslogHandler(_ hotline.SyslogConfig, _ *slog.HandlerOptions) (slog.Handler, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,29 @@
+//go:build !windows && !plan9
+
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net"
+	"testing"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	h, err := newSyslogHandler(hotline.SyslogConfig{Network: "udp", Address: conn.LocalAddr().String()}, &slog.HandlerOptions{})
+	assert.NoError(t, err)
+
+	slog.New(h).With("connID", 7).Error("Error serving request", "err", "EOF")
+
+	buf := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+
+	// Priority 27 is LOG_DAEMON (24) + LOG_ERR (3)
+	assert.Regexp(t, `^<27>.* mobius\[\d+\]: msg="Error serving request" connID=7 err=EOF\n?$`, string(buf[:n]))
+}
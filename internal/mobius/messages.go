@@ -0,0 +1,45 @@
+package mobius
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"path/filepath"
+	"strings"
+)
+
+// LoadMessageCatalog loads the message catalog for locale from the Locales dir of the config dir.  An empty locale
+// returns an empty catalog, so that the built-in English messages are used.
+func LoadMessageCatalog(configDir, locale string) (hotline.MessageCatalog, error) {
+	catalog := make(hotline.MessageCatalog)
+	if locale == "" {
+		return catalog, nil
+	}
+
+	if err := loadFromYAMLFile(filepath.Join(configDir, "Locales", locale+".yaml"), &catalog); err != nil {
+		return nil, fmt.Errorf("load locale %s: %w", locale, err)
+	}
+
+	return catalog, nil
+}
+
+// LoadMessageCatalogs loads the message catalogs of all locales in the Locales dir of the config dir, keyed by locale.
+// A missing Locales dir returns no catalogs.
+func LoadMessageCatalogs(configDir string) (map[string]hotline.MessageCatalog, error) {
+	files, err := filepath.Glob(filepath.Join(configDir, "Locales", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs := make(map[string]hotline.MessageCatalog)
+	for _, file := range files {
+		locale := strings.TrimSuffix(filepath.Base(file), ".yaml")
+
+		catalog, err := LoadMessageCatalog(configDir, locale)
+		if err != nil {
+			return nil, err
+		}
+		catalogs[locale] = catalog
+	}
+
+	return catalogs, nil
+}
@@ -0,0 +1,31 @@
+package mobius
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadMessageCatalog(t *testing.T) {
+	configDir := "../../cmd/mobius-hotline-server/mobius/config"
+
+	catalog, err := LoadMessageCatalog(configDir, "")
+	assert.NoError(t, err)
+	assert.Empty(t, catalog)
+
+	catalog, err = LoadMessageCatalog(configDir, "de")
+	assert.NoError(t, err)
+	assert.Equal(t, "Falsche Anmeldedaten.", catalog["Incorrect login."])
+
+	_, err = LoadMessageCatalog(configDir, "xx")
+	assert.Error(t, err)
+}
+
+func TestLoadMessageCatalogs(t *testing.T) {
+	catalogs, err := LoadMessageCatalogs("../../cmd/mobius-hotline-server/mobius/config")
+	assert.NoError(t, err)
+	assert.Equal(t, "Falsche Anmeldedaten.", catalogs["de"]["Incorrect login."])
+
+	catalogs, err = LoadMessageCatalogs(t.TempDir())
+	assert.NoError(t, err)
+	assert.Empty(t, catalogs)
+}
@@ -0,0 +1,226 @@
+package mobius
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var errPendingUploadNotFound = errors.New("pending upload not found")
+
+// isModerated returns true if uploads to folder, a "/" separated path relative to the FileRoot, must be approved by
+// a moderator.  A folder is moderated if it or any of its parents is listed in the ModeratedFolders config.
+func isModerated(config hotline.Config, folder string) bool {
+	folder = strings.ToLower(strings.Trim(folder, "/"))
+
+	for _, m := range config.ModeratedFolders {
+		m = strings.ToLower(strings.Trim(filepath.ToSlash(m), "/"))
+		if m == "" {
+			continue
+		}
+		if folder == m || strings.HasPrefix(folder, m+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requiresApproval returns true if an upload from cc to the folder at fp must be held for moderator approval.
+func requiresApproval(cc *hotline.ClientConn, fp hotline.FilePath) bool {
+	if cc.Authorize(hotline.AccessDisconUser) {
+		return false
+	}
+
+	return isModerated(cc.Server.Config, fp.String())
+}
+
+// pendingUploads returns the paths, relative to the PendingUploadsDir, of uploads awaiting approval.
+func pendingUploads(config hotline.Config) ([]string, error) {
+	var items []string
+
+	err := filepath.WalkDir(config.PendingUploadsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(config.PendingUploadsDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		if strings.HasPrefix(d.Name(), ".") || strings.HasSuffix(d.Name(), hotline.IncompleteFileSuffix) {
+			return nil
+		}
+
+		// Folders that are not themselves moderated only mirror the public folder structure.
+		rel = filepath.ToSlash(rel)
+		if !isModerated(config, filepath.ToSlash(filepath.Dir(rel))) {
+			return nil
+		}
+
+		items = append(items, rel)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	slices.Sort(items)
+
+	return items, err
+}
+
+// pendingRootPrefix is the file name prefix of the file that records the FileRoot of the uploader of a pending
+// upload.
+const pendingRootPrefix = ".root_"
+
+func pendingRootPath(path string) string {
+	return filepath.Join(filepath.Dir(path), pendingRootPrefix+filepath.Base(path))
+}
+
+// holdPendingUpload records the FileRoot of cc for the pending upload at path, so that the upload is approved into the
+// uploader's FileRoot.
+func holdPendingUpload(cc *hotline.ClientConn, path string) error {
+	return cc.Server.FS.WriteFile(pendingRootPath(path), []byte(cc.FileRoot()), 0644)
+}
+
+// pendingUploadRoot returns the FileRoot of the uploader of the pending upload at path.
+func pendingUploadRoot(srv *hotline.Server, path string) string {
+	root, err := srv.FS.ReadFile(pendingRootPath(path))
+	if err != nil || len(root) == 0 {
+		return srv.Config.FileRoot
+	}
+
+	return string(root)
+}
+
+// approvePendingUpload moves the pending upload at relPath into the uploader's FileRoot and announces it to all users.
+func approvePendingUpload(srv *hotline.Server, relPath string) error {
+	relPath = filepath.Join("/", relPath)
+	src := filepath.Join(srv.Config.PendingUploadsDir, relPath)
+
+	if _, err := srv.FS.Stat(src); err != nil {
+		return errPendingUploadNotFound
+	}
+
+	dstDir := filepath.Join(pendingUploadRoot(srv, src), filepath.Dir(relPath))
+
+	if _, err := srv.FS.Stat(filepath.Join(dstDir, filepath.Base(relPath))); err == nil {
+		return fmt.Errorf("a file named \"%s\" already exists", filepath.Base(relPath))
+	}
+
+	if err := srv.FS.MkdirAll(dstDir, 0777); err != nil {
+		return err
+	}
+
+	fw, err := hotline.NewFileWrapper(srv.FS, src, 0)
+	if err != nil {
+		return err
+	}
+
+	err = fw.Move(dstDir)
+	srv.FileIndex.Invalidate(dstDir)
+	if err != nil {
+		return err
+	}
+	_ = srv.FS.Remove(pendingRootPath(src))
+
+	msg, err := txtEncoder.String(fmt.Sprintf("\r*** New upload available: %s", strings.TrimPrefix(filepath.ToSlash(relPath), "/")))
+	if err != nil {
+		return err
+	}
+	srv.SendAll(hotline.TranChatMsg, hotline.NewField(hotline.FieldData, []byte(msg)))
+
+	return nil
+}
+
+// rejectPendingUpload deletes the pending upload at relPath.
+func rejectPendingUpload(srv *hotline.Server, relPath string) error {
+	src := filepath.Join(srv.Config.PendingUploadsDir, filepath.Join("/", relPath))
+
+	if _, err := srv.FS.Stat(src); err != nil {
+		return errPendingUploadNotFound
+	}
+
+	fw, err := hotline.NewFileWrapper(srv.FS, src, 0)
+	if err != nil {
+		return err
+	}
+
+	if err := fw.Delete(); err != nil {
+		return err
+	}
+	_ = srv.FS.Remove(pendingRootPath(src))
+
+	return nil
+}
+
+// HandlePendingCommand lists uploads awaiting moderator approval.
+func HandlePendingCommand(cc *hotline.ClientConn, _ *hotline.Transaction, _ string) (res []hotline.Transaction) {
+	if !cc.Authorize(hotline.AccessDisconUser) {
+		return append(res, serverMsg(cc.ID, "You are not allowed to view pending uploads."))
+	}
+
+	items, err := pendingUploads(cc.Server.Config)
+	if err != nil {
+		cc.Logger.Error("Error listing pending uploads", "err", err)
+		return append(res, serverMsg(cc.ID, "Error listing pending uploads."))
+	}
+
+	if len(items) == 0 {
+		return append(res, serverMsg(cc.ID, "There are no uploads awaiting approval."))
+	}
+
+	return append(res, serverMsg(cc.ID, "Uploads awaiting approval:\n"+strings.Join(items, "\n")))
+}
+
+// HandleApproveCommand moves a pending upload into its public folder.
+//
+// Example: /approve Uploads/game.sit
+func HandleApproveCommand(cc *hotline.ClientConn, _ *hotline.Transaction, args string) (res []hotline.Transaction) {
+	if !cc.Authorize(hotline.AccessDisconUser) {
+		return append(res, serverMsg(cc.ID, "You are not allowed to approve uploads."))
+	}
+
+	if args == "" {
+		return append(res, serverMsg(cc.ID, "Usage: /approve <path/to/file>"))
+	}
+
+	if err := approvePendingUpload(cc.Server, args); err != nil {
+		return append(res, serverMsg(cc.ID, fmt.Sprintf("Cannot approve \"%s\": %v", args, err)))
+	}
+
+	cc.Logger.Info("Approved pending upload", "path", args)
+
+	return append(res, serverMsg(cc.ID, fmt.Sprintf("Approved \"%s\".", args)))
+}
+
+// HandleRejectCommand deletes a pending upload.
+//
+// Example: /reject Uploads/game.sit
+func HandleRejectCommand(cc *hotline.ClientConn, _ *hotline.Transaction, args string) (res []hotline.Transaction) {
+	if !cc.Authorize(hotline.AccessDisconUser) {
+		return append(res, serverMsg(cc.ID, "You are not allowed to reject uploads."))
+	}
+
+	if args == "" {
+		return append(res, serverMsg(cc.ID, "Usage: /reject <path/to/file>"))
+	}
+
+	if err := rejectPendingUpload(cc.Server, args); err != nil {
+		return append(res, serverMsg(cc.ID, fmt.Sprintf("Cannot reject \"%s\": %v", args, err)))
+	}
+
+	cc.Logger.Info("Rejected pending upload", "path", args)
+
+	return append(res, serverMsg(cc.ID, fmt.Sprintf("Rejected \"%s\".", args)))
+}
@@ -0,0 +1,110 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsModerated(t *testing.T) {
+	config := hotline.Config{ModeratedFolders: []string{"Uploads", "/Public/Games/"}}
+
+	tests := []struct {
+		folder string
+		want   bool
+	}{
+		{"Uploads", true},
+		{"uploads/Sub Folder", true},
+		{"Public/Games", true},
+		{"Public/Games/Arcade", true},
+		{"Public", false},
+		{"Uploads Archive", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			assert.Equal(t, tt.want, isModerated(config, tt.folder))
+		})
+	}
+}
+
+func TestPendingUploads(t *testing.T) {
+	fileRoot := t.TempDir()
+	pendingDir := t.TempDir()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(pendingDir, "Uploads", "Folder Upload"), 0777))
+	assert.NoError(t, os.WriteFile(filepath.Join(pendingDir, "Uploads", "Folder Upload", "inner.txt"), []byte("inner"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(pendingDir, "Uploads", "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(pendingDir, "Uploads", "b.txt"+hotline.IncompleteFileSuffix), []byte("b"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(pendingDir, "Uploads", ".info_a.txt"), []byte{}, 0644))
+
+	srv := &hotline.Server{
+		FS: &hotline.OSFileStore{},
+		Config: hotline.Config{
+			FileRoot:          fileRoot,
+			PendingUploadsDir: pendingDir,
+			ModeratedFolders:  []string{"Uploads"},
+		},
+		ClientMgr: func() *hotline.MockClientMgr {
+			m := hotline.MockClientMgr{}
+			m.On("List").Return([]*hotline.ClientConn{})
+			return &m
+		}(),
+	}
+
+	items, err := pendingUploads(srv.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Uploads/Folder Upload", "Uploads/a.txt"}, items)
+
+	assert.NoError(t, approvePendingUpload(srv, "Uploads/a.txt"))
+	assert.FileExists(t, filepath.Join(fileRoot, "Uploads", "a.txt"))
+	assert.FileExists(t, filepath.Join(fileRoot, "Uploads", ".info_a.txt"))
+	assert.NoFileExists(t, filepath.Join(pendingDir, "Uploads", "a.txt"))
+
+	assert.ErrorIs(t, approvePendingUpload(srv, "Uploads/a.txt"), errPendingUploadNotFound)
+
+	assert.NoError(t, rejectPendingUpload(srv, "Uploads/Folder Upload"))
+	assert.NoDirExists(t, filepath.Join(pendingDir, "Uploads", "Folder Upload"))
+
+	items, err = pendingUploads(srv.Config)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestApprovePendingUpload_accountFileRoot(t *testing.T) {
+	fileRoot := t.TempDir()
+	accountRoot := t.TempDir()
+	pendingDir := t.TempDir()
+
+	srv := &hotline.Server{
+		FS: &hotline.OSFileStore{},
+		Config: hotline.Config{
+			FileRoot:          fileRoot,
+			PendingUploadsDir: pendingDir,
+			ModeratedFolders:  []string{"Uploads"},
+		},
+		ClientMgr: func() *hotline.MockClientMgr {
+			m := hotline.MockClientMgr{}
+			m.On("List").Return([]*hotline.ClientConn{})
+			return &m
+		}(),
+	}
+	cc := &hotline.ClientConn{Server: srv, Account: &hotline.Account{FileRoot: accountRoot}}
+
+	src := filepath.Join(pendingDir, "Uploads", "a.txt")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(src), 0777))
+	assert.NoError(t, os.WriteFile(src, []byte("a"), 0644))
+	assert.NoError(t, holdPendingUpload(cc, src))
+
+	// The upload is approved into the uploader's FileRoot, creating the folder it was uploaded to.
+	items, err := pendingUploads(srv.Config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Uploads/a.txt"}, items)
+
+	assert.NoError(t, approvePendingUpload(srv, "Uploads/a.txt"))
+	assert.FileExists(t, filepath.Join(accountRoot, "Uploads", "a.txt"))
+	assert.NoFileExists(t, filepath.Join(fileRoot, "Uploads", "a.txt"))
+	assert.NoFileExists(t, filepath.Join(pendingDir, "Uploads", pendingRootPrefix+"a.txt"))
+}
@@ -66,24 +66,30 @@ func (f *FlatNews) Write(p []byte) (int, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	f.data = slices.Concat(p, f.data)
-
-	tempFilePath := f.filePath + ".tmp"
-
-	if err := os.WriteFile(tempFilePath, f.data, 0644); err != nil {
-		return 0, fmt.Errorf("write to temporary file: %v", err)
-	}
-
-	// Atomically rename the temporary file to the final file path.
-	if err := os.Rename(tempFilePath, f.filePath); err != nil {
-		return 0, fmt.Errorf("rename temporary file to final file: %v", err)
+	// Posts are only added once they've been saved, so a failed save doesn't leave a post that disappears on restart.
+	data := slices.Concat(p, f.data)
+	if err := writeFileAtomic(f.filePath, data, nil); err != nil {
+		return 0, err
 	}
+	f.data = data
 
-	return len(p), os.WriteFile(f.filePath, f.data, 0644)
+	return len(p), nil
 }
 
 func (f *FlatNews) Seek(offset int64, _ int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	f.readOffset = int(offset)
 
 	return 0, nil
 }
+
+// Bytes returns a copy of the message board.  Unlike Seek and Read, it doesn't use the shared read offset, so it's safe
+// for concurrent readers.
+func (f *FlatNews) Bytes() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.data)
+}
@@ -0,0 +1,58 @@
+package mobius
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"strings"
+)
+
+// newsAnnouncementPayload is the JSON body sent to news announcement webhooks.
+type newsAnnouncementPayload struct {
+	Event    string `json:"event"`
+	Category string `json:"category"`
+	Title    string `json:"title"`
+	Poster   string `json:"poster"`
+}
+
+// matchNewsAnnouncement returns the first announcement config matching the news category path, if any.
+func matchNewsAnnouncement(announcements []hotline.NewsAnnouncement, category string) (hotline.NewsAnnouncement, bool) {
+	for _, a := range announcements {
+		if a.Category == "*" || strings.EqualFold(strings.Trim(a.Category, "/"), category) {
+			return a, true
+		}
+	}
+
+	return hotline.NewsAnnouncement{}, false
+}
+
+// announceNewsArticle announces a newly posted news article to public chat and webhooks as configured for its category.
+func announceNewsArticle(cc *hotline.ClientConn, pathStrs []string, title string) (res []hotline.Transaction) {
+	category := strings.Join(pathStrs, "/")
+
+	a, ok := matchNewsAnnouncement(cc.Server.Config.NewsAnnouncements, category)
+	if !ok {
+		return res
+	}
+
+	if a.Chat {
+		msg := fmt.Sprintf("\r*** New news post in %s: %s (by %s)", category, title, cc.UserName)
+		truncated, _ := hotline.TruncateText([]byte(msg), cc.Server.MaxChatMsgLen())
+
+		for _, c := range cc.Server.ClientMgr.List() {
+			if c.Authorize(hotline.AccessReadChat) {
+				res = append(res, hotline.NewTransaction(hotline.TranChatMsg, c.ID, hotline.NewField(hotline.FieldData, truncated)))
+			}
+		}
+	}
+
+	if a.WebhookURL != "" {
+		payload := newsAnnouncementPayload{Event: "news_post"}
+		payload.Category, _ = txtDecoder.String(category)
+		payload.Title, _ = txtDecoder.String(title)
+		payload.Poster, _ = txtDecoder.String(string(cc.UserName))
+
+		postWebhook(a.WebhookURL, payload, cc.Logger)
+	}
+
+	return res
+}
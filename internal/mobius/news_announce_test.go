@@ -0,0 +1,73 @@
+package mobius
+
+import (
+	"encoding/json"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAnnounceNewsArticle(t *testing.T) {
+	payloads := make(chan newsAnnouncementPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p newsAnnouncementPayload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		payloads <- p
+	}))
+	defer ts.Close()
+
+	reader := &hotline.ClientConn{
+		ID: [2]byte{0, 2},
+		Account: &hotline.Account{Access: func() hotline.AccessBitmap {
+			var bits hotline.AccessBitmap
+			bits.Set(hotline.AccessReadChat)
+			return bits
+		}()},
+	}
+	nonReader := &hotline.ClientConn{ID: [2]byte{0, 3}, Account: &hotline.Account{}}
+
+	cc := &hotline.ClientConn{
+		UserName: []byte("Alice"),
+		Logger:   slog.Default(),
+		Server: &hotline.Server{
+			Config: hotline.Config{
+				NewsAnnouncements: []hotline.NewsAnnouncement{
+					{Category: "General/Announcements", Chat: true, WebhookURL: ts.URL},
+				},
+			},
+			ClientMgr: func() *hotline.MockClientMgr {
+				m := hotline.MockClientMgr{}
+				m.On("List").Return([]*hotline.ClientConn{reader, nonReader})
+				return &m
+			}(),
+		},
+	}
+
+	res := announceNewsArticle(cc, []string{"General", "Announcements"}, "Server upgrade")
+	TranAssertEqual(t, []hotline.Transaction{
+		hotline.NewTransaction(
+			hotline.TranChatMsg,
+			[2]byte{0, 2},
+			hotline.NewField(hotline.FieldData, []byte("\r*** New news post in General/Announcements: Server upgrade (by Alice)")),
+		),
+	}, res)
+
+	select {
+	case p := <-payloads:
+		assert.Equal(t, newsAnnouncementPayload{
+			Event:    "news_post",
+			Category: "General/Announcements",
+			Title:    "Server upgrade",
+			Poster:   "Alice",
+		}, p)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not received")
+	}
+
+	// Categories without an announcement config are not announced.
+	assert.Empty(t, announceNewsArticle(cc, []string{"General"}, "Hello"))
+}
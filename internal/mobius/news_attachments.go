@@ -0,0 +1,111 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"math"
+	"slices"
+)
+
+// newsFlavorData is the data of an article posted in a flavor other than the text.
+type newsFlavorData struct {
+	flavor string
+	data   []byte
+}
+
+// newsArticleFlavors returns the text, alternate flavors, and attachments of an article posted with t.  Classic
+// clients send one text/plain data field.  Other flavors follow as pairs of data flavor and data fields, in that order;
+// a data field without a preceding flavor is the text.  Flavors in newsAltFlavors are alternate formats of the text,
+// and others are attachments.
+func newsArticleFlavors(t *hotline.Transaction) (text []byte, alt, attachments []newsFlavorData) {
+	var flavor string
+	for _, field := range t.Fields {
+		switch field.Type {
+		case hotline.FieldNewsArtDataFlav:
+			flavor = string(field.Data)
+		case hotline.FieldNewsArtData:
+			_, isAlt := newsAltFlavors[flavor]
+			switch {
+			case text == nil && (flavor == "" || flavor == string(hotline.NewsFlavor)):
+				text = field.Data
+			case isAlt:
+				if !slices.ContainsFunc(alt, func(f newsFlavorData) bool { return f.flavor == flavor }) {
+					alt = append(alt, newsFlavorData{flavor: flavor, data: field.Data})
+				}
+			default:
+				attachments = append(attachments, newsFlavorData{flavor: flavor, data: field.Data})
+			}
+			flavor = ""
+		}
+	}
+
+	return text, alt, attachments
+}
+
+// newsArticleText sanitizes the alternate flavors of an article.  Articles posted without a text/plain flavor get the
+// plain text of their first alternate flavor, for classic clients.
+func newsArticleText(text []byte, alt []newsFlavorData) (string, []hotline.NewsArtFlavor) {
+	var flavors []hotline.NewsArtFlavor
+	for _, f := range alt {
+		data := newsAltFlavors[f.flavor].sanitize(string(f.data))
+		if len(data) > math.MaxUint16 {
+			continue // Escaping made it too large to send
+		}
+		flavors = append(flavors, hotline.NewsArtFlavor{Flavor: f.flavor, Data: data})
+	}
+
+	if text == nil && len(alt) > 0 {
+		return newsAltFlavors[alt[0].flavor].plainText(string(alt[0].data)), flavors
+	}
+
+	return string(text), flavors
+}
+
+// checkNewsArticle returns an error reply to t if the article exceeds the NewsArticles limits, or nil if it can be
+// posted.
+func checkNewsArticle(cc *hotline.ClientConn, t *hotline.Transaction, text []byte, alt, attachments []newsFlavorData) []hotline.Transaction {
+	limits := cc.Server.Config.NewsArticles
+	if limits.MaxSize > 0 {
+		tooLong := len(text) > limits.MaxSize
+		for _, f := range alt {
+			tooLong = tooLong || len(f.data) > limits.MaxSize
+		}
+		if tooLong {
+			return cc.NewErrReplyf(t, "Your article is too long.  Articles can be at most %d bytes.", limits.MaxSize)
+		}
+	}
+
+	if len(attachments) == 0 {
+		return nil
+	}
+	if !limits.Attachments.Enabled || cc.Server.NewsAttachments == nil {
+		return cc.NewErrReply(t, "This server does not accept attachments to news articles.")
+	}
+	if len(attachments) > limits.Attachments.CountLimit() {
+		return cc.NewErrReplyf(t, "Articles can have at most %d attachments.", limits.Attachments.CountLimit())
+	}
+	for _, a := range attachments {
+		if a.flavor == "" || !limits.Attachments.TypeAllowed(a.flavor) {
+			return cc.NewErrReplyf(t, "Attachments of type \"%s\" are not allowed.", a.flavor)
+		}
+		if len(a.data) > limits.Attachments.SizeLimit() {
+			return cc.NewErrReplyf(t, "Your attachment is too large.  Attachments can be at most %d bytes.", limits.Attachments.SizeLimit())
+		}
+	}
+
+	return nil
+}
+
+// saveNewsAttachments stores the data of attachments, and returns the attachment records for the article.
+func saveNewsAttachments(store *hotline.NewsAttachmentStore, attachments []newsFlavorData) ([]hotline.NewsAttachment, error) {
+	var saved []hotline.NewsAttachment
+	for _, a := range attachments {
+		name, err := store.Save(a.data)
+		if err != nil {
+			_ = store.Delete(saved)
+			return nil, err
+		}
+		saved = append(saved, hotline.NewsAttachment{Flavor: a.flavor, File: name, Size: len(a.data)})
+	}
+
+	return saved, nil
+}
@@ -0,0 +1,232 @@
+package mobius
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeNewsPath encodes path as the data of a FieldNewsPath field.
+func encodeNewsPath(path ...string) []byte {
+	b := []byte{0, uint8(len(path))}
+	for _, p := range path {
+		b = append(b, 0, 0, uint8(len(p)))
+		b = append(b, p...)
+	}
+
+	return b
+}
+
+func newNewsAttachmentsConn(t *testing.T, config hotline.NewsArticles) *hotline.ClientConn {
+	t.Helper()
+
+	news := newTestNewsService(t).News
+	require.NoError(t, news.CreateGrouping(nil, "General", hotline.NewsCategory))
+
+	store, err := hotline.NewNewsAttachmentStore(t.TempDir())
+	require.NoError(t, err)
+
+	var access hotline.AccessBitmap
+	access.Set(hotline.AccessNewsPostArt)
+	access.Set(hotline.AccessNewsReadArt)
+	access.Set(hotline.AccessNewsDeleteArt)
+
+	return &hotline.ClientConn{
+		UserName: []byte("Alice"),
+		Account:  &hotline.Account{Login: "alice", Access: access},
+		Logger:   NewTestLogger(),
+		Server: &hotline.Server{
+			Config:          hotline.Config{NewsArticles: config},
+			ThreadedNewsMgr: news,
+			NewsAttachments: store,
+		},
+	}
+}
+
+func postNewsArticle(cc *hotline.ClientConn, fields ...hotline.Field) []hotline.Transaction {
+	fields = append([]hotline.Field{
+		hotline.NewField(hotline.FieldNewsPath, encodeNewsPath("General")),
+		hotline.NewField(hotline.FieldNewsArtID, []byte{0, 0, 0, 0}),
+		hotline.NewField(hotline.FieldNewsArtTitle, []byte("Photos")),
+	}, fields...)
+	tran := hotline.NewTransaction(hotline.TranPostNewsArt, [2]byte{0, 1}, fields...)
+
+	return HandlePostNewsArt(cc, &tran)
+}
+
+func TestHandlePostNewsArt_Attachments(t *testing.T) {
+	cc := newNewsAttachmentsConn(t, hotline.NewsArticles{
+		Attachments: hotline.NewsAttachments{Enabled: true, Types: []string{"image/png"}},
+	})
+
+	res := postNewsArticle(cc,
+		hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("text/plain")),
+		hotline.NewField(hotline.FieldNewsArtData, []byte("See attached")),
+		hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("image/png")),
+		hotline.NewField(hotline.FieldNewsArtData, []byte("\x89PNG")),
+	)
+	require.Len(t, res, 1)
+	assert.Equal(t, [4]byte{}, res[0].ErrorCode)
+
+	art := cc.Server.ThreadedNewsMgr.GetArticle([]string{"General"}, 1)
+	require.NotNil(t, art)
+	assert.Equal(t, "See attached", art.Data)
+	require.Len(t, art.Attachments, 1)
+	assert.Equal(t, "image/png", art.Attachments[0].Flavor)
+	assert.Equal(t, 4, art.Attachments[0].Size)
+
+	// The attachment is listed as a second flavor of the article.
+	list := cc.Server.ThreadedNewsMgr.ListArticles([]string{"General"})
+	b, err := io.ReadAll(&list)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "\x00\x02\x06Photos\x05Alice\x0atext/plain\x00\x0c\x09image/png\x00\x04")
+
+	getData := func(flavor string) []hotline.Transaction {
+		tran := hotline.NewTransaction(hotline.TranGetNewsArtData, [2]byte{0, 1},
+			hotline.NewField(hotline.FieldNewsPath, encodeNewsPath("General")),
+			hotline.NewField(hotline.FieldNewsArtID, []byte{0, 0, 0, 1}),
+			hotline.NewField(hotline.FieldNewsArtDataFlav, []byte(flavor)),
+		)
+		return HandleGetNewsArtData(cc, &tran)
+	}
+
+	res = getData("text/plain")
+	assert.Equal(t, []byte("text/plain"), res[0].GetField(hotline.FieldNewsArtDataFlav).Data)
+	assert.Equal(t, []byte("See attached"), res[0].GetField(hotline.FieldNewsArtData).Data)
+
+	res = getData("image/png")
+	assert.Equal(t, []byte("image/png"), res[0].GetField(hotline.FieldNewsArtDataFlav).Data)
+	assert.Equal(t, []byte("\x89PNG"), res[0].GetField(hotline.FieldNewsArtData).Data)
+
+	// Deleting the article deletes its attachments.
+	attachment, err := cc.Server.NewsAttachments.Read(art.Attachments[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\x89PNG"), attachment)
+
+	require.NoError(t, newsService(cc).DeleteArticle(cc, []string{"General"}, 1, false))
+	_, err = cc.Server.NewsAttachments.Read(art.Attachments[0].File)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestHandlePostNewsArt_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  hotline.NewsArticles
+		fields  []hotline.Field
+		wantErr string
+	}{
+		{
+			name:   "article too long",
+			config: hotline.NewsArticles{MaxSize: 4},
+			fields: []hotline.Field{
+				hotline.NewField(hotline.FieldNewsArtData, []byte("Hello")),
+			},
+			wantErr: "Your article is too long.  Articles can be at most 4 bytes.",
+		},
+		{
+			name:   "attachments disabled",
+			config: hotline.NewsArticles{},
+			fields: []hotline.Field{
+				hotline.NewField(hotline.FieldNewsArtData, []byte("Hello")),
+				hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("image/png")),
+				hotline.NewField(hotline.FieldNewsArtData, []byte("\x89PNG")),
+			},
+			wantErr: "This server does not accept attachments to news articles.",
+		},
+		{
+			name:   "too many attachments",
+			config: hotline.NewsArticles{Attachments: hotline.NewsAttachments{Enabled: true}},
+			fields: []hotline.Field{
+				hotline.NewField(hotline.FieldNewsArtData, []byte("Hello")),
+				hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("image/png")),
+				hotline.NewField(hotline.FieldNewsArtData, []byte("\x89PNG")),
+				hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("image/gif")),
+				hotline.NewField(hotline.FieldNewsArtData, []byte("GIF89a")),
+			},
+			wantErr: "Articles can have at most 1 attachments.",
+		},
+		{
+			name:   "type not allowed",
+			config: hotline.NewsArticles{Attachments: hotline.NewsAttachments{Enabled: true, Types: []string{"image/gif"}}},
+			fields: []hotline.Field{
+				hotline.NewField(hotline.FieldNewsArtData, []byte("Hello")),
+				hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("image/png")),
+				hotline.NewField(hotline.FieldNewsArtData, []byte("\x89PNG")),
+			},
+			wantErr: "Attachments of type \"image/png\" are not allowed.",
+		},
+		{
+			name:   "attachment too large",
+			config: hotline.NewsArticles{Attachments: hotline.NewsAttachments{Enabled: true, MaxSize: 3}},
+			fields: []hotline.Field{
+				hotline.NewField(hotline.FieldNewsArtData, []byte("Hello")),
+				hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("image/png")),
+				hotline.NewField(hotline.FieldNewsArtData, []byte("\x89PNG")),
+			},
+			wantErr: "Your attachment is too large.  Attachments can be at most 3 bytes.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newNewsAttachmentsConn(t, tt.config)
+
+			res := postNewsArticle(cc, tt.fields...)
+			require.Len(t, res, 1)
+			assert.Equal(t, [4]byte{0, 0, 0, 1}, res[0].ErrorCode)
+			assert.Equal(t, tt.wantErr, string(res[0].GetField(hotline.FieldError).Data))
+			assert.Nil(t, cc.Server.ThreadedNewsMgr.GetArticle([]string{"General"}, 1))
+		})
+	}
+}
+
+func TestHandlePostNewsArt_HTML(t *testing.T) {
+	cc := newNewsAttachmentsConn(t, hotline.NewsArticles{})
+
+	// Articles posted only as HTML get a plain text flavor for classic clients.
+	res := postNewsArticle(cc,
+		hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("text/html")),
+		hotline.NewField(hotline.FieldNewsArtData, []byte(`<p>Hello <b>all</b></p><script>evil()</script>`)),
+	)
+	require.Len(t, res, 1)
+	assert.Equal(t, [4]byte{}, res[0].ErrorCode)
+
+	art := cc.Server.ThreadedNewsMgr.GetArticle([]string{"General"}, 1)
+	require.NotNil(t, art)
+	assert.Equal(t, "Hello all", art.Data)
+	assert.Equal(t, []hotline.NewsArtFlavor{{Flavor: "text/html", Data: "<p>Hello <b>all</b></p>"}}, art.AltFlavors)
+
+	list := cc.Server.ThreadedNewsMgr.ListArticles([]string{"General"})
+	b, err := io.ReadAll(&list)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "\x0atext/plain\x00\x09\x09text/html\x00\x17")
+
+	for flavor, want := range map[string]string{
+		"text/plain": "Hello all",
+		"text/html":  "<p>Hello <b>all</b></p>",
+		"":           "Hello all",
+	} {
+		tran := hotline.NewTransaction(hotline.TranGetNewsArtData, [2]byte{0, 1},
+			hotline.NewField(hotline.FieldNewsPath, encodeNewsPath("General")),
+			hotline.NewField(hotline.FieldNewsArtID, []byte{0, 0, 0, 1}),
+			hotline.NewField(hotline.FieldNewsArtDataFlav, []byte(flavor)),
+		)
+		res := HandleGetNewsArtData(cc, &tran)
+		assert.Equal(t, want, string(res[0].GetField(hotline.FieldNewsArtData).Data), flavor)
+	}
+
+	// Articles with both flavors keep the plain text as posted.
+	res = postNewsArticle(cc,
+		hotline.NewField(hotline.FieldNewsArtData, []byte("Plain")),
+		hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("text/html")),
+		hotline.NewField(hotline.FieldNewsArtData, []byte("<i>Rich</i>")),
+	)
+	require.Len(t, res, 1)
+	art = cc.Server.ThreadedNewsMgr.GetArticle([]string{"General"}, 2)
+	require.NotNil(t, art)
+	assert.Equal(t, "Plain", art.Data)
+	assert.Equal(t, []hotline.NewsArtFlavor{{Flavor: "text/html", Data: "<i>Rich</i>"}}, art.AltFlavors)
+}
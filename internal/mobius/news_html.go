@@ -0,0 +1,190 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// newsAltFlavors are the formats that articles can be posted in besides text/plain, with the func that sanitizes
+// posted text in each format and the func that converts it to plain text for classic clients.
+var newsAltFlavors = map[string]struct {
+	sanitize  func(string) string
+	plainText func(string) string
+}{
+	hotline.NewsHTMLFlavor: {sanitize: sanitizeNewsHTML, plainText: newsHTMLText},
+}
+
+// newsHTMLTags are the HTML elements kept in articles and their allowed attributes.  Other elements are removed, but
+// their text is kept.
+var newsHTMLTags = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Code:       nil,
+	atom.Em:         nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt"},
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.S:          nil,
+	atom.Strong:     nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// newsHTMLDropped are the HTML elements removed from articles along with their contents.
+var newsHTMLDropped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Head:     true,
+	atom.Template: true,
+	atom.Noscript: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+// newsHTMLBlocks are the HTML elements that start a new line in the plain text of an article.
+var newsHTMLBlocks = map[atom.Atom]bool{
+	atom.Blockquote: true,
+	atom.Br:         true,
+	atom.Div:        true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.Hr:         true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+}
+
+// sanitizeNewsHTML returns the HTML of an article with only the elements and attributes in newsHTMLTags, and links
+// and images restricted to web and mail URLs, so that clients can show it safely.
+func sanitizeNewsHTML(s string) string {
+	var b strings.Builder
+	dropped := 0 // Depth within elements in newsHTMLDropped
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return b.String()
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if newsHTMLDropped[tok.DataAtom] {
+				if tt == html.StartTagToken {
+					dropped++
+				}
+				continue
+			}
+			attrs, ok := newsHTMLTags[tok.DataAtom]
+			if dropped > 0 || !ok {
+				continue
+			}
+
+			var kept []html.Attribute
+			for _, a := range tok.Attr {
+				if a.Namespace != "" || !slices.Contains(attrs, a.Key) {
+					continue
+				}
+				if (a.Key == "href" || a.Key == "src") && !safeNewsURL(a.Val) {
+					continue
+				}
+				kept = append(kept, html.Attribute{Key: a.Key, Val: a.Val})
+			}
+			tok.Attr = kept
+			b.WriteString(tok.String())
+		case html.EndTagToken:
+			tok := z.Token()
+			if newsHTMLDropped[tok.DataAtom] {
+				dropped = max(dropped-1, 0)
+				continue
+			}
+			if _, ok := newsHTMLTags[tok.DataAtom]; ok && dropped == 0 {
+				b.WriteString(tok.String())
+			}
+		}
+	}
+}
+
+// safeNewsURL returns true if u is an http, https, or mailto URL.
+func safeNewsURL(u string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+
+	return false
+}
+
+var (
+	htmlSpace  = regexp.MustCompile(`[ \t\r\n\f]+`)
+	extraLines = regexp.MustCompile(`\r{3,}`)
+)
+
+// newsHTMLText converts the HTML of an article to plain text, with a line break for each paragraph and line break
+// element.
+func newsHTMLText(s string) string {
+	var b strings.Builder
+	dropped := 0
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			lines := strings.Split(b.String(), "\r")
+			for i, line := range lines {
+				lines[i] = strings.TrimSpace(line)
+			}
+			return strings.Trim(extraLines.ReplaceAllString(strings.Join(lines, "\r"), "\r\r"), "\r")
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(htmlSpace.ReplaceAllString(string(z.Text()), " "))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			switch {
+			case newsHTMLDropped[a] && tt == html.StartTagToken:
+				dropped++
+			case newsHTMLDropped[a] && tt == html.EndTagToken:
+				dropped = max(dropped-1, 0)
+			case a == atom.Li:
+				if tt != html.EndTagToken {
+					b.WriteString("\r* ")
+				}
+			case newsHTMLBlocks[a]:
+				b.WriteString("\r")
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package mobius
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSanitizeNewsHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "allowed formatting is kept",
+			in:   `<p>Hello <b>world</b><br/>again</p>`,
+			want: `<p>Hello <b>world</b><br/>again</p>`,
+		},
+		{
+			name: "scripts and styles are removed with their contents",
+			in:   `<script>alert("hi")</script><style>p { color: red }</style><p>text</p>`,
+			want: `<p>text</p>`,
+		},
+		{
+			name: "unknown elements are removed but keep their text",
+			in:   `<div class="x"><span>kept</span></div>`,
+			want: `kept`,
+		},
+		{
+			name: "event handlers and styles are removed",
+			in:   `<p onclick="evil()" style="color: red">text</p>`,
+			want: `<p>text</p>`,
+		},
+		{
+			name: "only web and mail links are kept",
+			in:   `<a href="javascript:evil()">a</a> <a href="https://example.com/" title="Example">b</a> <a href="mailto:a@example.com">c</a>`,
+			want: `<a>a</a> <a href="https://example.com/" title="Example">b</a> <a href="mailto:a@example.com">c</a>`,
+		},
+		{
+			name: "images from other schemes are removed",
+			in:   `<img src="data:image/png;base64,AAAA" alt="pic"><img src="https://example.com/a.png">`,
+			want: `<img alt="pic"><img src="https://example.com/a.png">`,
+		},
+		{
+			name: "text is escaped",
+			in:   `1 &lt; 2 &amp; <b>3 > 2</b>`,
+			want: `1 &lt; 2 &amp; <b>3 &gt; 2</b>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeNewsHTML(tt.in))
+		})
+	}
+}
+
+func TestNewsHTMLText(t *testing.T) {
+	in := "<h1>News</h1>\n<p>Server   moved to\n<b>new</b> hardware.</p><ul><li>Faster</li><li>Bigger</li></ul><script>x()</script><p>Thanks &amp; enjoy</p>"
+
+	assert.Equal(t, "News\r\rServer moved to new hardware.\r\r* Faster\r* Bigger\r\rThanks & enjoy", newsHTMLText(in))
+}
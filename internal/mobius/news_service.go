@@ -0,0 +1,161 @@
+package mobius
+
+import (
+	"encoding/binary"
+	"github.com/jhalter/mobius/hotline"
+	"slices"
+)
+
+// NewsService reads and manages threaded news.  News paths are the names of the bundles and categories leading to an
+// item, from the top level.
+type NewsService struct {
+	News        hotline.ThreadedNewsMgr
+	Attachments *hotline.NewsAttachmentStore // Optional; files attached to articles
+}
+
+// Categories returns the bundles and categories at path.
+func (s NewsService) Categories(actor Actor, path []string) ([]hotline.NewsCategoryListData15, error) {
+	if !actor.Authorize(hotline.AccessNewsReadArt) {
+		return nil, userErr("You are not allowed to read news.")
+	}
+
+	return s.News.GetCategories(path), nil
+}
+
+// CreateCategory creates a news category named name in the bundle at path.
+func (s NewsService) CreateCategory(actor Actor, path []string, name string) error {
+	if !actor.Authorize(hotline.AccessNewsCreateCat) {
+		return userErr("You are not allowed to create news categories.")
+	}
+
+	return s.News.CreateGrouping(path, name, hotline.NewsCategory)
+}
+
+// CreateBundle creates a news bundle named name in the bundle at path.
+func (s NewsService) CreateBundle(actor Actor, path []string, name string) error {
+	if !actor.Authorize(hotline.AccessNewsCreateFldr) {
+		return userErr("You are not allowed to create news folders.")
+	}
+
+	return s.News.CreateGrouping(path, name, hotline.NewsBundle)
+}
+
+// DeleteItem deletes the bundle or category at path.  The files of the attachments of the articles in it are deleted
+// with it.
+func (s NewsService) DeleteItem(actor Actor, path []string) error {
+	if s.News.NewsItem(path).Type == hotline.NewsCategory {
+		if !actor.Authorize(hotline.AccessNewsDeleteCat) {
+			return userErr("You are not allowed to delete news categories.")
+		}
+	} else {
+		if !actor.Authorize(hotline.AccessNewsDeleteFldr) {
+			return userErr("You are not allowed to delete news folders.")
+		}
+	}
+
+	attachments := s.attachmentsIn(path)
+	if err := s.News.DeleteNewsItem(path); err != nil {
+		return err
+	}
+
+	return s.Attachments.Delete(attachments)
+}
+
+// attachmentsIn returns the attachments of the articles in the bundle or category at path.
+func (s NewsService) attachmentsIn(path []string) []hotline.NewsAttachment {
+	if s.Attachments == nil {
+		return nil
+	}
+
+	var attachments []hotline.NewsAttachment
+	if s.News.NewsItem(path).Type == hotline.NewsCategory {
+		for _, art := range s.News.Articles(path) {
+			attachments = append(attachments, art.Attachments...)
+		}
+		return attachments
+	}
+
+	for _, item := range s.News.GetCategories(path) {
+		attachments = append(attachments, s.attachmentsIn(append(slices.Clone(path), item.Name))...)
+	}
+
+	return attachments
+}
+
+// Articles returns the list of articles in the category at path.
+func (s NewsService) Articles(actor Actor, path []string) (hotline.NewsArtListData, error) {
+	if !actor.Authorize(hotline.AccessNewsReadArt) {
+		return hotline.NewsArtListData{}, userErr("You are not allowed to read news.")
+	}
+
+	return s.News.ListArticles(path), nil
+}
+
+// Article returns the article with the given ID in the category at path, or nil if there is no such article.
+func (s NewsService) Article(actor Actor, path []string, id uint32) (*hotline.NewsArtData, error) {
+	if !actor.Authorize(hotline.AccessNewsReadArt) {
+		return nil, userErr("You are not allowed to read news.")
+	}
+
+	return s.News.GetArticle(path, id), nil
+}
+
+// PostArticle adds article to the category at path, as a reply to parentID or as a new thread if parentID is 0.
+func (s NewsService) PostArticle(actor Actor, path []string, parentID uint32, article hotline.NewsArtData) error {
+	if !actor.Authorize(hotline.AccessNewsPostArt) {
+		return userErr("You are not allowed to post news articles.")
+	}
+
+	return s.News.PostArticle(path, parentID, article)
+}
+
+// DeleteArticle deletes the article with the given ID in the category at path, and its replies if recursive is true.
+// The files of the attachments of the deleted articles are deleted with them.
+func (s NewsService) DeleteArticle(actor Actor, path []string, id uint32, recursive bool) error {
+	if !actor.Authorize(hotline.AccessNewsDeleteArt) {
+		return userErr("You are not allowed to delete news articles.")
+	}
+
+	articles := s.News.Articles(path)
+
+	ids := []uint32{id}
+	if recursive {
+		ids = append(ids, replies(articles, id)...)
+	}
+
+	var attachments []hotline.NewsAttachment
+	for _, artID := range ids {
+		if err := s.News.DeleteArticle(path, artID, false); err != nil {
+			return err
+		}
+		if art := articles[artID]; art != nil {
+			attachments = append(attachments, art.Attachments...)
+		}
+	}
+
+	return s.Attachments.Delete(attachments)
+}
+
+// replies returns the IDs of the replies to the article with the given ID, and of the replies to those, in ascending
+// order.  Replies are always posted after the article they reply to, so they have higher IDs.
+func replies(articles map[uint32]*hotline.NewsArtData, id uint32) []uint32 {
+	var ids []uint32
+	for artID := range articles {
+		if artID > id {
+			ids = append(ids, artID)
+		}
+	}
+	slices.Sort(ids)
+
+	thread := map[uint32]bool{id: true}
+
+	var res []uint32
+	for _, artID := range ids {
+		if thread[binary.BigEndian.Uint32(articles[artID].ParentArt[:])] {
+			thread[artID] = true
+			res = append(res, artID)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,101 @@
+package mobius
+
+import (
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestNewsService(t *testing.T) NewsService {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ThreadedNews.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("Categories: {}\n"), 0640))
+
+	news, err := NewThreadedNewsYAML(path)
+	require.NoError(t, err)
+
+	return NewsService{News: news}
+}
+
+func TestNewsService(t *testing.T) {
+	svc := newTestNewsService(t)
+	admin := newTestActor(
+		hotline.AccessNewsCreateFldr, hotline.AccessNewsCreateCat, hotline.AccessNewsPostArt,
+		hotline.AccessNewsReadArt, hotline.AccessNewsDeleteCat,
+	)
+	reader := newTestActor(hotline.AccessNewsReadArt)
+
+	assert.EqualError(t, svc.CreateBundle(reader, nil, "Bundle"), "You are not allowed to create news folders.")
+	require.NoError(t, svc.CreateBundle(admin, nil, "Bundle"))
+
+	assert.EqualError(t, svc.CreateCategory(reader, []string{"Bundle"}, "General"), "You are not allowed to create news categories.")
+	require.NoError(t, svc.CreateCategory(admin, []string{"Bundle"}, "General"))
+
+	cats, err := svc.Categories(reader, []string{"Bundle"})
+	require.NoError(t, err)
+	require.Len(t, cats, 1)
+	assert.Equal(t, "General", cats[0].Name)
+
+	_, err = svc.Categories(newTestActor(), []string{"Bundle"})
+	assert.EqualError(t, err, "You are not allowed to read news.")
+
+	path := []string{"Bundle", "General"}
+	article := hotline.NewsArtData{Title: "Hello", Poster: "Admin", DataFlav: hotline.NewsFlavor, Data: "First post"}
+
+	assert.EqualError(t, svc.PostArticle(reader, path, 0, article), "You are not allowed to post news articles.")
+	require.NoError(t, svc.PostArticle(admin, path, 0, article))
+
+	art, err := svc.Article(reader, path, 1)
+	require.NoError(t, err)
+	require.NotNil(t, art)
+	assert.Equal(t, "First post", art.Data)
+
+	assert.EqualError(t, svc.DeleteArticle(admin, path, 1, false), "You are not allowed to delete news articles.")
+
+	// Deleting a category and a bundle require different access.
+	assert.EqualError(t, svc.DeleteItem(admin, []string{"Bundle"}), "You are not allowed to delete news folders.")
+	require.NoError(t, svc.DeleteItem(admin, path))
+}
+
+func TestNewsService_deleteAttachments(t *testing.T) {
+	svc := newTestNewsService(t)
+	dir := t.TempDir()
+	store, err := hotline.NewNewsAttachmentStore(dir)
+	require.NoError(t, err)
+	svc.Attachments = store
+
+	admin := newTestActor(hotline.AccessNewsDeleteArt, hotline.AccessNewsDeleteFldr)
+
+	require.NoError(t, svc.News.CreateGrouping(nil, "Bundle", hotline.NewsBundle))
+	require.NoError(t, svc.News.CreateGrouping([]string{"Bundle"}, "General", hotline.NewsCategory))
+	path := []string{"Bundle", "General"}
+
+	// Article 1 has a reply, 2, which has a reply of its own, 3.  Article 4 is a separate thread.
+	files := make(map[uint32]string)
+	for id, parent := range []uint32{0, 1, 2, 0} {
+		file, err := store.Save([]byte("data"))
+		require.NoError(t, err)
+		files[uint32(id+1)] = file
+
+		require.NoError(t, svc.News.PostArticle(path, parent, hotline.NewsArtData{
+			Title:       "Photo",
+			Attachments: []hotline.NewsAttachment{{Flavor: "image/png", File: file, Size: 4}},
+		}))
+	}
+
+	require.NoError(t, svc.DeleteArticle(admin, path, 1, true))
+	for _, id := range []uint32{1, 2, 3} {
+		assert.Nil(t, svc.News.GetArticle(path, id))
+		assert.NoFileExists(t, filepath.Join(dir, files[id]))
+	}
+	assert.NotNil(t, svc.News.GetArticle(path, 4))
+	assert.FileExists(t, filepath.Join(dir, files[4]))
+
+	// Deleting a bundle deletes the attachments of the articles in its categories.
+	require.NoError(t, svc.DeleteItem(admin, []string{"Bundle"}))
+	assert.NoFileExists(t, filepath.Join(dir, files[4]))
+}
@@ -0,0 +1,89 @@
+package mobius
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFlatNews(t *testing.T) *FlatNews {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "MessageBoard.txt")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	flatNews, err := NewFlatNews(path)
+	require.NoError(t, err)
+
+	return flatNews
+}
+
+func TestFlatNews_ConcurrentPosts(t *testing.T) {
+	flatNews := newTestFlatNews(t)
+	cc := &hotline.ClientConn{
+		UserName: []byte("Alice"),
+		Logger:   NewTestLogger(),
+		Server: &hotline.Server{
+			Config: hotline.Config{NewsDelimiter: "-- %s %s --\r%s", NewsDateFormat: "2006"},
+			ClientMgr: func() *hotline.MockClientMgr {
+				m := hotline.MockClientMgr{}
+				m.On("List").Return([]*hotline.ClientConn{})
+				return &m
+			}(),
+			MessageBoard: flatNews,
+		},
+	}
+
+	const posters = 20
+	var wg sync.WaitGroup
+	for i := range posters {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			post := hotline.NewTransaction(hotline.TranOldPostNews, [2]byte{0, 1},
+				hotline.NewField(hotline.FieldData, []byte(fmt.Sprintf("post %02d", i))),
+			)
+			res := HandleTranOldPostNews(cc, &post)
+			assert.Len(t, res, 1)
+		}()
+
+		// Readers only ever see whole posts.
+		go func() {
+			defer wg.Done()
+			get := hotline.NewTransaction(hotline.TranGetMsgs, [2]byte{0, 1})
+			res := HandleGetMsgs(cc, &get)
+			board := string(res[0].GetField(hotline.FieldData).Data)
+			assert.Equal(t, strings.Count(board, "-- Alice"), strings.Count(board, "post "))
+		}()
+	}
+	wg.Wait()
+
+	saved, err := os.ReadFile(flatNews.filePath)
+	require.NoError(t, err)
+	assert.Equal(t, flatNews.Bytes(), saved)
+
+	for i := range posters {
+		assert.Equal(t, 1, strings.Count(string(saved), fmt.Sprintf("-- Alice %s --\rpost %02d\r", time.Now().Format("2006"), i)))
+	}
+}
+
+func TestFlatNews_WriteError(t *testing.T) {
+	flatNews := newTestFlatNews(t)
+
+	_, err := flatNews.Write([]byte("first\r"))
+	require.NoError(t, err)
+
+	// Posts that can't be saved aren't kept.
+	flatNews.filePath = filepath.Join(t.TempDir(), "missing", "MessageBoard.txt")
+	_, err = flatNews.Write([]byte("second\r"))
+	assert.Error(t, err)
+	assert.Equal(t, []byte("first\r"), flatNews.Bytes())
+}
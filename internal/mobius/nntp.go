@@ -0,0 +1,825 @@
+package mobius
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNNTPHostname    = "mobius.invalid"
+	defaultNNTPGroupPrefix = "hotline"
+
+	// nntpIdleTimeout is how long a newsreader connection may be idle before it's closed.  RFC 3977 requires at least
+	// three minutes.
+	nntpIdleTimeout = 10 * time.Minute
+
+	// maxArticleHeaderLen is how many bytes of headers a posted article may have in addition to its text.
+	maxArticleHeaderLen = 16 * 1024
+)
+
+// NNTPServer serves threaded news over NNTP (RFC 3977), so that it can be read and posted to with a newsreader.  Each
+// news category is a newsgroup named after its news path, and articles are numbered by their Hotline article ID.
+// Articles posted by newsreaders are added to the threaded news as posted by the account the newsreader logged in with.
+type NNTPServer struct {
+	hlServer *hotline.Server
+	logger   *slog.Logger
+}
+
+func NewNNTPServer(hlServer *hotline.Server, logger *slog.Logger) *NNTPServer {
+	return &NNTPServer{hlServer: hlServer, logger: logger}
+}
+
+// Serve accepts newsreader connections on ln until ctx is cancelled.
+func (s *NNTPServer) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *NNTPServer) hostname() string {
+	if s.hlServer.Config.NNTP.Hostname != "" {
+		return s.hlServer.Config.NNTP.Hostname
+	}
+	return defaultNNTPHostname
+}
+
+// groupName returns the newsgroup name of the news category at newsPath.  Bundle and category names are lowercased,
+// and characters other than letters, digits, "+", and "-" are replaced by "_".
+func (s *NNTPServer) groupName(newsPath []string) string {
+	prefix := s.hlServer.Config.NNTP.GroupPrefix
+	if prefix == "" {
+		prefix = defaultNNTPGroupPrefix
+	}
+
+	parts := []string{prefix}
+	for _, name := range newsPath {
+		parts = append(parts, strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '+', r == '-':
+				return r
+			case r >= 'A' && r <= 'Z':
+				return r + 'a' - 'A'
+			}
+			return '_'
+		}, macRomanToUTF8(name)))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// messageID returns the message ID of article id in group, e.g. "<42.hotline.general@mobius.invalid>".
+func (s *NNTPServer) messageID(group string, id uint32) string {
+	return fmt.Sprintf("<%d.%s@%s>", id, group, s.hostname())
+}
+
+// parseMessageID returns the group and article ID of a message ID made by messageID.
+func parseMessageID(msgID string) (group string, id uint32, ok bool) {
+	local, _, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(msgID, "<"), ">"), "@")
+	if !ok {
+		return "", 0, false
+	}
+	idStr, group, ok := strings.Cut(local, ".")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return group, uint32(n), true
+}
+
+func macRomanToUTF8(s string) string {
+	decoded, _ := charmap.Macintosh.NewDecoder().String(s)
+	return decoded
+}
+
+// utf8ToMacRoman converts text from a newsreader to Mac Roman, replacing characters that Mac Roman doesn't have.
+func utf8ToMacRoman(s string) string {
+	encoded, _ := encoding.ReplaceUnsupported(charmap.Macintosh.NewEncoder()).String(s)
+	return encoded
+}
+
+// nntpGroup is a newsgroup for a news category.
+type nntpGroup struct {
+	name string
+	path []string // News path of the category
+	ids  []uint32 // IDs of the articles in the category, in order
+	arts map[uint32]bool
+}
+
+func (g *nntpGroup) low() uint32 {
+	if len(g.ids) == 0 {
+		return 1
+	}
+	return g.ids[0]
+}
+
+func (g *nntpGroup) high() uint32 {
+	if len(g.ids) == 0 {
+		return 0
+	}
+	return g.ids[len(g.ids)-1]
+}
+
+// nntpSession is the state of a newsreader connection.
+type nntpSession struct {
+	srv     *NNTPServer
+	conn    *textproto.Conn
+	ip      string
+	account *hotline.Account // Account the newsreader logged in with, or nil
+
+	user       string // Login given with AUTHINFO USER, for the following AUTHINFO PASS
+	triedGuest bool
+
+	group   *nntpGroup // Selected newsgroup
+	current uint32     // Current article number in the selected group; 0 if none
+}
+
+func (s *NNTPServer) serveConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	sess := &nntpSession{srv: s, conn: textproto.NewConn(conn), ip: ip}
+
+	if banned, _ := s.hlServer.BanList.IsBanned(ip); banned {
+		_ = sess.conn.PrintfLine("502 You are banned from this server")
+		return
+	}
+
+	_ = sess.conn.PrintfLine("200 %s NNTP service ready, posting allowed", s.hlServer.CurrentConfig().Name)
+
+	for {
+		_ = conn.SetDeadline(time.Now().Add(nntpIdleTimeout))
+
+		line, err := sess.conn.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if err := sess.handle(strings.ToUpper(cmd), strings.Fields(args)); err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.logger.Debug("NNTP connection closed", "remoteAddr", conn.RemoteAddr(), "err", err)
+			}
+			return
+		}
+	}
+}
+
+// errNNTPQuit ends a session after QUIT.
+var errNNTPQuit = io.EOF
+
+// handle runs a command.  An error closes the connection.
+func (sess *nntpSession) handle(cmd string, args []string) error {
+	switch cmd {
+	case "CAPABILITIES":
+		return sess.capabilities()
+	case "MODE":
+		if len(args) == 1 && strings.EqualFold(args[0], "READER") {
+			return sess.conn.PrintfLine("200 Reader mode, posting permitted")
+		}
+		return sess.conn.PrintfLine("501 Unknown MODE variant")
+	case "AUTHINFO":
+		return sess.authinfo(args)
+	case "LIST":
+		return sess.list(args)
+	case "GROUP", "LISTGROUP":
+		return sess.selectGroup(cmd, args)
+	case "ARTICLE", "HEAD", "BODY", "STAT":
+		return sess.article(cmd, args)
+	case "NEXT", "LAST":
+		return sess.move(cmd)
+	case "OVER", "XOVER":
+		return sess.over(args)
+	case "POST":
+		return sess.post()
+	case "NEWGROUPS":
+		// Creation times of news categories aren't recorded, so no groups are reported as new.
+		return sess.writeList("231 List of new newsgroups follows", nil)
+	case "DATE":
+		return sess.conn.PrintfLine("111 %s", time.Now().UTC().Format("20060102150405"))
+	case "HELP":
+		return sess.writeList("100 Help text follows", []string{
+			"ARTICLE BODY HEAD STAT [number|<message-id>]",
+			"AUTHINFO USER|PASS", "CAPABILITIES", "DATE", "GROUP newsgroup", "HELP", "LAST",
+			"LIST [ACTIVE|NEWSGROUPS|OVERVIEW.FMT] [wildmat]", "LISTGROUP [newsgroup [range]]",
+			"MODE READER", "NEWGROUPS", "NEXT", "OVER [range]", "POST", "QUIT",
+		})
+	case "QUIT":
+		_ = sess.conn.PrintfLine("205 Connection closing")
+		return errNNTPQuit
+	default:
+		return sess.conn.PrintfLine("500 Unknown command")
+	}
+}
+
+// writeList writes a multi-line response: the status line, then lines with dot-stuffing and the terminating ".".
+func (sess *nntpSession) writeList(status string, lines []string) error {
+	if err := sess.conn.PrintfLine("%s", status); err != nil {
+		return err
+	}
+
+	w := sess.conn.DotWriter()
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func (sess *nntpSession) capabilities() error {
+	caps := []string{
+		"VERSION 2",
+		"IMPLEMENTATION Mobius",
+		"READER",
+		"POST",
+		"OVER",
+		"LIST ACTIVE NEWSGROUPS OVERVIEW.FMT",
+	}
+	if sess.account == nil {
+		caps = append(caps, "AUTHINFO USER")
+	}
+
+	return sess.writeList("101 Capability list:", caps)
+}
+
+func (sess *nntpSession) authinfo(args []string) error {
+	if sess.account != nil && !sess.triedGuest {
+		return sess.conn.PrintfLine("502 Already authenticated")
+	}
+	if len(args) < 1 {
+		return sess.conn.PrintfLine("501 Syntax error")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "USER":
+		if len(args) != 2 {
+			return sess.conn.PrintfLine("501 Syntax error")
+		}
+		sess.user = args[1]
+		return sess.conn.PrintfLine("381 Password required")
+	case "PASS":
+		if sess.user == "" {
+			return sess.conn.PrintfLine("482 Authentication commands issued out of sequence")
+		}
+		login := sess.user
+		sess.user = ""
+
+		account, err := sess.srv.hlServer.CheckLogin(sess.ip, login, strings.Join(args[1:], " "))
+		if err != nil {
+			sess.srv.logger.Info("NNTP login failed", "login", login, "remoteAddr", sess.ip, "err", err)
+			return sess.conn.PrintfLine("481 Authentication failed")
+		}
+
+		sess.account, sess.triedGuest = account, false
+		sess.srv.logger.Info("NNTP login", "login", account.Login, "remoteAddr", sess.ip)
+		return sess.conn.PrintfLine("281 Authentication accepted")
+	default:
+		return sess.conn.PrintfLine("501 Unknown AUTHINFO variant")
+	}
+}
+
+// authorize returns true if the session's account has the access privilege.  Otherwise it tells the newsreader to log
+// in, or that the account isn't allowed, and returns false.  Sessions that haven't logged in use the guest account if
+// the server allows it.
+func (sess *nntpSession) authorize(access int, deniedCode int) (bool, error) {
+	if sess.account == nil && !sess.triedGuest && sess.srv.hlServer.Config.NNTP.AllowGuests {
+		sess.triedGuest = true
+		sess.account, _ = sess.srv.hlServer.CheckLogin(sess.ip, hotline.GuestAccount, "")
+	}
+
+	if sess.account == nil {
+		return false, sess.conn.PrintfLine("480 Authentication required")
+	}
+	if !sess.account.Access.IsSet(access) {
+		return false, sess.conn.PrintfLine("%d Permission denied", deniedCode)
+	}
+
+	return true, nil
+}
+
+func (sess *nntpSession) news() NewsService {
+	return NewsService{News: sess.srv.hlServer.ThreadedNewsMgr, Attachments: sess.srv.hlServer.NewsAttachments}
+}
+
+// groups returns the newsgroups of all news categories, sorted by name.  If two categories have the same group name,
+// the first one found is used.
+func (sess *nntpSession) groups() []*nntpGroup {
+	actor := accountActor{sess.account}
+	seen := make(map[string]bool)
+
+	var groups []*nntpGroup
+	var walk func(newsPath []string)
+	walk = func(newsPath []string) {
+		cats, err := sess.news().Categories(actor, newsPath)
+		if err != nil {
+			return
+		}
+		for _, cat := range cats {
+			catPath := append(newsPath[:len(newsPath):len(newsPath)], cat.Name)
+			switch cat.Type {
+			case hotline.NewsBundle:
+				walk(catPath)
+			case hotline.NewsCategory:
+				name := sess.srv.groupName(catPath)
+				if !seen[name] {
+					seen[name] = true
+					groups = append(groups, &nntpGroup{name: name, path: catPath})
+				}
+			}
+		}
+	}
+	walk(nil)
+
+	slices.SortFunc(groups, func(a, b *nntpGroup) int { return strings.Compare(a.name, b.name) })
+
+	return groups
+}
+
+// findGroup returns the newsgroup named name with its article numbers loaded, or nil if there is none.
+func (sess *nntpSession) findGroup(name string) *nntpGroup {
+	for _, g := range sess.groups() {
+		if g.name == name {
+			sess.loadArticles(g)
+			return g
+		}
+	}
+
+	return nil
+}
+
+// loadArticles loads the article numbers of g.
+func (sess *nntpSession) loadArticles(g *nntpGroup) {
+	g.ids, g.arts = nil, make(map[uint32]bool)
+
+	list, err := sess.news().Articles(accountActor{sess.account}, g.path)
+	if err != nil {
+		return
+	}
+	arts, err := hotline.DecodeNewsArtList(list.NewsArtList)
+	if err != nil {
+		sess.srv.logger.Error("Error reading news article list", "path", g.path, "err", err)
+		return
+	}
+
+	for _, art := range arts {
+		id := binary.BigEndian.Uint32(art.ID[:])
+		g.ids = append(g.ids, id)
+		g.arts[id] = true
+	}
+	slices.Sort(g.ids)
+}
+
+// wildmatMatch returns true if name matches the RFC 3977 wildmat pattern, a comma-separated list of glob patterns in
+// which the last matching pattern wins, and patterns starting with "!" exclude names.
+func wildmatMatch(pattern, name string) bool {
+	var matched bool
+	for _, p := range strings.Split(pattern, ",") {
+		negated := strings.HasPrefix(p, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(p, "!"), name); ok {
+			matched = !negated
+		}
+	}
+
+	return matched
+}
+
+func (sess *nntpSession) list(args []string) error {
+	keyword := "ACTIVE"
+	if len(args) > 0 {
+		keyword = strings.ToUpper(args[0])
+	}
+	pattern := "*"
+	if len(args) > 1 {
+		pattern = args[1]
+	}
+
+	if keyword == "OVERVIEW.FMT" {
+		return sess.writeList("215 Order of fields in overview database", []string{
+			"Subject:", "From:", "Date:", "Message-ID:", "References:", ":bytes", ":lines",
+		})
+	}
+	if keyword != "ACTIVE" && keyword != "NEWSGROUPS" {
+		return sess.conn.PrintfLine("501 Unknown LIST keyword")
+	}
+
+	if ok, err := sess.authorize(hotline.AccessNewsReadArt, 502); !ok {
+		return err
+	}
+
+	canPost := "n"
+	if sess.account.Access.IsSet(hotline.AccessNewsPostArt) {
+		canPost = "y"
+	}
+
+	var lines []string
+	for _, g := range sess.groups() {
+		if !wildmatMatch(pattern, g.name) {
+			continue
+		}
+
+		if keyword == "NEWSGROUPS" {
+			lines = append(lines, g.name+"\t"+macRomanToUTF8(strings.Join(g.path, "/")))
+			continue
+		}
+
+		sess.loadArticles(g)
+		lines = append(lines, fmt.Sprintf("%s %d %d %s", g.name, g.high(), g.low(), canPost))
+	}
+
+	return sess.writeList("215 List of newsgroups follows", lines)
+}
+
+func (sess *nntpSession) selectGroup(cmd string, args []string) error {
+	if ok, err := sess.authorize(hotline.AccessNewsReadArt, 502); !ok {
+		return err
+	}
+
+	g := sess.group
+	if len(args) > 0 {
+		if g = sess.findGroup(args[0]); g == nil {
+			return sess.conn.PrintfLine("411 No such newsgroup")
+		}
+	} else if g == nil {
+		return sess.conn.PrintfLine("412 No newsgroup selected")
+	}
+
+	sess.group, sess.current = g, 0
+	if len(g.ids) > 0 {
+		sess.current = g.ids[0]
+	}
+
+	status := fmt.Sprintf("211 %d %d %d %s", len(g.ids), g.low(), g.high(), g.name)
+	if cmd == "GROUP" {
+		return sess.conn.PrintfLine("%s", status)
+	}
+
+	lo, hi := g.low(), g.high()
+	if len(args) > 1 {
+		var ok bool
+		if lo, hi, ok = parseRange(args[1]); !ok {
+			return sess.conn.PrintfLine("501 Syntax error")
+		}
+	}
+
+	var lines []string
+	for _, id := range g.ids {
+		if id >= lo && id <= hi {
+			lines = append(lines, strconv.FormatUint(uint64(id), 10))
+		}
+	}
+
+	return sess.writeList(status+" list follows", lines)
+}
+
+// parseRange parses an article number range: "n", "n-", or "n-m".
+func parseRange(s string) (lo, hi uint32, ok bool) {
+	loStr, hiStr, isRange := strings.Cut(s, "-")
+
+	n, err := strconv.ParseUint(loStr, 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	lo, hi = uint32(n), uint32(n)
+
+	if isRange {
+		hi = ^uint32(0)
+		if hiStr != "" {
+			n, err := strconv.ParseUint(hiStr, 10, 32)
+			if err != nil {
+				return 0, 0, false
+			}
+			hi = uint32(n)
+		}
+	}
+
+	return lo, hi, true
+}
+
+// findArticle returns the group and number of the article named by arg, a message ID or an article number in the
+// selected group, or the current article if arg is empty.  If there is no such article, it writes the error response
+// and returns a nil group.
+func (sess *nntpSession) findArticle(arg string) (*nntpGroup, uint32, error) {
+	if strings.HasPrefix(arg, "<") {
+		name, id, ok := parseMessageID(arg)
+		if ok {
+			if g := sess.findGroup(name); g != nil && g.arts[id] {
+				return g, id, nil
+			}
+		}
+		return nil, 0, sess.conn.PrintfLine("430 No article with that message-id")
+	}
+
+	if sess.group == nil {
+		return nil, 0, sess.conn.PrintfLine("412 No newsgroup selected")
+	}
+
+	if arg == "" {
+		if sess.current == 0 {
+			return nil, 0, sess.conn.PrintfLine("420 Current article number is invalid")
+		}
+		return sess.group, sess.current, nil
+	}
+
+	n, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || !sess.group.arts[uint32(n)] {
+		return nil, 0, sess.conn.PrintfLine("423 No article with that number")
+	}
+	sess.current = uint32(n)
+
+	return sess.group, sess.current, nil
+}
+
+func (sess *nntpSession) article(cmd string, args []string) error {
+	if ok, err := sess.authorize(hotline.AccessNewsReadArt, 502); !ok {
+		return err
+	}
+
+	var arg string
+	if len(args) > 0 {
+		arg = args[0]
+	}
+
+	g, id, err := sess.findArticle(arg)
+	if g == nil {
+		return err
+	}
+
+	art, _ := sess.news().Article(accountActor{sess.account}, g.path, id)
+	if art == nil {
+		return sess.conn.PrintfLine("423 No article with that number")
+	}
+
+	header, body := sess.formatArticle(g, id, art)
+	msgID := sess.srv.messageID(g.name, id)
+
+	switch cmd {
+	case "ARTICLE":
+		return sess.writeList(fmt.Sprintf("220 %d %s", id, msgID), slices.Concat(header, []string{""}, body))
+	case "HEAD":
+		return sess.writeList(fmt.Sprintf("221 %d %s", id, msgID), header)
+	case "BODY":
+		return sess.writeList(fmt.Sprintf("222 %d %s", id, msgID), body)
+	default:
+		return sess.conn.PrintfLine("223 %d %s", id, msgID)
+	}
+}
+
+// move handles NEXT and LAST, which move the current article to the next or previous article in the group.
+func (sess *nntpSession) move(cmd string) error {
+	if ok, err := sess.authorize(hotline.AccessNewsReadArt, 502); !ok {
+		return err
+	}
+	if sess.group == nil {
+		return sess.conn.PrintfLine("412 No newsgroup selected")
+	}
+	if sess.current == 0 {
+		return sess.conn.PrintfLine("420 Current article number is invalid")
+	}
+
+	i, _ := slices.BinarySearch(sess.group.ids, sess.current)
+	if cmd == "NEXT" {
+		i++
+		if i >= len(sess.group.ids) {
+			return sess.conn.PrintfLine("421 No next article in this group")
+		}
+	} else {
+		i--
+		if i < 0 {
+			return sess.conn.PrintfLine("422 No previous article in this group")
+		}
+	}
+
+	sess.current = sess.group.ids[i]
+
+	return sess.conn.PrintfLine("223 %d %s", sess.current, sess.srv.messageID(sess.group.name, sess.current))
+}
+
+func (sess *nntpSession) over(args []string) error {
+	if ok, err := sess.authorize(hotline.AccessNewsReadArt, 502); !ok {
+		return err
+	}
+	if sess.group == nil {
+		return sess.conn.PrintfLine("412 No newsgroup selected")
+	}
+
+	lo, hi := sess.current, sess.current
+	if len(args) > 0 {
+		var ok bool
+		if lo, hi, ok = parseRange(args[0]); !ok {
+			return sess.conn.PrintfLine("501 Syntax error")
+		}
+	} else if sess.current == 0 {
+		return sess.conn.PrintfLine("420 Current article number is invalid")
+	}
+
+	actor := accountActor{sess.account}
+	var lines []string
+	for _, id := range sess.group.ids {
+		if id < lo || id > hi {
+			continue
+		}
+
+		art, _ := sess.news().Article(actor, sess.group.path, id)
+		if art == nil {
+			continue
+		}
+
+		fields := sess.overviewFields(sess.group, id, art)
+		lines = append(lines, strconv.FormatUint(uint64(id), 10)+"\t"+strings.Join(fields, "\t"))
+	}
+
+	return sess.writeList("224 Overview information follows", lines)
+}
+
+// formatArticle returns the header lines and body lines of an article.
+func (sess *nntpSession) formatArticle(g *nntpGroup, id uint32, art *hotline.NewsArtData) (header, body []string) {
+	body = articleLines(art.Data)
+
+	header = []string{
+		"Path: " + sess.srv.hostname() + "!not-for-mail",
+		"From: " + posterAddress(art, sess.srv.hostname()),
+		"Newsgroups: " + g.name,
+		"Subject: " + mime.QEncoding.Encode("utf-8", macRomanToUTF8(art.Title)),
+		"Date: " + hotline.Time(art.Date).Time().Format(time.RFC1123Z),
+		"Message-ID: " + sess.srv.messageID(g.name, id),
+	}
+	if parent := binary.BigEndian.Uint32(art.ParentArt[:]); parent != 0 {
+		header = append(header, "References: "+sess.srv.messageID(g.name, parent))
+	}
+	header = append(header,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+		"Lines: "+strconv.Itoa(len(body)),
+	)
+
+	return header, body
+}
+
+// overviewFields returns the OVER fields of an article after the article number, in the order of LIST OVERVIEW.FMT.
+func (sess *nntpSession) overviewFields(g *nntpGroup, id uint32, art *hotline.NewsArtData) []string {
+	var references string
+	if parent := binary.BigEndian.Uint32(art.ParentArt[:]); parent != 0 {
+		references = sess.srv.messageID(g.name, parent)
+	}
+
+	// Overview fields can't contain tabs or line breaks.
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+	return []string{
+		clean.Replace(mime.QEncoding.Encode("utf-8", macRomanToUTF8(art.Title))),
+		clean.Replace(posterAddress(art, sess.srv.hostname())),
+		hotline.Time(art.Date).Time().Format(time.RFC1123Z),
+		sess.srv.messageID(g.name, id),
+		references,
+		strconv.Itoa(len(macRomanToUTF8(art.Data))),
+		strconv.Itoa(len(articleLines(art.Data))),
+	}
+}
+
+// posterAddress returns the From address of an article: the poster's name, and their login at hostname.  Articles
+// posted before poster logins were recorded use the poster name as the login.
+func posterAddress(art *hotline.NewsArtData, hostname string) string {
+	login := art.PosterLogin
+	if login == "" {
+		login = art.Poster
+	}
+
+	addr := mail.Address{Name: macRomanToUTF8(art.Poster), Address: macRomanToUTF8(login) + "@" + hostname}
+
+	return addr.String()
+}
+
+// articleLines splits the Mac Roman text of an article into UTF-8 lines.  Hotline clients end lines with "\r".
+func articleLines(data string) []string {
+	text := strings.ReplaceAll(macRomanToUTF8(data), "\r\n", "\n")
+
+	return strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+}
+
+func (sess *nntpSession) post() error {
+	if ok, err := sess.authorize(hotline.AccessNewsPostArt, 440); !ok {
+		return err
+	}
+
+	if err := sess.conn.PrintfLine("340 Send article to be posted"); err != nil {
+		return err
+	}
+
+	limit := sess.srv.hlServer.Config.NewsArticles.MaxSize
+	if limit == 0 {
+		limit = 65535
+	}
+
+	// The whole article must be read before replying, even if it's rejected, but no more of it than could be posted is
+	// kept in memory.  Each Mac Roman character of the text takes up to three bytes of UTF-8 plus line endings.
+	maxLen := int64(limit)*4 + maxArticleHeaderLen
+	r := sess.conn.DotReader()
+	raw, err := io.ReadAll(io.LimitReader(r, maxLen+1))
+	if _, drainErr := io.Copy(io.Discard, r); drainErr != nil {
+		return drainErr
+	}
+	if err == nil && int64(len(raw)) > maxLen {
+		return sess.conn.PrintfLine("441 Posting failed: articles can be at most %d bytes", limit)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	var body []byte
+	if err == nil {
+		body, err = io.ReadAll(msg.Body)
+	}
+	if err != nil {
+		return sess.conn.PrintfLine("441 Posting failed: malformed article")
+	}
+
+	name, _, _ := strings.Cut(msg.Header.Get("Newsgroups"), ",")
+	g := sess.findGroup(strings.TrimSpace(name))
+	if g == nil {
+		return sess.conn.PrintfLine("441 Posting failed: no such newsgroup")
+	}
+
+	// Replies refer to the article they reply to last in References.
+	var parentID uint32
+	if refs := strings.Fields(msg.Header.Get("References")); len(refs) > 0 {
+		if refGroup, id, ok := parseMessageID(refs[len(refs)-1]); ok && refGroup == g.name && g.arts[id] {
+			parentID = id
+		}
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	text := strings.TrimRight(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	data := utf8ToMacRoman(strings.ReplaceAll(text, "\n", "\r"))
+	if len(data) > limit {
+		return sess.conn.PrintfLine("441 Posting failed: articles can be at most %d bytes", limit)
+	}
+
+	poster := sess.account.Name
+	if poster == "" {
+		poster = sess.account.Login
+	}
+
+	err = sess.news().PostArticle(accountActor{sess.account}, g.path, parentID, hotline.NewsArtData{
+		Title:       utf8ToMacRoman(subject),
+		Poster:      poster,
+		PosterLogin: sess.account.Login,
+		Date:        hotline.NewTime(time.Now()),
+		DataFlav:    hotline.NewsFlavor,
+		Data:        data,
+	})
+	if err != nil {
+		sess.srv.logger.Error("Error posting news article from NNTP", "login", sess.account.Login, "err", err)
+		return sess.conn.PrintfLine("441 Posting failed")
+	}
+
+	sess.srv.logger.Info("News article posted via NNTP", "login", sess.account.Login, "group", g.name)
+
+	return sess.conn.PrintfLine("240 Article received OK")
+}
+
+// accountActor is an Actor for a user logged in with an account outside of the Hotline protocol.  A nil account is
+// allowed nothing.
+type accountActor struct {
+	*hotline.Account
+}
+
+func (a accountActor) Authorize(access int) bool {
+	return a.Account != nil && a.Access.IsSet(access)
+}
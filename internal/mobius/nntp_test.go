@@ -0,0 +1,220 @@
+package mobius
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newNNTPTestServer(t *testing.T, config hotline.Config) (*hotline.Server, string) {
+	t.Helper()
+
+	am := newTestAccountManager(t, hotline.GuestAccount)
+	var access hotline.AccessBitmap
+	access.Set(hotline.AccessNewsReadArt)
+	access.Set(hotline.AccessNewsPostArt)
+	require.NoError(t, am.Create(hotline.Account{
+		Login:    "alice",
+		Name:     "Alice",
+		Password: hotline.HashAndSalt(hotline.EncodeString([]byte("secret"))),
+		Access:   access,
+	}))
+	var guestAccess hotline.AccessBitmap
+	guestAccess.Set(hotline.AccessNewsReadArt)
+	require.NoError(t, am.Update(*hotline.NewAccount(hotline.GuestAccount, "Guest", "", guestAccess), hotline.GuestAccount))
+
+	newsPath := filepath.Join(t.TempDir(), "ThreadedNews.yaml")
+	require.NoError(t, os.WriteFile(newsPath, []byte("Categories: {}\n"), 0640))
+	news, err := NewThreadedNewsYAML(newsPath)
+	require.NoError(t, err)
+	require.NoError(t, news.CreateGrouping(nil, "General", hotline.NewsBundle))
+	require.NoError(t, news.CreateGrouping([]string{"General"}, "Announcements", hotline.NewsCategory))
+	require.NoError(t, news.PostArticle([]string{"General", "Announcements"}, 0, hotline.NewsArtData{
+		Title:       "Welcome",
+		Poster:      "Bob",
+		PosterLogin: "bob",
+		Date:        hotline.NewTime(time.Date(2025, 3, 1, 20, 0, 0, 0, time.Local)),
+		DataFlav:    hotline.NewsFlavor,
+		Data:        "Hello\rworld",
+	}))
+
+	banList, err := NewBanFile(filepath.Join(t.TempDir(), "Banlist.yaml"))
+	require.NoError(t, err)
+
+	hlServer := &hotline.Server{
+		Config:          config,
+		AccountManager:  am,
+		BanList:         banList,
+		ThreadedNewsMgr: news,
+		Logger:          NewTestLogger(),
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = NewNNTPServer(hlServer, NewTestLogger()).Serve(ctx, ln) }()
+
+	return hlServer, ln.Addr().String()
+}
+
+func dialNNTP(t *testing.T, addr string) *textproto.Conn {
+	t.Helper()
+
+	conn, err := textproto.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	_, _, err = conn.ReadCodeLine(200)
+	require.NoError(t, err)
+
+	return conn
+}
+
+// nntpCmd sends a command and returns the status line, checking its code.
+func nntpCmd(t *testing.T, conn *textproto.Conn, code int, format string, args ...any) string {
+	t.Helper()
+
+	require.NoError(t, conn.PrintfLine(format, args...))
+	_, msg, err := conn.ReadCodeLine(code)
+	require.NoError(t, err)
+
+	return msg
+}
+
+// nntpList sends a command with a multi-line response and returns the lines.
+func nntpList(t *testing.T, conn *textproto.Conn, code int, format string, args ...any) []string {
+	t.Helper()
+
+	nntpCmd(t, conn, code, format, args...)
+	lines, err := conn.ReadDotLines()
+	require.NoError(t, err)
+
+	return lines
+}
+
+func TestNNTPServer_Read(t *testing.T) {
+	_, addr := newNNTPTestServer(t, hotline.Config{NNTP: hotline.NNTP{Hostname: "news.example.com"}})
+	conn := dialNNTP(t, addr)
+
+	// Reading news requires a login.
+	nntpCmd(t, conn, 480, "GROUP hotline.general.announcements")
+
+	nntpCmd(t, conn, 381, "AUTHINFO USER alice")
+	nntpCmd(t, conn, 281, "AUTHINFO PASS secret")
+
+	assert.Equal(t, []string{"hotline.general.announcements 1 1 y"}, nntpList(t, conn, 215, "LIST"))
+	assert.Equal(t,
+		[]string{"hotline.general.announcements\tGeneral/Announcements"},
+		nntpList(t, conn, 215, "LIST NEWSGROUPS hotline.*"),
+	)
+
+	assert.Equal(t, "1 1 1 hotline.general.announcements", nntpCmd(t, conn, 211, "GROUP hotline.general.announcements"))
+	nntpCmd(t, conn, 411, "GROUP hotline.nope")
+
+	over := nntpList(t, conn, 224, "OVER 1-")
+	require.Len(t, over, 1)
+	assert.Equal(t, []string{
+		"1", "Welcome", `"Bob" <bob@news.example.com>`, time.Date(2025, 3, 1, 20, 0, 0, 0, time.Local).Format(time.RFC1123Z),
+		"<1.hotline.general.announcements@news.example.com>", "", "11", "2",
+	}, strings.Split(over[0], "\t"))
+
+	article := nntpList(t, conn, 220, "ARTICLE 1")
+	assert.Contains(t, article, `From: "Bob" <bob@news.example.com>`)
+	assert.Contains(t, article, "Subject: Welcome")
+	assert.Contains(t, article, "Message-ID: <1.hotline.general.announcements@news.example.com>")
+	assert.Equal(t, []string{"", "Hello", "world"}, article[len(article)-3:])
+
+	body := nntpList(t, conn, 222, "BODY <1.hotline.general.announcements@news.example.com>")
+	assert.Equal(t, []string{"Hello", "world"}, body)
+	nntpCmd(t, conn, 430, "BODY <9.hotline.general.announcements@news.example.com>")
+	nntpCmd(t, conn, 423, "ARTICLE 9")
+	nntpCmd(t, conn, 421, "NEXT")
+
+	nntpCmd(t, conn, 205, "QUIT")
+}
+
+func TestNNTPServer_Post(t *testing.T) {
+	hlServer, addr := newNNTPTestServer(t, hotline.Config{NNTP: hotline.NNTP{AllowGuests: true}})
+	conn := dialNNTP(t, addr)
+
+	// Guests can read, but not post.
+	nntpCmd(t, conn, 211, "GROUP hotline.general.announcements")
+	nntpCmd(t, conn, 440, "POST")
+
+	nntpCmd(t, conn, 381, "AUTHINFO USER alice")
+	nntpCmd(t, conn, 281, "AUTHINFO PASS secret")
+
+	nntpCmd(t, conn, 340, "POST")
+	w := conn.DotWriter()
+	_, err := w.Write([]byte("From: alice@example.com\n" +
+		"Newsgroups: hotline.general.announcements\n" +
+		"Subject: =?utf-8?q?Re:_Caf=C3=A9?=\n" +
+		"References: <1.hotline.general.announcements@mobius.invalid>\n" +
+		"\n" +
+		"Thanks\n" +
+		"bye\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, _, err = conn.ReadCodeLine(240)
+	require.NoError(t, err)
+
+	art := hlServer.ThreadedNewsMgr.GetArticle([]string{"General", "Announcements"}, 2)
+	require.NotNil(t, art)
+	assert.Equal(t, "Re: Caf\x8e", art.Title)
+	assert.Equal(t, "Alice", art.Poster)
+	assert.Equal(t, "alice", art.PosterLogin)
+	assert.Equal(t, "Thanks\rbye", art.Data)
+	assert.Equal(t, [4]byte{0, 0, 0, 1}, art.ParentArt)
+
+	nntpCmd(t, conn, 340, "POST")
+	w = conn.DotWriter()
+	_, err = w.Write([]byte("Newsgroups: hotline.nope\nSubject: x\n\nbody\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, _, err = conn.ReadCodeLine(441)
+	require.NoError(t, err)
+
+}
+
+func TestNNTPServer_Post_tooLarge(t *testing.T) {
+	_, addr := newNNTPTestServer(t, hotline.Config{NewsArticles: hotline.NewsArticles{MaxSize: 16}})
+	conn := dialNNTP(t, addr)
+
+	nntpCmd(t, conn, 381, "AUTHINFO USER alice")
+	nntpCmd(t, conn, 281, "AUTHINFO PASS secret")
+
+	// Articles that are too large are rejected without being read into memory, and the connection stays usable.
+	nntpCmd(t, conn, 340, "POST")
+	w := conn.DotWriter()
+	_, err := w.Write([]byte("Newsgroups: hotline.general.announcements\nSubject: x\n\n" + strings.Repeat("spam\n", 20000)))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, msg, err := conn.ReadCodeLine(441)
+	require.NoError(t, err)
+	assert.Equal(t, "Posting failed: articles can be at most 16 bytes", msg)
+
+	nntpCmd(t, conn, 205, "QUIT")
+}
+
+func TestNNTPServer_groupName(t *testing.T) {
+	s := NewNNTPServer(&hotline.Server{Config: hotline.Config{NNTP: hotline.NNTP{GroupPrefix: "hl"}}}, NewTestLogger())
+
+	assert.Equal(t, "hl.general_news.caf_", s.groupName([]string{"General News", "Caf\x8e"}))
+}
+
+func TestWildmatMatch(t *testing.T) {
+	assert.True(t, wildmatMatch("*", "hotline.general"))
+	assert.True(t, wildmatMatch("hotline.*", "hotline.general"))
+	assert.False(t, wildmatMatch("hotline.*,!hotline.general", "hotline.general"))
+	assert.False(t, wildmatMatch("comp.*", "hotline.general"))
+}
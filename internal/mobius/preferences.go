@@ -0,0 +1,42 @@
+package mobius
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io/fs"
+	"maps"
+	"slices"
+)
+
+// savePreferences validates and saves changed preferences to the account with the given login, and applies them to the
+// account of any connected sessions.  Preferences with an empty value are removed.
+func savePreferences(srv *hotline.Server, login string, prefs map[string]string) (*hotline.Account, error) {
+	for _, name := range slices.Sorted(maps.Keys(prefs)) {
+		if err := srv.ValidatePreference(name, prefs[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	var account hotline.Account
+	err := srv.AccountManager.Modify(login, func(a *hotline.Account) {
+		for name, value := range prefs {
+			a.SetPreference(name, value)
+		}
+		account = *a
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("account %q not found", login)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if srv.ClientMgr != nil {
+		srv.UpdateSessionAccounts(login, func(a *hotline.Account) {
+			a.Preferences = account.Preferences
+		})
+	}
+
+	return &account, nil
+}
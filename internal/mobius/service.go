@@ -0,0 +1,65 @@
+package mobius
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+)
+
+// Services
+//
+// The account, news, and file services hold the permission checks and business rules for their area, separately from
+// the transaction handlers that decode requests and build replies.  Services depend only on the small store interfaces
+// they use, and on an Actor for the user they act for, so they can be tested with simple fakes and reused by other
+// frontends such as the HTTP API.
+
+// Actor is the user a service operation is performed for.  *hotline.ClientConn is an Actor; other frontends can provide
+// their own.
+type Actor interface {
+	Authorize(access int) bool
+}
+
+// UserError is a service error with a message that can be shown to the user, such as a permission denial.  Format is
+// the built-in English message, which is translated before Args are applied.
+type UserError struct {
+	Format string
+	Args   []any
+}
+
+func userErr(format string, args ...any) *UserError {
+	return &UserError{Format: format, Args: args}
+}
+
+func (e *UserError) Error() string {
+	return e.Message(func(msg string) string { return msg })
+}
+
+// Message returns the error message translated with translate, e.g. ClientConn.T.
+func (e *UserError) Message(translate func(string) string) string {
+	if len(e.Args) == 0 {
+		return translate(e.Format)
+	}
+	return fmt.Sprintf(translate(e.Format), e.Args...)
+}
+
+// userErrReply returns an error reply to t if err is a UserError.  Other errors are left for the handler to log.
+func userErrReply(cc *hotline.ClientConn, t *hotline.Transaction, err error) ([]hotline.Transaction, bool) {
+	var ue *UserError
+	if !errors.As(err, &ue) {
+		return nil, false
+	}
+
+	return cc.NewErrReplyf(t, ue.Format, ue.Args...), true
+}
+
+func accountService(cc *hotline.ClientConn) AccountService {
+	return AccountService{Accounts: cc.Server.AccountManager, Digest: cc.Server.Digest, HashPassword: cc.Server.HashPassword}
+}
+
+func newsService(cc *hotline.ClientConn) NewsService {
+	return NewsService{News: cc.Server.ThreadedNewsMgr, Attachments: cc.Server.NewsAttachments}
+}
+
+func fileService(cc *hotline.ClientConn) FileService {
+	return FileService{FS: cc.Server.FS, Index: cc.Server.FileIndex}
+}
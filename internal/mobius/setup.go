@@ -0,0 +1,221 @@
+package mobius
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	generatedPasswordLen = 16
+
+	// generatedPasswordChars omits characters that are easily confused with each other, such as 0 and O.
+	generatedPasswordChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// SetupAnswers are the settings collected by the setup wizard.
+type SetupAnswers struct {
+	Name          string
+	Description   string
+	AdminLogin    string
+	AdminPassword string
+	Port          int
+	FileRoot      string
+}
+
+// setupPrompter asks questions on out and reads the answers from in.
+type setupPrompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prompts for a value, returning def if the answer is empty.  Answers are re-prompted until valid returns nil.
+func (p *setupPrompter) ask(question, def string, valid func(string) error) (string, error) {
+	for {
+		_, _ = fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return "", err
+			}
+			return "", io.ErrUnexpectedEOF
+		}
+
+		answer := strings.TrimSpace(p.in.Text())
+		if answer == "" {
+			answer = def
+		}
+
+		if valid == nil {
+			return answer, nil
+		}
+		if err := valid(answer); err != nil {
+			_, _ = fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+
+		return answer, nil
+	}
+}
+
+// PromptSetup interactively asks for the basic server settings, offering the settings in defaults.  The admin password
+// is not asked for: a strong password is generated instead.
+func PromptSetup(in io.Reader, out io.Writer, defaults SetupAnswers) (SetupAnswers, error) {
+	p := setupPrompter{in: bufio.NewScanner(in), out: out}
+	answers := defaults
+
+	_, _ = fmt.Fprintln(out, "Welcome to Mobius!  Press return to accept the default shown in brackets.")
+
+	var err error
+	if answers.Name, err = p.ask("Server name", defaults.Name, maxLen(50)); err != nil {
+		return answers, err
+	}
+	if answers.Description, err = p.ask("Server description", defaults.Description, maxLen(200)); err != nil {
+		return answers, err
+	}
+	if answers.AdminLogin, err = p.ask("Admin account login", defaults.AdminLogin, validLogin); err != nil {
+		return answers, err
+	}
+
+	port, err := p.ask("Port (file transfers use the next port up)", strconv.Itoa(defaults.Port), validPort)
+	if err != nil {
+		return answers, err
+	}
+	answers.Port, _ = strconv.Atoi(port)
+
+	if answers.FileRoot, err = p.ask("Files directory", defaults.FileRoot, nil); err != nil {
+		return answers, err
+	}
+
+	answers.AdminPassword, err = GeneratePassword()
+	if err != nil {
+		return answers, err
+	}
+
+	return answers, nil
+}
+
+func maxLen(n int) func(string) error {
+	return func(s string) error {
+		if s == "" || len(s) > n {
+			return fmt.Errorf("must be between 1 and %d characters", n)
+		}
+		return nil
+	}
+}
+
+func validLogin(s string) error {
+	if s == "" || strings.ContainsAny(s, `/\`) {
+		return errors.New("must not be empty or contain slashes")
+	}
+	return nil
+}
+
+func validPort(s string) error {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65534 {
+		return errors.New("must be a number between 1 and 65534")
+	}
+	return nil
+}
+
+// GeneratePassword returns a random password suitable for an admin account.
+func GeneratePassword() (string, error) {
+	var sb strings.Builder
+	for range generatedPasswordLen {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(generatedPasswordChars))))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(generatedPasswordChars[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// ApplySetup writes the setup answers to a config dir that has been populated from the default config.  The default
+// admin account is renamed to AdminLogin and given AdminPassword.
+func ApplySetup(configDir string, answers SetupAnswers) error {
+	err := saveConfigValues(filepath.Join(configDir, "config.yaml"), map[string]any{
+		"Name":        answers.Name,
+		"Description": answers.Description,
+		"Port":        answers.Port,
+		"FileRoot":    answers.FileRoot,
+	})
+	if err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+
+	fileRoot := answers.FileRoot
+	if !filepath.IsAbs(fileRoot) {
+		fileRoot = filepath.Join(configDir, fileRoot)
+	}
+	if err := os.MkdirAll(fileRoot, 0750); err != nil {
+		return fmt.Errorf("create files directory: %w", err)
+	}
+
+	// The admin password is hashed as configured in the default config.  Clients send passwords obfuscated with
+	// EncodeString, so that's the form that's hashed.
+	config, err := LoadConfig(filepath.Join(configDir, "config.yaml"))
+	if err != nil {
+		return err
+	}
+	hasher, err := hotline.NewPasswordHasher(config.PasswordHashing)
+	if err != nil {
+		return err
+	}
+	hash, err := hasher.Hash(hotline.EncodeString([]byte(answers.AdminPassword)))
+	if err != nil {
+		return fmt.Errorf("hash admin password: %w", err)
+	}
+
+	am, err := NewYAMLAccountManager(filepath.Join(configDir, "Users"), 0)
+	if err != nil {
+		return err
+	}
+
+	admin := am.Get("admin")
+	if admin == nil {
+		return errors.New("default admin account not found")
+	}
+	admin.Name = answers.AdminLogin
+	admin.Password = hash
+
+	if err := am.Update(*admin, answers.AdminLogin); err != nil {
+		return fmt.Errorf("save admin account: %w", err)
+	}
+
+	return nil
+}
+
+// defaultAdminPassword is the password of the admin account in the default config.
+const defaultAdminPassword = "admin"
+
+// ReplaceDefaultAdminPassword gives the admin account a generated password if it still has the default password from
+// the default config.  It returns the new password, or an empty string if the password was not changed.  The new password is hashed with hashPassword, e.g. Server.HashPassword.
+func ReplaceDefaultAdminPassword(am hotline.AccountManager, hashPassword func(pwd []byte) string) (string, error) {
+	admin := am.Get("admin")
+	if admin == nil || !hotline.CheckPassword(admin.Password, hotline.EncodeString([]byte(defaultAdminPassword))) {
+		return "", nil
+	}
+
+	password, err := GeneratePassword()
+	if err != nil {
+		return "", err
+	}
+
+	hash := hashPassword(hotline.EncodeString([]byte(password)))
+	if err := am.Modify(admin.Login, func(a *hotline.Account) { a.Password = hash }); err != nil {
+		return "", fmt.Errorf("save admin account: %w", err)
+	}
+
+	return password, nil
+}
@@ -0,0 +1,100 @@
+package mobius
+
+import (
+	"bytes"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptSetup(t *testing.T) {
+	defaults := SetupAnswers{Name: "My Hotline server", Description: "Default", AdminLogin: "admin", Port: 5500, FileRoot: "Files"}
+
+	var out bytes.Buffer
+	answers, err := PromptSetup(strings.NewReader("Retro BBS\n\nsysop\n99999\n5600\n/srv/hotline\n"), &out, defaults)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Retro BBS", answers.Name)
+	assert.Equal(t, "Default", answers.Description)
+	assert.Equal(t, "sysop", answers.AdminLogin)
+	assert.Equal(t, 5600, answers.Port)
+	assert.Equal(t, "/srv/hotline", answers.FileRoot)
+	assert.Len(t, answers.AdminPassword, generatedPasswordLen)
+	assert.Contains(t, out.String(), "must be a number between 1 and 65534")
+
+	// Running out of input before all questions are answered is an error.
+	_, err = PromptSetup(strings.NewReader("Retro BBS\n"), &out, defaults)
+	assert.Error(t, err)
+}
+
+func TestApplySetup(t *testing.T) {
+	templateDir := "../../cmd/mobius-hotline-server/mobius/config"
+	configDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configDir, "Users"), 0750))
+	for _, name := range []string{"config.yaml", "Users/admin.yaml", "Users/guest.yaml"} {
+		data, err := os.ReadFile(filepath.Join(templateDir, name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, name), data, 0640))
+	}
+
+	require.NoError(t, ApplySetup(configDir, SetupAnswers{
+		Name:          "Retro BBS",
+		Description:   "A place for old Macs",
+		AdminLogin:    "sysop",
+		AdminPassword: "s3cretpassw0rd",
+		Port:          5600,
+		FileRoot:      "Shared",
+	}))
+
+	config, err := LoadConfig(filepath.Join(configDir, "config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "Retro BBS", config.Name)
+	assert.Equal(t, "A place for old Macs", config.Description)
+	assert.Equal(t, 5600, config.Port)
+	assert.Equal(t, filepath.Join(configDir, "Shared"), config.FileRoot)
+	assert.DirExists(t, config.FileRoot)
+
+	am, err := NewYAMLAccountManager(filepath.Join(configDir, "Users"), 0)
+	require.NoError(t, err)
+	assert.Nil(t, am.Get("admin"))
+
+	admin := am.Get("sysop")
+	require.NotNil(t, admin)
+	assert.True(t, admin.Access.IsSet(hotline.AccessDisconUser))
+	cc := &hotline.ClientConn{Server: &hotline.Server{AccountManager: am}}
+	assert.True(t, cc.Authenticate("sysop", hotline.EncodeString([]byte("s3cretpassw0rd"))))
+	assert.False(t, cc.Authenticate("sysop", []byte("s3cretpassw0rd")))
+}
+
+func TestReplaceDefaultAdminPassword(t *testing.T) {
+	usersDir := t.TempDir()
+	data, err := os.ReadFile("../../cmd/mobius-hotline-server/mobius/config/Users/admin.yaml")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(usersDir, "admin.yaml"), data, 0640))
+
+	am, err := NewYAMLAccountManager(usersDir, 0)
+	require.NoError(t, err)
+	srv := &hotline.Server{
+		AccountManager: am,
+		PasswordHasher: hotline.Argon2idHasher{Memory: 1024, Time: 1, Threads: 1},
+		Logger:         NewTestLogger(),
+	}
+	cc := &hotline.ClientConn{Server: srv}
+	require.True(t, cc.Authenticate("admin", hotline.EncodeString([]byte("admin"))))
+
+	password, err := ReplaceDefaultAdminPassword(am, srv.HashPassword)
+	require.NoError(t, err)
+	assert.Len(t, password, generatedPasswordLen)
+	assert.True(t, strings.HasPrefix(am.Get("admin").Password, "$argon2id$"))
+	assert.False(t, cc.Authenticate("admin", hotline.EncodeString([]byte("admin"))))
+	assert.True(t, cc.Authenticate("admin", hotline.EncodeString([]byte(password))))
+
+	// A password that has been changed is left alone.
+	password, err = ReplaceDefaultAdminPassword(am, srv.HashPassword)
+	assert.NoError(t, err)
+	assert.Empty(t, password)
+}
@@ -210,6 +210,57 @@ func (n *ThreadedNewsYAML) ListArticles(newsPath []string) hotline.NewsArtListDa
 	return cat.GetNewsArtListData()
 }
 
+// Articles returns copies of the articles in the category at newsPath, by article ID.
+func (n *ThreadedNewsYAML) Articles(newsPath []string) map[uint32]*hotline.NewsArtData {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var cat hotline.NewsCategoryListData15
+	cats := n.ThreadedNews.Categories
+
+	for _, fp := range newsPath {
+		cat = cats[fp]
+		cats = cats[fp].SubCats
+	}
+
+	articles := make(map[uint32]*hotline.NewsArtData, len(cat.Articles))
+	for id, art := range cat.Articles {
+		copied := *art
+		articles[id] = &copied
+	}
+
+	return articles
+}
+
+// RenamePoster is an AccountRenameHook that updates the poster login of articles posted by a renamed account.
+// Articles posted before poster logins were recorded are left unchanged, since their poster name is only the name the
+// user chose.
+func (n *ThreadedNewsYAML) RenamePoster(oldLogin, newLogin string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var changed bool
+	var rename func(cats map[string]hotline.NewsCategoryListData15)
+	rename = func(cats map[string]hotline.NewsCategoryListData15) {
+		for _, cat := range cats {
+			for _, art := range cat.Articles {
+				if art.PosterLogin == oldLogin {
+					art.PosterLogin = newLogin
+					changed = true
+				}
+			}
+			rename(cat.SubCats)
+		}
+	}
+	rename(n.ThreadedNews.Categories)
+
+	if !changed {
+		return nil
+	}
+
+	return n.writeFile()
+}
+
 func (n *ThreadedNewsYAML) Load() error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
@@ -226,23 +277,5 @@ func (n *ThreadedNewsYAML) Load() error {
 }
 
 func (n *ThreadedNewsYAML) writeFile() error {
-	out, err := yaml.Marshal(&n.ThreadedNews)
-	if err != nil {
-		return err
-	}
-
-	// Define a temporary file path in the same directory.
-	tempFilePath := n.filePath + ".tmp"
-
-	// Write the marshaled YAML to the temporary file.
-	if err := os.WriteFile(tempFilePath, out, 0644); err != nil {
-		return fmt.Errorf("write to temporary file: %v", err)
-	}
-
-	// Atomically rename the temporary file to the final file path.
-	if err := os.Rename(tempFilePath, n.filePath); err != nil {
-		return fmt.Errorf("rename temporary file to final file: %v", err)
-	}
-
-	return nil
+	return writeYAMLFile(n.filePath, &n.ThreadedNews)
 }
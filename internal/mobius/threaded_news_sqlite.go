@@ -0,0 +1,496 @@
+package mobius
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// newsSQLiteMigrations are the schema changes applied to a news database, in order.  As with sqliteMigrations, new
+// migrations must only ever be appended.
+var newsSQLiteMigrations = []string{
+	`CREATE TABLE news_groupings (
+		id        INTEGER PRIMARY KEY,
+		parent_id INTEGER NOT NULL,
+		name      TEXT NOT NULL,
+		type      INTEGER NOT NULL,
+		UNIQUE (parent_id, name)
+	)`,
+	`CREATE TABLE news_articles (
+		grouping_id INTEGER NOT NULL,
+		id          INTEGER NOT NULL,
+		article     TEXT NOT NULL,
+		PRIMARY KEY (grouping_id, id)
+	)`,
+}
+
+// SQLiteThreadedNews stores threaded news in a SQLite database with a row for each bundle, category, and article, so
+// that posting an article writes only that article instead of rewriting all of ThreadedNews.yaml.  Articles are stored
+// as YAML in the same format as in ThreadedNews.yaml.
+//
+// Bundles and categories are rows of news_groupings, with a parent_id of 0 at the top level.
+type SQLiteThreadedNews struct {
+	db *sql.DB
+}
+
+// NewSQLiteThreadedNews opens the news database at dbPath, creating it if needed.
+func NewSQLiteThreadedNews(dbPath string) (*SQLiteThreadedNews, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open news database: %w", err)
+	}
+
+	// SQLite allows one writer at a time; a single connection avoids "database is locked" errors between connections.
+	db.SetMaxOpenConns(1)
+
+	if _, err := migrateSQLite(db, newsSQLiteMigrations, "news database"); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SQLiteThreadedNews{db: db}, nil
+}
+
+// Close closes the news database.
+func (n *SQLiteThreadedNews) Close() error {
+	return n.db.Close()
+}
+
+// queryRower is implemented by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// grouping returns the ID and type of the bundle or category at newsPath.  The top level has ID 0.
+func grouping(q queryRower, newsPath []string) (id int64, t [2]byte, err error) {
+	for _, name := range newsPath {
+		var typ uint16
+		err := q.QueryRow("SELECT id, type FROM news_groupings WHERE parent_id = ? AND name = ?", id, name).Scan(&id, &typ)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, t, fmt.Errorf("news path %q: %w", newsPath, fs.ErrNotExist)
+		}
+		if err != nil {
+			return 0, t, fmt.Errorf("look up news path: %w", err)
+		}
+		binary.BigEndian.PutUint16(t[:], typ)
+	}
+
+	return id, t, nil
+}
+
+func (n *SQLiteThreadedNews) CreateGrouping(newsPath []string, name string, t [2]byte) error {
+	tx, err := n.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := insertGrouping(tx, newsPath, name, t); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertGrouping adds a bundle or category to the bundle at newsPath and returns its ID.
+func insertGrouping(tx *sql.Tx, newsPath []string, name string, t [2]byte) (int64, error) {
+	parentID, _, err := grouping(tx, newsPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, _, err := grouping(tx, append(newsPath[:len(newsPath):len(newsPath)], name)); err == nil {
+		return 0, fmt.Errorf("create news grouping %q: %w", name, fs.ErrExist)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO news_groupings (parent_id, name, type) VALUES (?, ?, ?)",
+		parentID, name, binary.BigEndian.Uint16(t[:]),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create news grouping %q: %w", name, err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (n *SQLiteThreadedNews) NewsItem(newsPath []string) hotline.NewsCategoryListData15 {
+	if len(newsPath) == 0 {
+		return hotline.NewsCategoryListData15{}
+	}
+
+	_, t, err := grouping(n.db, newsPath)
+	if err != nil {
+		return hotline.NewsCategoryListData15{}
+	}
+
+	return hotline.NewsCategoryListData15{Name: newsPath[len(newsPath)-1], Type: t}
+}
+
+// DeleteNewsItem deletes the bundle or category at newsPath, along with everything in it.
+func (n *SQLiteThreadedNews) DeleteNewsItem(newsPath []string) error {
+	if len(newsPath) == 0 {
+		return fmt.Errorf("invalid news path")
+	}
+
+	tx, err := n.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, _, err := grouping(tx, newsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	const descendants = `WITH RECURSIVE tree(id) AS (
+		SELECT ? UNION ALL SELECT g.id FROM news_groupings g JOIN tree ON g.parent_id = tree.id
+	)`
+	if _, err := tx.Exec(descendants+" DELETE FROM news_articles WHERE grouping_id IN (SELECT id FROM tree)", id); err != nil {
+		return fmt.Errorf("delete news articles: %w", err)
+	}
+	if _, err := tx.Exec(descendants+" DELETE FROM news_groupings WHERE id IN (SELECT id FROM tree)", id); err != nil {
+		return fmt.Errorf("delete news grouping: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (n *SQLiteThreadedNews) GetArticle(newsPath []string, articleID uint32) *hotline.NewsArtData {
+	id, _, err := grouping(n.db, newsPath)
+	if err != nil {
+		return nil
+	}
+
+	art, err := getArticle(n.db, id, articleID)
+	if err != nil {
+		return nil
+	}
+
+	return art
+}
+
+func getArticle(q queryRower, groupingID int64, articleID uint32) (*hotline.NewsArtData, error) {
+	var data string
+	err := q.QueryRow("SELECT article FROM news_articles WHERE grouping_id = ? AND id = ?", groupingID, articleID).Scan(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	var art hotline.NewsArtData
+	if err := yaml.Unmarshal([]byte(data), &art); err != nil {
+		return nil, fmt.Errorf("unmarshal article %d: %w", articleID, err)
+	}
+
+	return &art, nil
+}
+
+func putArticle(tx *sql.Tx, groupingID int64, articleID uint32, art *hotline.NewsArtData) error {
+	data, err := yaml.Marshal(art)
+	if err != nil {
+		return fmt.Errorf("marshal article: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"INSERT OR REPLACE INTO news_articles (grouping_id, id, article) VALUES (?, ?, ?)",
+		groupingID, articleID, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("save article %d: %w", articleID, err)
+	}
+
+	return nil
+}
+
+// GetCategories returns the bundles and categories in the bundle at paths, sorted by name.  Listings only use the
+// number of items in each, so their SubCats and Articles are filled with placeholders rather than loaded.
+func (n *SQLiteThreadedNews) GetCategories(paths []string) []hotline.NewsCategoryListData15 {
+	id, _, err := grouping(n.db, paths)
+	if err != nil {
+		return nil
+	}
+
+	rows, err := n.db.Query(`SELECT name, type,
+			(SELECT COUNT(*) FROM news_groupings c WHERE c.parent_id = g.id),
+			(SELECT COUNT(*) FROM news_articles a WHERE a.grouping_id = g.id)
+		FROM news_groupings g WHERE parent_id = ? ORDER BY name`, id)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var categories []hotline.NewsCategoryListData15
+	for rows.Next() {
+		var (
+			cat                   hotline.NewsCategoryListData15
+			typ                   uint16
+			subCatCount, artCount int
+		)
+		if err := rows.Scan(&cat.Name, &typ, &subCatCount, &artCount); err != nil {
+			continue
+		}
+		binary.BigEndian.PutUint16(cat.Type[:], typ)
+
+		cat.SubCats = make(map[string]hotline.NewsCategoryListData15, subCatCount)
+		for i := range subCatCount {
+			cat.SubCats[strconv.Itoa(i)] = hotline.NewsCategoryListData15{}
+		}
+		cat.Articles = make(map[uint32]*hotline.NewsArtData, artCount)
+		for i := range artCount {
+			cat.Articles[uint32(i)] = nil
+		}
+
+		categories = append(categories, cat)
+	}
+
+	return categories
+}
+
+func (n *SQLiteThreadedNews) PostArticle(newsPath []string, parentArticleID uint32, article hotline.NewsArtData) error {
+	if len(newsPath) == 0 {
+		return fmt.Errorf("invalid news path")
+	}
+
+	binary.BigEndian.PutUint32(article.ParentArt[:], parentArticleID)
+
+	tx, err := n.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	catID, _, err := grouping(tx, newsPath)
+	if err != nil {
+		return err
+	}
+
+	var prevID uint32
+	if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) FROM news_articles WHERE grouping_id = ?", catID).Scan(&prevID); err != nil {
+		return fmt.Errorf("read article IDs: %w", err)
+	}
+	nextID := prevID + 1
+
+	// Link the previous article to the new one.
+	if prevID != 0 {
+		binary.BigEndian.PutUint32(article.PrevArt[:], prevID)
+
+		prevArt, err := getArticle(tx, catID, prevID)
+		if err != nil {
+			return fmt.Errorf("read article %d: %w", prevID, err)
+		}
+		binary.BigEndian.PutUint32(prevArt.NextArt[:], nextID)
+		if err := putArticle(tx, catID, prevID, prevArt); err != nil {
+			return err
+		}
+	}
+
+	// Update parent article with first child reply
+	if parentArticleID != 0 {
+		parentArt, err := getArticle(tx, catID, parentArticleID)
+		if err != nil {
+			return fmt.Errorf("read article %d: %w", parentArticleID, err)
+		}
+		if parentArt.FirstChildArt == [4]byte{0, 0, 0, 0} {
+			binary.BigEndian.PutUint32(parentArt.FirstChildArt[:], nextID)
+			if err := putArticle(tx, catID, parentArticleID, parentArt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := putArticle(tx, catID, nextID, &article); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (n *SQLiteThreadedNews) DeleteArticle(newsPath []string, articleID uint32, _ bool) error {
+	if len(newsPath) == 0 {
+		return fmt.Errorf("invalid news path")
+	}
+
+	catID, _, err := grouping(n.db, newsPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := n.db.Exec("DELETE FROM news_articles WHERE grouping_id = ? AND id = ?", catID, articleID); err != nil {
+		return fmt.Errorf("delete article %d: %w", articleID, err)
+	}
+
+	return nil
+}
+
+func (n *SQLiteThreadedNews) ListArticles(newsPath []string) hotline.NewsArtListData {
+	cat := hotline.NewsCategoryListData15{Articles: n.Articles(newsPath)}
+
+	return cat.GetNewsArtListData()
+}
+
+// Articles returns the articles in the category at newsPath, by article ID.
+func (n *SQLiteThreadedNews) Articles(newsPath []string) map[uint32]*hotline.NewsArtData {
+	articles := make(map[uint32]*hotline.NewsArtData)
+
+	catID, _, err := grouping(n.db, newsPath)
+	if err != nil {
+		return articles
+	}
+
+	rows, err := n.db.Query("SELECT id, article FROM news_articles WHERE grouping_id = ?", catID)
+	if err != nil {
+		return articles
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   uint32
+			data string
+			art  hotline.NewsArtData
+		)
+		if err := rows.Scan(&id, &data); err != nil {
+			continue
+		}
+		if err := yaml.Unmarshal([]byte(data), &art); err != nil {
+			continue
+		}
+		articles[id] = &art
+	}
+
+	return articles
+}
+
+// RenamePoster is an AccountRenameHook that updates the poster login of articles posted by a renamed account, in the
+// same way as ThreadedNewsYAML.RenamePoster.
+func (n *SQLiteThreadedNews) RenamePoster(oldLogin, newLogin string) error {
+	tx, err := n.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	type articleKey struct {
+		groupingID int64
+		id         uint32
+	}
+	renamed := make(map[articleKey]*hotline.NewsArtData)
+
+	rows, err := tx.Query("SELECT grouping_id, id, article FROM news_articles")
+	if err != nil {
+		return fmt.Errorf("read articles: %w", err)
+	}
+	for rows.Next() {
+		var (
+			key  articleKey
+			data string
+			art  hotline.NewsArtData
+		)
+		if err := rows.Scan(&key.groupingID, &key.id, &data); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("read articles: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(data), &art); err != nil {
+			continue
+		}
+		if art.PosterLogin == oldLogin {
+			art.PosterLogin = newLogin
+			renamed[key] = &art
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("read articles: %w", err)
+	}
+
+	for key, art := range renamed {
+		if err := putArticle(tx, key.groupingID, key.id, art); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ImportYAML copies the threaded news in the ThreadedNews.yaml file at filePath into the database, keeping article IDs,
+// and returns the number of articles copied.  The database must be empty, so that news isn't imported twice.
+func (n *SQLiteThreadedNews) ImportYAML(filePath string) (int, error) {
+	var news hotline.ThreadedNews
+	if err := loadFromYAMLFile(filePath, &news); err != nil {
+		return 0, fmt.Errorf("load %s: %w", filePath, err)
+	}
+
+	tx, err := n.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM news_groupings").Scan(&existing); err != nil {
+		return 0, fmt.Errorf("read news database: %w", err)
+	}
+	if existing > 0 {
+		return 0, fmt.Errorf("news database already has news: %w", fs.ErrExist)
+	}
+
+	var count int
+	var importCats func(newsPath []string, cats map[string]hotline.NewsCategoryListData15) error
+	importCats = func(newsPath []string, cats map[string]hotline.NewsCategoryListData15) error {
+		for name, cat := range cats {
+			id, err := insertGrouping(tx, newsPath, name, cat.Type)
+			if err != nil {
+				return err
+			}
+
+			for artID, art := range cat.Articles {
+				if err := putArticle(tx, id, artID, art); err != nil {
+					return err
+				}
+				count++
+			}
+
+			if err := importCats(append(newsPath[:len(newsPath):len(newsPath)], name), cat.SubCats); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+	if err := importCats(nil, news.Categories); err != nil {
+		return 0, fmt.Errorf("import news: %w", err)
+	}
+
+	return count, tx.Commit()
+}
+
+// MigrateThreadedNews copies the news in the ThreadedNews.yaml file at yamlPath into a new news database at dbPath and
+// returns the number of articles copied.  If the copy fails, the new database is removed so that it can be tried again.
+func MigrateThreadedNews(yamlPath, dbPath string) (int, error) {
+	if _, err := os.Stat(dbPath); err == nil {
+		return 0, fmt.Errorf("%s already exists", dbPath)
+	}
+
+	n, err := NewSQLiteThreadedNews(dbPath)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := n.ImportYAML(yamlPath)
+	_ = n.Close()
+	if err != nil {
+		_ = os.Remove(dbPath)
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,100 @@
+package mobius
+
+import (
+	"encoding/binary"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteThreadedNews(t *testing.T) {
+	n, err := NewSQLiteThreadedNews(filepath.Join(t.TempDir(), "ThreadedNews.db"))
+	require.NoError(t, err)
+	defer n.Close()
+
+	assert.NoError(t, n.CreateGrouping(nil, "Bundle", hotline.NewsBundle))
+	assert.NoError(t, n.CreateGrouping([]string{"Bundle"}, "General", hotline.NewsCategory))
+	assert.ErrorIs(t, n.CreateGrouping([]string{"Bundle"}, "General", hotline.NewsCategory), fs.ErrExist)
+	assert.ErrorIs(t, n.CreateGrouping([]string{"Missing"}, "General", hotline.NewsCategory), fs.ErrNotExist)
+
+	path := []string{"Bundle", "General"}
+	assert.Equal(t, hotline.NewsCategory, n.NewsItem(path).Type)
+	assert.Equal(t, hotline.NewsBundle, n.NewsItem([]string{"Bundle"}).Type)
+
+	// Articles are linked to the previous article and their parent, as with ThreadedNewsYAML.
+	assert.NoError(t, n.PostArticle(path, 0, hotline.NewsArtData{Title: "Hello", Poster: "Alice", PosterLogin: "alice", Data: "first"}))
+	assert.NoError(t, n.PostArticle(path, 1, hotline.NewsArtData{Title: "Re: Hello", Poster: "bob", Data: "reply"}))
+
+	first := n.GetArticle(path, 1)
+	require.NotNil(t, first)
+	assert.Equal(t, "first", first.Data)
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(first.NextArt[:]))
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(first.FirstChildArt[:]))
+
+	reply := n.GetArticle(path, 2)
+	require.NotNil(t, reply)
+	assert.Equal(t, uint32(1), binary.BigEndian.Uint32(reply.PrevArt[:]))
+	assert.Equal(t, uint32(1), binary.BigEndian.Uint32(reply.ParentArt[:]))
+	assert.Nil(t, n.GetArticle(path, 3))
+
+	assert.Equal(t, 2, n.ListArticles(path).Count)
+
+	cats := n.GetCategories([]string{"Bundle"})
+	if assert.Len(t, cats, 1) {
+		assert.Equal(t, "General", cats[0].Name)
+		assert.Equal(t, hotline.NewsCategory, cats[0].Type)
+		assert.Len(t, cats[0].Articles, 2)
+	}
+
+	assert.NoError(t, n.RenamePoster("alice", "alicia"))
+	assert.Equal(t, "alicia", n.GetArticle(path, 1).PosterLogin)
+	assert.Equal(t, "", n.GetArticle(path, 2).PosterLogin)
+
+	assert.NoError(t, n.DeleteArticle(path, 2, false))
+	assert.Equal(t, 1, n.ListArticles(path).Count)
+
+	// Deleting a bundle deletes everything in it.
+	assert.NoError(t, n.DeleteNewsItem([]string{"Bundle"}))
+	assert.Empty(t, n.GetCategories(nil))
+	assert.Nil(t, n.GetArticle(path, 1))
+	assert.Error(t, n.PostArticle(path, 0, hotline.NewsArtData{Title: "Lost"}))
+}
+
+func TestMigrateThreadedNews(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "ThreadedNews.yaml")
+	dbPath := filepath.Join(dir, "ThreadedNews.db")
+
+	yamlNews, err := NewThreadedNewsYAML(yamlPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	yamlNews.ThreadedNews.Categories = map[string]hotline.NewsCategoryListData15{}
+	require.NoError(t, yamlNews.CreateGrouping(nil, "Bundle", hotline.NewsBundle))
+	require.NoError(t, yamlNews.CreateGrouping([]string{"Bundle"}, "General", hotline.NewsCategory))
+	require.NoError(t, yamlNews.CreateGrouping(nil, "Announcements", hotline.NewsCategory))
+	require.NoError(t, yamlNews.PostArticle([]string{"Bundle", "General"}, 0, hotline.NewsArtData{Title: "Hello", Data: "first"}))
+	require.NoError(t, yamlNews.PostArticle([]string{"Bundle", "General"}, 1, hotline.NewsArtData{Title: "Re: Hello", Data: "reply"}))
+	require.NoError(t, yamlNews.PostArticle([]string{"Announcements"}, 0, hotline.NewsArtData{Title: "Welcome", Data: "hi"}))
+
+	count, err := MigrateThreadedNews(yamlPath, dbPath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	n, err := NewSQLiteThreadedNews(dbPath)
+	require.NoError(t, err)
+	defer n.Close()
+
+	// The news is the same in both stores.
+	for _, path := range [][]string{{"Bundle", "General"}, {"Announcements"}} {
+		assert.Equal(t, yamlNews.ListArticles(path), n.ListArticles(path))
+	}
+	assert.Equal(t, *yamlNews.GetArticle([]string{"Bundle", "General"}, 2), *n.GetArticle([]string{"Bundle", "General"}, 2))
+	assert.Equal(t, []string{"Announcements", "Bundle"}, []string{n.GetCategories(nil)[0].Name, n.GetCategories(nil)[1].Name})
+
+	// News isn't migrated twice.
+	_, err = MigrateThreadedNews(yamlPath, dbPath)
+	assert.Error(t, err)
+}
@@ -212,3 +212,43 @@ func TestThreadedNewsYAML_CreateGrouping(t *testing.T) {
 		})
 	}
 }
+
+func TestThreadedNewsYAML_RenamePoster(t *testing.T) {
+	n := &ThreadedNewsYAML{
+		filePath: filepath.Join(t.TempDir(), "ThreadedNews.yaml"),
+		ThreadedNews: hotline.ThreadedNews{
+			Categories: map[string]hotline.NewsCategoryListData15{
+				"General": {
+					Type: hotline.NewsCategory,
+					Articles: map[uint32]*hotline.NewsArtData{
+						1: {Title: "recorded", Poster: "Alice", PosterLogin: "alice"},
+						2: {Title: "legacy", Poster: "alice"},
+						3: {Title: "other", Poster: "Bob", PosterLogin: "bob"},
+					},
+				},
+				"Bundle": {
+					Type: hotline.NewsBundle,
+					SubCats: map[string]hotline.NewsCategoryListData15{
+						"Nested": {
+							Type: hotline.NewsCategory,
+							Articles: map[uint32]*hotline.NewsArtData{
+								1: {Title: "nested", Poster: "Alice", PosterLogin: "alice"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	assert.NoError(t, n.RenamePoster("alice", "alicia"))
+
+	general := n.ThreadedNews.Categories["General"].Articles
+	assert.Equal(t, "alicia", general[1].PosterLogin)
+	assert.Equal(t, "", general[2].PosterLogin, "articles are matched by poster login only")
+	assert.Equal(t, "alice", general[2].Poster, "poster names are not changed")
+	assert.Equal(t, "bob", general[3].PosterLogin)
+	assert.Equal(t, "alicia", n.ThreadedNews.Categories["Bundle"].SubCats["Nested"].Articles[1].PosterLogin)
+
+	assert.FileExists(t, n.filePath)
+}
@@ -0,0 +1,166 @@
+package mobius
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tracingFlushInterval = 5 * time.Second
+	tracingMaxQueued     = 2048 // Spans beyond this are dropped until the next flush
+)
+
+// OTLPSpanExporter batches spans and sends them to an OpenTelemetry collector using OTLP/HTTP with JSON encoding.
+type OTLPSpanExporter struct {
+	url     string
+	headers map[string]string
+	version string
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	queue   []hotline.Span
+	dropped int
+}
+
+func NewOTLPSpanExporter(config hotline.Tracing, version string, logger *slog.Logger) *OTLPSpanExporter {
+	return &OTLPSpanExporter{
+		url:     strings.TrimSuffix(config.OTLPEndpoint, "/") + "/v1/traces",
+		headers: config.OTLPHeaders,
+		version: version,
+		logger:  logger,
+	}
+}
+
+func (e *OTLPSpanExporter) ExportSpan(span hotline.Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.queue) >= tracingMaxQueued {
+		e.dropped++
+		return
+	}
+
+	e.queue = append(e.queue, span)
+}
+
+// Run sends queued spans to the collector every few seconds until ctx is cancelled.
+func (e *OTLPSpanExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(tracingFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.Flush()
+			return
+		case <-ticker.C:
+			e.Flush()
+		}
+	}
+}
+
+// Flush sends the queued spans to the collector.
+func (e *OTLPSpanExporter) Flush() {
+	e.mu.Lock()
+	spans, dropped := e.queue, e.dropped
+	e.queue, e.dropped = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		e.logger.Warn("Dropped trace spans", "count", dropped)
+	}
+
+	if len(spans) == 0 {
+		return
+	}
+
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, otlpSpan(span))
+	}
+
+	payload := map[string]any{
+		"resourceSpans": []map[string]any{
+			{
+				"resource": map[string]any{
+					"attributes": []map[string]any{
+						otlpString("service.name", "mobius"),
+						otlpString("service.version", e.version),
+						otlpString("os.type", runtime.GOOS),
+					},
+				},
+				"scopeSpans": []map[string]any{
+					{
+						"scope": map[string]string{"name": "mobius"},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+
+	if err := postJSON(e.url, e.headers, payload); err != nil {
+		e.logger.Error("Error exporting trace spans", "count", len(spans), "err", err)
+	}
+}
+
+const (
+	otlpSpanKindServer  = 2
+	otlpStatusCodeOK    = 1
+	otlpStatusCodeError = 2
+)
+
+func otlpSpan(span hotline.Span) map[string]any {
+	var attrs []map[string]any
+	for k, v := range span.Attrs {
+		attrs = append(attrs, otlpAttr(k, v))
+	}
+
+	status := map[string]any{"code": otlpStatusCodeOK}
+	if span.Err != nil {
+		status = map[string]any{"code": otlpStatusCodeError, "message": span.Err.Error()}
+	}
+
+	return map[string]any{
+		"traceId":           hex.EncodeToString(span.TraceID[:]),
+		"spanId":            hex.EncodeToString(span.SpanID[:]),
+		"name":              span.Name,
+		"kind":              otlpSpanKindServer,
+		"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+		"attributes":        attrs,
+		"status":            status,
+	}
+}
+
+// otlpAttr encodes an attribute as an OTLP KeyValue.  64-bit integers are encoded as strings as required by the OTLP
+// JSON encoding.
+func otlpAttr(key string, value any) map[string]any {
+	var v map[string]any
+	switch value := value.(type) {
+	case int:
+		v = map[string]any{"intValue": strconv.FormatInt(int64(value), 10)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case uint16:
+		v = map[string]any{"intValue": strconv.FormatUint(uint64(value), 10)}
+	case uint64:
+		v = map[string]any{"intValue": strconv.FormatUint(value, 10)}
+	case bool:
+		v = map[string]any{"boolValue": value}
+	case float64:
+		v = map[string]any{"doubleValue": value}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(value)}
+	}
+
+	return map[string]any{"key": key, "value": v}
+}
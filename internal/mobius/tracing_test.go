@@ -0,0 +1,45 @@
+package mobius
+
+import (
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestOTLPSpanExporter(t *testing.T) {
+	var captured capturedRequest
+	ts := captureServer(t, &captured)
+	defer ts.Close()
+
+	exporter := NewOTLPSpanExporter(hotline.Tracing{OTLPEndpoint: ts.URL}, "1.2.3", slog.Default())
+
+	// Nothing is sent when there are no spans.
+	exporter.Flush()
+	assert.Empty(t, captured.path)
+
+	exporter.ExportSpan(hotline.Span{
+		TraceID: [16]byte{0xab},
+		SpanID:  [8]byte{0xcd},
+		Name:    "hotline.file_transfer",
+		Start:   time.Unix(1, 0),
+		End:     time.Unix(2, 0),
+		Attrs:   map[string]any{"hotline.transfer.bytes_sent": int64(1024)},
+		Err:     errors.New("file download: EOF"),
+	})
+	exporter.Flush()
+
+	assert.Equal(t, "/v1/traces", captured.path)
+
+	span := captured.body["resourceSpans"].([]any)[0].(map[string]any)["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)[0].(map[string]any)
+	assert.Equal(t, "ab000000000000000000000000000000", span["traceId"])
+	assert.Equal(t, "cd00000000000000", span["spanId"])
+	assert.Equal(t, "1000000000", span["startTimeUnixNano"])
+	assert.Equal(t, "2000000000", span["endTimeUnixNano"])
+	assert.Equal(t, []any{
+		map[string]any{"key": "hotline.transfer.bytes_sent", "value": map[string]any{"intValue": "1024"}},
+	}, span["attributes"])
+	assert.Equal(t, map[string]any{"code": float64(2), "message": "file download: EOF"}, span["status"])
+}
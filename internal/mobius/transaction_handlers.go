@@ -1,17 +1,20 @@
 package mobius
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/jhalter/mobius/hotline"
 	"golang.org/x/text/encoding/charmap"
 	"io"
+	"io/fs"
+	"maps"
 	"math/big"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 )
@@ -26,94 +29,172 @@ var txtEncoder = charmap.Macintosh.NewEncoder()
 func RegisterHandlers(srv *hotline.Server) {
 	srv.HandleFunc(hotline.TranAgreed, HandleTranAgreed)
 	srv.HandleFunc(hotline.TranChatSend, HandleChatSend)
-	srv.HandleFunc(hotline.TranDelNewsArt, HandleDelNewsArt)
+	srv.HandleFunc(hotline.TranDelNewsArt, HandleDelNewsArt,
+		hotline.RequireAccess(hotline.AccessNewsDeleteArt, "You are not allowed to delete news articles."))
 	srv.HandleFunc(hotline.TranDelNewsItem, HandleDelNewsItem)
 	srv.HandleFunc(hotline.TranDeleteFile, HandleDeleteFile)
 	srv.HandleFunc(hotline.TranDeleteUser, HandleDeleteUser)
-	srv.HandleFunc(hotline.TranDisconnectUser, HandleDisconnectUser)
-	srv.HandleFunc(hotline.TranDownloadFile, HandleDownloadFile)
-	srv.HandleFunc(hotline.TranDownloadFldr, HandleDownloadFolder)
-	srv.HandleFunc(hotline.TranGetClientInfoText, HandleGetClientInfoText)
+	srv.HandleFunc(hotline.TranDisconnectUser, HandleDisconnectUser,
+		hotline.RequireAccess(hotline.AccessDisconUser, "You are not allowed to disconnect users."))
+	srv.HandleFunc(hotline.TranDownloadFile, HandleDownloadFile,
+		hotline.RequireAccess(hotline.AccessDownloadFile, "You are not allowed to download files."))
+	srv.HandleFunc(hotline.TranDownloadFldr, HandleDownloadFolder,
+		hotline.RequireAccess(hotline.AccessDownloadFolder, "You are not allowed to download folders."))
+	srv.HandleFunc(hotline.TranGetClientInfoText, HandleGetClientInfoText,
+		hotline.RequireAccess(hotline.AccessGetClientInfo, "You are not allowed to get client info."))
 	srv.HandleFunc(hotline.TranGetFileInfo, HandleGetFileInfo)
 	srv.HandleFunc(hotline.TranGetFileNameList, HandleGetFileNameList)
-	srv.HandleFunc(hotline.TranGetMsgs, HandleGetMsgs)
-	srv.HandleFunc(hotline.TranGetNewsArtData, HandleGetNewsArtData)
+	srv.HandleFunc(hotline.TranGetMsgs, HandleGetMsgs,
+		hotline.RequireAccess(hotline.AccessNewsReadArt, "You are not allowed to read news."))
+	srv.HandleFunc(hotline.TranGetNewsArtData, HandleGetNewsArtData,
+		hotline.RequireAccess(hotline.AccessNewsReadArt, "You are not allowed to read news."))
 	srv.HandleFunc(hotline.TranGetNewsArtNameList, HandleGetNewsArtNameList)
 	srv.HandleFunc(hotline.TranGetNewsCatNameList, HandleGetNewsCatNameList)
 	srv.HandleFunc(hotline.TranGetUser, HandleGetUser)
 	srv.HandleFunc(hotline.TranGetUserNameList, HandleGetUserNameList)
-	srv.HandleFunc(hotline.TranInviteNewChat, HandleInviteNewChat)
-	srv.HandleFunc(hotline.TranInviteToChat, HandleInviteToChat)
+	srv.HandleFunc(hotline.TranInviteNewChat, HandleInviteNewChat,
+		hotline.RequireAccess(hotline.AccessOpenChat, "You are not allowed to request private chat."))
+	srv.HandleFunc(hotline.TranInviteToChat, HandleInviteToChat,
+		hotline.RequireAccess(hotline.AccessOpenChat, "You are not allowed to request private chat."))
 	srv.HandleFunc(hotline.TranJoinChat, HandleJoinChat)
 	srv.HandleFunc(hotline.TranKeepAlive, HandleKeepAlive)
 	srv.HandleFunc(hotline.TranLeaveChat, HandleLeaveChat)
 	srv.HandleFunc(hotline.TranListUsers, HandleListUsers)
 	srv.HandleFunc(hotline.TranMoveFile, HandleMoveFile)
-	srv.HandleFunc(hotline.TranNewFolder, HandleNewFolder)
+	srv.HandleFunc(hotline.TranNewFolder, HandleNewFolder,
+		hotline.RequireAccess(hotline.AccessCreateFolder, "You are not allowed to create folders."))
 	srv.HandleFunc(hotline.TranNewNewsCat, HandleNewNewsCat)
 	srv.HandleFunc(hotline.TranNewNewsFldr, HandleNewNewsFldr)
 	srv.HandleFunc(hotline.TranNewUser, HandleNewUser)
 	srv.HandleFunc(hotline.TranUpdateUser, HandleUpdateUser)
-	srv.HandleFunc(hotline.TranOldPostNews, HandleTranOldPostNews)
-	srv.HandleFunc(hotline.TranPostNewsArt, HandlePostNewsArt)
+	srv.HandleFunc(hotline.TranOldPostNews, HandleTranOldPostNews,
+		hotline.RequireAccess(hotline.AccessNewsPostArt, "You are not allowed to post news."))
+	srv.HandleFunc(hotline.TranPostNewsArt, HandlePostNewsArt,
+		hotline.RequireAccess(hotline.AccessNewsPostArt, "You are not allowed to post news articles."))
 	srv.HandleFunc(hotline.TranRejectChatInvite, HandleRejectChatInvite)
-	srv.HandleFunc(hotline.TranSendInstantMsg, HandleSendInstantMsg)
+	srv.HandleFunc(hotline.TranSendInstantMsg, HandleSendInstantMsg,
+		hotline.RequireAccess(hotline.AccessSendPrivMsg, "You are not allowed to send private messages."))
 	srv.HandleFunc(hotline.TranSetChatSubject, HandleSetChatSubject)
-	srv.HandleFunc(hotline.TranMakeFileAlias, HandleMakeAlias)
+	srv.HandleFunc(hotline.TranMakeFileAlias, HandleMakeAlias,
+		hotline.RequireAccess(hotline.AccessMakeAlias, "You are not allowed to make aliases."))
 	srv.HandleFunc(hotline.TranSetClientUserInfo, HandleSetClientUserInfo)
 	srv.HandleFunc(hotline.TranSetFileInfo, HandleSetFileInfo)
-	srv.HandleFunc(hotline.TranSetUser, HandleSetUser)
-	srv.HandleFunc(hotline.TranUploadFile, HandleUploadFile)
-	srv.HandleFunc(hotline.TranUploadFldr, HandleUploadFolder)
-	srv.HandleFunc(hotline.TranUserBroadcast, HandleUserBroadcast)
+	srv.HandleFunc(hotline.TranSetUser, HandleSetUser,
+		hotline.RequireAccess(hotline.AccessModifyUser, "You are not allowed to modify accounts."))
+	srv.HandleFunc(hotline.TranUploadFile, HandleUploadFile,
+		hotline.RequireAccess(hotline.AccessUploadFile, "You are not allowed to upload files."))
+	srv.HandleFunc(hotline.TranUploadFldr, HandleUploadFolder,
+		hotline.RequireAccess(hotline.AccessUploadFolder, "You are not allowed to upload folders."))
+	srv.HandleFunc(hotline.TranUserBroadcast, HandleUserBroadcast,
+		hotline.RequireAccess(hotline.AccessBroadcast, "You are not allowed to send broadcast messages."))
 	srv.HandleFunc(hotline.TranDownloadBanner, HandleDownloadBanner)
+	srv.HandleFunc(hotline.TranGetServerInfo, HandleGetServerInfo)
+	srv.HandleFunc(hotline.TranGetIncompleteUploads, HandleGetIncompleteUploads)
+	srv.HandleFunc(hotline.TranDeleteIncompleteUpload, HandleDeleteIncompleteUpload)
+	srv.HandleFunc(hotline.TranGetBookmarks, HandleGetBookmarks)
+	srv.HandleFunc(hotline.TranAddBookmark, HandleAddBookmark)
+	srv.HandleFunc(hotline.TranDeleteBookmark, HandleDeleteBookmark)
+	srv.HandleFunc(hotline.TranGetPreferences, HandleGetPreferences)
+	srv.HandleFunc(hotline.TranSetPreferences, HandleSetPreferences)
+	srv.HandleFunc(hotline.TranNegotiateCapabilities, HandleNegotiateCapabilities)
+
+	srv.RegisterCapability(hotline.CapServerInfo)
+	srv.RegisterCapability(hotline.CapIncompleteUploads)
+	srv.RegisterCapability(hotline.CapBookmarks)
+	srv.RegisterCapability(hotline.CapPreferences)
+	srv.RegisterCapability(hotline.CapUserGroups)
+
+	srv.HandleChatCommand("report", HandleReportCommand)
+	srv.HandleChatCommand("reports", HandleReportsCommand)
+	srv.HandleChatCommand("pending", HandlePendingCommand)
+	srv.HandleChatCommand("approve", HandleApproveCommand)
+	srv.HandleChatCommand("reject", HandleRejectCommand)
+	srv.HandleChatCommand("chatlimit", HandleChatLimitCommand)
+	srv.HandleChatCommand("inviteonly", HandleInviteOnlyCommand)
+	srv.HandleChatCommand("blockinvites", HandleBlockInvitesCommand)
+	srv.HandleChatCommand("away", HandleAwayCommand)
+	srv.HandleChatCommand("back", HandleBackCommand)
+	srv.HandleChatCommand("broadcasts", HandleBroadcastsCommand)
+	srv.HandleChatCommand("events", HandleEventsCommand)
 }
 
 func HandleChatSend(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	// Slash commands are handled by the server instead of being relayed to other users.  Each command is responsible
+	// for its own permission checks.
+	if name, args, ok := parseChatCommand(t.GetField(hotline.FieldData).Data); ok {
+		if handler, ok := cc.Server.ChatCommand(name); ok {
+			return handler(cc, t, args)
+		}
+	}
+
 	if !cc.Authorize(hotline.AccessSendChat) {
 		return cc.NewErrReply(t, "You are not allowed to participate in chat.")
 	}
 
+	msg, clipped := hotline.LimitLines(t.GetField(hotline.FieldData).Data, cc.Server.Config.MaxChatLines)
+
 	// Truncate long usernames
 	// %13.13s: This means a string that is right-aligned in a field of 13 characters.
 	// If the string is longer than 13 characters, it will be truncated to 13 characters.
-	formattedMsg := fmt.Sprintf("\r%13.13s:  %s", cc.UserName, t.GetField(hotline.FieldData).Data)
+	formattedMsg := fmt.Sprintf("\r%13.13s:  %s", cc.UserName, msg)
 
 	// By holding the option key, Hotline chat allows users to send /me formatted messages like:
 	// *** Halcyon does stuff
 	// This is indicated by the presence of the optional field FieldChatOptions set to a value of 1.
 	// Most clients do not send this option for normal chat messages.
-	if t.GetField(hotline.FieldChatOptions).Data != nil && bytes.Equal(t.GetField(hotline.FieldChatOptions).Data, []byte{0, 1}) {
-		formattedMsg = fmt.Sprintf("\r*** %s %s", cc.UserName, t.GetField(hotline.FieldData).Data)
+	emote := t.GetField(hotline.FieldChatOptions).Data != nil && bytes.Equal(t.GetField(hotline.FieldChatOptions).Data, []byte{0, 1})
+	if emote {
+		formattedMsg = fmt.Sprintf("\r*** %s %s", cc.UserName, msg)
 	}
 
-	// Truncate the message to the limit.  This does not handle the edge case of a string ending on multibyte character.
-	formattedMsg = formattedMsg[:min(len(formattedMsg), hotline.LimitChatMsg)]
+	truncated, tooLong := hotline.TruncateText([]byte(formattedMsg), cc.Server.MaxChatMsgLen())
+	formattedMsg = string(truncated)
+
+	// Let the sender know that others didn't see their whole message.
+	if clipped || tooLong {
+		res = append(res, hotline.NewTransaction(
+			hotline.TranServerMsg,
+			cc.ID,
+			hotline.NewField(hotline.FieldData, []byte(cc.T("Your chat message was too long and has been shortened."))),
+			hotline.NewField(hotline.FieldChatOptions, []byte{0, 0}),
+		))
+	}
 
 	// The ChatID field is used to identify messages as belonging to a private chat.
 	// All clients *except* Frogblast omit this field for public chat, but Frogblast sends a value of 00 00 00 00.
-	chatID := t.GetField(hotline.FieldChatID).Data
-	if chatID != nil && !bytes.Equal([]byte{0, 0, 0, 0}, chatID) {
+	if data := t.GetField(hotline.FieldChatID).Data; data != nil && !bytes.Equal([]byte{0, 0, 0, 0}, data) {
+		chatID, err := t.GetChatID()
+		if err != nil {
+			logInvalidRequest(cc, t, err)
+			return nil
+		}
 
 		// send the message to all connected clients of the private chat
-		for _, c := range cc.Server.ChatMgr.Members([4]byte(chatID)) {
+		for _, c := range cc.Server.ChatMgr.Members(chatID) {
+			if c.Ignores(cc) {
+				continue
+			}
 			res = append(res, hotline.NewTransaction(
 				hotline.TranChatMsg,
 				c.ID,
-				hotline.NewField(hotline.FieldChatID, chatID),
+				hotline.NewField(hotline.FieldChatID, chatID[:]),
 				hotline.NewField(hotline.FieldData, []byte(formattedMsg)),
 			))
 		}
 		return res
 	}
 
+	cc.Server.Digest.RecordChat(string(cc.UserName))
+	cc.Server.ChatFeed.Chat(cc.UserName, msg, emote)
+	cc.Server.RecordChat([]byte(formattedMsg))
+
 	//cc.Server.mux.Lock()
 	for _, c := range cc.Server.ClientMgr.List() {
 		if c == nil || cc.Account == nil {
 			continue
 		}
-		// Skip clients that do not have the read chat permission.
-		if c.Authorize(hotline.AccessReadChat) {
+		// Skip clients that do not have the read chat permission or that ignore the sender.
+		if c.Authorize(hotline.AccessReadChat) && !c.Ignores(cc) {
 			res = append(res, hotline.NewTransaction(hotline.TranChatMsg, c.ID, hotline.NewField(hotline.FieldData, []byte(formattedMsg))))
 		}
 	}
@@ -138,16 +219,15 @@ func HandleChatSend(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotli
 // Fields used in the reply:
 // None
 func HandleSendInstantMsg(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessSendPrivMsg) {
-		return cc.NewErrReply(t, "You are not allowed to send private messages.")
-	}
-
 	msg := t.GetField(hotline.FieldData)
-	userID := t.GetField(hotline.FieldUserID)
+	userID, err := t.GetClientID(hotline.FieldUserID)
+	if err != nil {
+		return invalidRequest(cc, t, err)
+	}
 
 	reply := hotline.NewTransaction(
 		hotline.TranServerMsg,
-		[2]byte(userID.Data),
+		userID,
 		hotline.NewField(hotline.FieldData, msg.Data),
 		hotline.NewField(hotline.FieldUserName, cc.UserName),
 		hotline.NewField(hotline.FieldUserID, cc.ID[:]),
@@ -160,11 +240,16 @@ func HandleSendInstantMsg(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 		reply.Fields = append(reply.Fields, hotline.NewField(hotline.FieldQuotingMsg, t.GetField(hotline.FieldQuotingMsg).Data))
 	}
 
-	otherClient := cc.Server.ClientMgr.Get([2]byte(userID.Data))
+	otherClient := cc.Server.ClientMgr.Get(userID)
 	if otherClient == nil {
 		return res
 	}
 
+	// Messages from ignored users are dropped without telling the sender.
+	if otherClient.Ignores(cc) {
+		return append(res, cc.NewReply(t))
+	}
+
 	// Check if target user has "Refuse private messages" flag
 	if otherClient.Flags.IsSet(hotline.UserFlagRefusePM) {
 		res = append(res,
@@ -204,9 +289,9 @@ func HandleGetFileInfo(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 	fileName := t.GetField(hotline.FieldFileName).Data
 	filePath := t.GetField(hotline.FieldFilePath).Data
 
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), filePath, fileName)
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	fw, err := hotline.NewFileWrapper(cc.Server.FS, fullFilePath, 0)
@@ -214,32 +299,33 @@ func HandleGetFileInfo(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 		return res
 	}
 
-	encodedName, err := txtEncoder.String(fw.Name)
-	if err != nil {
-		return res
-	}
+	info := fw.Ffo.FlatFileInformationFork
+	reply := cc.Reply(t).
+		WithFileName(hotline.ClientFileName(fw.Name)).
+		WithBytes(hotline.FieldFileTypeString, info.FriendlyType()).
+		WithBytes(hotline.FieldFileCreatorString, info.FriendlyCreator()).
+		WithBytes(hotline.FieldFileType, info.TypeSignature[:]).
+		WithTime(hotline.FieldFileCreateDate, hotline.Time(info.CreateDate)).
+		WithTime(hotline.FieldFileModifyDate, hotline.Time(info.ModifyDate)).
+		WithFileComment(info.Comment)
 
-	fields := []hotline.Field{
-		hotline.NewField(hotline.FieldFileName, []byte(encodedName)),
-		hotline.NewField(hotline.FieldFileTypeString, fw.Ffo.FlatFileInformationFork.FriendlyType()),
-		hotline.NewField(hotline.FieldFileCreatorString, fw.Ffo.FlatFileInformationFork.FriendlyCreator()),
-		hotline.NewField(hotline.FieldFileType, fw.Ffo.FlatFileInformationFork.TypeSignature[:]),
-		hotline.NewField(hotline.FieldFileCreateDate, fw.Ffo.FlatFileInformationFork.CreateDate[:]),
-		hotline.NewField(hotline.FieldFileModifyDate, fw.Ffo.FlatFileInformationFork.ModifyDate[:]),
+	// Include the FileSize field for files.
+	if info.TypeSignature != fileTypeFLDR {
+		reply.WithFileSize(binary.BigEndian.Uint32(fw.TotalSize()))
 	}
 
-	// Include the optional FileComment field if there is a comment.
-	if len(fw.Ffo.FlatFileInformationFork.Comment) != 0 {
-		fields = append(fields, hotline.NewField(hotline.FieldFileComment, fw.Ffo.FlatFileInformationFork.Comment))
+	// Include the uploader and upload date for files uploaded since Mobius started recording them.
+	meta, err := hotline.ReadFileMeta(cc.Server.FS, fullFilePath)
+	if err != nil {
+		cc.Logger.Error("Error reading file metadata", "path", fullFilePath, "err", err)
 	}
-
-	// Include the FileSize field for files.
-	if fw.Ffo.FlatFileInformationFork.TypeSignature != fileTypeFLDR {
-		fields = append(fields, hotline.NewField(hotline.FieldFileSize, fw.TotalSize()))
+	if meta != nil {
+		reply.
+			WithString(hotline.FieldFileUploader, meta.Uploader).
+			WithTime(hotline.FieldFileUploadDate, hotline.NewTime(meta.UploadDate))
 	}
 
-	res = append(res, cc.NewReply(t, fields...))
-	return res
+	return reply.Transactions()
 }
 
 // HandleSetFileInfo updates a file or folder Name and/or comment from the Get Info window
@@ -253,9 +339,9 @@ func HandleSetFileInfo(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 	fileName := t.GetField(hotline.FieldFileName).Data
 	filePath := t.GetField(hotline.FieldFilePath).Data
 
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), filePath, fileName)
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	fi, err := cc.Server.FS.Stat(fullFilePath)
@@ -296,36 +382,45 @@ func HandleSetFileInfo(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 	if err != nil {
 		return nil
 	}
+	// Keep the renamed file in the same folder as the original, whose path may have been resolved regardless of case.
+	fullNewFilePath = filepath.Join(filepath.Dir(fullFilePath), filepath.Base(fullNewFilePath))
 
 	fileNewName := t.GetField(hotline.FieldFileNewName).Data
 
+	if fileNewName != nil && hotline.ReservedFileName(string(fileNewName)) {
+		return cc.NewErrReplyf(t, "Cannot rename %s to \"%v\" because the name is reserved.  Try choosing a different Name.", fileName, string(fileNewName))
+	}
+
 	if fileNewName != nil {
 		switch mode := fi.Mode(); {
 		case mode.IsDir():
 			if !cc.Authorize(hotline.AccessRenameFolder) {
 				return cc.NewErrReply(t, "You are not allowed to rename folders.")
 			}
-			err = os.Rename(fullFilePath, fullNewFilePath)
+			err = cc.Server.FS.Rename(fullFilePath, fullNewFilePath)
+			cc.Server.FileIndex.Invalidate(fullFilePath)
+			cc.Server.FileIndex.Invalidate(fullNewFilePath)
 			if os.IsNotExist(err) {
-				return cc.NewErrReply(t, "Cannot rename folder "+string(fileName)+" because it does not exist or cannot be found.")
+				return cc.NewErrReplyf(t, "Cannot rename folder %s because it does not exist or cannot be found.", fileName)
 
 			}
 		case mode.IsRegular():
 			if !cc.Authorize(hotline.AccessRenameFile) {
 				return cc.NewErrReply(t, "You are not allowed to rename files.")
 			}
-			fileDir, err := hotline.ReadPath(cc.FileRoot(), filePath, []byte{})
+			fileDir, err := cc.ReadPath(filePath, []byte{})
 			if err != nil {
 				return nil
 			}
 			hlFile.Name, err = txtDecoder.String(string(fileNewName))
 			if err != nil {
-				return res
+				return cc.NewMalformedReply(t, err)
 			}
 
 			err = hlFile.Move(fileDir)
+			cc.Server.FileIndex.Invalidate(fileDir)
 			if os.IsNotExist(err) {
-				return cc.NewErrReply(t, "Cannot rename file "+string(fileName)+" because it does not exist or cannot be found.")
+				return cc.NewErrReplyf(t, "Cannot rename file %s because it does not exist or cannot be found.", fileName)
 			}
 			if err != nil {
 				return res
@@ -346,33 +441,15 @@ func HandleDeleteFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 	fileName := t.GetField(hotline.FieldFileName).Data
 	filePath := t.GetField(hotline.FieldFilePath).Data
 
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), filePath, fileName)
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
-	hlFile, err := hotline.NewFileWrapper(cc.Server.FS, fullFilePath, 0)
-	if err != nil {
-		return res
-	}
-
-	fi, err := hlFile.DataFile()
-	if err != nil {
-		return cc.NewErrReply(t, "Cannot delete file "+string(fileName)+" because it does not exist or cannot be found.")
-	}
-
-	switch mode := fi.Mode(); {
-	case mode.IsDir():
-		if !cc.Authorize(hotline.AccessDeleteFolder) {
-			return cc.NewErrReply(t, "You are not allowed to delete folders.")
+	if err := fileService(cc).Delete(cc, fullFilePath, string(fileName)); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
 		}
-	case mode.IsRegular():
-		if !cc.Authorize(hotline.AccessDeleteFile) {
-			return cc.NewErrReply(t, "You are not allowed to delete files.")
-		}
-	}
-
-	if err := hlFile.Delete(); err != nil {
 		return res
 	}
 
@@ -384,38 +461,22 @@ func HandleDeleteFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 func HandleMoveFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
 	fileName := string(t.GetField(hotline.FieldFileName).Data)
 
-	filePath, err := hotline.ReadPath(cc.FileRoot(), t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
+	filePath, err := cc.ReadPath(t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
-	fileNewPath, err := hotline.ReadPath(cc.FileRoot(), t.GetField(hotline.FieldFileNewPath).Data, nil)
+	fileNewPath, err := cc.ReadPath(t.GetField(hotline.FieldFileNewPath).Data, nil)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	cc.Logger.Info("Move file", "src", filePath+"/"+fileName, "dst", fileNewPath+"/"+fileName)
 
-	hlFile, err := hotline.NewFileWrapper(cc.Server.FS, filePath, 0)
-	if err != nil {
-		return res
-	}
-
-	fi, err := hlFile.DataFile()
-	if err != nil {
-		return cc.NewErrReply(t, "Cannot delete file "+fileName+" because it does not exist or cannot be found.")
-	}
-	switch mode := fi.Mode(); {
-	case mode.IsDir():
-		if !cc.Authorize(hotline.AccessMoveFolder) {
-			return cc.NewErrReply(t, "You are not allowed to move folders.")
+	if err := fileService(cc).Move(cc, filePath, fileNewPath, fileName); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
 		}
-	case mode.IsRegular():
-		if !cc.Authorize(hotline.AccessMoveFile) {
-			return cc.NewErrReply(t, "You are not allowed to move files.")
-		}
-	}
-	if err := hlFile.Move(fileNewPath); err != nil {
 		return res
 	}
 	// TODO: handle other possible errors; e.g. file delete fails due to permission issue
@@ -425,53 +486,33 @@ func HandleMoveFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotli
 }
 
 func HandleNewFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessCreateFolder) {
-		return cc.NewErrReply(t, "You are not allowed to create folders.")
-	}
 	folderName := string(t.GetField(hotline.FieldFileName).Data)
 
-	folderName = path.Join("/", folderName)
+	if hotline.ReservedFileName(folderName) {
+		return cc.NewErrReplyf(t, "Cannot create folder \"%v\" because the name is reserved.  Try choosing a different Name.", folderName)
+	}
 
-	var subPath string
+	folderName = path.Join("/", folderName)
 
 	// FieldFilePath is only present for nested paths
-	if t.GetField(hotline.FieldFilePath).Data != nil {
-		var newFp hotline.FilePath
-		_, err := newFp.Write(t.GetField(hotline.FieldFilePath).Data)
-		if err != nil {
-			return res
-		}
-
-		for _, pathItem := range newFp.Items {
-			subPath = filepath.Join("/", subPath, string(pathItem.Name))
-		}
-	}
-	newFolderPath := path.Join(cc.FileRoot(), subPath, folderName)
-	newFolderPath, err := txtDecoder.String(newFolderPath)
+	newFolderPath, err := cc.ReadPath(t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	// TODO: check path and folder Name lengths
 
-	if _, err := cc.Server.FS.Stat(newFolderPath); !os.IsNotExist(err) {
-		msg := fmt.Sprintf("Cannot create folder \"%s\" because there is already a file or folder with that Name.", folderName)
-		return cc.NewErrReply(t, msg)
-	}
-
-	if err := cc.Server.FS.Mkdir(newFolderPath, 0777); err != nil {
-		msg := fmt.Sprintf("Cannot create folder \"%s\" because an error occurred.", folderName)
-		return cc.NewErrReply(t, msg)
+	if err := fileService(cc).NewFolder(cc, newFolderPath, folderName); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
+		}
+		return res
 	}
 
 	return append(res, cc.NewReply(t))
 }
 
 func HandleSetUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessModifyUser) {
-		return cc.NewErrReply(t, "You are not allowed to modify accounts.")
-	}
-
 	login := t.GetField(hotline.FieldUserLogin).DecodeObfuscatedString()
 	userName := string(t.GetField(hotline.FieldUserName).Data)
 
@@ -481,17 +522,27 @@ func HandleSetUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotlin
 	if account == nil {
 		return cc.NewErrReply(t, "Account not found.")
 	}
+	if !cc.CanManageAccount(account) {
+		return cc.NewErrReply(t, "Cannot modify account with more access than yourself.")
+	}
+
+	var newAccess hotline.AccessBitmap
+	copy(newAccess[:], newAccessLvl)
+	if !cc.CanManageAccount(&hotline.Account{Access: newAccess}) {
+		return cc.NewErrReply(t, "Cannot grant more access than you have yourself.")
+	}
+
 	account.Name = userName
-	copy(account.Access[:], newAccessLvl)
+	account.Access = newAccess
 
 	// If the password field is cleared in the Hotline edit user UI, the SetUser transaction does
 	// not include FieldUserPassword
 	if t.GetField(hotline.FieldUserPassword).Data == nil {
-		account.Password = hotline.HashAndSalt([]byte(""))
+		account.Password = cc.Server.HashPassword([]byte(""))
 	}
 
 	if !bytes.Equal([]byte{0}, t.GetField(hotline.FieldUserPassword).Data) {
-		account.Password = hotline.HashAndSalt(t.GetField(hotline.FieldUserPassword).Data)
+		account.Password = cc.Server.HashPassword(t.GetField(hotline.FieldUserPassword).Data)
 	}
 
 	err := cc.Server.AccountManager.Update(*account, account.Login)
@@ -499,41 +550,17 @@ func HandleSetUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotlin
 		cc.Logger.Error("Error updating account", "Err", err)
 	}
 
-	// Notify connected clients logged in as the user of the new access level
-	for _, c := range cc.Server.ClientMgr.List() {
-		if c.Account.Login == login {
-			newT := hotline.NewTransaction(hotline.TranUserAccess, c.ID, hotline.NewField(hotline.FieldUserAccess, newAccessLvl))
-			res = append(res, newT)
-
-			if c.Authorize(hotline.AccessDisconUser) {
-				c.Flags.Set(hotline.UserFlagAdmin, 1)
-			} else {
-				c.Flags.Set(hotline.UserFlagAdmin, 0)
-			}
-
-			c.Account.Access = account.Access
-
-			cc.SendAll(
-				hotline.TranNotifyChangeUser,
-				hotline.NewField(hotline.FieldUserID, c.ID[:]),
-				hotline.NewField(hotline.FieldUserFlags, c.Flags[:]),
-				hotline.NewField(hotline.FieldUserName, c.UserName),
-				hotline.NewField(hotline.FieldUserIconID, c.Icon),
-			)
-		}
-	}
+	// Update the sessions of connected clients logged in as the user with the new access level and name
+	cc.Server.AccountChanged(account.Login)
 
 	return append(res, cc.NewReply(t))
 }
 
 func HandleGetUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessOpenUser) {
-		return cc.NewErrReply(t, "You are not allowed to view accounts.")
-	}
-
-	account := cc.Server.AccountManager.Get(string(t.GetField(hotline.FieldUserLogin).Data))
-	if account == nil {
-		return cc.NewErrReply(t, "Account does not exist.")
+	account, err := accountService(cc).Get(cc, string(t.GetField(hotline.FieldUserLogin).Data))
+	if err != nil {
+		reply, _ := userErrReply(cc, t, err)
+		return reply
 	}
 
 	return append(res, cc.NewReply(t,
@@ -545,12 +572,14 @@ func HandleGetUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotlin
 }
 
 func HandleListUsers(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessOpenUser) {
-		return cc.NewErrReply(t, "You are not allowed to view accounts.")
+	accounts, err := accountService(cc).List(cc)
+	if err != nil {
+		reply, _ := userErrReply(cc, t, err)
+		return reply
 	}
 
 	var userFields []hotline.Field
-	for _, acc := range cc.Server.AccountManager.List() {
+	for _, acc := range accounts {
 		b, err := io.ReadAll(&acc)
 		if err != nil {
 			cc.Logger.Error("Error reading account", "Account", acc.Login, "Err", err)
@@ -573,29 +602,46 @@ func HandleListUsers(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotl
 // contains another data field encoded in its payload with a varying number of sub fields depending on which action is
 // performed.  This seems to be the only place in the Hotline protocol where a data field contains another data field.
 func HandleUpdateUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	// Deletes are authorized up front, so that users who can't delete the accounts aren't asked to confirm first.
+	var deletes int
 	for _, field := range t.Fields {
-		var subFields []hotline.Field
+		subFields, err := hotline.DecodeFieldList(field.Data)
+		if err != nil {
+			return cc.NewMalformedReply(t, err)
+		}
 
-		// Create a new scanner for parsing incoming bytes into transaction tokens
-		scanner := bufio.NewScanner(bytes.NewReader(field.Data[2:]))
-		scanner.Split(hotline.FieldScanner)
+		// If there's only one subfield, that indicates this is a delete operation for the login in FieldData
+		if len(subFields) != 1 {
+			continue
+		}
 
-		for i := 0; i < int(binary.BigEndian.Uint16(field.Data[0:2])); i++ {
-			scanner.Scan()
+		if !cc.Authorize(hotline.AccessDeleteUser) {
+			return cc.NewErrReply(t, "You are not allowed to delete accounts.")
+		}
 
-			var field hotline.Field
-			if _, err := field.Write(scanner.Bytes()); err != nil {
-				return res
-			}
-			subFields = append(subFields, field)
+		login := string(hotline.EncodeString(hotline.GetField(hotline.FieldData, &subFields).Data))
+		if acc := cc.Server.AccountManager.Get(login); acc != nil && !cc.CanManageAccount(acc) {
+			return cc.NewErrReply(t, "Cannot delete account with more access than yourself.")
 		}
 
-		// If there's only one subfield, that indicates this is a delete operation for the login in FieldData
-		if len(subFields) == 1 {
-			if !cc.Authorize(hotline.AccessDeleteUser) {
-				return cc.NewErrReply(t, "You are not allowed to delete accounts.")
-			}
+		deletes++
+	}
+
+	// Deleting many accounts at once from the multi-user editor is easy to do by accident, so require confirmation.
+	if threshold := cc.Server.Config.ConfirmBatchDeletes; threshold > 0 && deletes >= threshold {
+		prompt := fmt.Sprintf(cc.T("This will delete %d accounts.  Send the request again to confirm."), deletes)
+		if challenge, ok := cc.Confirm(t, prompt); !ok {
+			return challenge
+		}
+	}
+
+	for _, field := range t.Fields {
+		subFields, err := hotline.DecodeFieldList(field.Data)
+		if err != nil {
+			return cc.NewMalformedReply(t, err)
+		}
 
+		if len(subFields) == 1 {
 			login := string(hotline.EncodeString(hotline.GetField(hotline.FieldData, &subFields).Data))
 
 			cc.Logger.Info("DeleteUser", "login", login)
@@ -654,6 +700,10 @@ func HandleUpdateUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 				return cc.NewErrReply(t, "You are not allowed to modify accounts.")
 			}
 
+			if !cc.CanManageAccount(acc) {
+				return cc.NewErrReply(t, "Cannot modify account with more access than yourself.")
+			}
+
 			// This part is a bit tricky. There are three possibilities:
 			// 1) The transaction is intended to update the password.
 			//	  In this case, FieldUserPassword is sent with the new password.
@@ -664,14 +714,19 @@ func HandleUpdateUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 			if hotline.GetField(hotline.FieldUserPassword, &subFields) != nil {
 				newPass := hotline.GetField(hotline.FieldUserPassword, &subFields).Data
 				if !bytes.Equal([]byte{0}, newPass) {
-					acc.Password = hotline.HashAndSalt(newPass)
+					acc.Password = cc.Server.HashPassword(newPass)
 				}
 			} else {
-				acc.Password = hotline.HashAndSalt([]byte(""))
+				acc.Password = cc.Server.HashPassword([]byte(""))
 			}
 
 			if hotline.GetField(hotline.FieldUserAccess, &subFields) != nil {
-				copy(acc.Access[:], hotline.GetField(hotline.FieldUserAccess, &subFields).Data)
+				var newAccess hotline.AccessBitmap
+				copy(newAccess[:], hotline.GetField(hotline.FieldUserAccess, &subFields).Data)
+				if !cc.CanManageAccount(&hotline.Account{Access: newAccess}) {
+					return cc.NewErrReply(t, "Cannot grant more access than you have yourself.")
+				}
+				acc.Access = newAccess
 			}
 
 			acc.Name = string(hotline.GetField(hotline.FieldUserName, &subFields).Data)
@@ -681,6 +736,11 @@ func HandleUpdateUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 			if err != nil {
 				return res
 			}
+
+			if loginToRename != "" {
+				cc.Server.AccountRenamed(loginToRename, userLogin)
+			}
+			cc.Server.AccountChanged(userLogin)
 		} else {
 			if !cc.Authorize(hotline.AccessCreateUser) {
 				return cc.NewErrReply(t, "You are not allowed to create new accounts.")
@@ -692,25 +752,22 @@ func HandleUpdateUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 			copy(newAccess[:], hotline.GetField(hotline.FieldUserAccess, &subFields).Data)
 
 			// Prevent account from creating new account with greater permission
-			for i := 0; i < 64; i++ {
-				if newAccess.IsSet(i) {
-					if !cc.Authorize(i) {
-						return cc.NewErrReply(t, "Cannot create account with more access than yourself.")
-					}
-				}
+			if !cc.CanManageAccount(&hotline.Account{Access: newAccess}) {
+				return cc.NewErrReply(t, "Cannot create account with more access than yourself.")
 			}
 
-			account := hotline.NewAccount(
-				userLogin,
-				string(hotline.GetField(hotline.FieldUserName, &subFields).Data),
-				string(hotline.GetField(hotline.FieldUserPassword, &subFields).Data),
-				newAccess,
-			)
+			account := &hotline.Account{
+				Login:    userLogin,
+				Name:     string(hotline.GetField(hotline.FieldUserName, &subFields).Data),
+				Password: cc.Server.HashPassword(hotline.GetField(hotline.FieldUserPassword, &subFields).Data),
+				Access:   newAccess,
+			}
 
 			err := cc.Server.AccountManager.Create(*account)
 			if err != nil {
 				return cc.NewErrReply(t, "Cannot create account because there is already an account with that login.")
 			}
+			cc.Server.Digest.RecordNewAccount()
 		}
 	}
 
@@ -719,47 +776,30 @@ func HandleUpdateUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 
 // HandleNewUser creates a new user account
 func HandleNewUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessCreateUser) {
-		return cc.NewErrReply(t, "You are not allowed to create new accounts.")
-	}
-
-	login := t.GetField(hotline.FieldUserLogin).DecodeObfuscatedString()
-
-	// If the account already exists, reply with an error.
-	if account := cc.Server.AccountManager.Get(login); account != nil {
-		return cc.NewErrReply(t, "Cannot create account "+login+" because there is already an account with that login.")
-	}
-
 	var newAccess hotline.AccessBitmap
 	copy(newAccess[:], t.GetField(hotline.FieldUserAccess).Data)
 
-	// Prevent account from creating new account with greater permission
-	for i := 0; i < 64; i++ {
-		if newAccess.IsSet(i) {
-			if !cc.Authorize(i) {
-				return cc.NewErrReply(t, "Cannot create account with more access than yourself.")
-			}
-		}
-	}
-
-	account := hotline.NewAccount(login, string(t.GetField(hotline.FieldUserName).Data), string(t.GetField(hotline.FieldUserPassword).Data), newAccess)
-
-	err := cc.Server.AccountManager.Create(*account)
+	err := accountService(cc).Create(cc,
+		t.GetField(hotline.FieldUserLogin).DecodeObfuscatedString(),
+		string(t.GetField(hotline.FieldUserName).Data),
+		string(t.GetField(hotline.FieldUserPassword).Data),
+		newAccess,
+	)
 	if err != nil {
-		return cc.NewErrReply(t, "Cannot create account because there is already an account with that login.")
+		reply, _ := userErrReply(cc, t, err)
+		return reply
 	}
 
 	return append(res, cc.NewReply(t))
 }
 
 func HandleDeleteUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessDeleteUser) {
-		return cc.NewErrReply(t, "You are not allowed to delete accounts.")
-	}
-
 	login := t.GetField(hotline.FieldUserLogin).DecodeObfuscatedString()
 
-	if err := cc.Server.AccountManager.Delete(login); err != nil {
+	if err := accountService(cc).Delete(cc, login); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
+		}
 		cc.Logger.Error("Error deleting account", "Err", err)
 		return res
 	}
@@ -785,8 +825,9 @@ func HandleDeleteUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 
 // HandleUserBroadcast sends an Administrator Message to all connected clients of the server
 func HandleUserBroadcast(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessBroadcast) {
-		return cc.NewErrReply(t, "You are not allowed to send broadcast messages.")
+	msg, _ := txtDecoder.String(string(t.GetField(hotline.FieldData).Data))
+	if err := cc.Server.RecordBroadcast(hotline.Broadcast{Message: msg, Sender: cc.Account.Login, Date: time.Now()}); err != nil {
+		cc.Logger.Error("Error saving broadcast history", "err", err)
 	}
 
 	cc.SendAll(
@@ -807,13 +848,12 @@ func HandleUserBroadcast(cc *hotline.ClientConn, t *hotline.Transaction) (res []
 // 102	User Name
 // 101	Data		User info text string
 func HandleGetClientInfoText(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessGetClientInfo) {
-		return cc.NewErrReply(t, "You are not allowed to get client info.")
+	clientID, err := t.GetClientID(hotline.FieldUserID)
+	if err != nil {
+		return invalidRequest(cc, t, err)
 	}
 
-	clientID := t.GetField(hotline.FieldUserID).Data
-
-	clientConn := cc.Server.ClientMgr.Get(hotline.ClientID(clientID))
+	clientConn := cc.Server.ClientMgr.Get(clientID)
 	if clientConn == nil {
 		return cc.NewErrReply(t, "User not found.")
 	}
@@ -838,6 +878,7 @@ func HandleGetUserNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res
 		}
 
 		fields = append(fields, hotline.NewField(hotline.FieldUsernameWithInfo, b))
+		fields = append(fields, cc.Server.UserGroupFields(cc, c)...)
 	}
 
 	return []hotline.Transaction{cc.NewReply(t, fields...)}
@@ -846,7 +887,7 @@ func HandleGetUserNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res
 func HandleTranAgreed(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
 	if t.GetField(hotline.FieldUserName).Data != nil {
 		if cc.Authorize(hotline.AccessAnyName) {
-			cc.UserName = t.GetField(hotline.FieldUserName).Data
+			cc.UserName = cc.Server.LimitUserName(t.GetField(hotline.FieldUserName).Data)
 		} else {
 			cc.UserName = []byte(cc.Account.Name)
 		}
@@ -874,21 +915,20 @@ func HandleTranAgreed(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 		cc.AutoReply = t.GetField(hotline.FieldAutomaticResponse).Data
 	}
 
-	trans := cc.NotifyOthers(
-		hotline.NewTransaction(
-			hotline.TranNotifyChangeUser, [2]byte{0, 0},
-			hotline.NewField(hotline.FieldUserName, cc.UserName),
-			hotline.NewField(hotline.FieldUserID, cc.ID[:]),
-			hotline.NewField(hotline.FieldUserIconID, cc.Icon),
-			hotline.NewField(hotline.FieldUserFlags, cc.Flags[:]),
-		),
-	)
-	res = append(res, trans...)
+	cc.ApplyPreferences()
+
+	res = append(res, cc.NotifyOthersChangeUser()...)
+	cc.Server.ChatFeed.Join(cc.UserName)
 
 	if cc.Server.Config.BannerFile != "" {
 		res = append(res, hotline.NewTransaction(hotline.TranServerBanner, cc.ID, hotline.NewField(hotline.FieldBannerType, []byte("JPEG"))))
 	}
 
+	res = append(res, cc.MOTD()...)
+	res = append(res, cc.MissedBroadcasts()...)
+	res = append(res, cc.ReplayChatHistory()...)
+	res = append(res, cc.RedeliverChatInvites()...)
+
 	res = append(res, cc.NewReply(t))
 
 	return res
@@ -898,10 +938,6 @@ func HandleTranAgreed(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 // Fields used in this request:
 // 101	Data
 func HandleTranOldPostNews(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsPostArt) {
-		return cc.NewErrReply(t, "You are not allowed to post news.")
-	}
-
 	newsDateTemplate := hotline.NewsDateFormat
 	if cc.Server.Config.NewsDateFormat != "" {
 		newsDateTemplate = cc.Server.Config.NewsDateFormat
@@ -931,22 +967,30 @@ func HandleTranOldPostNews(cc *hotline.ClientConn, t *hotline.Transaction) (res
 }
 
 func HandleDisconnectUser(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessDisconUser) {
-		return cc.NewErrReply(t, "You are not allowed to disconnect users.")
+	clientID, err := t.GetClientID(hotline.FieldUserID)
+	if err != nil {
+		return invalidRequest(cc, t, err)
 	}
 
-	clientID := [2]byte(t.GetField(hotline.FieldUserID).Data)
 	clientConn := cc.Server.ClientMgr.Get(clientID)
+	if clientConn == nil {
+		return cc.NewErrReply(t, "User not found.")
+	}
 
 	if clientConn.Authorize(hotline.AccessCannotBeDiscon) {
-		return cc.NewErrReply(t, clientConn.Account.Login+" is not allowed to be disconnected.")
+		return cc.NewErrReplyf(t, "%s is not allowed to be disconnected.", clientConn.Account.Login)
 	}
 
 	// If FieldOptions is set, then the client IP is banned in addition to disconnected.
 	// 00 01 = temporary ban
 	// 00 02 = permanent ban
 	if t.GetField(hotline.FieldOptions).Data != nil {
-		switch t.GetField(hotline.FieldOptions).Data[1] {
+		banType, err := t.GetUint16(hotline.FieldOptions)
+		if err != nil {
+			return invalidRequest(cc, t, err)
+		}
+
+		switch banType {
 		case 1:
 			// send message: "You are temporarily banned on this server"
 			cc.Logger.Info("Disconnect & temporarily ban " + string(clientConn.UserName))
@@ -954,14 +998,14 @@ func HandleDisconnectUser(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 			res = append(res, hotline.NewTransaction(
 				hotline.TranServerMsg,
 				clientConn.ID,
-				hotline.NewField(hotline.FieldData, []byte("You are temporarily banned on this server")),
+				hotline.NewField(hotline.FieldData, []byte(cc.T("You are temporarily banned on this server"))),
 				hotline.NewField(hotline.FieldChatOptions, []byte{0, 0}),
 			))
 
 			banUntil := time.Now().Add(hotline.BanDuration)
-			ip := strings.Split(clientConn.RemoteAddr, ":")[0]
+			ip := hotline.AddrIP(clientConn.RemoteAddr)
 
-			err := cc.Server.BanList.Add(ip, &banUntil)
+			err := cc.Server.BanList.Add(ip, hotline.Ban{Until: &banUntil})
 			if err != nil {
 				cc.Logger.Error("Error saving ban", "err", err)
 				// TODO
@@ -973,13 +1017,13 @@ func HandleDisconnectUser(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 			res = append(res, hotline.NewTransaction(
 				hotline.TranServerMsg,
 				clientConn.ID,
-				hotline.NewField(hotline.FieldData, []byte("You are permanently banned on this server")),
+				hotline.NewField(hotline.FieldData, []byte(cc.T("You are permanently banned on this server"))),
 				hotline.NewField(hotline.FieldChatOptions, []byte{0, 0}),
 			))
 
-			ip := strings.Split(clientConn.RemoteAddr, ":")[0]
+			ip := hotline.AddrIP(clientConn.RemoteAddr)
 
-			err := cc.Server.BanList.Add(ip, nil)
+			err := cc.Server.BanList.Add(ip, hotline.Ban{})
 			if err != nil {
 				cc.Logger.Error("Error saving ban", "err", err)
 			}
@@ -998,18 +1042,20 @@ func HandleDisconnectUser(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 // Fields used in the request:
 // 325	News path	(Optional)
 func HandleGetNewsCatNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsReadArt) {
-		return cc.NewErrReply(t, "You are not allowed to read news.")
-	}
-
 	pathStrs, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
 	if err != nil {
 		cc.Logger.Error("get news path", "err", err)
 		return nil
 	}
 
+	cats, err := newsService(cc).Categories(cc, pathStrs)
+	if err != nil {
+		reply, _ := userErrReply(cc, t, err)
+		return reply
+	}
+
 	var fields []hotline.Field
-	for _, cat := range cc.Server.ThreadedNewsMgr.GetCategories(pathStrs) {
+	for _, cat := range cats {
 		b, err := io.ReadAll(&cat)
 		if err != nil {
 			cc.Logger.Error("get news categories", "err", err)
@@ -1022,18 +1068,16 @@ func HandleGetNewsCatNameList(cc *hotline.ClientConn, t *hotline.Transaction) (r
 }
 
 func HandleNewNewsCat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsCreateCat) {
-		return cc.NewErrReply(t, "You are not allowed to create news categories.")
-	}
-
 	name := string(t.GetField(hotline.FieldNewsCatName).Data)
 	pathStrs, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
 	if err != nil {
-		return res
+		return cc.NewMalformedReply(t, err)
 	}
 
-	err = cc.Server.ThreadedNewsMgr.CreateGrouping(pathStrs, name, hotline.NewsCategory)
-	if err != nil {
+	if err := newsService(cc).CreateCategory(cc, pathStrs, name); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
+		}
 		cc.Logger.Error("error creating news category", "err", err)
 	}
 
@@ -1044,18 +1088,16 @@ func HandleNewNewsCat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 // 322	News category Name
 // 325	News path
 func HandleNewNewsFldr(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsCreateFldr) {
-		return cc.NewErrReply(t, "You are not allowed to create news folders.")
-	}
-
 	name := string(t.GetField(hotline.FieldFileName).Data)
 	pathStrs, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
 	if err != nil {
-		return res
+		return cc.NewMalformedReply(t, err)
 	}
 
-	err = cc.Server.ThreadedNewsMgr.CreateGrouping(pathStrs, name, hotline.NewsBundle)
-	if err != nil {
+	if err := newsService(cc).CreateBundle(cc, pathStrs, name); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
+		}
 		cc.Logger.Error("error creating news bundle", "err", err)
 	}
 
@@ -1070,16 +1112,16 @@ func HandleNewNewsFldr(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 // Fields used in the reply:
 // 321	News article list data	Optional
 func HandleGetNewsArtNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsReadArt) {
-		return cc.NewErrReply(t, "You are not allowed to read news.")
-	}
-
 	pathStrs, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
 	if err != nil {
-		return res
+		return cc.NewMalformedReply(t, err)
 	}
 
-	nald := cc.Server.ThreadedNewsMgr.ListArticles(pathStrs)
+	nald, err := newsService(cc).Articles(cc, pathStrs)
+	if err != nil {
+		reply, _ := userErrReply(cc, t, err)
+		return reply
+	}
 
 	b, err := io.ReadAll(&nald)
 	if err != nil {
@@ -1105,40 +1147,53 @@ func HandleGetNewsArtNameList(cc *hotline.ClientConn, t *hotline.Transaction) (r
 // 332	Next article Type
 // 335	Parent article Type
 // 336	First child article Type
-// 327	News article data flavor	"Should be “text/plain”
+// 327	News article data flavor	"Should be “text/plain”, or the flavor of the requested alternate flavor or attachment
 // 333	News article data	Optional (if data flavor is “text/plain”)
 func HandleGetNewsArtData(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsReadArt) {
-		return cc.NewErrReply(t, "You are not allowed to read news.")
-	}
-
 	newsPath, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
 	if err != nil {
-		return res
+		return cc.NewMalformedReply(t, err)
 	}
 
 	convertedID, err := t.GetField(hotline.FieldNewsArtID).DecodeInt()
 	if err != nil {
-		return res
+		return cc.NewMalformedReply(t, err)
 	}
 
-	art := cc.Server.ThreadedNewsMgr.GetArticle(newsPath, uint32(convertedID))
+	art, err := newsService(cc).Article(cc, newsPath, uint32(convertedID))
+	if err != nil {
+		reply, _ := userErrReply(cc, t, err)
+		return reply
+	}
 	if art == nil {
 		return append(res, cc.NewReply(t))
 	}
 
-	res = append(res, cc.NewReply(t,
-		hotline.NewField(hotline.FieldNewsArtTitle, []byte(art.Title)),
-		hotline.NewField(hotline.FieldNewsArtPoster, []byte(art.Poster)),
-		hotline.NewField(hotline.FieldNewsArtDate, art.Date[:]),
-		hotline.NewField(hotline.FieldNewsArtPrevArt, art.PrevArt[:]),
-		hotline.NewField(hotline.FieldNewsArtNextArt, art.NextArt[:]),
-		hotline.NewField(hotline.FieldNewsArtParentArt, art.ParentArt[:]),
-		hotline.NewField(hotline.FieldNewsArt1stChildArt, art.FirstChildArt[:]),
-		hotline.NewField(hotline.FieldNewsArtDataFlav, []byte("text/plain")),
-		hotline.NewField(hotline.FieldNewsArtData, []byte(art.Data)),
-	))
-	return res
+	// Alternate flavors and attachments are requested by their flavor; other flavors get the plain text.
+	requested := string(t.GetField(hotline.FieldNewsArtDataFlav).Data)
+	flavor, data := hotline.NewsFlavor, []byte(art.Data)
+	if alt, ok := art.AltFlavor(requested); ok {
+		flavor, data = []byte(requested), []byte(alt)
+	} else if a := art.Attachment(requested); a != nil && cc.Server.NewsAttachments != nil {
+		attachment, err := cc.Server.NewsAttachments.Read(a.File)
+		if err != nil {
+			cc.Logger.Error("error reading news attachment", "file", a.File, "err", err)
+			return cc.NewErrReply(t, "Error reading attachment.")
+		}
+		flavor, data = []byte(a.Flavor), attachment
+	}
+
+	return cc.Reply(t).
+		WithBytes(hotline.FieldNewsArtTitle, []byte(art.Title)).
+		WithBytes(hotline.FieldNewsArtPoster, []byte(art.Poster)).
+		WithTime(hotline.FieldNewsArtDate, art.Date).
+		WithBytes(hotline.FieldNewsArtPrevArt, art.PrevArt[:]).
+		WithBytes(hotline.FieldNewsArtNextArt, art.NextArt[:]).
+		WithBytes(hotline.FieldNewsArtParentArt, art.ParentArt[:]).
+		WithBytes(hotline.FieldNewsArt1stChildArt, art.FirstChildArt[:]).
+		WithBytes(hotline.FieldNewsArtDataFlav, flavor).
+		WithBytes(hotline.FieldNewsArtData, data).
+		Transactions()
 }
 
 // HandleDelNewsItem deletes a threaded news folder or category.
@@ -1153,20 +1208,10 @@ func HandleDelNewsItem(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 		return nil
 	}
 
-	item := cc.Server.ThreadedNewsMgr.NewsItem(pathStrs)
-
-	if item.Type == [2]byte{0, 3} {
-		if !cc.Authorize(hotline.AccessNewsDeleteCat) {
-			return cc.NewErrReply(t, "You are not allowed to delete news categories.")
+	if err := newsService(cc).DeleteItem(cc, pathStrs); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
 		}
-	} else {
-		if !cc.Authorize(hotline.AccessNewsDeleteFldr) {
-			return cc.NewErrReply(t, "You are not allowed to delete news folders.")
-		}
-	}
-
-	err = cc.Server.ThreadedNewsMgr.DeleteNewsItem(pathStrs)
-	if err != nil {
 		return res
 	}
 
@@ -1179,14 +1224,9 @@ func HandleDelNewsItem(cc *hotline.ClientConn, t *hotline.Transaction) (res []ho
 // 326	News article Type
 // 337	News article recursive delete	- Delete child articles (1) or not (0)
 func HandleDelNewsArt(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsDeleteArt) {
-		return cc.NewErrReply(t, "You are not allowed to delete news articles.")
-
-	}
-
 	pathStrs, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
 	if err != nil {
-		return res
+		return cc.NewMalformedReply(t, err)
 	}
 
 	articleID, err := t.GetField(hotline.FieldNewsArtID).DecodeInt()
@@ -1197,8 +1237,10 @@ func HandleDelNewsArt(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 
 	deleteRecursive := bytes.Equal([]byte{0, 1}, t.GetField(hotline.FieldNewsArtRecurseDel).Data)
 
-	err = cc.Server.ThreadedNewsMgr.DeleteArticle(pathStrs, uint32(articleID), deleteRecursive)
-	if err != nil {
+	if err := newsService(cc).DeleteArticle(cc, pathStrs, uint32(articleID), deleteRecursive); err != nil {
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
+		}
 		cc.Logger.Error("error deleting news article", "err", err)
 	}
 
@@ -1212,44 +1254,68 @@ func HandleDelNewsArt(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 // 334	News article flags
 // 327	News article data flavor		Currently “text/plain”
 // 333	News article data
+// Alternate flavors of the text, such as text/html, and attachments when enabled by NewsArticles.Attachments, follow
+// the text as further pairs of 327 and 333 fields.
 func HandlePostNewsArt(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsPostArt) {
-		return cc.NewErrReply(t, "You are not allowed to post news articles.")
-	}
-
 	pathStrs, err := t.GetField(hotline.FieldNewsPath).DecodeNewsPath()
-	if err != nil || len(pathStrs) == 0 {
-		cc.Logger.Error("invalid news path")
-		return res
+	if err == nil && len(pathStrs) == 0 {
+		err = errors.New("empty news path")
+	}
+	if err != nil {
+		return cc.NewMalformedReply(t, err)
 	}
 
 	parentArticleID, err := t.GetField(hotline.FieldNewsArtID).DecodeInt()
 	if err != nil {
+		return cc.NewMalformedReply(t, err)
+	}
+
+	text, altData, attachmentData := newsArticleFlavors(t)
+	if res := checkNewsArticle(cc, t, text, altData, attachmentData); res != nil {
 		return res
 	}
+	plainText, altFlavors := newsArticleText(text, altData)
+
+	attachments, err := saveNewsAttachments(cc.Server.NewsAttachments, attachmentData)
+	if err != nil {
+		cc.Logger.Error("error saving news attachments", "err", err)
+		return cc.NewErrReply(t, "Error saving attachments.")
+	}
 
-	err = cc.Server.ThreadedNewsMgr.PostArticle(
+	err = newsService(cc).PostArticle(
+		cc,
 		pathStrs,
 		uint32(parentArticleID),
 		hotline.NewsArtData{
-			Title:    string(t.GetField(hotline.FieldNewsArtTitle).Data),
-			Poster:   string(cc.UserName),
-			Date:     hotline.NewTime(time.Now()),
-			DataFlav: hotline.NewsFlavor,
-			Data:     string(t.GetField(hotline.FieldNewsArtData).Data),
+			Title:       string(t.GetField(hotline.FieldNewsArtTitle).Data),
+			Poster:      string(cc.UserName),
+			PosterLogin: cc.Account.Login,
+			Date:        hotline.NewTime(time.Now()),
+			DataFlav:    hotline.NewsFlavor,
+			Data:        plainText,
+			AltFlavors:  altFlavors,
+			Attachments: attachments,
 		},
 	)
 	if err != nil {
+		_ = cc.Server.NewsAttachments.Delete(attachments)
+		if reply, ok := userErrReply(cc, t, err); ok {
+			return reply
+		}
 		cc.Logger.Error("error posting news article", "err", err)
+		return append(res, cc.NewReply(t))
 	}
 
-	return append(res, cc.NewReply(t))
+	res = append(res, cc.NewReply(t))
+
+	return append(res, announceNewsArticle(cc, pathStrs, string(t.GetField(hotline.FieldNewsArtTitle).Data))...)
 }
 
 // HandleGetMsgs returns the flat news data
 func HandleGetMsgs(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessNewsReadArt) {
-		return cc.NewErrReply(t, "You are not allowed to read news.")
+	// Message boards with a Bytes method can be read without racing other readers for the read offset.
+	if board, ok := cc.Server.MessageBoard.(interface{ Bytes() []byte }); ok {
+		return append(res, cc.NewReply(t, hotline.NewField(hotline.FieldData, board.Bytes())))
 	}
 
 	_, _ = cc.Server.MessageBoard.Seek(0, 0)
@@ -1262,9 +1328,11 @@ func HandleGetMsgs(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotlin
 	return append(res, cc.NewReply(t, hotline.NewField(hotline.FieldData, newsData)))
 }
 
+const transferCapExceededMsg = "This server has reached its monthly transfer limit.  Downloads are disabled until the start of next month."
+
 func HandleDownloadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessDownloadFile) {
-		return cc.NewErrReply(t, "You are not allowed to download files.")
+	if cc.Server.TransferCapExceeded() {
+		return cc.NewErrReply(t, transferCapExceededMsg)
 	}
 
 	fileName := t.GetField(hotline.FieldFileName).Data
@@ -1275,15 +1343,15 @@ func HandleDownloadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []h
 	var frd hotline.FileResumeData
 	if resumeData != nil {
 		if err := frd.UnmarshalBinary(t.GetField(hotline.FieldFileResumeData).Data); err != nil {
-			return res
+			return cc.NewMalformedReply(t, err)
 		}
 		// TODO: handle rsrc fork offset
 		dataOffset = int64(binary.BigEndian.Uint32(frd.ForkInfoList[0].DataSize[:]))
 	}
 
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), filePath, fileName)
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	hlFile, err := hotline.NewFileWrapper(cc.Server.FS, fullFilePath, dataOffset)
@@ -1304,7 +1372,7 @@ func HandleDownloadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []h
 	if resumeData != nil {
 		var frd hotline.FileResumeData
 		if err := frd.UnmarshalBinary(t.GetField(hotline.FieldFileResumeData).Data); err != nil {
-			return res
+			return cc.NewMalformedReply(t, err)
 		}
 		ft.FileResumeData = &frd
 	}
@@ -1319,7 +1387,7 @@ func HandleDownloadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []h
 
 	res = append(res, cc.NewReply(t,
 		hotline.NewField(hotline.FieldRefNum, ft.RefNum[:]),
-		hotline.NewField(hotline.FieldWaitingCount, []byte{0x00, 0x00}), // TODO: Implement waiting count
+		hotline.NewField(hotline.FieldWaitingCount, hotline.EncodeWaitingCount(cc.Server.FileTransferMgr.QueuePosition(ft, cc.Server.DownloadLimits()))),
 		hotline.NewField(hotline.FieldTransferSize, xferSize),
 		hotline.NewField(hotline.FieldFileSize, hlFile.Ffo.FlatFileDataForkHeader.DataSize[:]),
 	))
@@ -1329,20 +1397,22 @@ func HandleDownloadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []h
 
 // Download all files from the specified folder and sub-folders
 func HandleDownloadFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessDownloadFolder) {
-		return cc.NewErrReply(t, "You are not allowed to download folders.")
+	if cc.Server.TransferCapExceeded() {
+		return cc.NewErrReply(t, transferCapExceededMsg)
 	}
 
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
+	fullFilePath, err := cc.ReadPath(t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
 	if err != nil {
 		return nil
 	}
 
-	transferSize, err := hotline.CalcTotalSize(fullFilePath)
-	if err != nil {
+	// Hidden folders are treated as though they don't exist.
+	hidden := cc.HiddenFiles()
+	if hidden.MatchPath(cc.FileRoot(), fullFilePath) {
 		return nil
 	}
-	itemCount, err := hotline.CalcItemCount(fullFilePath)
+
+	transferSize, itemCount, err := cc.Server.FileIndex.FolderSize(fullFilePath, hidden)
 	if err != nil {
 		return nil
 	}
@@ -1359,7 +1429,7 @@ func HandleDownloadFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 		hotline.NewField(hotline.FieldRefNum, fileTransfer.RefNum[:]),
 		hotline.NewField(hotline.FieldTransferSize, transferSize),
 		hotline.NewField(hotline.FieldFolderItemCount, itemCount),
-		hotline.NewField(hotline.FieldWaitingCount, []byte{0x00, 0x00}), // TODO: Implement waiting count
+		hotline.NewField(hotline.FieldWaitingCount, hotline.EncodeWaitingCount(cc.Server.FileTransferMgr.QueuePosition(fileTransfer, cc.Server.DownloadLimits()))),
 	))
 	return res
 }
@@ -1372,26 +1442,48 @@ func HandleDownloadFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 // 220	Folder item count
 // 204	File transfer options	"Optional Currently set to 1" (TODO: ??)
 func HandleUploadFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessUploadFolder) {
-		return cc.NewErrReply(t, "You are not allowed to upload folders.")
-	}
-
 	var fp hotline.FilePath
 	if t.GetField(hotline.FieldFilePath).Data != nil {
 		if _, err := fp.Write(t.GetField(hotline.FieldFilePath).Data); err != nil {
-			return res
+			return cc.NewMalformedReply(t, err)
 		}
 	}
 
 	// Handle special cases for Upload and Drop Box folders
 	if !cc.Authorize(hotline.AccessUploadAnywhere) {
 		if !fp.IsUploadDir() && !fp.IsDropbox() {
-			return cc.NewErrReply(t, fmt.Sprintf("Cannot accept upload of the folder \"%v\" because you are only allowed to upload to the \"Uploads\" folder.", string(t.GetField(hotline.FieldFileName).Data)))
+			return cc.NewErrReplyf(t, "Cannot accept upload of the folder \"%v\" because you are only allowed to upload to the \"Uploads\" folder.", string(t.GetField(hotline.FieldFileName).Data))
+		}
+	}
+
+	if hotline.ReservedFileName(string(t.GetField(hotline.FieldFileName).Data)) {
+		return cc.NewErrReplyf(t, "Cannot accept upload of the folder \"%v\" because the name is reserved.  Try choosing a different Name.", string(t.GetField(hotline.FieldFileName).Data))
+	}
+
+	if !cc.Server.HasUploadSpace(uploadSize(t)) {
+		return cc.NewErrReply(t, "Cannot accept upload because the server is low on disk space.")
+	}
+
+	// Uploads to moderated folders are held in the pending uploads area until approved.
+	fileRoot := cc.FileRoot()
+	moderated := requiresApproval(cc, fp)
+	if moderated {
+		fileRoot = cc.Server.Config.PendingUploadsDir
+		if err := cc.Server.FS.MkdirAll(filepath.Join(fileRoot, filepath.Join("/", fp.String())), 0777); err != nil {
+			return cc.NewErrReply(t, "Cannot accept upload because the pending uploads folder could not be created.")
+		}
+
+		pendingPath, err := hotline.ReadPath(fileRoot, t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
+		if err != nil {
+			return cc.NewMalformedReply(t, err)
+		}
+		if err := holdPendingUpload(cc, pendingPath); err != nil {
+			return cc.NewErrReply(t, "Cannot accept upload because the pending uploads folder could not be created.")
 		}
 	}
 
 	fileTransfer := cc.NewFileTransfer(hotline.FolderUpload,
-		cc.FileRoot(),
+		fileRoot,
 		t.GetField(hotline.FieldFileName).Data,
 		t.GetField(hotline.FieldFilePath).Data,
 		t.GetField(hotline.FieldTransferSize).Data,
@@ -1399,7 +1491,22 @@ func HandleUploadFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res []h
 
 	fileTransfer.FolderItemCount = t.GetField(hotline.FieldFolderItemCount).Data
 
-	return append(res, cc.NewReply(t, hotline.NewField(hotline.FieldRefNum, fileTransfer.RefNum[:])))
+	res = append(res, cc.NewReply(t, hotline.NewField(hotline.FieldRefNum, fileTransfer.RefNum[:])))
+	if moderated {
+		res = append(res, serverMsg(cc.ID, "Your upload will be visible to other users once it has been approved by a moderator."))
+	}
+
+	return res
+}
+
+// uploadSize returns the size of an upload from FieldTransferSize, or 0 if it isn't sent, e.g. when resuming.
+func uploadSize(t *hotline.Transaction) uint64 {
+	size, err := t.GetUint32(hotline.FieldTransferSize)
+	if err != nil {
+		return 0
+	}
+
+	return uint64(size)
 }
 
 // HandleUploadFile
@@ -1410,10 +1517,6 @@ func HandleUploadFolder(cc *hotline.ClientConn, t *hotline.Transaction) (res []h
 // Used only to resume download, currently has value 2"
 // 108	File transfer size	"Optional used if download is not resumed"
 func HandleUploadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessUploadFile) {
-		return cc.NewErrReply(t, "You are not allowed to upload files.")
-	}
-
 	fileName := t.GetField(hotline.FieldFileName).Data
 	filePath := t.GetField(hotline.FieldFilePath).Data
 	transferOptions := t.GetField(hotline.FieldFileTransferOptions).Data
@@ -1422,26 +1525,57 @@ func HandleUploadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 	var fp hotline.FilePath
 	if filePath != nil {
 		if _, err := fp.Write(filePath); err != nil {
-			return res
+			return cc.NewMalformedReply(t, err)
 		}
 	}
 
 	// Handle special cases for Upload and Drop Box folders
 	if !cc.Authorize(hotline.AccessUploadAnywhere) {
 		if !fp.IsUploadDir() && !fp.IsDropbox() {
-			return cc.NewErrReply(t, fmt.Sprintf("Cannot accept upload of the file \"%v\" because you are only allowed to upload to the \"Uploads\" folder.", string(fileName)))
+			return cc.NewErrReplyf(t, "Cannot accept upload of the file \"%v\" because you are only allowed to upload to the \"Uploads\" folder.", string(fileName))
 		}
 	}
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), filePath, fileName)
+
+	if hotline.ReservedFileName(string(fileName)) {
+		return cc.NewErrReplyf(t, "Cannot accept upload of the file \"%v\" because the name is reserved.  Try choosing a different Name.", string(fileName))
+	}
+
+	if !cc.Server.HasUploadSpace(uploadSize(t)) {
+		return cc.NewErrReply(t, "Cannot accept upload because the server is low on disk space.")
+	}
+
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	if _, err := cc.Server.FS.Stat(fullFilePath); err == nil {
-		return cc.NewErrReply(t, fmt.Sprintf("Cannot accept upload because there is already a file named \"%v\".  Try choosing a different Name.", string(fileName)))
+		return cc.NewErrReplyf(t, "Cannot accept upload because there is already a file named \"%v\".  Try choosing a different Name.", string(fileName))
 	}
 
-	ft := cc.NewFileTransfer(hotline.FileUpload, cc.FileRoot(), fileName, filePath, transferSize)
+	// Uploads to moderated folders are held in the pending uploads area until approved.
+	fileRoot := cc.FileRoot()
+	moderated := requiresApproval(cc, fp)
+	if moderated {
+		fileRoot = cc.Server.Config.PendingUploadsDir
+		fullFilePath, err = hotline.ReadPath(fileRoot, filePath, fileName)
+		if err != nil {
+			return cc.NewMalformedReply(t, err)
+		}
+
+		if _, err := cc.Server.FS.Stat(fullFilePath); err == nil {
+			return cc.NewErrReplyf(t, "Cannot accept upload because there is already a file named \"%v\" awaiting approval.  Try choosing a different Name.", string(fileName))
+		}
+
+		if err := cc.Server.FS.MkdirAll(filepath.Dir(fullFilePath), 0777); err != nil {
+			return cc.NewErrReply(t, "Cannot accept upload because the pending uploads folder could not be created.")
+		}
+		if err := holdPendingUpload(cc, fullFilePath); err != nil {
+			return cc.NewErrReply(t, "Cannot accept upload because the pending uploads folder could not be created.")
+		}
+	}
+
+	ft := cc.NewFileTransfer(hotline.FileUpload, fileRoot, fileName, filePath, transferSize)
 
 	replyT := cc.NewReply(t, hotline.NewField(hotline.FieldRefNum, ft.RefNum[:]))
 
@@ -1467,6 +1601,10 @@ func HandleUploadFile(cc *hotline.ClientConn, t *hotline.Transaction) (res []hot
 	}
 
 	res = append(res, replyT)
+	if moderated {
+		res = append(res, serverMsg(cc.ID, "Your upload will be visible to other users once it has been approved by a moderator."))
+	}
+
 	return res
 }
 
@@ -1477,7 +1615,7 @@ func HandleSetClientUserInfo(cc *hotline.ClientConn, t *hotline.Transaction) (re
 		cc.Icon = t.GetField(hotline.FieldUserIconID).Data
 	}
 	if cc.Authorize(hotline.AccessAnyName) {
-		cc.UserName = t.GetField(hotline.FieldUserName).Data
+		cc.UserName = cc.Server.LimitUserName(t.GetField(hotline.FieldUserName).Data)
 	}
 
 	// the options field is only passed by the client versions > 1.2.3.
@@ -1520,19 +1658,18 @@ func HandleKeepAlive(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotl
 }
 
 func HandleGetFileNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	fullPath, err := hotline.ReadPath(
-		cc.FileRoot(),
+	fullPath, err := cc.ReadPath(
 		t.GetField(hotline.FieldFilePath).Data,
 		nil,
 	)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	var fp hotline.FilePath
 	if t.GetField(hotline.FieldFilePath).Data != nil {
 		if _, err = fp.Write(t.GetField(hotline.FieldFilePath).Data); err != nil {
-			return res
+			return cc.NewMalformedReply(t, err)
 		}
 	}
 
@@ -1541,7 +1678,19 @@ func HandleGetFileNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res
 		return cc.NewErrReply(t, "You are not allowed to view drop boxes.")
 	}
 
-	fileNames, err := hotline.GetFileNameList(fullPath, cc.Server.Config.IgnoreFiles)
+	opts, err := hotline.ReadFileListOptions(t)
+	if err != nil {
+		return cc.NewErrReply(t, "Invalid file list sort or filter options.")
+	}
+
+	// Hidden folders are treated as though they don't exist.
+	opts.Hidden = cc.HiddenFiles()
+	opts.FS = cc.Server.FS
+	if opts.Hidden.MatchPath(cc.FileRoot(), fullPath) {
+		return res
+	}
+
+	fileNames, err := cc.Server.FileIndex.FileList(fullPath, cc.Server.Config.IgnoreFiles, opts)
 	if err != nil {
 		return res
 	}
@@ -1565,20 +1714,33 @@ func HandleGetFileNameList(cc *hotline.ClientConn, t *hotline.Transaction) (res
 
 // HandleInviteNewChat invites users to new private chat
 func HandleInviteNewChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessOpenChat) {
-		return cc.NewErrReply(t, "You are not allowed to request private chat.")
+	if !cc.AllowChatInvite() {
+		return cc.NewErrReply(t, "You are sending chat invitations too quickly.  Please wait a moment and try again.")
 	}
 
 	// Client to Invite
-	targetID := t.GetField(hotline.FieldUserID).Data
+	targetID, err := t.GetClientID(hotline.FieldUserID)
+	if err != nil {
+		return invalidRequest(cc, t, err)
+	}
+
+	targetClient := cc.Server.ClientMgr.Get(targetID)
+	if targetClient == nil {
+		return cc.NewErrReply(t, "User not found.")
+	}
 
 	// Create a new chat with self as initial member.
-	newChatID := cc.Server.ChatMgr.New(cc)
+	newChatID := cc.Server.ChatMgr.New(cc, hotline.ChatOptions{
+		MaxMembers: cc.Server.Config.MaxChatMembers,
+		InviteOnly: cc.Server.Config.ChatInviteOnly,
+	})
 
 	// Check if target user has "Refuse private chat" flag
-	targetClient := cc.Server.ClientMgr.Get([2]byte(targetID))
 	flagBitmap := big.NewInt(int64(binary.BigEndian.Uint16(targetClient.Flags[:])))
-	if flagBitmap.Bit(hotline.UserFlagRefusePChat) == 1 {
+	switch {
+	case targetClient.BlocksChatInvitesFrom(cc):
+		// Invitations blocked by the target user are dropped without telling the sender.
+	case flagBitmap.Bit(hotline.UserFlagRefusePChat) == 1:
 		res = append(res,
 			hotline.NewTransaction(
 				hotline.TranServerMsg,
@@ -1589,11 +1751,15 @@ func HandleInviteNewChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []
 				hotline.NewField(hotline.FieldOptions, []byte{0, 2}),
 			),
 		)
-	} else {
+	default:
+		if err := cc.Server.ChatMgr.Invite(newChatID, cc, targetClient); err != nil {
+			return cc.NewErrReply(t, chatInviteErrMsg(err))
+		}
+
 		res = append(res,
 			hotline.NewTransaction(
 				hotline.TranInviteToChat,
-				[2]byte(targetID),
+				targetID,
 				hotline.NewField(hotline.FieldChatID, newChatID[:]),
 				hotline.NewField(hotline.FieldUserName, cc.UserName),
 				hotline.NewField(hotline.FieldUserID, cc.ID[:]),
@@ -1614,35 +1780,94 @@ func HandleInviteNewChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []
 }
 
 func HandleInviteToChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessOpenChat) {
-		return cc.NewErrReply(t, "You are not allowed to request private chat.")
+	if !cc.AllowChatInvite() {
+		return cc.NewErrReply(t, "You are sending chat invitations too quickly.  Please wait a moment and try again.")
 	}
 
 	// Client to Invite
-	targetID := t.GetField(hotline.FieldUserID).Data
-	chatID := t.GetField(hotline.FieldChatID).Data
-
-	return []hotline.Transaction{
-		hotline.NewTransaction(
-			hotline.TranInviteToChat,
-			[2]byte(targetID),
-			hotline.NewField(hotline.FieldChatID, chatID),
-			hotline.NewField(hotline.FieldUserName, cc.UserName),
-			hotline.NewField(hotline.FieldUserID, cc.ID[:]),
-		),
+	targetID, err := t.GetClientID(hotline.FieldUserID)
+	if err != nil {
+		return invalidRequest(cc, t, err)
+	}
+	chatID, err := t.GetChatID()
+	if err != nil {
+		return invalidRequest(cc, t, err)
+	}
+
+	targetClient := cc.Server.ClientMgr.Get(targetID)
+	if targetClient == nil {
+		return cc.NewErrReply(t, "User not found.")
+	}
+
+	if err := cc.Server.ChatMgr.Invite(chatID, cc, targetClient); err != nil {
+		return cc.NewErrReply(t, chatInviteErrMsg(err))
+	}
+
+	// Invitations blocked by the target user are dropped without telling the sender.
+	if !targetClient.BlocksChatInvitesFrom(cc) {
+		res = append(res,
+			hotline.NewTransaction(
+				hotline.TranInviteToChat,
+				targetID,
+				hotline.NewField(hotline.FieldChatID, chatID[:]),
+				hotline.NewField(hotline.FieldUserName, cc.UserName),
+				hotline.NewField(hotline.FieldUserID, cc.ID[:]),
+			),
+		)
+	}
+
+	return append(res,
 		cc.NewReply(
 			t,
-			hotline.NewField(hotline.FieldChatID, chatID),
+			hotline.NewField(hotline.FieldChatID, chatID[:]),
 			hotline.NewField(hotline.FieldUserName, cc.UserName),
 			hotline.NewField(hotline.FieldUserID, cc.ID[:]),
 			hotline.NewField(hotline.FieldUserIconID, cc.Icon),
 			hotline.NewField(hotline.FieldUserFlags, cc.Flags[:]),
 		),
+	)
+}
+
+// invalidRequest logs a request with a missing or malformed field and replies with an error, so that the client isn't
+// left waiting for a reply.
+func invalidRequest(cc *hotline.ClientConn, t *hotline.Transaction, err error) []hotline.Transaction {
+	logInvalidRequest(cc, t, err)
+
+	return cc.NewErrReply(t, "Invalid request.")
+}
+
+// logInvalidRequest logs a request with a missing or malformed field, for requests that the client doesn't expect a
+// reply to.
+func logInvalidRequest(cc *hotline.ClientConn, t *hotline.Transaction, err error) {
+	cc.Logger.Warn("Invalid request", "type", t.Type, "err", err)
+}
+
+// pathErrReply returns the reply to t for an error from ClientConn.ReadPath.  Hidden files are treated as though they
+// don't exist, so no reply is sent, as for other missing files.
+func pathErrReply(cc *hotline.ClientConn, t *hotline.Transaction, err error) []hotline.Transaction {
+	if errors.Is(err, hotline.ErrHiddenPath) {
+		return nil
+	}
+
+	return cc.NewMalformedReply(t, err)
+}
+
+// chatInviteErrMsg returns the error message for a failed private chat invitation.
+func chatInviteErrMsg(err error) string {
+	if errors.Is(err, hotline.ErrChatFull) {
+		return "Cannot invite user because the chat is full."
 	}
+	return "You are not allowed to invite users to this chat."
 }
 
 func HandleRejectChatInvite(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	chatID := [4]byte(t.GetField(hotline.FieldChatID).Data)
+	chatID, err := t.GetChatID()
+	if err != nil {
+		logInvalidRequest(cc, t, err)
+		return nil
+	}
+
+	cc.Server.ChatMgr.DeclineInvite(chatID, cc.ID)
 
 	for _, c := range cc.Server.ChatMgr.Members(chatID) {
 		res = append(res,
@@ -1664,15 +1889,32 @@ func HandleRejectChatInvite(cc *hotline.ClientConn, t *hotline.Transaction) (res
 // * 300	User Name with info (Optional)
 // * 300 	(more user names with info)
 func HandleJoinChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	chatID := t.GetField(hotline.FieldChatID).Data
+	chatID, err := t.GetChatID()
+	if err != nil {
+		return invalidRequest(cc, t, err)
+	}
+
+	if err := cc.Server.ChatMgr.Join(chatID, cc); err != nil {
+		switch {
+		case errors.Is(err, hotline.ErrChatFull):
+			return cc.NewErrReply(t, "Cannot join chat because it is full.")
+		case errors.Is(err, hotline.ErrChatNotInvited):
+			return cc.NewErrReply(t, "You must be invited to join this chat.")
+		default:
+			return cc.NewErrReply(t, "Chat not found.")
+		}
+	}
 
 	// Send TranNotifyChatChangeUser to current members of the chat to inform of new user
-	for _, c := range cc.Server.ChatMgr.Members([4]byte(chatID)) {
+	for _, c := range cc.Server.ChatMgr.Members(chatID) {
+		if c.ID == cc.ID {
+			continue
+		}
 		res = append(res,
 			hotline.NewTransaction(
 				hotline.TranNotifyChatChangeUser,
 				c.ID,
-				hotline.NewField(hotline.FieldChatID, chatID),
+				hotline.NewField(hotline.FieldChatID, chatID[:]),
 				hotline.NewField(hotline.FieldUserName, cc.UserName),
 				hotline.NewField(hotline.FieldUserID, cc.ID[:]),
 				hotline.NewField(hotline.FieldUserIconID, cc.Icon),
@@ -1681,12 +1923,10 @@ func HandleJoinChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotli
 		)
 	}
 
-	cc.Server.ChatMgr.Join(hotline.ChatID(chatID), cc)
-
-	subject := cc.Server.ChatMgr.GetSubject(hotline.ChatID(chatID))
+	subject := cc.Server.ChatMgr.GetSubject(chatID)
 
 	replyFields := []hotline.Field{hotline.NewField(hotline.FieldChatSubject, []byte(subject))}
-	for _, c := range cc.Server.ChatMgr.Members([4]byte(chatID)) {
+	for _, c := range cc.Server.ChatMgr.Members(chatID) {
 		b, err := io.ReadAll(&hotline.User{
 			ID:    c.ID,
 			Icon:  c.Icon,
@@ -1708,17 +1948,21 @@ func HandleJoinChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotli
 //
 // Reply is not expected.
 func HandleLeaveChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	chatID := t.GetField(hotline.FieldChatID).Data
+	chatID, err := t.GetChatID()
+	if err != nil {
+		logInvalidRequest(cc, t, err)
+		return nil
+	}
 
-	cc.Server.ChatMgr.Leave([4]byte(chatID), cc.ID)
+	cc.Server.ChatMgr.Leave(chatID, cc.ID)
 
 	// Notify members of the private chat that the user has left
-	for _, c := range cc.Server.ChatMgr.Members(hotline.ChatID(chatID)) {
+	for _, c := range cc.Server.ChatMgr.Members(chatID) {
 		res = append(res,
 			hotline.NewTransaction(
 				hotline.TranNotifyChatDeleteUser,
 				c.ID,
-				hotline.NewField(hotline.FieldChatID, chatID),
+				hotline.NewField(hotline.FieldChatID, chatID[:]),
 				hotline.NewField(hotline.FieldUserID, cc.ID[:]),
 			),
 		)
@@ -1733,17 +1977,21 @@ func HandleLeaveChat(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotl
 // * 115	Chat subject
 // Reply is not expected.
 func HandleSetChatSubject(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	chatID := t.GetField(hotline.FieldChatID).Data
+	chatID, err := t.GetChatID()
+	if err != nil {
+		logInvalidRequest(cc, t, err)
+		return nil
+	}
 
-	cc.Server.ChatMgr.SetSubject([4]byte(chatID), string(t.GetField(hotline.FieldChatSubject).Data))
+	cc.Server.ChatMgr.SetSubject(chatID, string(t.GetField(hotline.FieldChatSubject).Data))
 
 	// Notify chat members of new subject.
-	for _, c := range cc.Server.ChatMgr.Members([4]byte(chatID)) {
+	for _, c := range cc.Server.ChatMgr.Members(chatID) {
 		res = append(res,
 			hotline.NewTransaction(
 				hotline.TranNotifyChatSubject,
 				c.ID,
-				hotline.NewField(hotline.FieldChatID, chatID),
+				hotline.NewField(hotline.FieldChatID, chatID[:]),
 				hotline.NewField(hotline.FieldChatSubject, t.GetField(hotline.FieldChatSubject).Data),
 			),
 		)
@@ -1761,26 +2009,24 @@ func HandleSetChatSubject(cc *hotline.ClientConn, t *hotline.Transaction) (res [
 // Fields used in the reply:
 // None
 func HandleMakeAlias(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
-	if !cc.Authorize(hotline.AccessMakeAlias) {
-		return cc.NewErrReply(t, "You are not allowed to make aliases.")
-	}
 	fileName := t.GetField(hotline.FieldFileName).Data
 	filePath := t.GetField(hotline.FieldFilePath).Data
 	fileNewPath := t.GetField(hotline.FieldFileNewPath).Data
 
-	fullFilePath, err := hotline.ReadPath(cc.FileRoot(), filePath, fileName)
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
-	fullNewFilePath, err := hotline.ReadPath(cc.FileRoot(), fileNewPath, fileName)
+	fullNewFilePath, err := cc.ReadPath(fileNewPath, fileName)
 	if err != nil {
-		return res
+		return pathErrReply(cc, t, err)
 	}
 
 	if err := cc.Server.FS.Symlink(fullFilePath, fullNewFilePath); err != nil {
 		return cc.NewErrReply(t, "Error creating alias")
 	}
+	cc.Server.FileIndex.Invalidate(fullNewFilePath)
 
 	res = append(res, cc.NewReply(t))
 	return res
@@ -1794,10 +2040,290 @@ func HandleMakeAlias(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotl
 // 108	FieldTransferSize	Size of data to be downloaded
 func HandleDownloadBanner(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
 	ft := cc.NewFileTransfer(hotline.BannerDownload, "", []byte{}, []byte{}, make([]byte, 4))
-	binary.BigEndian.PutUint32(ft.TransferSize, uint32(len(cc.Server.Banner)))
+	binary.BigEndian.PutUint32(ft.TransferSize, uint32(len(cc.Server.Banner())))
 
 	return append(res, cc.NewReply(t,
 		hotline.NewField(hotline.FieldRefNum, ft.RefNum[:]),
 		hotline.NewField(hotline.FieldTransferSize, ft.TransferSize),
 	))
 }
+
+// HandleNegotiateCapabilities enables the optional features listed by the client that the server supports, and replies
+// with the enabled features.
+func HandleNegotiateCapabilities(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	var requested []string
+	for _, field := range t.Fields {
+		if field.Type == hotline.FieldCapability {
+			requested = append(requested, string(field.Data))
+		}
+	}
+
+	enabled := cc.EnableCapabilities(requested)
+	cc.Logger.Debug("Negotiated capabilities", "requested", requested, "enabled", enabled)
+
+	reply := cc.NewReply(t)
+	for _, name := range enabled {
+		reply.Fields = append(reply.Fields, hotline.NewField(hotline.FieldCapability, []byte(name)))
+	}
+
+	return append(res, reply)
+}
+
+// HandleGetServerInfo replies with the server version, uptime, and usage counts.  No access privileges are required.
+//
+// Fields used in the reply:
+// 4001	FieldServerVersion
+// 4002	FieldServerUptime
+// 4003	FieldUserCount
+// 4004	FieldDownloadCount
+// 4005	FieldUploadCount
+// 4006	FieldDownloadsInProgress
+// 4007	FieldUploadsInProgress
+func HandleGetServerInfo(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	info := cc.Server.Info()
+
+	reply := cc.Reply(t).
+		WithString(hotline.FieldServerVersion, info.Version).
+		WithUint32(hotline.FieldServerUptime, uint32(info.UptimeSeconds))
+	if !cc.Server.Config.Privacy.HideUserCount || cc.Authorize(hotline.AccessDisconUser) {
+		reply.WithUint32(hotline.FieldUserCount, uint32(info.Users))
+	}
+
+	return reply.
+		WithUint32(hotline.FieldDownloadCount, uint32(info.Downloads)).
+		WithUint32(hotline.FieldUploadCount, uint32(info.Uploads)).
+		WithUint32(hotline.FieldDownloadsInProgress, uint32(info.DownloadsInProgress)).
+		WithUint32(hotline.FieldUploadsInProgress, uint32(info.UploadsInProgress)).
+		Transactions()
+}
+
+// HandleGetIncompleteUploads replies with the user's own interrupted uploads, which can be resumed by uploading the
+// file again to the same path, or deleted with TranDeleteIncompleteUpload.  Uploads whose partial file no longer
+// exists are forgotten.
+//
+// Fields used in the reply:
+// 4011	FieldIncompleteUpload	Repeated for each upload
+func HandleGetIncompleteUploads(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	if cc.Server.IncompleteUploadMgr == nil {
+		return append(res, cc.NewReply(t))
+	}
+
+	var fields []hotline.Field
+	for _, upload := range cc.Server.IncompleteUploadMgr.List(cc.Account.Login) {
+		info, err := cc.Server.FS.Stat(upload.FullPath + hotline.IncompleteFileSuffix)
+		if err != nil {
+			if err := cc.Server.IncompleteUploadMgr.Delete(upload.FullPath); err != nil {
+				cc.Logger.Error("Error removing incomplete upload", "path", upload.FullPath, "err", err)
+			}
+			continue
+		}
+
+		fields = append(fields, hotline.NewIncompleteUploadField(upload.Path, info.Size(), time.Since(upload.Started)))
+	}
+
+	return append(res, cc.NewReply(t, fields...))
+}
+
+// HandleDeleteIncompleteUpload deletes the partial file of one of the user's interrupted uploads.
+//
+// Fields used in the request:
+// 201	File Name
+// 202	File Path
+func HandleDeleteIncompleteUpload(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	if cc.Server.IncompleteUploadMgr == nil {
+		return cc.NewErrReply(t, "Incomplete upload not found.")
+	}
+
+	uploadPath, err := hotline.RelativePath(t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
+	if err != nil {
+		return cc.NewMalformedReply(t, err)
+	}
+
+	uploads := cc.Server.IncompleteUploadMgr.List(cc.Account.Login)
+	i := slices.IndexFunc(uploads, func(upload hotline.IncompleteUpload) bool {
+		return upload.Path == uploadPath
+	})
+	if i == -1 {
+		return cc.NewErrReply(t, "Incomplete upload not found.")
+	}
+	upload := uploads[i]
+
+	if err := cc.Server.FS.Remove(upload.FullPath + hotline.IncompleteFileSuffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		cc.Logger.Error("Error deleting incomplete upload", "path", upload.FullPath, "err", err)
+		return cc.NewErrReply(t, "Cannot delete incomplete upload.")
+	}
+
+	// Remove any resource and info forks saved with the partial file.
+	dir, name := filepath.Split(upload.FullPath)
+	for _, template := range []string{hotline.RsrcForkNameTemplate, hotline.InfoForkNameTemplate} {
+		_ = cc.Server.FS.Remove(filepath.Join(dir, fmt.Sprintf(template, name)))
+	}
+	cc.Server.FileIndex.Invalidate(upload.FullPath)
+
+	if err := cc.Server.IncompleteUploadMgr.Delete(upload.FullPath); err != nil {
+		cc.Logger.Error("Error removing incomplete upload", "path", upload.FullPath, "err", err)
+	}
+
+	cc.Logger.Info("Deleted incomplete upload", "path", upload.Path)
+
+	return append(res, cc.NewReply(t))
+}
+
+// HandleGetBookmarks replies with the account's bookmarked files and folders.
+//
+// Fields used in the reply:
+// 4014	FieldBookmark	Repeated for each bookmark
+func HandleGetBookmarks(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	account := cc.Server.AccountManager.Get(cc.Account.Login)
+	if account == nil {
+		return cc.NewErrReply(t, "Account not found.")
+	}
+
+	var fields []hotline.Field
+	for _, bookmark := range account.Bookmarks {
+		encoded, err := txtEncoder.String(bookmark)
+		if err != nil {
+			continue
+		}
+		fields = append(fields, hotline.NewField(hotline.FieldBookmark, []byte(encoded)))
+	}
+
+	return append(res, cc.NewReply(t, fields...))
+}
+
+// HandleAddBookmark adds a file or folder to the account's bookmarks.
+//
+// Fields used in the request:
+// 201	File Name
+// 202	File Path	Optional
+func HandleAddBookmark(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	if hotline.IsSharedAccount(cc.Account.Login) {
+		return cc.NewErrReply(t, "You are not allowed to save bookmarks.")
+	}
+
+	fileName := t.GetField(hotline.FieldFileName).Data
+	filePath := t.GetField(hotline.FieldFilePath).Data
+
+	fullFilePath, err := cc.ReadPath(filePath, fileName)
+	if err != nil && !errors.Is(err, hotline.ErrHiddenPath) {
+		return cc.NewMalformedReply(t, err)
+	}
+
+	// Hidden files are treated as though they don't exist.
+	if err == nil {
+		_, err = cc.Server.FS.Stat(fullFilePath)
+	}
+	if err != nil {
+		return cc.NewErrReplyf(t, "Cannot bookmark \"%s\" because it does not exist or cannot be found.", fileName)
+	}
+
+	bookmark, err := hotline.RelativePath(filePath, fileName)
+	if err != nil {
+		return cc.NewMalformedReply(t, err)
+	}
+
+	var addErr error
+	err = cc.Server.AccountManager.Modify(cc.Account.Login, func(account *hotline.Account) {
+		addErr = account.AddBookmark(bookmark)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return cc.NewErrReply(t, "Account not found.")
+	}
+	if err != nil {
+		cc.Logger.Error("Error saving bookmark", "err", err)
+		return cc.NewErrReply(t, "Cannot save bookmark.")
+	}
+	if addErr != nil {
+		return cc.NewErrReplyf(t, "Cannot add bookmark because you already have the maximum of %d bookmarks.", hotline.MaxBookmarks)
+	}
+
+	return append(res, cc.NewReply(t))
+}
+
+// HandleDeleteBookmark removes a file or folder from the account's bookmarks.  The bookmarked file does not need to
+// exist, so that bookmarks of files that have been moved or deleted can be removed.
+//
+// Fields used in the request:
+// 201	File Name
+// 202	File Path	Optional
+func HandleDeleteBookmark(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	bookmark, err := hotline.RelativePath(t.GetField(hotline.FieldFilePath).Data, t.GetField(hotline.FieldFileName).Data)
+	if err != nil {
+		return cc.NewMalformedReply(t, err)
+	}
+
+	if hotline.IsSharedAccount(cc.Account.Login) {
+		return cc.NewErrReply(t, "You are not allowed to save bookmarks.")
+	}
+
+	var removed bool
+	err = cc.Server.AccountManager.Modify(cc.Account.Login, func(account *hotline.Account) {
+		removed = account.RemoveBookmark(bookmark)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return cc.NewErrReply(t, "Account not found.")
+	}
+	if err != nil {
+		cc.Logger.Error("Error removing bookmark", "err", err)
+		return cc.NewErrReply(t, "Cannot remove bookmark.")
+	}
+	if !removed {
+		return cc.NewErrReply(t, "Bookmark not found.")
+	}
+
+	return append(res, cc.NewReply(t))
+}
+
+// HandleGetPreferences replies with the account's saved preferences.
+//
+// Fields used in the reply:
+// 4015	FieldPreference	Repeated for each preference, in the form "Name=Value"
+func HandleGetPreferences(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	account := cc.Server.AccountManager.Get(cc.Account.Login)
+	if account == nil {
+		return cc.NewErrReply(t, "Account not found.")
+	}
+
+	var fields []hotline.Field
+	for _, name := range slices.Sorted(maps.Keys(account.Preferences)) {
+		fields = append(fields, hotline.NewField(hotline.FieldPreference, []byte(hotline.ToMacRoman(name+"="+account.Preferences[name]))))
+	}
+
+	return append(res, cc.NewReply(t, fields...))
+}
+
+// HandleSetPreferences saves preferences to the user's account.  Preferences sent with an empty value are removed.
+// Preferences take effect the next time the user logs in, except for Ignore and Locale which take effect immediately.
+//
+// Fields used in the request:
+// 4015	FieldPreference	Repeated for each preference to set, in the form "Name=Value"
+func HandleSetPreferences(cc *hotline.ClientConn, t *hotline.Transaction) (res []hotline.Transaction) {
+	if hotline.IsSharedAccount(cc.Account.Login) {
+		return cc.NewErrReply(t, "You are not allowed to change account preferences.")
+	}
+
+	prefs := make(map[string]string)
+	for _, field := range t.Fields {
+		if field.Type != hotline.FieldPreference {
+			continue
+		}
+
+		decoded, err := txtDecoder.String(string(field.Data))
+		if err != nil {
+			return cc.NewErrReply(t, "Invalid preference.")
+		}
+
+		name, value, ok := strings.Cut(decoded, "=")
+		if !ok {
+			return cc.NewErrReply(t, "Invalid preference.")
+		}
+		prefs[name] = value
+	}
+
+	if _, err := savePreferences(cc.Server, cc.Account.Login, prefs); err != nil {
+		cc.Logger.Info("Error saving preferences", "err", err)
+		return cc.NewErrReplyf(t, "Cannot save preferences: %s.", err)
+	}
+
+	return append(res, cc.NewReply(t))
+}
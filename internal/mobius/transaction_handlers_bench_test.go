@@ -0,0 +1,70 @@
+package mobius
+
+import (
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func BenchmarkHandleChatSend(b *testing.B) {
+	for _, clients := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("%d clients", clients), func(b *testing.B) {
+			srv := &hotline.Server{ClientMgr: hotline.NewMemClientMgr()}
+			for i := 0; i < clients; i++ {
+				srv.ClientMgr.Add(&hotline.ClientConn{
+					Account: &hotline.Account{Access: hotline.AccessBitmap{255, 255, 255, 255, 255, 255, 255, 255}},
+				})
+			}
+
+			cc := srv.ClientMgr.List()[0]
+			cc.UserName = []byte("benchmark")
+			cc.Server = srv
+
+			t := hotline.NewTransaction(hotline.TranChatSend, hotline.ClientID{}, hotline.NewField(hotline.FieldData, []byte("hello, world")))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if res := HandleChatSend(cc, &t); len(res) != clients {
+					b.Fatalf("expected %d transactions, got %d", clients, len(res))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHandleGetFileNameList(b *testing.B) {
+	for _, files := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("%d files", files), func(b *testing.B) {
+			root := b.TempDir()
+			for i := 0; i < files; i++ {
+				name := filepath.Join(root, fmt.Sprintf("file-%05d.txt", i))
+				if i%10 == 0 {
+					if err := os.Mkdir(filepath.Join(root, fmt.Sprintf("folder-%05d", i)), 0755); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := os.WriteFile(name, []byte("data"), 0644); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			cc := &hotline.ClientConn{
+				Account: &hotline.Account{FileRoot: root, Access: hotline.AccessBitmap{255, 255, 255, 255, 255, 255, 255, 255}},
+				Server:  &hotline.Server{},
+			}
+			t := hotline.NewTransaction(hotline.TranGetFileNameList, hotline.ClientID{})
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				res := HandleGetFileNameList(cc, &t)
+				if len(res) != 1 || len(res[0].Fields) == 0 {
+					b.Fatal("expected file name list reply")
+				}
+			}
+		})
+	}
+}
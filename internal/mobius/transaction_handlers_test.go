@@ -51,6 +51,16 @@ var tranSortFunc = func(a, b hotline.Transaction) int {
 	)
 }
 
+// registeredHandler returns the handler for tranType as registered by RegisterHandlers, including the permission checks
+// declared at registration.
+func registeredHandler(tranType hotline.TranType) hotline.HandlerFunc {
+	srv, _ := hotline.NewServer()
+	RegisterHandlers(srv)
+	handler, _ := srv.Handler(tranType)
+
+	return handler
+}
+
 // TranAssertEqual compares equality of transactions slices after stripping out the random transaction Type
 func TranAssertEqual(t *testing.T, tran1, tran2 []hotline.Transaction) bool {
 	var newT1 []hotline.Transaction
@@ -952,6 +962,7 @@ func TestHandleNewFolder(t *testing.T) {
 			name: "when Write returns an err",
 			args: args{
 				cc: &hotline.ClientConn{
+					Logger: NewTestLogger(),
 					Account: &hotline.Account{
 						Access: func() hotline.AccessBitmap {
 							var bits hotline.AccessBitmap
@@ -980,7 +991,16 @@ func TestHandleNewFolder(t *testing.T) {
 					}),
 				),
 			},
-			wantRes: []hotline.Transaction{},
+			wantRes: []hotline.Transaction{
+				{
+					ClientID:  [2]byte{0, 1},
+					IsReply:   0x01,
+					ErrorCode: hotline.ErrorCodeMalformed,
+					Fields: []hotline.Field{
+						hotline.NewField(hotline.FieldError, []byte("The server could not understand the request.")),
+					},
+				},
+			},
 		},
 		{
 			name: "FieldFileName does not allow directory traversal",
@@ -1066,7 +1086,7 @@ func TestHandleNewFolder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleNewFolder(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranNewFolder)(tt.args.cc, &tt.args.t)
 
 			if !TranAssertEqual(t, tt.wantRes, gotRes) {
 				t.Errorf("HandleNewFolder() gotRes = %v, want %v", gotRes, tt.wantRes)
@@ -1120,7 +1140,7 @@ func TestHandleUploadFile(t *testing.T) {
 				{
 					IsReply: 0x01,
 					Fields: []hotline.Field{
-						hotline.NewField(hotline.FieldRefNum, []byte{0x52, 0xfd, 0xfc, 0x07}), // rand.Seed(1)
+						hotline.NewField(hotline.FieldRefNum, []byte{0x52, 0xfd, 0xfc, 0x07}), // RefNum is random and ignored by TranAssertEqual
 					},
 				},
 			},
@@ -1152,7 +1172,7 @@ func TestHandleUploadFile(t *testing.T) {
 					IsReply:   0x01,
 					ErrorCode: [4]byte{0, 0, 0, 1},
 					Fields: []hotline.Field{
-						hotline.NewField(hotline.FieldError, []byte("You are not allowed to upload files.")), // rand.Seed(1)
+						hotline.NewField(hotline.FieldError, []byte("You are not allowed to upload files.")),
 					},
 				},
 			},
@@ -1160,7 +1180,7 @@ func TestHandleUploadFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleUploadFile(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranUploadFile)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -1318,7 +1338,7 @@ func TestHandleMakeAlias(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleMakeAlias(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranMakeFileAlias)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -1473,6 +1493,7 @@ func TestHandleDeleteUser(t *testing.T) {
 					Server: &hotline.Server{
 						AccountManager: func() *MockAccountManager {
 							m := MockAccountManager{}
+							m.On("Get", "testuser").Return(&hotline.Account{Login: "testuser"})
 							m.On("Delete", "testuser").Return(nil)
 							return &m
 						}(),
@@ -1523,6 +1544,48 @@ func TestHandleDeleteUser(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "when the account has more access than the user",
+			args: args{
+				cc: &hotline.ClientConn{
+					Account: &hotline.Account{
+						Access: func() hotline.AccessBitmap {
+							var bits hotline.AccessBitmap
+							bits.Set(hotline.AccessDeleteUser)
+							return bits
+						}(),
+					},
+					Server: &hotline.Server{
+						AccountManager: func() *MockAccountManager {
+							m := MockAccountManager{}
+							m.On("Get", "admin").Return(&hotline.Account{
+								Login: "admin",
+								Access: func() hotline.AccessBitmap {
+									var bits hotline.AccessBitmap
+									bits.Set(hotline.AccessDeleteUser)
+									bits.Set(hotline.AccessDisconUser)
+									return bits
+								}(),
+							})
+							return &m
+						}(),
+					},
+				},
+				t: hotline.NewTransaction(
+					hotline.TranDeleteUser, [2]byte{0, 1},
+					hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte("admin"))),
+				),
+			},
+			wantRes: []hotline.Transaction{
+				{
+					IsReply:   0x01,
+					ErrorCode: [4]byte{0, 0, 0, 1},
+					Fields: []hotline.Field{
+						hotline.NewField(hotline.FieldError, []byte("Cannot delete account with more access than yourself.")),
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1606,7 +1669,7 @@ func TestHandleGetMsgs(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleGetMsgs(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranGetMsgs)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -1827,6 +1890,38 @@ func TestHandleDownloadFile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "when the monthly transfer cap has been reached",
+			args: args{
+				cc: &hotline.ClientConn{
+					Account: &hotline.Account{
+						Access: func() hotline.AccessBitmap {
+							var bits hotline.AccessBitmap
+							bits.Set(hotline.AccessDownloadFile)
+							return bits
+						}(),
+					},
+					Server: &hotline.Server{
+						Config: hotline.Config{MonthlyTransferCapMB: 1},
+						Bandwidth: func() *hotline.BandwidthMeter {
+							m := hotline.NewBandwidthMeter(hotline.BandwidthUsage{})
+							m.Add(512*1024, 512*1024)
+							return m
+						}(),
+					},
+				},
+				t: hotline.NewTransaction(hotline.TranDownloadFile, [2]byte{0, 1}),
+			},
+			wantRes: []hotline.Transaction{
+				{
+					IsReply:   0x01,
+					ErrorCode: [4]byte{0, 0, 0, 1},
+					Fields: []hotline.Field{
+						hotline.NewField(hotline.FieldError, []byte(transferCapExceededMsg)),
+					},
+				},
+			},
+		},
 		{
 			name: "with a valid file",
 			args: args{
@@ -1947,7 +2042,7 @@ func TestHandleDownloadFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleDownloadFile(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranDownloadFile)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -2108,6 +2203,103 @@ func TestHandleUpdateUser(t *testing.T) {
 	}
 }
 
+// encodeFieldList encodes fields in the nested field list format of TranUpdateUser.
+func encodeFieldList(fields ...hotline.Field) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(len(fields)))
+	for _, f := range fields {
+		b = append(b, f.Type[:]...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(f.Data)))
+		b = append(b, f.Data...)
+	}
+
+	return b
+}
+
+func TestHandleUpdateUser_grantAccess(t *testing.T) {
+	var adminAccess, grantedAccess hotline.AccessBitmap
+	adminAccess.Set(hotline.AccessModifyUser)
+	grantedAccess.Set(hotline.AccessModifyUser)
+	grantedAccess.Set(hotline.AccessDisconUser)
+
+	am := &MockAccountManager{}
+	am.On("Get", "bbb").Return(&hotline.Account{Login: "bbb"})
+
+	cc := &hotline.ClientConn{
+		Logger:  NewTestLogger(),
+		Server:  &hotline.Server{AccountManager: am, Logger: NewTestLogger()},
+		Account: &hotline.Account{Access: adminAccess},
+	}
+
+	// Admins can't grant access privileges they don't have, to other accounts or to their own.
+	res := HandleUpdateUser(cc, &hotline.Transaction{
+		Type: hotline.TranUpdateUser,
+		Fields: []hotline.Field{hotline.NewField(hotline.FieldData, encodeFieldList(
+			hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte("bbb"))),
+			hotline.NewField(hotline.FieldUserPassword, []byte{0}),
+			hotline.NewField(hotline.FieldUserName, []byte("bbb")),
+			hotline.NewField(hotline.FieldUserAccess, grantedAccess[:]),
+		))},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("Cannot grant more access than you have yourself."))},
+	}}, res)
+	am.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestHandleUpdateUser_confirmDeletes(t *testing.T) {
+	cc := &hotline.ClientConn{
+		Logger:  NewTestLogger(),
+		Server:  &hotline.Server{Config: hotline.Config{ConfirmBatchDeletes: 1}},
+		Account: &hotline.Account{},
+	}
+
+	// Users who can't delete accounts are refused without being asked to confirm.
+	res := HandleUpdateUser(cc, &hotline.Transaction{
+		Type: hotline.TranUpdateUser,
+		Fields: []hotline.Field{hotline.NewField(hotline.FieldData, encodeFieldList(
+			hotline.NewField(hotline.FieldData, hotline.EncodeString([]byte("bbb"))),
+		))},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("You are not allowed to delete accounts."))},
+	}}, res)
+}
+
+func TestHandleSetUser(t *testing.T) {
+	var adminAccess, grantedAccess hotline.AccessBitmap
+	adminAccess.Set(hotline.AccessModifyUser)
+	grantedAccess.Set(hotline.AccessDisconUser)
+
+	am := &MockAccountManager{}
+	am.On("Get", "bbb").Return(&hotline.Account{Login: "bbb"})
+
+	cc := &hotline.ClientConn{
+		Logger:  NewTestLogger(),
+		Server:  &hotline.Server{AccountManager: am, Logger: NewTestLogger()},
+		Account: &hotline.Account{Access: adminAccess},
+	}
+
+	res := HandleSetUser(cc, &hotline.Transaction{
+		Type: hotline.TranSetUser,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldUserLogin, hotline.EncodeString([]byte("bbb"))),
+			hotline.NewField(hotline.FieldUserName, []byte("bbb")),
+			hotline.NewField(hotline.FieldUserPassword, []byte{0}),
+			hotline.NewField(hotline.FieldUserAccess, grantedAccess[:]),
+		},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("Cannot grant more access than you have yourself."))},
+	}}, res)
+	am.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
 func TestHandleDelNewsArt(t *testing.T) {
 	type args struct {
 		cc *hotline.ClientConn
@@ -2147,7 +2339,7 @@ func TestHandleDelNewsArt(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleDelNewsArt(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranDelNewsArt)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -2234,10 +2426,39 @@ func TestHandleDisconnectUser(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "when the user ID is malformed",
+			args: args{
+				cc: &hotline.ClientConn{
+					Logger: NewTestLogger(),
+					Account: &hotline.Account{
+						Access: func() hotline.AccessBitmap {
+							var bits hotline.AccessBitmap
+							bits.Set(hotline.AccessDisconUser)
+							return bits
+						}(),
+					},
+				},
+				t: hotline.NewTransaction(
+					hotline.TranDisconnectUser,
+					[2]byte{0, 0},
+					hotline.NewField(hotline.FieldUserID, []byte{1}),
+				),
+			},
+			wantRes: []hotline.Transaction{
+				{
+					IsReply:   0x01,
+					ErrorCode: [4]byte{0, 0, 0, 1},
+					Fields: []hotline.Field{
+						hotline.NewField(hotline.FieldError, []byte("Invalid request.")),
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleDisconnectUser(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranDisconnectUser)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -2436,7 +2657,7 @@ func TestHandleSendInstantMsg(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleSendInstantMsg(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranSendInstantMsg)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -2541,6 +2762,8 @@ func TestHandleDeleteFile(t *testing.T) {
 							mfs.On("Remove", "/fakeRoot/Files/aaa/testfile.incomplete").Return(nil)
 							mfs.On("Remove", "/fakeRoot/Files/aaa/.rsrc_testfile").Return(nil)
 							mfs.On("Remove", "/fakeRoot/Files/aaa/.info_testfile").Return(nil)
+							mfs.On("Remove", "/fakeRoot/Files/aaa/.thumb_testfile").Return(nil)
+							mfs.On("Remove", "/fakeRoot/Files/aaa/.meta_testfile").Return(nil)
 
 							return mfs
 						}(),
@@ -2597,7 +2820,7 @@ func TestHandleGetFileNameList(t *testing.T) {
 						}(),
 					},
 					Server: &hotline.Server{
-
+						FS: &hotline.OSFileStore{},
 						Config: hotline.Config{
 							FileRoot: func() string {
 								path, _ := os.Getwd()
@@ -2636,7 +2859,7 @@ func TestHandleGetFileNameList(t *testing.T) {
 							return filepath.Join(path, "/test/config/Files/getFileNameListTestDir")
 						}(),
 					},
-					Server: &hotline.Server{},
+					Server: &hotline.Server{FS: &hotline.OSFileStore{}},
 				},
 				t: hotline.NewTransaction(
 					hotline.TranGetFileNameList, [2]byte{0, 1},
@@ -2672,6 +2895,33 @@ func TestHandleGetFileNameList(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with an invalid filter",
+			args: args{
+				cc: &hotline.ClientConn{
+					Account: &hotline.Account{
+						FileRoot: func() string {
+							path, _ := os.Getwd()
+							return filepath.Join(path, "/test/config/Files/getFileNameListTestDir")
+						}(),
+					},
+					Server: &hotline.Server{FS: &hotline.OSFileStore{}},
+				},
+				t: hotline.NewTransaction(
+					hotline.TranGetFileNameList, [2]byte{0, 1},
+					hotline.NewField(hotline.FieldFileFilter, []byte("[")),
+				),
+			},
+			wantRes: []hotline.Transaction{
+				{
+					IsReply:   0x01,
+					ErrorCode: [4]byte{0, 0, 0, 1},
+					Fields: []hotline.Field{
+						hotline.NewField(hotline.FieldError, []byte("Invalid file list sort or filter options.")),
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -2802,7 +3052,7 @@ None.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleGetClientInfoText(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranGetClientInfoText)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -3166,7 +3416,7 @@ func TestHandleTranOldPostNews(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleTranOldPostNews(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranOldPostNews)(tt.args.cc, &tt.args.t)
 
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
@@ -3232,7 +3482,8 @@ func TestHandleInviteNewChat(t *testing.T) {
 						}(),
 						ChatMgr: func() *hotline.MockChatManager {
 							m := hotline.MockChatManager{}
-							m.On("New", mock.AnythingOfType("*hotline.ClientConn")).Return(hotline.ChatID{0x52, 0xfd, 0xfc, 0x07})
+							m.On("New", mock.AnythingOfType("*hotline.ClientConn"), hotline.ChatOptions{}).Return(hotline.ChatID{0x52, 0xfd, 0xfc, 0x07})
+							m.On("Invite", hotline.ChatID{0x52, 0xfd, 0xfc, 0x07}, mock.AnythingOfType("*hotline.ClientConn"), mock.AnythingOfType("*hotline.ClientConn")).Return(nil)
 							return &m
 						}(),
 					},
@@ -3293,7 +3544,8 @@ func TestHandleInviteNewChat(t *testing.T) {
 						}(),
 						ChatMgr: func() *hotline.MockChatManager {
 							m := hotline.MockChatManager{}
-							m.On("New", mock.AnythingOfType("*hotline.ClientConn")).Return(hotline.ChatID{0x52, 0xfd, 0xfc, 0x07})
+							m.On("New", mock.AnythingOfType("*hotline.ClientConn"), hotline.ChatOptions{}).Return(hotline.ChatID{0x52, 0xfd, 0xfc, 0x07})
+							m.On("Invite", hotline.ChatID{0x52, 0xfd, 0xfc, 0x07}, mock.AnythingOfType("*hotline.ClientConn"), mock.AnythingOfType("*hotline.ClientConn")).Return(nil)
 							return &m
 						}(),
 					},
@@ -3331,7 +3583,7 @@ func TestHandleInviteNewChat(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			gotRes := HandleInviteNewChat(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranInviteNewChat)(tt.args.cc, &tt.args.t)
 
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
@@ -3424,7 +3676,7 @@ func TestHandleGetNewsArtData(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRes := HandleGetNewsArtData(tt.args.cc, &tt.args.t)
+			gotRes := registeredHandler(hotline.TranGetNewsArtData)(tt.args.cc, &tt.args.t)
 			TranAssertEqual(t, tt.wantRes, gotRes)
 		})
 	}
@@ -3770,7 +4022,7 @@ func TestHandlePostNewsArt(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			TranAssertEqual(t, tt.wantRes, HandlePostNewsArt(tt.args.cc, &tt.args.t))
+			TranAssertEqual(t, tt.wantRes, registeredHandler(hotline.TranPostNewsArt)(tt.args.cc, &tt.args.t))
 		})
 	}
 }
@@ -3817,7 +4069,7 @@ func TestHandleUploadFolder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			TranAssertEqual(t, tt.wantRes, HandleUploadFolder(tt.args.cc, &tt.args.t))
+			TranAssertEqual(t, tt.wantRes, registeredHandler(hotline.TranUploadFldr)(tt.args.cc, &tt.args.t))
 		})
 	}
 }
@@ -3864,7 +4116,185 @@ func TestHandleDownloadFolder(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			TranAssertEqual(t, tt.wantRes, HandleDownloadFolder(tt.args.cc, &tt.args.t))
+			TranAssertEqual(t, tt.wantRes, registeredHandler(hotline.TranDownloadFldr)(tt.args.cc, &tt.args.t))
 		})
 	}
 }
+
+func TestHandleGetServerInfo(t *testing.T) {
+	stats := hotline.NewStats()
+	stats.Increment(hotline.StatDownloadCounter, hotline.StatDownloadCounter, hotline.StatUploadCounter, hotline.StatDownloadsInProgress)
+
+	clientMgr := &hotline.MockClientMgr{}
+	clientMgr.On("List").Return([]*hotline.ClientConn{{}, {}, {}})
+
+	cc := &hotline.ClientConn{
+		Account: &hotline.Account{},
+		Server: &hotline.Server{
+			Version:   "v1.2.3",
+			Stats:     stats,
+			ClientMgr: clientMgr,
+		},
+	}
+
+	res := HandleGetServerInfo(cc, &hotline.Transaction{ID: [4]byte{0, 0, 0, 1}})
+
+	TranAssertEqual(t, []hotline.Transaction{
+		{
+			IsReply: 0x01,
+			ID:      [4]byte{0, 0, 0, 1},
+			Fields: []hotline.Field{
+				hotline.NewField(hotline.FieldServerVersion, []byte("v1.2.3")),
+				*res[0].GetField(hotline.FieldServerUptime),
+				hotline.NewField(hotline.FieldUserCount, []byte{0, 0, 0, 3}),
+				hotline.NewField(hotline.FieldDownloadCount, []byte{0, 0, 0, 2}),
+				hotline.NewField(hotline.FieldUploadCount, []byte{0, 0, 0, 1}),
+				hotline.NewField(hotline.FieldDownloadsInProgress, []byte{0, 0, 0, 1}),
+				hotline.NewField(hotline.FieldUploadsInProgress, []byte{0, 0, 0, 0}),
+			},
+		},
+	}, res)
+}
+
+func TestHandleBookmarks(t *testing.T) {
+	fileRoot := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(fileRoot, "Uploads"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(fileRoot, "Uploads", "a.sit"), []byte("a"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(fileRoot, "Uploads", ".hidden"), []byte("h"), 0644))
+
+	am := newTestAccountManager(t, "alice", hotline.GuestAccount)
+	assert.NoError(t, am.Modify("alice", func(a *hotline.Account) { a.Bookmarks = []string{"Uploads"} }))
+
+	cc := &hotline.ClientConn{
+		Account: &hotline.Account{Login: "alice"},
+		Server: &hotline.Server{
+			FS:             &hotline.OSFileStore{},
+			AccountManager: am,
+			Config:         hotline.Config{FileRoot: fileRoot, HiddenFiles: hotline.DefaultHiddenFiles},
+		},
+		Logger: NewTestLogger(),
+	}
+
+	bookmarkTran := func(tranType [2]byte, name string) *hotline.Transaction {
+		return &hotline.Transaction{
+			Type: tranType,
+			Fields: []hotline.Field{
+				hotline.NewField(hotline.FieldFileName, []byte(name)),
+				hotline.NewField(hotline.FieldFilePath, hotline.EncodeFilePath("Uploads")),
+			},
+		}
+	}
+
+	// Missing and hidden files can't be bookmarked.
+	for _, name := range []string{"missing.sit", ".hidden"} {
+		res := HandleAddBookmark(cc, bookmarkTran(hotline.TranAddBookmark, name))
+		TranAssertEqual(t, []hotline.Transaction{{
+			IsReply:   0x01,
+			ErrorCode: [4]byte{0, 0, 0, 1},
+			Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte(`Cannot bookmark "`+name+`" because it does not exist or cannot be found.`))},
+		}}, res)
+	}
+
+	res := HandleAddBookmark(cc, bookmarkTran(hotline.TranAddBookmark, "a.sit"))
+	TranAssertEqual(t, []hotline.Transaction{{IsReply: 0x01}}, res)
+
+	res = HandleGetBookmarks(cc, &hotline.Transaction{Type: hotline.TranGetBookmarks})
+	TranAssertEqual(t, []hotline.Transaction{{
+		IsReply: 0x01,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldBookmark, []byte("Uploads")),
+			hotline.NewField(hotline.FieldBookmark, []byte("Uploads/a.sit")),
+		},
+	}}, res)
+
+	res = HandleDeleteBookmark(cc, bookmarkTran(hotline.TranDeleteBookmark, "a.sit"))
+	TranAssertEqual(t, []hotline.Transaction{{IsReply: 0x01}}, res)
+
+	res = HandleDeleteBookmark(cc, bookmarkTran(hotline.TranDeleteBookmark, "a.sit"))
+	TranAssertEqual(t, []hotline.Transaction{{
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("Bookmark not found."))},
+	}}, res)
+	assert.Equal(t, []string{"Uploads"}, am.Get("alice").Bookmarks)
+
+	// Guests share the guest account, so they can't change its bookmarks.
+	cc.Account = am.Get(hotline.GuestAccount)
+	for _, res := range [][]hotline.Transaction{
+		HandleAddBookmark(cc, bookmarkTran(hotline.TranAddBookmark, "a.sit")),
+		HandleDeleteBookmark(cc, bookmarkTran(hotline.TranDeleteBookmark, "Uploads")),
+	} {
+		TranAssertEqual(t, []hotline.Transaction{{
+			IsReply:   0x01,
+			ErrorCode: [4]byte{0, 0, 0, 1},
+			Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("You are not allowed to save bookmarks."))},
+		}}, res)
+	}
+	assert.Empty(t, am.Get(hotline.GuestAccount).Bookmarks)
+}
+
+func TestHandlePreferences(t *testing.T) {
+	am := newTestAccountManager(t, "alice")
+
+	cc := &hotline.ClientConn{
+		ID:      [2]byte{0, 1},
+		Account: am.Get("alice"),
+		Server: &hotline.Server{
+			AccountManager: am,
+			ClientMgr:      hotline.NewMemClientMgr(),
+		},
+		Logger: NewTestLogger(),
+	}
+	cc.Server.ClientMgr.Add(cc)
+
+	res := HandleSetPreferences(cc, &hotline.Transaction{
+		Type: hotline.TranSetPreferences,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldPreference, []byte("Ignore=Spammer")),
+			hotline.NewField(hotline.FieldPreference, []byte("RefuseChat=true")),
+		},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{ClientID: [2]byte{0, 1}, IsReply: 0x01}}, res)
+	assert.Equal(t, map[string]string{"Ignore": "Spammer", "RefuseChat": "true"}, cc.Account.Preferences)
+
+	res = HandleSetPreferences(cc, &hotline.Transaction{
+		Type:   hotline.TranSetPreferences,
+		Fields: []hotline.Field{hotline.NewField(hotline.FieldPreference, []byte("Color=blue"))},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{
+		ClientID:  [2]byte{0, 1},
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte(`Cannot save preferences: unknown preference "Color".`))},
+	}}, res)
+
+	res = HandleGetPreferences(cc, &hotline.Transaction{Type: hotline.TranGetPreferences})
+	TranAssertEqual(t, []hotline.Transaction{{
+		ClientID: [2]byte{0, 1},
+		IsReply:  0x01,
+		Fields: []hotline.Field{
+			hotline.NewField(hotline.FieldPreference, []byte("Ignore=Spammer")),
+			hotline.NewField(hotline.FieldPreference, []byte("RefuseChat=true")),
+		},
+	}}, res)
+
+	// Guests share the guest account, so they can't change its preferences.
+	guestAM := newTestAccountManager(t, hotline.GuestAccount)
+	guest := &hotline.ClientConn{
+		ID:      [2]byte{0, 2},
+		Account: guestAM.Get(hotline.GuestAccount),
+		Server:  &hotline.Server{AccountManager: guestAM},
+		Logger:  NewTestLogger(),
+	}
+	res = HandleSetPreferences(guest, &hotline.Transaction{
+		Type:   hotline.TranSetPreferences,
+		Fields: []hotline.Field{hotline.NewField(hotline.FieldPreference, []byte("AutoReply=Gone fishing"))},
+	})
+	TranAssertEqual(t, []hotline.Transaction{{
+		ClientID:  [2]byte{0, 2},
+		IsReply:   0x01,
+		ErrorCode: [4]byte{0, 0, 0, 1},
+		Fields:    []hotline.Field{hotline.NewField(hotline.FieldError, []byte("You are not allowed to change account preferences."))},
+	}}, res)
+	assert.Empty(t, guestAM.Get(hotline.GuestAccount).Preferences)
+}
@@ -0,0 +1,66 @@
+package mobius
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultScanCommand is used when UploadScan is enabled without a Command.  --fdpass lets clamd scan files that only
+// the server can read.
+var defaultScanCommand = []string{"clamdscan", "--no-summary", "--fdpass"}
+
+const defaultScanTimeout = 5 * time.Minute
+
+// NewUploadScanner returns a scanner that runs the command in config on uploaded files, or nil if scanning is disabled.
+func NewUploadScanner(config hotline.UploadScan) (hotline.UploadScanner, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	command := config.Command
+	if len(command) == 0 {
+		command = defaultScanCommand
+	}
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil, fmt.Errorf("upload scanner: %w", err)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+
+	return &commandScanner{command: command, timeout: timeout}, nil
+}
+
+// commandScanner scans uploads by running a virus scanner such as clamdscan with the path of the file.
+type commandScanner struct {
+	command []string
+	timeout time.Duration
+}
+
+// ScanUpload runs the scanner on the uploaded file.  As with clamdscan, the scanner must exit with status 0 for clean
+// files and 1 for infected files; any other status is an error.
+func (cs *commandScanner) ScanUpload(ctx context.Context, upload hotline.UploadInfo) error {
+	ctx, cancel := context.WithTimeout(ctx, cs.timeout)
+	defer cancel()
+
+	args := append(slices.Clone(cs.command[1:]), upload.Path)
+	out, err := exec.CommandContext(ctx, cs.command[0], args...).CombinedOutput()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return fmt.Errorf("%w: %s", hotline.ErrUploadRejected, strings.TrimSpace(string(out)))
+	default:
+		return fmt.Errorf("%s: %w: %s", cs.command[0], err, strings.TrimSpace(string(out)))
+	}
+}
@@ -0,0 +1,48 @@
+package mobius
+
+import (
+	"context"
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCommandScanner_ScanUpload(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		wantErr      bool
+		wantRejected bool
+	}{
+		{name: "clean", script: `test -f "$1"`},
+		{name: "infected", script: `echo "$1: Eicar-Signature FOUND"; exit 1`, wantErr: true, wantRejected: true},
+		{name: "scanner error", script: `echo "ERROR: Could not connect to clamd"; exit 2`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := NewUploadScanner(hotline.UploadScan{
+				Enabled: true,
+				Command: []string{"sh", "-c", tt.script, "sh"},
+				Timeout: time.Minute,
+			})
+			require.NoError(t, err)
+
+			err = scanner.ScanUpload(context.Background(), hotline.UploadInfo{Path: "upload_scan_test.go"})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantRejected, errors.Is(err, hotline.ErrUploadRejected))
+		})
+	}
+}
+
+func TestNewUploadScanner_Disabled(t *testing.T) {
+	scanner, err := NewUploadScanner(hotline.UploadScan{})
+	assert.NoError(t, err)
+	assert.Nil(t, scanner)
+}
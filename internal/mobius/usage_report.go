@@ -0,0 +1,87 @@
+package mobius
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"github.com/jhalter/mobius/hotline"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// usageReportPayload is the JSON body sent to the usage report webhook.
+type usageReportPayload struct {
+	Event string `json:"event"`
+	hotline.WeeklyUsage
+	Accounts int                     `json:"accounts"`
+	TopFiles []hotline.FileDownloads `json:"topFiles"`
+}
+
+// LoadWeeklyUsage reads saved weekly usage from the YAML file at path.  A missing file is not an error.
+func LoadWeeklyUsage(path string) (usage hotline.WeeklyUsage, err error) {
+	err = loadFromYAMLFile(path, &usage)
+	if errors.Is(err, fs.ErrNotExist) {
+		return usage, nil
+	}
+
+	return usage, err
+}
+
+// SaveWeeklyUsage writes weekly usage to the YAML file at path so that it survives server restarts.
+func SaveWeeklyUsage(path string, usage hotline.WeeklyUsage) error {
+	return writeYAMLFile(path, usage)
+}
+
+// SendUsageReport posts the usage report for a finished week to the configured news category and webhook.
+func SendUsageReport(srv *hotline.Server, usage hotline.WeeklyUsage) {
+	cfg := srv.Config.UsageReports
+	topFiles := cmp.Or(cfg.TopFiles, hotline.DefaultUsageReportTopFiles)
+
+	if cfg.NewsCategory != "" {
+		title, body := usage.Report(topFiles)
+
+		err := srv.ThreadedNewsMgr.PostArticle(strings.Split(strings.Trim(cfg.NewsCategory, "/"), "/"), 0, hotline.NewsArtData{
+			Title:    title,
+			Poster:   srv.CurrentConfig().Name,
+			Date:     hotline.NewTime(time.Now()),
+			DataFlav: hotline.NewsFlavor,
+			Data:     hotline.ToMacRoman(body),
+		})
+		if err != nil {
+			srv.Logger.Error("Error posting usage report news article", "err", err)
+		}
+	}
+
+	if cfg.WebhookURL != "" {
+		postWebhook(cfg.WebhookURL, usageReportPayload{
+			Event:       "usage_report",
+			WeeklyUsage: usage,
+			Accounts:    usage.Accounts(),
+			TopFiles:    usage.TopFiles(topFiles),
+		}, srv.Logger)
+	}
+}
+
+// RunUsageReports saves the usage collected by srv.Usage to path every minute, and sends a usage report when a week
+// finishes, until ctx is cancelled.  A week that finished while the server was stopped is reported on start.
+func RunUsageReports(ctx context.Context, srv *hotline.Server, path string) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if finished, ok := srv.Usage.Rollover(time.Now()); ok {
+			SendUsageReport(srv, finished)
+		}
+
+		if err := SaveWeeklyUsage(path, srv.Usage.Usage()); err != nil {
+			srv.Logger.Error("Error saving usage", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
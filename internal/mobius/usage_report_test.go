@@ -0,0 +1,84 @@
+package mobius
+
+import (
+	"encoding/json"
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWeeklyUsage_saveAndLoad(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Usage.yaml")
+
+	usage, err := LoadWeeklyUsage(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, hotline.WeeklyUsage{}, usage)
+
+	want := hotline.WeeklyUsage{
+		Week:        "2025-W10",
+		Logins:      map[string]int{"alice": 2},
+		Connections: 2,
+		PeakUsers:   1,
+		Files:       map[string]int{"Apps/game.sit": 1},
+		Downloads:   1,
+	}
+	require.NoError(t, SaveWeeklyUsage(filePath, want))
+
+	usage, err = LoadWeeklyUsage(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, want, usage)
+}
+
+func TestSendUsageReport(t *testing.T) {
+	payloads := make(chan map[string]any, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		payloads <- p
+	}))
+	defer ts.Close()
+
+	newsPath := filepath.Join(t.TempDir(), "ThreadedNews.yaml")
+	require.NoError(t, os.WriteFile(newsPath, []byte("Categories: {}\n"), 0640))
+
+	news, err := NewThreadedNewsYAML(newsPath)
+	require.NoError(t, err)
+	require.NoError(t, news.CreateGrouping(nil, "Reports", hotline.NewsCategory))
+
+	srv := &hotline.Server{
+		Config: hotline.Config{
+			Name:         "Test Server",
+			UsageReports: hotline.UsageReports{NewsCategory: "Reports", WebhookURL: ts.URL, TopFiles: 1},
+		},
+		Logger:          slog.Default(),
+		ThreadedNewsMgr: news,
+	}
+
+	SendUsageReport(srv, hotline.WeeklyUsage{
+		Week:        "2025-W10",
+		Logins:      map[string]int{"alice": 2, "bob": 1},
+		Connections: 3,
+		Files:       map[string]int{"Apps/game.sit": 2, "Docs/readme.txt": 1},
+		Downloads:   3,
+	})
+
+	assert.Equal(t, "Usage report for 2025-W10", news.GetArticle([]string{"Reports"}, 1).Title)
+
+	select {
+	case p := <-payloads:
+		assert.Equal(t, "usage_report", p["event"])
+		assert.Equal(t, "2025-W10", p["week"])
+		assert.EqualValues(t, 2, p["accounts"])
+		assert.EqualValues(t, 3, p["connections"])
+		assert.Equal(t, []any{map[string]any{"path": "Apps/game.sit", "downloads": float64(2)}}, p["topFiles"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not received")
+	}
+}
@@ -0,0 +1,231 @@
+package mobius
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"golang.org/x/net/websocket"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/time/rate"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultWebChatBotName is the name that messages from web clients are sent from when WebChat.BotName isn't set.
+const defaultWebChatBotName = "Web"
+
+// webChatMaxPayload is the max size in bytes of a message from a web chat client.
+const webChatMaxPayload = 16 * 1024
+
+// webChatRequest is a message from a web chat client: either a "login" with a Hotline account's Login and Password, or
+// a "chat" message with Text.
+type webChatRequest struct {
+	Type     string `json:"type"`
+	Login    string `json:"login,omitempty"`
+	Password string `json:"password,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// webChatReply is a message to a web chat client other than a hotline.ChatFeedEvent: the "users" connected when the
+// client connects, or when it logs in if Privacy.HideUserCount is set, the Name of the user after a successful
+// "login", or an "error".
+type webChatReply struct {
+	Type  string   `json:"type"`
+	Users []string `json:"users,omitempty"`
+	Name  string   `json:"name,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// WebChatHandler serves the WebSocket endpoint of WebChat.  Clients are sent the names of the connected users, and
+// then each hotline.ChatFeedEvent as JSON.  If Privacy.HideUserCount is set, the users and users joining and leaving
+// are only sent to clients that have logged in.
+func (srv *APIServer) WebChatHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := srv.hlServer.Config.WebChat
+	if !cfg.Enabled || srv.hlServer.ChatFeed == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			return checkWebChatOrigin(config, req, cfg.AllowedOrigins)
+		},
+		Handler: srv.webChat,
+	}.ServeHTTP(w, r)
+}
+
+// checkWebChatOrigin rejects connections from web sites with origins that aren't in allowed, or from web sites other
+// than the API's own if no origins are listed.  Clients other than browsers may not send an origin, and are allowed.
+func checkWebChatOrigin(config *websocket.Config, req *http.Request, allowed []string) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	config.Origin = origin
+
+	if origin == nil {
+		return nil
+	}
+	if len(allowed) == 0 {
+		if strings.EqualFold(origin.Host, req.Host) {
+			return nil
+		}
+	} else {
+		for _, o := range allowed {
+			if strings.EqualFold(strings.TrimSuffix(o, "/"), origin.String()) {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("origin not allowed")
+}
+
+func (srv *APIServer) webChat(ws *websocket.Conn) {
+	defer ws.Close()
+	ws.MaxPayloadBytes = webChatMaxPayload
+
+	ip := hotline.AddrIP(ws.Request().RemoteAddr)
+	logger := srv.logger.With("ip", ip)
+	logger.Info("Web chat client connected")
+	defer logger.Info("Web chat client disconnected")
+
+	events, unsubscribe := srv.hlServer.ChatFeed.Subscribe()
+	defer unsubscribe()
+
+	var mu sync.Mutex
+	send := func(v any) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return websocket.JSON.Send(ws, v)
+	}
+
+	// With HideUserCount, who is connected is only shown to clients that have logged in.
+	hideUsers := srv.hlServer.Config.Privacy.HideUserCount
+	var loggedIn atomic.Bool
+	if !hideUsers {
+		if err := send(webChatReply{Type: "users", Users: srv.userNames()}); err != nil {
+			return
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.webChatReceive(ws, ip, send, &loggedIn, logger)
+	}()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if hideUsers && !loggedIn.Load() && e.Type != hotline.ChatFeedChat {
+				continue
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// webChatReceive handles messages from a web chat client until it disconnects.  Chat is rate limited like the Send chat
+// transactions of a Hotline connection with the same account.
+func (srv *APIServer) webChatReceive(ws *websocket.Conn, ip string, send func(any) error, loggedIn *atomic.Bool, logger *slog.Logger) {
+	var account *hotline.Account
+	var limiter *rate.Limiter
+	for {
+		var req webChatRequest
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			return
+		}
+
+		var reply *webChatReply
+		switch req.Type {
+		case "login":
+			acc, err := srv.hlServer.CheckLogin(ip, req.Login, req.Password)
+			if err != nil {
+				logger.Info("Web chat login failed", "login", req.Login, "err", err)
+				reply = &webChatReply{Type: "error", Error: err.Error()}
+				break
+			}
+
+			account = acc
+			limiter = srv.hlServer.TransactionLimiter(acc, hotline.TranChatSend)
+			logger.Info("Web chat login", "login", acc.Login)
+			if err := send(webChatReply{Type: "login", Name: webChatName(acc)}); err != nil {
+				return
+			}
+			if !loggedIn.Swap(true) && srv.hlServer.Config.Privacy.HideUserCount {
+				reply = &webChatReply{Type: "users", Users: srv.userNames()}
+			}
+		case "chat":
+			if account == nil || !account.Access.IsSet(hotline.AccessSendChat) {
+				reply = &webChatReply{Type: "error", Error: "You are not allowed to participate in chat."}
+				break
+			}
+
+			text := strings.TrimSpace(strings.ReplaceAll(req.Text, "\n", "\r"))
+			if text == "" {
+				break
+			}
+			if limiter != nil && !limiter.Allow() {
+				logger.Info("Rate limit exceeded", "type", "Web chat")
+				if srv.hlServer.Config.RateLimits.Disconnect {
+					return
+				}
+				reply = &webChatReply{Type: "error", Error: "You are doing that too often.  Please wait a moment and try again."}
+				break
+			}
+			srv.hlServer.SendChat(srv.webChatBotName(), fmt.Sprintf("<%s> %s", webChatName(account), text))
+		default:
+			reply = &webChatReply{Type: "error", Error: fmt.Sprintf("unknown message type %q", req.Type)}
+		}
+
+		if reply != nil {
+			if err := send(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (srv *APIServer) webChatBotName() string {
+	if name := srv.hlServer.Config.WebChat.BotName; name != "" {
+		return name
+	}
+
+	return defaultWebChatBotName
+}
+
+// webChatName returns the name shown for a web chat user with account.
+func webChatName(account *hotline.Account) string {
+	if account.Name != "" {
+		return account.Name
+	}
+
+	return account.Login
+}
+
+// userNames returns the names of the users who have finished logging in.
+func (srv *APIServer) userNames() []string {
+	names := []string{}
+	for _, c := range srv.hlServer.ClientMgr.List() {
+		if len(c.UserName) == 0 {
+			continue
+		}
+
+		name, _ := charmap.Macintosh.NewDecoder().String(string(c.UserName))
+		names = append(names, name)
+	}
+
+	return names
+}
@@ -0,0 +1,212 @@
+package mobius
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jhalter/mobius/hotline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func newWebChatServer(t *testing.T, config hotline.Config) (*hotline.Server, *httptest.Server) {
+	t.Helper()
+
+	am := newTestAccountManager(t, "guest")
+	var access hotline.AccessBitmap
+	access.Set(hotline.AccessSendChat)
+	require.NoError(t, am.Create(hotline.Account{
+		Login:    "alice",
+		Name:     "Alice",
+		Password: hotline.HashAndSalt(hotline.EncodeString([]byte("secret"))),
+		Access:   access,
+	}))
+
+	banList, err := NewBanFile(filepath.Join(t.TempDir(), "Banlist.yaml"))
+	require.NoError(t, err)
+
+	hlServer := &hotline.Server{
+		Config:         config,
+		AccountManager: am,
+		BanList:        banList,
+		ClientMgr:      hotline.NewMemClientMgr(),
+		ChatFeed:       hotline.NewChatFeed(),
+		Logger:         NewTestLogger(),
+	}
+	hlServer.ClientMgr.Add(&hotline.ClientConn{ID: [2]byte{0, 1}, UserName: []byte("Bob")})
+
+	srv := NewAPIServer(hlServer, "", func() {}, NewTestLogger())
+	ts := httptest.NewServer(srv.mux)
+	t.Cleanup(ts.Close)
+
+	return hlServer, ts
+}
+
+func dialWebChat(t *testing.T, ts *httptest.Server, origin string) *websocket.Conn {
+	t.Helper()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/chat", "", origin)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ws.Close() })
+	require.NoError(t, ws.SetDeadline(time.Now().Add(5*time.Second)))
+
+	return ws
+}
+
+func TestWebChatHandler(t *testing.T) {
+	hlServer, ts := newWebChatServer(t, hotline.Config{WebChat: hotline.WebChat{Enabled: true, BotName: "Website"}})
+	ws := dialWebChat(t, ts, ts.URL)
+
+	var users webChatReply
+	require.NoError(t, websocket.JSON.Receive(ws, &users))
+	assert.Equal(t, webChatReply{Type: "users", Users: []string{"Bob"}}, users)
+
+	// Chat from the Hotline server is streamed to the client.
+	hlServer.ChatFeed.Chat([]byte("Bob"), []byte("hello"), false)
+	var event hotline.ChatFeedEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, hotline.ChatFeedChat, event.Type)
+	assert.Equal(t, "Bob", event.User)
+	assert.Equal(t, "hello", event.Text)
+
+	var reply webChatReply
+
+	// Chat requires a login.
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "chat", Text: "hi"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, "error", reply.Type)
+
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "login", Login: "alice", Password: "wrong"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, webChatReply{Type: "error", Error: hotline.ErrIncorrectLogin.Error()}, reply)
+
+	reply = webChatReply{}
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "login", Login: "alice", Password: "secret"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, webChatReply{Type: "login", Name: "Alice"}, reply)
+
+	// Chat from the web client is relayed into public chat as the bot user, and streamed back to the client.
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "chat", Text: "hi\n"}))
+	event = hotline.ChatFeedEvent{}
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, hotline.ChatFeedChat, event.Type)
+	assert.Equal(t, "Website", event.User)
+	assert.Equal(t, "<Alice> hi", event.Text)
+}
+
+func TestWebChatHandler_NoChatAccess(t *testing.T) {
+	_, ts := newWebChatServer(t, hotline.Config{WebChat: hotline.WebChat{Enabled: true}})
+	ws := dialWebChat(t, ts, ts.URL)
+
+	var users webChatReply
+	require.NoError(t, websocket.JSON.Receive(ws, &users))
+
+	var reply webChatReply
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "login", Login: "guest"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, webChatReply{Type: "login", Name: "guest"}, reply)
+
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "chat", Text: "hi"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, "error", reply.Type)
+}
+
+func TestWebChatHandler_AllowedOrigins(t *testing.T) {
+	_, ts := newWebChatServer(t, hotline.Config{WebChat: hotline.WebChat{Enabled: true, AllowedOrigins: []string{"https://example.com/"}}})
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/chat"
+
+	_, err := websocket.Dial(url, "", "https://evil.example")
+	assert.Error(t, err)
+
+	ws, err := websocket.Dial(url, "", "https://example.com")
+	require.NoError(t, err)
+	_ = ws.Close()
+}
+
+func TestWebChatHandler_sameOrigin(t *testing.T) {
+	_, ts := newWebChatServer(t, hotline.Config{WebChat: hotline.WebChat{Enabled: true}})
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/chat"
+
+	// Without AllowedOrigins, only pages from the API's own address can connect.
+	_, err := websocket.Dial(url, "", "https://evil.example")
+	assert.Error(t, err)
+
+	ws, err := websocket.Dial(url, "", ts.URL)
+	require.NoError(t, err)
+	_ = ws.Close()
+}
+
+func TestWebChatHandler_HideUserCount(t *testing.T) {
+	hlServer, ts := newWebChatServer(t, hotline.Config{
+		WebChat: hotline.WebChat{Enabled: true},
+		Privacy: hotline.Privacy{HideUserCount: true},
+	})
+	ws := dialWebChat(t, ts, ts.URL)
+
+	// No users are sent on connecting, so wait for a reply to know that the client is subscribed.
+	var reply webChatReply
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "chat", Text: "hi"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, "error", reply.Type)
+
+	// Users joining aren't shown to clients that haven't logged in, but chat is.
+	hlServer.ChatFeed.Join([]byte("Carol"))
+	hlServer.ChatFeed.Chat([]byte("Bob"), []byte("hello"), false)
+	var event hotline.ChatFeedEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, hotline.ChatFeedChat, event.Type)
+
+	reply = webChatReply{}
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "login", Login: "alice", Password: "secret"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, webChatReply{Type: "login", Name: "Alice"}, reply)
+	reply = webChatReply{}
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, webChatReply{Type: "users", Users: []string{"Bob"}}, reply)
+
+	hlServer.ChatFeed.Join([]byte("Carol"))
+	event = hotline.ChatFeedEvent{}
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, hotline.ChatFeedJoin, event.Type)
+	assert.Equal(t, "Carol", event.User)
+}
+
+func TestWebChatHandler_RateLimits(t *testing.T) {
+	_, ts := newWebChatServer(t, hotline.Config{
+		WebChat:    hotline.WebChat{Enabled: true},
+		RateLimits: hotline.RateLimits{Transactions: hotline.TransactionLimits{"Send chat": {Count: 1, Per: time.Hour}}},
+	})
+	ws := dialWebChat(t, ts, ts.URL)
+
+	var users webChatReply
+	require.NoError(t, websocket.JSON.Receive(ws, &users))
+
+	var reply webChatReply
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "login", Login: "alice", Password: "secret"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "chat", Text: "one"}))
+	var event hotline.ChatFeedEvent
+	require.NoError(t, websocket.JSON.Receive(ws, &event))
+	assert.Equal(t, "<Alice> one", event.Text)
+
+	require.NoError(t, websocket.JSON.Send(ws, webChatRequest{Type: "chat", Text: "two"}))
+	reply = webChatReply{}
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, webChatReply{Type: "error", Error: "You are doing that too often.  Please wait a moment and try again."}, reply)
+}
+
+func TestWebChatHandler_Disabled(t *testing.T) {
+	_, ts := newWebChatServer(t, hotline.Config{WebChat: hotline.WebChat{}})
+
+	resp, err := http.Get(ts.URL + "/api/v1/chat")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,104 @@
+package mobius
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jhalter/mobius/hotline"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// postWebhook sends payload as a JSON POST request to url in the background.  Failures are logged and not retried.
+func postWebhook(url string, payload any, logger *slog.Logger) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Error encoding webhook payload", "err", err)
+		return
+	}
+
+	go sendWebhook(url, body, logger)
+}
+
+// sendWebhook sends body as a JSON POST request to url, logging any failure.
+func sendWebhook(url string, body []byte, logger *slog.Logger) {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Error("Error sending webhook", "url", url, "err", err)
+		return
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		logger.Error("Error sending webhook", "url", url, "err", fmt.Errorf("unexpected status: %s", resp.Status))
+	}
+}
+
+// webhookQueueSize is the number of payloads a webhookQueue holds while waiting to send them.
+const webhookQueueSize = 1000
+
+// webhookQueue sends JSON payloads to a URL one at a time, in the order they are posted, for webhooks that can be
+// posted faster than the receiver accepts them.  Payloads posted while the queue is full are dropped.
+type webhookQueue struct {
+	url    string
+	logger *slog.Logger
+	queue  chan []byte
+
+	once    sync.Once
+	dropped atomic.Uint64 // Payloads dropped since the last one was queued, for logging
+}
+
+func newWebhookQueue(url string, size int, logger *slog.Logger) *webhookQueue {
+	return &webhookQueue{url: url, logger: logger, queue: make(chan []byte, size)}
+}
+
+// post queues payload to be sent after the payloads already queued, starting the sending goroutine on first use.
+func (q *webhookQueue) post(payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		q.logger.Error("Error encoding webhook payload", "err", err)
+		return
+	}
+
+	q.once.Do(func() { go q.run() })
+
+	select {
+	case q.queue <- body:
+		if dropped := q.dropped.Swap(0); dropped > 0 {
+			q.logger.Warn("Dropped webhook payloads because the queue was full", "url", q.url, "count", dropped)
+		}
+	default:
+		q.dropped.Add(1)
+	}
+}
+
+func (q *webhookQueue) run() {
+	for body := range q.queue {
+		sendWebhook(q.url, body, q.logger)
+	}
+}
+
+// lowDiskSpacePayload is the JSON body sent to the low disk space webhook.
+type lowDiskSpacePayload struct {
+	Event        string `json:"event"`
+	Path         string `json:"path"`
+	FreeBytes    uint64 `json:"freeBytes"`
+	MinFreeBytes uint64 `json:"minFreeBytes"`
+}
+
+// LowDiskSpaceWebhook returns a hook that posts an alert to url when free space on the disk holding path runs low.
+func LowDiskSpaceWebhook(url, path string, logger *slog.Logger) hotline.LowDiskSpaceHook {
+	return func(freeBytes, minFreeBytes uint64) {
+		postWebhook(url, lowDiskSpacePayload{
+			Event:        "low_disk_space",
+			Path:         path,
+			FreeBytes:    freeBytes,
+			MinFreeBytes: minFreeBytes,
+		}, logger)
+	}
+}
@@ -0,0 +1,55 @@
+// Package version reports the version of the Mobius commands, set at build time with linker flags, e.g.:
+//
+//	go build -ldflags "-X github.com/jhalter/mobius/internal/version.Version=v0.18.0" ./cmd/mobius-hotline-server
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"     // Release version, e.g. "v0.18.0"
+	Commit  = "none"    // Git commit the binary was built from
+	Date    = "unknown" // Time the binary was built, in RFC 3339 format
+)
+
+// Info is the version and build information of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the version and build information of the running binary.  Binaries built without linker flags, such as
+// with go install, fall back to the module version, and the commit and commit time recorded by the Go toolchain.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "vcs.revision" && info.Commit == "none":
+			info.Commit = s.Value
+		case s.Key == "vcs.time" && info.Date == "unknown":
+			info.Date = s.Value
+		}
+	}
+
+	return info
+}
+
+// String returns the version and build information on one line, e.g. "v0.18.0 (commit 1a2b3c4, built
+// 2025-03-01T20:00:00Z, go1.23.4)".
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.Date, i.GoVersion)
+}